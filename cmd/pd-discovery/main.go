@@ -16,7 +16,11 @@ package main
 import (
 	"flag"
 	"net/http"
-	_ "net/http/pprof"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
@@ -33,11 +37,15 @@ import (
 var (
 	printVersion bool
 	port         int
+	enablePprof  bool
+	pprofAddress string
 )
 
 func init() {
 	flag.BoolVar(&printVersion, "version", false, "Show version and quit")
 	flag.IntVar(&port, "port", 10261, "The port that the tidb discovery's http service runs on (default 10261)")
+	flag.BoolVar(&enablePprof, "enable-pprof", true, "Whether to serve the pprof handlers")
+	flag.StringVar(&pprofAddress, "pprof-address", ":6060", "The address that the pprof http service binds to (default :6060)")
 	flag.Parse()
 }
 
@@ -65,8 +73,39 @@ func main() {
 		klog.Fatalf("failed to get kubernetes Clientset: %v", err)
 	}
 
-	go wait.Forever(func() {
-		server.StartServer(cli, kubeCli, port)
-	}, 5*time.Second)
-	klog.Fatal(http.ListenAndServe(":6060", nil))
+	stopCh := make(chan struct{})
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-sigCh
+		klog.Infof("received signal %s, shutting down", sig)
+		close(stopCh)
+	}()
+
+	var wg sync.WaitGroup
+	if enablePprof {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			klog.Infof("starting pprof server, listening on %s", pprofAddress)
+			server.ListenAndServe(&http.Server{Addr: pprofAddress, Handler: pprofHandler()}, stopCh)
+		}()
+	}
+	tcName := os.Getenv("TC_NAME")
+	wait.Until(func() {
+		server.StartServer(cli, kubeCli, port, tcName, stopCh)
+	}, 5*time.Second, stopCh)
+	wg.Wait()
+}
+
+// pprofHandler returns the pprof handlers, they are not registered to the default mux
+// which the discovery service is served on
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
 }
@@ -19,6 +19,9 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -26,9 +29,12 @@ import (
 	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
 	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
 	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/controller/backup"
 	"github.com/tikv/tikv-operator/pkg/controller/tikvcluster"
+	"github.com/tikv/tikv-operator/pkg/metrics"
 	"github.com/tikv/tikv-operator/pkg/scheme"
 	"github.com/tikv/tikv-operator/pkg/verflag"
+	"github.com/tikv/tikv-operator/pkg/webhook"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/server/healthz"
@@ -41,10 +47,12 @@ import (
 	"k8s.io/client-go/tools/record"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/component-base/cli/globalflag"
+	componentbaseconfig "k8s.io/component-base/config"
 	"k8s.io/component-base/version"
 	"k8s.io/klog"
 	utilflag "k8s.io/kubernetes/pkg/util/flag"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 var (
@@ -52,10 +60,12 @@ var (
 	autoFailover       bool
 	pdFailoverPeriod   time.Duration
 	tikvFailoverPeriod time.Duration
-	leaseDuration      = 15 * time.Second
-	renewDuration      = 5 * time.Second
-	retryPeriod        = 3 * time.Second
+	leaderElection     componentbaseconfig.LeaderElectionConfiguration
 	waitDuration       = 5 * time.Second
+	webhookPort        int
+	webhookCertDir     string
+	metricsPort        int
+	watchNamespace     string
 	namedFlagSets      cliflag.NamedFlagSets
 )
 
@@ -65,8 +75,59 @@ func initFlags(fs *flag.FlagSet) {
 	fs.BoolVar(&autoFailover, "auto-failover", true, "Auto failover")
 	fs.DurationVar(&pdFailoverPeriod, "pd-failover-period", time.Duration(5*time.Minute), "PD failover period default(5m)")
 	fs.DurationVar(&tikvFailoverPeriod, "tikv-failover-period", time.Duration(5*time.Minute), "TiKV failover period default(5m)")
-	fs.DurationVar(&controller.ResyncDuration, "resync-duration", time.Duration(30*time.Second), "Resync time of informer")
+	fs.DurationVar(&controller.ResyncDuration, "resync-duration", time.Duration(30*time.Second), "Resync period of the informers")
+	fs.DurationVar(&controller.RequeueInterval, "requeue-interval", 0, "Requeue interval of the converging objects, 0 backs off exponentially")
 	fs.StringVar(&controller.PDDiscoveryImage, "pd-discovery-image", "tikv/tikv-operator:latest", "The image of the PD discovery service")
+	fs.StringVar(&controller.ServiceNodePortRange, "service-node-port-range", controller.ServiceNodePortRange, "NodePort range of kube-apiserver")
+	fs.BoolVar(&controller.NodeDrainLeaderEviction, "node-drain-leader-eviction", false, "Evict TiKV leaders on draining nodes")
+	fs.DurationVar(&controller.TiKVStoresCleanupTimeout, "tikv-stores-cleanup-timeout", controller.TiKVStoresCleanupTimeout, "TiKV stores cleanup timeout on deletion, 0 disables it")
+	fs.BoolVar(&controller.DryRun, "dry-run", false, "Log changes without applying them")
+	fs.IntVar(&webhookPort, "webhook-port", 0, "Admission webhook port, 0 disables it")
+	fs.StringVar(&webhookCertDir, "webhook-cert-dir", "/etc/webhook/certs", "Admission webhook certificate directory")
+	fs.IntVar(&metricsPort, "metrics-port", 8080, "Metrics port, 0 disables it")
+	fs.StringVar(&watchNamespace, "watch-namespace", "", "Comma-separated namespaces to watch, empty for all")
+}
+
+func initLeaderElectionFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&leaderElection.LeaderElect, "leader-elect", true, "Enable leader election")
+	fs.DurationVar(&leaderElection.LeaseDuration.Duration, "leader-elect-lease-duration", 15*time.Second, "Leader election lease duration")
+	fs.DurationVar(&leaderElection.RenewDeadline.Duration, "leader-elect-renew-deadline", 5*time.Second, "Leader election renew deadline")
+	fs.DurationVar(&leaderElection.RetryPeriod.Duration, "leader-elect-retry-period", 3*time.Second, "Leader election retry period")
+	fs.StringVar(&leaderElection.ResourceLock, "leader-elect-resource-lock", resourcelock.EndpointsResourceLock, fmt.Sprintf("Leader election resource lock: %s, %s or %s",
+		resourcelock.EndpointsResourceLock, resourcelock.ConfigMapsResourceLock, resourcelock.LeasesResourceLock))
+	fs.StringVar(&leaderElection.ResourceName, "leader-elect-resource-name", "tikv-controller-manager", "Leader election resource name")
+	fs.StringVar(&leaderElection.ResourceNamespace, "leader-elect-resource-namespace", "", "Leader election resource namespace, defaults to the operator namespace")
+}
+
+// parseWatchNamespaces returns the namespaces in the comma-separated list, which is empty if all the
+// namespaces are watched
+func parseWatchNamespaces(list string) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, ns := range strings.Split(list, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces
+}
+
+// validateLeaderElection checks the durations of the leader election, which are otherwise checked by
+// leaderelection.RunOrDie by panicking
+func validateLeaderElection(config componentbaseconfig.LeaderElectionConfiguration) error {
+	if config.RetryPeriod.Duration <= 0 {
+		return fmt.Errorf("the leader election retry period must be positive")
+	}
+	if config.LeaseDuration.Duration <= config.RenewDeadline.Duration {
+		return fmt.Errorf("the leader election lease duration %s must be greater than the renew deadline %s", config.LeaseDuration.Duration, config.RenewDeadline.Duration)
+	}
+	if config.RenewDeadline.Duration <= time.Duration(leaderelection.JitterFactor*float64(config.RetryPeriod.Duration)) {
+		return fmt.Errorf("the leader election renew deadline %s must be greater than %v times the retry period %s", config.RenewDeadline.Duration, leaderelection.JitterFactor, config.RetryPeriod.Duration)
+	}
+	return nil
 }
 
 // Run runs the controller-manager. This should never exit.
@@ -95,74 +156,162 @@ func Run(stopCh <-chan struct{}) error {
 	if err != nil {
 		klog.Fatalf("failed to get kubernetes Clientset: %v", err)
 	}
-	genericCli, err := client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	// the dynamic RESTMapper discovers the CRDs installed after the operator starts, e.g. the ServiceMonitor
+	mapper, err := apiutil.NewDynamicRESTMapper(cfg)
+	if err != nil {
+		klog.Fatalf("failed to create the RESTMapper: %v", err)
+	}
+	genericCli, err := client.New(cfg, client.Options{Scheme: scheme.Scheme, Mapper: mapper})
 	if err != nil {
 		klog.Fatalf("failed to get the generic kube-apiserver client: %v", err)
 	}
 
-	var informerFactory informers.SharedInformerFactory
-	var kubeInformerFactory kubeinformers.SharedInformerFactory
-	var options []informers.SharedInformerOption
-	var kubeoptions []kubeinformers.SharedInformerOption
-	informerFactory = informers.NewSharedInformerFactoryWithOptions(cli, controller.ResyncDuration, options...)
-	kubeInformerFactory = kubeinformers.NewSharedInformerFactoryWithOptions(kubeCli, controller.ResyncDuration, kubeoptions...)
-
-	rl := resourcelock.EndpointsLock{
-		EndpointsMeta: metav1.ObjectMeta{
-			Namespace: ns,
-			Name:      "tikv-controller-manager",
-		},
-		Client: kubeCli.CoreV1(),
-		LockConfig: resourcelock.ResourceLockConfig{
-			Identity:      hostName,
-			EventRecorder: &record.FakeRecorder{},
-		},
+	watchNamespaces := parseWatchNamespaces(watchNamespace)
+	if len(watchNamespaces) > 0 {
+		klog.Infof("watching the namespaces %v only", watchNamespaces)
+	} else {
+		// a single set of informers watching all the namespaces
+		watchNamespaces = []string{metav1.NamespaceAll}
 	}
 
 	controllerCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// the leader releases its lease on termination so that another instance takes over without waiting
+	// for the lease to expire
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		select {
+		case sig := <-sigCh:
+			klog.Infof("received signal %s, stopping the controllers", sig)
+		case <-stopCh:
+		}
+		cancel()
+	}()
+
 	onStarted := func(ctx context.Context) {
-		_ = genericCli
-		tcController := tikvcluster.NewController(kubeCli, cli, genericCli, informerFactory, kubeInformerFactory, autoFailover, pdFailoverPeriod, tikvFailoverPeriod)
+		// the informers and the controllers are set up per watched namespace, the listers of the controllers
+		// only see the objects in their namespace. The cluster scoped resources, e.g. the nodes and the
+		// persistent volumes, are watched once by the informers shared by all the controllers
+		clusterInformerFactory := kubeinformers.NewSharedInformerFactory(kubeCli, controller.ResyncDuration)
+		var runs []func()
+		for _, ns := range watchNamespaces {
+			informerFactory := informers.NewSharedInformerFactoryWithOptions(cli, controller.ResyncDuration, informers.WithNamespace(ns))
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeCli, controller.ResyncDuration, kubeinformers.WithNamespace(ns))
 
-		// Start informer factories after all controller are initialized.
-		informerFactory.Start(ctx.Done())
-		kubeInformerFactory.Start(ctx.Done())
+			tcController := tikvcluster.NewController(kubeCli, cli, genericCli, informerFactory, kubeInformerFactory, clusterInformerFactory, autoFailover, pdFailoverPeriod, tikvFailoverPeriod)
+			backupController := backup.NewController(kubeCli, cli, genericCli, informerFactory, kubeInformerFactory)
+			restoreController := backup.NewRestoreController(kubeCli, cli, genericCli, informerFactory, kubeInformerFactory)
 
-		// Wait for all started informers' cache were synced.
-		for v, synced := range informerFactory.WaitForCacheSync(wait.NeverStop) {
-			if !synced {
-				klog.Fatalf("error syncing informer for %v", v)
+			// Start informer factories after all controller are initialized.
+			informerFactory.Start(ctx.Done())
+			kubeInformerFactory.Start(ctx.Done())
+
+			// Wait for all started informers' cache were synced.
+			for v, synced := range informerFactory.WaitForCacheSync(wait.NeverStop) {
+				if !synced {
+					klog.Fatalf("error syncing informer for %v in namespace %q", v, ns)
+				}
 			}
+			for v, synced := range kubeInformerFactory.WaitForCacheSync(wait.NeverStop) {
+				if !synced {
+					klog.Fatalf("error syncing informer for %v in namespace %q", v, ns)
+				}
+			}
+			klog.Infof("cache of informer factories of namespace %q sync successfully", ns)
+
+			runs = append(runs,
+				func() { backupController.Run(workers, ctx.Done()) },
+				func() { restoreController.Run(workers, ctx.Done()) },
+				func() { tcController.Run(workers, ctx.Done()) },
+			)
 		}
-		for v, synced := range kubeInformerFactory.WaitForCacheSync(wait.NeverStop) {
+
+		clusterInformerFactory.Start(ctx.Done())
+		for v, synced := range clusterInformerFactory.WaitForCacheSync(wait.NeverStop) {
 			if !synced {
 				klog.Fatalf("error syncing informer for %v", v)
 			}
 		}
-		klog.Infof("cache of informer factories sync successfully")
+		klog.Infof("cache of the cluster scoped informer factory sync successfully")
 
-		wait.Forever(func() { tcController.Run(workers, ctx.Done()) }, waitDuration)
+		for _, run := range runs {
+			go wait.Forever(run, waitDuration)
+		}
+		<-ctx.Done()
 	}
 
 	onStopped := func() {
+		if controllerCtx.Err() != nil {
+			klog.Infof("released the leadership, exiting")
+			os.Exit(0)
+		}
 		klog.Fatalf("leader election lost")
 	}
 
-	// leader election for multiple tikv-controller-manager instances
-	go wait.Forever(func() {
-		leaderelection.RunOrDie(controllerCtx, leaderelection.LeaderElectionConfig{
-			Lock:          &rl,
-			LeaseDuration: leaseDuration,
-			RenewDeadline: renewDuration,
-			RetryPeriod:   retryPeriod,
-			Callbacks: leaderelection.LeaderCallbacks{
-				OnStartedLeading: onStarted,
-				OnStoppedLeading: onStopped,
-			},
-		})
-	}, waitDuration)
+	if leaderElection.LeaderElect {
+		if err := validateLeaderElection(leaderElection); err != nil {
+			klog.Fatal(err)
+		}
+		lockNamespace := leaderElection.ResourceNamespace
+		if lockNamespace == "" {
+			lockNamespace = ns
+		}
+		rl, err := resourcelock.New(leaderElection.ResourceLock, lockNamespace, leaderElection.ResourceName,
+			kubeCli.CoreV1(), kubeCli.CoordinationV1(), resourcelock.ResourceLockConfig{
+				Identity:      hostName,
+				EventRecorder: &record.FakeRecorder{},
+			})
+		if err != nil {
+			klog.Fatalf("failed to create the resource lock of the leader election: %v", err)
+		}
+		klog.Infof("electing the leader through %s %s/%s", leaderElection.ResourceLock, lockNamespace, leaderElection.ResourceName)
+
+		// leader election for multiple tikv-controller-manager instances
+		go wait.Forever(func() {
+			leaderelection.RunOrDie(controllerCtx, leaderelection.LeaderElectionConfig{
+				Lock:            rl,
+				LeaseDuration:   leaderElection.LeaseDuration.Duration,
+				RenewDeadline:   leaderElection.RenewDeadline.Duration,
+				RetryPeriod:     leaderElection.RetryPeriod.Duration,
+				ReleaseOnCancel: true,
+				Callbacks: leaderelection.LeaderCallbacks{
+					OnStartedLeading: onStarted,
+					OnStoppedLeading: onStopped,
+				},
+			})
+		}, waitDuration)
+	} else {
+		klog.Warningf("leader election is disabled, make sure only one instance of the operator is running")
+		go func() {
+			onStarted(controllerCtx)
+		}()
+		go func() {
+			<-controllerCtx.Done()
+			klog.Infof("controllers stopped, exiting")
+			os.Exit(0)
+		}()
+	}
+
+	// the admission webhooks are served by all the instances regardless of the leader election
+	if webhookPort > 0 {
+		go func() {
+			if err := webhook.StartServer(webhookPort, webhookCertDir, kubeCli, cli, stopCh); err != nil {
+				klog.Fatalf("failed to start the admission webhooks: %v", err)
+			}
+		}()
+	}
+
+	// the metrics are served by all the instances, only the leader reports the reconcile and TiKV store metrics
+	if metricsPort > 0 {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			klog.Infof("starting metrics server, listening on :%d", metricsPort)
+			klog.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", metricsPort), mux))
+		}()
+	}
 
 	healthz.InstallHandler(http.DefaultServeMux)
 	klog.Fatal(http.ListenAndServe(":6060", nil))
@@ -186,6 +335,7 @@ func NewControllerManagerCommand() *cobra.Command {
 	}
 
 	initFlags(namedFlagSets.FlagSet("generic"))
+	initLeaderElectionFlags(namedFlagSets.FlagSet("leader election"))
 	verflag.AddFlags(namedFlagSets.FlagSet("global"))
 	globalflag.AddGlobalFlags(namedFlagSets.FlagSet("global"), cmd.Name())
 	for _, f := range namedFlagSets.FlagSets {
@@ -0,0 +1,78 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultBRBaseImage             = "pingcap/br"
+	defaultPauseSchedulingDuration = 10 * time.Minute
+)
+
+// IsFinished returns whether the backup is completed or failed, a finished backup is never retried
+func (b *TikvBackup) IsFinished() bool {
+	return b.Status.Phase == BackupComplete || b.Status.Phase == BackupFailed
+}
+
+// BRImage returns the image of the BR job, the version of the TiKV is used as the tag
+func (b *TikvBackup) BRImage(tc *TikvCluster) string {
+	return brImage(b.Spec.BR, tc)
+}
+
+func brImage(br *BRConfig, tc *TikvCluster) string {
+	baseImage := defaultBRBaseImage
+	if br != nil && br.BaseImage != "" {
+		baseImage = br.BaseImage
+	}
+	return baseImage + ":" + tc.TiKVVersion()
+}
+
+// PauseSchedulingDuration returns the longest time the PD schedulers are paused while the volume snapshots are taken
+func (b *TikvBackup) PauseSchedulingDuration() time.Duration {
+	if b.Spec.VolumeSnapshot == nil || b.Spec.VolumeSnapshot.PauseSchedulingSeconds == nil {
+		return defaultPauseSchedulingDuration
+	}
+	return time.Duration(*b.Spec.VolumeSnapshot.PauseSchedulingSeconds) * time.Second
+}
+
+// GetBackupCondition returns the condition of the given type, or nil if it is absent
+func GetBackupCondition(status *TikvBackupStatus, condType TikvBackupConditionType) *TikvBackupCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// UpdateBackupCondition sets the condition of the backup, the transition time is only
+// updated when the status of the condition changes
+func UpdateBackupCondition(status *TikvBackupStatus, condition TikvBackupCondition) {
+	current := GetBackupCondition(status, condition.Type)
+	if current == nil {
+		condition.LastTransitionTime = metav1.Now()
+		status.Conditions = append(status.Conditions, condition)
+		return
+	}
+	if current.Status != condition.Status {
+		current.LastTransitionTime = metav1.Now()
+	}
+	current.Status = condition.Status
+	current.Reason = condition.Reason
+	current.Message = condition.Message
+}
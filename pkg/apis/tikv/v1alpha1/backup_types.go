@@ -0,0 +1,243 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.cluster`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Path",type=string,JSONPath=`.status.backupPath`
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +k8s:openapi-gen=true
+// TikvBackup is a backup of a tikv cluster taken by BR
+type TikvBackup struct {
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec defines the behavior of the backup
+	Spec TikvBackupSpec `json:"spec"`
+
+	// +k8s:openapi-gen=false
+	// +kubebuilder:validation:Optional
+	// Most recently observed status of the backup
+	Status TikvBackupStatus `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +k8s:openapi-gen=true
+// TikvBackupList is TikvBackup list
+type TikvBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ListMeta `json:"metadata"`
+
+	Items []TikvBackup `json:"items"`
+}
+
+// +k8s:openapi-gen=true
+// TikvBackupSpec describes the backup of a tikv cluster
+type TikvBackupSpec struct {
+	// Cluster is the name of the TikvCluster to back up, it must be in the same namespace as the backup
+	// +kubebuilder:validation:MinLength=1
+	Cluster string `json:"cluster"`
+
+	// StorageProvider is the object storage the backup is stored to
+	StorageProvider `json:",inline"`
+
+	// VolumeSnapshot takes the backup with the CSI VolumeSnapshots of the volumes of the TiKV stores
+	// instead of BR, the storage is not used
+	// +optional
+	VolumeSnapshot *VolumeSnapshotConfig `json:"volumeSnapshot,omitempty"`
+
+	// BR configures the BR job taking the backup
+	// +optional
+	BR *BRConfig `json:"br,omitempty"`
+
+	// Resource requirements of the BR job
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// ServiceAccount of the BR job
+	// +optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// StorageProvider defines the object storage of the backups
+type StorageProvider struct {
+	// S3 is the S3 compatible storage
+	// +optional
+	S3 *S3StorageProvider `json:"s3,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// S3StorageProvider defines an S3 compatible bucket to store the backups
+type S3StorageProvider struct {
+	// Provider of the S3 compatible storage, e.g. aws, ceph or minio
+	// +optional
+	Provider string `json:"provider,omitempty"`
+	// Region of the bucket
+	// +optional
+	Region string `json:"region,omitempty"`
+	// Endpoint of the storage, required by the storages other than aws
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// Bucket to store the backups
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+	// Prefix of the path of the backups in the bucket
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// SecretName is the secret with the access_key and secret_key of the storage,
+	// the IAM role of the job is used if it is not specified
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// BRConfig configures the BR job
+type BRConfig struct {
+	// Base image of BR, the version of the cluster is used as the tag
+	// Optional: Defaults to pingcap/br
+	// +optional
+	BaseImage string `json:"baseImage,omitempty"`
+	// Concurrency is the number of the concurrent tasks on each TiKV
+	// +optional
+	Concurrency *uint32 `json:"concurrency,omitempty"`
+	// RateLimit is the rate limit of each TiKV in MiB/s
+	// +optional
+	RateLimit *uint32 `json:"rateLimit,omitempty"`
+	// Checksum verifies the backup after it is taken
+	// Optional: Defaults to true
+	// +optional
+	Checksum *bool `json:"checksum,omitempty"`
+	// Options are the extra command line arguments of BR
+	// +optional
+	Options []string `json:"options,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// VolumeSnapshotConfig configures the backups taken with the CSI VolumeSnapshots
+type VolumeSnapshotConfig struct {
+	// VolumeSnapshotClassName is the VolumeSnapshotClass of the snapshots
+	// Optional: Defaults to the default VolumeSnapshotClass of the CSI driver
+	// +optional
+	VolumeSnapshotClassName *string `json:"volumeSnapshotClassName,omitempty"`
+	// PauseSchedulingSeconds is the longest time the PD balance schedulers are paused to keep the regions
+	// from moving between the stores while the snapshots are taken, the schedulers are resumed
+	// as soon as all snapshots are taken
+	// Optional: Defaults to 600
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PauseSchedulingSeconds *int32 `json:"pauseSchedulingSeconds,omitempty"`
+}
+
+// BackupPhase is the phase of a backup
+type BackupPhase string
+
+const (
+	// BackupPending means the backup is waiting for the cluster to be available
+	BackupPending BackupPhase = "Pending"
+	// BackupRunning means the BR job is running
+	BackupRunning BackupPhase = "Running"
+	// BackupComplete means the backup is taken successfully
+	BackupComplete BackupPhase = "Complete"
+	// BackupFailed means the backup failed and will not be retried
+	BackupFailed BackupPhase = "Failed"
+)
+
+// TikvBackupStatus represents the current status of a backup
+type TikvBackupStatus struct {
+	// Phase of the backup
+	// +optional
+	Phase BackupPhase `json:"phase,omitempty"`
+	// BackupPath is the location of the backup in the storage
+	// +optional
+	BackupPath string `json:"backupPath,omitempty"`
+	// TimeStarted is the time the BR job was created
+	// +optional
+	TimeStarted *metav1.Time `json:"timeStarted,omitempty"`
+	// TimeCompleted is the time the backup completed or failed
+	// +optional
+	TimeCompleted *metav1.Time `json:"timeCompleted,omitempty"`
+	// VolumeSnapshots are the snapshots of the volumes of the TiKV stores taken by the backup
+	// +optional
+	VolumeSnapshots []TiKVVolumeSnapshot `json:"volumeSnapshots,omitempty"`
+	// PausedSchedulers are the PD schedulers paused by the backup while the volume snapshots are taken,
+	// the schedulers already paused before the backup are not resumed by it
+	// +optional
+	PausedSchedulers []string `json:"pausedSchedulers,omitempty"`
+	// Represents the latest available observations of the backup's state.
+	// +optional
+	Conditions []TikvBackupCondition `json:"conditions,omitempty"`
+}
+
+// TiKVVolumeSnapshot is the CSI VolumeSnapshot of the volume of a TiKV store
+type TiKVVolumeSnapshot struct {
+	// StoreID is the id of the TiKV store the volume belongs to
+	// +optional
+	StoreID string `json:"storeID,omitempty"`
+	// PVCName is the name of the PersistentVolumeClaim of the volume
+	PVCName string `json:"pvcName"`
+	// Name of the VolumeSnapshot
+	Name string `json:"name"`
+	// VolumeSnapshotContentName is the name of the VolumeSnapshotContent bound to the snapshot
+	// +optional
+	VolumeSnapshotContentName string `json:"volumeSnapshotContentName,omitempty"`
+	// SnapshotHandle is the id of the snapshot in the storage system
+	// +optional
+	SnapshotHandle string `json:"snapshotHandle,omitempty"`
+	// ReadyToUse indicates whether the snapshot is ready to provision a volume from
+	// +optional
+	ReadyToUse bool `json:"readyToUse,omitempty"`
+}
+
+// TikvBackupCondition describes the state of a backup at a certain point.
+type TikvBackupCondition struct {
+	// Type of the condition.
+	Type TikvBackupConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// TikvBackupConditionType represents a backup condition value.
+type TikvBackupConditionType string
+
+const (
+	// TikvBackupScheduled indicates whether the BR job of the backup is created
+	TikvBackupScheduled TikvBackupConditionType = "Scheduled"
+	// TikvBackupComplete indicates whether the backup is completed
+	TikvBackupComplete TikvBackupConditionType = "Complete"
+	// TikvBackupFailed indicates whether the backup failed
+	TikvBackupFailed TikvBackupConditionType = "Failed"
+)
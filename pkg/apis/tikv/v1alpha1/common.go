@@ -3,3 +3,28 @@ package v1alpha1
 // SecurityProtocol is the protocol used to communicate with brokers.
 // Valid values are: plaintext, ssl, sasl_plaintext, sasl_ssl.
 type SecurityProtocol string
+
+const (
+	// SecurityProtocolPlaintext is the plaintext protocol
+	SecurityProtocolPlaintext SecurityProtocol = "plaintext"
+	// SecurityProtocolSSL is the ssl protocol
+	SecurityProtocolSSL SecurityProtocol = "ssl"
+	// SecurityProtocolSaslPlaintext is the sasl_plaintext protocol
+	SecurityProtocolSaslPlaintext SecurityProtocol = "sasl_plaintext"
+	// SecurityProtocolSaslSSL is the sasl_ssl protocol
+	SecurityProtocolSaslSSL SecurityProtocol = "sasl_ssl"
+)
+
+// IsSSL returns whether the protocol requires tls certificates
+func (p SecurityProtocol) IsSSL() bool {
+	return p == SecurityProtocolSSL || p == SecurityProtocolSaslSSL
+}
+
+// IsValid returns whether the protocol is one of the valid values
+func (p SecurityProtocol) IsValid() bool {
+	switch p {
+	case SecurityProtocolPlaintext, SecurityProtocolSSL, SecurityProtocolSaslPlaintext, SecurityProtocolSaslSSL:
+		return true
+	}
+	return false
+}
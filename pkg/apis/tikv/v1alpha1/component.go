@@ -14,11 +14,14 @@
 package v1alpha1
 
 import (
+	"github.com/tikv/tikv-operator/pkg/label"
 	corev1 "k8s.io/api/core/v1"
 )
 
 const (
 	defaultHostNetwork = false
+	// topologySpreadWeight is the weight of the preferred pod anti-affinity expanded from the topology key
+	topologySpreadWeight = 100
 )
 
 // +kubebuilder:object:generate=false
@@ -28,16 +31,21 @@ type ComponentAccessor interface {
 	ImagePullPolicy() corev1.PullPolicy
 	HostNetwork() bool
 	Affinity() *corev1.Affinity
+	TopologyKey() string
+	TopologyAffinityPolicy() TopologyAffinityPolicy
 	PriorityClassName() *string
 	NodeSelector() map[string]string
 	Annotations() map[string]string
+	Labels() map[string]string
 	Tolerations() []corev1.Toleration
 	PodSecurityContext() *corev1.PodSecurityContext
+	PrometheusAnnotations() *PrometheusAnnotations
 	SchedulerName() string
 	DnsPolicy() corev1.DNSPolicy
 	ConfigUpdateStrategy() ConfigUpdateStrategy
 	BuildPodSpec() corev1.PodSpec
 	Env() []corev1.EnvVar
+	EnvFrom() []corev1.EnvFromSource
 }
 
 type componentAccessorImpl struct {
@@ -46,10 +54,26 @@ type componentAccessorImpl struct {
 
 	// Cluster is the Component Spec
 	ComponentSpec *ComponentSpec
+
+	// instanceName and component select the pods of the component, which are spread by the topology key
+	instanceName string
+	component    string
 }
 
 func (a *componentAccessorImpl) PodSecurityContext() *corev1.PodSecurityContext {
-	return a.ComponentSpec.PodSecurityContext
+	psc := a.ComponentSpec.PodSecurityContext
+	if psc == nil {
+		psc = a.ClusterSpec.PodSecurityContext
+	}
+	return psc
+}
+
+func (a *componentAccessorImpl) PrometheusAnnotations() *PrometheusAnnotations {
+	pa := a.ComponentSpec.PrometheusAnnotations
+	if pa == nil {
+		pa = a.ClusterSpec.PrometheusAnnotations
+	}
+	return pa
 }
 
 func (a *componentAccessorImpl) ImagePullPolicy() corev1.PullPolicy {
@@ -79,6 +103,54 @@ func (a *componentAccessorImpl) Affinity() *corev1.Affinity {
 	return affi
 }
 
+func (a *componentAccessorImpl) TopologyKey() string {
+	if a.ComponentSpec.TopologyKey != nil {
+		return *a.ComponentSpec.TopologyKey
+	}
+	return a.ClusterSpec.TopologyKey
+}
+
+func (a *componentAccessorImpl) TopologyAffinityPolicy() TopologyAffinityPolicy {
+	policy := a.ClusterSpec.TopologyAffinityPolicy
+	if a.ComponentSpec.TopologyAffinityPolicy != nil {
+		policy = *a.ComponentSpec.TopologyAffinityPolicy
+	}
+	if policy == "" {
+		return TopologyAffinityPolicyPreferred
+	}
+	return policy
+}
+
+// topologySpreadAffinity returns the affinity of the component with the pod anti-affinity expanded from the
+// topology key added, which spreads the pods of the component across the values of the topology key
+func (a *componentAccessorImpl) topologySpreadAffinity() *corev1.Affinity {
+	affinity := a.Affinity()
+	key := a.TopologyKey()
+	if key == "" {
+		return affinity
+	}
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	} else {
+		affinity = affinity.DeepCopy()
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	term := corev1.PodAffinityTerm{
+		LabelSelector: label.New().Instance(a.instanceName).Component(a.component).LabelSelector(),
+		TopologyKey:   key,
+	}
+	antiAffinity := affinity.PodAntiAffinity
+	if a.TopologyAffinityPolicy() == TopologyAffinityPolicyRequired {
+		antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+	} else {
+		antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			corev1.WeightedPodAffinityTerm{Weight: topologySpreadWeight, PodAffinityTerm: term})
+	}
+	return affinity
+}
+
 func (a *componentAccessorImpl) PriorityClassName() *string {
 	pcn := a.ComponentSpec.PriorityClassName
 	if pcn == nil {
@@ -117,6 +189,17 @@ func (a *componentAccessorImpl) Annotations() map[string]string {
 	return anno
 }
 
+func (a *componentAccessorImpl) Labels() map[string]string {
+	labels := map[string]string{}
+	for k, v := range a.ClusterSpec.Labels {
+		labels[k] = v
+	}
+	for k, v := range a.ComponentSpec.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
 func (a *componentAccessorImpl) Tolerations() []corev1.Toleration {
 	tols := a.ComponentSpec.Tolerations
 	if len(tols) == 0 {
@@ -134,22 +217,16 @@ func (a *componentAccessorImpl) DnsPolicy() corev1.DNSPolicy {
 }
 
 func (a *componentAccessorImpl) ConfigUpdateStrategy() ConfigUpdateStrategy {
-	strategy := a.ComponentSpec.ConfigUpdateStrategy
-	if strategy == nil {
-		strategy = &a.ClusterSpec.ConfigUpdateStrategy
-	}
-	// defaulting logic will set a default value for configUpdateStrategy field, but if the
-	// object is created in early version without this field being set, we should set a safe default
-	if string(*strategy) == "" {
-		return ConfigUpdateStrategyInPlace
+	if a.ComponentSpec.ConfigUpdateStrategy != nil {
+		return *a.ComponentSpec.ConfigUpdateStrategy
 	}
-	return *strategy
+	return a.ClusterSpec.ConfigUpdateStrategy
 }
 
 func (a *componentAccessorImpl) BuildPodSpec() corev1.PodSpec {
 	spec := corev1.PodSpec{
 		SchedulerName:   a.SchedulerName(),
-		Affinity:        a.Affinity(),
+		Affinity:        a.topologySpreadAffinity(),
 		NodeSelector:    a.NodeSelector(),
 		HostNetwork:     a.HostNetwork(),
 		RestartPolicy:   corev1.RestartPolicyAlways,
@@ -166,12 +243,21 @@ func (a *componentAccessorImpl) Env() []corev1.EnvVar {
 	return a.ComponentSpec.Env
 }
 
+func (a *componentAccessorImpl) EnvFrom() []corev1.EnvFromSource {
+	if len(a.ClusterSpec.EnvFrom) == 0 {
+		return a.ComponentSpec.EnvFrom
+	}
+	envFrom := make([]corev1.EnvFromSource, 0, len(a.ClusterSpec.EnvFrom)+len(a.ComponentSpec.EnvFrom))
+	envFrom = append(envFrom, a.ClusterSpec.EnvFrom...)
+	return append(envFrom, a.ComponentSpec.EnvFrom...)
+}
+
 // BaseTiKVSpec returns the base spec of TiKV servers
 func (tc *TikvCluster) BaseTiKVSpec() ComponentAccessor {
-	return &componentAccessorImpl{&tc.Spec, &tc.Spec.TiKV.ComponentSpec}
+	return &componentAccessorImpl{&tc.Spec, &tc.Spec.TiKV.ComponentSpec, tc.GetInstanceName(), label.TiKVLabelVal}
 }
 
 // BasePDSpec returns the base spec of PD servers
 func (tc *TikvCluster) BasePDSpec() ComponentAccessor {
-	return &componentAccessorImpl{&tc.Spec, &tc.Spec.PD.ComponentSpec}
+	return &componentAccessorImpl{&tc.Spec, &tc.Spec.PD.ComponentSpec, tc.GetInstanceName(), label.PDLabelVal}
 }
@@ -0,0 +1,224 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestBuildPodSpecTopologySpread(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	required := TopologyAffinityPolicyRequired
+	nodeAffinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key:      "dedicated",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{"tikv"},
+					}},
+				}},
+			},
+		},
+	}
+	tests := []struct {
+		name   string
+		update func(tc *TikvCluster)
+		expect func(affinity *corev1.Affinity)
+	}{
+		{
+			name:   "no topology key",
+			update: func(tc *TikvCluster) {},
+			expect: func(affinity *corev1.Affinity) {
+				g.Expect(affinity).To(BeNil())
+			},
+		},
+		{
+			name: "cluster-level topology key",
+			update: func(tc *TikvCluster) {
+				tc.Spec.TopologyKey = "topology.kubernetes.io/zone"
+			},
+			expect: func(affinity *corev1.Affinity) {
+				terms := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+				g.Expect(terms).To(HaveLen(1))
+				g.Expect(terms[0].Weight).To(Equal(int32(topologySpreadWeight)))
+				g.Expect(terms[0].PodAffinityTerm.TopologyKey).To(Equal("topology.kubernetes.io/zone"))
+				g.Expect(terms[0].PodAffinityTerm.LabelSelector).To(Equal(label.New().Instance("demo").TiKV().LabelSelector()))
+				g.Expect(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(BeEmpty())
+			},
+		},
+		{
+			name: "component-level required topology key merged into the affinity",
+			update: func(tc *TikvCluster) {
+				tc.Spec.TopologyKey = "topology.kubernetes.io/zone"
+				tc.Spec.TiKV.TopologyKey = pointer.StringPtr("kubernetes.io/hostname")
+				tc.Spec.TiKV.TopologyAffinityPolicy = &required
+				tc.Spec.TiKV.Affinity = nodeAffinity
+			},
+			expect: func(affinity *corev1.Affinity) {
+				g.Expect(affinity.NodeAffinity).To(Equal(nodeAffinity.NodeAffinity))
+				terms := affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+				g.Expect(terms).To(HaveLen(1))
+				g.Expect(terms[0].TopologyKey).To(Equal("kubernetes.io/hostname"))
+				g.Expect(affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(BeEmpty())
+				// the affinity of the spec is not modified
+				g.Expect(nodeAffinity.PodAntiAffinity).To(BeNil())
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TikvCluster{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"}}
+			tt.update(tc)
+			tt.expect(tc.BaseTiKVSpec().BuildPodSpec().Affinity)
+		})
+	}
+}
+
+func TestPodSecurityContext(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	clusterContext := &corev1.PodSecurityContext{RunAsUser: pointer.Int64Ptr(1000)}
+	componentContext := &corev1.PodSecurityContext{RunAsUser: pointer.Int64Ptr(2000)}
+	tests := []struct {
+		name   string
+		update func(tc *TikvCluster)
+		expect *corev1.PodSecurityContext
+	}{
+		{
+			name:   "not set",
+			update: func(tc *TikvCluster) {},
+			expect: nil,
+		},
+		{
+			name: "fall back to the cluster-level setting",
+			update: func(tc *TikvCluster) {
+				tc.Spec.PodSecurityContext = clusterContext
+			},
+			expect: clusterContext,
+		},
+		{
+			name: "component-level setting overrides the cluster-level one",
+			update: func(tc *TikvCluster) {
+				tc.Spec.PodSecurityContext = clusterContext
+				tc.Spec.TiKV.PodSecurityContext = componentContext
+			},
+			expect: componentContext,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TikvCluster{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"}}
+			tt.update(tc)
+			g.Expect(tc.BaseTiKVSpec().PodSecurityContext()).To(Equal(tt.expect))
+			g.Expect(tc.BaseTiKVSpec().BuildPodSpec().SecurityContext).To(Equal(tt.expect))
+		})
+	}
+}
+
+func TestSchedulerName(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		name   string
+		update func(tc *TikvCluster)
+		expect string
+	}{
+		{
+			name:   "not set",
+			update: func(tc *TikvCluster) {},
+			expect: "",
+		},
+		{
+			name: "fall back to the cluster-level setting",
+			update: func(tc *TikvCluster) {
+				tc.Spec.SchedulerName = "tikv-scheduler"
+			},
+			expect: "tikv-scheduler",
+		},
+		{
+			name: "component-level setting overrides the cluster-level one",
+			update: func(tc *TikvCluster) {
+				tc.Spec.SchedulerName = "tikv-scheduler"
+				tc.Spec.TiKV.SchedulerName = pointer.StringPtr("custom-scheduler")
+			},
+			expect: "custom-scheduler",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TikvCluster{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"}}
+			tt.update(tc)
+			g.Expect(tc.BaseTiKVSpec().SchedulerName()).To(Equal(tt.expect))
+			g.Expect(tc.BaseTiKVSpec().BuildPodSpec().SchedulerName).To(Equal(tt.expect))
+			g.Expect(tc.BasePDSpec().SchedulerName()).To(Equal(tc.Spec.SchedulerName))
+		})
+	}
+}
+
+func TestEnvFrom(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	clusterSource := corev1.EnvFromSource{
+		ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "cluster-env"}},
+	}
+	tikvSource := corev1.EnvFromSource{
+		Prefix:    "TIKV_",
+		SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "tikv-env"}},
+	}
+	tests := []struct {
+		name   string
+		update func(tc *TikvCluster)
+		expect []corev1.EnvFromSource
+	}{
+		{
+			name:   "not set",
+			update: func(tc *TikvCluster) {},
+			expect: nil,
+		},
+		{
+			name: "cluster-level sources only",
+			update: func(tc *TikvCluster) {
+				tc.Spec.EnvFrom = []corev1.EnvFromSource{clusterSource}
+			},
+			expect: []corev1.EnvFromSource{clusterSource},
+		},
+		{
+			name: "component-level sources appended to the cluster-level ones",
+			update: func(tc *TikvCluster) {
+				tc.Spec.EnvFrom = []corev1.EnvFromSource{clusterSource}
+				tc.Spec.TiKV.EnvFrom = []corev1.EnvFromSource{tikvSource}
+			},
+			expect: []corev1.EnvFromSource{clusterSource, tikvSource},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TikvCluster{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"}}
+			tt.update(tc)
+			g.Expect(tc.BaseTiKVSpec().EnvFrom()).To(Equal(tt.expect))
+			g.Expect(tc.BasePDSpec().EnvFrom()).To(Equal(tc.Spec.EnvFrom))
+			// the cluster-level sources are not modified by the merge
+			g.Expect(len(tc.Spec.EnvFrom)).To(BeNumerically("<=", 1))
+		})
+	}
+}
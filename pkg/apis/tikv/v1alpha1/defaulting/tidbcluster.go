@@ -16,15 +16,23 @@ package defaulting
 import (
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/utils/pointer"
 )
 
 const (
-	defaultTiKVImage = "pingcap/tikv"
-	defaultPDImage   = "pingcap/pd"
+	defaultTiKVImage        = "pingcap/tikv"
+	defaultPDImage          = "pingcap/pd"
+	defaultVersion          = "latest"
+	defaultMaxFailoverCount = 3
+	defaultTiKVStorage      = "10Gi"
+	defaultPDStorage        = "1Gi"
 )
 
-func SetTikvClusterDefault(tc *v1alpha1.TikvCluster) {
+// SetDefaults_TikvCluster sets the defaults of all the omitted fields of a TikvCluster, it is applied by the
+// mutating admission webhook and at the beginning of each reconcile, so the controller can assume that the
+// object is fully defaulted
+func SetDefaults_TikvCluster(tc *v1alpha1.TikvCluster) {
 	setTikvClusterSpecDefault(tc)
 	setPdSpecDefault(tc)
 	setTikvSpecDefault(tc)
@@ -35,6 +43,13 @@ func setTikvClusterSpecDefault(tc *v1alpha1.TikvCluster) {
 	if string(tc.Spec.ImagePullPolicy) == "" {
 		tc.Spec.ImagePullPolicy = corev1.PullIfNotPresent
 	}
+	if string(tc.Spec.ConfigUpdateStrategy) == "" {
+		tc.Spec.ConfigUpdateStrategy = v1alpha1.ConfigUpdateStrategyInPlace
+	}
+	// the legacy image fields carry their own tags, only default the version when none of them is used
+	if tc.Spec.Version == "" && tc.Spec.PD.Image == "" && tc.Spec.TiKV.Image == "" {
+		tc.Spec.Version = defaultVersion
+	}
 }
 
 func setTikvSpecDefault(tc *v1alpha1.TikvCluster) {
@@ -44,8 +59,16 @@ func setTikvSpecDefault(tc *v1alpha1.TikvCluster) {
 		}
 	}
 	if tc.Spec.TiKV.MaxFailoverCount == nil {
-		tc.Spec.TiKV.MaxFailoverCount = pointer.Int32Ptr(3)
+		tc.Spec.TiKV.MaxFailoverCount = pointer.Int32Ptr(defaultMaxFailoverCount)
+	}
+	// the replicas are derived from the zones
+	if len(tc.Spec.TiKV.Zones) > 0 {
+		tc.Spec.TiKV.Replicas = 0
+		for _, zone := range tc.Spec.TiKV.Zones {
+			tc.Spec.TiKV.Replicas += zone.Replicas
+		}
 	}
+	setRequestsStorageDefault(&tc.Spec.TiKV.ResourceRequirements, defaultTiKVStorage)
 }
 
 func setPdSpecDefault(tc *v1alpha1.TikvCluster) {
@@ -55,6 +78,17 @@ func setPdSpecDefault(tc *v1alpha1.TikvCluster) {
 		}
 	}
 	if tc.Spec.PD.MaxFailoverCount == nil {
-		tc.Spec.PD.MaxFailoverCount = pointer.Int32Ptr(3)
+		tc.Spec.PD.MaxFailoverCount = pointer.Int32Ptr(defaultMaxFailoverCount)
+	}
+	setRequestsStorageDefault(&tc.Spec.PD.ResourceRequirements, defaultPDStorage)
+}
+
+func setRequestsStorageDefault(requirements *corev1.ResourceRequirements, storage string) {
+	if _, ok := requirements.Requests[corev1.ResourceStorage]; ok {
+		return
+	}
+	if requirements.Requests == nil {
+		requirements.Requests = corev1.ResourceList{}
 	}
+	requirements.Requests[corev1.ResourceStorage] = resource.MustParse(storage)
 }
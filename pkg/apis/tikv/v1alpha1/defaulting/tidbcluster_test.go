@@ -0,0 +1,93 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package defaulting
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/pointer"
+)
+
+func TestSetDefaultsTikvCluster(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name   string
+		update func(tc *v1alpha1.TikvCluster)
+		expect func(tc *v1alpha1.TikvCluster)
+	}{
+		{
+			name: "empty cluster",
+			expect: func(tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Spec.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+				g.Expect(tc.Spec.ConfigUpdateStrategy).To(Equal(v1alpha1.ConfigUpdateStrategyInPlace))
+				g.Expect(tc.PDImage()).To(Equal("pingcap/pd:latest"))
+				g.Expect(tc.TiKVImage()).To(Equal("pingcap/tikv:latest"))
+				g.Expect(*tc.Spec.PD.MaxFailoverCount).To(Equal(int32(3)))
+				g.Expect(*tc.Spec.TiKV.MaxFailoverCount).To(Equal(int32(3)))
+				g.Expect(requestsStorage(tc.Spec.PD.Requests)).To(Equal("1Gi"))
+				g.Expect(requestsStorage(tc.Spec.TiKV.Requests)).To(Equal("10Gi"))
+			},
+		},
+		{
+			name: "specified fields are kept",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.Version = "v4.0.0"
+				tc.Spec.ConfigUpdateStrategy = v1alpha1.ConfigUpdateStrategyRollingUpdate
+				tc.Spec.TiKV.BaseImage = "tikv/tikv"
+				tc.Spec.TiKV.MaxFailoverCount = pointer.Int32Ptr(0)
+				tc.Spec.TiKV.Requests = corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("100Gi"),
+				}
+			},
+			expect: func(tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Spec.ConfigUpdateStrategy).To(Equal(v1alpha1.ConfigUpdateStrategyRollingUpdate))
+				g.Expect(tc.PDImage()).To(Equal("pingcap/pd:v4.0.0"))
+				g.Expect(tc.TiKVImage()).To(Equal("tikv/tikv:v4.0.0"))
+				g.Expect(*tc.Spec.TiKV.MaxFailoverCount).To(Equal(int32(0)))
+				g.Expect(requestsStorage(tc.Spec.TiKV.Requests)).To(Equal("100Gi"))
+			},
+		},
+		{
+			name: "legacy image fields",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.PD.Image = "pingcap/pd:v3.1.0"
+				tc.Spec.TiKV.Image = "pingcap/tikv:v3.1.0"
+			},
+			expect: func(tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Spec.Version).To(BeEmpty())
+				g.Expect(tc.PDImage()).To(Equal("pingcap/pd:v3.1.0"))
+				g.Expect(tc.TiKVImage()).To(Equal("pingcap/tikv:v3.1.0"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TikvCluster{}
+			if tt.update != nil {
+				tt.update(tc)
+			}
+			SetDefaults_TikvCluster(tc)
+			tt.expect(tc)
+		})
+	}
+}
+
+func requestsStorage(requests corev1.ResourceList) string {
+	storage := requests[corev1.ResourceStorage]
+	return storage.String()
+}
@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	"github.com/tikv/tikv-operator/pkg/label"
@@ -27,6 +28,16 @@ import (
 const (
 	defaultHelperImage = "busybox:1.26.2"
 	defaultTimeZone    = "UTC"
+
+	defaultStaleHeartbeatThreshold = time.Minute
+	defaultTiKVStartScriptPath     = "/usr/local/bin/tikv_start_script.sh"
+
+	defaultRebalanceRegionScheduleLimit = 4096
+	defaultRebalanceWindow              = 30 * time.Minute
+
+	defaultTombstoneRetention = 24 * time.Hour
+
+	defaultMaxReplicas = 3
 )
 
 func (tc *TikvCluster) PDImage() string {
@@ -44,13 +55,7 @@ func (tc *TikvCluster) PDImage() string {
 }
 
 func (tc *TikvCluster) PDVersion() string {
-	image := tc.PDImage()
-	colonIdx := strings.LastIndexByte(image, ':')
-	if colonIdx >= 0 {
-		return image[colonIdx+1:]
-	}
-
-	return "latest"
+	return imageVersion(tc.PDImage())
 }
 
 func (tc *TikvCluster) TiKVImage() string {
@@ -67,14 +72,46 @@ func (tc *TikvCluster) TiKVImage() string {
 	return image
 }
 
+func (tc *TikvCluster) TiKVVersion() string {
+	return imageVersion(tc.TiKVImage())
+}
+
+// TiKVRunningVersion returns the version of the tikv image running, it is empty if the image is not known yet
+func (tc *TikvCluster) TiKVRunningVersion() string {
+	if tc.Status.TiKV.Image == "" {
+		return ""
+	}
+	return imageVersion(tc.Status.TiKV.Image)
+}
+
+// imageVersion returns the tag of the image as its version
+func imageVersion(image string) string {
+	colonIdx := strings.LastIndexByte(image, ':')
+	if colonIdx >= 0 {
+		return image[colonIdx+1:]
+	}
+
+	return "latest"
+}
+
 func (tc *TikvCluster) GetInstanceName() string {
 	return tc.Name
 }
 
 func (tc *TikvCluster) IsTLSClusterEnabled() bool {
-	return false
+	return tc.Spec.TLSCluster != nil && tc.Spec.TLSCluster.Enabled
+}
+
+// ClusterClientTLSSecretName returns the name of the secret with the client certificates the operator connects
+// to the cluster with, the secret of the cluster certificates is used if it is specified
+func (tc *TikvCluster) ClusterClientTLSSecretName() string {
+	if tc.Spec.TLSCluster != nil && tc.Spec.TLSCluster.SecretName != "" {
+		return tc.Spec.TLSCluster.SecretName
+	}
+	return fmt.Sprintf("%s-cluster-client-secret", tc.GetName())
 }
 
+// Timezone returns the time zone of the pods, which is set as the TZ env of the containers
 func (tc *TikvCluster) Timezone() string {
 	tz := tc.Spec.Timezone
 	if tz == "" {
@@ -151,8 +188,38 @@ func (tc *TikvCluster) TiKVAllStoresReady() bool {
 	return true
 }
 
+// TiKVStaleHeartbeatThreshold returns the duration since the last heartbeat after which a store Up is stale
+func (tc *TikvCluster) TiKVStaleHeartbeatThreshold() time.Duration {
+	if tc.Spec.TiKV.StaleHeartbeatThreshold != nil {
+		return tc.Spec.TiKV.StaleHeartbeatThreshold.Duration
+	}
+	return defaultStaleHeartbeatThreshold
+}
+
+// TiKVAnyStoreStale returns whether any store is stale
+func (tc *TikvCluster) TiKVAnyStoreStale() bool {
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.Stale {
+			return true
+		}
+	}
+	return false
+}
+
+// TiKVFailoverReplicas returns the number of the extra pods created to replace the failure stores,
+// which never exceeds the max failover count
+func (tc *TikvCluster) TiKVFailoverReplicas() int32 {
+	replicas := int32(len(tc.Status.TiKV.FailureStores))
+	if max := tc.Spec.TiKV.MaxFailoverCount; max != nil && replicas > *max {
+		return *max
+	}
+	return replicas
+}
+
+// TiKVStsDesiredReplicas returns the effective replicas of the statefulset, which are the replicas
+// requested by the user plus the failover replicas
 func (tc *TikvCluster) TiKVStsDesiredReplicas() int32 {
-	return tc.Spec.TiKV.Replicas + int32(len(tc.Status.TiKV.FailureStores))
+	return tc.Spec.TiKV.Replicas + tc.TiKVFailoverReplicas()
 }
 
 func (tc *TikvCluster) TiKVStsActualReplicas() int32 {
@@ -198,6 +265,20 @@ func (tc *TikvCluster) getDeleteSlots(component string) (deleteSlots sets.Int32)
 	return
 }
 
+// TiKVPinnedNodes returns the nodes the tikv pods are pinned to by their ordinals, which are set by the
+// pinned nodes annotation
+func (tc *TikvCluster) TiKVPinnedNodes() (map[int32]string, error) {
+	pinned := map[int32]string{}
+	value, ok := tc.GetAnnotations()[label.AnnTiKVPinnedNodes]
+	if !ok {
+		return pinned, nil
+	}
+	if err := json.Unmarshal([]byte(value), &pinned); err != nil {
+		return nil, fmt.Errorf("invalid annotation %s %q, %v", label.AnnTiKVPinnedNodes, value, err)
+	}
+	return pinned, nil
+}
+
 func (tc *TikvCluster) Scheme() string {
 	if tc.IsTLSClusterEnabled() {
 		return "https"
@@ -213,6 +294,12 @@ func (tc *TikvCluster) TiKVUpgrading() bool {
 	return tc.Status.TiKV.Phase == UpgradePhase
 }
 
+// TiKVScaling returns whether TiKV is being scaled out or in
+func (tc *TikvCluster) TiKVScaling() bool {
+	phase := tc.Status.TiKV.ScalePhase
+	return phase == ScaleOutPhase || phase == ScaleInPhase
+}
+
 func (tc *TikvCluster) PDIsAvailable() bool {
 	lowerLimit := tc.Spec.PD.Replicas/2 + 1
 	if int32(len(tc.Status.PD.Members)) < lowerLimit {
@@ -252,3 +339,279 @@ func (tc *TikvCluster) TiKVContainerPrivilege() *bool {
 	}
 	return tc.Spec.TiKV.Privileged
 }
+
+// TiKVContainerSecurityContext returns the SecurityContext of the TiKV container, which is the
+// ContainerSecurityContext of the TiKV spec with the privileged flag applied
+func (tc *TikvCluster) TiKVContainerSecurityContext() *corev1.SecurityContext {
+	sc := tc.Spec.TiKV.ContainerSecurityContext.DeepCopy()
+	if sc == nil {
+		sc = &corev1.SecurityContext{}
+	}
+	if tc.Spec.TiKV.Privileged != nil || sc.Privileged == nil {
+		sc.Privileged = tc.TiKVContainerPrivilege()
+	}
+	return sc
+}
+
+// TiKVRebalanceRegionScheduleLimit returns the region-schedule-limit of PD while the regions are rebalanced
+// after a scale-out
+func (tc *TikvCluster) TiKVRebalanceRegionScheduleLimit() int64 {
+	rebalance := tc.Spec.TiKV.ScaleOutRebalance
+	if rebalance == nil || rebalance.RegionScheduleLimit == nil || *rebalance.RegionScheduleLimit < 1 {
+		return defaultRebalanceRegionScheduleLimit
+	}
+	return int64(*rebalance.RegionScheduleLimit)
+}
+
+// TiKVRebalanceWindow returns how long the regions are rebalanced for after a scale-out
+func (tc *TikvCluster) TiKVRebalanceWindow() time.Duration {
+	rebalance := tc.Spec.TiKV.ScaleOutRebalance
+	if rebalance == nil || rebalance.Window == nil || rebalance.Window.Duration <= 0 {
+		return defaultRebalanceWindow
+	}
+	return rebalance.Window.Duration
+}
+
+// TiKVPeerServiceName returns the name of the headless peer service of TiKV
+func (tc *TikvCluster) TiKVPeerServiceName() string {
+	if tc.Spec.TiKV.PeerServiceName != "" {
+		return tc.Spec.TiKV.PeerServiceName
+	}
+	return fmt.Sprintf("%s-tikv-peer", tc.Name)
+}
+
+// TiKVTombstoneRetention returns how long a store is kept in PD after it is tombstone
+func (tc *TikvCluster) TiKVTombstoneRetention() time.Duration {
+	cleanup := tc.Spec.TiKV.TombstoneCleanup
+	if cleanup == nil || cleanup.Retention == nil || cleanup.Retention.Duration <= 0 {
+		return defaultTombstoneRetention
+	}
+	return cleanup.Retention.Duration
+}
+
+// TiKVMinUpStores returns the minimum number of the up stores tikv can be scaled in to
+func (tc *TikvCluster) TiKVMinUpStores() int32 {
+	if tc.Spec.TiKV.MinUpStores != nil {
+		return *tc.Spec.TiKV.MinUpStores
+	}
+	return tc.TiKVMaxReplicas()
+}
+
+// TiKVRebalancing returns whether the region-schedule-limit of PD is raised to rebalance the regions
+// after a scale-out
+func (tc *TikvCluster) TiKVRebalancing() bool {
+	rebalance := tc.Status.TiKV.Rebalance
+	return rebalance != nil && rebalance.StartTime != nil && rebalance.EndTime == nil
+}
+
+// TiKVStartScriptPath returns the path the start script of tikv is mounted at
+func (tc *TikvCluster) TiKVStartScriptPath() string {
+	if tc.Spec.TiKV.StartScriptPath == "" {
+		return defaultTiKVStartScriptPath
+	}
+	return tc.Spec.TiKV.StartScriptPath
+}
+
+// TiKVStartScriptExecutable returns whether the start script of tikv is mounted executable, in which case
+// it is executed directly instead of by sh
+func (tc *TikvCluster) TiKVStartScriptExecutable() bool {
+	mode := tc.Spec.TiKV.StartScriptMode
+	return mode != nil && *mode&0111 != 0
+}
+
+// TiKVPodDisruptionBudgetEnabled returns whether the PodDisruptionBudget of TiKV is created
+func (tc *TikvCluster) TiKVPodDisruptionBudgetEnabled() bool {
+	pdb := tc.Spec.TiKV.PodDisruptionBudget
+	return pdb == nil || pdb.Enabled == nil || *pdb.Enabled
+}
+
+// TiKVStatusServiceEnabled returns whether the ClusterIP service fronting the status port of TiKV is created
+func (tc *TikvCluster) TiKVStatusServiceEnabled() bool {
+	return tc.Spec.TiKV.StatusServiceEnabled != nil && *tc.Spec.TiKV.StatusServiceEnabled
+}
+
+// TiKVPodDisruptionBudgetMaxUnavailable returns the max number of the TiKV pods unavailable due to voluntary disruptions
+func (tc *TikvCluster) TiKVPodDisruptionBudgetMaxUnavailable() int32 {
+	pdb := tc.Spec.TiKV.PodDisruptionBudget
+	if pdb == nil || pdb.MaxUnavailable == nil || *pdb.MaxUnavailable < 1 {
+		return 1
+	}
+	return *pdb.MaxUnavailable
+}
+
+// TiKVMaxReplicas returns the max-replicas of pd observed, or the default of pd if it is not known yet
+func (tc *TikvCluster) TiKVMaxReplicas() int32 {
+	if tc.Status.TiKV.MaxReplicas > 0 {
+		return tc.Status.TiKV.MaxReplicas
+	}
+	return defaultMaxReplicas
+}
+
+// TiKVLeaderEvictionParallelism returns the max number of the TiKV pods evicting their leaders at the same time,
+// which is capped by the number of the up stores beyond the max-replicas so that enough stores are left to hold
+// the leaders of all the regions
+func (tc *TikvCluster) TiKVLeaderEvictionParallelism() int {
+	if tc.Spec.TiKV.LeaderEvictionParallelism == nil || *tc.Spec.TiKV.LeaderEvictionParallelism < 1 {
+		return 1
+	}
+	upStores := 0
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.State == TiKVStateUp {
+			upStores++
+		}
+	}
+	parallelism := int(*tc.Spec.TiKV.LeaderEvictionParallelism)
+	if max := upStores - int(tc.TiKVMaxReplicas()); parallelism > max {
+		parallelism = max
+	}
+	if parallelism < 1 {
+		return 1
+	}
+	return parallelism
+}
+
+// TiKVAutoScalingEnabled returns whether TiKV is scaled automatically
+func (tc *TikvCluster) TiKVAutoScalingEnabled() bool {
+	return tc.Spec.TiKV.AutoScaling != nil && tc.Spec.TiKV.AutoScaling.Enabled
+}
+
+// TiKVStorageUtilizationThreshold returns the percentage of the average storage utilization above which
+// TiKV is scaled out
+func (tc *TikvCluster) TiKVStorageUtilizationThreshold() int32 {
+	as := tc.Spec.TiKV.AutoScaling
+	if as == nil || as.StorageUtilizationThreshold == nil {
+		return 80
+	}
+	return *as.StorageUtilizationThreshold
+}
+
+// TiKVScaleOutInterval returns the cool-down window after a scale-out of the autoscaler
+func (tc *TikvCluster) TiKVScaleOutInterval() time.Duration {
+	as := tc.Spec.TiKV.AutoScaling
+	if as == nil || as.ScaleOutIntervalSeconds == nil {
+		return 600 * time.Second
+	}
+	return time.Duration(*as.ScaleOutIntervalSeconds) * time.Second
+}
+
+// TiKVScaleInInterval returns the cool-down window after a scaling of the autoscaler during which TiKV is not scaled in
+func (tc *TikvCluster) TiKVScaleInInterval() time.Duration {
+	as := tc.Spec.TiKV.AutoScaling
+	if as == nil || as.ScaleInIntervalSeconds == nil {
+		return 1800 * time.Second
+	}
+	return time.Duration(*as.ScaleInIntervalSeconds) * time.Second
+}
+
+// TiKVRegionCount returns the average region count of the Up stores, false is returned if there is no such store
+func (tc *TikvCluster) TiKVRegionCount() (float64, bool) {
+	var total float64
+	count := 0
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.State != TiKVStateUp {
+			continue
+		}
+		total += float64(store.RegionCount)
+		count++
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
+// TiKVStorageUtilization returns the average storage utilization in percentage of the Up stores
+// reporting their capacity, false is returned if there is no such store
+func (tc *TikvCluster) TiKVStorageUtilization() (float64, bool) {
+	var total float64
+	count := 0
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.State != TiKVStateUp || store.Capacity.IsZero() {
+			continue
+		}
+		capacity := float64(store.Capacity.Value())
+		used := capacity - float64(store.Available.Value())
+		total += used / capacity * 100
+		count++
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
+// TiKVResourceRequirements returns the resource requirements of TiKV, the CPU and memory not set
+// are filled with the recommended values of the sizing profile, and the requests of the CPU and memory
+// only limited are derived from their limits if enabled by RequestsFromLimits
+func (tc *TikvCluster) TiKVResourceRequirements() corev1.ResourceRequirements {
+	requirements := tc.Spec.TiKV.ResourceRequirements.DeepCopy()
+	node, ok := tc.Spec.TiKV.SizingProfile.NodeSize()
+	if !ok {
+		if tc.Spec.TiKV.RequestsFromLimits {
+			setTiKVRequestsFromLimits(requirements)
+		}
+		return *requirements
+	}
+	recommended := RecommendedTiKVResources(node)
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		_, requested := requirements.Requests[name]
+		_, limited := requirements.Limits[name]
+		// the requests and the limits of a resource are set or recommended together
+		if requested || limited {
+			continue
+		}
+		if q, ok := recommended.Requests[name]; ok {
+			if requirements.Requests == nil {
+				requirements.Requests = corev1.ResourceList{}
+			}
+			requirements.Requests[name] = q
+		}
+		if q, ok := recommended.Limits[name]; ok {
+			if requirements.Limits == nil {
+				requirements.Limits = corev1.ResourceList{}
+			}
+			requirements.Limits[name] = q
+		}
+	}
+	if tc.Spec.TiKV.RequestsFromLimits {
+		setTiKVRequestsFromLimits(requirements)
+	}
+	return *requirements
+}
+
+// TiKVConfig returns the config of TiKV, the fields not set are filled with the recommended values
+// of the sizing profile
+func (tc *TikvCluster) TiKVConfig() *TiKVConfig {
+	config := tc.Spec.TiKV.Config
+	node, sized := tc.Spec.TiKV.SizingProfile.NodeSize()
+	reserveSpace, reserved := tc.TiKVReserveSpace()
+	if !sized && !reserved {
+		return config
+	}
+	if config == nil {
+		config = &TiKVConfig{}
+	} else {
+		config = config.DeepCopy()
+	}
+	if sized {
+		SetRecommendedTiKVConfig(config, node)
+	}
+	if reserved {
+		config.MergeDefaults(&TiKVConfig{Storage: &TiKVStorageConfig{ReserveSpace: &reserveSpace}})
+	}
+	return config
+}
+
+// TiKVReserveSpace returns the reserve-space of tikv derived from the storage request by the reserve space
+// percent, false is returned if the space is not reserved by the percent
+func (tc *TikvCluster) TiKVReserveSpace() (string, bool) {
+	percent := tc.Spec.TiKV.ReserveSpacePercent
+	if percent == nil || *percent <= 0 {
+		return "", false
+	}
+	request, ok := tc.Spec.TiKV.Requests[corev1.ResourceStorage]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%dMB", request.Value()/1024/1024*int64(*percent)/100), true
+}
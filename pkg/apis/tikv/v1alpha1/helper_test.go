@@ -0,0 +1,64 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/label"
+)
+
+func TestTiKVPinnedNodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expectErr   bool
+		expect      map[int32]string
+	}{
+		{
+			name:   "not pinned",
+			expect: map[int32]string{},
+		},
+		{
+			name:        "pinned",
+			annotations: map[string]string{label.AnnTiKVPinnedNodes: `{"0":"node-1","2":"node-3"}`},
+			expect:      map[int32]string{0: "node-1", 2: "node-3"},
+		},
+		{
+			name:        "unpinned",
+			annotations: map[string]string{label.AnnTiKVPinnedNodes: `{}`},
+			expect:      map[int32]string{},
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{label.AnnTiKVPinnedNodes: `["node-1"]`},
+			expectErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TikvCluster{}
+			tc.Annotations = tt.annotations
+			pinned, err := tc.TiKVPinnedNodes()
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pinned).To(Equal(tt.expect))
+		})
+	}
+}
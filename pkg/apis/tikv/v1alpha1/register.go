@@ -60,6 +60,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&TikvCluster{},
 		&TikvClusterList{},
+		&TikvBackup{},
+		&TikvBackupList{},
+		&TikvRestore{},
+		&TikvRestoreList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
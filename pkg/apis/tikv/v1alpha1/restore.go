@@ -0,0 +1,55 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsFinished returns whether the restore is completed or failed, a finished restore is never retried
+func (r *TikvRestore) IsFinished() bool {
+	return r.Status.Phase == RestoreComplete || r.Status.Phase == RestoreFailed
+}
+
+// BRImage returns the image of the BR job, the version of the TiKV is used as the tag
+func (r *TikvRestore) BRImage(tc *TikvCluster) string {
+	return brImage(r.Spec.BR, tc)
+}
+
+// GetRestoreCondition returns the condition of the given type, or nil if it is absent
+func GetRestoreCondition(status *TikvRestoreStatus, condType TikvRestoreConditionType) *TikvRestoreCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// UpdateRestoreCondition sets the condition of the restore, the transition time is only
+// updated when the status of the condition changes
+func UpdateRestoreCondition(status *TikvRestoreStatus, condition TikvRestoreCondition) {
+	current := GetRestoreCondition(status, condition.Type)
+	if current == nil {
+		condition.LastTransitionTime = metav1.Now()
+		status.Conditions = append(status.Conditions, condition)
+		return
+	}
+	if current.Status != condition.Status {
+		current.LastTransitionTime = metav1.Now()
+	}
+	current.Status = condition.Status
+	current.Reason = condition.Reason
+	current.Message = condition.Message
+}
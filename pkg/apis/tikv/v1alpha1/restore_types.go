@@ -0,0 +1,156 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.cluster`
+// +kubebuilder:printcolumn:name="Backup",type=string,JSONPath=`.spec.backup`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +k8s:openapi-gen=true
+// TikvRestore restores a tikv cluster from a backup taken by BR
+type TikvRestore struct {
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec defines the behavior of the restore
+	Spec TikvRestoreSpec `json:"spec"`
+
+	// +k8s:openapi-gen=false
+	// +kubebuilder:validation:Optional
+	// Most recently observed status of the restore
+	Status TikvRestoreStatus `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +k8s:openapi-gen=true
+// TikvRestoreList is TikvRestore list
+type TikvRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ListMeta `json:"metadata"`
+
+	Items []TikvRestore `json:"items"`
+}
+
+// +k8s:openapi-gen=true
+// TikvRestoreSpec describes the restore of a tikv cluster
+type TikvRestoreSpec struct {
+	// Cluster is the name of the TikvCluster to restore, it must be in the same namespace as the restore
+	// +kubebuilder:validation:MinLength=1
+	Cluster string `json:"cluster"`
+
+	// ClusterSpec is the spec of the TikvCluster created to restore the backup to
+	// if the cluster does not exist
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	ClusterSpec *TikvClusterSpec `json:"clusterSpec,omitempty"`
+
+	// Backup is the name of a completed TikvBackup in the same namespace to restore,
+	// its storage and path are used
+	// +optional
+	Backup string `json:"backup,omitempty"`
+
+	// StorageProvider is the object storage the backup is read from if Backup is not specified
+	StorageProvider `json:",inline"`
+
+	// BackupPath is the location of the backup in the storage if Backup is not specified,
+	// e.g. s3://bucket/prefix/ns-name
+	// +optional
+	BackupPath string `json:"backupPath,omitempty"`
+
+	// BR configures the BR job restoring the backup
+	// +optional
+	BR *BRConfig `json:"br,omitempty"`
+
+	// Resource requirements of the BR job
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// ServiceAccount of the BR job
+	// +optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+}
+
+// RestorePhase is the phase of a restore
+type RestorePhase string
+
+const (
+	// RestorePending means the restore is waiting for the backup or the cluster to be ready
+	RestorePending RestorePhase = "Pending"
+	// RestoreRunning means the BR job is running
+	RestoreRunning RestorePhase = "Running"
+	// RestoreComplete means the backup is restored successfully
+	RestoreComplete RestorePhase = "Complete"
+	// RestoreFailed means the restore failed and will not be retried
+	RestoreFailed RestorePhase = "Failed"
+)
+
+// TikvRestoreStatus represents the current status of a restore
+type TikvRestoreStatus struct {
+	// Phase of the restore
+	// +optional
+	Phase RestorePhase `json:"phase,omitempty"`
+	// TimeStarted is the time the BR job was created
+	// +optional
+	TimeStarted *metav1.Time `json:"timeStarted,omitempty"`
+	// TimeCompleted is the time the restore completed or failed
+	// +optional
+	TimeCompleted *metav1.Time `json:"timeCompleted,omitempty"`
+	// Represents the latest available observations of the restore's state.
+	// +optional
+	Conditions []TikvRestoreCondition `json:"conditions,omitempty"`
+}
+
+// TikvRestoreCondition describes the state of a restore at a certain point.
+type TikvRestoreCondition struct {
+	// Type of the condition.
+	Type TikvRestoreConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// TikvRestoreConditionType represents a restore condition value.
+type TikvRestoreConditionType string
+
+const (
+	// TikvRestoreScheduled indicates whether the BR job of the restore is created
+	TikvRestoreScheduled TikvRestoreConditionType = "Scheduled"
+	// TikvRestoreComplete indicates whether the restore is completed
+	TikvRestoreComplete TikvRestoreConditionType = "Complete"
+	// TikvRestoreFailed indicates whether the restore failed
+	TikvRestoreFailed TikvRestoreConditionType = "Failed"
+)
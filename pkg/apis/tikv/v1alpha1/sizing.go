@@ -0,0 +1,145 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// the CPU and memory of the node left to the kubelet, the system daemons and the sidecars
+	nodeReservedCPUPercent    = 10
+	nodeReservedMemoryPercent = 15
+	// the share of the memory of TiKV used by the block cache, which is the default of TiKV
+	// but TiKV derives it from the memory of the node instead of its memory limit
+	blockCacheMemoryPercent = 45
+	// the share of the limits of TiKV requested if only the limits are set and RequestsFromLimits is
+	// enabled, otherwise the limits are requested entirely by the defaulting of the api server. The memory
+	// is mostly requested as the memory used beyond the request makes TiKV an early victim of the eviction
+	// under the memory pressure of the node.
+	cpuRequestLimitPercent    = 50
+	memoryRequestLimitPercent = 80
+)
+
+// NodeSize is the CPU and memory of the nodes TiKV runs on
+type NodeSize struct {
+	CPU    resource.Quantity
+	Memory resource.Quantity
+}
+
+// NodeSize returns the size of the nodes the profile is designed for, false is returned if the profile is empty or unknown
+func (p SizingProfile) NodeSize() (NodeSize, bool) {
+	switch p {
+	case SizingProfileSmall:
+		return NodeSize{CPU: resource.MustParse("4"), Memory: resource.MustParse("8Gi")}, true
+	case SizingProfileMedium:
+		return NodeSize{CPU: resource.MustParse("8"), Memory: resource.MustParse("32Gi")}, true
+	case SizingProfileLarge:
+		return NodeSize{CPU: resource.MustParse("16"), Memory: resource.MustParse("64Gi")}, true
+	}
+	return NodeSize{}, false
+}
+
+// RecommendedTiKVResources returns the CPU and memory resources of TiKV recommended for the nodes of the size,
+// TiKV takes the node except what is reserved for the system. The CPU is not limited to avoid throttling
+// and the memory is limited to what is requested so that the block cache sized by the limit fits in.
+func RecommendedTiKVResources(node NodeSize) corev1.ResourceRequirements {
+	cpu := resource.NewMilliQuantity(node.CPU.MilliValue()*(100-nodeReservedCPUPercent)/100, resource.DecimalSI)
+	memory := resource.NewQuantity(recommendedTiKVMemoryMiB(node)*1024*1024, resource.BinarySI)
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    *cpu,
+			corev1.ResourceMemory: *memory,
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: *memory,
+		},
+	}
+}
+
+// RecommendedTiKVConfig returns the config of TiKV recommended for the nodes of the size
+func RecommendedTiKVConfig(node NodeSize) *TiKVConfig {
+	config := &TiKVConfig{}
+	SetRecommendedTiKVConfig(config, node)
+	return config
+}
+
+// SetRecommendedTiKVConfig fills the fields of the config not set with the values recommended for the nodes of the size:
+//   - the raftstore pools grow with the CPUs, one thread per 4 CPUs but no less than the default 2
+//   - the unified read pool takes 80% of the CPUs but no less than the default 4 threads
+//   - the shared block cache takes 45% of the memory limit of TiKV
+func SetRecommendedTiKVConfig(config *TiKVConfig, node NodeSize) {
+	cpus := node.CPU.Value()
+	poolSize := cpus / 4
+	if poolSize < 2 {
+		poolSize = 2
+	}
+	if poolSize > 8 {
+		poolSize = 8
+	}
+	readPoolSize := int32(cpus * 8 / 10)
+	if readPoolSize < 4 {
+		readPoolSize = 4
+	}
+	blockCache := fmt.Sprintf("%dMB", recommendedTiKVMemoryMiB(node)*blockCacheMemoryPercent/100)
+
+	applyPoolSize := poolSize
+	config.MergeDefaults(&TiKVConfig{
+		Raftstore: &TiKVRaftstoreConfig{
+			StorePoolSize: &poolSize,
+			ApplyPoolSize: &applyPoolSize,
+		},
+		ReadPool: &TiKVReadPoolConfig{
+			Unified: &TiKVUnifiedReadPoolConfig{
+				MaxThreadCount: &readPoolSize,
+			},
+		},
+		Storage: &TiKVStorageConfig{
+			BlockCache: &TiKVBlockCacheConfig{
+				Capacity: &blockCache,
+			},
+		},
+	})
+}
+
+// recommendedTiKVMemoryMiB returns the memory of TiKV in MiB on the nodes of the size
+func recommendedTiKVMemoryMiB(node NodeSize) int64 {
+	return node.Memory.Value() / 1024 / 1024 * (100 - nodeReservedMemoryPercent) / 100
+}
+
+// setTiKVRequestsFromLimits sets the requests of the CPU and memory only limited to the share of their limits
+func setTiKVRequestsFromLimits(requirements *corev1.ResourceRequirements) {
+	percents := map[corev1.ResourceName]int64{
+		corev1.ResourceCPU:    cpuRequestLimitPercent,
+		corev1.ResourceMemory: memoryRequestLimitPercent,
+	}
+	for name, percent := range percents {
+		limit, limited := requirements.Limits[name]
+		if _, requested := requirements.Requests[name]; requested || !limited {
+			continue
+		}
+		if requirements.Requests == nil {
+			requirements.Requests = corev1.ResourceList{}
+		}
+		if name == corev1.ResourceCPU {
+			requirements.Requests[name] = *resource.NewMilliQuantity(limit.MilliValue()*percent/100, resource.DecimalSI)
+		} else {
+			// rounded down to MiB like the recommended memory
+			requirements.Requests[name] = *resource.NewQuantity(limit.Value()/1024/1024*percent/100*1024*1024, resource.BinarySI)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRecommendedTiKVConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		profile           SizingProfile
+		expectPoolSize    int64
+		expectReadPool    int32
+		expectBlockCache  string
+		expectCPURequests string
+		expectMemory      string
+	}{
+		{SizingProfileSmall, 2, 4, "3133MB", "3600m", "6963Mi"},
+		{SizingProfileMedium, 2, 6, "12533MB", "7200m", "27852Mi"},
+		{SizingProfileLarge, 4, 12, "25067MB", "14400m", "55705Mi"},
+	}
+	for _, test := range tests {
+		node, ok := test.profile.NodeSize()
+		g.Expect(ok).To(BeTrue())
+
+		config := RecommendedTiKVConfig(node)
+		g.Expect(*config.Raftstore.StorePoolSize).To(Equal(test.expectPoolSize), string(test.profile))
+		g.Expect(*config.Raftstore.ApplyPoolSize).To(Equal(test.expectPoolSize), string(test.profile))
+		g.Expect(*config.ReadPool.Unified.MaxThreadCount).To(Equal(test.expectReadPool), string(test.profile))
+		g.Expect(*config.Storage.BlockCache.Capacity).To(Equal(test.expectBlockCache), string(test.profile))
+
+		resources := RecommendedTiKVResources(node)
+		g.Expect(resources.Requests.Cpu().String()).To(Equal(test.expectCPURequests), string(test.profile))
+		g.Expect(resources.Requests.Memory().String()).To(Equal(test.expectMemory), string(test.profile))
+		g.Expect(resources.Limits.Memory().String()).To(Equal(test.expectMemory), string(test.profile))
+		g.Expect(resources.Limits).NotTo(HaveKey(corev1.ResourceCPU), string(test.profile))
+	}
+
+	_, ok := SizingProfile("").NodeSize()
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestTiKVResourceRequirements(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := &TikvCluster{}
+	tc.Spec.TiKV.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("100Gi"),
+		corev1.ResourceCPU:     resource.MustParse("2"),
+	}
+	g.Expect(tc.TiKVResourceRequirements()).To(Equal(tc.Spec.TiKV.ResourceRequirements))
+
+	tc.Spec.TiKV.SizingProfile = SizingProfileSmall
+	requirements := tc.TiKVResourceRequirements()
+	// the cpu set explicitly is kept
+	g.Expect(requirements.Requests.Cpu().String()).To(Equal("2"))
+	g.Expect(requirements.Requests.Memory().String()).To(Equal("6963Mi"))
+	g.Expect(requirements.Limits.Memory().String()).To(Equal("6963Mi"))
+	g.Expect(tc.Spec.TiKV.Requests).NotTo(HaveKey(corev1.ResourceMemory))
+}
+
+func TestTiKVResourceRequirementsFromLimits(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := &TikvCluster{}
+	tc.Spec.TiKV.Limits = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("4"),
+		corev1.ResourceMemory: resource.MustParse("16Gi"),
+	}
+	// the requirements of the existing clusters are kept unless enabled
+	g.Expect(tc.TiKVResourceRequirements()).To(Equal(tc.Spec.TiKV.ResourceRequirements))
+
+	tc.Spec.TiKV.RequestsFromLimits = true
+	requirements := tc.TiKVResourceRequirements()
+	g.Expect(requirements.Requests.Cpu().String()).To(Equal("2"))
+	g.Expect(requirements.Requests.Memory().String()).To(Equal("13107Mi"))
+	g.Expect(requirements.Limits).To(Equal(tc.Spec.TiKV.Limits))
+	g.Expect(tc.Spec.TiKV.Requests).To(BeNil())
+
+	// the requests set explicitly are kept
+	tc.Spec.TiKV.Requests = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")}
+	requirements = tc.TiKVResourceRequirements()
+	g.Expect(requirements.Requests.Cpu().String()).To(Equal("2"))
+	g.Expect(requirements.Requests.Memory().String()).To(Equal("16Gi"))
+
+	// the cpu only limited is requested from the limit rather than recommended by the sizing profile
+	tc = &TikvCluster{}
+	tc.Spec.TiKV.RequestsFromLimits = true
+	tc.Spec.TiKV.SizingProfile = SizingProfileSmall
+	tc.Spec.TiKV.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")}
+	requirements = tc.TiKVResourceRequirements()
+	g.Expect(requirements.Requests.Cpu().String()).To(Equal("1500m"))
+	g.Expect(requirements.Requests.Memory().String()).To(Equal("6963Mi"))
+}
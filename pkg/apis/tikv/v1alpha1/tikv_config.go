@@ -13,6 +13,8 @@
 
 package v1alpha1
 
+import "reflect"
+
 // Port from TiKV v3.0.6
 
 // TiKVConfig is the configuration of TiKV.
@@ -956,3 +958,33 @@ type TiKVPessimisticTxn struct {
 	// +optional
 	Pipelined *bool `json:"pipelined,omitempty" toml:"pipelined,omitempty"`
 }
+
+// MergeDefaults fills the items of the config not set with the ones of the defaults. The sections are merged
+// recursively, so the items set by the users always take precedence over the defaults of the operator.
+func (c *TiKVConfig) MergeDefaults(defaults *TiKVConfig) {
+	if c == nil || defaults == nil {
+		return
+	}
+	mergeConfigDefaults(reflect.ValueOf(c).Elem(), reflect.ValueOf(defaults.DeepCopy()).Elem())
+}
+
+func mergeConfigDefaults(config, defaults reflect.Value) {
+	for i := 0; i < config.NumField(); i++ {
+		field, def := config.Field(i), defaults.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			mergeConfigDefaults(field, def)
+		case reflect.Ptr, reflect.Map, reflect.Slice:
+			if def.IsNil() {
+				continue
+			}
+			if field.IsNil() {
+				field.Set(def)
+				continue
+			}
+			if field.Kind() == reflect.Ptr && field.Elem().Kind() == reflect.Struct {
+				mergeConfigDefaults(field.Elem(), def.Elem())
+			}
+		}
+	}
+}
@@ -20,6 +20,8 @@ import (
 
 	"github.com/BurntSushi/toml"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/utils/pointer"
 )
 
@@ -69,3 +71,106 @@ func TestTiKVConfig(t *testing.T) {
 	g.Expect(err).To(Succeed())
 	g.Expect(&tUnmarshaled).To(Equal(c))
 }
+
+func TestTiKVConfigMergeDefaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+	c := &TiKVConfig{
+		LogLevel: pointer.StringPtr("warn"),
+		Raftstore: &TiKVRaftstoreConfig{
+			StorePoolSize: pointer.Int64Ptr(4),
+		},
+		Server: &TiKVServerConfig{
+			Labels: map[string]string{"zone": "a"},
+		},
+	}
+	defaults := &TiKVConfig{
+		LogLevel: pointer.StringPtr("info"),
+		Raftstore: &TiKVRaftstoreConfig{
+			StorePoolSize: pointer.Int64Ptr(2),
+			ApplyPoolSize: pointer.Int64Ptr(2),
+		},
+		Server: &TiKVServerConfig{
+			Labels:              map[string]string{"host": "h"},
+			GrpcCompressionType: pointer.StringPtr("gzip"),
+		},
+		Storage: &TiKVStorageConfig{
+			BlockCache: &TiKVBlockCacheConfig{
+				Capacity: pointer.StringPtr("1GB"),
+			},
+		},
+	}
+	c.MergeDefaults(defaults)
+	g.Expect(c).To(Equal(&TiKVConfig{
+		LogLevel: pointer.StringPtr("warn"),
+		Raftstore: &TiKVRaftstoreConfig{
+			StorePoolSize: pointer.Int64Ptr(4),
+			ApplyPoolSize: pointer.Int64Ptr(2),
+		},
+		Server: &TiKVServerConfig{
+			Labels:              map[string]string{"zone": "a"},
+			GrpcCompressionType: pointer.StringPtr("gzip"),
+		},
+		Storage: &TiKVStorageConfig{
+			BlockCache: &TiKVBlockCacheConfig{
+				Capacity: pointer.StringPtr("1GB"),
+			},
+		},
+	}))
+
+	// the defaults are copied, not shared with the config
+	*defaults.Storage.BlockCache.Capacity = "2GB"
+	g.Expect(*c.Storage.BlockCache.Capacity).To(Equal("1GB"))
+}
+
+func TestTiKVConfigReserveSpace(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name    string
+		percent *int32
+		request string
+		config  *TiKVConfig
+		expect  *string
+	}{
+		{
+			name:    "not reserved",
+			request: "100Gi",
+		},
+		{
+			name:    "reserved from the storage request",
+			percent: pointer.Int32Ptr(5),
+			request: "100Gi",
+			expect:  pointer.StringPtr("5120MB"),
+		},
+		{
+			name:    "no storage request",
+			percent: pointer.Int32Ptr(5),
+		},
+		{
+			name:    "the reserve-space of the config takes precedence",
+			percent: pointer.Int32Ptr(5),
+			request: "100Gi",
+			config:  &TiKVConfig{Storage: &TiKVStorageConfig{ReserveSpace: pointer.StringPtr("2GB")}},
+			expect:  pointer.StringPtr("2GB"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TikvCluster{}
+			tc.Spec.TiKV.ReserveSpacePercent = tt.percent
+			tc.Spec.TiKV.Config = tt.config
+			if tt.request != "" {
+				tc.Spec.TiKV.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(tt.request)}
+			}
+			config := tc.TiKVConfig()
+			if tt.expect == nil {
+				g.Expect(config == nil || config.Storage == nil || config.Storage.ReserveSpace == nil).To(BeTrue())
+				return
+			}
+			g.Expect(config.Storage.ReserveSpace).To(Equal(tt.expect))
+			// the config of the spec is not modified
+			if tt.config != nil {
+				g.Expect(tt.config.Storage.ReserveSpace).To(Equal(pointer.StringPtr("2GB")))
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package v1alpha1
 import (
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -53,6 +54,20 @@ const (
 	NormalPhase MemberPhase = "Normal"
 	// UpgradePhase represents the upgrade state of TiDB cluster.
 	UpgradePhase MemberPhase = "Upgrade"
+	// SuspendedPhase represents the state of the members scaled to zero by suspending the cluster.
+	SuspendedPhase MemberPhase = "Suspended"
+)
+
+// ScalePhase is the current state of the scaling of member
+type ScalePhase string
+
+const (
+	// ScaleIdlePhase represents the members are not being scaled.
+	ScaleIdlePhase ScalePhase = "Idle"
+	// ScaleOutPhase represents the pods added by a scale-out are waiting for their stores to be up.
+	ScaleOutPhase ScalePhase = "ScaleOut"
+	// ScaleInPhase represents a store is being offlined, its pod is removed once the store is tombstone.
+	ScaleInPhase ScalePhase = "ScaleIn"
 )
 
 // ConfigUpdateStrategy represents the strategy to update configuration
@@ -66,6 +81,50 @@ const (
 	ConfigUpdateStrategyRollingUpdate ConfigUpdateStrategy = "RollingUpdate"
 )
 
+// PVCDeletePolicy is what is done to the PVCs of a component once the TikvCluster is deleted
+type PVCDeletePolicy string
+
+const (
+	// PVCDeletePolicyRetain keeps the PVCs after the TikvCluster is deleted
+	PVCDeletePolicyRetain PVCDeletePolicy = "Retain"
+	// PVCDeletePolicyDelete deletes the PVCs after the TikvCluster is deleted
+	PVCDeletePolicyDelete PVCDeletePolicy = "Delete"
+)
+
+// SizingProfile is a preset size of the nodes TiKV runs on, which the recommended resources and
+// config of TiKV are derived from
+type SizingProfile string
+
+const (
+	// SizingProfileSmall is for the nodes of 4 CPUs and 8Gi memory
+	SizingProfileSmall SizingProfile = "small"
+	// SizingProfileMedium is for the nodes of 8 CPUs and 32Gi memory
+	SizingProfileMedium SizingProfile = "medium"
+	// SizingProfileLarge is for the nodes of 16 CPUs and 64Gi memory
+	SizingProfileLarge SizingProfile = "large"
+)
+
+// ReplicasMismatchPolicy represents how the operator handles the replicas of the statefulset
+// being changed outside of the operator
+type ReplicasMismatchPolicy string
+
+const (
+	// ReplicasMismatchPolicyRevert scales the statefulset back to the desired replicas
+	ReplicasMismatchPolicyRevert ReplicasMismatchPolicy = "Revert"
+	// ReplicasMismatchPolicyAdopt takes the replicas of the statefulset scaled out as the desired replicas
+	ReplicasMismatchPolicyAdopt ReplicasMismatchPolicy = "Adopt"
+)
+
+// TopologyAffinityPolicy represents how strictly the pods of a component are spread across the topology domains
+type TopologyAffinityPolicy string
+
+const (
+	// TopologyAffinityPolicyPreferred spreads the pods on a best-effort basis by a preferred pod anti-affinity
+	TopologyAffinityPolicyPreferred TopologyAffinityPolicy = "Preferred"
+	// TopologyAffinityPolicyRequired allows at most one pod in a topology domain by a required pod anti-affinity
+	TopologyAffinityPolicyRequired TopologyAffinityPolicy = "Required"
+)
+
 // +genclient
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
@@ -103,6 +162,7 @@ type TikvClusterList struct {
 // +k8s:openapi-gen=true
 // CommonListenerSpec defines the common building block for Listener type
 type CommonListenerSpec struct {
+	// +kubebuilder:validation:Enum=plaintext;ssl;sasl_plaintext;sasl_ssl
 	Type          SecurityProtocol `json:"type"`
 	Name          string           `json:"name"`
 	ContainerPort int32            `json:"containerPort"`
@@ -121,13 +181,39 @@ type ExternalListenerConfig struct {
 	CommonListenerSpec   `json:",inline"`
 	ExternalStartingPort int32              `json:"externalStartingPort"`
 	AccessMethod         corev1.ServiceType `json:"accessMethod,omitempty"`
+	// TLSSecretName is the secret with the certificates served on the listener,
+	// it is required when the type is ssl or sasl_ssl
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// ExternalTrafficPolicy of the services of the listener, Local preserves the client source IPs
+	// and avoids a second hop
+	// Optional: Defaults to Cluster
+	// +kubebuilder:validation:Enum=Cluster;Local
+	// +optional
+	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+	// NodeExternalIPKey is the key of the node label or annotation holding the public IP of the node,
+	// which is used as the external IP of the NodePort services instead of the host IP of the pods,
+	// it is only supported by the external listeners of TiKV
+	// Optional: Defaults to the host IP of the pods
+	// +optional
+	NodeExternalIPKey string `json:"nodeExternalIPKey,omitempty"`
+}
+
+func (c ExternalListenerConfig) GetExternalTrafficPolicy() corev1.ServiceExternalTrafficPolicyType {
+	if c.ExternalTrafficPolicy == "" {
+		return corev1.ServiceExternalTrafficPolicyTypeCluster
+	}
+	return c.ExternalTrafficPolicy
 }
 
 // +k8s:openapi-gen=true
 //ListenersConfig defines the Kafka listener types
 type ListenersConfig struct {
-	ExternalListeners  []ExternalListenerConfig `json:"externalListeners,omitempty"`
-	ServiceAnnotations map[string]string        `json:"serviceAnnotations,omitempty"`
+	ExternalListeners []ExternalListenerConfig `json:"externalListeners,omitempty"`
+	// ServiceAnnotations are added to the LoadBalancer services of the external listeners,
+	// e.g. to configure the cloud LB controllers
+	// +optional
+	ServiceAnnotations map[string]string `json:"serviceAnnotations,omitempty"`
 }
 
 // +k8s:openapi-gen=true
@@ -147,11 +233,20 @@ type TikvClusterSpec struct {
 	// +optional
 	Paused bool `json:"paused,omitempty"`
 
+	// Indicates that the tikv cluster is suspended, the statefulsets of PD and TiKV are scaled to zero
+	// while the PVCs and the stores in PD are retained, the replicas are restored once it is unset.
+	// Optional: Defaults to false
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
 	// Cluster version
 	// +optional
 	Version string `json:"version"`
 
-	// SchedulerName of TiKV cluster Pods
+	// SchedulerName of TiKV cluster Pods, the components may override it respectively.
+	// It must be a valid DNS label, e.g. the name of a custom scheduler
+	// Optional: Defaults to the default scheduler of kubernetes
+	// +optional
 	SchedulerName string `json:"schedulerName,omitempty"`
 
 	// ImagePullPolicy of TiDB cluster Pods
@@ -176,6 +271,17 @@ type TikvClusterSpec struct {
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
+	// TopologyKey is the node label key, e.g. topology.kubernetes.io/zone, across whose values the Pods of
+	// each component are spread, it is expanded into a pod anti-affinity added to the affinity of the Pods
+	// +optional
+	TopologyKey string `json:"topologyKey,omitempty"`
+
+	// TopologyAffinityPolicy is the policy of the pod anti-affinity expanded from the topologyKey
+	// Optional: Defaults to Preferred
+	// +kubebuilder:validation:Enum=Preferred;Required
+	// +optional
+	TopologyAffinityPolicy TopologyAffinityPolicy `json:"topologyAffinityPolicy,omitempty"`
+
 	// PriorityClassName of TiDB cluster Pods
 	// Optional: Defaults to omitted
 	// +optional
@@ -189,14 +295,81 @@ type TikvClusterSpec struct {
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
 
+	// Base extra labels of TiDB cluster Pods, components may add or override labels upon this respectively.
+	// They are not part of the selectors of the StatefulSets and never override the labels managed by the operator
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
 	// Base tolerations of TiDB cluster Pods, components may add more tolerations upon this respectively
 	// +optional
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 
+	// Base sources of the environment variables of TiDB cluster containers, components may add more sources
+	// upon this respectively
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// PodSecurityContext of TiDB cluster Pods, components may override it respectively
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+
+	// PrometheusAnnotations of TiDB cluster Pods, components may override it respectively
+	// +optional
+	PrometheusAnnotations *PrometheusAnnotations `json:"prometheusAnnotations,omitempty"`
+
 	// Time zone of TiDB cluster Pods
 	// Optional: Defaults to UTC
 	// +optional
 	Timezone string `json:"timezone,omitempty"`
+
+	// Whether enable the TLS connection between TiKV server components
+	// Optional: Defaults to nil
+	// +optional
+	TLSCluster *TLSCluster `json:"tlsCluster,omitempty"`
+
+	// NotificationWebhook receives the lifecycle events of the cluster
+	// Optional: Defaults to nil
+	// +optional
+	NotificationWebhook *NotificationWebhook `json:"notificationWebhook,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// NotificationWebhook is the webhook the lifecycle events (created, upgrading,
+// scaled, failover) of the cluster are POSTed to in JSON
+type NotificationWebhook struct {
+	// URL of the webhook
+	URL string `json:"url"`
+
+	// Retries is the number of retries when the webhook call fails
+	// Optional: Defaults to 3
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// TLSCluster can enable TLS connection between TiKV server components
+type TLSCluster struct {
+	// Enable mutual TLS authentication among TiKV server components.
+	// The operator talks to PD with the client certificate and the cluster CA
+	// in the <cluster>-cluster-client-secret secret, which must exist.
+	// It can not be disabled once the cluster is running.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// SecretName is the name of the secret which contains the certificates
+	// (ca.crt, tls.crt and tls.key) of all components, which are also used by the
+	// operator and the backups to connect to the cluster. Useful when the
+	// certificates are issued by cert-manager with a custom naming.
+	// Optional: Defaults to <cluster>-<component>-cluster-secret for each component
+	// and <cluster>-cluster-client-secret for the clients
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// MountPath is the directory the certificates are mounted into
+	// Optional: Defaults to /var/lib/<component>-tls
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
 }
 
 // TikvClusterStatus represents the current status of a tikv cluster.
@@ -240,6 +413,15 @@ const (
 	// - All TiKV stores are up.
 	// - All TiFlash stores are up.
 	TikvClusterReady TikvClusterConditionType = "Ready"
+	// TiKVFailoverSaturated indicates that the number of the TiKV failure stores reaches
+	// spec.tikv.maxFailoverCount, no more replacement stores are created for the down stores.
+	TiKVFailoverSaturated TikvClusterConditionType = "TiKVFailoverSaturated"
+	// TiKVUnderReplicated indicates that the up TiKV stores span fewer failure domains than
+	// the max-replicas of PD, so the replicas of a region can't be placed apart.
+	TiKVUnderReplicated TikvClusterConditionType = "TiKVUnderReplicated"
+	// TiKVVersionUnsupported indicates that the version of the TiKV image is below the minimum
+	// version supported by the operator, or lower than the version running, i.e. a downgrade.
+	TiKVVersionUnsupported TikvClusterConditionType = "TiKVVersionUnsupported"
 )
 
 // +k8s:openapi-gen=true
@@ -291,6 +473,37 @@ type PDSpec struct {
 	// which used by Dashboard.
 	// +optional
 	TLSClientSecretName *string `json:"tlsClientSecretName,omitempty"`
+
+	// ManagedConfig is the scheduling config reconciled against the live config of PD, unlike Config
+	// which only takes effect when PD is bootstrapped, the changes are applied to the running cluster
+	// and the config changed by pd-ctl is reverted
+	// +optional
+	ManagedConfig *PDManagedConfig `json:"managedConfig,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// PDManagedConfig is the curated set of the scheduling parameters of PD managed by the operator,
+// the parameters not set are left as they are in PD
+type PDManagedConfig struct {
+	// MaxReplicas is the number of the replicas of each region
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// LocationLabels are the label keys of the stores specifying their locations, in the order of the
+	// priority of placing the replicas apart
+	// +optional
+	LocationLabels []string `json:"locationLabels,omitempty"`
+
+	// LeaderScheduleLimit is the max number of the leader schedules running at the same time
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LeaderScheduleLimit *int32 `json:"leaderScheduleLimit,omitempty"`
+
+	// RegionScheduleLimit is the max number of the region schedules running at the same time
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RegionScheduleLimit *int32 `json:"regionScheduleLimit,omitempty"`
 }
 
 // +k8s:openapi-gen=true
@@ -318,23 +531,414 @@ type TiKVSpec struct {
 	// +optional
 	Privileged *bool `json:"privileged,omitempty"`
 
+	// ContainerSecurityContext is merged into the SecurityContext of the TiKV container, e.g. to run as
+	// non-root or drop capabilities. The privileged flag above takes precedence over its Privileged field
+	// +optional
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+
 	// MaxFailoverCount limit the max replicas could be added in failover, 0 means no failover
 	// Optional: Defaults to 3
 	// +kubebuilder:validation:Minimum=0
 	// +optional
 	MaxFailoverCount *int32 `json:"maxFailoverCount,omitempty"`
 
+	// Zones pins the tikv pods to the zones, each zone is scaled independently to its replicas.
+	// The replicas of tikv are the sum of the replicas of the zones when set. The pods are assigned to the zones
+	// by the admission webhook of the operator, which must be enabled. The store label zone is taken from
+	// the zone label of the nodes, add zone to the location labels of PD to place the replicas across the zones.
+	// Zones can not be set with the autoScaling or the Adopt replicasMismatchPolicy, which change the replicas.
+	// +optional
+	Zones []TiKVZone `json:"zones,omitempty"`
+
+	// FailoverPaused pauses the failover of the down stores, e.g. during a planned maintenance of the nodes,
+	// the status of the stores is still synced and the failure stores recorded are still recovered
+	// Optional: Defaults to false
+	// +optional
+	FailoverPaused bool `json:"failoverPaused,omitempty"`
+
+	// StaleHeartbeatThreshold is the duration since the last heartbeat after which a store Up in PD is
+	// marked stale in the status
+	// Optional: Defaults to 1m
+	// +optional
+	StaleHeartbeatThreshold *metav1.Duration `json:"staleHeartbeatThreshold,omitempty"`
+
+	// FailoverStaleStores fails over the stale stores like the Down stores, a stale store is failed over once
+	// its last heartbeat is older than the failover period of the operator
+	// Optional: Defaults to false
+	// +optional
+	FailoverStaleStores bool `json:"failoverStaleStores,omitempty"`
+
 	// The storageClassName of the persistent volume for TiKV data storage.
 	// Defaults to Kubernetes default storage class.
 	// +optional
 	StorageClassName *string `json:"storageClassName,omitempty"`
 
-	// Config is the Configuration of tikv-servers
+	// StorageCapacity is the capacity of the store advertised to PD by the CAPACITY env, e.g. less than the
+	// storage request to reserve some headroom on the volume. It must not exceed the storage request.
+	// Optional: Defaults to the storage limit, or the size of the volume if the limit is not set
+	// +optional
+	StorageCapacity *resource.Quantity `json:"storageCapacity,omitempty"`
+
+	// RequestsFromLimits derives the CPU and memory requests of TiKV not set from their limits, 50% of the CPU
+	// and 80% of the memory, instead of requesting the limits entirely. The pods are Burstable rather than
+	// Guaranteed then, and enabling it on a running cluster restarts the TiKV pods.
+	// Optional: Defaults to false
+	// +optional
+	RequestsFromLimits bool `json:"requestsFromLimits,omitempty"`
+
+	// ReserveSpacePercent is the percentage of the storage request reserved by tikv as the storage.reserve-space
+	// of the config, which keeps the volume from filling up completely. The reserve-space of the config takes
+	// precedence over it.
+	// Optional: Defaults to nil, the reserve-space is left to tikv
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=50
+	// +optional
+	ReserveSpacePercent *int32 `json:"reserveSpacePercent,omitempty"`
+
+	// SizingProfile fills the CPU and memory resources and the config of TiKV not set explicitly
+	// with the values recommended for the nodes of the profile, e.g. the raftstore pool sizes and
+	// the capacity of the block cache
+	// +kubebuilder:validation:Enum=small;medium;large
+	// +optional
+	SizingProfile SizingProfile `json:"sizingProfile,omitempty"`
+
+	// Config is the Configuration of tikv-servers.
+	// The string values may refer to the variables of the pod as $(NAME), which are resolved when tikv starts,
+	// the variables available are NAMESPACE, POD_NAME, CLUSTER_NAME and HEADLESS_SERVICE_NAME
 	// +optional
 	Config *TiKVConfig `json:"config,omitempty"`
 
+	// ConfigFiles are the extra files written to the config directory /etc/tikv alongside tikv.toml,
+	// keyed by the file names, e.g. the CA of an external service referred to by the config
+	// +optional
+	ConfigFiles map[string]string `json:"configFiles,omitempty"`
+
+	// StartScriptPath is the absolute path the start script of tikv is mounted at. The directory of the script
+	// is mounted from the ConfigMap and hides the files in it, set it to a dedicated directory on the images
+	// disallowing executing from /usr/local/bin
+	// Optional: Defaults to /usr/local/bin/tikv_start_script.sh
+	// +optional
+	StartScriptPath string `json:"startScriptPath,omitempty"`
+
+	// StartScriptMode is the file mode of the start script, the script is executed directly instead of by sh
+	// when the mode is executable by anyone
+	// Optional: Defaults to 0644
+	// +optional
+	StartScriptMode *int32 `json:"startScriptMode,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	ListenersConfig ListenersConfig `json:"listenersConfig"`
+
+	// PeerServiceAnnotations are added to the headless peer service of TiKV,
+	// e.g. to integrate with the internal DNS or the service mesh
+	// +optional
+	PeerServiceAnnotations map[string]string `json:"peerServiceAnnotations,omitempty"`
+
+	// PeerServiceName overrides the name of the headless peer service of TiKV, e.g. to match the name an
+	// external DNS expects. It is the ServiceName of the TiKV statefulset and the domain of the advertise
+	// address of the stores, so it can not be changed once set.
+	// Optional: Defaults to <cluster name>-tikv-peer
+	// +optional
+	PeerServiceName string `json:"peerServiceName,omitempty"`
+
+	// StatusServiceEnabled creates a ClusterIP service fronting the status port of the TiKV pods,
+	// so that the tools can reach any store through a stable virtual IP
+	// Optional: Defaults to false
+	// +optional
+	StatusServiceEnabled *bool `json:"statusServiceEnabled,omitempty"`
+
+	// LeaderEvictionParallelism is the max number of TiKV pods evicting their leaders
+	// at the same time during a rolling upgrade, the leaders of the pods to upgrade next
+	// are evicted in advance to speed up the upgrade of large clusters. It must be less than the replicas
+	// and is capped by the number of the up stores beyond the max-replicas of PD
+	// Optional: Defaults to 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	LeaderEvictionParallelism *int32 `json:"leaderEvictionParallelism,omitempty"`
+
+	// ReplicasMismatchPolicy determines how the operator handles the replicas of the TiKV statefulset
+	// being changed directly, Revert scales it back to the desired replicas and Adopt updates
+	// the replicas of the spec to the replicas of the statefulset scaled out. A scale-in is always reverted
+	// Optional: Defaults to Revert
+	// +kubebuilder:validation:Enum=Revert;Adopt
+	// +optional
+	ReplicasMismatchPolicy ReplicasMismatchPolicy `json:"replicasMismatchPolicy,omitempty"`
+
+	// CloneFrom clones the data volumes of new TiKV pods from the volumes of
+	// another TikvCluster, e.g. to create a staging copy of a cluster
+	// +optional
+	CloneFrom *TiKVCloneSource `json:"cloneFrom,omitempty"`
+
+	// ServiceMonitor generates a ServiceMonitor of the Prometheus Operator scraping the
+	// status port of the TiKV pods, it is only created if the ServiceMonitor CRD exists
+	// +optional
+	ServiceMonitor *ServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+
+	// PodDisruptionBudget limits the TiKV pods evicted at the same time by voluntary disruptions,
+	// e.g. node drains and the cluster autoscaler
+	// +optional
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+
+	// AutoScaling scales out TiKV automatically once the stores run out of storage
+	// +optional
+	AutoScaling *TiKVAutoScalingSpec `json:"autoScaling,omitempty"`
+
+	// StoreLimit limits the rate of the peers added to and removed from each store by PD,
+	// e.g. to throttle the rebalancing after scaling out
+	// +optional
+	StoreLimit *TiKVStoreLimit `json:"storeLimit,omitempty"`
+
+	// StoreWeights sets the leader and region weights of the stores selected by their labels in PD,
+	// e.g. to keep the leaders off the stores of a slower zone. The first weight selecting a store
+	// applies, and the weight annotations of the tikv pods take precedence over them. The weights of
+	// a store no longer selected or annotated are reset to 1.
+	// +optional
+	StoreWeights []TiKVStoreWeight `json:"storeWeights,omitempty"`
+
+	// CordonedStores are the IDs of the stores PD stops placing leaders and regions on, e.g. before they are
+	// decommissioned. The leaders of the stores are evicted and their add-peer limit is set to 0, which is
+	// reverted once a store is removed from the list. Unlike scaling in, the stores and their data are kept.
+	// +optional
+	CordonedStores []string `json:"cordonedStores,omitempty"`
+
+	// ScaleOutRebalance accelerates the rebalance of the regions to the stores added by a scale-out, the
+	// region-schedule-limit of PD is raised for a bounded window after the scale-out completes and restored then
+	// +optional
+	ScaleOutRebalance *TiKVScaleOutRebalance `json:"scaleOutRebalance,omitempty"`
+
+	// MinUpStores is the minimum number of the up stores, a scale-in offlining an up store is rejected
+	// if it would leave fewer up stores than it to keep the regions from being under-replicated
+	// Optional: Defaults to the max-replicas of PD
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinUpStores *int32 `json:"minUpStores,omitempty"`
+
+	// TombstoneCleanup removes the records of the tombstone stores from PD once they have been tombstone
+	// for the retention, the tombstone stores are kept in PD if it is not set
+	// +optional
+	TombstoneCleanup *TiKVTombstoneCleanup `json:"tombstoneCleanup,omitempty"`
+
+	// PVCDeletePolicy is what is done to the PVCs of TiKV once the TikvCluster is deleted, the PVCs
+	// are deleted after the statefulset of TiKV if it is Delete
+	// Optional: Defaults to Retain
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +optional
+	PVCDeletePolicy PVCDeletePolicy `json:"pvcDeletePolicy,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// TiKVAutoScalingSpec configures the autoscaler of TiKV, the replicas of TiKV are increased by one
+// at a time once the average storage utilization, region count or CPU utilization of the stores exceeds
+// the scale-out threshold, and decreased by one at a time once the region count and CPU utilization
+// are both below the scale-in thresholds
+type TiKVAutoScalingSpec struct {
+	// Whether to scale TiKV automatically
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// MinReplicas is the lower limit of the replicas TiKV is scaled in to, TiKV is never
+	// scaled in automatically if not set
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper limit of the replicas TiKV is scaled out to
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// StorageUtilizationThreshold is the percentage of the average storage utilization
+	// of the stores above which TiKV is scaled out
+	// Optional: Defaults to 80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	StorageUtilizationThreshold *int32 `json:"storageUtilizationThreshold,omitempty"`
+
+	// RegionCount scales TiKV by the average region count of the stores
+	// +optional
+	RegionCount *AutoScalingThreshold `json:"regionCount,omitempty"`
+
+	// CPUUtilization scales TiKV by the average CPU utilization of the TiKV pods in percentage
+	// of their CPU requests, the CPU usage is queried from the Prometheus at MetricsURL
+	// +optional
+	CPUUtilization *AutoScalingThreshold `json:"cpuUtilization,omitempty"`
+
+	// MetricsURL is the address of the Prometheus scraping the metrics of TiKV,
+	// e.g. http://prometheus-operated.monitoring:9090
+	// +optional
+	MetricsURL string `json:"metricsURL,omitempty"`
+
+	// ScaleOutIntervalSeconds is the cool-down window after a scale-out during which
+	// TiKV is not scaled out again, so that the regions are rebalanced to the new store
+	// Optional: Defaults to 600
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleOutIntervalSeconds *int32 `json:"scaleOutIntervalSeconds,omitempty"`
+
+	// ScaleInIntervalSeconds is the cool-down window after a scaling during which
+	// TiKV is not scaled in
+	// Optional: Defaults to 1800
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleInIntervalSeconds *int32 `json:"scaleInIntervalSeconds,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// AutoScalingThreshold is the thresholds of a metric TiKV is scaled by
+type AutoScalingThreshold struct {
+	// ScaleOut is the average value of the metric above which TiKV is scaled out
+	// +kubebuilder:validation:Minimum=1
+	ScaleOut int32 `json:"scaleOut"`
+
+	// ScaleIn is the average value of the metric below which TiKV is scaled in,
+	// TiKV is not scaled in by the metric if not set
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleIn *int32 `json:"scaleIn,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// TiKVStoreLimit is the store limit of PD applied to all the stores of TiKV, the limits not set
+// are left as they are in PD
+type TiKVStoreLimit struct {
+	// AddPeer is the number of the peers added to a store per minute
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	AddPeer *int32 `json:"addPeer,omitempty"`
+
+	// RemovePeer is the number of the peers removed from a store per minute
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RemovePeer *int32 `json:"removePeer,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// TiKVScaleOutRebalance configures the rebalance of the regions after tikv is scaled out
+type TiKVScaleOutRebalance struct {
+	// RegionScheduleLimit is the region-schedule-limit of PD in the window, the live limit is never lowered
+	// Optional: Defaults to 4096
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RegionScheduleLimit *int32 `json:"regionScheduleLimit,omitempty"`
+
+	// Window is how long the region-schedule-limit is raised for, the rebalance ends earlier once the new
+	// stores have as many regions as the average of the stores
+	// Optional: Defaults to 30m
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// TiKVTombstoneCleanup configures the removal of the tombstone stores from PD
+type TiKVTombstoneCleanup struct {
+	// Retention is how long a store is kept in PD after it is tombstone. PD removes all the tombstone stores
+	// at once, so they are removed only after every one of them has been tombstone for the retention.
+	// Optional: Defaults to 24h
+	// +optional
+	Retention *metav1.Duration `json:"retention,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// TiKVStoreWeight is the weights of PD applied to the stores selected, the weights not set are left as they
+// are in PD
+type TiKVStoreWeight struct {
+	// Labels selects the stores whose labels in PD contain all of them, all the stores are selected if empty
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// LeaderWeight is the leader weight of the stores, PD balances the leaders in proportion to the weights
+	// and moves the leaders off the stores weighted 0
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LeaderWeight *int32 `json:"leaderWeight,omitempty"`
+
+	// RegionWeight is the region weight of the stores, PD balances the regions in proportion to the weights
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RegionWeight *int32 `json:"regionWeight,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// PodDisruptionBudgetSpec configures the PodDisruptionBudget of a component
+type PodDisruptionBudgetSpec struct {
+	// Whether to create the PodDisruptionBudget, it is deleted once disabled
+	// Optional: Defaults to true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// MaxUnavailable is the max number of the pods unavailable due to voluntary disruptions,
+	// the minAvailable of the PodDisruptionBudget is the replicas minus it
+	// Optional: Defaults to 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxUnavailable *int32 `json:"maxUnavailable,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// TiKVCloneSource references the TiKV volumes to clone from
+type TiKVCloneSource struct {
+	// Name of the source TikvCluster in the same namespace, the volume of the
+	// TiKV pod with ordinal N is cloned from the volume of its pod with ordinal N
+	ClusterName string `json:"clusterName"`
+}
+
+// +k8s:openapi-gen=true
+// ServiceMonitorSpec configures the ServiceMonitor of a component
+type ServiceMonitorSpec struct {
+	// Whether to create the ServiceMonitor, it is deleted once disabled
+	Enabled bool `json:"enabled"`
+
+	// Interval at which the metrics are scraped, e.g. 15s
+	// Optional: Defaults to the scrape interval of Prometheus
+	// +kubebuilder:validation:Pattern=`^([0-9]+(ms|s|m|h))+$`
+	// +optional
+	Interval string `json:"interval,omitempty"`
+
+	// Additional labels of the ServiceMonitor, e.g. to be selected by the serviceMonitorSelector of Prometheus
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Relabelings applied to the targets before scraping
+	// +optional
+	Relabelings []RelabelConfig `json:"relabelings,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// RelabelConfig is a relabeling step of Prometheus, see
+// https://prometheus.io/docs/prometheus/latest/configuration/configuration/#relabel_config
+type RelabelConfig struct {
+	// The source labels select values from existing labels
+	// +optional
+	SourceLabels []string `json:"sourceLabels,omitempty"`
+
+	// Separator placed between concatenated source label values
+	// Optional: Defaults to ;
+	// +optional
+	Separator string `json:"separator,omitempty"`
+
+	// Label to which the resulting value is written in a replace action
+	// +optional
+	TargetLabel string `json:"targetLabel,omitempty"`
+
+	// Regular expression against which the extracted value is matched
+	// Optional: Defaults to (.*)
+	// +optional
+	Regex string `json:"regex,omitempty"`
+
+	// Modulus to take of the hash of the source label values
+	// +optional
+	Modulus uint64 `json:"modulus,omitempty"`
+
+	// Replacement value against which a regex replace is performed if the regular expression matches
+	// Optional: Defaults to $1
+	// +optional
+	Replacement string `json:"replacement,omitempty"`
+
+	// Action to perform based on regex matching
+	// Optional: Defaults to replace
+	// +kubebuilder:validation:Enum=replace;keep;drop;hashmod;labelmap;labeldrop;labelkeep
+	// +optional
+	Action string `json:"action,omitempty"`
 }
 
 // +k8s:openapi-gen=true
@@ -365,12 +969,24 @@ type ComponentSpec struct {
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
+	// TopologyKey of the component. Override the cluster-level one if present
+	// Optional: Defaults to cluster-level setting
+	// +optional
+	TopologyKey *string `json:"topologyKey,omitempty"`
+
+	// TopologyAffinityPolicy of the component. Override the cluster-level one if present
+	// Optional: Defaults to cluster-level setting
+	// +kubebuilder:validation:Enum=Preferred;Required
+	// +optional
+	TopologyAffinityPolicy *TopologyAffinityPolicy `json:"topologyAffinityPolicy,omitempty"`
+
 	// PriorityClassName of the component. Override the cluster-level one if present
 	// Optional: Defaults to cluster-level setting
 	// +optional
 	PriorityClassName *string `json:"priorityClassName,omitempty"`
 
-	// SchedulerName of the component. Override the cluster-level one if present
+	// SchedulerName of the component. Override the cluster-level one if present,
+	// it must be a valid DNS label
 	// Optional: Defaults to cluster-level setting
 	// +optional
 	SchedulerName *string `json:"schedulerName,omitempty"`
@@ -385,12 +1001,18 @@ type ComponentSpec struct {
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
 
+	// Extra labels of the component Pods. Merged into the cluster-level labels if non-empty
+	// Optional: Defaults to cluster-level setting
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
 	// Tolerations of the component. Override the cluster-level tolerations if non-empty
 	// Optional: Defaults to cluster-level setting
 	// +optional
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 
-	// PodSecurityContext of the component
+	// PodSecurityContext of the component. Override the cluster-level one if present
+	// Optional: Defaults to cluster-level setting
 	// +optional
 	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
 
@@ -399,9 +1021,43 @@ type ComponentSpec struct {
 	// +optional
 	ConfigUpdateStrategy *ConfigUpdateStrategy `json:"configUpdateStrategy,omitempty"`
 
+	// PrometheusAnnotations of the component. Override the cluster-level one if present
+	// Optional: Defaults to cluster-level setting
+	// +optional
+	PrometheusAnnotations *PrometheusAnnotations `json:"prometheusAnnotations,omitempty"`
+
 	// List of environment variables to set in the container, like
 	// v1.Container.Env.
+	// The variables set by the operator, e.g. TZ, are overridden by the ones with the same names
 	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// List of sources to populate environment variables in the container, like v1.Container.EnvFrom.
+	// Appended to the cluster-level sources, the variables of the later sources take precedence and
+	// the ones of Env take precedence over all of them
+	// Optional: Defaults to cluster-level setting
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+}
+
+// PrometheusAnnotations configures the prometheus.io annotations added to the Pods for scraping their metrics
+// +k8s:openapi-gen=true
+type PrometheusAnnotations struct {
+	// Whether to add the annotations, disable it if the Pods are scraped otherwise, e.g. by a ServiceMonitor
+	// Optional: Defaults to true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Path of the metrics
+	// Optional: Defaults to /metrics
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Port of the metrics
+	// Optional: Defaults to the status port of the component
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port *int32 `json:"port,omitempty"`
 }
 
 // +k8s:openapi-gen=true
@@ -480,6 +1136,137 @@ type TiKVStatus struct {
 	TombstoneStores map[string]TiKVStore        `json:"tombstoneStores,omitempty"`
 	FailureStores   map[string]TiKVFailureStore `json:"failureStores,omitempty"`
 	Image           string                      `json:"image,omitempty"`
+	// Replicas is the number of TiKV stores requested by the user, i.e. spec.tikv.replicas
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// FailoverReplicas is the number of the extra pods created to replace the failure stores
+	// +optional
+	FailoverReplicas int32 `json:"failoverReplicas,omitempty"`
+	// StatefulSetReplicas is the effective replicas of the statefulset, i.e. Replicas + FailoverReplicas
+	// +optional
+	StatefulSetReplicas int32 `json:"statefulSetReplicas,omitempty"`
+	// LastAutoScaledTime is the last time TiKV was scaled by the autoscaler
+	// +optional
+	LastAutoScaledTime *metav1.Time `json:"lastAutoScaledTime,omitempty"`
+	// CurrentRevision is the revision of the TiKV statefulset that the pods are upgraded from
+	// +optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// UpdateRevision is the revision of the TiKV statefulset that the pods are upgraded to
+	// +optional
+	UpdateRevision string `json:"updateRevision,omitempty"`
+	// CurrentRevisionPods is the number of the TiKV pods on the CurrentRevision
+	// +optional
+	CurrentRevisionPods int32 `json:"currentRevisionPods,omitempty"`
+	// UpdateRevisionPods is the number of the TiKV pods on the UpdateRevision,
+	// a rolling upgrade is done when it equals to the replicas of the statefulset
+	// +optional
+	UpdateRevisionPods int32 `json:"updateRevisionPods,omitempty"`
+	// PodRevisions maps the name of each TiKV pod to its revision
+	// +optional
+	PodRevisions map[string]string `json:"podRevisions,omitempty"`
+	// FailoverHistory is the timeline of the latest failovers of the tikv stores, oldest first
+	// +optional
+	FailoverHistory []TiKVFailoverRecord `json:"failoverHistory,omitempty"`
+	// NodeDrainEvictions maps the ID of each store whose leaders are evicted as its node is being drained
+	// to the name of the node
+	// +optional
+	NodeDrainEvictions map[string]string `json:"nodeDrainEvictions,omitempty"`
+	// CordonedStores maps the ID of each cordoned store to its add-peer limit before it was cordoned,
+	// which is restored once the store is uncordoned
+	// +optional
+	CordonedStores map[string]string `json:"cordonedStores,omitempty"`
+	// ReregisteredStores are the IDs of the stale stores of the pods registered again with a new store ID,
+	// which are to be offlined in PD
+	// +optional
+	ReregisteredStores []string `json:"reregisteredStores,omitempty"`
+	// MissingAffinityNodeLabels are the node label keys required by the affinity which do not exist
+	// on any schedulable node
+	// +optional
+	MissingAffinityNodeLabels []string `json:"missingAffinityNodeLabels,omitempty"`
+	// UnpinnedPods are the TiKV pods which do not run on the nodes they are pinned to by the pinned nodes
+	// annotation, as they were created before the pins
+	// +optional
+	UnpinnedPods []string `json:"unpinnedPods,omitempty"`
+	// FailedConfigMap is the ConfigMap rolled back from as the TiKV pods using it were crash-looping,
+	// it is not rolled out again until the config is changed
+	FailedConfigMap string `json:"failedConfigMap,omitempty"`
+	// MaxReplicas is the max-replicas of the replication config of PD
+	// +optional
+	MaxReplicas int32 `json:"maxReplicas,omitempty"`
+	// FailureDomains is the number of the distinct locations of the up stores, told by the values of
+	// the location labels of PD, each store is a failure domain of its own if there are no location labels
+	// +optional
+	FailureDomains int32 `json:"failureDomains,omitempty"`
+	// StoreLabelsSet is the number of the stores whose labels were set in PD by the last sync, it stays
+	// nonzero only if the labels are set repeatedly
+	// +optional
+	StoreLabelsSet int32 `json:"storeLabelsSet,omitempty"`
+	// LastStoreLabelsSetTime is the last time the labels of any store were set in PD
+	// +optional
+	LastStoreLabelsSetTime *metav1.Time `json:"lastStoreLabelsSetTime,omitempty"`
+	// ScalingOutPods are the pods added by the scale-outs in progress whose stores are not up yet
+	// +optional
+	ScalingOutPods []string `json:"scalingOutPods,omitempty"`
+	// Rebalance is the rebalance of the regions to the stores added by the last scale-out
+	// +optional
+	Rebalance *TiKVRebalanceStatus `json:"rebalance,omitempty"`
+	// ScalePhase is the state of the scaling of TiKV
+	// +optional
+	ScalePhase ScalePhase `json:"scalePhase,omitempty"`
+	// ScaleInRejected is whether the scale-in of TiKV is rejected as it leaves fewer up stores than the minimum
+	// +optional
+	ScaleInRejected bool `json:"scaleInRejected,omitempty"`
+	// FailoverSkipped is whether the failover of the TiKV stores not ready is skipped as it is paused
+	// +optional
+	FailoverSkipped bool `json:"failoverSkipped,omitempty"`
+	// DowngradeRefused is whether the downgrade of TiKV is refused as the allow-downgrade annotation is not set
+	// +optional
+	DowngradeRefused bool `json:"downgradeRefused,omitempty"`
+	// DesiredReplicas is the number of the replicas TiKV is being scaled to
+	// +optional
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+	// CurrentReplicas is the number of the replicas of the TiKV statefulset, it reaches the DesiredReplicas one
+	// pod at a time as TiKV is scaled
+	// +optional
+	CurrentReplicas int32 `json:"currentReplicas,omitempty"`
+	// WeightedStores are the IDs of the stores whose weights are set by the store weights or the weight
+	// annotations, the weights of a store are reset to 1 once it is no longer weighted
+	// +optional
+	WeightedStores []string `json:"weightedStores,omitempty"`
+	// ServiceMonitorCreated is whether the ServiceMonitor of TiKV is created by the operator, it is deleted once
+	// the ServiceMonitor is disabled
+	// +optional
+	ServiceMonitorCreated bool `json:"serviceMonitorCreated,omitempty"`
+}
+
+// TiKVRebalanceStatus is the status of the rebalance of the regions to the stores added by a scale-out
+type TiKVRebalanceStatus struct {
+	// Pods are the tikv pods added by the scale-out
+	Pods []string `json:"pods"`
+	// StartTime is the time the rebalance started, which is after the stores of the pods are up
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// EndTime is the time the rebalance ended
+	// +optional
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+	// OriginalRegionScheduleLimit is the region-schedule-limit of PD restored when the rebalance ends,
+	// it is not set if the live limit was not raised
+	// +optional
+	OriginalRegionScheduleLimit *int64 `json:"originalRegionScheduleLimit,omitempty"`
+	// Progress is the percentage of the average region count of the stores that the new stores have
+	// +optional
+	Progress int32 `json:"progress,omitempty"`
+}
+
+// TiKVZone is a zone the tikv pods are pinned to
+// +k8s:openapi-gen=true
+type TiKVZone struct {
+	// Name is the value of the zone label failure-domain.beta.kubernetes.io/zone of the nodes in the zone
+	Name string `json:"name"`
+
+	// Replicas is the number of the tikv pods in the zone
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas"`
 }
 
 // TiKVStores is either Up/Down/Offline/Tombstone
@@ -489,10 +1276,22 @@ type TiKVStore struct {
 	PodName           string      `json:"podName"`
 	IP                string      `json:"ip"`
 	LeaderCount       int32       `json:"leaderCount"`
+	RegionCount       int32       `json:"regionCount,omitempty"`
 	State             string      `json:"state"`
 	LastHeartbeatTime metav1.Time `json:"lastHeartbeatTime"`
+	// Stale is true if the store is Up in PD but its last heartbeat is older than the stale heartbeat threshold
+	// +optional
+	Stale bool `json:"stale,omitempty"`
 	// Last time the health transitioned from one to another.
 	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// ExternalAddress is the ip or hostname assigned to the LoadBalancer service of the store
+	ExternalAddress string `json:"externalAddress,omitempty"`
+	// Capacity is the storage capacity of the store reported to PD
+	// +optional
+	Capacity resource.Quantity `json:"capacity,omitempty"`
+	// Available is the available storage of the store reported to PD
+	// +optional
+	Available resource.Quantity `json:"available,omitempty"`
 }
 
 // TiKVFailureStore is the tikv failure store information
@@ -500,4 +1299,22 @@ type TiKVFailureStore struct {
 	PodName   string      `json:"podName,omitempty"`
 	StoreID   string      `json:"storeID,omitempty"`
 	CreatedAt metav1.Time `json:"createdAt,omitempty"`
+	// ReplacementPodName is the pod created to replace the failure store
+	// +optional
+	ReplacementPodName string `json:"replacementPodName,omitempty"`
+}
+
+// TiKVFailoverRecord records a failover of a tikv store
+type TiKVFailoverRecord struct {
+	StoreID string `json:"storeID"`
+	// PodName is the original pod of the failure store
+	PodName string `json:"podName"`
+	// ReplacementPodName is the pod created to replace the failure store
+	// +optional
+	ReplacementPodName string `json:"replacementPodName,omitempty"`
+	// CreatedAt is the time the store was marked as a failure store
+	CreatedAt metav1.Time `json:"createdAt"`
+	// RecoveredAt is the time the failure store was recovered
+	// +optional
+	RecoveredAt *metav1.Time `json:"recoveredAt,omitempty"`
 }
@@ -14,12 +14,22 @@
 package validation
 
 import (
+	"fmt"
+	"io/ioutil"
+	"path"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 )
@@ -45,6 +55,10 @@ func validateAnnotations(anns map[string]string, fldPath *field.Path) field.Erro
 
 func validateTiKVClusterSpec(spec *v1alpha1.TikvClusterSpec, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateTopologyKey(spec.TopologyKey, fldPath.Child("topologyKey"))...)
+	allErrs = append(allErrs, validateSchedulerName(spec.SchedulerName, fldPath.Child("schedulerName"))...)
+	allErrs = append(allErrs, validateTimezone(spec.Timezone, fldPath.Child("timezone"))...)
+	allErrs = append(allErrs, metav1validation.ValidateLabels(spec.Labels, fldPath.Child("labels"))...)
 	allErrs = append(allErrs, validatePDSpec(&spec.PD, fldPath.Child("pd"))...)
 	allErrs = append(allErrs, validateTiKVSpec(&spec.TiKV, fldPath.Child("tikv"))...)
 	return allErrs
@@ -54,6 +68,11 @@ func validatePDSpec(spec *v1alpha1.PDSpec, fldPath *field.Path) field.ErrorList
 	allErrs := field.ErrorList{}
 	allErrs = append(allErrs, validateComponentSpec(&spec.ComponentSpec, fldPath)...)
 	allErrs = append(allErrs, validateRequestsStorage(spec.ResourceRequirements.Requests, fldPath)...)
+	allErrs = append(allErrs, validateListenersConfig(&spec.ListenersConfig, fldPath.Child("listenersConfig"))...)
+	allErrs = append(allErrs, validateListenerPorts(&spec.ListenersConfig, spec.Replicas, fldPath.Child("listenersConfig"))...)
+	if spec.Config != nil {
+		allErrs = append(allErrs, validateConfig(spec.Config, fldPath.Child("config"))...)
+	}
 	return allErrs
 }
 
@@ -61,6 +80,172 @@ func validateTiKVSpec(spec *v1alpha1.TiKVSpec, fldPath *field.Path) field.ErrorL
 	allErrs := field.ErrorList{}
 	allErrs = append(allErrs, validateComponentSpec(&spec.ComponentSpec, fldPath)...)
 	allErrs = append(allErrs, validateRequestsStorage(spec.ResourceRequirements.Requests, fldPath)...)
+	allErrs = append(allErrs, validateListenersConfig(&spec.ListenersConfig, fldPath.Child("listenersConfig"))...)
+	allErrs = append(allErrs, validateListenerPorts(&spec.ListenersConfig, spec.Replicas, fldPath.Child("listenersConfig"))...)
+	if spec.Config != nil {
+		allErrs = append(allErrs, validateConfig(spec.Config, fldPath.Child("config"))...)
+	}
+	allErrs = append(allErrs, validateConfigFiles(spec.ConfigFiles, fldPath.Child("configFiles"))...)
+	allErrs = append(allErrs, validateZones(spec.Zones, fldPath.Child("zones"))...)
+	allErrs = append(allErrs, validateZoneReplicas(spec, fldPath)...)
+	allErrs = append(allErrs, validateStartScript(spec, fldPath)...)
+	allErrs = append(allErrs, validateCordonedStores(spec.CordonedStores, fldPath.Child("cordonedStores"))...)
+	allErrs = append(allErrs, validateBlockCacheCapacity(spec, fldPath)...)
+	if spec.PeerServiceName != "" {
+		for _, msg := range validation.IsDNS1035Label(spec.PeerServiceName) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("peerServiceName"), spec.PeerServiceName, msg))
+		}
+	}
+	if percent := spec.ReserveSpacePercent; percent != nil && (*percent < 0 || *percent > 50) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("reserveSpacePercent"), *percent, "must be between 0 and 50"))
+	}
+	if spec.StorageCapacity != nil {
+		allErrs = append(allErrs, validateStorageCapacity(*spec.StorageCapacity, spec.ResourceRequirements.Requests, fldPath.Child("storageCapacity"))...)
+	}
+	allErrs = append(allErrs, validateLeaderEvictionParallelism(spec, fldPath.Child("leaderEvictionParallelism"))...)
+	if spec.CloneFrom != nil && spec.CloneFrom.ClusterName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("cloneFrom", "clusterName"), "source cluster name must not be empty"))
+	}
+	return allErrs
+}
+
+// maxBlockCacheMemoryPercent is the largest share of the memory limit of TiKV the block cache may take,
+// the rest is left to the page cache TiKV relies on and the memory used beyond the block cache
+const maxBlockCacheMemoryPercent = 60
+
+// validateBlockCacheCapacity validates the block cache leaves room for the page cache within the memory limit,
+// including the limit recommended by the sizing profile
+func validateBlockCacheCapacity(spec *v1alpha1.TiKVSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	tc := &v1alpha1.TikvCluster{Spec: v1alpha1.TikvClusterSpec{TiKV: *spec}}
+	limit, limited := tc.TiKVResourceRequirements().Limits[corev1.ResourceMemory]
+	if !limited || spec.Config == nil || spec.Config.Storage == nil || spec.Config.Storage.BlockCache == nil ||
+		spec.Config.Storage.BlockCache.Capacity == nil {
+		return allErrs
+	}
+	capacityPath := fldPath.Child("config", "storage", "block-cache", "capacity")
+	value := *spec.Config.Storage.BlockCache.Capacity
+	capacity, err := parseReadableSize(value)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(capacityPath, value, err.Error()))
+		return allErrs
+	}
+	if capacity > limit.Value()*maxBlockCacheMemoryPercent/100 {
+		allErrs = append(allErrs, field.Invalid(capacityPath, value, fmt.Sprintf(
+			"must not exceed %d%% of the memory limit %s to leave room for the page cache", maxBlockCacheMemoryPercent, limit.String())))
+	}
+	return allErrs
+}
+
+// parseReadableSize parses a size of the config of TiKV, e.g. 512MB or 1GiB, into bytes, the units are binary
+func parseReadableSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	s = strings.TrimSuffix(s, "B")
+	s = strings.TrimSuffix(s, "I")
+	shift := uint(0)
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("KMGTP", s[n-1]); i >= 0 {
+			shift = uint(i+1) * 10
+			s = s[:n-1]
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return int64(value * float64(int64(1)<<shift)), nil
+}
+
+// validateCordonedStores validates the cordoned stores are the unique IDs of the stores
+func validateCordonedStores(ids []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	seen := sets.NewString()
+	for i, id := range ids {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), id, "must be the ID of a store"))
+			continue
+		}
+		if seen.Has(id) {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), id))
+		}
+		seen.Insert(id)
+	}
+	return allErrs
+}
+
+// validateConfigFiles validates the names of the extra config files are valid ConfigMap keys and
+// not used by the config and the startup script
+func validateConfigFiles(files map[string]string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for name := range files {
+		for _, msg := range validation.IsConfigMapKey(name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(name), name, msg))
+		}
+		switch name {
+		case "config-file", "startup-script", "tikv.toml":
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(name), name, "file name is reserved"))
+		}
+	}
+	return allErrs
+}
+
+// validateZones validates the zones are named uniquely by valid label values
+func validateZones(zones []v1alpha1.TiKVZone, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := sets.NewString()
+	for i, zone := range zones {
+		idxPath := fldPath.Index(i)
+		if zone.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "zone name must not be empty"))
+		}
+		for _, msg := range validation.IsValidLabelValue(zone.Name) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), zone.Name, msg))
+		}
+		if names.Has(zone.Name) {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), zone.Name))
+		}
+		names.Insert(zone.Name)
+		if zone.Replicas < 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("replicas"), zone.Replicas, "must be greater than or equal to 0"))
+		}
+	}
+	return allErrs
+}
+
+// validateZoneReplicas validates the replicas derived from the zones are not changed by the autoscaler or
+// the adopted replicas of the statefulset, which would be reverted by the defaulting on every sync
+func validateZoneReplicas(spec *v1alpha1.TiKVSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(spec.Zones) == 0 {
+		return allErrs
+	}
+	if spec.AutoScaling != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("autoScaling"), "must not be set with zones"))
+	}
+	if spec.ReplicasMismatchPolicy == v1alpha1.ReplicasMismatchPolicyAdopt {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("replicasMismatchPolicy"),
+			fmt.Sprintf("must not be %s with zones", v1alpha1.ReplicasMismatchPolicyAdopt)))
+	}
+	return allErrs
+}
+
+// validateStartScript validates the start script is mounted at a dedicated directory with a valid file mode
+func validateStartScript(spec *v1alpha1.TiKVSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if p := spec.StartScriptPath; p != "" {
+		pathPath := fldPath.Child("startScriptPath")
+		if !path.IsAbs(p) || path.Clean(p) != p {
+			allErrs = append(allErrs, field.Invalid(pathPath, p, "must be a clean absolute path"))
+		} else {
+			switch path.Dir(p) {
+			case "/", "/var/lib/tikv", "/etc/tikv", "/etc/podinfo":
+				allErrs = append(allErrs, field.Invalid(pathPath, p, "directory of the start script is reserved"))
+			}
+		}
+	}
+	if mode := spec.StartScriptMode; mode != nil && (*mode < 0 || *mode > 0777) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("startScriptMode"), *mode, "must be a file mode between 0 and 0777"))
+	}
 	return allErrs
 }
 
@@ -68,6 +253,147 @@ func validateComponentSpec(spec *v1alpha1.ComponentSpec, fldPath *field.Path) fi
 	allErrs := field.ErrorList{}
 	// TODO validate other fields
 	allErrs = append(allErrs, validateEnv(spec.Env, fldPath.Child("env"))...)
+	if spec.TopologyKey != nil {
+		allErrs = append(allErrs, validateTopologyKey(*spec.TopologyKey, fldPath.Child("topologyKey"))...)
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabels(spec.Labels, fldPath.Child("labels"))...)
+	if spec.SchedulerName != nil {
+		allErrs = append(allErrs, validateSchedulerName(*spec.SchedulerName, fldPath.Child("schedulerName"))...)
+	}
+	return allErrs
+}
+
+// validateSchedulerName validates the scheduler name is a valid DNS label if present
+func validateSchedulerName(name string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if name == "" {
+		return allErrs
+	}
+	for _, msg := range validation.IsDNS1123Label(name) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, msg))
+	}
+	return allErrs
+}
+
+// validateTimezone validates the time zone is a name of the IANA time zone database if present, e.g. Asia/Shanghai
+func validateTimezone(tz string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if tz == "" {
+		return allErrs
+	}
+	// Local is the time zone of the operator rather than a name of the time zone database
+	if _, err := time.LoadLocation(tz); err != nil || tz == "Local" {
+		allErrs = append(allErrs, field.Invalid(fldPath, tz, "must be a time zone name of the IANA time zone database, e.g. UTC or Asia/Shanghai"))
+	}
+	return allErrs
+}
+
+// validateTopologyKey validates the topology key is a valid node label key if present
+func validateTopologyKey(key string, fldPath *field.Path) field.ErrorList {
+	if key == "" {
+		return nil
+	}
+	return metav1validation.ValidateLabelName(key, fldPath)
+}
+
+// validateListenersConfig validates the names and the security protocols of the external listeners,
+// the names are part of the names of the external services so they must be valid DNS labels
+func validateListenersConfig(config *v1alpha1.ListenersConfig, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, listener := range config.ExternalListeners {
+		idxPath := fldPath.Child("externalListeners").Index(i)
+		if listener.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "the name of the external listener must be specified"))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(listener.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), listener.Name, msg))
+			}
+		}
+		if !listener.Type.IsValid() {
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("type"), listener.Type, []string{
+				string(v1alpha1.SecurityProtocolPlaintext),
+				string(v1alpha1.SecurityProtocolSSL),
+				string(v1alpha1.SecurityProtocolSaslPlaintext),
+				string(v1alpha1.SecurityProtocolSaslSSL),
+			}))
+			continue
+		}
+		if listener.Type.IsSSL() && listener.TLSSecretName == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("tlsSecretName"),
+				fmt.Sprintf("tls secret must be referenced when security protocol is %s", listener.Type)))
+		}
+	}
+	return allErrs
+}
+
+// validateListenerPorts validates that the external listeners do not conflict with each other, i.e. they must have
+// distinct names and container ports, and the node ports allocated to the pods must not overlap
+func validateListenerPorts(config *v1alpha1.ListenersConfig, replicas int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := map[string]bool{}
+	ports := map[int32]bool{}
+	nodePorts := map[int32]string{}
+	for i, listener := range config.ExternalListeners {
+		idxPath := fldPath.Child("externalListeners").Index(i)
+		if names[listener.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), listener.Name))
+		}
+		names[listener.Name] = true
+		if ports[listener.ContainerPort] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("containerPort"), listener.ContainerPort))
+		}
+		ports[listener.ContainerPort] = true
+		if listener.GetAccessMethod() != corev1.ServiceTypeNodePort || listener.ExternalStartingPort <= 0 {
+			continue
+		}
+		for j := int32(0); j < replicas; j++ {
+			port := listener.ExternalStartingPort + j
+			if name, ok := nodePorts[port]; ok {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("externalStartingPort"), listener.ExternalStartingPort,
+					fmt.Sprintf("node port %d conflicts with external listener %s", port, name)))
+				break
+			}
+			nodePorts[port] = listener.Name
+		}
+	}
+	return allErrs
+}
+
+// validateConfig validates that the config can be rendered to the TOML file of the component
+func validateConfig(config interface{}, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if err := toml.NewEncoder(ioutil.Discard).Encode(config); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", fmt.Sprintf("cannot be rendered as TOML: %v", err)))
+	}
+	return allErrs
+}
+
+// validatePDReplicas validates that the PD cluster is not scaled in below the quorum of the current members at once,
+// which makes PD lose the majority and unavailable
+func validatePDReplicas(old, replicas int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if quorum := old/2 + 1; replicas < quorum {
+		allErrs = append(allErrs, field.Invalid(fldPath, replicas,
+			fmt.Sprintf("must not be less than %d, the quorum of the current %d members", quorum, old)))
+	}
+	return allErrs
+}
+
+// validateLeaderEvictionParallelism validates fewer pods than the replicas evict their leaders at the same time,
+// so that at least one pod is left holding the leaders
+func validateLeaderEvictionParallelism(spec *v1alpha1.TiKVSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	parallelism := spec.LeaderEvictionParallelism
+	if parallelism == nil {
+		return allErrs
+	}
+	max := spec.Replicas - 1
+	if max < 1 {
+		max = 1
+	}
+	if *parallelism < 1 || *parallelism > max {
+		allErrs = append(allErrs, field.Invalid(fldPath, *parallelism, fmt.Sprintf("must be between 1 and %d", max)))
+	}
 	return allErrs
 }
 
@@ -80,6 +406,18 @@ func validateRequestsStorage(requests corev1.ResourceList, fldPath *field.Path)
 	return allErrs
 }
 
+// validateStorageCapacity validates the capacity advertised is positive and fits in the storage requested
+func validateStorageCapacity(capacity resource.Quantity, requests corev1.ResourceList, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if capacity.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, capacity.String(), "must be greater than 0"))
+	}
+	if request, ok := requests[corev1.ResourceStorage]; ok && capacity.Cmp(request) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, capacity.String(), fmt.Sprintf("must not exceed the storage request %s", request.String())))
+	}
+	return allErrs
+}
+
 // validateEnv validates env vars
 func validateEnv(vars []corev1.EnvVar, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
@@ -187,7 +525,11 @@ func ValidateUpdateTikvCluster(old, tc *v1alpha1.TikvCluster) field.ErrorList {
 	// basic validation
 	allErrs = append(allErrs, ValidateTikvCluster(tc)...)
 	allErrs = append(allErrs, validateUpdatePDConfig(old.Spec.PD.Config, tc.Spec.PD.Config, field.NewPath("spec.pd.config"))...)
+	allErrs = append(allErrs, validatePDReplicas(old.Spec.PD.Replicas, tc.Spec.PD.Replicas, field.NewPath("spec", "pd", "replicas"))...)
 	allErrs = append(allErrs, disallowUsingLegacyAPIInNewCluster(old, tc)...)
+	allErrs = append(allErrs, disallowDisablingTLSCluster(old, tc)...)
+	allErrs = append(allErrs, disallowChangingTiKVPeerServiceName(old, tc)...)
+	allErrs = append(allErrs, disallowRemovingDerivedTiKVConfig(old, tc)...)
 
 	return allErrs
 }
@@ -213,6 +555,50 @@ func validateNewTikvClusterSpec(spec *v1alpha1.TikvClusterSpec, path *field.Path
 	return allErrs
 }
 
+// disallowDisablingTLSCluster rejects disabling the TLS of a running cluster, the pods rolled to the plaintext
+// config one by one can not talk to the peers still serving TLS in the transition
+func disallowDisablingTLSCluster(old, tc *v1alpha1.TikvCluster) field.ErrorList {
+	allErrs := field.ErrorList{}
+	running := len(old.Status.PD.Members) > 0 || len(old.Status.TiKV.Stores) > 0
+	if old.IsTLSClusterEnabled() && !tc.IsTLSClusterEnabled() && running {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "tlsCluster", "enabled"),
+			"TLS can not be disabled on a running cluster as the pods rolled to plaintext can not talk to the TLS peers, "+
+				"back up the data and restore it to a new cluster without TLS instead"))
+	}
+	return allErrs
+}
+
+// disallowChangingTiKVPeerServiceName rejects changing the peer service name of TiKV, the ServiceName of a
+// statefulset is immutable and the stores advertise their addresses in the domain of the service
+func disallowChangingTiKVPeerServiceName(old, tc *v1alpha1.TikvCluster) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if old.TiKVPeerServiceName() != tc.TiKVPeerServiceName() {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "tikv", "peerServiceName"),
+			fmt.Sprintf("peer service name can not be changed from %s to %s", old.TiKVPeerServiceName(), tc.TiKVPeerServiceName())))
+	}
+	return allErrs
+}
+
+// disallowRemovingDerivedTiKVConfig rejects removing the sizing profile or the reserve space percent the config of
+// TiKV is derived from while the config is not set, the ConfigMap of TiKV would be dropped and all the pods
+// restarted in the legacy mode
+func disallowRemovingDerivedTiKVConfig(old, tc *v1alpha1.TikvCluster) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if old.Spec.TiKV.Config != nil || old.TiKVConfig() == nil || tc.TiKVConfig() != nil {
+		return allErrs
+	}
+	path := field.NewPath("spec", "tikv")
+	if _, sized := old.Spec.TiKV.SizingProfile.NodeSize(); sized {
+		allErrs = append(allErrs, field.Forbidden(path.Child("sizingProfile"),
+			"can not be removed while TiKV.config is nil, set TiKV.config first"))
+	}
+	if _, reserved := old.TiKVReserveSpace(); reserved {
+		allErrs = append(allErrs, field.Forbidden(path.Child("reserveSpacePercent"),
+			"can not be removed while TiKV.config is nil, set TiKV.config first"))
+	}
+	return allErrs
+}
+
 // disallowUsingLegacyAPIInNewCluster checks if user use the legacy API in newly create cluster during update
 // TODO(aylei): this could be removed after we enable validateTikvCluster() in update, which is more strict
 func disallowUsingLegacyAPIInNewCluster(old, tc *v1alpha1.TikvCluster) field.ErrorList {
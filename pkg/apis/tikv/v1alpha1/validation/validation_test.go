@@ -20,6 +20,9 @@ import (
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
 )
 
 func TestValidateRequestsStorage(t *testing.T) {
@@ -69,3 +72,775 @@ func newTikvCluster() *v1alpha1.TikvCluster {
 	tc.Namespace = "default"
 	return tc
 }
+
+func TestValidateListenersConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		listener       v1alpha1.ExternalListenerConfig
+		expectedErrors int
+	}{
+		{
+			name: "plaintext listener",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolPlaintext, Name: "external"},
+			},
+			expectedErrors: 0,
+		},
+		{
+			name: "ssl listener with tls secret",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolSSL, Name: "external"},
+				TLSSecretName:      "external-tls",
+			},
+			expectedErrors: 0,
+		},
+		{
+			name: "sasl_ssl listener without tls secret",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolSaslSSL, Name: "external"},
+			},
+			expectedErrors: 1,
+		},
+		{
+			name: "security protocol not set",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "external"},
+			},
+			expectedErrors: 1,
+		},
+		{
+			name: "unknown security protocol",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: "tls", Name: "external"},
+			},
+			expectedErrors: 1,
+		},
+		{
+			name: "listener without name",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolPlaintext},
+			},
+			expectedErrors: 1,
+		},
+		{
+			name: "listener name is not a DNS label",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolPlaintext, Name: "External_1"},
+			},
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &v1alpha1.ListenersConfig{
+				ExternalListeners: []v1alpha1.ExternalListenerConfig{tt.listener},
+			}
+			err := validateListenersConfig(config, field.NewPath("spec", "tikv", "listenersConfig"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateListenerPorts(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		listeners      []v1alpha1.ExternalListenerConfig
+		expectedErrors int
+	}{
+		{
+			name: "distinct listeners",
+			listeners: []v1alpha1.ExternalListenerConfig{
+				{CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "a", ContainerPort: 20170}, AccessMethod: corev1.ServiceTypeNodePort, ExternalStartingPort: 30000},
+				{CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "b", ContainerPort: 20171}, AccessMethod: corev1.ServiceTypeNodePort, ExternalStartingPort: 30003},
+			},
+			expectedErrors: 0,
+		},
+		{
+			name: "duplicated names and container ports",
+			listeners: []v1alpha1.ExternalListenerConfig{
+				{CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "a", ContainerPort: 20170}},
+				{CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "a", ContainerPort: 20170}},
+			},
+			expectedErrors: 2,
+		},
+		{
+			name: "overlapping node ports",
+			listeners: []v1alpha1.ExternalListenerConfig{
+				{CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "a", ContainerPort: 20170}, AccessMethod: corev1.ServiceTypeNodePort, ExternalStartingPort: 30000},
+				{CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "b", ContainerPort: 20171}, AccessMethod: corev1.ServiceTypeNodePort, ExternalStartingPort: 30002},
+			},
+			expectedErrors: 1,
+		},
+		{
+			name: "dynamic node ports",
+			listeners: []v1alpha1.ExternalListenerConfig{
+				{CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "a", ContainerPort: 20170}, AccessMethod: corev1.ServiceTypeNodePort},
+				{CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "b", ContainerPort: 20171}, AccessMethod: corev1.ServiceTypeNodePort},
+			},
+			expectedErrors: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &v1alpha1.ListenersConfig{ExternalListeners: tt.listeners}
+			err := validateListenerPorts(config, 3, field.NewPath("spec", "tikv", "listenersConfig"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidatePDReplicas(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		old            int32
+		replicas       int32
+		expectedErrors int
+	}{
+		{
+			name:           "scale out",
+			old:            3,
+			replicas:       5,
+			expectedErrors: 0,
+		},
+		{
+			name:           "scale in to the quorum",
+			old:            5,
+			replicas:       3,
+			expectedErrors: 0,
+		},
+		{
+			name:           "scale in below the quorum",
+			old:            3,
+			replicas:       1,
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePDReplicas(tt.old, tt.replicas, field.NewPath("spec", "pd", "replicas"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateLeaderEvictionParallelism(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		replicas       int32
+		parallelism    *int32
+		expectedErrors int
+	}{
+		{
+			name:           "not set",
+			replicas:       1,
+			expectedErrors: 0,
+		},
+		{
+			name:           "fewer than the replicas",
+			replicas:       5,
+			parallelism:    pointer.Int32Ptr(4),
+			expectedErrors: 0,
+		},
+		{
+			name:           "one pod of a single replica",
+			replicas:       1,
+			parallelism:    pointer.Int32Ptr(1),
+			expectedErrors: 0,
+		},
+		{
+			name:           "as many as the replicas",
+			replicas:       3,
+			parallelism:    pointer.Int32Ptr(3),
+			expectedErrors: 1,
+		},
+		{
+			name:           "zero",
+			replicas:       3,
+			parallelism:    pointer.Int32Ptr(0),
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &v1alpha1.TiKVSpec{Replicas: tt.replicas, LeaderEvictionParallelism: tt.parallelism}
+			err := validateLeaderEvictionParallelism(spec, field.NewPath("spec", "tikv", "leaderEvictionParallelism"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateTopologyKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		key            string
+		expectedErrors int
+	}{
+		{
+			name:           "empty",
+			key:            "",
+			expectedErrors: 0,
+		},
+		{
+			name:           "well-known label",
+			key:            "topology.kubernetes.io/zone",
+			expectedErrors: 0,
+		},
+		{
+			name:           "invalid label",
+			key:            "-zone",
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTopologyKey(tt.key, field.NewPath("spec", "topologyKey"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateSchedulerName(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		schedulerName  string
+		expectedErrors int
+	}{
+		{
+			name:           "empty",
+			schedulerName:  "",
+			expectedErrors: 0,
+		},
+		{
+			name:           "custom scheduler",
+			schedulerName:  "tikv-scheduler",
+			expectedErrors: 0,
+		},
+		{
+			name:           "invalid DNS label",
+			schedulerName:  "TiKV_Scheduler",
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedulerName(tt.schedulerName, field.NewPath("spec", "schedulerName"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateConfigFiles(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		files          map[string]string
+		expectedErrors int
+	}{
+		{
+			name:           "empty",
+			expectedErrors: 0,
+		},
+		{
+			name:           "valid file names",
+			files:          map[string]string{"pd.toml": "", "ca.crt": ""},
+			expectedErrors: 0,
+		},
+		{
+			name:           "invalid file name",
+			files:          map[string]string{"tls/ca.crt": ""},
+			expectedErrors: 1,
+		},
+		{
+			name:           "reserved file names",
+			files:          map[string]string{"tikv.toml": "", "startup-script": ""},
+			expectedErrors: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfigFiles(tt.files, field.NewPath("spec", "tikv", "configFiles"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateZones(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		zones          []v1alpha1.TiKVZone
+		expectedErrors int
+	}{
+		{
+			name:           "empty",
+			expectedErrors: 0,
+		},
+		{
+			name:           "valid zones",
+			zones:          []v1alpha1.TiKVZone{{Name: "us-west-2a", Replicas: 1}, {Name: "us-west-2b", Replicas: 0}},
+			expectedErrors: 0,
+		},
+		{
+			name:           "empty zone name",
+			zones:          []v1alpha1.TiKVZone{{Name: "", Replicas: 1}},
+			expectedErrors: 1,
+		},
+		{
+			name:           "duplicate zone names",
+			zones:          []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}, {Name: "a", Replicas: 1}},
+			expectedErrors: 1,
+		},
+		{
+			name:           "invalid zone name and negative replicas",
+			zones:          []v1alpha1.TiKVZone{{Name: "us/west", Replicas: -1}},
+			expectedErrors: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateZones(tt.zones, field.NewPath("spec", "tikv", "zones"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateZoneReplicas(t *testing.T) {
+	g := NewGomegaWithT(t)
+	zones := []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}}
+	tests := []struct {
+		name           string
+		spec           v1alpha1.TiKVSpec
+		expectedErrors int
+	}{
+		{
+			name:           "autoscaling without zones",
+			spec:           v1alpha1.TiKVSpec{AutoScaling: &v1alpha1.TiKVAutoScalingSpec{}, ReplicasMismatchPolicy: v1alpha1.ReplicasMismatchPolicyAdopt},
+			expectedErrors: 0,
+		},
+		{
+			name:           "zones",
+			spec:           v1alpha1.TiKVSpec{Zones: zones, ReplicasMismatchPolicy: v1alpha1.ReplicasMismatchPolicyRevert},
+			expectedErrors: 0,
+		},
+		{
+			name:           "autoscaling with zones",
+			spec:           v1alpha1.TiKVSpec{Zones: zones, AutoScaling: &v1alpha1.TiKVAutoScalingSpec{}},
+			expectedErrors: 1,
+		},
+		{
+			name:           "adopt with zones",
+			spec:           v1alpha1.TiKVSpec{Zones: zones, ReplicasMismatchPolicy: v1alpha1.ReplicasMismatchPolicyAdopt},
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateZoneReplicas(&tt.spec, field.NewPath("spec", "tikv"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateStartScript(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mode := func(m int32) *int32 { return &m }
+	tests := []struct {
+		name           string
+		path           string
+		mode           *int32
+		expectedErrors int
+	}{
+		{
+			name:           "empty",
+			expectedErrors: 0,
+		},
+		{
+			name:           "valid path and mode",
+			path:           "/opt/tikv/start.sh",
+			mode:           mode(0755),
+			expectedErrors: 0,
+		},
+		{
+			name:           "relative path",
+			path:           "bin/start.sh",
+			expectedErrors: 1,
+		},
+		{
+			name:           "unclean path",
+			path:           "/opt/tikv/../start.sh",
+			expectedErrors: 1,
+		},
+		{
+			name:           "reserved directories",
+			path:           "/etc/tikv/start.sh",
+			expectedErrors: 1,
+		},
+		{
+			name:           "script in the root directory and invalid mode",
+			path:           "/start.sh",
+			mode:           mode(01777),
+			expectedErrors: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &v1alpha1.TiKVSpec{StartScriptPath: tt.path, StartScriptMode: tt.mode}
+			err := validateStartScript(spec, field.NewPath("spec", "tikv"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateTimezone(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		tz             string
+		expectedErrors int
+	}{
+		{
+			name:           "empty",
+			expectedErrors: 0,
+		},
+		{
+			name:           "UTC",
+			tz:             "UTC",
+			expectedErrors: 0,
+		},
+		{
+			name:           "location name",
+			tz:             "Asia/Shanghai",
+			expectedErrors: 0,
+		},
+		{
+			name:           "unknown location name",
+			tz:             "Mars/Olympus_Mons",
+			expectedErrors: 1,
+		},
+		{
+			name:           "offset",
+			tz:             "+08:00",
+			expectedErrors: 1,
+		},
+		{
+			name:           "time zone of the operator",
+			tz:             "Local",
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTimezone(tt.tz, field.NewPath("spec", "timezone"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestDisallowDisablingTLSCluster(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		oldTLS         *v1alpha1.TLSCluster
+		tls            *v1alpha1.TLSCluster
+		running        bool
+		expectedErrors int
+	}{
+		{
+			name:           "enable tls",
+			tls:            &v1alpha1.TLSCluster{Enabled: true},
+			running:        true,
+			expectedErrors: 0,
+		},
+		{
+			name:           "keep tls enabled",
+			oldTLS:         &v1alpha1.TLSCluster{Enabled: true},
+			tls:            &v1alpha1.TLSCluster{Enabled: true, SecretName: "tikv-tls"},
+			running:        true,
+			expectedErrors: 0,
+		},
+		{
+			name:           "disable tls on a running cluster",
+			oldTLS:         &v1alpha1.TLSCluster{Enabled: true},
+			tls:            &v1alpha1.TLSCluster{Enabled: false},
+			running:        true,
+			expectedErrors: 1,
+		},
+		{
+			name:           "remove tls on a running cluster",
+			oldTLS:         &v1alpha1.TLSCluster{Enabled: true},
+			running:        true,
+			expectedErrors: 1,
+		},
+		{
+			name:           "disable tls before the cluster runs",
+			oldTLS:         &v1alpha1.TLSCluster{Enabled: true},
+			expectedErrors: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &v1alpha1.TikvCluster{Spec: v1alpha1.TikvClusterSpec{TLSCluster: tt.oldTLS}}
+			if tt.running {
+				old.Status.PD.Members = map[string]v1alpha1.PDMember{"test-pd-0": {Name: "test-pd-0", Health: true}}
+			}
+			tc := &v1alpha1.TikvCluster{Spec: v1alpha1.TikvClusterSpec{TLSCluster: tt.tls}}
+			err := disallowDisablingTLSCluster(old, tc)
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestDisallowChangingTiKVPeerServiceName(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		oldName        string
+		newName        string
+		expectedErrors int
+	}{
+		{
+			name:           "keep the default",
+			expectedErrors: 0,
+		},
+		{
+			name:           "set to the default",
+			newName:        "test-tikv-peer",
+			expectedErrors: 0,
+		},
+		{
+			name:           "override the default",
+			newName:        "tikv-dns",
+			expectedErrors: 1,
+		},
+		{
+			name:           "change the override",
+			oldName:        "tikv-dns",
+			newName:        "tikv-dns-2",
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &v1alpha1.TikvCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			old.Spec.TiKV.PeerServiceName = tt.oldName
+			tc := old.DeepCopy()
+			tc.Spec.TiKV.PeerServiceName = tt.newName
+			err := disallowChangingTiKVPeerServiceName(old, tc)
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestDisallowRemovingDerivedTiKVConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		update         func(old, tc *v1alpha1.TikvCluster)
+		expectedErrors int
+	}{
+		{
+			name: "keep the sizing profile",
+			update: func(old, tc *v1alpha1.TikvCluster) {
+				old.Spec.TiKV.SizingProfile = v1alpha1.SizingProfileSmall
+				tc.Spec.TiKV.SizingProfile = v1alpha1.SizingProfileMedium
+			},
+			expectedErrors: 0,
+		},
+		{
+			name: "remove the sizing profile with the config set",
+			update: func(old, tc *v1alpha1.TikvCluster) {
+				old.Spec.TiKV.SizingProfile = v1alpha1.SizingProfileSmall
+				tc.Spec.TiKV.Config = &v1alpha1.TiKVConfig{}
+			},
+			expectedErrors: 0,
+		},
+		{
+			name: "remove the sizing profile without the config",
+			update: func(old, tc *v1alpha1.TikvCluster) {
+				old.Spec.TiKV.SizingProfile = v1alpha1.SizingProfileSmall
+			},
+			expectedErrors: 1,
+		},
+		{
+			name: "remove the reserve space percent without the config",
+			update: func(old, tc *v1alpha1.TikvCluster) {
+				old.Spec.TiKV.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")}
+				old.Spec.TiKV.ReserveSpacePercent = pointer.Int32Ptr(10)
+				tc.Spec.TiKV.Requests = old.Spec.TiKV.Requests
+			},
+			expectedErrors: 1,
+		},
+		{
+			name: "keep the reserve space percent without the config",
+			update: func(old, tc *v1alpha1.TikvCluster) {
+				old.Spec.TiKV.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")}
+				old.Spec.TiKV.ReserveSpacePercent = pointer.Int32Ptr(10)
+				tc.Spec.TiKV.Requests = old.Spec.TiKV.Requests
+				tc.Spec.TiKV.ReserveSpacePercent = pointer.Int32Ptr(20)
+			},
+			expectedErrors: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &v1alpha1.TikvCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			tc := old.DeepCopy()
+			tt.update(old, tc)
+			err := disallowRemovingDerivedTiKVConfig(old, tc)
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateBlockCacheCapacity(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		memoryLimit    string
+		sizingProfile  v1alpha1.SizingProfile
+		capacity       string
+		expectedErrors int
+	}{
+		{
+			name:           "no memory limit",
+			capacity:       "64GB",
+			expectedErrors: 0,
+		},
+		{
+			name:           "room left for the page cache",
+			memoryLimit:    "16Gi",
+			capacity:       "7GB",
+			expectedErrors: 0,
+		},
+		{
+			name:           "fractional size",
+			memoryLimit:    "16Gi",
+			capacity:       "9.5GiB",
+			expectedErrors: 0,
+		},
+		{
+			name:           "no room left for the page cache",
+			memoryLimit:    "16Gi",
+			capacity:       "12GB",
+			expectedErrors: 1,
+		},
+		{
+			name:           "invalid size",
+			memoryLimit:    "16Gi",
+			capacity:       "lots",
+			expectedErrors: 1,
+		},
+		{
+			name:           "no room left within the memory limit of the sizing profile",
+			sizingProfile:  v1alpha1.SizingProfileSmall,
+			capacity:       "5GB",
+			expectedErrors: 1,
+		},
+		{
+			name:           "memory limit set explicitly over the sizing profile",
+			memoryLimit:    "16Gi",
+			sizingProfile:  v1alpha1.SizingProfileSmall,
+			capacity:       "5GB",
+			expectedErrors: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &v1alpha1.TiKVSpec{Config: &v1alpha1.TiKVConfig{
+				Storage: &v1alpha1.TiKVStorageConfig{BlockCache: &v1alpha1.TiKVBlockCacheConfig{Capacity: &tt.capacity}},
+			}, SizingProfile: tt.sizingProfile}
+			if tt.memoryLimit != "" {
+				spec.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(tt.memoryLimit)}
+			}
+			err := validateBlockCacheCapacity(spec, field.NewPath("spec", "tikv"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateCordonedStores(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		ids            []string
+		expectedErrors int
+	}{
+		{
+			name:           "store ids",
+			ids:            []string{"1", "4"},
+			expectedErrors: 0,
+		},
+		{
+			name:           "not a store id",
+			ids:            []string{"1", "test-tikv-0"},
+			expectedErrors: 1,
+		},
+		{
+			name:           "duplicate store id",
+			ids:            []string{"1", "1"},
+			expectedErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCordonedStores(tt.ids, field.NewPath("spec", "tikv", "cordonedStores"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
+
+func TestValidateStorageCapacity(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		capacity       string
+		request        string
+		expectedErrors int
+	}{
+		{
+			name:           "headroom reserved",
+			capacity:       "90Gi",
+			request:        "100Gi",
+			expectedErrors: 0,
+		},
+		{
+			name:           "equal to the request",
+			capacity:       "100Gi",
+			request:        "100Gi",
+			expectedErrors: 0,
+		},
+		{
+			name:           "exceed the request",
+			capacity:       "120Gi",
+			request:        "100Gi",
+			expectedErrors: 1,
+		},
+		{
+			name:           "zero",
+			capacity:       "0",
+			request:        "100Gi",
+			expectedErrors: 1,
+		},
+		{
+			name:           "no request",
+			capacity:       "100Gi",
+			expectedErrors: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := corev1.ResourceList{}
+			if tt.request != "" {
+				requests[corev1.ResourceStorage] = resource.MustParse(tt.request)
+			}
+			err := validateStorageCapacity(resource.MustParse(tt.capacity), requests, field.NewPath("spec", "tikv", "storageCapacity"))
+			g.Expect(len(err)).Should(Equal(tt.expectedErrors))
+		})
+	}
+}
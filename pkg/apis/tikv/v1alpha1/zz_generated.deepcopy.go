@@ -21,9 +21,65 @@ package v1alpha1
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoScalingThreshold) DeepCopyInto(out *AutoScalingThreshold) {
+	*out = *in
+	if in.ScaleIn != nil {
+		in, out := &in.ScaleIn, &out.ScaleIn
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoScalingThreshold.
+func (in *AutoScalingThreshold) DeepCopy() *AutoScalingThreshold {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoScalingThreshold)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BRConfig) DeepCopyInto(out *BRConfig) {
+	*out = *in
+	if in.Concurrency != nil {
+		in, out := &in.Concurrency, &out.Concurrency
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.Checksum != nil {
+		in, out := &in.Checksum, &out.Checksum
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BRConfig.
+func (in *BRConfig) DeepCopy() *BRConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BRConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CommonListenerSpec) DeepCopyInto(out *CommonListenerSpec) {
 	*out = *in
@@ -62,6 +118,16 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 		*out = new(v1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TopologyKey != nil {
+		in, out := &in.TopologyKey, &out.TopologyKey
+		*out = new(string)
+		**out = **in
+	}
+	if in.TopologyAffinityPolicy != nil {
+		in, out := &in.TopologyAffinityPolicy, &out.TopologyAffinityPolicy
+		*out = new(TopologyAffinityPolicy)
+		**out = **in
+	}
 	if in.PriorityClassName != nil {
 		in, out := &in.PriorityClassName, &out.PriorityClassName
 		*out = new(string)
@@ -86,6 +152,13 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
 		*out = make([]v1.Toleration, len(*in))
@@ -103,6 +176,11 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 		*out = new(ConfigUpdateStrategy)
 		**out = **in
 	}
+	if in.PrometheusAnnotations != nil {
+		in, out := &in.PrometheusAnnotations, &out.PrometheusAnnotations
+		*out = new(PrometheusAnnotations)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make([]v1.EnvVar, len(*in))
@@ -110,6 +188,13 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentSpec.
@@ -321,6 +406,43 @@ func (in *MasterKeyKMSConfig) DeepCopy() *MasterKeyKMSConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeSize) DeepCopyInto(out *NodeSize) {
+	*out = *in
+	out.CPU = in.CPU.DeepCopy()
+	out.Memory = in.Memory.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeSize.
+func (in *NodeSize) DeepCopy() *NodeSize {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeSize)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationWebhook) DeepCopyInto(out *NotificationWebhook) {
+	*out = *in
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationWebhook.
+func (in *NotificationWebhook) DeepCopy() *NotificationWebhook {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationWebhook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PDConfig) DeepCopyInto(out *PDConfig) {
 	*out = *in
@@ -571,6 +693,41 @@ func (in *PDLogConfig) DeepCopy() *PDLogConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PDManagedConfig) DeepCopyInto(out *PDManagedConfig) {
+	*out = *in
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LocationLabels != nil {
+		in, out := &in.LocationLabels, &out.LocationLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LeaderScheduleLimit != nil {
+		in, out := &in.LeaderScheduleLimit, &out.LeaderScheduleLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RegionScheduleLimit != nil {
+		in, out := &in.RegionScheduleLimit, &out.RegionScheduleLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDManagedConfig.
+func (in *PDManagedConfig) DeepCopy() *PDManagedConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PDManagedConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PDMember) DeepCopyInto(out *PDMember) {
 	*out = *in
@@ -997,6 +1154,11 @@ func (in *PDSpec) DeepCopyInto(out *PDSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.ManagedConfig != nil {
+		in, out := &in.ManagedConfig, &out.ManagedConfig
+		*out = new(PDManagedConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDSpec.
@@ -1097,6 +1259,120 @@ func (in PDStoreLabels) DeepCopy() PDStoreLabels {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetSpec) DeepCopyInto(out *PodDisruptionBudgetSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetSpec.
+func (in *PodDisruptionBudgetSpec) DeepCopy() *PodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusAnnotations) DeepCopyInto(out *PrometheusAnnotations) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusAnnotations.
+func (in *PrometheusAnnotations) DeepCopy() *PrometheusAnnotations {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusAnnotations)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelabelConfig) DeepCopyInto(out *RelabelConfig) {
+	*out = *in
+	if in.SourceLabels != nil {
+		in, out := &in.SourceLabels, &out.SourceLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelabelConfig.
+func (in *RelabelConfig) DeepCopy() *RelabelConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RelabelConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3StorageProvider) DeepCopyInto(out *S3StorageProvider) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3StorageProvider.
+func (in *S3StorageProvider) DeepCopy() *S3StorageProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(S3StorageProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMonitorSpec) DeepCopyInto(out *ServiceMonitorSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Relabelings != nil {
+		in, out := &in.Relabelings, &out.Relabelings
+		*out = make([]RelabelConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMonitorSpec.
+func (in *ServiceMonitorSpec) DeepCopy() *ServiceMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceSpec) DeepCopyInto(out *ServiceSpec) {
 	*out = *in
@@ -1139,6 +1415,86 @@ func (in *ServiceSpec) DeepCopy() *ServiceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageProvider) DeepCopyInto(out *StorageProvider) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3StorageProvider)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageProvider.
+func (in *StorageProvider) DeepCopy() *StorageProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSCluster) DeepCopyInto(out *TLSCluster) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSCluster.
+func (in *TLSCluster) DeepCopy() *TLSCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVAutoScalingSpec) DeepCopyInto(out *TiKVAutoScalingSpec) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.StorageUtilizationThreshold != nil {
+		in, out := &in.StorageUtilizationThreshold, &out.StorageUtilizationThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RegionCount != nil {
+		in, out := &in.RegionCount, &out.RegionCount
+		*out = new(AutoScalingThreshold)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CPUUtilization != nil {
+		in, out := &in.CPUUtilization, &out.CPUUtilization
+		*out = new(AutoScalingThreshold)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleOutIntervalSeconds != nil {
+		in, out := &in.ScaleOutIntervalSeconds, &out.ScaleOutIntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleInIntervalSeconds != nil {
+		in, out := &in.ScaleInIntervalSeconds, &out.ScaleInIntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVAutoScalingSpec.
+func (in *TiKVAutoScalingSpec) DeepCopy() *TiKVAutoScalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVAutoScalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiKVBlockCacheConfig) DeepCopyInto(out *TiKVBlockCacheConfig) {
 	*out = *in
@@ -1370,12 +1726,27 @@ func (in *TiKVCfConfig) DeepCopy() *TiKVCfConfig {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *TiKVConfig) DeepCopyInto(out *TiKVConfig) {
+func (in *TiKVCloneSource) DeepCopyInto(out *TiKVCloneSource) {
 	*out = *in
-	if in.LogLevel != nil {
-		in, out := &in.LogLevel, &out.LogLevel
-		*out = new(string)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVCloneSource.
+func (in *TiKVCloneSource) DeepCopy() *TiKVCloneSource {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVCloneSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVConfig) DeepCopyInto(out *TiKVConfig) {
+	*out = *in
+	if in.LogLevel != nil {
+		in, out := &in.LogLevel, &out.LogLevel
+		*out = new(string)
+		**out = **in
 	}
 	if in.LogFile != nil {
 		in, out := &in.LogFile, &out.LogFile
@@ -1774,6 +2145,26 @@ func (in *TiKVEncryptionConfig) DeepCopy() *TiKVEncryptionConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVFailoverRecord) DeepCopyInto(out *TiKVFailoverRecord) {
+	*out = *in
+	in.CreatedAt.DeepCopyInto(&out.CreatedAt)
+	if in.RecoveredAt != nil {
+		in, out := &in.RecoveredAt, &out.RecoveredAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVFailoverRecord.
+func (in *TiKVFailoverRecord) DeepCopy() *TiKVFailoverRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVFailoverRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiKVFailureStore) DeepCopyInto(out *TiKVFailureStore) {
 	*out = *in
@@ -2382,6 +2773,64 @@ func (in *TiKVReadPoolConfig) DeepCopy() *TiKVReadPoolConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVRebalanceStatus) DeepCopyInto(out *TiKVRebalanceStatus) {
+	*out = *in
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OriginalRegionScheduleLimit != nil {
+		in, out := &in.OriginalRegionScheduleLimit, &out.OriginalRegionScheduleLimit
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVRebalanceStatus.
+func (in *TiKVRebalanceStatus) DeepCopy() *TiKVRebalanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVRebalanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVScaleOutRebalance) DeepCopyInto(out *TiKVScaleOutRebalance) {
+	*out = *in
+	if in.RegionScheduleLimit != nil {
+		in, out := &in.RegionScheduleLimit, &out.RegionScheduleLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVScaleOutRebalance.
+func (in *TiKVScaleOutRebalance) DeepCopy() *TiKVScaleOutRebalance {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVScaleOutRebalance)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiKVSecurityConfig) DeepCopyInto(out *TiKVSecurityConfig) {
 	*out = *in
@@ -2668,22 +3117,128 @@ func (in *TiKVSpec) DeepCopyInto(out *TiKVSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ContainerSecurityContext != nil {
+		in, out := &in.ContainerSecurityContext, &out.ContainerSecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.MaxFailoverCount != nil {
 		in, out := &in.MaxFailoverCount, &out.MaxFailoverCount
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]TiKVZone, len(*in))
+		copy(*out, *in)
+	}
+	if in.StaleHeartbeatThreshold != nil {
+		in, out := &in.StaleHeartbeatThreshold, &out.StaleHeartbeatThreshold
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.StorageClassName != nil {
 		in, out := &in.StorageClassName, &out.StorageClassName
 		*out = new(string)
 		**out = **in
 	}
+	if in.StorageCapacity != nil {
+		in, out := &in.StorageCapacity, &out.StorageCapacity
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.ReserveSpacePercent != nil {
+		in, out := &in.ReserveSpacePercent, &out.ReserveSpacePercent
+		*out = new(int32)
+		**out = **in
+	}
 	if in.Config != nil {
 		in, out := &in.Config, &out.Config
 		*out = new(TiKVConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ConfigFiles != nil {
+		in, out := &in.ConfigFiles, &out.ConfigFiles
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.StartScriptMode != nil {
+		in, out := &in.StartScriptMode, &out.StartScriptMode
+		*out = new(int32)
+		**out = **in
+	}
 	in.ListenersConfig.DeepCopyInto(&out.ListenersConfig)
+	if in.PeerServiceAnnotations != nil {
+		in, out := &in.PeerServiceAnnotations, &out.PeerServiceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.StatusServiceEnabled != nil {
+		in, out := &in.StatusServiceEnabled, &out.StatusServiceEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LeaderEvictionParallelism != nil {
+		in, out := &in.LeaderEvictionParallelism, &out.LeaderEvictionParallelism
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CloneFrom != nil {
+		in, out := &in.CloneFrom, &out.CloneFrom
+		*out = new(TiKVCloneSource)
+		**out = **in
+	}
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(ServiceMonitorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(PodDisruptionBudgetSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AutoScaling != nil {
+		in, out := &in.AutoScaling, &out.AutoScaling
+		*out = new(TiKVAutoScalingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StoreLimit != nil {
+		in, out := &in.StoreLimit, &out.StoreLimit
+		*out = new(TiKVStoreLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StoreWeights != nil {
+		in, out := &in.StoreWeights, &out.StoreWeights
+		*out = make([]TiKVStoreWeight, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CordonedStores != nil {
+		in, out := &in.CordonedStores, &out.CordonedStores
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScaleOutRebalance != nil {
+		in, out := &in.ScaleOutRebalance, &out.ScaleOutRebalance
+		*out = new(TiKVScaleOutRebalance)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MinUpStores != nil {
+		in, out := &in.MinUpStores, &out.MinUpStores
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TombstoneCleanup != nil {
+		in, out := &in.TombstoneCleanup, &out.TombstoneCleanup
+		*out = new(TiKVTombstoneCleanup)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVSpec.
@@ -2725,6 +3280,72 @@ func (in *TiKVStatus) DeepCopyInto(out *TiKVStatus) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.LastAutoScaledTime != nil {
+		in, out := &in.LastAutoScaledTime, &out.LastAutoScaledTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PodRevisions != nil {
+		in, out := &in.PodRevisions, &out.PodRevisions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FailoverHistory != nil {
+		in, out := &in.FailoverHistory, &out.FailoverHistory
+		*out = make([]TiKVFailoverRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeDrainEvictions != nil {
+		in, out := &in.NodeDrainEvictions, &out.NodeDrainEvictions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CordonedStores != nil {
+		in, out := &in.CordonedStores, &out.CordonedStores
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ReregisteredStores != nil {
+		in, out := &in.ReregisteredStores, &out.ReregisteredStores
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MissingAffinityNodeLabels != nil {
+		in, out := &in.MissingAffinityNodeLabels, &out.MissingAffinityNodeLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnpinnedPods != nil {
+		in, out := &in.UnpinnedPods, &out.UnpinnedPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastStoreLabelsSetTime != nil {
+		in, out := &in.LastStoreLabelsSetTime, &out.LastStoreLabelsSetTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ScalingOutPods != nil {
+		in, out := &in.ScalingOutPods, &out.ScalingOutPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rebalance != nil {
+		in, out := &in.Rebalance, &out.Rebalance
+		*out = new(TiKVRebalanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WeightedStores != nil {
+		in, out := &in.WeightedStores, &out.WeightedStores
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVStatus.
@@ -2842,6 +3463,8 @@ func (in *TiKVStore) DeepCopyInto(out *TiKVStore) {
 	*out = *in
 	in.LastHeartbeatTime.DeepCopyInto(&out.LastHeartbeatTime)
 	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	out.Capacity = in.Capacity.DeepCopy()
+	out.Available = in.Available.DeepCopy()
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVStore.
@@ -2854,6 +3477,63 @@ func (in *TiKVStore) DeepCopy() *TiKVStore {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVStoreLimit) DeepCopyInto(out *TiKVStoreLimit) {
+	*out = *in
+	if in.AddPeer != nil {
+		in, out := &in.AddPeer, &out.AddPeer
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RemovePeer != nil {
+		in, out := &in.RemovePeer, &out.RemovePeer
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVStoreLimit.
+func (in *TiKVStoreLimit) DeepCopy() *TiKVStoreLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVStoreLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVStoreWeight) DeepCopyInto(out *TiKVStoreWeight) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LeaderWeight != nil {
+		in, out := &in.LeaderWeight, &out.LeaderWeight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RegionWeight != nil {
+		in, out := &in.RegionWeight, &out.RegionWeight
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVStoreWeight.
+func (in *TiKVStoreWeight) DeepCopy() *TiKVStoreWeight {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVStoreWeight)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiKVTitanCfConfig) DeepCopyInto(out *TiKVTitanCfConfig) {
 	*out = *in
@@ -2964,6 +3644,26 @@ func (in *TiKVTitanDBConfig) DeepCopy() *TiKVTitanDBConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVTombstoneCleanup) DeepCopyInto(out *TiKVTombstoneCleanup) {
+	*out = *in
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVTombstoneCleanup.
+func (in *TiKVTombstoneCleanup) DeepCopy() *TiKVTombstoneCleanup {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVTombstoneCleanup)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiKVUnifiedReadPoolConfig) DeepCopyInto(out *TiKVUnifiedReadPoolConfig) {
 	*out = *in
@@ -2999,6 +3699,178 @@ func (in *TiKVUnifiedReadPoolConfig) DeepCopy() *TiKVUnifiedReadPoolConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVVolumeSnapshot) DeepCopyInto(out *TiKVVolumeSnapshot) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVVolumeSnapshot.
+func (in *TiKVVolumeSnapshot) DeepCopy() *TiKVVolumeSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVVolumeSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVZone) DeepCopyInto(out *TiKVZone) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVZone.
+func (in *TiKVZone) DeepCopy() *TiKVZone {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvBackup) DeepCopyInto(out *TikvBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvBackup.
+func (in *TikvBackup) DeepCopy() *TikvBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TikvBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvBackupCondition) DeepCopyInto(out *TikvBackupCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvBackupCondition.
+func (in *TikvBackupCondition) DeepCopy() *TikvBackupCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvBackupCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvBackupList) DeepCopyInto(out *TikvBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TikvBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvBackupList.
+func (in *TikvBackupList) DeepCopy() *TikvBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TikvBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvBackupSpec) DeepCopyInto(out *TikvBackupSpec) {
+	*out = *in
+	in.StorageProvider.DeepCopyInto(&out.StorageProvider)
+	if in.VolumeSnapshot != nil {
+		in, out := &in.VolumeSnapshot, &out.VolumeSnapshot
+		*out = new(VolumeSnapshotConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BR != nil {
+		in, out := &in.BR, &out.BR
+		*out = new(BRConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvBackupSpec.
+func (in *TikvBackupSpec) DeepCopy() *TikvBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvBackupStatus) DeepCopyInto(out *TikvBackupStatus) {
+	*out = *in
+	if in.TimeStarted != nil {
+		in, out := &in.TimeStarted, &out.TimeStarted
+		*out = (*in).DeepCopy()
+	}
+	if in.TimeCompleted != nil {
+		in, out := &in.TimeCompleted, &out.TimeCompleted
+		*out = (*in).DeepCopy()
+	}
+	if in.VolumeSnapshots != nil {
+		in, out := &in.VolumeSnapshots, &out.VolumeSnapshots
+		*out = make([]TiKVVolumeSnapshot, len(*in))
+		copy(*out, *in)
+	}
+	if in.PausedSchedulers != nil {
+		in, out := &in.PausedSchedulers, &out.PausedSchedulers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]TikvBackupCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvBackupStatus.
+func (in *TikvBackupStatus) DeepCopy() *TikvBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TikvCluster) DeepCopyInto(out *TikvCluster) {
 	*out = *in
@@ -3110,6 +3982,13 @@ func (in *TikvClusterSpec) DeepCopyInto(out *TikvClusterSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
 		*out = make([]v1.Toleration, len(*in))
@@ -3117,6 +3996,33 @@ func (in *TikvClusterSpec) DeepCopyInto(out *TikvClusterSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PodSecurityContext != nil {
+		in, out := &in.PodSecurityContext, &out.PodSecurityContext
+		*out = new(v1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrometheusAnnotations != nil {
+		in, out := &in.PrometheusAnnotations, &out.PrometheusAnnotations
+		*out = new(PrometheusAnnotations)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSCluster != nil {
+		in, out := &in.TLSCluster, &out.TLSCluster
+		*out = new(TLSCluster)
+		**out = **in
+	}
+	if in.NotificationWebhook != nil {
+		in, out := &in.NotificationWebhook, &out.NotificationWebhook
+		*out = new(NotificationWebhook)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvClusterSpec.
@@ -3153,6 +4059,138 @@ func (in *TikvClusterStatus) DeepCopy() *TikvClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvRestore) DeepCopyInto(out *TikvRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvRestore.
+func (in *TikvRestore) DeepCopy() *TikvRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TikvRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvRestoreCondition) DeepCopyInto(out *TikvRestoreCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvRestoreCondition.
+func (in *TikvRestoreCondition) DeepCopy() *TikvRestoreCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvRestoreCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvRestoreList) DeepCopyInto(out *TikvRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TikvRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvRestoreList.
+func (in *TikvRestoreList) DeepCopy() *TikvRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TikvRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvRestoreSpec) DeepCopyInto(out *TikvRestoreSpec) {
+	*out = *in
+	if in.ClusterSpec != nil {
+		in, out := &in.ClusterSpec, &out.ClusterSpec
+		*out = new(TikvClusterSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.StorageProvider.DeepCopyInto(&out.StorageProvider)
+	if in.BR != nil {
+		in, out := &in.BR, &out.BR
+		*out = new(BRConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvRestoreSpec.
+func (in *TikvRestoreSpec) DeepCopy() *TikvRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TikvRestoreStatus) DeepCopyInto(out *TikvRestoreStatus) {
+	*out = *in
+	if in.TimeStarted != nil {
+		in, out := &in.TimeStarted, &out.TimeStarted
+		*out = (*in).DeepCopy()
+	}
+	if in.TimeCompleted != nil {
+		in, out := &in.TimeCompleted, &out.TimeCompleted
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]TikvRestoreCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TikvRestoreStatus.
+func (in *TikvRestoreStatus) DeepCopy() *TikvRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TikvRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UnjoinedMember) DeepCopyInto(out *UnjoinedMember) {
 	*out = *in
@@ -3168,3 +4206,28 @@ func (in *UnjoinedMember) DeepCopy() *UnjoinedMember {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeSnapshotConfig) DeepCopyInto(out *VolumeSnapshotConfig) {
+	*out = *in
+	if in.VolumeSnapshotClassName != nil {
+		in, out := &in.VolumeSnapshotClassName, &out.VolumeSnapshotClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.PauseSchedulingSeconds != nil {
+		in, out := &in.PauseSchedulingSeconds, &out.PauseSchedulingSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeSnapshotConfig.
+func (in *VolumeSnapshotConfig) DeepCopy() *VolumeSnapshotConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSnapshotConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -25,10 +25,18 @@ type FakeTikvV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeTikvV1alpha1) TikvBackups(namespace string) v1alpha1.TikvBackupInterface {
+	return &FakeTikvBackups{c, namespace}
+}
+
 func (c *FakeTikvV1alpha1) TikvClusters(namespace string) v1alpha1.TikvClusterInterface {
 	return &FakeTikvClusters{c, namespace}
 }
 
+func (c *FakeTikvV1alpha1) TikvRestores(namespace string) v1alpha1.TikvRestoreInterface {
+	return &FakeTikvRestores{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeTikvV1alpha1) RESTClient() rest.Interface {
@@ -15,4 +15,8 @@
 
 package v1alpha1
 
+type TikvBackupExpansion interface{}
+
 type TikvClusterExpansion interface{}
+
+type TikvRestoreExpansion interface{}
@@ -23,7 +23,9 @@ import (
 
 type TikvV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	TikvBackupsGetter
 	TikvClustersGetter
+	TikvRestoresGetter
 }
 
 // TikvV1alpha1Client is used to interact with features provided by the tikv.org group.
@@ -31,10 +33,18 @@ type TikvV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *TikvV1alpha1Client) TikvBackups(namespace string) TikvBackupInterface {
+	return newTikvBackups(c, namespace)
+}
+
 func (c *TikvV1alpha1Client) TikvClusters(namespace string) TikvClusterInterface {
 	return newTikvClusters(c, namespace)
 }
 
+func (c *TikvV1alpha1Client) TikvRestores(namespace string) TikvRestoreInterface {
+	return newTikvRestores(c, namespace)
+}
+
 // NewForConfig creates a new TikvV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*TikvV1alpha1Client, error) {
 	config := *c
@@ -50,8 +50,12 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=tikv.org, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("tikvbackups"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Tikv().V1alpha1().TikvBackups().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("tikvclusters"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Tikv().V1alpha1().TikvClusters().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("tikvrestores"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Tikv().V1alpha1().TikvRestores().Informer()}, nil
 
 	}
 
@@ -21,8 +21,12 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// TikvBackups returns a TikvBackupInformer.
+	TikvBackups() TikvBackupInformer
 	// TikvClusters returns a TikvClusterInformer.
 	TikvClusters() TikvClusterInformer
+	// TikvRestores returns a TikvRestoreInformer.
+	TikvRestores() TikvRestoreInformer
 }
 
 type version struct {
@@ -36,7 +40,17 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// TikvBackups returns a TikvBackupInformer.
+func (v *version) TikvBackups() TikvBackupInformer {
+	return &tikvBackupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // TikvClusters returns a TikvClusterInformer.
 func (v *version) TikvClusters() TikvClusterInformer {
 	return &tikvClusterInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// TikvRestores returns a TikvRestoreInformer.
+func (v *version) TikvRestores() TikvRestoreInformer {
+	return &tikvRestoreInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -15,6 +15,14 @@
 
 package v1alpha1
 
+// TikvBackupListerExpansion allows custom methods to be added to
+// TikvBackupLister.
+type TikvBackupListerExpansion interface{}
+
+// TikvBackupNamespaceListerExpansion allows custom methods to be added to
+// TikvBackupNamespaceLister.
+type TikvBackupNamespaceListerExpansion interface{}
+
 // TikvClusterListerExpansion allows custom methods to be added to
 // TikvClusterLister.
 type TikvClusterListerExpansion interface{}
@@ -22,3 +30,11 @@ type TikvClusterListerExpansion interface{}
 // TikvClusterNamespaceListerExpansion allows custom methods to be added to
 // TikvClusterNamespaceLister.
 type TikvClusterNamespaceListerExpansion interface{}
+
+// TikvRestoreListerExpansion allows custom methods to be added to
+// TikvRestoreLister.
+type TikvRestoreListerExpansion interface{}
+
+// TikvRestoreNamespaceListerExpansion allows custom methods to be added to
+// TikvRestoreNamespaceLister.
+type TikvRestoreNamespaceListerExpansion interface{}
@@ -0,0 +1,196 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	listers "github.com/tikv/tikv-operator/pkg/client/listers/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// ControlInterface implements the control logic for taking the backups of the tikv clusters
+type ControlInterface interface {
+	// UpdateBackup runs the BR job of the backup and records its result to the status of the backup
+	UpdateBackup(*v1alpha1.TikvBackup) error
+}
+
+// NewDefaultBackupControl returns a new instance of the default implementation ControlInterface that
+// implements the documented semantics for TikvBackups.
+func NewDefaultBackupControl(
+	statusControl controller.BackupStatusControlInterface,
+	control controller.GenericControlInterface,
+	pdControl pdapi.PDControlInterface,
+	tcLister listers.TikvClusterLister,
+	jobLister batchlisters.JobLister,
+	pvcLister corelisters.PersistentVolumeClaimLister,
+	recorder record.EventRecorder) ControlInterface {
+	return &defaultBackupControl{
+		statusControl,
+		control,
+		pdControl,
+		tcLister,
+		jobLister,
+		pvcLister,
+		recorder,
+	}
+}
+
+type defaultBackupControl struct {
+	statusControl controller.BackupStatusControlInterface
+	control       controller.GenericControlInterface
+	pdControl     pdapi.PDControlInterface
+	tcLister      listers.TikvClusterLister
+	jobLister     batchlisters.JobLister
+	pvcLister     corelisters.PersistentVolumeClaimLister
+	recorder      record.EventRecorder
+}
+
+// UpdateBackup executes the core logic loop for a backup.
+func (bc *defaultBackupControl) UpdateBackup(backup *v1alpha1.TikvBackup) error {
+	if backup.DeletionTimestamp != nil || backup.IsFinished() {
+		return nil
+	}
+
+	status := backup.Status.DeepCopy()
+	err := bc.syncBackup(backup, status)
+	if apiequality.Semantic.DeepEqual(status, &backup.Status) {
+		return err
+	}
+	if _, updateErr := bc.statusControl.UpdateBackupStatus(backup, status); updateErr != nil {
+		return errorutils.NewAggregate([]error{err, updateErr})
+	}
+	switch status.Phase {
+	case v1alpha1.BackupComplete:
+		if backup.Spec.VolumeSnapshot != nil {
+			bc.recorder.Eventf(backup, corev1.EventTypeNormal, "BackupComplete", "%d volume snapshots are taken", len(status.VolumeSnapshots))
+		} else {
+			bc.recorder.Eventf(backup, corev1.EventTypeNormal, "BackupComplete", "backup is stored to %s", status.BackupPath)
+		}
+	case v1alpha1.BackupFailed:
+		cond := v1alpha1.GetBackupCondition(status, v1alpha1.TikvBackupFailed)
+		bc.recorder.Eventf(backup, corev1.EventTypeWarning, "BackupFailed", "backup failed: %s", cond.Message)
+	}
+	return err
+}
+
+func (bc *defaultBackupControl) syncBackup(backup *v1alpha1.TikvBackup, status *v1alpha1.TikvBackupStatus) error {
+	ns := backup.GetNamespace()
+	name := backup.GetName()
+
+	if backup.Spec.S3 == nil && backup.Spec.VolumeSnapshot == nil {
+		failBackup(status, "InvalidStorage", "the storage of the backup is not specified")
+		return nil
+	}
+
+	tc, err := bc.tcLister.TikvClusters(ns).Get(backup.Spec.Cluster)
+	if errors.IsNotFound(err) {
+		pendBackup(status, "ClusterNotFound", fmt.Sprintf("TikvCluster %s/%s does not exist", ns, backup.Spec.Cluster))
+		return controller.RequeueErrorf("TikvBackup: [%s/%s], waiting for TikvCluster %s to be created", ns, name, backup.Spec.Cluster)
+	}
+	if err != nil {
+		return err
+	}
+
+	if backup.Spec.VolumeSnapshot != nil {
+		return bc.syncVolumeSnapshotBackup(tc, backup, status)
+	}
+
+	job, err := bc.jobLister.Jobs(ns).Get(controller.BackupJobName(name))
+	if errors.IsNotFound(err) {
+		if !tc.PDIsAvailable() {
+			pendBackup(status, "ClusterNotAvailable", fmt.Sprintf("PD of TikvCluster %s/%s is not available", ns, tc.Name))
+			return controller.RequeueErrorf("TikvBackup: [%s/%s], waiting for PD cluster running", ns, name)
+		}
+		if err := bc.control.Create(backup, getBackupJob(tc, backup), true); err != nil {
+			return err
+		}
+		now := metav1.Now()
+		status.Phase = v1alpha1.BackupRunning
+		status.TimeStarted = &now
+		status.BackupPath = backupPath(backup)
+		v1alpha1.UpdateBackupCondition(status, v1alpha1.TikvBackupCondition{
+			Type:   v1alpha1.TikvBackupScheduled,
+			Status: corev1.ConditionTrue,
+			Reason: "JobCreated",
+		})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(job, backup) {
+		return fmt.Errorf("TikvBackup: [%s/%s], job %s already exists and is not controlled by the backup", ns, name, job.Name)
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			status.Phase = v1alpha1.BackupComplete
+			status.TimeCompleted = job.Status.CompletionTime
+			if status.TimeCompleted == nil {
+				status.TimeCompleted = &cond.LastTransitionTime
+			}
+			v1alpha1.UpdateBackupCondition(status, v1alpha1.TikvBackupCondition{
+				Type:   v1alpha1.TikvBackupComplete,
+				Status: corev1.ConditionTrue,
+				Reason: "JobComplete",
+			})
+			return nil
+		case batchv1.JobFailed:
+			failBackup(status, cond.Reason, cond.Message)
+			status.TimeCompleted = &cond.LastTransitionTime
+			return nil
+		}
+	}
+	status.Phase = v1alpha1.BackupRunning
+	return nil
+}
+
+// pendBackup records the reason the BR job of the backup can not be created yet
+func pendBackup(status *v1alpha1.TikvBackupStatus, reason, message string) {
+	status.Phase = v1alpha1.BackupPending
+	v1alpha1.UpdateBackupCondition(status, v1alpha1.TikvBackupCondition{
+		Type:    v1alpha1.TikvBackupScheduled,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+func failBackup(status *v1alpha1.TikvBackupStatus, reason, message string) {
+	status.Phase = v1alpha1.BackupFailed
+	v1alpha1.UpdateBackupCondition(status, v1alpha1.TikvBackupCondition{
+		Type:    v1alpha1.TikvBackupFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+var _ ControlInterface = &defaultBackupControl{}
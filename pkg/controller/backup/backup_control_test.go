@@ -0,0 +1,365 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned/fake"
+	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	apps "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestBackupControlUpdateBackup(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		name         string
+		update       func(backup *v1alpha1.TikvBackup, tc *v1alpha1.TikvCluster)
+		noCluster    bool
+		jobCondition *batchv1.JobCondition
+		expectErr    bool
+		expectPhase  v1alpha1.BackupPhase
+		expectReason string
+		expectJob    bool
+	}{
+		{
+			name:         "storage is not specified",
+			update:       func(backup *v1alpha1.TikvBackup, _ *v1alpha1.TikvCluster) { backup.Spec.S3 = nil },
+			expectPhase:  v1alpha1.BackupFailed,
+			expectReason: "InvalidStorage",
+		},
+		{
+			name:         "cluster does not exist",
+			noCluster:    true,
+			expectErr:    true,
+			expectPhase:  v1alpha1.BackupPending,
+			expectReason: "ClusterNotFound",
+		},
+		{
+			name:         "pd is not available",
+			update:       func(_ *v1alpha1.TikvBackup, tc *v1alpha1.TikvCluster) { tc.Status.PD.Members = nil },
+			expectErr:    true,
+			expectPhase:  v1alpha1.BackupPending,
+			expectReason: "ClusterNotAvailable",
+		},
+		{
+			name:         "job is created",
+			expectPhase:  v1alpha1.BackupRunning,
+			expectReason: "JobCreated",
+			expectJob:    true,
+		},
+		{
+			name:         "job is complete",
+			jobCondition: &batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			expectPhase:  v1alpha1.BackupComplete,
+			expectReason: "JobComplete",
+		},
+		{
+			name:         "job failed",
+			jobCondition: &batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+			expectPhase:  v1alpha1.BackupFailed,
+			expectReason: "BackoffLimitExceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backup := newTikvBackup()
+			tc := newTikvCluster()
+			if tt.update != nil {
+				tt.update(backup, tc)
+			}
+			bc, genericControl, _, indexers := newFakeBackupControl(tc)
+			g.Expect(indexers.backup.Add(backup)).To(Succeed())
+			if !tt.noCluster {
+				g.Expect(indexers.tc.Add(tc)).To(Succeed())
+			}
+			if tt.jobCondition != nil {
+				job := getBackupJob(tc, backup)
+				job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(backup, controller.BackupControllerKind)}
+				job.Status.Conditions = []batchv1.JobCondition{*tt.jobCondition}
+				g.Expect(indexers.job.Add(job)).To(Succeed())
+			}
+
+			err := bc.UpdateBackup(backup)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+
+			obj, _, err := indexers.backup.Get(backup)
+			g.Expect(err).NotTo(HaveOccurred())
+			updated := obj.(*v1alpha1.TikvBackup)
+			g.Expect(updated.Status.Phase).To(Equal(tt.expectPhase))
+			reasons := []string{}
+			for _, cond := range updated.Status.Conditions {
+				reasons = append(reasons, cond.Reason)
+			}
+			g.Expect(reasons).To(ContainElement(tt.expectReason))
+
+			exist, err := genericControl.Exist(client.ObjectKey{Namespace: backup.Namespace, Name: controller.BackupJobName(backup.Name)}, &batchv1.Job{})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(exist).To(Equal(tt.expectJob))
+			if tt.expectJob {
+				g.Expect(updated.Status.BackupPath).To(Equal("s3://bucket/prefix/default-demo"))
+				g.Expect(updated.Status.TimeStarted).NotTo(BeNil())
+			}
+		})
+	}
+}
+
+func TestBackupControlVolumeSnapshot(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	backup := newTikvBackup()
+	backup.Spec.S3 = nil
+	backup.Spec.VolumeSnapshot = &v1alpha1.VolumeSnapshotConfig{VolumeSnapshotClassName: pointer.StringPtr("csi-snapclass")}
+	tc := newTikvCluster()
+	tc.Spec.TiKV.Replicas = 2
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", State: v1alpha1.TiKVStateUp},
+		"2": {ID: "2", State: v1alpha1.TiKVStateUp},
+	}
+	bc, genericControl, pdClient, indexers := newFakeBackupControl(tc)
+	g.Expect(indexers.backup.Add(backup)).To(Succeed())
+	g.Expect(indexers.tc.Add(tc)).To(Succeed())
+	for i := 0; i < 2; i++ {
+		pvcLabels := label.New().Instance(tc.GetInstanceName()).TiKV()
+		pvcLabels[label.StoreIDLabelKey] = fmt.Sprint(i + 1)
+		g.Expect(indexers.pvc.Add(&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("tikv-demo-tikv-%d", i),
+				Namespace: tc.Namespace,
+				Labels:    pvcLabels.Labels(),
+			},
+		})).To(Succeed())
+	}
+	// the balance-leader-scheduler is paused by the user
+	pdClient.AddReaction(pdapi.GetSchedulersActionType, func(action *pdapi.Action) (interface{}, error) {
+		if action.Paused {
+			return []string{"balance-leader-scheduler"}, nil
+		}
+		return []string{"balance-leader-scheduler", "balance-region-scheduler", "evict-leader-scheduler-1"}, nil
+	})
+	var pauses []time.Duration
+	pdClient.AddReaction(pdapi.PauseSchedulersActionType, func(action *pdapi.Action) (interface{}, error) {
+		g.Expect(action.Schedulers).To(Equal([]string{"balance-region-scheduler"}))
+		pauses = append(pauses, action.Delay)
+		return nil, nil
+	})
+	sync := func() *v1alpha1.TikvBackup {
+		obj, _, err := indexers.backup.Get(backup)
+		g.Expect(err).NotTo(HaveOccurred())
+		bc.UpdateBackup(obj.(*v1alpha1.TikvBackup).DeepCopy())
+		obj, _, err = indexers.backup.Get(backup)
+		g.Expect(err).NotTo(HaveOccurred())
+		return obj.(*v1alpha1.TikvBackup)
+	}
+	setSnapshotStatus := func(name string, status map[string]interface{}) {
+		snapshot := newVolumeSnapshot()
+		key := client.ObjectKey{Namespace: backup.Namespace, Name: name}
+		g.Expect(genericControl.FakeCli.Get(context.TODO(), key, snapshot)).To(Succeed())
+		snapshot.Object["status"] = status
+		g.Expect(genericControl.FakeCli.Update(context.TODO(), snapshot)).To(Succeed())
+	}
+
+	// the snapshots of all volumes are created with the schedulers paused
+	updated := sync()
+	g.Expect(updated.Status.Phase).To(Equal(v1alpha1.BackupRunning))
+	g.Expect(updated.Status.VolumeSnapshots).To(Equal([]v1alpha1.TiKVVolumeSnapshot{
+		{StoreID: "1", PVCName: "tikv-demo-tikv-0", Name: "demo-tikv-demo-tikv-0"},
+		{StoreID: "2", PVCName: "tikv-demo-tikv-1", Name: "demo-tikv-demo-tikv-1"},
+	}))
+	g.Expect(pauses).To(Equal([]time.Duration{10 * time.Minute}))
+	g.Expect(updated.Status.PausedSchedulers).To(Equal([]string{"balance-region-scheduler"}))
+	snapshot := newVolumeSnapshot()
+	g.Expect(genericControl.FakeCli.Get(context.TODO(), client.ObjectKey{Namespace: backup.Namespace, Name: "demo-tikv-demo-tikv-0"}, snapshot)).To(Succeed())
+	source, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName")
+	g.Expect(source).To(Equal("tikv-demo-tikv-0"))
+	className, _, _ := unstructured.NestedString(snapshot.Object, "spec", "volumeSnapshotClassName")
+	g.Expect(className).To(Equal("csi-snapclass"))
+
+	// the schedulers are kept paused until all snapshots are taken
+	setSnapshotStatus("demo-tikv-demo-tikv-0", map[string]interface{}{"creationTime": "2020-01-01T00:00:00Z"})
+	updated = sync()
+	g.Expect(updated.Status.Phase).To(Equal(v1alpha1.BackupRunning))
+	g.Expect(pauses).To(HaveLen(1))
+
+	// the schedulers are resumed once all snapshots are taken
+	content := &unstructured.Unstructured{}
+	content.SetGroupVersionKind(VolumeSnapshotContentGVK)
+	content.SetName("snapcontent-0")
+	content.Object["status"] = map[string]interface{}{"snapshotHandle": "snap-0"}
+	g.Expect(genericControl.FakeCli.Create(context.TODO(), content)).To(Succeed())
+	setSnapshotStatus("demo-tikv-demo-tikv-0", map[string]interface{}{
+		"creationTime":                   "2020-01-01T00:00:00Z",
+		"readyToUse":                     true,
+		"boundVolumeSnapshotContentName": "snapcontent-0",
+	})
+	setSnapshotStatus("demo-tikv-demo-tikv-1", map[string]interface{}{"creationTime": "2020-01-01T00:00:00Z"})
+	updated = sync()
+	g.Expect(updated.Status.Phase).To(Equal(v1alpha1.BackupRunning))
+	g.Expect(pauses).To(Equal([]time.Duration{10 * time.Minute, 0}))
+	g.Expect(updated.Status.PausedSchedulers).To(BeEmpty())
+	g.Expect(updated.Status.VolumeSnapshots[0].ReadyToUse).To(BeTrue())
+	g.Expect(updated.Status.VolumeSnapshots[0].SnapshotHandle).To(Equal("snap-0"))
+
+	// the backup completes once all snapshots are ready
+	setSnapshotStatus("demo-tikv-demo-tikv-1", map[string]interface{}{"creationTime": "2020-01-01T00:00:00Z", "readyToUse": true})
+	updated = sync()
+	g.Expect(updated.Status.Phase).To(Equal(v1alpha1.BackupComplete))
+	g.Expect(updated.Status.TimeCompleted).NotTo(BeNil())
+	g.Expect(pauses).To(HaveLen(2))
+}
+
+func TestGetBackupJob(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	backup := newTikvBackup()
+	backup.Spec.S3.SecretName = "s3-secret"
+	concurrency := uint32(8)
+	backup.Spec.BR = &v1alpha1.BRConfig{
+		Concurrency: &concurrency,
+		Checksum:    pointer.BoolPtr(false),
+		Options:     []string{"--log-level=debug"},
+	}
+	tc := newTikvCluster()
+	tc.Spec.TLSCluster = &v1alpha1.TLSCluster{Enabled: true}
+
+	job := getBackupJob(tc, backup)
+	g.Expect(job.Name).To(Equal("demo-backup"))
+	g.Expect(*job.Spec.BackoffLimit).To(BeZero())
+	container := job.Spec.Template.Spec.Containers[0]
+	g.Expect(container.Image).To(Equal("pingcap/br:v4.0.0"))
+	g.Expect(container.Args).To(Equal([]string{
+		"backup", "raw",
+		"--storage=s3://bucket/prefix/default-demo",
+		"--pd=demo-pd.default:2379",
+		"--ca=/var/lib/cluster-client-tls/ca.crt",
+		"--cert=/var/lib/cluster-client-tls/tls.crt",
+		"--key=/var/lib/cluster-client-tls/tls.key",
+		"--concurrency=8",
+		"--checksum=false",
+		"--s3.provider=aws",
+		"--s3.region=us-west-2",
+		"--send-credentials-to-tikv=true",
+		"--log-level=debug",
+	}))
+	g.Expect(container.Env).To(HaveLen(2))
+	g.Expect(container.VolumeMounts).To(HaveLen(1))
+	g.Expect(job.Spec.Template.Spec.Volumes[0].Secret.SecretName).To(Equal("demo-cluster-client-secret"))
+
+	// the certificates of the cluster are used as the client certificates if their secret is specified
+	tc.Spec.TLSCluster.SecretName = "demo-tls"
+	job = getBackupJob(tc, backup)
+	g.Expect(job.Spec.Template.Spec.Volumes[0].Secret.SecretName).To(Equal("demo-tls"))
+}
+
+type backupIndexers struct {
+	backup cache.Indexer
+	tc     cache.Indexer
+	job    cache.Indexer
+	pvc    cache.Indexer
+}
+
+func newFakeBackupControl(tc *v1alpha1.TikvCluster) (ControlInterface, *controller.FakeGenericControl, *pdapi.FakePDClient, backupIndexers) {
+	cli := fake.NewSimpleClientset()
+	kubeCli := kubefake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(cli, 0)
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeCli, 0)
+	backupInformer := informerFactory.Tikv().V1alpha1().TikvBackups()
+	tcInformer := informerFactory.Tikv().V1alpha1().TikvClusters()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+	pvcInformer := kubeInformerFactory.Core().V1().PersistentVolumeClaims()
+	statusControl := controller.NewFakeBackupStatusControl(backupInformer)
+	genericControl := controller.NewFakeGenericControl()
+	pdControl := pdapi.NewFakePDControl(kubeCli)
+	pdClient := controller.NewFakePDClient(pdControl, tc)
+
+	bc := NewDefaultBackupControl(statusControl, genericControl, pdControl, tcInformer.Lister(), jobInformer.Lister(), pvcInformer.Lister(), record.NewFakeRecorder(10))
+	return bc, genericControl, pdClient, backupIndexers{
+		backup: backupInformer.Informer().GetIndexer(),
+		tc:     tcInformer.Informer().GetIndexer(),
+		job:    jobInformer.Informer().GetIndexer(),
+		pvc:    pvcInformer.Informer().GetIndexer(),
+	}
+}
+
+func newTikvBackup() *v1alpha1.TikvBackup {
+	return &v1alpha1.TikvBackup{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "TikvBackup",
+			APIVersion: "tikv.org/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo",
+			Namespace: corev1.NamespaceDefault,
+			UID:       "backup-uid",
+		},
+		Spec: v1alpha1.TikvBackupSpec{
+			Cluster: "demo",
+			StorageProvider: v1alpha1.StorageProvider{
+				S3: &v1alpha1.S3StorageProvider{
+					Provider: "aws",
+					Region:   "us-west-2",
+					Bucket:   "bucket",
+					Prefix:   "prefix",
+				},
+			},
+		},
+	}
+}
+
+func newTikvCluster() *v1alpha1.TikvCluster {
+	return &v1alpha1.TikvCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo",
+			Namespace: corev1.NamespaceDefault,
+		},
+		Spec: v1alpha1.TikvClusterSpec{
+			Version: "v4.0.0",
+			PD:      v1alpha1.PDSpec{Replicas: 1},
+			TiKV: v1alpha1.TiKVSpec{
+				Replicas:  3,
+				BaseImage: "pingcap/tikv",
+			},
+		},
+		Status: v1alpha1.TikvClusterStatus{
+			PD: v1alpha1.PDStatus{
+				Members:     map[string]v1alpha1.PDMember{"demo-pd-0": {Name: "demo-pd-0", Health: true}},
+				StatefulSet: &apps.StatefulSetStatus{ReadyReplicas: 1},
+			},
+		},
+	}
+}
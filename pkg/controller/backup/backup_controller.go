@@ -0,0 +1,197 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	perrors "github.com/pingcap/errors"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
+	listers "github.com/tikv/tikv-operator/pkg/client/listers/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	eventv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Controller controls tikvbackups.
+type Controller struct {
+	// control returns an interface capable of syncing a backup.
+	// Abstracted out for testing.
+	control ControlInterface
+	// backupLister is able to list/get tikvbackups from a shared informer's store
+	backupLister listers.TikvBackupLister
+	// backupListerSynced returns true if the tikvbackup shared informer has synced at least once
+	backupListerSynced cache.InformerSynced
+	// jobListerSynced returns true if the job shared informer has synced at least once
+	jobListerSynced cache.InformerSynced
+	// tikvbackups that need to be synced.
+	queue workqueue.RateLimitingInterface
+}
+
+// NewController creates a tikvbackup controller.
+func NewController(
+	kubeCli kubernetes.Interface,
+	cli versioned.Interface,
+	genericCli client.Client,
+	informerFactory informers.SharedInformerFactory,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+) *Controller {
+	eventBroadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{QPS: 1})
+	eventBroadcaster.StartLogging(klog.V(2).Infof)
+	eventBroadcaster.StartRecordingToSink(&eventv1.EventSinkImpl{
+		Interface: eventv1.New(kubeCli.CoreV1().RESTClient()).Events("")})
+	recorder := eventBroadcaster.NewRecorder(v1alpha1.Scheme, corev1.EventSource{Component: "tikv-backup-controller"})
+
+	backupInformer := informerFactory.Tikv().V1alpha1().TikvBackups()
+	tcInformer := informerFactory.Tikv().V1alpha1().TikvClusters()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+	pvcInformer := kubeInformerFactory.Core().V1().PersistentVolumeClaims()
+
+	bc := &Controller{
+		control: NewDefaultBackupControl(
+			controller.NewRealBackupStatusControl(cli, backupInformer.Lister()),
+			controller.NewRealGenericControl(genericCli, recorder),
+			pdapi.NewDefaultPDControl(kubeCli),
+			tcInformer.Lister(),
+			jobInformer.Lister(),
+			pvcInformer.Lister(),
+			recorder,
+		),
+		queue: workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(),
+			"tikvbackup",
+		),
+	}
+
+	backupInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: bc.enqueueBackup,
+		UpdateFunc: func(old, cur interface{}) {
+			bc.enqueueBackup(cur)
+		},
+		DeleteFunc: bc.enqueueBackup,
+	})
+	bc.backupLister = backupInformer.Lister()
+	bc.backupListerSynced = backupInformer.Informer().HasSynced
+
+	jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, cur interface{}) {
+			bc.updateJob(cur)
+		},
+	})
+	bc.jobListerSynced = jobInformer.Informer().HasSynced
+
+	return bc
+}
+
+// Run runs the tikvbackup controller.
+func (bc *Controller) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer bc.queue.ShutDown()
+
+	klog.Info("Starting tikvbackup controller")
+	defer klog.Info("Shutting down tikvbackup controller")
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(bc.worker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+// worker runs a worker goroutine that invokes processNextWorkItem until the the controller's queue is closed
+func (bc *Controller) worker() {
+	for bc.processNextWorkItem() {
+	}
+}
+
+// processNextWorkItem dequeues items, processes them, and marks them done. It enforces that the syncHandler is never
+// invoked concurrently with the same key.
+func (bc *Controller) processNextWorkItem() bool {
+	key, quit := bc.queue.Get()
+	if quit {
+		return false
+	}
+	defer bc.queue.Done(key)
+	if err := bc.sync(key.(string)); err != nil {
+		if perrors.Find(err, controller.IsRequeueError) != nil {
+			klog.Infof("TikvBackup: %v, still need sync: %v, requeuing", key.(string), err)
+		} else {
+			utilruntime.HandleError(fmt.Errorf("TikvBackup: %v, sync failed %v, requeuing", key.(string), err))
+		}
+		controller.Requeue(bc.queue, key, err)
+	} else {
+		bc.queue.Forget(key)
+	}
+	return true
+}
+
+// sync syncs the given tikvbackup.
+func (bc *Controller) sync(key string) error {
+	startTime := time.Now()
+	defer func() {
+		klog.V(4).Infof("Finished syncing TikvBackup %q (%v)", key, time.Since(startTime))
+	}()
+
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	backup, err := bc.backupLister.TikvBackups(ns).Get(name)
+	if errors.IsNotFound(err) {
+		klog.Infof("TikvBackup has been deleted %v", key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return bc.control.UpdateBackup(backup.DeepCopy())
+}
+
+// enqueueBackup enqueues the given tikvbackup in the work queue.
+func (bc *Controller) enqueueBackup(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Cound't get key for object %+v: %v", obj, err))
+		return
+	}
+	bc.queue.Add(key)
+}
+
+// updateJob enqueues the tikvbackup controlling the BR job once the job is updated, e.g. completed
+func (bc *Controller) updateJob(cur interface{}) {
+	job := cur.(*batchv1.Job)
+	controllerRef := metav1.GetControllerOf(job)
+	if controllerRef == nil || controllerRef.Kind != controller.BackupControllerKind.Kind {
+		return
+	}
+	klog.V(4).Infof("Job %s/%s updated, TikvBackup: %s/%s", job.Namespace, job.Name, job.Namespace, controllerRef.Name)
+	bc.queue.Add(fmt.Sprintf("%s/%s", job.Namespace, controllerRef.Name))
+}
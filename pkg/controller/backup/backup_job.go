@@ -0,0 +1,167 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	clusterClientTLSVolumeName = "cluster-client-tls"
+	clusterClientTLSMountPath  = "/var/lib/cluster-client-tls"
+)
+
+// backupPath returns the location of the backup in the storage, each backup is stored to a distinct directory
+// named after its namespace and name
+func backupPath(backup *v1alpha1.TikvBackup) string {
+	s3 := backup.Spec.S3
+	return fmt.Sprintf("s3://%s", path.Join(s3.Bucket, s3.Prefix, fmt.Sprintf("%s-%s", backup.Namespace, backup.Name)))
+}
+
+// s3Args returns the command line arguments of BR to access the S3 storage
+func s3Args(s3 *v1alpha1.S3StorageProvider) []string {
+	args := []string{}
+	if s3.Provider != "" {
+		args = append(args, fmt.Sprintf("--s3.provider=%s", s3.Provider))
+	}
+	if s3.Region != "" {
+		args = append(args, fmt.Sprintf("--s3.region=%s", s3.Region))
+	}
+	if s3.Endpoint != "" {
+		args = append(args, fmt.Sprintf("--s3.endpoint=%s", s3.Endpoint))
+	}
+	if s3.SecretName != "" {
+		// TiKV uploads the SST files to the storage by itself
+		args = append(args, "--send-credentials-to-tikv=true")
+	}
+	return args
+}
+
+// s3Env returns the credentials of the S3 storage read from the secret
+func s3Env(s3 *v1alpha1.S3StorageProvider) []corev1.EnvVar {
+	if s3.SecretName == "" {
+		return nil
+	}
+	secretKeyRef := func(key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s3.SecretName},
+				Key:                  key,
+			},
+		}
+	}
+	return []corev1.EnvVar{
+		{Name: "AWS_ACCESS_KEY_ID", ValueFrom: secretKeyRef("access_key")},
+		{Name: "AWS_SECRET_ACCESS_KEY", ValueFrom: secretKeyRef("secret_key")},
+	}
+}
+
+// brArgs returns the command line arguments of BR shared by the backups and the restores
+func brArgs(tc *v1alpha1.TikvCluster, br *v1alpha1.BRConfig) []string {
+	args := []string{fmt.Sprintf("--pd=%s.%s:2379", controller.PDMemberName(tc.Name), tc.Namespace)}
+	if tc.IsTLSClusterEnabled() {
+		args = append(args,
+			fmt.Sprintf("--ca=%s", path.Join(clusterClientTLSMountPath, corev1.ServiceAccountRootCAKey)),
+			fmt.Sprintf("--cert=%s", path.Join(clusterClientTLSMountPath, corev1.TLSCertKey)),
+			fmt.Sprintf("--key=%s", path.Join(clusterClientTLSMountPath, corev1.TLSPrivateKeyKey)),
+		)
+	}
+	if br == nil {
+		return args
+	}
+	if br.Concurrency != nil {
+		args = append(args, fmt.Sprintf("--concurrency=%d", *br.Concurrency))
+	}
+	if br.RateLimit != nil {
+		args = append(args, fmt.Sprintf("--ratelimit=%d", *br.RateLimit))
+	}
+	if br.Checksum != nil {
+		args = append(args, fmt.Sprintf("--checksum=%t", *br.Checksum))
+	}
+	return args
+}
+
+// getBackupJob returns the job running BR to back up the whole tikv cluster to the storage
+func getBackupJob(tc *v1alpha1.TikvCluster, backup *v1alpha1.TikvBackup) *batchv1.Job {
+	s3 := backup.Spec.S3
+	args := []string{"backup", "raw", fmt.Sprintf("--storage=%s", backupPath(backup))}
+	args = append(args, brArgs(tc, backup.Spec.BR)...)
+	args = append(args, s3Args(s3)...)
+	if backup.Spec.BR != nil {
+		args = append(args, backup.Spec.BR.Options...)
+	}
+
+	jobMeta := metav1.ObjectMeta{
+		Name:      controller.BackupJobName(backup.Name),
+		Namespace: backup.Namespace,
+		Labels:    label.New().Instance(tc.GetInstanceName()).Backup(backup.Name).Labels(),
+	}
+	container := corev1.Container{
+		Image:     backup.BRImage(tc),
+		Args:      args,
+		Env:       s3Env(s3),
+		Resources: backup.Spec.Resources,
+	}
+	return newBRJob(tc, jobMeta, container, backup.Spec.ServiceAccount)
+}
+
+// newBRJob returns a job running the BR container once, the cluster client certificate
+// is mounted to the container if TLS is enabled
+func newBRJob(tc *v1alpha1.TikvCluster, jobMeta metav1.ObjectMeta, container corev1.Container, serviceAccount string) *batchv1.Job {
+	var volumes []corev1.Volume
+	if tc.IsTLSClusterEnabled() {
+		volumes = append(volumes, corev1.Volume{
+			Name: clusterClientTLSVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: tc.ClusterClientTLSSecretName()},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      clusterClientTLSVolumeName,
+			ReadOnly:  true,
+			MountPath: clusterClientTLSMountPath,
+		})
+	}
+	container.Name = "br"
+	container.ImagePullPolicy = tc.Spec.ImagePullPolicy
+	container.Command = []string{"/br"}
+
+	return &batchv1.Job{
+		ObjectMeta: jobMeta,
+		Spec: batchv1.JobSpec{
+			// a failed job is reported instead of being retried
+			BackoffLimit: pointer.Int32Ptr(0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: jobMeta.Labels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: serviceAccount,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					Containers:         []corev1.Container{container},
+					Volumes:            volumes,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	listers "github.com/tikv/tikv-operator/pkg/client/listers/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+)
+
+// RestoreControlInterface implements the control logic for restoring the tikv clusters from the backups
+type RestoreControlInterface interface {
+	// UpdateRestore runs the BR job of the restore and records its result to the status of the restore
+	UpdateRestore(*v1alpha1.TikvRestore) error
+	// ReleaseCluster resumes syncing the tikv cluster held by the deleted restore
+	ReleaseCluster(ns, restoreName string) error
+}
+
+// NewDefaultRestoreControl returns a new instance of the default implementation RestoreControlInterface that
+// implements the documented semantics for TikvRestores.
+func NewDefaultRestoreControl(
+	cli versioned.Interface,
+	statusControl controller.RestoreStatusControlInterface,
+	control controller.GenericControlInterface,
+	tcLister listers.TikvClusterLister,
+	backupLister listers.TikvBackupLister,
+	jobLister batchlisters.JobLister,
+	recorder record.EventRecorder) RestoreControlInterface {
+	return &defaultRestoreControl{
+		cli,
+		statusControl,
+		control,
+		tcLister,
+		backupLister,
+		jobLister,
+		recorder,
+	}
+}
+
+type defaultRestoreControl struct {
+	cli           versioned.Interface
+	statusControl controller.RestoreStatusControlInterface
+	control       controller.GenericControlInterface
+	tcLister      listers.TikvClusterLister
+	backupLister  listers.TikvBackupLister
+	jobLister     batchlisters.JobLister
+	recorder      record.EventRecorder
+}
+
+// UpdateRestore executes the core logic loop for a restore.
+func (rc *defaultRestoreControl) UpdateRestore(restore *v1alpha1.TikvRestore) error {
+	if restore.DeletionTimestamp != nil || restore.IsFinished() {
+		return nil
+	}
+
+	status := restore.Status.DeepCopy()
+	err := rc.syncRestore(restore, status)
+	if apiequality.Semantic.DeepEqual(status, &restore.Status) {
+		return err
+	}
+	if _, updateErr := rc.statusControl.UpdateRestoreStatus(restore, status); updateErr != nil {
+		return errorutils.NewAggregate([]error{err, updateErr})
+	}
+	switch status.Phase {
+	case v1alpha1.RestoreComplete:
+		rc.recorder.Eventf(restore, corev1.EventTypeNormal, "RestoreComplete", "TikvCluster %s is restored", restore.Spec.Cluster)
+	case v1alpha1.RestoreFailed:
+		cond := v1alpha1.GetRestoreCondition(status, v1alpha1.TikvRestoreFailed)
+		rc.recorder.Eventf(restore, corev1.EventTypeWarning, "RestoreFailed", "restore failed: %s", cond.Message)
+	}
+	return err
+}
+
+// ReleaseCluster removes the restoring annotation of the clusters held by the restore
+func (rc *defaultRestoreControl) ReleaseCluster(ns, restoreName string) error {
+	tcs, err := rc.tcLister.TikvClusters(ns).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, tc := range tcs {
+		if tc.Annotations[label.AnnRestoring] != restoreName {
+			continue
+		}
+		if err := rc.releaseCluster(ns, tc.Name, restoreName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errorutils.NewAggregate(errs)
+}
+
+func (rc *defaultRestoreControl) syncRestore(restore *v1alpha1.TikvRestore, status *v1alpha1.TikvRestoreStatus) error {
+	ns := restore.GetNamespace()
+	name := restore.GetName()
+
+	s3, path, err := rc.getBackupSource(restore, status)
+	if err != nil || s3 == nil {
+		return err
+	}
+
+	tc, err := rc.tcLister.TikvClusters(ns).Get(restore.Spec.Cluster)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if errors.IsNotFound(err) {
+		if restore.Spec.ClusterSpec == nil {
+			pendRestore(status, "ClusterNotFound", fmt.Sprintf("TikvCluster %s/%s does not exist", ns, restore.Spec.Cluster))
+			return controller.RequeueErrorf("TikvRestore: [%s/%s], waiting for TikvCluster %s to be created", ns, name, restore.Spec.Cluster)
+		}
+		if err := rc.createCluster(restore); err != nil {
+			return err
+		}
+		pendRestore(status, "ClusterCreated", fmt.Sprintf("TikvCluster %s/%s is created", ns, restore.Spec.Cluster))
+		return controller.RequeueErrorf("TikvRestore: [%s/%s], waiting for TikvCluster %s running", ns, name, restore.Spec.Cluster)
+	}
+
+	job, err := rc.jobLister.Jobs(ns).Get(controller.RestoreJobName(name))
+	if errors.IsNotFound(err) {
+		// hold the tikv statefulset of the cluster before restoring the data to it
+		if err := rc.holdCluster(ns, tc.Name, name); err != nil {
+			return err
+		}
+		if !tc.PDIsAvailable() || !tc.TiKVAllStoresReady() {
+			pendRestore(status, "ClusterNotReady", fmt.Sprintf("TikvCluster %s/%s is not ready", ns, tc.Name))
+			return controller.RequeueErrorf("TikvRestore: [%s/%s], waiting for TikvCluster %s running", ns, name, tc.Name)
+		}
+		if err := rc.control.Create(restore, getRestoreJob(tc, restore, s3, path), true); err != nil {
+			return err
+		}
+		now := metav1.Now()
+		status.Phase = v1alpha1.RestoreRunning
+		status.TimeStarted = &now
+		v1alpha1.UpdateRestoreCondition(status, v1alpha1.TikvRestoreCondition{
+			Type:   v1alpha1.TikvRestoreScheduled,
+			Status: corev1.ConditionTrue,
+			Reason: "JobCreated",
+		})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(job, restore) {
+		return fmt.Errorf("TikvRestore: [%s/%s], job %s already exists and is not controlled by the restore", ns, name, job.Name)
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue || (cond.Type != batchv1.JobComplete && cond.Type != batchv1.JobFailed) {
+			continue
+		}
+		// the restore is finished, resume syncing the tikv statefulset
+		if err := rc.releaseCluster(ns, tc.Name, name); err != nil {
+			return err
+		}
+		if cond.Type == batchv1.JobFailed {
+			failRestore(status, cond.Reason, cond.Message)
+			status.TimeCompleted = &cond.LastTransitionTime
+			return nil
+		}
+		status.Phase = v1alpha1.RestoreComplete
+		status.TimeCompleted = job.Status.CompletionTime
+		if status.TimeCompleted == nil {
+			status.TimeCompleted = &cond.LastTransitionTime
+		}
+		v1alpha1.UpdateRestoreCondition(status, v1alpha1.TikvRestoreCondition{
+			Type:   v1alpha1.TikvRestoreComplete,
+			Status: corev1.ConditionTrue,
+			Reason: "JobComplete",
+		})
+		return nil
+	}
+	status.Phase = v1alpha1.RestoreRunning
+	return nil
+}
+
+// getBackupSource returns the storage and the path of the backup to restore, the storage is nil
+// if the backup is not available
+func (rc *defaultRestoreControl) getBackupSource(restore *v1alpha1.TikvRestore, status *v1alpha1.TikvRestoreStatus) (*v1alpha1.S3StorageProvider, string, error) {
+	ns := restore.GetNamespace()
+	name := restore.GetName()
+
+	if restore.Spec.Backup == "" {
+		if restore.Spec.S3 == nil || restore.Spec.BackupPath == "" {
+			failRestore(status, "InvalidStorage", "neither the backup nor the storage and the path of the backup is specified")
+			return nil, "", nil
+		}
+		return restore.Spec.S3, restore.Spec.BackupPath, nil
+	}
+
+	backup, err := rc.backupLister.TikvBackups(ns).Get(restore.Spec.Backup)
+	if errors.IsNotFound(err) {
+		pendRestore(status, "BackupNotFound", fmt.Sprintf("TikvBackup %s/%s does not exist", ns, restore.Spec.Backup))
+		return nil, "", controller.RequeueErrorf("TikvRestore: [%s/%s], waiting for TikvBackup %s to be created", ns, name, restore.Spec.Backup)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	switch backup.Status.Phase {
+	case v1alpha1.BackupComplete:
+		return backup.Spec.S3, backup.Status.BackupPath, nil
+	case v1alpha1.BackupFailed:
+		failRestore(status, "BackupFailed", fmt.Sprintf("TikvBackup %s/%s failed", ns, backup.Name))
+		return nil, "", nil
+	default:
+		pendRestore(status, "BackupNotComplete", fmt.Sprintf("TikvBackup %s/%s is not complete", ns, backup.Name))
+		return nil, "", controller.RequeueErrorf("TikvRestore: [%s/%s], waiting for TikvBackup %s to complete", ns, name, backup.Name)
+	}
+}
+
+// createCluster creates the TikvCluster from the ClusterSpec of the restore, the tikv statefulset
+// is held by the restore from the beginning
+func (rc *defaultRestoreControl) createCluster(restore *v1alpha1.TikvRestore) error {
+	tc := &v1alpha1.TikvCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        restore.Spec.Cluster,
+			Namespace:   restore.Namespace,
+			Annotations: map[string]string{label.AnnRestoring: restore.Name},
+		},
+		Spec: *restore.Spec.ClusterSpec.DeepCopy(),
+	}
+	// the cluster outlives the restore
+	return rc.control.Create(restore, tc, false)
+}
+
+// holdCluster sets the restoring annotation of the cluster to stop syncing its tikv statefulset
+func (rc *defaultRestoreControl) holdCluster(ns, tcName, restoreName string) error {
+	return rc.updateClusterAnnotations(ns, tcName, func(annotations map[string]string) {
+		annotations[label.AnnRestoring] = restoreName
+	})
+}
+
+// releaseCluster removes the restoring annotation of the cluster if it is held by the restore
+func (rc *defaultRestoreControl) releaseCluster(ns, tcName, restoreName string) error {
+	return rc.updateClusterAnnotations(ns, tcName, func(annotations map[string]string) {
+		if annotations[label.AnnRestoring] == restoreName {
+			delete(annotations, label.AnnRestoring)
+		}
+	})
+}
+
+// updateClusterAnnotations applies fn to the annotations of the latest cluster and updates the cluster
+// if they are changed
+func (rc *defaultRestoreControl) updateClusterAnnotations(ns, tcName string, fn func(map[string]string)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		tc, err := rc.cli.TikvV1alpha1().TikvClusters(ns).Get(tcName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		annotations := map[string]string{}
+		for k, v := range tc.Annotations {
+			annotations[k] = v
+		}
+		fn(annotations)
+		if apiequality.Semantic.DeepEqual(annotations, tc.Annotations) {
+			return nil
+		}
+		tc.Annotations = annotations
+		_, err = rc.cli.TikvV1alpha1().TikvClusters(ns).Update(tc)
+		return err
+	})
+}
+
+// pendRestore records the reason the BR job of the restore can not be created yet
+func pendRestore(status *v1alpha1.TikvRestoreStatus, reason, message string) {
+	status.Phase = v1alpha1.RestorePending
+	v1alpha1.UpdateRestoreCondition(status, v1alpha1.TikvRestoreCondition{
+		Type:    v1alpha1.TikvRestoreScheduled,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+func failRestore(status *v1alpha1.TikvRestoreStatus, reason, message string) {
+	status.Phase = v1alpha1.RestoreFailed
+	v1alpha1.UpdateRestoreCondition(status, v1alpha1.TikvRestoreCondition{
+		Type:    v1alpha1.TikvRestoreFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+var _ RestoreControlInterface = &defaultRestoreControl{}
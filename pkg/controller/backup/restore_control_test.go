@@ -0,0 +1,306 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned/fake"
+	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRestoreControlUpdateRestore(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		name          string
+		update        func(restore *v1alpha1.TikvRestore, backup *v1alpha1.TikvBackup, tc *v1alpha1.TikvCluster)
+		noBackup      bool
+		noCluster     bool
+		jobCondition  *batchv1.JobCondition
+		expectErr     bool
+		expectPhase   v1alpha1.RestorePhase
+		expectReason  string
+		expectJob     bool
+		expectHolding bool
+	}{
+		{
+			name: "backup is not specified",
+			update: func(restore *v1alpha1.TikvRestore, _ *v1alpha1.TikvBackup, _ *v1alpha1.TikvCluster) {
+				restore.Spec.Backup = ""
+			},
+			expectPhase:  v1alpha1.RestoreFailed,
+			expectReason: "InvalidStorage",
+		},
+		{
+			name:         "backup does not exist",
+			noBackup:     true,
+			expectErr:    true,
+			expectPhase:  v1alpha1.RestorePending,
+			expectReason: "BackupNotFound",
+		},
+		{
+			name: "backup is running",
+			update: func(_ *v1alpha1.TikvRestore, backup *v1alpha1.TikvBackup, _ *v1alpha1.TikvCluster) {
+				backup.Status.Phase = v1alpha1.BackupRunning
+			},
+			expectErr:    true,
+			expectPhase:  v1alpha1.RestorePending,
+			expectReason: "BackupNotComplete",
+		},
+		{
+			name: "backup failed",
+			update: func(_ *v1alpha1.TikvRestore, backup *v1alpha1.TikvBackup, _ *v1alpha1.TikvCluster) {
+				backup.Status.Phase = v1alpha1.BackupFailed
+			},
+			expectPhase:  v1alpha1.RestoreFailed,
+			expectReason: "BackupFailed",
+		},
+		{
+			name:         "cluster does not exist",
+			noCluster:    true,
+			expectErr:    true,
+			expectPhase:  v1alpha1.RestorePending,
+			expectReason: "ClusterNotFound",
+		},
+		{
+			name: "cluster is created from the cluster spec",
+			update: func(restore *v1alpha1.TikvRestore, _ *v1alpha1.TikvBackup, tc *v1alpha1.TikvCluster) {
+				restore.Spec.ClusterSpec = tc.Spec.DeepCopy()
+			},
+			noCluster:    true,
+			expectErr:    true,
+			expectPhase:  v1alpha1.RestorePending,
+			expectReason: "ClusterCreated",
+		},
+		{
+			name: "tikv is not ready",
+			update: func(_ *v1alpha1.TikvRestore, _ *v1alpha1.TikvBackup, tc *v1alpha1.TikvCluster) {
+				tc.Status.TiKV.Stores = nil
+			},
+			expectErr:     true,
+			expectPhase:   v1alpha1.RestorePending,
+			expectReason:  "ClusterNotReady",
+			expectHolding: true,
+		},
+		{
+			name:          "job is created",
+			expectPhase:   v1alpha1.RestoreRunning,
+			expectReason:  "JobCreated",
+			expectJob:     true,
+			expectHolding: true,
+		},
+		{
+			name:         "job is complete",
+			jobCondition: &batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			expectPhase:  v1alpha1.RestoreComplete,
+			expectReason: "JobComplete",
+		},
+		{
+			name:         "job failed",
+			jobCondition: &batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+			expectPhase:  v1alpha1.RestoreFailed,
+			expectReason: "BackoffLimitExceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore := newTikvRestore()
+			backup := newTikvBackup()
+			backup.Status = v1alpha1.TikvBackupStatus{Phase: v1alpha1.BackupComplete, BackupPath: "s3://bucket/prefix/default-demo"}
+			tc := newTikvCluster()
+			tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", State: v1alpha1.TiKVStateUp},
+				"2": {ID: "2", State: v1alpha1.TiKVStateUp},
+				"3": {ID: "3", State: v1alpha1.TiKVStateUp},
+			}
+			if tt.update != nil {
+				tt.update(restore, backup, tc)
+			}
+			rc, cli, genericControl, indexers := newFakeRestoreControl()
+			g.Expect(indexers.restore.Add(restore)).To(Succeed())
+			if !tt.noBackup {
+				g.Expect(indexers.backup.Add(backup)).To(Succeed())
+			}
+			if !tt.noCluster {
+				if tt.jobCondition != nil {
+					tc.Annotations = map[string]string{label.AnnRestoring: restore.Name}
+				}
+				g.Expect(indexers.tc.Add(tc)).To(Succeed())
+				_, err := cli.TikvV1alpha1().TikvClusters(tc.Namespace).Create(tc)
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if tt.jobCondition != nil {
+				job := getRestoreJob(tc, restore, backup.Spec.S3, backup.Status.BackupPath)
+				job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(restore, controller.RestoreControllerKind)}
+				job.Status.Conditions = []batchv1.JobCondition{*tt.jobCondition}
+				g.Expect(indexers.job.Add(job)).To(Succeed())
+			}
+
+			err := rc.UpdateRestore(restore)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+
+			obj, _, err := indexers.restore.Get(restore)
+			g.Expect(err).NotTo(HaveOccurred())
+			updated := obj.(*v1alpha1.TikvRestore)
+			g.Expect(updated.Status.Phase).To(Equal(tt.expectPhase))
+			reasons := []string{}
+			for _, cond := range updated.Status.Conditions {
+				reasons = append(reasons, cond.Reason)
+			}
+			g.Expect(reasons).To(ContainElement(tt.expectReason))
+
+			job := &batchv1.Job{}
+			exist, err := genericControl.Exist(client.ObjectKey{Namespace: restore.Namespace, Name: controller.RestoreJobName(restore.Name)}, job)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(exist).To(Equal(tt.expectJob))
+			if tt.expectJob {
+				g.Expect(job.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--storage=s3://bucket/prefix/default-demo"))
+			}
+
+			if restore.Spec.ClusterSpec != nil {
+				created := &v1alpha1.TikvCluster{}
+				g.Expect(genericControl.FakeCli.Get(context.TODO(), client.ObjectKey{Namespace: restore.Namespace, Name: restore.Spec.Cluster}, created)).To(Succeed())
+				g.Expect(created.Annotations).To(HaveKeyWithValue(label.AnnRestoring, restore.Name))
+				g.Expect(created.Spec).To(Equal(*restore.Spec.ClusterSpec))
+			} else if !tt.noCluster {
+				g.Expect(isHolding(cli, tc)).To(Equal(tt.expectHolding))
+			}
+		})
+	}
+}
+
+func TestRestoreControlReleaseCluster(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	rc, cli, _, indexers := newFakeRestoreControl()
+	for _, name := range []string{"held", "held-by-other", "not-held"} {
+		tc := newTikvCluster()
+		tc.Name = name
+		switch name {
+		case "held":
+			tc.Annotations = map[string]string{label.AnnRestoring: "demo"}
+		case "held-by-other":
+			tc.Annotations = map[string]string{label.AnnRestoring: "other"}
+		}
+		g.Expect(indexers.tc.Add(tc)).To(Succeed())
+		_, err := cli.TikvV1alpha1().TikvClusters(tc.Namespace).Create(tc)
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+
+	g.Expect(rc.ReleaseCluster(corev1.NamespaceDefault, "demo")).To(Succeed())
+	for name, holding := range map[string]bool{"held": false, "held-by-other": true, "not-held": false} {
+		tc := newTikvCluster()
+		tc.Name = name
+		g.Expect(isHolding(cli, tc)).To(Equal(holding), name)
+	}
+}
+
+func TestGetRestoreJob(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	restore := newTikvRestore()
+	restore.Spec.BR = &v1alpha1.BRConfig{BaseImage: "my-registry/br"}
+	tc := newTikvCluster()
+	s3 := &v1alpha1.S3StorageProvider{Provider: "aws", Bucket: "bucket", SecretName: "s3-secret"}
+
+	job := getRestoreJob(tc, restore, s3, "s3://bucket/demo")
+	g.Expect(job.Name).To(Equal("demo-restore"))
+	g.Expect(job.Labels).To(HaveKeyWithValue(label.RestoreLabelKey, "demo"))
+	container := job.Spec.Template.Spec.Containers[0]
+	g.Expect(container.Image).To(Equal("my-registry/br:v4.0.0"))
+	g.Expect(container.Args).To(Equal([]string{
+		"restore", "raw",
+		"--storage=s3://bucket/demo",
+		"--pd=demo-pd.default:2379",
+		"--s3.provider=aws",
+		"--send-credentials-to-tikv=true",
+	}))
+	g.Expect(container.Env).To(HaveLen(2))
+	g.Expect(job.Spec.Template.Spec.Volumes).To(BeEmpty())
+}
+
+type restoreIndexers struct {
+	restore cache.Indexer
+	backup  cache.Indexer
+	tc      cache.Indexer
+	job     cache.Indexer
+}
+
+func newFakeRestoreControl() (RestoreControlInterface, versioned.Interface, *controller.FakeGenericControl, restoreIndexers) {
+	cli := fake.NewSimpleClientset()
+	kubeCli := kubefake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(cli, 0)
+	restoreInformer := informerFactory.Tikv().V1alpha1().TikvRestores()
+	backupInformer := informerFactory.Tikv().V1alpha1().TikvBackups()
+	tcInformer := informerFactory.Tikv().V1alpha1().TikvClusters()
+	jobInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Batch().V1().Jobs()
+	statusControl := controller.NewFakeRestoreStatusControl(restoreInformer)
+	genericControl := controller.NewFakeGenericControl()
+
+	rc := NewDefaultRestoreControl(cli, statusControl, genericControl, tcInformer.Lister(), backupInformer.Lister(), jobInformer.Lister(), record.NewFakeRecorder(10))
+	return rc, cli, genericControl, restoreIndexers{
+		restore: restoreInformer.Informer().GetIndexer(),
+		backup:  backupInformer.Informer().GetIndexer(),
+		tc:      tcInformer.Informer().GetIndexer(),
+		job:     jobInformer.Informer().GetIndexer(),
+	}
+}
+
+// isHolding returns whether the tikv statefulset of the cluster is held by a restore
+func isHolding(cli versioned.Interface, tc *v1alpha1.TikvCluster) bool {
+	latest, err := cli.TikvV1alpha1().TikvClusters(tc.Namespace).Get(tc.Name, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	_, ok := latest.Annotations[label.AnnRestoring]
+	return ok
+}
+
+func newTikvRestore() *v1alpha1.TikvRestore {
+	return &v1alpha1.TikvRestore{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "TikvRestore",
+			APIVersion: "tikv.org/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo",
+			Namespace: corev1.NamespaceDefault,
+			UID:       "restore-uid",
+		},
+		Spec: v1alpha1.TikvRestoreSpec{
+			Cluster: "demo",
+			Backup:  "demo",
+		},
+	}
+}
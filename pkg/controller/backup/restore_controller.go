@@ -0,0 +1,196 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	perrors "github.com/pingcap/errors"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
+	listers "github.com/tikv/tikv-operator/pkg/client/listers/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	eventv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RestoreController controls tikvrestores.
+type RestoreController struct {
+	// control returns an interface capable of syncing a restore.
+	// Abstracted out for testing.
+	control RestoreControlInterface
+	// restoreLister is able to list/get tikvrestores from a shared informer's store
+	restoreLister listers.TikvRestoreLister
+	// restoreListerSynced returns true if the tikvrestore shared informer has synced at least once
+	restoreListerSynced cache.InformerSynced
+	// jobListerSynced returns true if the job shared informer has synced at least once
+	jobListerSynced cache.InformerSynced
+	// tikvrestores that need to be synced.
+	queue workqueue.RateLimitingInterface
+}
+
+// NewRestoreController creates a tikvrestore controller.
+func NewRestoreController(
+	kubeCli kubernetes.Interface,
+	cli versioned.Interface,
+	genericCli client.Client,
+	informerFactory informers.SharedInformerFactory,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+) *RestoreController {
+	eventBroadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{QPS: 1})
+	eventBroadcaster.StartLogging(klog.V(2).Infof)
+	eventBroadcaster.StartRecordingToSink(&eventv1.EventSinkImpl{
+		Interface: eventv1.New(kubeCli.CoreV1().RESTClient()).Events("")})
+	recorder := eventBroadcaster.NewRecorder(v1alpha1.Scheme, corev1.EventSource{Component: "tikv-restore-controller"})
+
+	restoreInformer := informerFactory.Tikv().V1alpha1().TikvRestores()
+	tcInformer := informerFactory.Tikv().V1alpha1().TikvClusters()
+	backupInformer := informerFactory.Tikv().V1alpha1().TikvBackups()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+
+	rc := &RestoreController{
+		control: NewDefaultRestoreControl(
+			cli,
+			controller.NewRealRestoreStatusControl(cli, restoreInformer.Lister()),
+			controller.NewRealGenericControl(genericCli, recorder),
+			tcInformer.Lister(),
+			backupInformer.Lister(),
+			jobInformer.Lister(),
+			recorder,
+		),
+		queue: workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(),
+			"tikvrestore",
+		),
+	}
+
+	restoreInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: rc.enqueueRestore,
+		UpdateFunc: func(old, cur interface{}) {
+			rc.enqueueRestore(cur)
+		},
+		DeleteFunc: rc.enqueueRestore,
+	})
+	rc.restoreLister = restoreInformer.Lister()
+	rc.restoreListerSynced = restoreInformer.Informer().HasSynced
+
+	jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, cur interface{}) {
+			rc.updateJob(cur)
+		},
+	})
+	rc.jobListerSynced = jobInformer.Informer().HasSynced
+
+	return rc
+}
+
+// Run runs the tikvrestore controller.
+func (rc *RestoreController) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer rc.queue.ShutDown()
+
+	klog.Info("Starting tikvrestore controller")
+	defer klog.Info("Shutting down tikvrestore controller")
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(rc.worker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+// worker runs a worker goroutine that invokes processNextWorkItem until the the controller's queue is closed
+func (rc *RestoreController) worker() {
+	for rc.processNextWorkItem() {
+	}
+}
+
+// processNextWorkItem dequeues items, processes them, and marks them done. It enforces that the syncHandler is never
+// invoked concurrently with the same key.
+func (rc *RestoreController) processNextWorkItem() bool {
+	key, quit := rc.queue.Get()
+	if quit {
+		return false
+	}
+	defer rc.queue.Done(key)
+	if err := rc.sync(key.(string)); err != nil {
+		if perrors.Find(err, controller.IsRequeueError) != nil {
+			klog.Infof("TikvRestore: %v, still need sync: %v, requeuing", key.(string), err)
+		} else {
+			utilruntime.HandleError(fmt.Errorf("TikvRestore: %v, sync failed %v, requeuing", key.(string), err))
+		}
+		controller.Requeue(rc.queue, key, err)
+	} else {
+		rc.queue.Forget(key)
+	}
+	return true
+}
+
+// sync syncs the given tikvrestore.
+func (rc *RestoreController) sync(key string) error {
+	startTime := time.Now()
+	defer func() {
+		klog.V(4).Infof("Finished syncing TikvRestore %q (%v)", key, time.Since(startTime))
+	}()
+
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	restore, err := rc.restoreLister.TikvRestores(ns).Get(name)
+	if errors.IsNotFound(err) {
+		klog.Infof("TikvRestore has been deleted %v", key)
+		return rc.control.ReleaseCluster(ns, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	return rc.control.UpdateRestore(restore.DeepCopy())
+}
+
+// enqueueRestore enqueues the given tikvrestore in the work queue.
+func (rc *RestoreController) enqueueRestore(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Cound't get key for object %+v: %v", obj, err))
+		return
+	}
+	rc.queue.Add(key)
+}
+
+// updateJob enqueues the tikvrestore controlling the BR job once the job is updated, e.g. completed
+func (rc *RestoreController) updateJob(cur interface{}) {
+	job := cur.(*batchv1.Job)
+	controllerRef := metav1.GetControllerOf(job)
+	if controllerRef == nil || controllerRef.Kind != controller.RestoreControllerKind.Kind {
+		return
+	}
+	klog.V(4).Infof("Job %s/%s updated, TikvRestore: %s/%s", job.Namespace, job.Name, job.Namespace, controllerRef.Name)
+	rc.queue.Add(fmt.Sprintf("%s/%s", job.Namespace, controllerRef.Name))
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getRestoreJob returns the job running BR to restore the backup at the given path to the tikv cluster
+func getRestoreJob(tc *v1alpha1.TikvCluster, restore *v1alpha1.TikvRestore, s3 *v1alpha1.S3StorageProvider, path string) *batchv1.Job {
+	args := []string{"restore", "raw", fmt.Sprintf("--storage=%s", path)}
+	args = append(args, brArgs(tc, restore.Spec.BR)...)
+	args = append(args, s3Args(s3)...)
+	if restore.Spec.BR != nil {
+		args = append(args, restore.Spec.BR.Options...)
+	}
+
+	jobMeta := metav1.ObjectMeta{
+		Name:      controller.RestoreJobName(restore.Name),
+		Namespace: restore.Namespace,
+		Labels:    label.New().Instance(tc.GetInstanceName()).Restore(restore.Name).Labels(),
+	}
+	container := corev1.Container{
+		Image:     restore.BRImage(tc),
+		Args:      args,
+		Env:       s3Env(s3),
+		Resources: restore.Spec.Resources,
+	}
+	return newBRJob(tc, jobMeta, container, restore.Spec.ServiceAccount)
+}
@@ -0,0 +1,234 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// VolumeSnapshotGVK is the GroupVersionKind of the CSI VolumeSnapshot
+	VolumeSnapshotGVK = schema.GroupVersionKind{
+		Group:   "snapshot.storage.k8s.io",
+		Version: "v1beta1",
+		Kind:    "VolumeSnapshot",
+	}
+	// VolumeSnapshotContentGVK is the GroupVersionKind of the CSI VolumeSnapshotContent
+	VolumeSnapshotContentGVK = VolumeSnapshotGVK.GroupVersion().WithKind("VolumeSnapshotContent")
+)
+
+// balanceSchedulers are the PD schedulers moving the regions and the leaders between the stores, which are paused
+// while the volume snapshots are taken. The evict-leader schedulers of the upgrade, the node drain and the cordoned
+// stores are left running.
+var balanceSchedulers = []string{"balance-leader-scheduler", "balance-region-scheduler"}
+
+// syncVolumeSnapshotBackup takes the CSI VolumeSnapshots of the volumes of all TiKV stores at once
+// with the PD balance schedulers paused, so that no region moves between the stores while the snapshots are taken
+func (bc *defaultBackupControl) syncVolumeSnapshotBackup(tc *v1alpha1.TikvCluster, backup *v1alpha1.TikvBackup, status *v1alpha1.TikvBackupStatus) error {
+	ns := backup.GetNamespace()
+	name := backup.GetName()
+	pdClient := controller.GetPDClient(bc.pdControl, tc)
+
+	if len(status.VolumeSnapshots) == 0 {
+		if !tc.PDIsAvailable() || !tc.TiKVAllStoresReady() {
+			pendBackup(status, "ClusterNotAvailable", fmt.Sprintf("TikvCluster %s/%s is not ready", ns, tc.Name))
+			return controller.RequeueErrorf("TikvBackup: [%s/%s], waiting for TikvCluster %s running", ns, name, tc.Name)
+		}
+		selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+		if err != nil {
+			return err
+		}
+		pvcs, err := bc.pvcLister.PersistentVolumeClaims(ns).List(selector)
+		if err != nil {
+			return err
+		}
+		if len(pvcs) == 0 {
+			pendBackup(status, "VolumeNotFound", fmt.Sprintf("TikvCluster %s/%s has no tikv volumes", ns, tc.Name))
+			return controller.RequeueErrorf("TikvBackup: [%s/%s], waiting for the tikv volumes of TikvCluster %s", ns, name, tc.Name)
+		}
+		sort.Slice(pvcs, func(i, j int) bool { return pvcs[i].Name < pvcs[j].Name })
+
+		if err := pauseBalanceSchedulers(pdClient, backup, status); err != nil {
+			return err
+		}
+		snapshots := []v1alpha1.TiKVVolumeSnapshot{}
+		for _, pvc := range pvcs {
+			snapshot := getVolumeSnapshot(tc, backup, pvc)
+			exist, err := bc.control.Exist(client.ObjectKey{Namespace: ns, Name: snapshot.GetName()}, newVolumeSnapshot())
+			if meta.IsNoMatchError(err) {
+				failBackup(status, "VolumeSnapshotNotSupported", "the VolumeSnapshot CRD is not installed")
+				return resumeSchedulers(pdClient, status)
+			}
+			if err != nil {
+				return err
+			}
+			if !exist {
+				if err := bc.control.Create(backup, snapshot, true); err != nil {
+					return err
+				}
+			}
+			snapshots = append(snapshots, v1alpha1.TiKVVolumeSnapshot{
+				StoreID: pvc.Labels[label.StoreIDLabelKey],
+				PVCName: pvc.Name,
+				Name:    snapshot.GetName(),
+			})
+		}
+
+		now := metav1.Now()
+		status.Phase = v1alpha1.BackupRunning
+		status.TimeStarted = &now
+		status.VolumeSnapshots = snapshots
+		v1alpha1.UpdateBackupCondition(status, v1alpha1.TikvBackupCondition{
+			Type:   v1alpha1.TikvBackupScheduled,
+			Status: corev1.ConditionTrue,
+			Reason: "SnapshotsCreated",
+		})
+		return controller.RequeueErrorf("TikvBackup: [%s/%s], waiting for the volume snapshots to be taken", ns, name)
+	}
+
+	allTaken, allReady := true, true
+	for i := range status.VolumeSnapshots {
+		vs := &status.VolumeSnapshots[i]
+		snapshot := newVolumeSnapshot()
+		exist, err := bc.control.Exist(client.ObjectKey{Namespace: ns, Name: vs.Name}, snapshot)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			failBackup(status, "VolumeSnapshotNotFound", fmt.Sprintf("VolumeSnapshot %s/%s is deleted", ns, vs.Name))
+			return resumeSchedulers(pdClient, status)
+		}
+		if message, _, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message"); message != "" {
+			failBackup(status, "VolumeSnapshotFailed", fmt.Sprintf("VolumeSnapshot %s/%s failed: %s", ns, vs.Name, message))
+			return resumeSchedulers(pdClient, status)
+		}
+		if _, taken, _ := unstructured.NestedString(snapshot.Object, "status", "creationTime"); !taken {
+			allTaken = false
+		}
+		vs.ReadyToUse, _, _ = unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+		vs.VolumeSnapshotContentName, _, _ = unstructured.NestedString(snapshot.Object, "status", "boundVolumeSnapshotContentName")
+		if vs.VolumeSnapshotContentName != "" && vs.SnapshotHandle == "" {
+			content := &unstructured.Unstructured{}
+			content.SetGroupVersionKind(VolumeSnapshotContentGVK)
+			exist, err := bc.control.Exist(client.ObjectKey{Name: vs.VolumeSnapshotContentName}, content)
+			if err != nil {
+				return err
+			}
+			if exist {
+				vs.SnapshotHandle, _, _ = unstructured.NestedString(content.Object, "status", "snapshotHandle")
+			}
+		}
+		allReady = allReady && vs.ReadyToUse
+	}
+
+	if !allTaken {
+		return controller.RequeueErrorf("TikvBackup: [%s/%s], waiting for the volume snapshots to be taken", ns, name)
+	}
+	// the data is captured once the snapshots are taken, the upload of the snapshots does not
+	// need the schedulers to be paused
+	if err := resumeSchedulers(pdClient, status); err != nil {
+		return err
+	}
+	if !allReady {
+		return controller.RequeueErrorf("TikvBackup: [%s/%s], waiting for the volume snapshots to be ready", ns, name)
+	}
+	now := metav1.Now()
+	status.Phase = v1alpha1.BackupComplete
+	status.TimeCompleted = &now
+	v1alpha1.UpdateBackupCondition(status, v1alpha1.TikvBackupCondition{
+		Type:   v1alpha1.TikvBackupComplete,
+		Status: corev1.ConditionTrue,
+		Reason: "SnapshotsReady",
+	})
+	return nil
+}
+
+// pauseBalanceSchedulers pauses the balance schedulers running in PD, which are recorded in the status to be
+// resumed by the backup. The schedulers paused by the user are left to the user.
+func pauseBalanceSchedulers(pdClient pdapi.PDClient, backup *v1alpha1.TikvBackup, status *v1alpha1.TikvBackupStatus) error {
+	if len(status.PausedSchedulers) == 0 {
+		schedulers, err := pdClient.GetSchedulers(false)
+		if err != nil {
+			return err
+		}
+		paused, err := pdClient.GetSchedulers(true)
+		if err != nil {
+			return err
+		}
+		running := sets.NewString(schedulers...).Difference(sets.NewString(paused...))
+		for _, scheduler := range balanceSchedulers {
+			if running.Has(scheduler) {
+				status.PausedSchedulers = append(status.PausedSchedulers, scheduler)
+			}
+		}
+	}
+	if len(status.PausedSchedulers) == 0 {
+		return nil
+	}
+	return pdClient.PauseSchedulers(status.PausedSchedulers, backup.PauseSchedulingDuration())
+}
+
+// resumeSchedulers resumes the schedulers paused by the backup
+func resumeSchedulers(pdClient pdapi.PDClient, status *v1alpha1.TikvBackupStatus) error {
+	if len(status.PausedSchedulers) == 0 {
+		return nil
+	}
+	if err := pdClient.PauseSchedulers(status.PausedSchedulers, 0); err != nil {
+		return err
+	}
+	status.PausedSchedulers = nil
+	return nil
+}
+
+func newVolumeSnapshot() *unstructured.Unstructured {
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(VolumeSnapshotGVK)
+	return snapshot
+}
+
+// getVolumeSnapshot returns the VolumeSnapshot of the PVC of a TiKV store taken by the backup
+func getVolumeSnapshot(tc *v1alpha1.TikvCluster, backup *v1alpha1.TikvBackup, pvc *corev1.PersistentVolumeClaim) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": pvc.Name,
+		},
+	}
+	if className := backup.Spec.VolumeSnapshot.VolumeSnapshotClassName; className != nil {
+		spec["volumeSnapshotClassName"] = *className
+	}
+
+	snapshot := newVolumeSnapshot()
+	snapshot.SetName(fmt.Sprintf("%s-%s", backup.Name, pvc.Name))
+	snapshot.SetNamespace(backup.Namespace)
+	snapshotLabels := label.New().Instance(tc.GetInstanceName()).Backup(backup.Name)
+	if storeID := pvc.Labels[label.StoreIDLabelKey]; storeID != "" {
+		snapshotLabels[label.StoreIDLabelKey] = storeID
+	}
+	snapshot.SetLabels(snapshotLabels.Labels())
+	snapshot.Object["spec"] = spec
+	return snapshot
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	tcinformers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions/tikv/v1alpha1"
+	listers "github.com/tikv/tikv-operator/pkg/client/listers/tikv/v1alpha1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+)
+
+// BackupStatusControlInterface updates the status of TikvBackups
+type BackupStatusControlInterface interface {
+	UpdateBackupStatus(*v1alpha1.TikvBackup, *v1alpha1.TikvBackupStatus) (*v1alpha1.TikvBackup, error)
+}
+
+type realBackupStatusControl struct {
+	cli          versioned.Interface
+	backupLister listers.TikvBackupLister
+}
+
+// NewRealBackupStatusControl creates a new BackupStatusControlInterface
+func NewRealBackupStatusControl(cli versioned.Interface, backupLister listers.TikvBackupLister) BackupStatusControlInterface {
+	return &realBackupStatusControl{
+		cli,
+		backupLister,
+	}
+}
+
+func (rbc *realBackupStatusControl) UpdateBackupStatus(backup *v1alpha1.TikvBackup, newStatus *v1alpha1.TikvBackupStatus) (*v1alpha1.TikvBackup, error) {
+	ns := backup.GetNamespace()
+	name := backup.GetName()
+
+	backup = backup.DeepCopy()
+	backup.Status = *newStatus
+	var updated *v1alpha1.TikvBackup
+
+	// don't wait due to limited number of clients, but backoff after the default number of steps
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var updateErr error
+		updated, updateErr = rbc.cli.TikvV1alpha1().TikvBackups(ns).UpdateStatus(backup)
+		if updateErr == nil {
+			klog.Infof("TikvBackup: [%s/%s] status updated successfully", ns, name)
+			return nil
+		}
+		klog.Errorf("failed to update the status of TikvBackup: [%s/%s], error: %v", ns, name, updateErr)
+
+		if latest, err := rbc.backupLister.TikvBackups(ns).Get(name); err == nil {
+			// make a copy so we don't mutate the shared cache
+			backup = latest.DeepCopy()
+			backup.Status = *newStatus
+		} else {
+			utilruntime.HandleError(fmt.Errorf("error getting updated TikvBackup %s/%s from lister: %v", ns, name, err))
+		}
+
+		return updateErr
+	})
+	return updated, err
+}
+
+// FakeBackupStatusControl is a fake BackupStatusControlInterface
+type FakeBackupStatusControl struct {
+	BackupLister              listers.TikvBackupLister
+	BackupIndexer             cache.Indexer
+	updateBackupStatusTracker RequestTracker
+}
+
+// NewFakeBackupStatusControl returns a FakeBackupStatusControl
+func NewFakeBackupStatusControl(backupInformer tcinformers.TikvBackupInformer) *FakeBackupStatusControl {
+	return &FakeBackupStatusControl{
+		backupInformer.Lister(),
+		backupInformer.Informer().GetIndexer(),
+		RequestTracker{},
+	}
+}
+
+// SetUpdateBackupStatusError sets the error attributes of updateBackupStatusTracker
+func (fbc *FakeBackupStatusControl) SetUpdateBackupStatusError(err error, after int) {
+	fbc.updateBackupStatusTracker.SetError(err).SetAfter(after)
+}
+
+// UpdateBackupStatus updates the status of the TikvBackup
+func (fbc *FakeBackupStatusControl) UpdateBackupStatus(backup *v1alpha1.TikvBackup, newStatus *v1alpha1.TikvBackupStatus) (*v1alpha1.TikvBackup, error) {
+	defer fbc.updateBackupStatusTracker.Inc()
+	if fbc.updateBackupStatusTracker.ErrorReady() {
+		defer fbc.updateBackupStatusTracker.Reset()
+		return backup, fbc.updateBackupStatusTracker.GetError()
+	}
+
+	backup = backup.DeepCopy()
+	backup.Status = *newStatus
+	return backup, fbc.BackupIndexer.Update(backup)
+}
@@ -0,0 +1,336 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	listers "github.com/tikv/tikv-operator/pkg/client/listers/tikv/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type dryRunStatefulSetControl struct {
+	setLister appslisters.StatefulSetLister
+}
+
+// NewDryRunStatefulSetControl returns a StatefulSetControlInterface which only logs the
+// intended operations and the diff against the cached objects, without issuing any writes
+func NewDryRunStatefulSetControl(setLister appslisters.StatefulSetLister) StatefulSetControlInterface {
+	return &dryRunStatefulSetControl{setLister}
+}
+
+func (sc *dryRunStatefulSetControl) CreateStatefulSet(tc *v1alpha1.TikvCluster, set *apps.StatefulSet) error {
+	logDryRun("create", "StatefulSet", tc, set.GetName(), nil, set)
+	return nil
+}
+
+func (sc *dryRunStatefulSetControl) UpdateStatefulSet(tc *v1alpha1.TikvCluster, set *apps.StatefulSet) (*apps.StatefulSet, error) {
+	var existing runtime.Object
+	if old, err := sc.setLister.StatefulSets(tc.GetNamespace()).Get(set.GetName()); err == nil {
+		existing = old
+	}
+	logDryRun("update", "StatefulSet", tc, set.GetName(), existing, set)
+	return set, nil
+}
+
+func (sc *dryRunStatefulSetControl) DeleteStatefulSet(tc *v1alpha1.TikvCluster, set *apps.StatefulSet) error {
+	logDryRun("delete", "StatefulSet", tc, set.GetName(), nil, nil)
+	return nil
+}
+
+var _ StatefulSetControlInterface = &dryRunStatefulSetControl{}
+
+type dryRunServiceControl struct {
+	svcLister corelisters.ServiceLister
+}
+
+// NewDryRunServiceControl returns a ServiceControlInterface which only logs the
+// intended operations and the diff against the cached objects, without issuing any writes
+func NewDryRunServiceControl(svcLister corelisters.ServiceLister) ServiceControlInterface {
+	return &dryRunServiceControl{svcLister}
+}
+
+func (sc *dryRunServiceControl) CreateService(tc *v1alpha1.TikvCluster, svc *corev1.Service) error {
+	logDryRun("create", "Service", tc, svc.GetName(), nil, svc)
+	return nil
+}
+
+func (sc *dryRunServiceControl) UpdateService(tc *v1alpha1.TikvCluster, svc *corev1.Service) (*corev1.Service, error) {
+	var existing runtime.Object
+	if old, err := sc.svcLister.Services(tc.GetNamespace()).Get(svc.GetName()); err == nil {
+		existing = old
+	}
+	logDryRun("update", "Service", tc, svc.GetName(), existing, svc)
+	return svc, nil
+}
+
+func (sc *dryRunServiceControl) DeleteService(tc *v1alpha1.TikvCluster, svc *corev1.Service) error {
+	logDryRun("delete", "Service", tc, svc.GetName(), nil, nil)
+	return nil
+}
+
+var _ ServiceControlInterface = &dryRunServiceControl{}
+
+type dryRunGenericControl struct {
+	client client.Client
+}
+
+// NewDryRunGenericControl returns a GenericControlInterface which reads through the given client,
+// but only logs the intended operations and the diff against the existing objects, without issuing any writes
+func NewDryRunGenericControl(client client.Client) GenericControlInterface {
+	return &dryRunGenericControl{client}
+}
+
+func (c *dryRunGenericControl) CreateOrUpdate(controller, obj runtime.Object, mergeFn MergeFn, setOwnerFlag bool) (runtime.Object, error) {
+	desired := obj.DeepCopyObject()
+	if setOwnerFlag {
+		if err := setControllerReference(controller, desired); err != nil {
+			return desired, err
+		}
+	}
+
+	existing, err := EmptyClone(obj)
+	if err != nil {
+		return nil, err
+	}
+	key, err := client.ObjectKeyFromObject(existing)
+	if err != nil {
+		return nil, err
+	}
+	err = c.client.Get(context.TODO(), key, existing)
+	if errors.IsNotFound(err) {
+		logDryRun("create", objectKind(desired), controller, key.Name, nil, desired)
+		return desired, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if setOwnerFlag {
+		if err := setControllerReference(controller, existing); err != nil {
+			return nil, err
+		}
+	}
+	mutated := existing.DeepCopyObject()
+	if err := mergeFn(mutated, desired); err != nil {
+		return nil, err
+	}
+	if !apiequality.Semantic.DeepEqual(existing, mutated) {
+		logDryRun("update", objectKind(mutated), controller, key.Name, existing, mutated)
+	}
+	return mutated, nil
+}
+
+func (c *dryRunGenericControl) Create(controller, obj runtime.Object, setOwnerFlag bool) error {
+	desired := obj.DeepCopyObject()
+	if setOwnerFlag {
+		if err := setControllerReference(controller, desired); err != nil {
+			return err
+		}
+	}
+	logDryRun("create", objectKind(desired), controller, objectName(desired), nil, desired)
+	return nil
+}
+
+func (c *dryRunGenericControl) UpdateStatus(obj runtime.Object) error {
+	logDryRun("update status of", objectKind(obj), nil, objectName(obj), nil, obj)
+	return nil
+}
+
+func (c *dryRunGenericControl) Exist(key client.ObjectKey, obj runtime.Object) (bool, error) {
+	err := c.client.Get(context.TODO(), key, obj)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return true, err
+	}
+	return true, nil
+}
+
+func (c *dryRunGenericControl) Delete(controller, obj runtime.Object) error {
+	logDryRun("delete", objectKind(obj), controller, objectName(obj), nil, nil)
+	return nil
+}
+
+var _ GenericControlInterface = &dryRunGenericControl{}
+
+type dryRunPodControl struct {
+	podLister corelisters.PodLister
+}
+
+// NewDryRunPodControl returns a PodControlInterface which only logs the
+// intended operations and the diff against the cached objects, without issuing any writes
+func NewDryRunPodControl(podLister corelisters.PodLister) PodControlInterface {
+	return &dryRunPodControl{podLister}
+}
+
+func (pc *dryRunPodControl) UpdateMetaInfo(tc *v1alpha1.TikvCluster, pod *corev1.Pod) (*corev1.Pod, error) {
+	logDryRun("update the meta info of", "Pod", tc, pod.GetName(), nil, nil)
+	return pod, nil
+}
+
+func (pc *dryRunPodControl) UpdatePod(tc *v1alpha1.TikvCluster, pod *corev1.Pod) (*corev1.Pod, error) {
+	var existing runtime.Object
+	if old, err := pc.podLister.Pods(tc.GetNamespace()).Get(pod.GetName()); err == nil {
+		existing = old
+	}
+	logDryRun("update", "Pod", tc, pod.GetName(), existing, pod)
+	return pod, nil
+}
+
+func (pc *dryRunPodControl) DeletePod(tc *v1alpha1.TikvCluster, pod *corev1.Pod) error {
+	logDryRun("delete", "Pod", tc, pod.GetName(), nil, nil)
+	return nil
+}
+
+var _ PodControlInterface = &dryRunPodControl{}
+
+type dryRunPVCControl struct {
+	pvcLister corelisters.PersistentVolumeClaimLister
+}
+
+// NewDryRunPVCControl returns a PVCControlInterface which only logs the
+// intended operations and the diff against the cached objects, without issuing any writes
+func NewDryRunPVCControl(pvcLister corelisters.PersistentVolumeClaimLister) PVCControlInterface {
+	return &dryRunPVCControl{pvcLister}
+}
+
+func (pc *dryRunPVCControl) UpdateMetaInfo(tc *v1alpha1.TikvCluster, pvc *corev1.PersistentVolumeClaim, _ *corev1.Pod) (*corev1.PersistentVolumeClaim, error) {
+	logDryRun("update the meta info of", "PersistentVolumeClaim", tc, pvc.GetName(), nil, nil)
+	return pvc, nil
+}
+
+func (pc *dryRunPVCControl) UpdatePVC(tc *v1alpha1.TikvCluster, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
+	var existing runtime.Object
+	if old, err := pc.pvcLister.PersistentVolumeClaims(tc.GetNamespace()).Get(pvc.GetName()); err == nil {
+		existing = old
+	}
+	logDryRun("update", "PersistentVolumeClaim", tc, pvc.GetName(), existing, pvc)
+	return pvc, nil
+}
+
+func (pc *dryRunPVCControl) DeletePVC(tc *v1alpha1.TikvCluster, pvc *corev1.PersistentVolumeClaim) error {
+	logDryRun("delete", "PersistentVolumeClaim", tc, pvc.GetName(), nil, nil)
+	return nil
+}
+
+func (pc *dryRunPVCControl) GetPVC(name, namespace string) (*corev1.PersistentVolumeClaim, error) {
+	return pc.pvcLister.PersistentVolumeClaims(namespace).Get(name)
+}
+
+var _ PVCControlInterface = &dryRunPVCControl{}
+
+type dryRunPVControl struct{}
+
+// NewDryRunPVControl returns a PVControlInterface which only logs the intended operations,
+// without issuing any writes
+func NewDryRunPVControl() PVControlInterface {
+	return &dryRunPVControl{}
+}
+
+func (pc *dryRunPVControl) PatchPVReclaimPolicy(obj runtime.Object, pv *corev1.PersistentVolume, reclaimPolicy corev1.PersistentVolumeReclaimPolicy) error {
+	logDryRun(fmt.Sprintf("patch the reclaim policy to %s of", reclaimPolicy), "PersistentVolume", obj, pv.GetName(), nil, nil)
+	return nil
+}
+
+func (pc *dryRunPVControl) UpdateMetaInfo(obj runtime.Object, pv *corev1.PersistentVolume) (*corev1.PersistentVolume, error) {
+	logDryRun("update the meta info of", "PersistentVolume", obj, pv.GetName(), nil, nil)
+	return pv, nil
+}
+
+var _ PVControlInterface = &dryRunPVControl{}
+
+type dryRunTikvClusterControl struct {
+	tcLister listers.TikvClusterLister
+}
+
+// NewDryRunTikvClusterControl returns a TikvClusterControlInterface which only logs the
+// intended updates and the diff against the cached objects, without issuing any writes
+func NewDryRunTikvClusterControl(tcLister listers.TikvClusterLister) TikvClusterControlInterface {
+	return &dryRunTikvClusterControl{tcLister}
+}
+
+func (tcc *dryRunTikvClusterControl) UpdateTikvCluster(tc *v1alpha1.TikvCluster, _ *v1alpha1.TikvClusterStatus, _ *v1alpha1.TikvClusterStatus) (*v1alpha1.TikvCluster, error) {
+	var existing runtime.Object
+	if old, err := tcc.tcLister.TikvClusters(tc.GetNamespace()).Get(tc.GetName()); err == nil {
+		existing = old
+	}
+	logDryRun("update", "TikvCluster", tc, tc.GetName(), existing, tc)
+	return tc, nil
+}
+
+var _ TikvClusterControlInterface = &dryRunTikvClusterControl{}
+
+// logDryRun logs the operation that would have been issued for an object owned by controller,
+// along with the diff between the existing and the desired object if any
+func logDryRun(verb, kind string, controller runtime.Object, name string, existing, desired runtime.Object) {
+	owner := "<none>"
+	if accessor, ok := controller.(metav1.Object); ok {
+		owner = fmt.Sprintf("%s/%s", accessor.GetNamespace(), accessor.GetName())
+	}
+	klog.Infof("[dry-run] would %s %s %s of %s", verb, kind, name, owner)
+	if desired == nil {
+		return
+	}
+	diff, err := objectDiff(existing, desired)
+	if err != nil {
+		klog.Warningf("[dry-run] failed to diff %s %s of %s: %v", kind, name, owner, err)
+		return
+	}
+	klog.Infof("[dry-run] %s %s of %s diff (-existing +desired):\n%s", kind, name, owner, diff)
+}
+
+// objectDiff returns the diff between the unstructured representations of the existing and the
+// desired object, so that the fields without exported members like resource.Quantity are comparable
+func objectDiff(existing, desired runtime.Object) (string, error) {
+	var a, b map[string]interface{}
+	var err error
+	if existing != nil {
+		if a, err = runtime.DefaultUnstructuredConverter.ToUnstructured(existing); err != nil {
+			return "", err
+		}
+	}
+	if b, err = runtime.DefaultUnstructuredConverter.ToUnstructured(desired); err != nil {
+		return "", err
+	}
+	return cmp.Diff(a, b), nil
+}
+
+func objectKind(obj runtime.Object) string {
+	gvk, err := InferObjectKind(obj)
+	if err != nil {
+		return fmt.Sprintf("%T", obj)
+	}
+	return gvk.Kind
+}
+
+func objectName(obj runtime.Object) string {
+	if accessor, ok := obj.(metav1.Object); ok {
+		return accessor.GetName()
+	}
+	return ""
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/scheme"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDryRunStatefulSetControl(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvCluster()
+	old := newStatefulSet(tc, "pd")
+	old.Spec.Replicas = Int32Ptr(3)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	g.Expect(indexer.Add(old)).To(Succeed())
+	control := NewDryRunStatefulSetControl(appslisters.NewStatefulSetLister(indexer))
+
+	g.Expect(control.CreateStatefulSet(tc, newStatefulSet(tc, "tikv"))).To(Succeed())
+
+	set := old.DeepCopy()
+	set.Spec.Replicas = Int32Ptr(5)
+	updated, err := control.UpdateStatefulSet(tc, set)
+	g.Expect(err).To(Succeed())
+	g.Expect(updated).To(Equal(set))
+	cached, err := appslisters.NewStatefulSetLister(indexer).StatefulSets(tc.Namespace).Get(set.Name)
+	g.Expect(err).To(Succeed())
+	g.Expect(*cached.Spec.Replicas).To(Equal(int32(3)))
+
+	g.Expect(control.DeleteStatefulSet(tc, set)).To(Succeed())
+}
+
+func TestDryRunServiceControl(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvCluster()
+	old := newService(tc, "pd")
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	g.Expect(indexer.Add(old)).To(Succeed())
+	control := NewDryRunServiceControl(corelisters.NewServiceLister(indexer))
+
+	g.Expect(control.CreateService(tc, newService(tc, "tikv"))).To(Succeed())
+
+	svc := old.DeepCopy()
+	svc.Spec.Type = corev1.ServiceTypeNodePort
+	updated, err := control.UpdateService(tc, svc)
+	g.Expect(err).To(Succeed())
+	g.Expect(updated).To(Equal(svc))
+
+	g.Expect(control.DeleteService(tc, svc)).To(Succeed())
+}
+
+func TestDryRunPodControl(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvCluster()
+	old := newPod(tc)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	g.Expect(indexer.Add(old)).To(Succeed())
+	control := NewDryRunPodControl(corelisters.NewPodLister(indexer))
+
+	pod := old.DeepCopy()
+	pod.Labels = map[string]string{"a": "b"}
+	updated, err := control.UpdatePod(tc, pod)
+	g.Expect(err).To(Succeed())
+	g.Expect(updated).To(Equal(pod))
+	cached, err := corelisters.NewPodLister(indexer).Pods(tc.Namespace).Get(pod.Name)
+	g.Expect(err).To(Succeed())
+	g.Expect(cached.Labels).NotTo(HaveKey("a"))
+
+	g.Expect(control.DeletePod(tc, pod)).To(Succeed())
+}
+
+func TestDryRunPVCControl(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvCluster()
+	old := newPVC(tc)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	g.Expect(indexer.Add(old)).To(Succeed())
+	control := NewDryRunPVCControl(corelisters.NewPersistentVolumeClaimLister(indexer))
+
+	pvc := old.DeepCopy()
+	pvc.Annotations = map[string]string{"a": "b"}
+	updated, err := control.UpdatePVC(tc, pvc)
+	g.Expect(err).To(Succeed())
+	g.Expect(updated).To(Equal(pvc))
+	cached, err := control.GetPVC(pvc.Name, tc.Namespace)
+	g.Expect(err).To(Succeed())
+	g.Expect(cached.Annotations).NotTo(HaveKey("a"))
+
+	g.Expect(control.DeletePVC(tc, pvc)).To(Succeed())
+}
+
+func TestDryRunGenericControl(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	existing := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "existing",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: Int32Ptr(1),
+		},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme.Scheme, existing.DeepCopy())
+	control := NewDryRunGenericControl(cli)
+	controller := newTikvCluster()
+	mergeFn := func(existing, desired runtime.Object) error {
+		existing.(*appsv1.Deployment).Spec.Replicas = desired.(*appsv1.Deployment).Spec.Replicas
+		return nil
+	}
+
+	// the object to create is returned but not persisted
+	desired := existing.DeepCopy()
+	desired.Name = "desired"
+	result, err := control.CreateOrUpdate(controller, desired, mergeFn, true)
+	g.Expect(err).To(Succeed())
+	g.Expect(result.(*appsv1.Deployment).OwnerReferences).To(HaveLen(1))
+	err = cli.Get(context.TODO(), client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "desired"}, &appsv1.Deployment{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	// the merged object is returned but the existing one is not updated
+	desired = existing.DeepCopy()
+	desired.Spec.Replicas = Int32Ptr(3)
+	result, err = control.CreateOrUpdate(controller, desired, mergeFn, false)
+	g.Expect(err).To(Succeed())
+	g.Expect(*result.(*appsv1.Deployment).Spec.Replicas).To(Equal(int32(3)))
+	current := &appsv1.Deployment{}
+	g.Expect(cli.Get(context.TODO(), client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "existing"}, current)).To(Succeed())
+	g.Expect(*current.Spec.Replicas).To(Equal(int32(1)))
+
+	g.Expect(control.Delete(controller, current)).To(Succeed())
+	exist, err := control.Exist(client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: "existing"}, &appsv1.Deployment{})
+	g.Expect(err).To(Succeed())
+	g.Expect(exist).To(BeTrue())
+}
+
+func TestObjectDiff(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvCluster()
+	old := newStatefulSet(tc, "pd")
+	old.Spec.Replicas = Int32Ptr(3)
+	set := old.DeepCopy()
+	set.Spec.Replicas = Int32Ptr(5)
+
+	diff, err := objectDiff(old, set)
+	g.Expect(err).To(Succeed())
+	g.Expect(diff).To(ContainSubstring("int64(3)"))
+	g.Expect(diff).To(ContainSubstring("int64(5)"))
+
+	diff, err = objectDiff(set, set)
+	g.Expect(err).To(Succeed())
+	g.Expect(diff).To(BeEmpty())
+}
@@ -16,11 +16,13 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/klog"
 )
 
@@ -30,6 +32,9 @@ const (
 
 	// LastAppliedConfigAnnotation is annotation key of last applied configuration
 	LastAppliedConfigAnnotation = "tikv.org/last-applied-configuration"
+
+	// LastAppliedAnnotationsAnnotation is annotation key of the annotation keys last applied by the operator
+	LastAppliedAnnotationsAnnotation = "tikv.org/last-applied-annotations"
 )
 
 // GetDeploymentLastAppliedPodTemplate set last applied pod template from Deployment's annotation
@@ -56,7 +61,8 @@ func DeploymentPodSpecChanged(newDep *appsv1.Deployment, oldDep *appsv1.Deployme
 	return !apiequality.Semantic.DeepEqual(newDep.Spec.Template.Spec, lastAppliedPodTemplate)
 }
 
-// SetServiceLastAppliedConfigAnnotation set last applied config info to Service's annotation
+// SetServiceLastAppliedConfigAnnotation set last applied config info to Service's annotation,
+// the keys of the other annotations of the Service are recorded as the ones managed by the operator
 func SetServiceLastAppliedConfigAnnotation(svc *corev1.Service) error {
 	b, err := json.Marshal(svc.Spec)
 	if err != nil {
@@ -66,9 +72,131 @@ func SetServiceLastAppliedConfigAnnotation(svc *corev1.Service) error {
 		svc.Annotations = map[string]string{}
 	}
 	svc.Annotations[LastAppliedConfigAnnotation] = string(b)
+
+	var keys []string
+	for k := range svc.Annotations {
+		if k != LastAppliedConfigAnnotation && k != LastAppliedAnnotationsAnnotation {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		delete(svc.Annotations, LastAppliedAnnotationsAnnotation)
+		return nil
+	}
+	sort.Strings(keys)
+	b, err = json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	svc.Annotations[LastAppliedAnnotationsAnnotation] = string(b)
 	return nil
 }
 
+// getServiceLastAppliedAnnotations returns the annotation keys last applied to the Service by the operator
+func getServiceLastAppliedAnnotations(svc *corev1.Service) ([]string, error) {
+	applied, ok := svc.Annotations[LastAppliedAnnotationsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var keys []string
+	if err := json.Unmarshal([]byte(applied), &keys); err != nil {
+		return nil, fmt.Errorf("unmarshal applied annotations of Service: [%s/%s] failed, error: %v", svc.GetNamespace(), svc.GetName(), err)
+	}
+	return keys, nil
+}
+
+// ServiceAnnotationsEqual checks whether the annotations of the new Service are applied to the old Service
+// and none of the annotations last applied by the operator has been dropped from the new Service
+func ServiceAnnotationsEqual(newSvc, oldSvc *corev1.Service) (bool, error) {
+	for k, v := range newSvc.Annotations {
+		if cur, ok := oldSvc.Annotations[k]; !ok || cur != v {
+			return false, nil
+		}
+	}
+	keys, err := getServiceLastAppliedAnnotations(oldSvc)
+	if err != nil {
+		return false, err
+	}
+	for _, k := range keys {
+		if _, ok := newSvc.Annotations[k]; !ok {
+			if _, exist := oldSvc.Annotations[k]; exist {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// MergeService three-way merges the new Service into the old Service based on the last applied config,
+// the operator managed fields and annotations are reconciled while the ones added by others,
+// e.g. the annotations written by the cloud load balancer controllers, are preserved
+func MergeService(newSvc, oldSvc *corev1.Service) (*corev1.Service, error) {
+	desired := newSvc.DeepCopy()
+	if err := SetServiceLastAppliedConfigAnnotation(desired); err != nil {
+		return nil, err
+	}
+	appliedKeys, err := getServiceLastAppliedAnnotations(oldSvc)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := oldSvc.DeepCopy()
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	for _, k := range appliedKeys {
+		if _, ok := desired.Annotations[k]; !ok {
+			delete(svc.Annotations, k)
+		}
+	}
+	if _, ok := desired.Annotations[LastAppliedAnnotationsAnnotation]; !ok {
+		delete(svc.Annotations, LastAppliedAnnotationsAnnotation)
+	}
+	for k, v := range desired.Annotations {
+		svc.Annotations[k] = v
+	}
+
+	modified, err := json.Marshal(newSvc.Spec)
+	if err != nil {
+		return nil, err
+	}
+	current, err := json.Marshal(oldSvc.Spec)
+	if err != nil {
+		return nil, err
+	}
+	// the Services created by the earlier versions of the operator may have no last applied config,
+	// nothing is removed from the old Service in this case
+	original := modified
+	if lastAppliedConfig, ok := oldSvc.Annotations[LastAppliedConfigAnnotation]; ok {
+		original = []byte(lastAppliedConfig)
+	}
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(corev1.ServiceSpec{})
+	if err != nil {
+		return nil, err
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, patchMeta, true)
+	if err != nil {
+		return nil, fmt.Errorf("create three-way merge patch of Service: [%s/%s] failed, error: %v", oldSvc.GetNamespace(), oldSvc.GetName(), err)
+	}
+	merged, err := strategicpatch.StrategicMergePatchUsingLookupPatchMeta(current, patch, patchMeta)
+	if err != nil {
+		return nil, fmt.Errorf("apply three-way merge patch of Service: [%s/%s] failed, error: %v", oldSvc.GetNamespace(), oldSvc.GetName(), err)
+	}
+	spec := corev1.ServiceSpec{}
+	if err := json.Unmarshal(merged, &spec); err != nil {
+		return nil, err
+	}
+	// the node ports kept from the old Service are not allowed once the Service is switched to ClusterIP
+	if spec.Type == corev1.ServiceTypeClusterIP {
+		for i := range spec.Ports {
+			spec.Ports[i].NodePort = 0
+		}
+		spec.HealthCheckNodePort = 0
+	}
+	svc.Spec = spec
+	return svc, nil
+}
+
 // ServiceEqual compares the new Service's spec with old Service's last applied config
 func ServiceEqual(newSvc, oldSvc *corev1.Service) (bool, error) {
 	oldSpec := corev1.ServiceSpec{}
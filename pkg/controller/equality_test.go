@@ -0,0 +1,142 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeService(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newService := func(annotations map[string]string, svcType corev1.ServiceType, ports ...int32) *corev1.Service {
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default", Annotations: annotations},
+			Spec:       corev1.ServiceSpec{Type: svcType},
+		}
+		for _, port := range ports {
+			svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{Name: "port", Port: port, Protocol: corev1.ProtocolTCP})
+		}
+		return svc
+	}
+	// applied returns the Service as created by the operator and then modified by the others
+	applied := func(svc *corev1.Service, modify func(*corev1.Service)) *corev1.Service {
+		svc = svc.DeepCopy()
+		g.Expect(SetServiceLastAppliedConfigAnnotation(svc)).To(Succeed())
+		if modify != nil {
+			modify(svc)
+		}
+		return svc
+	}
+
+	type testcase struct {
+		name   string
+		oldSvc *corev1.Service
+		newSvc *corev1.Service
+		equal  bool
+		expect func(*corev1.Service)
+	}
+
+	tests := []testcase{
+		{
+			name: "external annotations and fields are preserved",
+			oldSvc: applied(newService(map[string]string{"a": "1"}, corev1.ServiceTypeLoadBalancer, 20160), func(svc *corev1.Service) {
+				svc.Annotations["lb.example.com/status"] = "ready"
+				svc.Spec.ClusterIP = "10.0.0.1"
+				svc.Spec.Ports[0].NodePort = 30001
+			}),
+			newSvc: newService(map[string]string{"a": "1"}, corev1.ServiceTypeLoadBalancer, 20160),
+			equal:  true,
+			expect: func(svc *corev1.Service) {
+				g.Expect(svc.Annotations).To(HaveKeyWithValue("a", "1"))
+				g.Expect(svc.Annotations).To(HaveKeyWithValue("lb.example.com/status", "ready"))
+				g.Expect(svc.Spec.ClusterIP).To(Equal("10.0.0.1"))
+				g.Expect(svc.Spec.Ports[0].NodePort).To(Equal(int32(30001)))
+			},
+		},
+		{
+			name: "operator managed annotations are updated and removed",
+			oldSvc: applied(newService(map[string]string{"a": "1", "b": "2"}, corev1.ServiceTypeClusterIP, 20160), func(svc *corev1.Service) {
+				svc.Annotations["lb.example.com/status"] = "ready"
+			}),
+			newSvc: newService(map[string]string{"a": "3"}, corev1.ServiceTypeClusterIP, 20160),
+			equal:  false,
+			expect: func(svc *corev1.Service) {
+				g.Expect(svc.Annotations).To(HaveKeyWithValue("a", "3"))
+				g.Expect(svc.Annotations).NotTo(HaveKey("b"))
+				g.Expect(svc.Annotations).To(HaveKeyWithValue("lb.example.com/status", "ready"))
+				g.Expect(svc.Annotations).To(HaveKeyWithValue(LastAppliedAnnotationsAnnotation, `["a"]`))
+			},
+		},
+		{
+			name:   "all operator managed annotations are removed",
+			oldSvc: applied(newService(map[string]string{"a": "1"}, corev1.ServiceTypeClusterIP, 20160), nil),
+			newSvc: newService(nil, corev1.ServiceTypeClusterIP, 20160),
+			equal:  false,
+			expect: func(svc *corev1.Service) {
+				g.Expect(svc.Annotations).NotTo(HaveKey("a"))
+				g.Expect(svc.Annotations).NotTo(HaveKey(LastAppliedAnnotationsAnnotation))
+				g.Expect(svc.Annotations).To(HaveKey(LastAppliedConfigAnnotation))
+			},
+		},
+		{
+			name: "operator managed ports are reconciled",
+			oldSvc: applied(newService(nil, corev1.ServiceTypeNodePort, 20160, 20180), func(svc *corev1.Service) {
+				svc.Spec.Ports[0].NodePort = 30001
+				svc.Spec.Ports[1].NodePort = 30002
+			}),
+			newSvc: newService(nil, corev1.ServiceTypeNodePort, 20160),
+			equal:  true,
+			expect: func(svc *corev1.Service) {
+				g.Expect(svc.Spec.Ports).To(HaveLen(1))
+				g.Expect(svc.Spec.Ports[0].Port).To(Equal(int32(20160)))
+				g.Expect(svc.Spec.Ports[0].NodePort).To(Equal(int32(30001)))
+			},
+		},
+		{
+			name: "node ports are dropped once switched to ClusterIP",
+			oldSvc: applied(newService(nil, corev1.ServiceTypeNodePort, 20160), func(svc *corev1.Service) {
+				svc.Spec.Ports[0].NodePort = 30001
+			}),
+			newSvc: newService(nil, corev1.ServiceTypeClusterIP, 20160),
+			equal:  true,
+			expect: func(svc *corev1.Service) {
+				g.Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+				g.Expect(svc.Spec.Ports[0].NodePort).To(BeZero())
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Log(test.name)
+		equal, err := ServiceAnnotationsEqual(test.newSvc, test.oldSvc)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(equal).To(Equal(test.equal))
+
+		svc, err := MergeService(test.newSvc, test.oldSvc)
+		g.Expect(err).NotTo(HaveOccurred())
+		test.expect(svc)
+
+		equal, err = ServiceAnnotationsEqual(test.newSvc, svc)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(equal).To(BeTrue())
+		equal, err = ServiceEqual(test.newSvc, svc)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(equal).To(BeTrue())
+	}
+}
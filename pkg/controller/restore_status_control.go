@@ -0,0 +1,110 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	tcinformers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions/tikv/v1alpha1"
+	listers "github.com/tikv/tikv-operator/pkg/client/listers/tikv/v1alpha1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+)
+
+// RestoreStatusControlInterface updates the status of TikvRestores
+type RestoreStatusControlInterface interface {
+	UpdateRestoreStatus(*v1alpha1.TikvRestore, *v1alpha1.TikvRestoreStatus) (*v1alpha1.TikvRestore, error)
+}
+
+type realRestoreStatusControl struct {
+	cli           versioned.Interface
+	restoreLister listers.TikvRestoreLister
+}
+
+// NewRealRestoreStatusControl creates a new RestoreStatusControlInterface
+func NewRealRestoreStatusControl(cli versioned.Interface, restoreLister listers.TikvRestoreLister) RestoreStatusControlInterface {
+	return &realRestoreStatusControl{
+		cli,
+		restoreLister,
+	}
+}
+
+func (rrc *realRestoreStatusControl) UpdateRestoreStatus(restore *v1alpha1.TikvRestore, newStatus *v1alpha1.TikvRestoreStatus) (*v1alpha1.TikvRestore, error) {
+	ns := restore.GetNamespace()
+	name := restore.GetName()
+
+	restore = restore.DeepCopy()
+	restore.Status = *newStatus
+	var updated *v1alpha1.TikvRestore
+
+	// don't wait due to limited number of clients, but backoff after the default number of steps
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var updateErr error
+		updated, updateErr = rrc.cli.TikvV1alpha1().TikvRestores(ns).UpdateStatus(restore)
+		if updateErr == nil {
+			klog.Infof("TikvRestore: [%s/%s] status updated successfully", ns, name)
+			return nil
+		}
+		klog.Errorf("failed to update the status of TikvRestore: [%s/%s], error: %v", ns, name, updateErr)
+
+		if latest, err := rrc.restoreLister.TikvRestores(ns).Get(name); err == nil {
+			// make a copy so we don't mutate the shared cache
+			restore = latest.DeepCopy()
+			restore.Status = *newStatus
+		} else {
+			utilruntime.HandleError(fmt.Errorf("error getting updated TikvRestore %s/%s from lister: %v", ns, name, err))
+		}
+
+		return updateErr
+	})
+	return updated, err
+}
+
+// FakeRestoreStatusControl is a fake RestoreStatusControlInterface
+type FakeRestoreStatusControl struct {
+	RestoreLister              listers.TikvRestoreLister
+	RestoreIndexer             cache.Indexer
+	updateRestoreStatusTracker RequestTracker
+}
+
+// NewFakeRestoreStatusControl returns a FakeRestoreStatusControl
+func NewFakeRestoreStatusControl(restoreInformer tcinformers.TikvRestoreInformer) *FakeRestoreStatusControl {
+	return &FakeRestoreStatusControl{
+		restoreInformer.Lister(),
+		restoreInformer.Informer().GetIndexer(),
+		RequestTracker{},
+	}
+}
+
+// SetUpdateRestoreStatusError sets the error attributes of updateRestoreStatusTracker
+func (frc *FakeRestoreStatusControl) SetUpdateRestoreStatusError(err error, after int) {
+	frc.updateRestoreStatusTracker.SetError(err).SetAfter(after)
+}
+
+// UpdateRestoreStatus updates the status of the TikvRestore
+func (frc *FakeRestoreStatusControl) UpdateRestoreStatus(restore *v1alpha1.TikvRestore, newStatus *v1alpha1.TikvRestoreStatus) (*v1alpha1.TikvRestore, error) {
+	defer frc.updateRestoreStatusTracker.Inc()
+	if frc.updateRestoreStatusTracker.ErrorReady() {
+		defer frc.updateRestoreStatusTracker.Reset()
+		return restore, frc.updateRestoreStatusTracker.GetError()
+	}
+
+	restore = restore.DeepCopy()
+	restore.Status = *newStatus
+	return restore, frc.RestoreIndexer.Update(restore)
+}
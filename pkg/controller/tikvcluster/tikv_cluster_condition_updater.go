@@ -14,12 +14,19 @@
 package tikvcluster
 
 import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	utiltikvcluster "github.com/tikv/tikv-operator/pkg/util/tikvcluster"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 )
 
+// minSupportedTiKVVersion is the minimum version of TiKV supported by the operator, the config format and the
+// APIs of PD and TiKV the operator relies on are not available in the earlier versions
+var minSupportedTiKVVersion = semver.MustParse("v4.0.0")
+
 // TikvClusterConditionUpdater interface that translates cluster state into
 // into tikv cluster status conditions.
 type TikvClusterConditionUpdater interface {
@@ -33,6 +40,9 @@ var _ TikvClusterConditionUpdater = &tikvClusterConditionUpdater{}
 
 func (u *tikvClusterConditionUpdater) Update(tc *v1alpha1.TikvCluster) error {
 	u.updateReadyCondition(tc)
+	u.updateTiKVFailoverSaturatedCondition(tc)
+	u.updateTiKVUnderReplicatedCondition(tc)
+	u.updateTiKVVersionUnsupportedCondition(tc)
 	// in the future, we may return error when we need to Kubernetes API, etc.
 	return nil
 }
@@ -54,6 +64,9 @@ func (u *tikvClusterConditionUpdater) updateReadyCondition(tc *v1alpha1.TikvClus
 	message := ""
 
 	switch {
+	case tc.Spec.Suspend:
+		reason = utiltikvcluster.Suspended
+		message = "TiKV cluster is suspended"
 	case !allStatefulSetsAreUpToDate(tc):
 		reason = utiltikvcluster.StatfulSetNotUpToDate
 		message = "Statefulset(s) are in progress"
@@ -71,3 +84,90 @@ func (u *tikvClusterConditionUpdater) updateReadyCondition(tc *v1alpha1.TikvClus
 	cond := utiltikvcluster.NewTikvClusterCondition(v1alpha1.TikvClusterReady, status, reason, message)
 	utiltikvcluster.SetTikvClusterCondition(&tc.Status, *cond)
 }
+
+func (u *tikvClusterConditionUpdater) updateTiKVFailoverSaturatedCondition(tc *v1alpha1.TikvCluster) {
+	status := v1.ConditionFalse
+	reason := ""
+	message := ""
+
+	maxFailoverCount := tc.Spec.TiKV.MaxFailoverCount
+	switch {
+	case maxFailoverCount == nil || *maxFailoverCount <= 0:
+		reason = utiltikvcluster.FailoverDisabled
+		message = "TiKV failover is disabled"
+	case len(tc.Status.TiKV.FailureStores) >= int(*maxFailoverCount):
+		status = v1.ConditionTrue
+		reason = utiltikvcluster.MaxFailoverCountReached
+		message = fmt.Sprintf("TiKV failure stores reached the max failover count %d, down stores are not replaced", *maxFailoverCount)
+	default:
+		reason = utiltikvcluster.FailoverAvailable
+		message = "TiKV down stores can be replaced"
+	}
+	cond := utiltikvcluster.NewTikvClusterCondition(v1alpha1.TiKVFailoverSaturated, status, reason, message)
+	utiltikvcluster.SetTikvClusterCondition(&tc.Status, *cond)
+}
+
+func (u *tikvClusterConditionUpdater) updateTiKVUnderReplicatedCondition(tc *v1alpha1.TikvCluster) {
+	status := v1.ConditionFalse
+	reason := ""
+	message := ""
+
+	maxReplicas := tc.Status.TiKV.MaxReplicas
+	failureDomains := tc.Status.TiKV.FailureDomains
+	switch {
+	case maxReplicas == 0:
+		status = v1.ConditionUnknown
+		reason = utiltikvcluster.ReplicationUnknown
+		message = "The max-replicas of PD or the TiKV stores are not known yet"
+	case failureDomains < maxReplicas:
+		status = v1.ConditionTrue
+		reason = utiltikvcluster.InsufficientFailureDomains
+		message = fmt.Sprintf("TiKV stores span %d failure domains, fewer than the max-replicas %d", failureDomains, maxReplicas)
+	default:
+		reason = utiltikvcluster.SufficientFailureDomains
+		message = fmt.Sprintf("TiKV stores span %d failure domains for the max-replicas %d", failureDomains, maxReplicas)
+	}
+	cond := utiltikvcluster.NewTikvClusterCondition(v1alpha1.TiKVUnderReplicated, status, reason, message)
+	utiltikvcluster.SetTikvClusterCondition(&tc.Status, *cond)
+}
+
+func (u *tikvClusterConditionUpdater) updateTiKVVersionUnsupportedCondition(tc *v1alpha1.TikvCluster) {
+	status := v1.ConditionFalse
+	reason := ""
+	message := ""
+
+	version := tc.TiKVVersion()
+	v, err := semver.NewVersion(version)
+	var running *semver.Version
+	if runningVersion := tc.TiKVRunningVersion(); runningVersion != "" {
+		running, _ = semver.NewVersion(runningVersion)
+	}
+	switch {
+	case err != nil:
+		status = v1.ConditionUnknown
+		reason = utiltikvcluster.UnknownVersion
+		message = fmt.Sprintf("TiKV version %q can't be parsed from the image tag, it is not checked against the minimum supported version %s",
+			version, minSupportedTiKVVersion.Original())
+	case releaseVersion(v).LessThan(minSupportedTiKVVersion):
+		status = v1.ConditionTrue
+		reason = utiltikvcluster.UnsupportedVersion
+		message = fmt.Sprintf("TiKV version %s is below the minimum supported version %s", version, minSupportedTiKVVersion.Original())
+	case running != nil && v.LessThan(running):
+		status = v1.ConditionTrue
+		reason = utiltikvcluster.VersionDowngrade
+		message = fmt.Sprintf("TiKV version %s is lower than the running version %s", version, running.Original())
+	default:
+		reason = utiltikvcluster.SupportedVersion
+		message = fmt.Sprintf("TiKV version %s is supported", version)
+	}
+	cond := utiltikvcluster.NewTikvClusterCondition(v1alpha1.TiKVVersionUnsupported, status, reason, message)
+	utiltikvcluster.SetTikvClusterCondition(&tc.Status, *cond)
+}
+
+// releaseVersion returns the version without the pre-release and the metadata, so that the pre-releases of the
+// minimum supported version are supported as well
+func releaseVersion(v *semver.Version) *semver.Version {
+	release, _ := v.SetPrerelease("")
+	release, _ = release.SetMetadata("")
+	return &release
+}
@@ -14,6 +14,7 @@
 package tikvcluster
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -21,6 +22,7 @@ import (
 	utiltikvcluster "github.com/tikv/tikv-operator/pkg/util/tikvcluster"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
 )
 
 func TestTikvClusterConditionUpdater_Ready(t *testing.T) {
@@ -31,6 +33,17 @@ func TestTikvClusterConditionUpdater_Ready(t *testing.T) {
 		wantReason  string
 		wantMessage string
 	}{
+		{
+			name: "suspended",
+			tc: &v1alpha1.TikvCluster{
+				Spec: v1alpha1.TikvClusterSpec{
+					Suspend: true,
+				},
+			},
+			wantStatus:  v1.ConditionFalse,
+			wantReason:  utiltikvcluster.Suspended,
+			wantMessage: "TiKV cluster is suspended",
+		},
 		{
 			name: "statfulset(s) not up to date",
 			tc: &v1alpha1.TikvCluster{
@@ -184,3 +197,163 @@ func TestTikvClusterConditionUpdater_Ready(t *testing.T) {
 		})
 	}
 }
+
+func TestTikvClusterConditionUpdater_TiKVFailoverSaturated(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxFailoverCount *int32
+		failureStores    int
+		wantStatus       v1.ConditionStatus
+		wantReason       string
+	}{
+		{
+			name:             "failover disabled",
+			maxFailoverCount: pointer.Int32Ptr(0),
+			failureStores:    0,
+			wantStatus:       v1.ConditionFalse,
+			wantReason:       utiltikvcluster.FailoverDisabled,
+		},
+		{
+			name:             "below the max failover count",
+			maxFailoverCount: pointer.Int32Ptr(3),
+			failureStores:    2,
+			wantStatus:       v1.ConditionFalse,
+			wantReason:       utiltikvcluster.FailoverAvailable,
+		},
+		{
+			name:             "max failover count reached",
+			maxFailoverCount: pointer.Int32Ptr(3),
+			failureStores:    3,
+			wantStatus:       v1.ConditionTrue,
+			wantReason:       utiltikvcluster.MaxFailoverCountReached,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TikvCluster{}
+			tc.Spec.TiKV.MaxFailoverCount = tt.maxFailoverCount
+			tc.Status.TiKV.FailureStores = map[string]v1alpha1.TiKVFailureStore{}
+			for i := 0; i < tt.failureStores; i++ {
+				id := strconv.Itoa(i)
+				tc.Status.TiKV.FailureStores[id] = v1alpha1.TiKVFailureStore{StoreID: id}
+			}
+			conditionUpdater := &tikvClusterConditionUpdater{}
+			conditionUpdater.Update(tc)
+			cond := utiltikvcluster.GetTikvClusterCondition(tc.Status, v1alpha1.TiKVFailoverSaturated)
+			if diff := cmp.Diff(tt.wantStatus, cond.Status); diff != "" {
+				t.Errorf("unexpected status (-want, +got): %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantReason, cond.Reason); diff != "" {
+				t.Errorf("unexpected reason (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestTikvClusterConditionUpdater_TiKVUnderReplicated(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxReplicas    int32
+		failureDomains int32
+		wantStatus     v1.ConditionStatus
+		wantReason     string
+	}{
+		{
+			name:       "replication unknown",
+			wantStatus: v1.ConditionUnknown,
+			wantReason: utiltikvcluster.ReplicationUnknown,
+		},
+		{
+			name:           "fewer failure domains than max-replicas",
+			maxReplicas:    3,
+			failureDomains: 2,
+			wantStatus:     v1.ConditionTrue,
+			wantReason:     utiltikvcluster.InsufficientFailureDomains,
+		},
+		{
+			name:           "enough failure domains",
+			maxReplicas:    3,
+			failureDomains: 3,
+			wantStatus:     v1.ConditionFalse,
+			wantReason:     utiltikvcluster.SufficientFailureDomains,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TikvCluster{}
+			tc.Status.TiKV.MaxReplicas = tt.maxReplicas
+			tc.Status.TiKV.FailureDomains = tt.failureDomains
+			conditionUpdater := &tikvClusterConditionUpdater{}
+			conditionUpdater.Update(tc)
+			cond := utiltikvcluster.GetTikvClusterCondition(tc.Status, v1alpha1.TiKVUnderReplicated)
+			if diff := cmp.Diff(tt.wantStatus, cond.Status); diff != "" {
+				t.Errorf("unexpected status (-want, +got): %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantReason, cond.Reason); diff != "" {
+				t.Errorf("unexpected reason (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestTikvClusterConditionUpdater_TiKVVersionUnsupported(t *testing.T) {
+	tests := []struct {
+		name         string
+		image        string
+		runningImage string
+		wantStatus   v1.ConditionStatus
+		wantReason   string
+	}{
+		{
+			name:       "version can't be parsed",
+			image:      "pingcap/tikv:latest",
+			wantStatus: v1.ConditionUnknown,
+			wantReason: utiltikvcluster.UnknownVersion,
+		},
+		{
+			name:       "below the minimum supported version",
+			image:      "pingcap/tikv:v3.1.0",
+			wantStatus: v1.ConditionTrue,
+			wantReason: utiltikvcluster.UnsupportedVersion,
+		},
+		{
+			name:       "pre-release of the minimum supported version",
+			image:      "pingcap/tikv:v4.0.0-rc",
+			wantStatus: v1.ConditionFalse,
+			wantReason: utiltikvcluster.SupportedVersion,
+		},
+		{
+			name:         "downgrade",
+			image:        "pingcap/tikv:v4.0.0",
+			runningImage: "pingcap/tikv:v4.0.2",
+			wantStatus:   v1.ConditionTrue,
+			wantReason:   utiltikvcluster.VersionDowngrade,
+		},
+		{
+			name:         "upgrade",
+			image:        "pingcap/tikv:v4.0.2",
+			runningImage: "pingcap/tikv:v4.0.0",
+			wantStatus:   v1.ConditionFalse,
+			wantReason:   utiltikvcluster.SupportedVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TikvCluster{}
+			tc.Spec.TiKV.Image = tt.image
+			tc.Status.TiKV.Image = tt.runningImage
+			conditionUpdater := &tikvClusterConditionUpdater{}
+			conditionUpdater.Update(tc)
+			cond := utiltikvcluster.GetTikvClusterCondition(tc.Status, v1alpha1.TiKVVersionUnsupported)
+			if diff := cmp.Diff(tt.wantStatus, cond.Status); diff != "" {
+				t.Errorf("unexpected status (-want, +got): %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantReason, cond.Reason); diff != "" {
+				t.Errorf("unexpected reason (-want, +got): %s", diff)
+			}
+		})
+	}
+}
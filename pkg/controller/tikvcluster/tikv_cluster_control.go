@@ -18,6 +18,7 @@ import (
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1/defaulting"
 	v1alpha1validation "github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1/validation"
 	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
 	"github.com/tikv/tikv-operator/pkg/manager"
 	"github.com/tikv/tikv-operator/pkg/manager/member"
 	v1 "k8s.io/api/core/v1"
@@ -40,8 +41,13 @@ type ControlInterface interface {
 func NewDefaultTikvClusterControl(
 	tcControl controller.TikvClusterControlInterface,
 	pdMemberManager manager.Manager,
+	tikvAutoScaler manager.Manager,
 	tikvMemberManager manager.Manager,
+	tikvServiceMonitorManager manager.Manager,
 	metaManager manager.Manager,
+	tikvStoresCleaner manager.Manager,
+	tikvPVCCleaner manager.Manager,
+	suspender manager.Manager,
 	orphanPodsCleaner member.OrphanPodsCleaner,
 	discoveryManager member.PDDiscoveryManager,
 	conditionUpdater TikvClusterConditionUpdater,
@@ -49,8 +55,13 @@ func NewDefaultTikvClusterControl(
 	return &defaultTikvClusterControl{
 		tcControl,
 		pdMemberManager,
+		tikvAutoScaler,
 		tikvMemberManager,
+		tikvServiceMonitorManager,
 		metaManager,
+		tikvStoresCleaner,
+		tikvPVCCleaner,
+		suspender,
 		orphanPodsCleaner,
 		discoveryManager,
 		conditionUpdater,
@@ -59,14 +70,19 @@ func NewDefaultTikvClusterControl(
 }
 
 type defaultTikvClusterControl struct {
-	tcControl         controller.TikvClusterControlInterface
-	pdMemberManager   manager.Manager
-	tikvMemberManager manager.Manager
-	metaManager       manager.Manager
-	orphanPodsCleaner member.OrphanPodsCleaner
-	discoveryManager  member.PDDiscoveryManager
-	conditionUpdater  TikvClusterConditionUpdater
-	recorder          record.EventRecorder
+	tcControl                 controller.TikvClusterControlInterface
+	pdMemberManager           manager.Manager
+	tikvAutoScaler            manager.Manager
+	tikvMemberManager         manager.Manager
+	tikvServiceMonitorManager manager.Manager
+	metaManager               manager.Manager
+	tikvStoresCleaner         manager.Manager
+	tikvPVCCleaner            manager.Manager
+	suspender                 manager.Manager
+	orphanPodsCleaner         member.OrphanPodsCleaner
+	discoveryManager          member.PDDiscoveryManager
+	conditionUpdater          TikvClusterConditionUpdater
+	recorder                  record.EventRecorder
 }
 
 // UpdateStatefulSet executes the core logic loop for a tikvcluster.
@@ -78,16 +94,28 @@ func (tcc *defaultTikvClusterControl) UpdateTikvCluster(tc *v1alpha1.TikvCluster
 
 	var errs []error
 	oldStatus := tc.Status.DeepCopy()
+	oldSpec := tc.Spec.DeepCopy()
+	oldFinalizers := append([]string{}, tc.Finalizers...)
 
-	if err := tcc.updateTikvCluster(tc); err != nil {
-		errs = append(errs, err)
-	}
+	if tc.DeletionTimestamp != nil {
+		if err := tcc.finalizeTikvCluster(tc); err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		tcc.syncFinalizers(tc)
 
-	if err := tcc.conditionUpdater.Update(tc); err != nil {
-		errs = append(errs, err)
+		if err := tcc.updateTikvCluster(tc); err != nil {
+			errs = append(errs, err)
+		}
+
+		if err := tcc.conditionUpdater.Update(tc); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	if apiequality.Semantic.DeepEqual(&tc.Status, oldStatus) {
+	// the spec may be changed by the managers, e.g. to adopt the replicas of the statefulset
+	if apiequality.Semantic.DeepEqual(&tc.Status, oldStatus) && apiequality.Semantic.DeepEqual(&tc.Spec, oldSpec) &&
+		apiequality.Semantic.DeepEqual(tc.Finalizers, oldFinalizers) {
 		return errorutils.NewAggregate(errs)
 	}
 	if _, err := tcc.tcControl.UpdateTikvCluster(tc.DeepCopy(), &tc.Status, oldStatus); err != nil {
@@ -109,7 +137,61 @@ func (tcc *defaultTikvClusterControl) validate(tc *v1alpha1.TikvCluster) bool {
 }
 
 func (tcc *defaultTikvClusterControl) defaulting(tc *v1alpha1.TikvCluster) {
-	defaulting.SetTikvClusterDefault(tc)
+	defaulting.SetDefaults_TikvCluster(tc)
+}
+
+// tikvClusterFinalizer is a finalizer of the operator with the cleanup done before it is removed
+type tikvClusterFinalizer struct {
+	name    string
+	enabled bool
+	cleaner manager.Manager
+}
+
+// finalizers returns the finalizers of the operator in the order their cleanups are done:
+//   - offline the tikv stores in pd and wait for them to become tombstone
+//   - delete the tikv statefulset and then the tikv pvcs
+func (tcc *defaultTikvClusterControl) finalizers(tc *v1alpha1.TikvCluster) []tikvClusterFinalizer {
+	return []tikvClusterFinalizer{
+		{
+			name:    label.TiKVStoresCleanupFinalizer,
+			enabled: controller.TiKVStoresCleanupTimeout > 0,
+			cleaner: tcc.tikvStoresCleaner,
+		},
+		{
+			name:    label.TiKVPVCCleanupFinalizer,
+			enabled: tc.Spec.TiKV.PVCDeletePolicy == v1alpha1.PVCDeletePolicyDelete,
+			cleaner: tcc.tikvPVCCleaner,
+		},
+	}
+}
+
+// syncFinalizers adds the finalizers enabled to the tikvcluster and removes the ones disabled
+func (tcc *defaultTikvClusterControl) syncFinalizers(tc *v1alpha1.TikvCluster) {
+	for _, f := range tcc.finalizers(tc) {
+		has := hasFinalizer(tc, f.name)
+		if f.enabled && !has {
+			tc.Finalizers = append(tc.Finalizers, f.name)
+		} else if !f.enabled && has {
+			tc.Finalizers = removeFinalizer(tc.Finalizers, f.name)
+		}
+	}
+}
+
+// finalizeTikvCluster removes the finalizers of the operator from the tikvcluster being deleted one by one once
+// their cleanups are done, the cleanups disabled after the finalizers were added are skipped
+func (tcc *defaultTikvClusterControl) finalizeTikvCluster(tc *v1alpha1.TikvCluster) error {
+	for _, f := range tcc.finalizers(tc) {
+		if !hasFinalizer(tc, f.name) {
+			continue
+		}
+		if f.enabled {
+			if err := f.cleaner.Sync(tc); err != nil {
+				return err
+			}
+		}
+		tc.Finalizers = removeFinalizer(tc.Finalizers, f.name)
+	}
+	return nil
 }
 
 func (tcc *defaultTikvClusterControl) updateTikvCluster(tc *v1alpha1.TikvCluster) error {
@@ -123,6 +205,15 @@ func (tcc *defaultTikvClusterControl) updateTikvCluster(tc *v1alpha1.TikvCluster
 		return err
 	}
 
+	// scale the pd and tikv statefulsets to zero while the cluster is suspended, or back to the desired replicas
+	// once it is resumed, the members are not synced while suspended
+	if err := tcc.suspender.Sync(tc); err != nil {
+		return err
+	}
+	if tc.Spec.Suspend {
+		return nil
+	}
+
 	// works that should do to making the pd cluster current state match the desired state:
 	//   - create or update the pd service
 	//   - create or update the pd headless service
@@ -138,6 +229,11 @@ func (tcc *defaultTikvClusterControl) updateTikvCluster(tc *v1alpha1.TikvCluster
 		return err
 	}
 
+	// scale out the tikv cluster by increasing the replicas if the storage utilization of the stores is high
+	if err := tcc.tikvAutoScaler.Sync(tc); err != nil {
+		return err
+	}
+
 	// works that should do to making the tikv cluster current state match the desired state:
 	//   - waiting for the pd cluster available(pd cluster is in quorum)
 	//   - create or update tikv headless service
@@ -147,7 +243,15 @@ func (tcc *defaultTikvClusterControl) updateTikvCluster(tc *v1alpha1.TikvCluster
 	//   - upgrade the tikv cluster
 	//   - scale out/in the tikv cluster
 	//   - failover the tikv cluster
-	if err := tcc.tikvMemberManager.Sync(tc); err != nil {
+	// the requeue of the tikv cluster, e.g. as the labels of some stores failed to be set, does not block
+	// the managers below
+	tikvErr := tcc.tikvMemberManager.Sync(tc)
+	if tikvErr != nil && !controller.IsRequeueError(tikvErr) {
+		return tikvErr
+	}
+
+	// create, update or delete the ServiceMonitor of TiKV if the Prometheus Operator is installed
+	if err := tcc.tikvServiceMonitorManager.Sync(tc); err != nil {
 		return err
 	}
 
@@ -159,7 +263,7 @@ func (tcc *defaultTikvClusterControl) updateTikvCluster(tc *v1alpha1.TikvCluster
 		return err
 	}
 
-	return nil
+	return tikvErr
 }
 
 var _ ControlInterface = &defaultTikvClusterControl{}
@@ -184,3 +288,22 @@ func (ftcc *FakeTikvClusterControlInterface) UpdateTikvCluster(_ *v1alpha1.TikvC
 }
 
 var _ ControlInterface = &FakeTikvClusterControlInterface{}
+
+func hasFinalizer(tc *v1alpha1.TikvCluster, finalizer string) bool {
+	for _, f := range tc.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	result := []string{}
+	for _, f := range finalizers {
+		if f != finalizer {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -17,12 +17,14 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned/fake"
 	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
 	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
 	mm "github.com/tikv/tikv-operator/pkg/manager/member"
 	"github.com/tikv/tikv-operator/pkg/manager/meta"
 	apps "k8s.io/api/apps/v1"
@@ -43,6 +45,7 @@ func TestTikvClusterControlUpdateTikvCluster(t *testing.T) {
 		orphanPodCleanerErr      bool
 		syncPDMemberManagerErr   bool
 		syncTiKVMemberManagerErr bool
+		requeueTiKVMemberManager bool
 		syncMetaManagerErr       bool
 		updateTCStatusErr        bool
 		errExpectFn              func(*GomegaWithT, error)
@@ -65,6 +68,9 @@ func TestTikvClusterControlUpdateTikvCluster(t *testing.T) {
 		if test.syncTiKVMemberManagerErr {
 			tikvMemberManager.SetSyncError(fmt.Errorf("tikv member manager sync error"))
 		}
+		if test.requeueTiKVMemberManager {
+			tikvMemberManager.SetSyncError(controller.RequeueErrorf("tikv member manager requeue"))
+		}
 		if test.syncMetaManagerErr {
 			metaManager.SetSyncError(fmt.Errorf("meta manager sync error"))
 		}
@@ -117,6 +123,32 @@ func TestTikvClusterControlUpdateTikvCluster(t *testing.T) {
 				g.Expect(strings.Contains(err.Error(), "tikv member manager sync error")).To(Equal(true))
 			},
 		},
+		{
+			name:                     "tikv member manager requeue",
+			update:                   nil,
+			orphanPodCleanerErr:      false,
+			syncPDMemberManagerErr:   false,
+			requeueTiKVMemberManager: true,
+			syncMetaManagerErr:       false,
+			updateTCStatusErr:        false,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(strings.Contains(err.Error(), "tikv member manager requeue")).To(Equal(true))
+			},
+		},
+		{
+			name:                     "tikv member manager requeue does not block the meta manager",
+			update:                   nil,
+			orphanPodCleanerErr:      false,
+			syncPDMemberManagerErr:   false,
+			requeueTiKVMemberManager: true,
+			syncMetaManagerErr:       true,
+			updateTCStatusErr:        false,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(strings.Contains(err.Error(), "meta manager sync error")).To(Equal(true))
+			},
+		},
 		{
 			name:                     "meta manager sync error",
 			update:                   nil,
@@ -200,6 +232,137 @@ func TestTikvClusterControlUpdateTikvCluster(t *testing.T) {
 	}
 }
 
+func TestTikvClusterControlFinalizer(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name             string
+		cleanupTimeout   time.Duration
+		pvcDeletePolicy  v1alpha1.PVCDeletePolicy
+		deleting         bool
+		finalizers       []string
+		cleanerErr       bool
+		pvcCleanerErr    bool
+		expectErr        bool
+		expectFinalizers []string
+	}{
+		{
+			name:             "add the finalizer",
+			cleanupTimeout:   time.Minute,
+			expectFinalizers: []string{label.TiKVStoresCleanupFinalizer},
+		},
+		{
+			name:           "cleanup disabled",
+			cleanupTimeout: 0,
+		},
+		{
+			name:             "add the finalizers in order",
+			cleanupTimeout:   time.Minute,
+			pvcDeletePolicy:  v1alpha1.PVCDeletePolicyDelete,
+			expectFinalizers: []string{label.TiKVStoresCleanupFinalizer, label.TiKVPVCCleanupFinalizer},
+		},
+		{
+			name:             "remove the pvc finalizer once the pvcs are retained",
+			cleanupTimeout:   time.Minute,
+			pvcDeletePolicy:  v1alpha1.PVCDeletePolicyRetain,
+			finalizers:       []string{label.TiKVStoresCleanupFinalizer, label.TiKVPVCCleanupFinalizer},
+			expectFinalizers: []string{label.TiKVStoresCleanupFinalizer},
+		},
+		{
+			name:             "pvcs are not deleted until the stores are cleaned up",
+			cleanupTimeout:   time.Minute,
+			pvcDeletePolicy:  v1alpha1.PVCDeletePolicyDelete,
+			deleting:         true,
+			finalizers:       []string{label.TiKVStoresCleanupFinalizer, label.TiKVPVCCleanupFinalizer},
+			cleanerErr:       true,
+			expectErr:        true,
+			expectFinalizers: []string{label.TiKVStoresCleanupFinalizer, label.TiKVPVCCleanupFinalizer},
+		},
+		{
+			name:             "waiting for the pvcs to be deleted",
+			cleanupTimeout:   time.Minute,
+			pvcDeletePolicy:  v1alpha1.PVCDeletePolicyDelete,
+			deleting:         true,
+			finalizers:       []string{label.TiKVStoresCleanupFinalizer, label.TiKVPVCCleanupFinalizer},
+			pvcCleanerErr:    true,
+			expectErr:        true,
+			expectFinalizers: []string{label.TiKVPVCCleanupFinalizer},
+		},
+		{
+			name:             "remove the finalizers once the pvcs are deleted",
+			cleanupTimeout:   time.Minute,
+			pvcDeletePolicy:  v1alpha1.PVCDeletePolicyDelete,
+			deleting:         true,
+			finalizers:       []string{label.TiKVStoresCleanupFinalizer, label.TiKVPVCCleanupFinalizer},
+			expectFinalizers: []string{},
+		},
+		{
+			name:             "waiting for the stores to be cleaned up",
+			cleanupTimeout:   time.Minute,
+			deleting:         true,
+			finalizers:       []string{"foo", label.TiKVStoresCleanupFinalizer},
+			cleanerErr:       true,
+			expectErr:        true,
+			expectFinalizers: []string{"foo", label.TiKVStoresCleanupFinalizer},
+		},
+		{
+			name:             "remove the finalizer once the stores are cleaned up",
+			cleanupTimeout:   time.Minute,
+			deleting:         true,
+			finalizers:       []string{"foo", label.TiKVStoresCleanupFinalizer},
+			expectFinalizers: []string{"foo"},
+		},
+		{
+			name:             "remove the finalizer if the cleanup is disabled",
+			cleanupTimeout:   0,
+			deleting:         true,
+			finalizers:       []string{label.TiKVStoresCleanupFinalizer},
+			cleanerErr:       true,
+			expectFinalizers: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(timeout time.Duration) {
+				controller.TiKVStoresCleanupTimeout = timeout
+			}(controller.TiKVStoresCleanupTimeout)
+			controller.TiKVStoresCleanupTimeout = tt.cleanupTimeout
+
+			tc := newTikvClusterForTikvClusterControl()
+			tc.Finalizers = tt.finalizers
+			tc.Spec.TiKV.PVCDeletePolicy = tt.pvcDeletePolicy
+			if tt.deleting {
+				now := metav1.Now()
+				tc.DeletionTimestamp = &now
+			}
+			control, _, pdMemberManager, _, _, _ := newFakeTikvClusterControl()
+			if tt.deleting {
+				// the members are not synced while the cluster is being deleted
+				pdMemberManager.SetSyncError(fmt.Errorf("pd member manager sync error"))
+			}
+			if tt.cleanerErr {
+				cleaner := control.(*defaultTikvClusterControl).tikvStoresCleaner.(*mm.FakeTiKVStoresCleaner)
+				cleaner.SetSyncError(controller.RequeueErrorf("waiting for the tikv stores to become tombstone"))
+			}
+			if tt.pvcCleanerErr {
+				cleaner := control.(*defaultTikvClusterControl).tikvPVCCleaner.(*mm.FakeTiKVPVCCleaner)
+				cleaner.SetSyncError(controller.RequeueErrorf("waiting for the tikv pods to be deleted"))
+			}
+
+			err := control.UpdateTikvCluster(tc)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if tt.expectFinalizers == nil {
+				g.Expect(tc.Finalizers).To(BeEmpty())
+			} else {
+				g.Expect(tc.Finalizers).To(Equal(tt.expectFinalizers))
+			}
+		})
+	}
+}
+
 func TestTikvClusterStatusEquality(t *testing.T) {
 	g := NewGomegaWithT(t)
 	tcStatus := v1alpha1.TikvClusterStatus{}
@@ -226,15 +389,22 @@ func newFakeTikvClusterControl() (
 
 	tcUpdater := controller.NewFakeTikvClusterControl(tcInformer)
 	pdMemberManager := mm.NewFakePDMemberManager()
+	tikvAutoScaler := mm.NewFakeTiKVAutoScaler()
 	tikvMemberManager := mm.NewFakeTiKVMemberManager()
+	tikvServiceMonitorManager := mm.NewFakeTiKVServiceMonitorManager()
 	metaManager := meta.NewFakeMetaManager()
 	orphanPodCleaner := mm.NewFakeOrphanPodsCleaner()
 	discoveryManager := mm.NewFakeDiscoveryManger()
 	control := NewDefaultTikvClusterControl(
 		tcUpdater,
 		pdMemberManager,
+		tikvAutoScaler,
 		tikvMemberManager,
+		tikvServiceMonitorManager,
 		metaManager,
+		mm.NewFakeTiKVStoresCleaner(),
+		mm.NewFakeTiKVPVCCleaner(),
+		mm.NewFakeTikvClusterSuspender(),
 		orphanPodCleaner,
 		discoveryManager,
 		&tikvClusterConditionUpdater{},
@@ -23,9 +23,13 @@ import (
 	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
 	listers "github.com/tikv/tikv-operator/pkg/client/listers/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
 	mm "github.com/tikv/tikv-operator/pkg/manager/member"
 	"github.com/tikv/tikv-operator/pkg/manager/meta"
+	"github.com/tikv/tikv-operator/pkg/metrics"
+	"github.com/tikv/tikv-operator/pkg/notification"
 	"github.com/tikv/tikv-operator/pkg/pdapi"
+	"github.com/tikv/tikv-operator/pkg/tikvapi"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -36,6 +40,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	eventv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -60,17 +65,21 @@ type Controller struct {
 	setLister appslisters.StatefulSetLister
 	// setListerSynced returns true if the statefulset shared informer has synced at least once
 	setListerSynced cache.InformerSynced
+	// podLister is able to list/get pods from a shared informer's store
+	podLister corelisters.PodLister
 	// tikvclusters that need to be synced.
 	queue workqueue.RateLimitingInterface
 }
 
-// NewController creates a tikvcluster controller.
+// NewController creates a tikvcluster controller. The cluster scoped resources, i.e. the nodes and the persistent
+// volumes, are watched by the clusterInformerFactory, which is shared by the controllers of all the watched namespaces.
 func NewController(
 	kubeCli kubernetes.Interface,
 	cli versioned.Interface,
 	genericCli client.Client,
 	informerFactory informers.SharedInformerFactory,
 	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	clusterInformerFactory kubeinformers.SharedInformerFactory,
 	autoFailover bool,
 	pdFailoverPeriod time.Duration,
 	tikvFailoverPeriod time.Duration,
@@ -86,24 +95,41 @@ func NewController(
 	svcInformer := kubeInformerFactory.Core().V1().Services()
 	epsInformer := kubeInformerFactory.Core().V1().Endpoints()
 	pvcInformer := kubeInformerFactory.Core().V1().PersistentVolumeClaims()
-	pvInformer := kubeInformerFactory.Core().V1().PersistentVolumes()
+	pvInformer := clusterInformerFactory.Core().V1().PersistentVolumes()
 	podInformer := kubeInformerFactory.Core().V1().Pods()
-	nodeInformer := kubeInformerFactory.Core().V1().Nodes()
+	nodeInformer := clusterInformerFactory.Core().V1().Nodes()
+	cmInformer := kubeInformerFactory.Core().V1().ConfigMaps()
 
 	tcControl := controller.NewRealTikvClusterControl(cli, tcInformer.Lister(), recorder)
-	pdControl := pdapi.NewDefaultPDControl(kubeCli)
+	pdControl := pdapi.NewMetricsPDControl(pdapi.NewDefaultPDControl(kubeCli))
 	setControl := controller.NewRealStatefuSetControl(kubeCli, setInformer.Lister(), recorder)
 	svcControl := controller.NewRealServiceControl(kubeCli, svcInformer.Lister(), recorder)
 	pvControl := controller.NewRealPVControl(kubeCli, pvcInformer.Lister(), pvInformer.Lister(), recorder)
 	pvcControl := controller.NewRealPVCControl(kubeCli, recorder, pvcInformer.Lister())
 	podControl := controller.NewRealPodControl(kubeCli, pdControl, podInformer.Lister(), recorder)
-	typedControl := controller.NewTypedControl(controller.NewRealGenericControl(genericCli, recorder))
+	genericControl := controller.NewRealGenericControl(genericCli, recorder)
+	tikvControl := tikvapi.NewDefaultTiKVControl(kubeCli)
+	notifier := notification.NewWebhookNotifier()
+	if controller.DryRun {
+		klog.Infof("dry-run mode is enabled, changes to the kubernetes resources, pd and tikv will only be logged")
+		tcControl = controller.NewDryRunTikvClusterControl(tcInformer.Lister())
+		pdControl = pdapi.NewDryRunPDControl(pdControl)
+		setControl = controller.NewDryRunStatefulSetControl(setInformer.Lister())
+		svcControl = controller.NewDryRunServiceControl(svcInformer.Lister())
+		pvControl = controller.NewDryRunPVControl()
+		pvcControl = controller.NewDryRunPVCControl(pvcInformer.Lister())
+		podControl = controller.NewDryRunPodControl(podInformer.Lister())
+		genericControl = controller.NewDryRunGenericControl(genericCli)
+		tikvControl = tikvapi.NewDryRunTiKVControl()
+		notifier = notification.NewDryRunNotifier()
+	}
+	typedControl := controller.NewTypedControl(genericControl)
 	pdScaler := mm.NewPDScaler(pdControl, pvcInformer.Lister(), pvcControl)
-	tikvScaler := mm.NewTiKVScaler(pdControl, pvcInformer.Lister(), pvcControl, podInformer.Lister())
+	tikvScaler := mm.NewTiKVScaler(pdControl, pvcInformer.Lister(), pvcControl, podInformer.Lister(), recorder)
 	pdFailover := mm.NewPDFailover(cli, pdControl, pdFailoverPeriod, podInformer.Lister(), podControl, pvcInformer.Lister(), pvcControl, pvInformer.Lister(), recorder)
 	tikvFailover := mm.NewTiKVFailover(tikvFailoverPeriod, recorder)
 	pdUpgrader := mm.NewPDUpgrader(pdControl, podControl, podInformer.Lister())
-	tikvUpgrader := mm.NewTiKVUpgrader(pdControl, podControl, podInformer.Lister())
+	tikvUpgrader := mm.NewTiKVUpgrader(pdControl, podControl, podInformer.Lister(), recorder)
 
 	tcc := &Controller{
 		kubeClient: kubeCli,
@@ -125,21 +151,30 @@ func NewController(
 				pdUpgrader,
 				autoFailover,
 				pdFailover,
+				recorder,
 			),
+			mm.NewTiKVAutoScaler(mm.NewPrometheusMetricsQuerier(), recorder),
 			mm.NewTiKVMemberManager(
 				pdControl,
+				tikvControl,
 				setControl,
 				svcControl,
+				podControl,
 				typedControl,
 				setInformer.Lister(),
 				svcInformer.Lister(),
 				podInformer.Lister(),
 				nodeInformer.Lister(),
+				pvcInformer.Lister(),
+				cmInformer.Lister(),
 				autoFailover,
 				tikvFailover,
 				tikvScaler,
 				tikvUpgrader,
+				notifier,
+				recorder,
 			),
+			mm.NewTiKVServiceMonitorManager(genericControl),
 			meta.NewMetaManager(
 				pvcInformer.Lister(),
 				pvcControl,
@@ -148,6 +183,15 @@ func NewController(
 				podInformer.Lister(),
 				podControl,
 			),
+			mm.NewTiKVStoresCleaner(pdControl, recorder),
+			mm.NewTiKVPVCCleaner(
+				setControl,
+				pvcControl,
+				setInformer.Lister(),
+				podInformer.Lister(),
+				pvcInformer.Lister(),
+			),
+			mm.NewTikvClusterSuspender(setControl, setInformer.Lister(), recorder),
 			mm.NewOrphanPodsCleaner(
 				podInformer.Lister(),
 				podControl,
@@ -183,6 +227,13 @@ func NewController(
 	})
 	tcc.setLister = setInformer.Lister()
 	tcc.setListerSynced = setInformer.Informer().HasSynced
+	tcc.podLister = podInformer.Lister()
+
+	if controller.NodeDrainLeaderEviction {
+		nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: tcc.updateNode,
+		})
+	}
 
 	return tcc
 }
@@ -222,7 +273,7 @@ func (tcc *Controller) processNextWorkItem() bool {
 		} else {
 			utilruntime.HandleError(fmt.Errorf("TikvCluster: %v, sync failed %v, requeuing", key.(string), err))
 		}
-		tcc.queue.AddRateLimited(key)
+		controller.Requeue(tcc.queue, key, err)
 	} else {
 		tcc.queue.Forget(key)
 	}
@@ -243,13 +294,23 @@ func (tcc *Controller) sync(key string) error {
 	tc, err := tcc.tcLister.TikvClusters(ns).Get(name)
 	if errors.IsNotFound(err) {
 		klog.Infof("TikvCluster has been deleted %v", key)
+		metrics.DeleteTikvCluster(ns, name)
 		return nil
 	}
 	if err != nil {
 		return err
 	}
 
-	return tcc.syncTikvCluster(tc.DeepCopy())
+	err = tcc.syncTikvCluster(tc.DeepCopy())
+	metrics.ReconcileDuration.WithLabelValues(ns, name).Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		errType := metrics.ReconcileErrorOther
+		if perrors.Find(err, controller.IsRequeueError) != nil {
+			errType = metrics.ReconcileErrorRequeue
+		}
+		metrics.ReconcileErrors.WithLabelValues(ns, name, errType).Inc()
+	}
+	return err
 }
 
 func (tcc *Controller) syncTikvCluster(tc *v1alpha1.TikvCluster) error {
@@ -266,6 +327,38 @@ func (tcc *Controller) enqueueTikvCluster(obj interface{}) {
 	tcc.queue.Add(key)
 }
 
+// updateNode enqueues the tikvclusters with TiKV pods on the node when the node is cordoned or uncordoned,
+// so that the leaders of the stores are evicted before the pods are drained
+func (tcc *Controller) updateNode(old, cur interface{}) {
+	oldNode := old.(*corev1.Node)
+	curNode := cur.(*corev1.Node)
+	if mm.IsNodeDraining(oldNode) == mm.IsNodeDraining(curNode) {
+		return
+	}
+	selector, err := label.New().TiKV().Selector()
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get the selector of tikv pods: %v", err))
+		return
+	}
+	pods, err := tcc.podLister.List(selector)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't list tikv pods: %v", err))
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName != curNode.GetName() {
+			continue
+		}
+		instanceName := pod.Labels[label.InstanceLabelKey]
+		if instanceName == "" {
+			continue
+		}
+		klog.V(4).Infof("Node %s of pod %s/%s is cordoned or uncordoned, TikvCluster: %s/%s",
+			curNode.GetName(), pod.GetNamespace(), pod.GetName(), pod.GetNamespace(), instanceName)
+		tcc.queue.Add(fmt.Sprintf("%s/%s", pod.GetNamespace(), instanceName))
+	}
+}
+
 // addStatefulSet adds the tikvcluster for the statefulset to the sync queue
 func (tcc *Controller) addStatefulSet(obj interface{}) {
 	set := obj.(*apps.StatefulSet)
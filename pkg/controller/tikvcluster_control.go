@@ -58,12 +58,20 @@ func (rtc *realTikvClusterControl) UpdateTikvCluster(tc *v1alpha1.TikvCluster, n
 	tcName := tc.GetName()
 
 	status := tc.Status.DeepCopy()
+	// the replicas may be changed by the autoscaler or adopted from the statefulset
+	replicas := tc.Spec.TiKV.Replicas
 	var updateTC *v1alpha1.TikvCluster
 
 	// don't wait due to limited number of clients, but backoff after the default number of steps
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		var updateErr error
 		updateTC, updateErr = rtc.cli.TikvV1alpha1().TikvClusters(ns).Update(tc)
+		// the status is a subresource that is ignored by Update
+		if updateErr == nil && !apiequality.Semantic.DeepEqual(&updateTC.Status, status) {
+			updateTC = updateTC.DeepCopy()
+			updateTC.Status = *status
+			updateTC, updateErr = rtc.cli.TikvV1alpha1().TikvClusters(ns).UpdateStatus(updateTC)
+		}
 		if updateErr == nil {
 			klog.Infof("TikvCluster: [%s/%s] updated successfully", ns, tcName)
 			return nil
@@ -73,6 +81,7 @@ func (rtc *realTikvClusterControl) UpdateTikvCluster(tc *v1alpha1.TikvCluster, n
 		if updated, err := rtc.tcLister.TikvClusters(ns).Get(tcName); err == nil {
 			// make a copy so we don't mutate the shared cache
 			tc = updated.DeepCopy()
+			tc.Spec.TiKV.Replicas = replicas
 			tc.Status = *status
 		} else {
 			utilruntime.HandleError(fmt.Errorf("error getting updated TikvCluster %s/%s from lister: %v", ns, tcName, err))
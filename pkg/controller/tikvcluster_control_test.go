@@ -68,6 +68,53 @@ func TestTikvClusterControlUpdateTikvClusterConflictSuccess(t *testing.T) {
 	g.Expect(err).To(Succeed())
 }
 
+func TestTikvClusterControlUpdateTikvClusterConflictReplicas(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := record.NewFakeRecorder(10)
+	tc := newTikvCluster()
+	fakeClient := &fake.Clientset{}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	indexer.Add(tc.DeepCopy())
+	tcLister := listers.NewTikvClusterLister(indexer)
+	control := NewRealTikvClusterControl(fakeClient, tcLister, recorder)
+	conflict := false
+	fakeClient.AddReactor("update", "tikvclusters", func(action core.Action) (bool, runtime.Object, error) {
+		update := action.(core.UpdateAction)
+		if !conflict {
+			conflict = true
+			return true, update.GetObject(), apierrors.NewConflict(action.GetResource().GroupResource(), tc.Name, errors.New("conflict"))
+		}
+		return true, update.GetObject(), nil
+	})
+	// the replicas set by the autoscaler are kept on retry
+	tc.Spec.TiKV.Replicas = tc.Spec.TiKV.Replicas + 1
+	updateTC, err := control.UpdateTikvCluster(tc, &tc.Status, &v1alpha1.TikvClusterStatus{})
+	g.Expect(err).To(Succeed())
+	g.Expect(updateTC.Spec.TiKV.Replicas).To(Equal(tc.Spec.TiKV.Replicas))
+}
+
+func TestTikvClusterControlUpdateTikvClusterStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := record.NewFakeRecorder(10)
+	tc := newTikvCluster()
+	tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+	fakeClient := &fake.Clientset{}
+	control := NewRealTikvClusterControl(fakeClient, nil, recorder)
+	fakeClient.AddReactor("update", "tikvclusters", func(action core.Action) (bool, runtime.Object, error) {
+		update := action.(core.UpdateAction)
+		obj := update.GetObject().(*v1alpha1.TikvCluster).DeepCopy()
+		if update.GetSubresource() != "status" {
+			// the status is not changed by the update of the spec
+			obj.Status = v1alpha1.TikvClusterStatus{}
+		}
+		return true, obj, nil
+	})
+	updateTC, err := control.UpdateTikvCluster(tc, &tc.Status, &v1alpha1.TikvClusterStatus{})
+	g.Expect(err).To(Succeed())
+	g.Expect(updateTC.Status.TiKV.Phase).To(Equal(v1alpha1.UpgradePhase))
+	g.Expect(fakeClient.Actions()).To(HaveLen(2))
+}
+
 func TestDeepEqualExceptHeartbeatTime(t *testing.T) {
 	g := NewGomegaWithT(t)
 
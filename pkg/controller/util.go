@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
+	perrors "github.com/pingcap/errors"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/scheme"
 	"github.com/tikv/tikv-operator/pkg/util"
@@ -26,6 +27,7 @@ import (
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -40,6 +42,12 @@ var (
 	// controllerKind contains the schema.GroupVersionKind for tikvcluster controller type.
 	ControllerKind = v1alpha1.SchemeGroupVersion.WithKind("TikvCluster")
 
+	// BackupControllerKind contains the schema.GroupVersionKind for backup controller type.
+	BackupControllerKind = v1alpha1.SchemeGroupVersion.WithKind("TikvBackup")
+
+	// RestoreControllerKind contains the schema.GroupVersionKind for restore controller type.
+	RestoreControllerKind = v1alpha1.SchemeGroupVersion.WithKind("TikvRestore")
+
 	// ClusterScoped controls whether operator should manage kubernetes cluster wide TiDB clusters
 	ClusterScoped bool
 
@@ -49,8 +57,28 @@ var (
 	// ResyncDuration is the resync time of informer
 	ResyncDuration time.Duration
 
+	// RequeueInterval is the interval after which the objects still converging, i.e. whose sync returned a
+	// RequeueError, are synced again, 0 backs them off exponentially by the rate limiter of the queue
+	RequeueInterval time.Duration
+
 	// PDDiscoveryImage is the image of pd discovery service
 	PDDiscoveryImage string
+
+	// ServiceNodePortRange is the port range reserved for services with NodePort visibility,
+	// it should match the --service-node-port-range of the kube-apiserver
+	ServiceNodePortRange = "30000-32767"
+
+	// NodeDrainLeaderEviction controls whether the leaders of the TiKV stores on the cordoned nodes are
+	// evicted before the pods are drained
+	NodeDrainLeaderEviction bool
+
+	// TiKVStoresCleanupTimeout is how long the deletion of a TikvCluster waits for its TiKV stores to be
+	// offlined and become tombstone in PD, 0 disables the cleanup
+	TiKVStoresCleanupTimeout time.Duration
+
+	// DryRun controls whether the changes of the TikvClusters to the kubernetes resources, PD and TiKV
+	// are only logged, with the diff if any, instead of being issued
+	DryRun bool
 )
 
 const (
@@ -78,6 +106,16 @@ func IsRequeueError(err error) bool {
 	return ok
 }
 
+// Requeue adds the key whose sync failed with the error back to the queue, the objects still converging are synced
+// again after RequeueInterval if set, and the other failures are backed off by the rate limiter
+func Requeue(queue workqueue.RateLimitingInterface, key interface{}, err error) {
+	if RequeueInterval > 0 && perrors.Find(err, IsRequeueError) != nil {
+		queue.AddAfter(key, RequeueInterval)
+		return
+	}
+	queue.AddRateLimited(key)
+}
+
 // IgnoreError is used to ignore this item, this error type should't be considered as a real error, no need to requeue
 type IgnoreError struct {
 	s string
@@ -158,6 +196,16 @@ func TiKVPeerMemberName(clusterName string) string {
 	return fmt.Sprintf("%s-tikv-peer", clusterName)
 }
 
+// BackupJobName returns the name of the BR job of the backup
+func BackupJobName(backupName string) string {
+	return fmt.Sprintf("%s-backup", backupName)
+}
+
+// RestoreJobName returns the name of the BR job of the restore
+func RestoreJobName(restoreName string) string {
+	return fmt.Sprintf("%s-restore", restoreName)
+}
+
 // TiFlashMemberName returns tiflash member name
 func TiFlashMemberName(clusterName string) string {
 	return fmt.Sprintf("%s-tiflash", clusterName)
@@ -410,6 +458,14 @@ func EmptyClone(obj runtime.Object) (runtime.Object, error) {
 	if !ok {
 		return nil, fmt.Errorf("Obj %v is not a metav1.Object, cannot call EmptyClone", obj)
 	}
+	// the kinds of unstructured objects, e.g. the custom resources of other operators, may be unknown to the scheme
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		inst := &unstructured.Unstructured{}
+		inst.SetGroupVersionKind(u.GroupVersionKind())
+		inst.SetName(meta.GetName())
+		inst.SetNamespace(meta.GetNamespace())
+		return inst, nil
+	}
 	gvk, err := InferObjectKind(obj)
 	if err != nil {
 		return nil, err
@@ -16,6 +16,7 @@ package controller
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
@@ -24,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 )
 
 func TestRequeueError(t *testing.T) {
@@ -37,6 +39,60 @@ func TestRequeueError(t *testing.T) {
 	g.Expect(IsRequeueError(fmt.Errorf("i am not a requeue error"))).To(BeFalse())
 }
 
+type fakeRequeueQueue struct {
+	workqueue.RateLimitingInterface
+	after       time.Duration
+	rateLimited bool
+}
+
+func (q *fakeRequeueQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.after = duration
+}
+
+func (q *fakeRequeueQueue) AddRateLimited(item interface{}) {
+	q.rateLimited = true
+}
+
+func TestRequeue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer func(interval time.Duration) { RequeueInterval = interval }(RequeueInterval)
+
+	tests := []struct {
+		name              string
+		interval          time.Duration
+		err               error
+		expectAfter       time.Duration
+		expectRateLimited bool
+	}{
+		{
+			name:              "requeue error without the interval",
+			err:               RequeueErrorf("waiting"),
+			expectRateLimited: true,
+		},
+		{
+			name:        "requeue error with the interval",
+			interval:    10 * time.Second,
+			err:         RequeueErrorf("waiting"),
+			expectAfter: 10 * time.Second,
+		},
+		{
+			name:              "other error with the interval",
+			interval:          10 * time.Second,
+			err:               fmt.Errorf("failed"),
+			expectRateLimited: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RequeueInterval = tt.interval
+			queue := &fakeRequeueQueue{}
+			Requeue(queue, "ns/name", tt.err)
+			g.Expect(queue.after).To(Equal(tt.expectAfter))
+			g.Expect(queue.rateLimited).To(Equal(tt.expectRateLimited))
+		})
+	}
+}
+
 func TestGetOwnerRef(t *testing.T) {
 	g := NewGomegaWithT(t)
 
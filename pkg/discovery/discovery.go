@@ -21,6 +21,7 @@ import (
 
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	"github.com/tikv/tikv-operator/pkg/controller"
 	"github.com/tikv/tikv-operator/pkg/pdapi"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -30,6 +31,10 @@ import (
 // PDDiscovery helps new PD member to discover all other members in cluster bootstrap phase.
 type PDDiscovery interface {
 	Discover(string) (string, error)
+	// DiscoverByOrdinal returns the arguments for the PD member of the given ordinal to start with
+	DiscoverByOrdinal(tcName string, ordinal int32) (string, error)
+	// Ready returns an error if the kube API or the PD API of the tikv cluster can not be reached
+	Ready(tcName string) error
 }
 
 type pdDiscovery struct {
@@ -98,7 +103,7 @@ func (td *pdDiscovery) Discover(advertisePeerUrl string) (string, error) {
 		return fmt.Sprintf("--initial-cluster=%s=%s://%s", podName, tc.Scheme(), advertisePeerUrl), nil
 	}
 
-	pdClient := td.pdControl.GetPDClient(pdapi.Namespace(tc.GetNamespace()), tc.GetName(), tc.IsTLSClusterEnabled())
+	pdClient := td.pdControl.GetPDClient(pdapi.Namespace(tc.GetNamespace()), tc.GetName(), tc.IsTLSClusterEnabled(), tc.ClusterClientTLSSecretName())
 	membersInfo, err := pdClient.GetMembers()
 	if err != nil {
 		return "", err
@@ -113,6 +118,65 @@ func (td *pdDiscovery) Discover(advertisePeerUrl string) (string, error) {
 	return fmt.Sprintf("--join=%s", strings.Join(membersArr, ",")), nil
 }
 
+// DiscoverByOrdinal returns --initial-cluster for the first PD member to bootstrap the cluster,
+// and --join with the current PD members for the others, e.g. the members added in scale-out
+func (td *pdDiscovery) DiscoverByOrdinal(tcName string, ordinal int32) (string, error) {
+	if ordinal < 0 {
+		return "", fmt.Errorf("invalid ordinal: %d", ordinal)
+	}
+	ns := os.Getenv("MY_POD_NAMESPACE")
+	tc, err := td.tcGetFn(ns, tcName)
+	if err != nil {
+		return "", err
+	}
+	podName := fmt.Sprintf("%s-%d", controller.PDMemberName(tcName), ordinal)
+	advertisePeerURL := fmt.Sprintf("%s.%s.%s.svc:2380", podName, controller.PDPeerMemberName(tcName), ns)
+	initialCluster := fmt.Sprintf("--initial-cluster=%s=%s://%s", podName, tc.Scheme(), advertisePeerURL)
+
+	pdClient := td.pdControl.GetPDClient(pdapi.Namespace(ns), tcName, tc.IsTLSClusterEnabled(), tc.ClusterClientTLSSecretName())
+	membersInfo, err := pdClient.GetMembers()
+	if err != nil {
+		// the cluster is not bootstrapped yet
+		if ordinal == 0 && len(tc.Status.PD.Members) == 0 {
+			return initialCluster, nil
+		}
+		return "", err
+	}
+
+	membersArr := make([]string, 0)
+	for _, member := range membersInfo.Members {
+		if member.Name == podName || len(member.PeerUrls) == 0 {
+			continue
+		}
+		memberURL := strings.ReplaceAll(member.PeerUrls[0], ":2380", ":2379")
+		membersArr = append(membersArr, memberURL)
+	}
+	if len(membersArr) == 0 {
+		if ordinal == 0 {
+			return initialCluster, nil
+		}
+		return "", fmt.Errorf("no PD member of %s/%s to join for %s", ns, tcName, podName)
+	}
+	return fmt.Sprintf("--join=%s", strings.Join(membersArr, ",")), nil
+}
+
+func (td *pdDiscovery) Ready(tcName string) error {
+	ns := os.Getenv("MY_POD_NAMESPACE")
+	tc, err := td.tcGetFn(ns, tcName)
+	if err != nil {
+		return fmt.Errorf("failed to get tikv cluster %s/%s from kube API: %v", ns, tcName, err)
+	}
+	// PD members are started with the help of discovery, PD can not be reached before it is bootstrapped
+	if len(tc.Status.PD.Members) == 0 {
+		return nil
+	}
+	pdClient := td.pdControl.GetPDClient(pdapi.Namespace(ns), tcName, tc.IsTLSClusterEnabled(), tc.ClusterClientTLSSecretName())
+	if _, err := pdClient.GetHealth(); err != nil {
+		return fmt.Errorf("failed to reach PD API of tikv cluster %s/%s: %v", ns, tcName, err)
+	}
+	return nil
+}
+
 func (td *pdDiscovery) realTCGetFn(ns, tcName string) (*v1alpha1.TikvCluster, error) {
 	return td.cli.TikvV1alpha1().TikvClusters(ns).Get(tcName, metav1.GetOptions{})
 }
@@ -402,6 +402,170 @@ func TestDiscoveryDiscovery(t *testing.T) {
 	}
 }
 
+func TestDiscoveryDiscoverByOrdinal(t *testing.T) {
+	g := NewGomegaWithT(t)
+	members := func(names ...string) (*pdapi.MembersInfo, error) {
+		info := &pdapi.MembersInfo{}
+		for _, name := range names {
+			info.Members = append(info.Members, &pdpb.Member{
+				Name:     name,
+				PeerUrls: []string{fmt.Sprintf("http://%s.demo-pd-peer.default.svc:2380", name)},
+			})
+		}
+		return info, nil
+	}
+	tests := []struct {
+		name         string
+		ordinal      int32
+		updateTC     func(*v1alpha1.TikvCluster)
+		getMembersFn func() (*pdapi.MembersInfo, error)
+		expectErr    bool
+		expect       string
+	}{
+		{
+			name:    "bootstrap the first member",
+			ordinal: 0,
+			getMembersFn: func() (*pdapi.MembersInfo, error) {
+				return nil, fmt.Errorf("pd is not running")
+			},
+			expect: "--initial-cluster=demo-pd-0=http://demo-pd-0.demo-pd-peer.default.svc:2380",
+		},
+		{
+			name:    "the first member when pd is unavailable after bootstrapped",
+			ordinal: 0,
+			updateTC: func(tc *v1alpha1.TikvCluster) {
+				tc.Status.PD.Members = map[string]v1alpha1.PDMember{"demo-pd-1": {Name: "demo-pd-1"}}
+			},
+			getMembersFn: func() (*pdapi.MembersInfo, error) {
+				return nil, fmt.Errorf("pd is not running")
+			},
+			expectErr: true,
+		},
+		{
+			name:    "the first member rejoins the cluster",
+			ordinal: 0,
+			getMembersFn: func() (*pdapi.MembersInfo, error) {
+				return members("demo-pd-0", "demo-pd-1")
+			},
+			expect: "--join=http://demo-pd-1.demo-pd-peer.default.svc:2379",
+		},
+		{
+			name:    "scale out member joins the cluster",
+			ordinal: 3,
+			getMembersFn: func() (*pdapi.MembersInfo, error) {
+				return members("demo-pd-0", "demo-pd-1", "demo-pd-2")
+			},
+			expect: "--join=http://demo-pd-0.demo-pd-peer.default.svc:2379,http://demo-pd-1.demo-pd-peer.default.svc:2379,http://demo-pd-2.demo-pd-peer.default.svc:2379",
+		},
+		{
+			name:    "no member to join",
+			ordinal: 1,
+			getMembersFn: func() (*pdapi.MembersInfo, error) {
+				return members()
+			},
+			expectErr: true,
+		},
+		{
+			name:      "invalid ordinal",
+			ordinal:   -1,
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, _ := newTC()
+			if tt.updateTC != nil {
+				tt.updateTC(tc)
+			}
+			kubeCli := kubefake.NewSimpleClientset()
+			fakePDControl := pdapi.NewFakePDControl(kubeCli)
+			pdClient := pdapi.NewFakePDClient()
+			fakePDControl.SetPDClient(pdapi.Namespace(tc.GetNamespace()), tc.GetName(), pdClient)
+			pdClient.AddReaction(pdapi.GetMembersActionType, func(action *pdapi.Action) (interface{}, error) {
+				return tt.getMembersFn()
+			})
+			td := &pdDiscovery{
+				pdControl: fakePDControl,
+				tcGetFn: func(ns, tcName string) (*v1alpha1.TikvCluster, error) {
+					return tc, nil
+				},
+				clusters: map[string]*clusterInfo{},
+			}
+			os.Setenv("MY_POD_NAMESPACE", "default")
+
+			s, err := td.DiscoverByOrdinal("demo", tt.ordinal)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(s).To(Equal(tt.expect))
+			}
+		})
+	}
+}
+
+func TestDiscoveryReady(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name         string
+		tcErr        error
+		bootstrapped bool
+		healthErr    error
+		expectErr    string
+	}{
+		{
+			name:      "kube API is unavailable",
+			tcErr:     fmt.Errorf("connection refused"),
+			expectErr: "failed to get tikv cluster default/demo from kube API",
+		},
+		{
+			name:      "PD is not bootstrapped",
+			healthErr: fmt.Errorf("connection refused"),
+		},
+		{
+			name:         "PD API is unavailable",
+			bootstrapped: true,
+			healthErr:    fmt.Errorf("connection refused"),
+			expectErr:    "failed to reach PD API of tikv cluster default/demo",
+		},
+		{
+			name:         "ready",
+			bootstrapped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, _ := newTC()
+			if tt.bootstrapped {
+				tc.Status.PD.Members = map[string]v1alpha1.PDMember{"demo-pd-0": {Name: "demo-pd-0"}}
+			}
+			kubeCli := kubefake.NewSimpleClientset()
+			fakePDControl := pdapi.NewFakePDControl(kubeCli)
+			pdClient := pdapi.NewFakePDClient()
+			fakePDControl.SetPDClient(pdapi.Namespace(tc.GetNamespace()), tc.GetName(), pdClient)
+			pdClient.AddReaction(pdapi.GetHealthActionType, func(action *pdapi.Action) (interface{}, error) {
+				return &pdapi.HealthInfo{}, tt.healthErr
+			})
+			td := &pdDiscovery{
+				pdControl: fakePDControl,
+				tcGetFn: func(ns, tcName string) (*v1alpha1.TikvCluster, error) {
+					return tc, tt.tcErr
+				},
+				clusters: map[string]*clusterInfo{},
+			}
+			os.Setenv("MY_POD_NAMESPACE", "default")
+
+			err := td.Ready("demo")
+			if tt.expectErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.expectErr))
+			}
+		})
+	}
+}
+
 func newTC() (*v1alpha1.TikvCluster, error) {
 	return &v1alpha1.TikvCluster{
 		TypeMeta: metav1.TypeMeta{Kind: "TikvCluster", APIVersion: "v1alpha1"},
@@ -14,10 +14,13 @@
 package server
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	restful "github.com/emicklei/go-restful"
 	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
@@ -26,20 +29,54 @@ import (
 	"k8s.io/klog"
 )
 
+const (
+	// shutdownTimeout is how long the in-flight requests are waited for on shutdown
+	shutdownTimeout = 10 * time.Second
+)
+
 type server struct {
 	discovery discovery.PDDiscovery
+	tcName    string
 }
 
-// StartServer starts a TiDB Discovery server
-func StartServer(cli versioned.Interface, kubeCli kubernetes.Interface, port int) {
-	svr := &server{discovery.NewPDDiscovery(cli, kubeCli)}
+// StartServer starts a TiDB Discovery server, the server is shut down gracefully
+// and StartServer returns when stopCh is closed
+func StartServer(cli versioned.Interface, kubeCli kubernetes.Interface, port int, tcName string, stopCh <-chan struct{}) {
+	svr := &server{discovery.NewPDDiscovery(cli, kubeCli), tcName}
 
 	ws := new(restful.WebService)
+	// the PD start script gets the start arguments by the ordinal of the pod, /new is kept for the PD pods
+	// started by the scripts of the older versions
 	ws.Route(ws.GET("/new/{advertise-peer-url}").To(svr.newHandler))
-	restful.Add(ws)
+	ws.Route(ws.GET("/ordinal/{tc-name}/{ordinal}").To(svr.ordinalHandler))
+	ws.Route(ws.GET("/healthz").To(svr.healthzHandler))
+	ws.Route(ws.GET("/readyz").To(svr.readyzHandler))
+	container := restful.NewContainer()
+	container.Add(ws)
 
 	klog.Infof("starting PD Discovery server, listening on 0.0.0.0:%d", port)
-	klog.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
+	ListenAndServe(&http.Server{Addr: fmt.Sprintf(":%d", port), Handler: container}, stopCh)
+}
+
+// ListenAndServe serves the http server until stopCh is closed, then shuts it down gracefully
+// to let the in-flight requests complete
+func ListenAndServe(httpServer *http.Server, stopCh <-chan struct{}) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			klog.Errorf("failed to shut down the server on %s gracefully: %v", httpServer.Addr, err)
+		}
+	}()
+
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		klog.Fatal(err)
+	}
+	<-done
+	klog.Infof("server on %s is shut down", httpServer.Addr)
 }
 
 func (svr *server) newHandler(req *restful.Request, resp *restful.Response) {
@@ -68,3 +105,56 @@ func (svr *server) newHandler(req *restful.Request, resp *restful.Response) {
 		klog.Errorf("failed to write, string: %s, %v", result, err)
 	}
 }
+
+func (svr *server) ordinalHandler(req *restful.Request, resp *restful.Response) {
+	tcName := req.PathParameter("tc-name")
+	ordinal, err := strconv.ParseInt(req.PathParameter("ordinal"), 10, 32)
+	if err != nil {
+		klog.Errorf("failed to parse ordinal: %s", req.PathParameter("ordinal"))
+		if err := resp.WriteError(http.StatusBadRequest, err); err != nil {
+			klog.Errorf("failed to write, error: %v", err)
+		}
+		return
+	}
+
+	result, err := svr.discovery.DiscoverByOrdinal(tcName, int32(ordinal))
+	if err != nil {
+		klog.Errorf("failed to discover: %s, ordinal %d, %v", tcName, ordinal, err)
+		if err := resp.WriteError(http.StatusInternalServerError, err); err != nil {
+			klog.Errorf("failed to write, error: %v", err)
+		}
+		return
+	}
+
+	klog.Infof("generated args for %s ordinal %d: %s", tcName, ordinal, result)
+	if _, err := io.WriteString(resp, result); err != nil {
+		klog.Errorf("failed to write, string: %s, %v", result, err)
+	}
+}
+
+// healthzHandler reports the server is up
+func (svr *server) healthzHandler(_ *restful.Request, resp *restful.Response) {
+	if _, err := io.WriteString(resp, "ok"); err != nil {
+		klog.Errorf("failed to write, error: %v", err)
+	}
+}
+
+// readyzHandler reports whether the kube API and the PD API can be reached
+func (svr *server) readyzHandler(_ *restful.Request, resp *restful.Response) {
+	if svr.tcName == "" {
+		if err := resp.WriteErrorString(http.StatusServiceUnavailable, "tikv cluster name is not set"); err != nil {
+			klog.Errorf("failed to write, error: %v", err)
+		}
+		return
+	}
+	if err := svr.discovery.Ready(svr.tcName); err != nil {
+		klog.Errorf("discovery is not ready: %v", err)
+		if err := resp.WriteErrorString(http.StatusServiceUnavailable, err.Error()); err != nil {
+			klog.Errorf("failed to write, error: %v", err)
+		}
+		return
+	}
+	if _, err := io.WriteString(resp, "ok"); err != nil {
+		klog.Errorf("failed to write, error: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestListenAndServeShutdown(t *testing.T) {
+	g := NewGomegaWithT(t)
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ListenAndServe(&http.Server{Addr: "127.0.0.1:0", Handler: http.NewServeMux()}, stopCh)
+	}()
+
+	g.Consistently(done, 100*time.Millisecond).ShouldNot(BeClosed())
+	close(stopCh)
+	g.Eventually(done, shutdownTimeout).Should(BeClosed())
+}
@@ -26,9 +26,10 @@ import (
 )
 
 var (
-	allFeatures     = sets.NewString(AdvancedStatefulSet)
+	allFeatures     = sets.NewString(AdvancedStatefulSet, ConfigRollback)
 	defaultFeatures = map[string]bool{
 		AdvancedStatefulSet: false,
+		ConfigRollback:      false,
 	}
 	// DefaultFeatureGate is a shared global FeatureGate.
 	DefaultFeatureGate FeatureGate = NewFeatureGate()
@@ -37,6 +38,8 @@ var (
 const (
 	// AdvancedStatefulSet controls whether to use AdvancedStatefulSet to manage pods
 	AdvancedStatefulSet string = "AdvancedStatefulSet"
+	// ConfigRollback controls whether to roll back the TiKV ConfigMap when the pods using a new one are crash-looping
+	ConfigRollback string = "ConfigRollback"
 )
 
 type FeatureGate interface {
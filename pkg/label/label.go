@@ -48,9 +48,18 @@ const (
 	// MemberIDLabelKey is member id label key
 	MemberIDLabelKey string = "tikv.org/member-id"
 
+	// BackupLabelKey is the label key of the name of the backup of the BR jobs
+	BackupLabelKey string = "tikv.org/backup"
+
+	// RestoreLabelKey is the label key of the name of the restore of the BR jobs
+	RestoreLabelKey string = "tikv.org/restore"
+
 	// AnnForceUpgradeKey is tc annotation key to indicate whether force upgrade should be done
 	AnnForceUpgradeKey = "tikv.org/force-upgrade"
 
+	// AnnAllowDowngradeKey is tc annotation key to indicate whether the tikv image is allowed to be downgraded
+	AnnAllowDowngradeKey = "tikv.org/allow-downgrade"
+
 	// AnnPDDeferDeleting is pd pod annotation key  in pod for defer for deleting pod
 	AnnPDDeferDeleting = "tikv.org/pd-defer-deleting"
 
@@ -75,15 +84,55 @@ const (
 	// TiKVDeleteSlots is annotation key of tikv delete slots.
 	AnnTiKVDeleteSlots = "tikv.tikv.org/delete-slots"
 
+	// AnnTiKVPinnedNodes is tc annotation key of the nodes the tikv pods are pinned to, the value maps the ordinals
+	// of the pods to the node names in JSON, e.g. {"0":"node-1"}. The pins are kept on the tc rather than on the
+	// pods, since the affinity of a pod can not be changed once it is created, and they only take effect when the
+	// pods are recreated, which a running pod never is by the scheduler. The pod webhook applies the pins to the
+	// recreated pods, the pods not running on their pinned nodes are reported by a PinSkipped event
+	AnnTiKVPinnedNodes = "tikv.tikv.org/pinned-nodes"
+
+	// AnnTiKVZone is pod and pvc annotation key of the zone the tikv pod is pinned to, the pvc keeps the
+	// zone for the pod recreated
+	AnnTiKVZone = "tikv.org/zone"
+
+	// AnnTiKVLeaderWeight is pod annotation key of the leader weight of the store of the tikv pod in PD
+	AnnTiKVLeaderWeight = "tikv.org/leader-weight"
+	// AnnTiKVRegionWeight is pod annotation key of the region weight of the store of the tikv pod in PD
+	AnnTiKVRegionWeight = "tikv.org/region-weight"
+
+	// AnnTiKVRestartedAt is tc annotation key of the time the tikv pods are requested to be restarted, it is
+	// stamped into the pod template of tikv, so updating it restarts the tikv pods one by one with the leaders evicted
+	AnnTiKVRestartedAt = "tikv.tikv.org/restartedAt"
+
 	// AnnSysctlInit is pod annotation key to indicate whether configuring sysctls with init container
 	AnnSysctlInit = "tikv.org/sysctl-init"
 
+	// AnnListenerSecurityProtocol is external service annotation key of the security protocol of the listener,
+	// it only tells the clients and the load balancers how to connect and does not configure TiKV
+	AnnListenerSecurityProtocol = "tikv.org/listener-security-protocol"
+
+	// AnnListenerTLSSecret is external service annotation key of the tls secret of the listener
+	AnnListenerTLSSecret = "tikv.org/listener-tls-secret"
+
+	// AnnRestoring is tc annotation key of the name of the restore restoring the data of the cluster,
+	// the tikv statefulset is not synced until the restore finishes
+	AnnRestoring = "tikv.org/restoring"
+
+	// TiKVStoresCleanupFinalizer is tc finalizer to remove the tikv stores from pd before the cluster is deleted
+	TiKVStoresCleanupFinalizer = "tikv.org/tikv-stores-cleanup"
+
+	// TiKVPVCCleanupFinalizer is tc finalizer to delete the tikv pvcs after the tikv statefulset is deleted
+	TiKVPVCCleanupFinalizer = "tikv.org/tikv-pvc-cleanup"
+
 	// AnnSysctlInitVal is pod annotation value to indicate whether configuring sysctls with init container
 	AnnSysctlInitVal = "true"
 
 	// AnnForceUpgradeVal is tc annotation value to indicate whether force upgrade should be done
 	AnnForceUpgradeVal = "true"
 
+	// AnnAllowDowngradeVal is tc annotation value to indicate whether the tikv image is allowed to be downgraded
+	AnnAllowDowngradeVal = "true"
+
 	// PDLabelVal is PD label value
 	PDLabelVal string = "pd"
 
@@ -93,6 +142,12 @@ const (
 	// DiscoveryLabelVal is Discovery label value
 	DiscoveryLabelVal string = "discovery"
 
+	// BackupLabelVal is Backup label value
+	BackupLabelVal string = "backup"
+
+	// RestoreLabelVal is Restore label value
+	RestoreLabelVal string = "restore"
+
 	// TiKVOperator is ManagedByLabelKey label value
 	TiKVOperator string = "tikv-operator"
 )
@@ -143,6 +198,20 @@ func (l Label) Discovery() Label {
 	return l
 }
 
+// Backup assigns backup to component key and the name of the backup to the backup key in label
+func (l Label) Backup(name string) Label {
+	l.Component(BackupLabelVal)
+	l[BackupLabelKey] = name
+	return l
+}
+
+// Restore assigns restore to component key and the name of the restore to the restore key in label
+func (l Label) Restore(name string) Label {
+	l.Component(RestoreLabelVal)
+	l[RestoreLabelKey] = name
+	return l
+}
+
 // IsPD returns whether label is a PD
 func (l Label) IsPD() bool {
 	return l[ComponentLabelKey] == PDLabelVal
@@ -17,11 +17,11 @@ import (
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/controller"
 	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/util/log"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
-	"k8s.io/klog"
 )
 
 const (
@@ -153,10 +153,10 @@ func (opc *orphanPodsCleaner) Clean(tc *v1alpha1.TikvCluster) (map[string]string
 		}
 		err = opc.podControl.DeletePod(tc, pod)
 		if err != nil {
-			klog.Errorf("orphan pods cleaner: failed to clean orphan pod: %s/%s, %v", ns, podName, err)
+			log.ForCluster(tc, "orphan-pods-cleaner").Errorf("failed to clean orphan pod: %s/%s, %v", ns, podName, err)
 			return skipReason, err
 		}
-		klog.Infof("orphan pods cleaner: clean orphan pod: %s/%s successfully", ns, podName)
+		log.ForCluster(tc, "orphan-pods-cleaner").Infof("clean orphan pod: %s/%s successfully", ns, podName)
 	}
 
 	return skipReason, nil
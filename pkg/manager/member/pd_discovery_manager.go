@@ -150,6 +150,21 @@ func getTidbDiscoveryDeployment(tc *v1alpha1.TikvCluster) (*appsv1.Deployment, e
 								Name:  "TZ",
 								Value: tc.Timezone(),
 							},
+							{
+								Name:  "TC_NAME",
+								Value: tc.Name,
+							},
+						},
+						// readiness is not probed, otherwise the PD members could not discover each other
+						// to recover when the PD API is unavailable
+						LivenessProbe: &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/healthz",
+									Port: intstr.FromInt(10261),
+								},
+							},
+							InitialDelaySeconds: 10,
 						},
 					}},
 				},
@@ -28,7 +28,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/record"
-	"k8s.io/klog"
 )
 
 type pdFailover struct {
@@ -94,7 +93,7 @@ func (pf *pdFailover) Failover(tc *v1alpha1.TikvCluster) error {
 
 	failureReplicas := getFailureReplicas(tc)
 	if failureReplicas >= int(*tc.Spec.PD.MaxFailoverCount) {
-		klog.Errorf("PD failover replicas (%d) reaches the limit (%d), skip failover", failureReplicas, *tc.Spec.PD.MaxFailoverCount)
+		pdLogger(tc).Errorf("PD failover replicas (%d) reaches the limit (%d), skip failover", failureReplicas, *tc.Spec.PD.MaxFailoverCount)
 		return nil
 	}
 
@@ -114,7 +113,7 @@ func (pf *pdFailover) Failover(tc *v1alpha1.TikvCluster) error {
 
 func (pf *pdFailover) Recover(tc *v1alpha1.TikvCluster) {
 	tc.Status.PD.FailureMembers = nil
-	klog.Infof("pd failover: clearing pd failoverMembers, %s/%s", tc.GetNamespace(), tc.GetName())
+	pdLogger(tc).Infof("pd failover: clearing pd failoverMembers")
 }
 
 func (pf *pdFailover) tryToMarkAPeerAsFailure(tc *v1alpha1.TikvCluster) error {
@@ -194,10 +193,10 @@ func (pf *pdFailover) tryToDeleteAFailureMember(tc *v1alpha1.TikvCluster) error
 	// invoke deleteMember api to delete a member from the pd cluster
 	err = controller.GetPDClient(pf.pdControl, tc).DeleteMemberByID(memberID)
 	if err != nil {
-		klog.Errorf("pd failover: failed to delete member: %d, %v", memberID, err)
+		pdLogger(tc).Errorf("pd failover: failed to delete member: %d, %v", memberID, err)
 		return err
 	}
-	klog.Infof("pd failover: delete member: %d successfully", memberID)
+	pdLogger(tc).Infof("pd failover: delete member: %d successfully", memberID)
 	pf.recorder.Eventf(tc, apiv1.EventTypeWarning, "PDMemberDeleted",
 		"%s(%d) deleted from cluster", failurePodName, memberID)
 
@@ -229,10 +228,10 @@ func (pf *pdFailover) tryToDeleteAFailureMember(tc *v1alpha1.TikvCluster) error
 	if pvc != nil && pvc.DeletionTimestamp == nil && pvc.GetUID() == failureMember.PVCUID {
 		err = pf.pvcControl.DeletePVC(tc, pvc)
 		if err != nil {
-			klog.Errorf("pd failover: failed to delete pvc: %s/%s, %v", ns, pvcName, err)
+			pdLogger(tc).Errorf("pd failover: failed to delete pvc: %s/%s, %v", ns, pvcName, err)
 			return err
 		}
-		klog.Infof("pd failover: pvc: %s/%s successfully", ns, pvcName)
+		pdLogger(tc).Infof("pd failover: pvc: %s/%s successfully", ns, pvcName)
 	}
 
 	setMemberDeleted(tc, failurePodName)
@@ -243,7 +242,7 @@ func setMemberDeleted(tc *v1alpha1.TikvCluster, podName string) {
 	failureMember := tc.Status.PD.FailureMembers[podName]
 	failureMember.MemberDeleted = true
 	tc.Status.PD.FailureMembers[podName] = failureMember
-	klog.Infof("pd failover: set pd member: %s/%s deleted", tc.GetName(), podName)
+	pdLogger(tc).Infof("pd failover: set pd member: %s deleted", podName)
 }
 
 type fakePDFailover struct{}
@@ -265,7 +264,7 @@ func (pf *pdFailover) isPodDesired(tc *v1alpha1.TikvCluster, podName string) boo
 	ordinals := tc.PDStsDesiredOrdinals(true)
 	ordinal, err := util.GetOrdinalFromPodName(podName)
 	if err != nil {
-		klog.Errorf("unexpected pod name %q: %v", podName, err)
+		pdLogger(tc).Errorf("unexpected pod name %q: %v", podName, err)
 		return false
 	}
 	return ordinals.Has(ordinal)
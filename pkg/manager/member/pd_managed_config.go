@@ -0,0 +1,91 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"reflect"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+)
+
+// syncManagedConfig reconciles the managed config of the spec against the live config of PD, only the
+// parameters differing from the live ones are updated
+func (pmm *pdMemberManager) syncManagedConfig(tc *v1alpha1.TikvCluster) error {
+	managed := tc.Spec.PD.ManagedConfig
+	if managed == nil || tc.Spec.Paused || !tc.PDIsAvailable() {
+		return nil
+	}
+	logger := pdLogger(tc)
+
+	pdCli := controller.GetPDClient(pmm.pdControl, tc)
+	config, err := pdCli.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	liveReplication := config.Replication
+	if liveReplication == nil {
+		liveReplication = &pdapi.PDReplicationConfig{}
+	}
+	replication := pdapi.PDReplicationConfig{}
+	replicationChanged := false
+	if managed.MaxReplicas != nil && !uint64PtrEqual(liveReplication.MaxReplicas, *managed.MaxReplicas) {
+		replication.MaxReplicas = uint64Ptr(*managed.MaxReplicas)
+		replicationChanged = true
+	}
+	if managed.LocationLabels != nil && !reflect.DeepEqual([]string(liveReplication.LocationLabels), managed.LocationLabels) {
+		replication.LocationLabels = pdapi.StringSlice(managed.LocationLabels)
+		replicationChanged = true
+	}
+	if replicationChanged {
+		if err := pdCli.UpdateReplicationConfig(replication); err != nil {
+			return err
+		}
+		logger.Infof("updated the replication config of pd to the managed config")
+	}
+
+	liveSchedule := config.Schedule
+	if liveSchedule == nil {
+		liveSchedule = &pdapi.PDScheduleConfig{}
+	}
+	schedule := pdapi.PDScheduleConfig{}
+	scheduleChanged := false
+	if managed.LeaderScheduleLimit != nil && !uint64PtrEqual(liveSchedule.LeaderScheduleLimit, *managed.LeaderScheduleLimit) {
+		schedule.LeaderScheduleLimit = uint64Ptr(*managed.LeaderScheduleLimit)
+		scheduleChanged = true
+	}
+	// the region-schedule-limit raised to rebalance the regions after tikv is scaled out is restored afterwards
+	if managed.RegionScheduleLimit != nil && !tc.TiKVRebalancing() && !uint64PtrEqual(liveSchedule.RegionScheduleLimit, *managed.RegionScheduleLimit) {
+		schedule.RegionScheduleLimit = uint64Ptr(*managed.RegionScheduleLimit)
+		scheduleChanged = true
+	}
+	if scheduleChanged {
+		if err := pdCli.UpdateScheduleConfig(schedule); err != nil {
+			return err
+		}
+		logger.Infof("updated the schedule config of pd to the managed config")
+	}
+	return nil
+}
+
+func uint64Ptr(v int32) *uint64 {
+	u := uint64(v)
+	return &u
+}
+
+func uint64PtrEqual(live *uint64, managed int32) bool {
+	return live != nil && *live == uint64(managed)
+}
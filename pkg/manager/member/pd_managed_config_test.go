@@ -0,0 +1,132 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestPDMemberManagerSyncManagedConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+	three := uint64(3)
+	four := uint64(4)
+	tests := []struct {
+		name              string
+		managed           *v1alpha1.PDManagedConfig
+		pdUnavailable     bool
+		rebalancing       bool
+		live              *pdapi.PDConfigFromAPI
+		expectReplication *pdapi.PDReplicationConfig
+		expectSchedule    *pdapi.PDScheduleConfig
+	}{
+		{
+			name: "no managed config",
+			live: &pdapi.PDConfigFromAPI{},
+		},
+		{
+			name:          "pd unavailable",
+			managed:       &v1alpha1.PDManagedConfig{MaxReplicas: pointer.Int32Ptr(5)},
+			pdUnavailable: true,
+			live:          &pdapi.PDConfigFromAPI{},
+		},
+		{
+			name: "in sync",
+			managed: &v1alpha1.PDManagedConfig{
+				MaxReplicas:         pointer.Int32Ptr(3),
+				LocationLabels:      []string{"zone", "host"},
+				LeaderScheduleLimit: pointer.Int32Ptr(4),
+			},
+			live: &pdapi.PDConfigFromAPI{
+				Replication: &pdapi.PDReplicationConfig{MaxReplicas: &three, LocationLabels: pdapi.StringSlice{"zone", "host"}},
+				Schedule:    &pdapi.PDScheduleConfig{LeaderScheduleLimit: &four, RegionScheduleLimit: &four},
+			},
+		},
+		{
+			name: "update the parameters differing from the live ones",
+			managed: &v1alpha1.PDManagedConfig{
+				MaxReplicas:         pointer.Int32Ptr(5),
+				LocationLabels:      []string{"zone", "host"},
+				LeaderScheduleLimit: pointer.Int32Ptr(4),
+				RegionScheduleLimit: pointer.Int32Ptr(8),
+			},
+			live: &pdapi.PDConfigFromAPI{
+				Replication: &pdapi.PDReplicationConfig{MaxReplicas: &three, LocationLabels: pdapi.StringSlice{"zone", "host"}},
+				Schedule:    &pdapi.PDScheduleConfig{LeaderScheduleLimit: &four, RegionScheduleLimit: &four},
+			},
+			expectReplication: &pdapi.PDReplicationConfig{MaxReplicas: func() *uint64 { v := uint64(5); return &v }()},
+			expectSchedule:    &pdapi.PDScheduleConfig{RegionScheduleLimit: func() *uint64 { v := uint64(8); return &v }()},
+		},
+		{
+			name: "the region-schedule-limit raised by the rebalance of tikv is kept",
+			managed: &v1alpha1.PDManagedConfig{
+				LeaderScheduleLimit: pointer.Int32Ptr(8),
+				RegionScheduleLimit: pointer.Int32Ptr(8),
+			},
+			rebalancing: true,
+			live: &pdapi.PDConfigFromAPI{
+				Schedule: &pdapi.PDScheduleConfig{LeaderScheduleLimit: &four, RegionScheduleLimit: &four},
+			},
+			expectSchedule: &pdapi.PDScheduleConfig{LeaderScheduleLimit: func() *uint64 { v := uint64(8); return &v }()},
+		},
+		{
+			name:              "set the location labels missing in pd",
+			managed:           &v1alpha1.PDManagedConfig{LocationLabels: []string{"zone"}},
+			live:              &pdapi.PDConfigFromAPI{},
+			expectReplication: &pdapi.PDReplicationConfig{LocationLabels: pdapi.StringSlice{"zone"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.PD.ManagedConfig = tt.managed
+			if tt.rebalancing {
+				tc.Status.TiKV.Rebalance = &v1alpha1.TiKVRebalanceStatus{StartTime: &metav1.Time{Time: time.Now()}}
+			}
+			if !tt.pdUnavailable {
+				tc.Status.PD.Members = map[string]v1alpha1.PDMember{
+					"pd-0": {Health: true}, "pd-1": {Health: true}, "pd-2": {Health: true},
+				}
+				tc.Status.PD.StatefulSet = &apps.StatefulSetStatus{ReadyReplicas: 3}
+			}
+			pmm, _, _, pdControl, _, _, _ := newFakePDMemberManager()
+			pdClient := controller.NewFakePDClient(pdControl, tc)
+			pdClient.AddReaction(pdapi.GetConfigActionType, func(action *pdapi.Action) (interface{}, error) {
+				return tt.live, nil
+			})
+			var replication *pdapi.PDReplicationConfig
+			pdClient.AddReaction(pdapi.UpdateReplicationActionType, func(action *pdapi.Action) (interface{}, error) {
+				replication = &action.Replication
+				return nil, nil
+			})
+			var schedule *pdapi.PDScheduleConfig
+			pdClient.AddReaction(pdapi.UpdateScheduleActionType, func(action *pdapi.Action) (interface{}, error) {
+				schedule = &action.Schedule
+				return nil, nil
+			})
+
+			g.Expect(pmm.syncManagedConfig(tc)).To(Succeed())
+			g.Expect(replication).To(Equal(tt.expectReplication))
+			g.Expect(schedule).To(Equal(tt.expectSchedule))
+		})
+	}
+}
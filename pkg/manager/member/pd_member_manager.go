@@ -15,7 +15,7 @@ package member
 
 import (
 	"fmt"
-	"strconv"
+	"path"
 	"strings"
 
 	"github.com/Masterminds/semver"
@@ -33,8 +33,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/uuid"
 	v1 "k8s.io/client-go/listers/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
-	"k8s.io/klog"
+	"k8s.io/client-go/tools/record"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/utils/pointer"
 )
 
 const (
@@ -58,6 +59,7 @@ type pdMemberManager struct {
 	pdUpgrader   Upgrader
 	autoFailover bool
 	pdFailover   Failover
+	recorder     record.EventRecorder
 }
 
 // NewPDMemberManager returns a *pdMemberManager
@@ -74,7 +76,8 @@ func NewPDMemberManager(pdControl pdapi.PDControlInterface,
 	pdScaler Scaler,
 	pdUpgrader Upgrader,
 	autoFailover bool,
-	pdFailover Failover) manager.Manager {
+	pdFailover Failover,
+	recorder record.EventRecorder) manager.Manager {
 	return &pdMemberManager{
 		pdControl,
 		setControl,
@@ -89,7 +92,8 @@ func NewPDMemberManager(pdControl pdapi.PDControlInterface,
 		pdScaler,
 		pdUpgrader,
 		autoFailover,
-		pdFailover}
+		pdFailover,
+		recorder}
 }
 
 func (pmm *pdMemberManager) Sync(tc *v1alpha1.TikvCluster) error {
@@ -113,8 +117,14 @@ func (pmm *pdMemberManager) Sync(tc *v1alpha1.TikvCluster) error {
 					return err
 				}
 
-				for idx, pod := range pods {
-					svcList = append(svcList, getNewNodeportServiceForTikvCluster(tc, int32(idx), eListener, pod.Status.HostIP, true))
+				ordinals, podMap := podsByOrdinal(pods, controller.PDMemberName(tc.GetName()))
+				if len(ordinals) > 0 {
+					if err := validateNodePorts(eListener, int(ordinals[len(ordinals)-1])+1); err != nil {
+						return err
+					}
+				}
+				for _, ordinal := range ordinals {
+					svcList = append(svcList, getNewNodeportServiceForTikvCluster(tc, ordinal, eListener, podMap[ordinal].Status.HostIP, true))
 				}
 			}
 		}
@@ -132,12 +142,17 @@ func (pmm *pdMemberManager) Sync(tc *v1alpha1.TikvCluster) error {
 	}
 
 	// Sync PD StatefulSet
-	return pmm.syncPDStatefulSetForTikvCluster(tc)
+	if err := pmm.syncPDStatefulSetForTikvCluster(tc); err != nil {
+		return err
+	}
+
+	// Sync PD managed config
+	return pmm.syncManagedConfig(tc)
 }
 
 func (pmm *pdMemberManager) syncPDServiceForTikvCluster(tc *v1alpha1.TikvCluster, newSvc *corev1.Service) error {
 	if tc.Spec.Paused {
-		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for pd service", tc.GetNamespace(), tc.GetName())
+		pdLogger(tc).V(4).Infof("tikv cluster is paused, skip syncing for pd service")
 		return nil
 	}
 
@@ -155,21 +170,22 @@ func (pmm *pdMemberManager) syncPDServiceForTikvCluster(tc *v1alpha1.TikvCluster
 	}
 
 	oldSvc := oldSvcTmp.DeepCopy()
+	preserveNodePorts(newSvc, oldSvc)
 
 	equal, err := controller.ServiceEqual(newSvc, oldSvc)
 	if err != nil {
 		return err
 	}
-	if !equal {
-		svc := *oldSvc
-		svc.Spec = newSvc.Spec
-		// TODO add unit test
-		err = controller.SetServiceLastAppliedConfigAnnotation(&svc)
+	annotationsEqual, err := controller.ServiceAnnotationsEqual(newSvc, oldSvc)
+	if err != nil {
+		return err
+	}
+	if !equal || !annotationsEqual {
+		svc, err := controller.MergeService(newSvc, oldSvc)
 		if err != nil {
 			return err
 		}
-		svc.Spec.ClusterIP = oldSvc.Spec.ClusterIP
-		_, err = pmm.svcControl.UpdateService(tc, &svc)
+		_, err = pmm.svcControl.UpdateService(tc, svc)
 		return err
 	}
 
@@ -178,7 +194,7 @@ func (pmm *pdMemberManager) syncPDServiceForTikvCluster(tc *v1alpha1.TikvCluster
 
 func (pmm *pdMemberManager) syncPDHeadlessServiceForTikvCluster(tc *v1alpha1.TikvCluster) error {
 	if tc.Spec.Paused {
-		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for pd headless service", tc.GetNamespace(), tc.GetName())
+		pdLogger(tc).V(4).Infof("tikv cluster is paused, skip syncing for pd headless service")
 		return nil
 	}
 
@@ -202,14 +218,16 @@ func (pmm *pdMemberManager) syncPDHeadlessServiceForTikvCluster(tc *v1alpha1.Tik
 	if err != nil {
 		return err
 	}
-	if !equal {
-		svc := *oldSvc
-		svc.Spec = newSvc.Spec
-		err = controller.SetServiceLastAppliedConfigAnnotation(&svc)
+	annotationsEqual, err := controller.ServiceAnnotationsEqual(newSvc, oldSvc)
+	if err != nil {
+		return err
+	}
+	if !equal || !annotationsEqual {
+		svc, err := controller.MergeService(newSvc, oldSvc)
 		if err != nil {
 			return err
 		}
-		_, err = pmm.svcControl.UpdateService(tc, &svc)
+		_, err = pmm.svcControl.UpdateService(tc, svc)
 		return err
 	}
 
@@ -229,11 +247,11 @@ func (pmm *pdMemberManager) syncPDStatefulSetForTikvCluster(tc *v1alpha1.TikvClu
 	oldPDSet := oldPDSetTmp.DeepCopy()
 
 	if err := pmm.syncTikvClusterStatus(tc, oldPDSet); err != nil {
-		klog.Errorf("failed to sync TikvCluster: [%s/%s]'s status, error: %v", ns, tcName, err)
+		pdLogger(tc).Errorf("failed to sync the status of pd, error: %v", err)
 	}
 
 	if tc.Spec.Paused {
-		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for pd statefulset", tc.GetNamespace(), tc.GetName())
+		pdLogger(tc).V(4).Infof("tikv cluster is paused, skip syncing for pd statefulset")
 		return nil
 	}
 
@@ -303,7 +321,7 @@ func (pmm *pdMemberManager) shouldRecover(tc *v1alpha1.TikvCluster) bool {
 		name := fmt.Sprintf("%s-%d", controller.PDMemberName(tc.GetName()), ordinal)
 		pod, err := pmm.podLister.Pods(tc.Namespace).Get(name)
 		if err != nil {
-			klog.Errorf("pod %s/%s does not exist: %v", tc.Namespace, name, err)
+			pdLogger(tc).Errorf("pod %s/%s does not exist: %v", tc.Namespace, name, err)
 			return false
 		}
 		if !podutil.IsPodReady(pod) {
@@ -360,7 +378,10 @@ func (pmm *pdMemberManager) syncTikvClusterStatus(tc *v1alpha1.TikvCluster, set
 		tc.Status.PD.Synced = false
 		return err
 	}
-	tc.Status.ClusterID = strconv.FormatUint(cluster.Id, 10)
+	if err := syncClusterID(tc, cluster, pmm.recorder); err != nil {
+		tc.Status.PD.Synced = false
+		return err
+	}
 	leader, err := pdClient.GetPDLeader()
 	if err != nil {
 		tc.Status.PD.Synced = false
@@ -376,8 +397,8 @@ func (pmm *pdMemberManager) syncTikvClusterStatus(tc *v1alpha1.TikvCluster, set
 		}
 		name := memberHealth.Name
 		if len(name) == 0 {
-			klog.Warningf("PD member: [%d] doesn't have a name, and can't get it from clientUrls: [%s], memberHealth Info: [%v] in [%s/%s]",
-				id, memberHealth.ClientUrls, memberHealth, ns, tcName)
+			pdLogger(tc).Warningf("PD member: [%d] doesn't have a name, and can't get it from clientUrls: [%s], memberHealth Info: [%v]",
+				id, memberHealth.ClientUrls, memberHealth)
 			continue
 		}
 
@@ -426,11 +447,13 @@ func (pmm *pdMemberManager) syncPDConfigMap(tc *v1alpha1.TikvCluster, set *apps.
 	if err != nil {
 		return nil, err
 	}
-	if set != nil && tc.BasePDSpec().ConfigUpdateStrategy() == v1alpha1.ConfigUpdateStrategyInPlace {
+	if set != nil {
 		inUseName := FindConfigMapVolume(&set.Spec.Template.Spec, func(name string) bool {
 			return strings.HasPrefix(name, controller.PDMemberName(tc.Name))
 		})
-		if inUseName != "" {
+		// the ConfigMap equivalent to the one in use is not rolled out, e.g. the configs only formatted differently
+		inPlace := tc.BasePDSpec().ConfigUpdateStrategy() == v1alpha1.ConfigUpdateStrategyInPlace
+		if inUseName != "" && (inPlace || configMapEquivalent(pmm.typedControl, inUseName, newCm)) {
 			newCm.Name = inUseName
 		}
 	}
@@ -571,7 +594,7 @@ func getNewPDSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap) (
 	}
 	if tc.IsTLSClusterEnabled() {
 		volMounts = append(volMounts, corev1.VolumeMount{
-			Name: "pd-tls", ReadOnly: true, MountPath: "/var/lib/pd-tls",
+			Name: "pd-tls", ReadOnly: true, MountPath: tlsClusterMountPath(tc, pdClusterCertPath),
 		})
 	}
 
@@ -602,7 +625,7 @@ func getNewPDSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap) (
 		vols = append(vols, corev1.Volume{
 			Name: "pd-tls", VolumeSource: corev1.VolumeSource{
 				Secret: &corev1.SecretVolumeSource{
-					SecretName: util.ClusterTLSSecretName(tc.Name, label.PDLabelVal),
+					SecretName: tlsClusterSecretName(tc, label.PDLabelVal),
 				},
 			},
 		})
@@ -615,7 +638,7 @@ func getNewPDSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap) (
 
 	pdLabel := label.New().Instance(instanceName).PD()
 	setName := controller.PDMemberName(tcName)
-	podAnnotations := CombineAnnotations(controller.AnnProm(2379), basePDSpec.Annotations())
+	podAnnotations := CombineAnnotations(promAnnotations(basePDSpec, 2379), basePDSpec.Annotations())
 	stsAnnotations := getStsAnnotations(tc, label.PDLabelVal)
 	failureReplicas := getFailureReplicas(tc)
 
@@ -662,7 +685,7 @@ func getNewPDSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap) (
 		},
 		{
 			Name:  "TZ",
-			Value: tc.Spec.Timezone,
+			Value: tc.Timezone(),
 		},
 		{
 			Name: "HostIP",
@@ -687,6 +710,7 @@ func getNewPDSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap) (
 		})
 	}
 	pdContainer.Env = util.AppendEnv(env, basePDSpec.Env())
+	pdContainer.EnvFrom = basePDSpec.EnvFrom()
 	podSpec.Volumes = vols
 	podSpec.Containers = []corev1.Container{pdContainer}
 
@@ -703,7 +727,7 @@ func getNewPDSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap) (
 			Selector: pdLabel.LabelSelector(),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels:      pdLabel.Labels(),
+					Labels:      CombineLabels(basePDSpec.Labels(), pdLabel),
 					Annotations: podAnnotations,
 				},
 				Spec: podSpec,
@@ -743,6 +767,18 @@ func getPDConfigMap(tc *v1alpha1.TikvCluster) (*corev1.ConfigMap, error) {
 		return nil, nil
 	}
 
+	// make sure the tls paths of pd match where the certificates are mounted
+	if tc.IsTLSClusterEnabled() {
+		config = config.DeepCopy()
+		if config.Security == nil {
+			config.Security = &v1alpha1.PDSecurityConfig{}
+		}
+		certPath := tlsClusterMountPath(tc, pdClusterCertPath)
+		config.Security.CAPath = pointer.StringPtr(path.Join(certPath, corev1.ServiceAccountRootCAKey))
+		config.Security.CertPath = pointer.StringPtr(path.Join(certPath, corev1.TLSCertKey))
+		config.Security.KeyPath = pointer.StringPtr(path.Join(certPath, corev1.TLSPrivateKeyKey))
+	}
+
 	confText, err := MarshalTOML(config)
 	if err != nil {
 		return nil, err
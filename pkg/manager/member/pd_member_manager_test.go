@@ -39,6 +39,7 @@ import (
 	kubeinformers "k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 )
 
@@ -742,6 +743,7 @@ func newFakePDMemberManager() (*pdMemberManager, *controller.FakeStatefulSetCont
 		pdUpgrader,
 		autoFailover,
 		pdFailover,
+		record.NewFakeRecorder(100),
 	}, setControl, svcControl, pdControl, podInformer.Informer().GetIndexer(), pvcInformer.Informer().GetIndexer(), podControl
 }
 
@@ -1022,7 +1024,7 @@ func TestGetNewPDSetForTikvCluster(t *testing.T) {
 								},
 								{
 									Name:  "TZ",
-									Value: "ignored",
+									Value: "Asia/Shanghai",
 								},
 							},
 						},
@@ -1051,7 +1053,8 @@ func TestGetNewPDSetForTikvCluster(t *testing.T) {
 					Value: "tc-pd",
 				},
 				{
-					Name: "TZ",
+					Name:  "TZ",
+					Value: "Asia/Shanghai",
 				},
 				{
 					Name: "HostIP",
@@ -1852,3 +1855,69 @@ func hasTLSVolMount(sts *apps.StatefulSet) bool {
 	}
 	return false
 }
+
+func TestPDMemberManagerSyncPDConfigMap(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name        string
+		inUseConfig string
+		expectInUse bool
+	}{
+		{
+			name: "equivalent config in use",
+			inUseConfig: `[replication]
+  location-labels = ["zone", "host"]
+  max-replicas = 3
+`,
+			expectInUse: true,
+		},
+		{
+			name: "config changed",
+			inUseConfig: `[replication]
+  max-replicas = 5
+  location-labels = ["zone", "host"]
+`,
+			expectInUse: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pmm, _, _, _, _, _, _ := newFakePDMemberManager()
+			tc := newTikvClusterForPD()
+			tc.Spec.ConfigUpdateStrategy = v1alpha1.ConfigUpdateStrategyRollingUpdate
+			tc.Spec.PD.Config = &v1alpha1.PDConfig{
+				Replication: &v1alpha1.PDReplicationConfig{
+					MaxReplicas:    func() *uint64 { i := uint64(3); return &i }(),
+					LocationLabels: []string{"zone", "host"},
+				},
+			}
+			newCm, err := getPDConfigMap(tc)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			inUseName := fmt.Sprintf("%s-0123456", controller.PDMemberName(tc.GetName()))
+			_, err = pmm.typedControl.CreateOrUpdateConfigMap(tc, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: inUseName, Namespace: tc.GetNamespace()},
+				Data:       map[string]string{"config-file": tt.inUseConfig, "startup-script": newCm.Data["startup-script"]},
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+			set := &apps.StatefulSet{}
+			set.Spec.Template.Spec.Volumes = []corev1.Volume{{
+				Name: "config",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: inUseName},
+					},
+				},
+			}}
+
+			cm, err := pmm.syncPDConfigMap(tc, set)
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.expectInUse {
+				g.Expect(cm.Name).To(Equal(inUseName))
+			} else {
+				g.Expect(cm.Name).To(Equal(newCm.Name))
+				g.Expect(cm.Name).NotTo(Equal(inUseName))
+			}
+		})
+	}
+}
@@ -24,7 +24,6 @@ import (
 	"github.com/tikv/tikv-operator/pkg/pdapi"
 	apps "k8s.io/api/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
-	"k8s.io/klog"
 )
 
 // TODO add e2e test specs
@@ -59,7 +58,7 @@ func (psd *pdScaler) ScaleOut(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSet
 		return nil
 	}
 
-	klog.Infof("scaling out pd statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
+	pdLogger(tc).Infof("scaling out pd statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
 	_, err := psd.deleteDeferDeletingPVC(tc, oldSet.GetName(), v1alpha1.PDMemberType, ordinal)
 	if err != nil {
 		return err
@@ -110,7 +109,7 @@ func (psd *pdScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSet,
 		return fmt.Errorf("TikvCluster: %s/%s's pd status sync failed,can't scale in now", ns, tcName)
 	}
 
-	klog.Infof("scaling in pd statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
+	pdLogger(tc).Infof("scaling in pd statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
 
 	pdClient := controller.GetPDClient(psd.pdControl, tc)
 	// If the pd pod was pd leader during scale-in, we would transfer pd leader to pd-0 directly
@@ -132,10 +131,10 @@ func (psd *pdScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSet,
 
 	err := pdClient.DeleteMember(memberName)
 	if err != nil {
-		klog.Errorf("pd scale in: failed to delete member %s, %v", memberName, err)
+		pdLogger(tc).Errorf("pd scale in: failed to delete member %s, %v", memberName, err)
 		return err
 	}
-	klog.Infof("pd scale in: delete member %s successfully", memberName)
+	pdLogger(tc).Infof("pd scale in: delete member %s successfully", memberName)
 
 	pvcName := ordinalPVCName(v1alpha1.PDMemberType, setName, ordinal)
 	pvc, err := psd.pvcLister.PersistentVolumeClaims(ns).Get(pvcName)
@@ -151,11 +150,11 @@ func (psd *pdScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSet,
 
 	_, err = psd.pvcControl.UpdatePVC(tc, pvc)
 	if err != nil {
-		klog.Errorf("pd scale in: failed to set pvc %s/%s annotation: %s to %s",
+		pdLogger(tc).Errorf("pd scale in: failed to set pvc %s/%s annotation: %s to %s",
 			ns, pvcName, label.AnnPVCDeferDeleting, now)
 		return err
 	}
-	klog.Infof("pd scale in: set pvc %s/%s annotation: %s to %s",
+	pdLogger(tc).Infof("pd scale in: set pvc %s/%s annotation: %s to %s",
 		ns, pvcName, label.AnnPVCDeferDeleting, now)
 
 	setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
@@ -22,7 +22,6 @@ import (
 	"github.com/tikv/tikv-operator/pkg/pdapi"
 	apps "k8s.io/api/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
-	"k8s.io/klog"
 )
 
 type pdUpgrader struct {
@@ -68,7 +67,7 @@ func (pu *pdUpgrader) gracefulUpgrade(tc *v1alpha1.TikvCluster, oldSet *apps.Sta
 		// If we encounter this situation, we will let the native statefulset controller do the upgrade completely, which may be unsafe for upgrading pd.
 		// Therefore, in the production environment, we should try to avoid modifying the pd statefulset update strategy directly.
 		newSet.Spec.UpdateStrategy = oldSet.Spec.UpdateStrategy
-		klog.Warningf("tidbcluster: [%s/%s] pd statefulset %s UpdateStrategy has been modified manually", ns, tcName, oldSet.GetName())
+		pdLogger(tc).Warningf("pd statefulset %s UpdateStrategy has been modified manually", oldSet.GetName())
 		return nil
 	}
 
@@ -114,10 +113,10 @@ func (pu *pdUpgrader) upgradePDPod(tc *v1alpha1.TikvCluster, ordinal int32, newS
 		}
 		err := pu.transferPDLeaderTo(tc, targetName)
 		if err != nil {
-			klog.Errorf("pd upgrader: failed to transfer pd leader to: %s, %v", targetName, err)
+			pdLogger(tc).Errorf("pd upgrader: failed to transfer pd leader to: %s, %v", targetName, err)
 			return err
 		}
-		klog.Infof("pd upgrader: transfer pd leader to: %s successfully", targetName)
+		pdLogger(tc).Infof("pd upgrader: transfer pd leader to: %s successfully", targetName)
 		return controller.RequeueErrorf("tidbcluster: [%s/%s]'s pd member: [%s] is transferring leader to pd member: [%s]", ns, tcName, upgradePodName, targetName)
 	}
 
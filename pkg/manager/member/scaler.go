@@ -23,6 +23,7 @@ import (
 	"github.com/tikv/tikv-operator/pkg/features"
 	"github.com/tikv/tikv-operator/pkg/label"
 	"github.com/tikv/tikv-operator/pkg/pdapi"
+	"github.com/tikv/tikv-operator/pkg/util/log"
 	apps "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	corelisters "k8s.io/client-go/listers/core/v1"
@@ -54,6 +55,7 @@ type generalScaler struct {
 func (gs *generalScaler) deleteDeferDeletingPVC(tc *v1alpha1.TikvCluster,
 	setName string, memberType v1alpha1.MemberType, ordinal int32) (map[string]string, error) {
 	ns := tc.GetNamespace()
+	logger := log.ForCluster(tc, string(memberType))
 	// for unit test
 	skipReason := map[string]string{}
 
@@ -69,11 +71,11 @@ func (gs *generalScaler) deleteDeferDeletingPVC(tc *v1alpha1.TikvCluster,
 	pvcs, err := gs.pvcLister.PersistentVolumeClaims(ns).List(selector)
 	if err != nil {
 		msg := fmt.Sprintf("Cluster %s/%s list pvc failed, selector: %s, err: %v", ns, tc.Name, selector, err)
-		klog.Errorf(msg)
+		logger.Errorf(msg)
 		return skipReason, fmt.Errorf(msg)
 	}
 	if len(pvcs) == 0 {
-		klog.Infof("Cluster %s/%s list pvc not found, selector: %s", ns, tc.Name, selector)
+		logger.Infof("Cluster %s/%s list pvc not found, selector: %s", ns, tc.Name, selector)
 		skipReason[podName] = skipReasonScalerPVCNotFound
 		return skipReason, nil
 	}
@@ -91,10 +93,10 @@ func (gs *generalScaler) deleteDeferDeletingPVC(tc *v1alpha1.TikvCluster,
 
 		err = gs.pvcControl.DeletePVC(tc, pvc)
 		if err != nil {
-			klog.Errorf("Scale out: failed to delete pvc %s/%s, %v", ns, pvcName, err)
+			logger.Errorf("Scale out: failed to delete pvc %s/%s, %v", ns, pvcName, err)
 			return skipReason, err
 		}
-		klog.Infof("Scale out: delete pvc %s/%s successfully", ns, pvcName)
+		logger.Infof("Scale out: delete pvc %s/%s successfully", ns, pvcName)
 	}
 	return skipReason, nil
 }
@@ -102,6 +104,7 @@ func (gs *generalScaler) deleteDeferDeletingPVC(tc *v1alpha1.TikvCluster,
 func (gs *generalScaler) updateDeferDeletingPVC(tc *v1alpha1.TikvCluster,
 	memberType v1alpha1.MemberType, ordinal int32) error {
 	ns := tc.GetNamespace()
+	logger := log.ForCluster(tc, string(memberType))
 	podName := ordinalPodName(memberType, tc.Name, ordinal)
 
 	l := label.New().Instance(tc.GetInstanceName())
@@ -114,12 +117,12 @@ func (gs *generalScaler) updateDeferDeletingPVC(tc *v1alpha1.TikvCluster,
 	pvcs, err := gs.pvcLister.PersistentVolumeClaims(ns).List(selector)
 	if err != nil {
 		msg := fmt.Sprintf("Cluster %s/%s list pvc failed, selector: %s, err: %v", ns, tc.Name, selector, err)
-		klog.Errorf(msg)
+		logger.Errorf(msg)
 		return fmt.Errorf(msg)
 	}
 	if len(pvcs) == 0 {
 		msg := fmt.Sprintf("Cluster %s/%s list pvc not found, selector: %s", ns, tc.Name, selector)
-		klog.Errorf(msg)
+		logger.Errorf(msg)
 		return fmt.Errorf(msg)
 	}
 
@@ -132,11 +135,11 @@ func (gs *generalScaler) updateDeferDeletingPVC(tc *v1alpha1.TikvCluster,
 		pvc.Annotations[label.AnnPVCDeferDeleting] = now
 		_, err = gs.pvcControl.UpdatePVC(tc, pvc)
 		if err != nil {
-			klog.Errorf("Scale in: failed to set pvc %s/%s annotation: %s to %s, error: %v",
+			logger.Errorf("Scale in: failed to set pvc %s/%s annotation: %s to %s, error: %v",
 				ns, pvcName, label.AnnPVCDeferDeleting, now, err)
 			return err
 		}
-		klog.Infof("Scale in: set pvc %s/%s annotation: %s to %s",
+		logger.Infof("Scale in: set pvc %s/%s annotation: %s to %s",
 			ns, pvcName, label.AnnPVCDeferDeleting, now)
 	}
 	return nil
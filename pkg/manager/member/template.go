@@ -124,8 +124,8 @@ cluster_name=` + "`" + `echo ${PEER_SERVICE_NAME} | sed 's/-pd-peer//'` + "`" +
 	`
 domain="${POD_NAME}.${PEER_SERVICE_NAME}.${NAMESPACE}.svc"
 discovery_url="${cluster_name}-discovery.${NAMESPACE}.svc:10261"
-encoded_domain_url=` + "`" + `echo ${domain}:2380 | base64 | tr "\n" " " | sed "s/ //g"` + "`" +
-	`
+# the ordinal of the pod, e.g. 0 of demo-pd-0, the first member bootstraps the cluster and the others join it
+ordinal=${POD_NAME##*-}
 elapseTime=0
 period=1
 threshold=30
@@ -168,7 +168,7 @@ join=${join%,}
 ARGS="${ARGS} --join=${join}"
 elif [[ ! -d /var/lib/pd/member/wal ]]
 then
-until result=$(wget -qO- -T 3 http://${discovery_url}/new/${encoded_domain_url} 2>/dev/null); do
+until result=$(wget -qO- -T 3 http://${discovery_url}/ordinal/${cluster_name}/${ordinal} 2>/dev/null); do
 echo "waiting for discovery service to return start args ..."
 sleep $((RANDOM % 5))
 done
@@ -219,13 +219,25 @@ fi
 
 # Use HOSTNAME if POD_NAME is unset for backward compatibility.
 POD_NAME=${POD_NAME:-$HOSTNAME}
+
+# Resolve the variables referred to as $(NAME) in the config, the config mounted is read-only
+CONFIG=/etc/tikv/tikv.toml
+if grep -q '\$(' ${CONFIG}; then
+  sed -e "s|\$(NAMESPACE)|${NAMESPACE}|g" \
+    -e "s|\$(POD_NAME)|${POD_NAME}|g" \
+    -e "s|\$(CLUSTER_NAME)|${CLUSTER_NAME}|g" \
+    -e "s|\$(HEADLESS_SERVICE_NAME)|${HEADLESS_SERVICE_NAME}|g" \
+    ${CONFIG} > /tmp/tikv.toml
+  CONFIG=/tmp/tikv.toml
+fi
+
 ARGS="--pd={{ .Scheme }}://${CLUSTER_NAME}-pd:2379 \
 --advertise-addr=${POD_NAME}.${HEADLESS_SERVICE_NAME}.${NAMESPACE}.svc:20160 \
 --addr=0.0.0.0:20160 \
 --status-addr=0.0.0.0:20180 \
 --data-dir=/var/lib/tikv \
 --capacity=${CAPACITY} \
---config=/etc/tikv/tikv.toml
+--config=${CONFIG}
 "
 
 if [ ! -z "${STORE_LABELS:-}" ]; then
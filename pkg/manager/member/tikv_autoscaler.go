@@ -0,0 +1,275 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/manager"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// regionBalancedRatio is the ratio of the region count of every store to the average region count
+	// above which the regions are considered balanced, e.g. the new store has received its share of
+	// the regions after a scale-out
+	regionBalancedRatio = 0.5
+)
+
+type tikvAutoScaler struct {
+	metrics  TiKVMetricsQuerier
+	recorder record.EventRecorder
+}
+
+// NewTiKVAutoScaler returns a manager.Manager which scales TiKV by one store at a time based on the storage
+// utilization, the region count and the CPU utilization of the stores. The scaling itself is left to the TiKV
+// scaler, the autoscaler waits for the previous scaling to finish, for PD to rebalance the regions among the
+// stores and for the cool-down window to elapse before scaling again.
+func NewTiKVAutoScaler(metrics TiKVMetricsQuerier, recorder record.EventRecorder) manager.Manager {
+	return &tikvAutoScaler{metrics, recorder}
+}
+
+// tikvMetrics is the averages of the metrics of the stores TiKV is scaled by, a metric is nil
+// if it is not configured or not available
+type tikvMetrics struct {
+	storageUtilization *float64
+	regionCount        *float64
+	cpuUtilization     *float64
+}
+
+func (as *tikvAutoScaler) Sync(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.Paused || !tc.TiKVAutoScalingEnabled() {
+		return nil
+	}
+	logger := tikvLogger(tc)
+
+	if tc.Status.TiKV.Phase != v1alpha1.NormalPhase || !tc.TiKVAllPodsStarted() || !tc.TiKVAllStoresReady() {
+		logger.V(4).Infof("tikv is not stable, skip auto-scaling")
+		return nil
+	}
+
+	metrics := as.getMetrics(tc)
+	replicas := tc.Spec.TiKV.Replicas
+	if reason := scaleOutReason(tc, metrics); reason != "" {
+		if !as.canScale(tc, tc.TiKVScaleOutInterval()) {
+			return nil
+		}
+		maxReplicas := tc.Spec.TiKV.AutoScaling.MaxReplicas
+		if replicas >= maxReplicas {
+			logger.Warningf("%s, but the replicas already reach the max replicas %d", reason, maxReplicas)
+			return nil
+		}
+		return as.scale(tc, replicas+1, reason)
+	}
+
+	if reason := scaleInReason(tc, metrics); reason != "" {
+		minReplicas := tc.Spec.TiKV.AutoScaling.MinReplicas
+		if minReplicas == nil || replicas <= *minReplicas {
+			return nil
+		}
+		if !as.canScale(tc, tc.TiKVScaleInInterval()) {
+			return nil
+		}
+		if !as.scaleInSafe(tc, metrics) {
+			return nil
+		}
+		return as.scale(tc, replicas-1, reason)
+	}
+	return nil
+}
+
+// canScale returns whether the cool-down window after the last scaling has elapsed and the regions are
+// balanced among the stores, so that the effect of the last scaling is observed before scaling again
+func (as *tikvAutoScaler) canScale(tc *v1alpha1.TikvCluster, interval time.Duration) bool {
+	logger := tikvLogger(tc)
+	if last := tc.Status.TiKV.LastAutoScaledTime; last != nil && time.Since(last.Time) < interval {
+		logger.V(4).Infof("tikv was auto-scaled at %s, skip auto-scaling in the cool-down window", last.Time)
+		return false
+	}
+	if !regionsBalanced(tc) {
+		logger.Infof("the regions are not balanced among the stores yet, skip auto-scaling")
+		return false
+	}
+	return true
+}
+
+// scale changes the replicas of the spec and requeues the sync, so that the replicas and the last auto-scaled
+// time are persisted with the TikvCluster before the TiKV scaler scales the statefulset
+func (as *tikvAutoScaler) scale(tc *v1alpha1.TikvCluster, replicas int32, reason string) error {
+	action := "scale out"
+	if replicas < tc.Spec.TiKV.Replicas {
+		action = "scale in"
+	}
+	tikvLogger(tc).Infof("%s, %s tikv from %d to %d", reason, action, tc.Spec.TiKV.Replicas, replicas)
+	as.recorder.Eventf(tc, corev1.EventTypeNormal, "AutoScaled", "%s, %s tikv from %d to %d", reason, action, tc.Spec.TiKV.Replicas, replicas)
+	now := metav1.Now()
+	tc.Spec.TiKV.Replicas = replicas
+	tc.Status.TiKV.LastAutoScaledTime = &now
+	return controller.RequeueErrorf("TikvCluster: [%s/%s], persisting the auto-scaled tikv replicas %d before scaling the statefulset",
+		tc.GetNamespace(), tc.GetName(), replicas)
+}
+
+// getMetrics collects the metrics TiKV is scaled by, the CPU utilization is skipped with a warning
+// if it is unavailable so that the other metrics still take effect
+func (as *tikvAutoScaler) getMetrics(tc *v1alpha1.TikvCluster) tikvMetrics {
+	metrics := tikvMetrics{}
+	if utilization, ok := tc.TiKVStorageUtilization(); ok {
+		metrics.storageUtilization = &utilization
+	}
+	if regionCount, ok := tc.TiKVRegionCount(); ok {
+		metrics.regionCount = &regionCount
+	}
+	if tc.Spec.TiKV.AutoScaling.CPUUtilization != nil {
+		utilization, err := as.getCPUUtilization(tc)
+		if err != nil {
+			tikvLogger(tc).Warningf("failed to get the cpu utilization of tikv, skip auto-scaling by it: %v", err)
+		} else {
+			metrics.cpuUtilization = &utilization
+		}
+	}
+	return metrics
+}
+
+// getCPUUtilization returns the average CPU usage of the TiKV pods in percentage of their CPU requests,
+// the CPU limits are used if the requests are not set. The requirements recommended by the sizing profile count.
+func (as *tikvAutoScaler) getCPUUtilization(tc *v1alpha1.TikvCluster) (float64, error) {
+	requirements := tc.TiKVResourceRequirements()
+	cpu, ok := requirements.Requests[corev1.ResourceCPU]
+	if !ok || cpu.IsZero() {
+		cpu, ok = requirements.Limits[corev1.ResourceCPU]
+	}
+	if !ok || cpu.IsZero() {
+		return 0, fmt.Errorf("neither the cpu requests nor the cpu limits of tikv are set")
+	}
+	if tc.Spec.TiKV.AutoScaling.MetricsURL == "" {
+		return 0, fmt.Errorf("the metrics url is not set")
+	}
+	usage, err := as.metrics.QueryCPUUsage(tc)
+	if err != nil {
+		return 0, err
+	}
+	return usage / float64(cpu.MilliValue()) * 1000 * 100, nil
+}
+
+// scaleInSafe returns whether the regions on the store to be removed can be rebalanced to the remaining
+// stores, which must not be fewer than the minimum up stores and must not be scaled out again right
+// after taking over the regions
+func (as *tikvAutoScaler) scaleInSafe(tc *v1alpha1.TikvCluster, metrics tikvMetrics) bool {
+	logger := tikvLogger(tc)
+	stores := int32(len(tc.Status.TiKV.Stores))
+	if stores <= 1 {
+		return false
+	}
+	if stores-1 < tc.TiKVMinUpStores() {
+		logger.Infof("tikv can not be scaled in, the remaining %d stores are fewer than the minimum %d up stores", stores-1, tc.TiKVMinUpStores())
+		return false
+	}
+
+	// the metrics of the remaining stores once the regions are rebalanced to them evenly
+	ratio := float64(stores) / float64(stores-1)
+	if metrics.storageUtilization != nil && *metrics.storageUtilization*ratio > float64(tc.TiKVStorageUtilizationThreshold()) {
+		logger.Infof("tikv can not be scaled in, the storage utilization of the remaining stores would exceed %d%%", tc.TiKVStorageUtilizationThreshold())
+		return false
+	}
+	spec := tc.Spec.TiKV.AutoScaling
+	if spec.RegionCount != nil && metrics.regionCount != nil && *metrics.regionCount*ratio > float64(spec.RegionCount.ScaleOut) {
+		logger.Infof("tikv can not be scaled in, the region count of the remaining stores would exceed %d", spec.RegionCount.ScaleOut)
+		return false
+	}
+	if spec.CPUUtilization != nil && metrics.cpuUtilization != nil && *metrics.cpuUtilization*ratio > float64(spec.CPUUtilization.ScaleOut) {
+		logger.Infof("tikv can not be scaled in, the cpu utilization of the remaining stores would exceed %d%%", spec.CPUUtilization.ScaleOut)
+		return false
+	}
+	return true
+}
+
+// scaleOutReason returns why TiKV should be scaled out, which is empty if any metric does not exceed
+// its scale-out threshold
+func scaleOutReason(tc *v1alpha1.TikvCluster, metrics tikvMetrics) string {
+	as := tc.Spec.TiKV.AutoScaling
+	if u := metrics.storageUtilization; u != nil && *u > float64(tc.TiKVStorageUtilizationThreshold()) {
+		return fmt.Sprintf("the storage utilization of tikv %.1f%% exceeds %d%%", *u, tc.TiKVStorageUtilizationThreshold())
+	}
+	if c := metrics.regionCount; as.RegionCount != nil && c != nil && *c > float64(as.RegionCount.ScaleOut) {
+		return fmt.Sprintf("the region count of tikv %.0f exceeds %d", *c, as.RegionCount.ScaleOut)
+	}
+	if u := metrics.cpuUtilization; as.CPUUtilization != nil && u != nil && *u > float64(as.CPUUtilization.ScaleOut) {
+		return fmt.Sprintf("the cpu utilization of tikv %.1f%% exceeds %d%%", *u, as.CPUUtilization.ScaleOut)
+	}
+	return ""
+}
+
+// scaleInReason returns why TiKV should be scaled in, which is empty unless the region count and
+// the CPU utilization are configured with the scale-in thresholds and are both below them
+func scaleInReason(tc *v1alpha1.TikvCluster, metrics tikvMetrics) string {
+	as := tc.Spec.TiKV.AutoScaling
+	if as.RegionCount == nil && as.CPUUtilization == nil {
+		return ""
+	}
+	reason := ""
+	if t := as.RegionCount; t != nil {
+		if t.ScaleIn == nil || metrics.regionCount == nil || *metrics.regionCount >= float64(*t.ScaleIn) {
+			return ""
+		}
+		reason = fmt.Sprintf("the region count of tikv %.0f is below %d", *metrics.regionCount, *t.ScaleIn)
+	}
+	if t := as.CPUUtilization; t != nil {
+		if t.ScaleIn == nil || metrics.cpuUtilization == nil || *metrics.cpuUtilization >= float64(*t.ScaleIn) {
+			return ""
+		}
+		if reason != "" {
+			reason += " and "
+		}
+		reason += fmt.Sprintf("the cpu utilization of tikv %.1f%% is below %d%%", *metrics.cpuUtilization, *t.ScaleIn)
+	}
+	return reason
+}
+
+// regionsBalanced returns whether every store holds its share of the regions, PD moves the regions
+// to the new stores gradually after a scale-out
+func regionsBalanced(tc *v1alpha1.TikvCluster) bool {
+	average, ok := tc.TiKVRegionCount()
+	if !ok {
+		return true
+	}
+	for _, store := range tc.Status.TiKV.Stores {
+		if float64(store.RegionCount) < average*regionBalancedRatio {
+			return false
+		}
+	}
+	return true
+}
+
+var _ manager.Manager = &tikvAutoScaler{}
+
+type FakeTiKVAutoScaler struct {
+	err error
+}
+
+func NewFakeTiKVAutoScaler() *FakeTiKVAutoScaler {
+	return &FakeTiKVAutoScaler{}
+}
+
+func (fas *FakeTiKVAutoScaler) SetSyncError(err error) {
+	fas.err = err
+}
+
+func (fas *FakeTiKVAutoScaler) Sync(_ *v1alpha1.TikvCluster) error {
+	return fas.err
+}
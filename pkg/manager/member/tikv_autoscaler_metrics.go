@@ -0,0 +1,89 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+)
+
+const metricsQueryTimeout = 10 * time.Second
+
+// TiKVMetricsQuerier queries the metrics of TiKV the autoscaler is based on
+type TiKVMetricsQuerier interface {
+	// QueryCPUUsage returns the average CPU usage in cores of the TiKV pods over the last minute
+	QueryCPUUsage(tc *v1alpha1.TikvCluster) (float64, error)
+}
+
+type prometheusMetricsQuerier struct{}
+
+// NewPrometheusMetricsQuerier returns a TiKVMetricsQuerier querying the Prometheus at the MetricsURL of
+// the autoscaler, the metrics of the TiKV pods are selected by their namespace and pod labels, which are
+// attached by the ServiceMonitor of TiKV
+func NewPrometheusMetricsQuerier() TiKVMetricsQuerier {
+	return &prometheusMetricsQuerier{}
+}
+
+func (q *prometheusMetricsQuerier) QueryCPUUsage(tc *v1alpha1.TikvCluster) (float64, error) {
+	cli, err := api.NewClient(api.Config{Address: tc.Spec.TiKV.AutoScaling.MetricsURL})
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf(`avg(rate(process_cpu_seconds_total{namespace="%s",pod=~"%s-[0-9]+"}[1m]))`,
+		tc.Namespace, controller.TiKVMemberName(tc.Name))
+
+	ctx, cancel := context.WithTimeout(context.Background(), metricsQueryTimeout)
+	defer cancel()
+	value, err := promv1.NewAPI(cli).Query(ctx, query, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s from %s: %v", query, tc.Spec.TiKV.AutoScaling.MetricsURL, err)
+	}
+	vector, ok := value.(model.Vector)
+	if !ok || len(vector) == 0 {
+		return 0, fmt.Errorf("no result of %s from %s", query, tc.Spec.TiKV.AutoScaling.MetricsURL)
+	}
+	return float64(vector[0].Value), nil
+}
+
+var _ TiKVMetricsQuerier = &prometheusMetricsQuerier{}
+
+type FakeTiKVMetricsQuerier struct {
+	cpuUsage float64
+	err      error
+}
+
+func NewFakeTiKVMetricsQuerier() *FakeTiKVMetricsQuerier {
+	return &FakeTiKVMetricsQuerier{}
+}
+
+func (fq *FakeTiKVMetricsQuerier) SetCPUUsage(cpuUsage float64) {
+	fq.cpuUsage = cpuUsage
+}
+
+func (fq *FakeTiKVMetricsQuerier) SetQueryError(err error) {
+	fq.err = err
+}
+
+func (fq *FakeTiKVMetricsQuerier) QueryCPUUsage(_ *v1alpha1.TikvCluster) (float64, error) {
+	return fq.cpuUsage, fq.err
+}
+
+var _ TiKVMetricsQuerier = &FakeTiKVMetricsQuerier{}
@@ -0,0 +1,329 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/manager"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestTiKVAutoScalerSync(t *testing.T) {
+	type testcase struct {
+		name             string
+		update           func(tc *v1alpha1.TikvCluster)
+		cpuUsage         float64
+		expectReplicas   int32
+		expectAutoScaled bool
+	}
+
+	testFn := func(test *testcase, t *testing.T) {
+		t.Log(test.name)
+		g := NewGomegaWithT(t)
+
+		tc := newTikvClusterForAutoScaler(3, 10, 100)
+		if test.update != nil {
+			test.update(tc)
+		}
+		as, querier, recorder := newFakeTiKVAutoScaler()
+		querier.SetCPUUsage(test.cpuUsage)
+		lastAutoScaledTime := tc.Status.TiKV.LastAutoScaledTime
+
+		err := as.Sync(tc)
+		g.Expect(tc.Spec.TiKV.Replicas).To(Equal(test.expectReplicas))
+		if test.expectAutoScaled {
+			g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+			g.Expect(tc.Status.TiKV.LastAutoScaledTime).NotTo(Equal(lastAutoScaledTime))
+			g.Expect(recorder.Events).To(HaveLen(1))
+		} else {
+			g.Expect(err).To(Succeed())
+			g.Expect(tc.Status.TiKV.LastAutoScaledTime).To(Equal(lastAutoScaledTime))
+			g.Expect(recorder.Events).To(HaveLen(0))
+		}
+	}
+
+	// scaleInReady makes the tikv cluster of 5 stores idle, which is scaled in to 4 stores
+	scaleInReady := func(tc *v1alpha1.TikvCluster) {
+		*tc = *newTikvClusterForAutoScaler(5, 80, 100)
+		tc.Spec.TiKV.AutoScaling.MinReplicas = pointer.Int32Ptr(3)
+		tc.Spec.TiKV.AutoScaling.RegionCount = &v1alpha1.AutoScalingThreshold{ScaleOut: 1000, ScaleIn: pointer.Int32Ptr(500)}
+	}
+
+	tests := []testcase{
+		{
+			name:             "scale out on high storage utilization",
+			expectReplicas:   4,
+			expectAutoScaled: true,
+		},
+		{
+			name: "disabled",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.AutoScaling.Enabled = false
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "paused",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.Paused = true
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "storage utilization below the threshold",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.AutoScaling.StorageUtilizationThreshold = pointer.Int32Ptr(95)
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "max replicas reached",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.AutoScaling.MaxReplicas = 3
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "tikv is upgrading",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "tikv is scaling",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Status.TiKV.StatefulSet.Replicas = 2
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "store is down",
+			update: func(tc *v1alpha1.TikvCluster) {
+				store := tc.Status.TiKV.Stores["1"]
+				store.State = v1alpha1.TiKVStateDown
+				tc.Status.TiKV.Stores["1"] = store
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "in the cool-down window",
+			update: func(tc *v1alpha1.TikvCluster) {
+				lastAutoScaledTime := metav1.NewTime(time.Now().Add(-time.Minute))
+				tc.Status.TiKV.LastAutoScaledTime = &lastAutoScaledTime
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "cool-down window elapsed",
+			update: func(tc *v1alpha1.TikvCluster) {
+				lastAutoScaledTime := metav1.NewTime(time.Now().Add(-time.Hour))
+				tc.Status.TiKV.LastAutoScaledTime = &lastAutoScaledTime
+			},
+			expectReplicas:   4,
+			expectAutoScaled: true,
+		},
+		{
+			name: "capacity not reported",
+			update: func(tc *v1alpha1.TikvCluster) {
+				for id, store := range tc.Status.TiKV.Stores {
+					store.Capacity = resource.Quantity{}
+					tc.Status.TiKV.Stores[id] = store
+				}
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "the new store has not received its regions",
+			update: func(tc *v1alpha1.TikvCluster) {
+				store := tc.Status.TiKV.Stores["3"]
+				store.RegionCount = 10
+				tc.Status.TiKV.Stores["3"] = store
+			},
+			expectReplicas: 3,
+		},
+		{
+			name: "scale out on high region count",
+			update: func(tc *v1alpha1.TikvCluster) {
+				*tc = *newTikvClusterForAutoScaler(3, 80, 100)
+				tc.Spec.TiKV.AutoScaling.RegionCount = &v1alpha1.AutoScalingThreshold{ScaleOut: 50}
+			},
+			expectReplicas:   4,
+			expectAutoScaled: true,
+		},
+		{
+			name: "scale out on high cpu utilization",
+			update: func(tc *v1alpha1.TikvCluster) {
+				*tc = *newTikvClusterForAutoScaler(3, 80, 100)
+				tc.Spec.TiKV.AutoScaling.CPUUtilization = &v1alpha1.AutoScalingThreshold{ScaleOut: 70}
+			},
+			cpuUsage:         0.9,
+			expectReplicas:   4,
+			expectAutoScaled: true,
+		},
+		{
+			name: "scale out on high cpu utilization of the sizing profile",
+			update: func(tc *v1alpha1.TikvCluster) {
+				*tc = *newTikvClusterForAutoScaler(3, 80, 100)
+				tc.Spec.TiKV.AutoScaling.CPUUtilization = &v1alpha1.AutoScalingThreshold{ScaleOut: 70}
+				delete(tc.Spec.TiKV.Requests, corev1.ResourceCPU)
+				tc.Spec.TiKV.SizingProfile = v1alpha1.SizingProfileSmall
+			},
+			cpuUsage:         3,
+			expectReplicas:   4,
+			expectAutoScaled: true,
+		},
+		{
+			name: "cpu utilization without the metrics url",
+			update: func(tc *v1alpha1.TikvCluster) {
+				*tc = *newTikvClusterForAutoScaler(3, 80, 100)
+				tc.Spec.TiKV.AutoScaling.CPUUtilization = &v1alpha1.AutoScalingThreshold{ScaleOut: 70}
+				tc.Spec.TiKV.AutoScaling.MetricsURL = ""
+			},
+			cpuUsage:       0.9,
+			expectReplicas: 3,
+		},
+		{
+			name:             "scale in on low region count",
+			update:           scaleInReady,
+			expectReplicas:   4,
+			expectAutoScaled: true,
+		},
+		{
+			name: "scale in without min replicas",
+			update: func(tc *v1alpha1.TikvCluster) {
+				scaleInReady(tc)
+				tc.Spec.TiKV.AutoScaling.MinReplicas = nil
+			},
+			expectReplicas: 5,
+		},
+		{
+			name: "min replicas reached",
+			update: func(tc *v1alpha1.TikvCluster) {
+				scaleInReady(tc)
+				tc.Spec.TiKV.AutoScaling.MinReplicas = pointer.Int32Ptr(5)
+			},
+			expectReplicas: 5,
+		},
+		{
+			name: "cpu utilization above the scale-in threshold",
+			update: func(tc *v1alpha1.TikvCluster) {
+				scaleInReady(tc)
+				tc.Spec.TiKV.AutoScaling.CPUUtilization = &v1alpha1.AutoScalingThreshold{ScaleOut: 70, ScaleIn: pointer.Int32Ptr(30)}
+			},
+			cpuUsage:       0.4,
+			expectReplicas: 5,
+		},
+		{
+			name: "cpu utilization and region count below the scale-in thresholds",
+			update: func(tc *v1alpha1.TikvCluster) {
+				scaleInReady(tc)
+				tc.Spec.TiKV.AutoScaling.CPUUtilization = &v1alpha1.AutoScalingThreshold{ScaleOut: 70, ScaleIn: pointer.Int32Ptr(30)}
+			},
+			cpuUsage:         0.2,
+			expectReplicas:   4,
+			expectAutoScaled: true,
+		},
+		{
+			name: "scale in in the cool-down window",
+			update: func(tc *v1alpha1.TikvCluster) {
+				scaleInReady(tc)
+				lastAutoScaledTime := metav1.NewTime(time.Now().Add(-20 * time.Minute))
+				tc.Status.TiKV.LastAutoScaledTime = &lastAutoScaledTime
+			},
+			expectReplicas: 5,
+		},
+		{
+			name: "remaining stores fewer than the minimum up stores",
+			update: func(tc *v1alpha1.TikvCluster) {
+				scaleInReady(tc)
+				tc.Status.TiKV.MaxReplicas = 5
+			},
+			expectReplicas: 5,
+		},
+		{
+			name: "remaining stores would be scaled out again",
+			update: func(tc *v1alpha1.TikvCluster) {
+				scaleInReady(tc)
+				tc.Spec.TiKV.AutoScaling.StorageUtilizationThreshold = pointer.Int32Ptr(22)
+			},
+			expectReplicas: 5,
+		},
+	}
+
+	for i := range tests {
+		testFn(&tests[i], t)
+	}
+}
+
+func TestTiKVStorageUtilization(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTikvClusterForAutoScaler(3, 10, 100)
+	utilization, ok := tc.TiKVStorageUtilization()
+	g.Expect(ok).To(BeTrue())
+	g.Expect(utilization).To(BeNumerically("~", 90))
+
+	// the stores not Up are excluded
+	store := tc.Status.TiKV.Stores["1"]
+	store.State = v1alpha1.TiKVStateOffline
+	store.Available = resource.MustParse("100Gi")
+	tc.Status.TiKV.Stores["1"] = store
+	utilization, ok = tc.TiKVStorageUtilization()
+	g.Expect(ok).To(BeTrue())
+	g.Expect(utilization).To(BeNumerically("~", 90))
+}
+
+func newFakeTiKVAutoScaler() (manager.Manager, *FakeTiKVMetricsQuerier, *record.FakeRecorder) {
+	querier := NewFakeTiKVMetricsQuerier()
+	recorder := record.NewFakeRecorder(10)
+	return NewTiKVAutoScaler(querier, recorder), querier, recorder
+}
+
+// newTikvClusterForAutoScaler returns a stable tikv cluster, each store of which has the given
+// available storage out of 100Gi and the given region count
+func newTikvClusterForAutoScaler(stores int32, availableGi int, regionCount int32) *v1alpha1.TikvCluster {
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.Replicas = stores
+	tc.Spec.TiKV.AutoScaling = &v1alpha1.TiKVAutoScalingSpec{
+		Enabled:     true,
+		MaxReplicas: 5,
+		MetricsURL:  "http://prometheus:9090",
+	}
+	tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	tc.Status.TiKV.StatefulSet = &apps.StatefulSetStatus{Replicas: stores}
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{}
+	for i := int32(1); i <= stores; i++ {
+		id := fmt.Sprintf("%d", i)
+		tc.Status.TiKV.Stores[id] = v1alpha1.TiKVStore{
+			ID:          id,
+			State:       v1alpha1.TiKVStateUp,
+			RegionCount: regionCount,
+			Capacity:    resource.MustParse("100Gi"),
+			Available:   resource.MustParse(fmt.Sprintf("%dGi", availableGi)),
+		}
+	}
+	return tc
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/manager"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type tikvClusterSuspender struct {
+	setControl controller.StatefulSetControlInterface
+	setLister  v1.StatefulSetLister
+	recorder   record.EventRecorder
+}
+
+// NewTikvClusterSuspender returns a manager scaling the statefulsets of PD and TiKV to zero while the TikvCluster
+// is suspended and back to the desired replicas once it is resumed. The statefulsets are scaled directly instead of
+// by the scalers, so the PVCs and the stores in PD are retained.
+func NewTikvClusterSuspender(
+	setControl controller.StatefulSetControlInterface,
+	setLister v1.StatefulSetLister,
+	recorder record.EventRecorder) manager.Manager {
+	return &tikvClusterSuspender{
+		setControl: setControl,
+		setLister:  setLister,
+		recorder:   recorder,
+	}
+}
+
+func (ts *tikvClusterSuspender) Sync(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.Paused {
+		return nil
+	}
+	if tc.Spec.Suspend {
+		return ts.suspend(tc)
+	}
+	return ts.resume(tc)
+}
+
+// suspend stops TiKV before PD, so the stores are stopped while PD is still running
+func (ts *tikvClusterSuspender) suspend(tc *v1alpha1.TikvCluster) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	tikvSet, err := ts.getStatefulSet(tc, controller.TiKVMemberName(tcName))
+	if err != nil {
+		return err
+	}
+	if tikvSet != nil {
+		if err := ts.scaleTo(tc, tikvSet, 0, "Suspended"); err != nil {
+			return err
+		}
+		tc.Status.TiKV.StatefulSet = tikvSet.Status.DeepCopy()
+		tc.Status.TiKV.Phase = v1alpha1.SuspendedPhase
+		if tikvSet.Status.Replicas > 0 {
+			return controller.RequeueErrorf("TikvCluster: [%s/%s] is being suspended, waiting for %d tikv pods to be stopped",
+				ns, tcName, tikvSet.Status.Replicas)
+		}
+	}
+
+	pdSet, err := ts.getStatefulSet(tc, controller.PDMemberName(tcName))
+	if err != nil {
+		return err
+	}
+	if pdSet != nil {
+		if err := ts.scaleTo(tc, pdSet, 0, "Suspended"); err != nil {
+			return err
+		}
+		tc.Status.PD.StatefulSet = pdSet.Status.DeepCopy()
+		tc.Status.PD.Phase = v1alpha1.SuspendedPhase
+	}
+	return nil
+}
+
+// resume starts PD and TiKV together, TiKV keeps retrying to connect to PD until it is running
+func (ts *tikvClusterSuspender) resume(tc *v1alpha1.TikvCluster) error {
+	tcName := tc.GetName()
+
+	if tc.Status.PD.Phase == v1alpha1.SuspendedPhase {
+		pdSet, err := ts.getStatefulSet(tc, controller.PDMemberName(tcName))
+		if err != nil {
+			return err
+		}
+		if pdSet != nil {
+			if err := ts.scaleTo(tc, pdSet, tc.PDStsDesiredReplicas(), "Resumed"); err != nil {
+				return err
+			}
+		}
+		tc.Status.PD.Phase = v1alpha1.NormalPhase
+	}
+
+	if tc.Status.TiKV.Phase == v1alpha1.SuspendedPhase {
+		tikvSet, err := ts.getStatefulSet(tc, controller.TiKVMemberName(tcName))
+		if err != nil {
+			return err
+		}
+		if tikvSet != nil {
+			if err := ts.scaleTo(tc, tikvSet, tc.TiKVStsDesiredReplicas(), "Resumed"); err != nil {
+				return err
+			}
+		}
+		tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	}
+	return nil
+}
+
+func (ts *tikvClusterSuspender) getStatefulSet(tc *v1alpha1.TikvCluster, name string) (*apps.StatefulSet, error) {
+	set, err := ts.setLister.StatefulSets(tc.GetNamespace()).Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return set, err
+}
+
+// scaleTo sets the replicas of the statefulset along with the last applied config, so the replicas are not taken as
+// changed outside of the operator
+func (ts *tikvClusterSuspender) scaleTo(tc *v1alpha1.TikvCluster, set *apps.StatefulSet, replicas int32, reason string) error {
+	if set.Spec.Replicas != nil && *set.Spec.Replicas == replicas {
+		return nil
+	}
+	newSet := set.DeepCopy()
+	newSet.Spec.Replicas = &replicas
+	if err := SetStatefulSetLastAppliedConfigAnnotation(newSet); err != nil {
+		return err
+	}
+	if _, err := ts.setControl.UpdateStatefulSet(tc, newSet); err != nil {
+		return err
+	}
+	ts.recorder.Eventf(tc, corev1.EventTypeNormal, reason, "scaled statefulset %s to %d replicas", set.GetName(), replicas)
+	return nil
+}
+
+var _ manager.Manager = &tikvClusterSuspender{}
+
+type FakeTikvClusterSuspender struct {
+	err error
+}
+
+// NewFakeTikvClusterSuspender returns a fake tikv cluster suspender
+func NewFakeTikvClusterSuspender() *FakeTikvClusterSuspender {
+	return &FakeTikvClusterSuspender{}
+}
+
+func (fts *FakeTikvClusterSuspender) SetSyncError(err error) {
+	fts.err = err
+}
+
+func (fts *FakeTikvClusterSuspender) Sync(_ *v1alpha1.TikvCluster) error {
+	return fts.err
+}
+
+var _ manager.Manager = &FakeTikvClusterSuspender{}
@@ -0,0 +1,146 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned/fake"
+	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestTikvClusterSuspenderSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name                string
+		suspend             bool
+		paused              bool
+		phase               v1alpha1.MemberPhase
+		replicas            int32
+		tikvRunningReplicas int32
+		expectErr           bool
+		expectPDReplicas    int32
+		expectTiKVReplicas  int32
+		expectPDPhase       v1alpha1.MemberPhase
+		expectTiKVPhase     v1alpha1.MemberPhase
+	}{
+		{
+			name:                "stop tikv first",
+			suspend:             true,
+			phase:               v1alpha1.NormalPhase,
+			replicas:            3,
+			tikvRunningReplicas: 3,
+			expectErr:           true,
+			expectPDReplicas:    3,
+			expectTiKVReplicas:  0,
+			expectPDPhase:       v1alpha1.NormalPhase,
+			expectTiKVPhase:     v1alpha1.SuspendedPhase,
+		},
+		{
+			name:               "stop pd once tikv is stopped",
+			suspend:            true,
+			phase:              v1alpha1.NormalPhase,
+			replicas:           3,
+			expectPDReplicas:   0,
+			expectTiKVReplicas: 0,
+			expectPDPhase:      v1alpha1.SuspendedPhase,
+			expectTiKVPhase:    v1alpha1.SuspendedPhase,
+		},
+		{
+			name:               "restore the replicas once resumed",
+			suspend:            false,
+			phase:              v1alpha1.SuspendedPhase,
+			replicas:           0,
+			expectPDReplicas:   3,
+			expectTiKVReplicas: 3,
+			expectPDPhase:      v1alpha1.NormalPhase,
+			expectTiKVPhase:    v1alpha1.NormalPhase,
+		},
+		{
+			name:               "not suspended",
+			suspend:            false,
+			phase:              v1alpha1.NormalPhase,
+			replicas:           2,
+			expectPDReplicas:   2,
+			expectTiKVReplicas: 2,
+			expectPDPhase:      v1alpha1.NormalPhase,
+			expectTiKVPhase:    v1alpha1.NormalPhase,
+		},
+		{
+			name:               "paused",
+			suspend:            true,
+			paused:             true,
+			phase:              v1alpha1.NormalPhase,
+			replicas:           3,
+			expectPDReplicas:   3,
+			expectTiKVReplicas: 3,
+			expectPDPhase:      v1alpha1.NormalPhase,
+			expectTiKVPhase:    v1alpha1.NormalPhase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.PD.Replicas = 3
+			tc.Spec.TiKV.Replicas = 3
+			tc.Spec.Suspend = tt.suspend
+			tc.Spec.Paused = tt.paused
+			tc.Status.PD.Phase = tt.phase
+			tc.Status.TiKV.Phase = tt.phase
+
+			kubeCli := kubefake.NewSimpleClientset()
+			setInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Apps().V1().StatefulSets()
+			tcInformer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Tikv().V1alpha1().TikvClusters()
+			setControl := controller.NewFakeStatefulSetControl(setInformer, tcInformer)
+			suspender := NewTikvClusterSuspender(setControl, setInformer.Lister(), record.NewFakeRecorder(100))
+
+			g.Expect(setInformer.Informer().GetIndexer().Add(&apps.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: controller.PDMemberName(tc.GetName()), Namespace: tc.GetNamespace()},
+				Spec:       apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(tt.replicas)},
+				Status:     apps.StatefulSetStatus{Replicas: tt.replicas},
+			})).To(Succeed())
+			g.Expect(setInformer.Informer().GetIndexer().Add(&apps.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: controller.TiKVMemberName(tc.GetName()), Namespace: tc.GetNamespace()},
+				Spec:       apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(tt.replicas)},
+				Status:     apps.StatefulSetStatus{Replicas: tt.tikvRunningReplicas},
+			})).To(Succeed())
+
+			err := suspender.Sync(tc)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+
+			pdSet, err := setInformer.Lister().StatefulSets(tc.GetNamespace()).Get(controller.PDMemberName(tc.GetName()))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(*pdSet.Spec.Replicas).To(Equal(tt.expectPDReplicas))
+			tikvSet, err := setInformer.Lister().StatefulSets(tc.GetNamespace()).Get(controller.TiKVMemberName(tc.GetName()))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(*tikvSet.Spec.Replicas).To(Equal(tt.expectTiKVReplicas))
+			g.Expect(tc.Status.PD.Phase).To(Equal(tt.expectPDPhase))
+			g.Expect(tc.Status.TiKV.Phase).To(Equal(tt.expectTiKVPhase))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// tikvOnlineConfigPrefixes are the config items tikv supports to change online through the status server, the
+// changes of the other items are applied by restarting the pods
+var tikvOnlineConfigPrefixes = []string{
+	"raftstore.",
+	"coprocessor.",
+	"gc.",
+	"pessimistic-txn.",
+	"split.",
+	"rocksdb.max-background-jobs",
+	"rocksdb.defaultcf.block-cache-size",
+	"rocksdb.writecf.block-cache-size",
+	"rocksdb.lockcf.block-cache-size",
+	"storage.block-cache.capacity",
+}
+
+// reloadTiKVConfig changes the config of the running tikv pods online if all the items changed from the ConfigMap in
+// use are supported to change online, and returns whether the ConfigMap in use can be updated to the new config in
+// place, so the pods are not restarted for the changes. Any failure falls back to rolling update the pods.
+func (tkmm *tikvMemberManager) reloadTiKVConfig(tc *v1alpha1.TikvCluster, inUseName string, newCm *corev1.ConfigMap) bool {
+	ns := tc.GetNamespace()
+	logger := tikvLogger(tc)
+
+	inUse := &corev1.ConfigMap{}
+	exist, err := tkmm.typedControl.Exist(client.ObjectKey{Namespace: ns, Name: inUseName}, inUse)
+	if err != nil || !exist {
+		return false
+	}
+	// the files other than the config are only read by tikv on start
+	if !reflect.DeepEqual(withoutConfigFile(inUse.Data), withoutConfigFile(newCm.Data)) {
+		return false
+	}
+	changed, removed, err := diffTOMLConfig(inUse.Data["config-file"], newCm.Data["config-file"])
+	if err != nil {
+		logger.Warningf("failed to diff the config of tikv ConfigMap %s, error: %v", inUseName, err)
+		return false
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return true
+	}
+	// the removed items are reset to their defaults, which are only known by tikv
+	if len(removed) > 0 {
+		return false
+	}
+	keys := make([]string, 0, len(changed))
+	for key, value := range changed {
+		// the variables are resolved by the start script of each pod
+		if !isTiKVOnlineConfig(key) || hasConfigVariable(value) {
+			return false
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return false
+	}
+	pods, err := tkmm.podLister.Pods(ns).List(selector)
+	if err != nil {
+		return false
+	}
+	for _, pod := range pods {
+		// the pods not running pick up the new config once started
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		tikvCli := tkmm.tikvControl.GetTiKVPodClient(ns, tc.GetName(), tc.TiKVPeerServiceName(), pod.GetName(), tc.IsTLSClusterEnabled(), tc.ClusterClientTLSSecretName())
+		if err := tikvCli.UpdateConfig(changed); err != nil {
+			logger.Warningf("failed to change config %v of tikv pod %s online, roll out by restarting the pods, error: %v", keys, pod.GetName(), err)
+			tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "ConfigReloadFailed",
+				"failed to change config %v of tikv pod %s online, roll out by restarting the pods, error: %v", keys, pod.GetName(), err)
+			return false
+		}
+	}
+	logger.Infof("changed config %v of tikv online", keys)
+	tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "ConfigReloaded", "changed config %v of tikv online", keys)
+	return true
+}
+
+func withoutConfigFile(data map[string]string) map[string]string {
+	files := map[string]string{}
+	for key, value := range data {
+		if key != "config-file" {
+			files[key] = value
+		}
+	}
+	return files
+}
+
+func hasConfigVariable(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.Contains(s, "$(")
+}
+
+func isTiKVOnlineConfig(key string) bool {
+	for _, prefix := range tikvOnlineConfigPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffTOMLConfig returns the items changed or added in the new config keyed by their dotted paths, and the paths of
+// the items removed from the old config
+func diffTOMLConfig(oldConfig, newConfig string) (map[string]interface{}, []string, error) {
+	oldItems, err := parseTOMLConfigItems(oldConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	newItems, err := parseTOMLConfigItems(newConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed := map[string]interface{}{}
+	for key, value := range newItems {
+		if old, ok := oldItems[key]; !ok || !reflect.DeepEqual(old, value) {
+			changed[key] = value
+		}
+	}
+	var removed []string
+	for key := range oldItems {
+		if _, ok := newItems[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	return changed, removed, nil
+}
+
+// parseTOMLConfigItems returns the items of the config keyed by their dotted paths
+func parseTOMLConfigItems(config string) (map[string]interface{}, error) {
+	parsed := map[string]interface{}{}
+	if err := UnmarshalTOML([]byte(config), &parsed); err != nil {
+		return nil, err
+	}
+	items := map[string]interface{}{}
+	flattenConfig("", parsed, items)
+	return items, nil
+}
+
+func flattenConfig(prefix string, config map[string]interface{}, items map[string]interface{}) {
+	for key, value := range config {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenConfig(prefix+key+".", nested, items)
+			continue
+		}
+		items[prefix+key] = value
+	}
+}
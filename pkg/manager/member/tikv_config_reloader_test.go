@@ -0,0 +1,168 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/tikvapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTiKVMemberManagerReloadTiKVConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+	oldConfig := `[raftstore]
+raft-log-gc-threshold = 50
+
+[server]
+grpc-concurrency = 4
+`
+	tests := []struct {
+		name         string
+		inUseExists  bool
+		newConfig    string
+		newFiles     map[string]string
+		updateErr    bool
+		expectReload bool
+		expectConfig map[string]interface{}
+	}{
+		{
+			name:         "config not changed",
+			inUseExists:  true,
+			newConfig:    oldConfig,
+			expectReload: true,
+		},
+		{
+			name:        "config changed online",
+			inUseExists: true,
+			newConfig: `[raftstore]
+raft-log-gc-threshold = 100
+
+[server]
+grpc-concurrency = 4
+`,
+			expectReload: true,
+			expectConfig: map[string]interface{}{"raftstore.raft-log-gc-threshold": int64(100)},
+		},
+		{
+			name:        "config changed requires restart",
+			inUseExists: true,
+			newConfig: `[raftstore]
+raft-log-gc-threshold = 100
+
+[server]
+grpc-concurrency = 8
+`,
+			expectReload: false,
+		},
+		{
+			name:        "config removed",
+			inUseExists: true,
+			newConfig: `[server]
+grpc-concurrency = 4
+`,
+			expectReload: false,
+		},
+		{
+			name:        "config refers to variables",
+			inUseExists: true,
+			newConfig: `[raftstore]
+raft-log-gc-threshold = 50
+
+[server]
+grpc-concurrency = 4
+
+[coprocessor]
+region-split-size = "$(POD_NAME)"
+`,
+			expectReload: false,
+		},
+		{
+			name:        "failed to change config online",
+			inUseExists: true,
+			newConfig: `[raftstore]
+raft-log-gc-threshold = 100
+
+[server]
+grpc-concurrency = 4
+`,
+			updateErr:    true,
+			expectReload: false,
+		},
+		{
+			name:        "config file changed",
+			inUseExists: true,
+			newConfig: `[raftstore]
+raft-log-gc-threshold = 100
+
+[server]
+grpc-concurrency = 4
+`,
+			newFiles:     map[string]string{"pd.toml": "[replication]\n"},
+			expectReload: false,
+		},
+		{
+			name:         "configmap in use not found",
+			inUseExists:  false,
+			newConfig:    oldConfig,
+			expectReload: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tkmm, _, _, _, podIndexer, _ := newFakeTiKVMemberManager(tc)
+			inUseName := fmt.Sprintf("%s-tikv-0123456", tc.GetName())
+			if tt.inUseExists {
+				_, err := tkmm.typedControl.CreateOrUpdateConfigMap(tc, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: inUseName, Namespace: tc.GetNamespace()},
+					Data:       map[string]string{"config-file": oldConfig, "startup-script": "start"},
+				})
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			tikvClient := tikvapi.NewFakeTiKVClient()
+			if tt.updateErr {
+				tikvClient.SetUpdateConfigError(fmt.Errorf("config not supported to change online"))
+			}
+			podName := TikvPodName(tc.GetName(), 0)
+			tkmm.tikvControl.(*tikvapi.FakeTiKVControl).SetTiKVPodClient(tc.GetNamespace(), tc.GetName(), podName, tikvClient)
+			g.Expect(podIndexer.Add(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      podName,
+					Namespace: tc.GetNamespace(),
+					Labels:    label.New().Instance(tc.GetInstanceName()).TiKV().Labels(),
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			})).To(Succeed())
+
+			newCm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-tikv-abcdef0", tc.GetName()), Namespace: tc.GetNamespace()},
+				Data:       map[string]string{"config-file": tt.newConfig, "startup-script": "start"},
+			}
+			for name, content := range tt.newFiles {
+				newCm.Data[name] = content
+			}
+			g.Expect(tkmm.reloadTiKVConfig(tc, inUseName, newCm)).To(Equal(tt.expectReload))
+			if tt.expectConfig == nil {
+				g.Expect(tikvClient.Configs).To(BeEmpty())
+			} else {
+				g.Expect(tikvClient.Configs).To(ConsistOf(tt.expectConfig))
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// syncCordonedStores evicts the leaders of the cordoned stores and sets their add-peer limit to 0 so that PD
+// places no leaders and regions on them, both are compared with PD on every sync as the evictions may be ended
+// by the upgrader or the node drain. The stores removed from the spec are uncordoned.
+func (tkmm *tikvMemberManager) syncCordonedStores(tc *v1alpha1.TikvCluster) error {
+	cordoned := sets.NewString(tc.Spec.TiKV.CordonedStores...)
+	if tc.Spec.Paused || (cordoned.Len() == 0 && len(tc.Status.TiKV.CordonedStores) == 0) {
+		return nil
+	}
+	if tc.Status.TiKV.CordonedStores == nil {
+		tc.Status.TiKV.CordonedStores = map[string]string{}
+	}
+	status := tc.Status.TiKV.CordonedStores
+	logger := tikvLogger(tc)
+
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	schedulers, err := pdCli.GetEvictLeaderSchedulers()
+	if err != nil {
+		return err
+	}
+	evicting := map[string]bool{}
+	for _, scheduler := range schedulers {
+		evicting[strings.TrimPrefix(scheduler, evictLeaderSchedulerPrefix)] = true
+	}
+	limits, err := pdCli.GetStoreLimits()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range cordoned.List() {
+		store, ok := tc.Status.TiKV.Stores[id]
+		if !ok {
+			logger.V(4).Infof("cordoned store %s is not found in the cluster", id)
+			delete(status, id)
+			continue
+		}
+		storeID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		if _, ok := status[id]; !ok {
+			status[id] = strconv.FormatFloat(limits[storeID].AddPeer, 'f', -1, 64)
+			logger.Infof("cordon store %s of pod %s", id, store.PodName)
+			tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "StoreCordoned",
+				"cordoned store %s of pod %s, evicting its leaders and placing no regions on it", id, store.PodName)
+		}
+		if limits[storeID].AddPeer != 0 {
+			if err := pdCli.SetStoreLimit(storeID, pdapi.StoreLimitTypeAddPeer, 0); err != nil {
+				return err
+			}
+		}
+		if !evicting[id] {
+			if err := pdCli.BeginEvictLeader(storeID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for id, original := range status {
+		if cordoned.Has(id) {
+			continue
+		}
+		store, ok := tc.Status.TiKV.Stores[id]
+		if !ok {
+			delete(status, id)
+			continue
+		}
+		storeID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		// the add-peer limit of the spec is applied by syncStoreLimit
+		if tc.Spec.TiKV.StoreLimit == nil || tc.Spec.TiKV.StoreLimit.AddPeer == nil {
+			limit, err := strconv.ParseFloat(original, 64)
+			if err != nil {
+				return err
+			}
+			if err := pdCli.SetStoreLimit(storeID, pdapi.StoreLimitTypeAddPeer, limit); err != nil {
+				return err
+			}
+		}
+		// the leaders are kept evicted while the node of the store is being drained
+		if _, ok := tc.Status.TiKV.NodeDrainEvictions[id]; evicting[id] && !ok {
+			if err := pdCli.EndEvictLeader(storeID); err != nil {
+				return err
+			}
+		}
+		delete(status, id)
+		logger.Infof("uncordon store %s of pod %s", id, store.PodName)
+		tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "StoreUncordoned", "uncordoned store %s of pod %s", id, store.PodName)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	"k8s.io/utils/pointer"
+)
+
+func TestTiKVMemberManagerSyncCordonedStores(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name              string
+		paused            bool
+		cordoned          []string
+		status            map[string]string
+		evicting          []string
+		nodeDrainEvicting bool
+		specAddPeer       *int32
+		expectLimits      map[uint64]float64
+		expectBegin       []uint64
+		expectEnd         []uint64
+		expectStatus      map[string]string
+	}{
+		{
+			name:         "cordon a store",
+			cordoned:     []string{"1"},
+			expectLimits: map[uint64]float64{1: 0},
+			expectBegin:  []uint64{1},
+			expectStatus: map[string]string{"1": "15"},
+		},
+		{
+			name:     "a paused cluster is not cordoned",
+			paused:   true,
+			cordoned: []string{"1"},
+		},
+		{
+			name:         "the eviction ended by others is restored",
+			cordoned:     []string{"1"},
+			status:       map[string]string{"1": "15"},
+			expectBegin:  []uint64{1},
+			expectStatus: map[string]string{"1": "15"},
+		},
+		{
+			name:         "a cordoned store is kept as is",
+			cordoned:     []string{"1"},
+			status:       map[string]string{"1": "15"},
+			evicting:     []string{"1"},
+			expectStatus: map[string]string{"1": "15"},
+		},
+		{
+			name:         "a store not in the cluster is not cordoned",
+			cordoned:     []string{"9"},
+			expectStatus: map[string]string{},
+		},
+		{
+			name:         "uncordon a store",
+			status:       map[string]string{"1": "15"},
+			evicting:     []string{"1"},
+			expectLimits: map[uint64]float64{1: 15},
+			expectEnd:    []uint64{1},
+			expectStatus: map[string]string{},
+		},
+		{
+			name:         "the add-peer limit of the spec is left to the store limit sync",
+			status:       map[string]string{"1": "15"},
+			evicting:     []string{"1"},
+			specAddPeer:  pointer.Int32Ptr(5),
+			expectEnd:    []uint64{1},
+			expectStatus: map[string]string{},
+		},
+		{
+			name:              "the leaders are kept evicted while the node is being drained",
+			status:            map[string]string{"1": "15"},
+			evicting:          []string{"1"},
+			nodeDrainEvicting: true,
+			expectLimits:      map[uint64]float64{1: 15},
+			expectStatus:      map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.Paused = tt.paused
+			tc.Spec.TiKV.CordonedStores = tt.cordoned
+			if tt.specAddPeer != nil {
+				tc.Spec.TiKV.StoreLimit = &v1alpha1.TiKVStoreLimit{AddPeer: tt.specAddPeer}
+			}
+			tc.Status.TiKV.CordonedStores = tt.status
+			tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-0", State: v1alpha1.TiKVStateUp},
+				"2": {ID: "2", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp},
+			}
+			if tt.nodeDrainEvicting {
+				tc.Status.TiKV.NodeDrainEvictions = map[string]string{"1": "node-1"}
+			}
+
+			tkmm, _, _, pdClient, _, _ := newFakeTiKVMemberManager(tc)
+			pdClient.AddReaction(pdapi.GetEvictLeaderSchedulersActionType, func(action *pdapi.Action) (interface{}, error) {
+				var schedulers []string
+				for _, id := range tt.evicting {
+					schedulers = append(schedulers, evictLeaderSchedulerPrefix+id)
+				}
+				return schedulers, nil
+			})
+			pdClient.AddReaction(pdapi.GetStoreLimitsActionType, func(action *pdapi.Action) (interface{}, error) {
+				limits := map[uint64]pdapi.StoreLimit{2: {AddPeer: 15, RemovePeer: 15}}
+				if tt.status == nil {
+					limits[1] = pdapi.StoreLimit{AddPeer: 15, RemovePeer: 15}
+				} else {
+					limits[1] = pdapi.StoreLimit{RemovePeer: 15}
+				}
+				return limits, nil
+			})
+			var limits map[uint64]float64
+			pdClient.AddReaction(pdapi.SetStoreLimitActionType, func(action *pdapi.Action) (interface{}, error) {
+				g.Expect(action.Name).To(Equal(pdapi.StoreLimitTypeAddPeer))
+				if limits == nil {
+					limits = map[uint64]float64{}
+				}
+				limits[action.ID] = action.Rate
+				return nil, nil
+			})
+			var begin, end []uint64
+			pdClient.AddReaction(pdapi.BeginEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+				begin = append(begin, action.ID)
+				return nil, nil
+			})
+			pdClient.AddReaction(pdapi.EndEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+				end = append(end, action.ID)
+				return nil, nil
+			})
+
+			g.Expect(tkmm.syncCordonedStores(tc)).To(Succeed())
+			g.Expect(limits).To(Equal(tt.expectLimits))
+			g.Expect(begin).To(Equal(tt.expectBegin))
+			g.Expect(end).To(Equal(tt.expectEnd))
+			g.Expect(tc.Status.TiKV.CordonedStores).To(Equal(tt.expectStatus))
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// evictLeaderSchedulerPrefix is the prefix of the names of the evict leader schedulers of PD,
+// which are suffixed by the id of the store
+const evictLeaderSchedulerPrefix = "evict-leader-scheduler-"
+
+// cleanStaleEvictLeaderSchedulers removes the evict leader schedulers left behind by the upgrader, e.g. when
+// the operator crashed or the upgrade was rolled back after the leaders were evicted, otherwise the stores
+// never take leaders again. The schedulers created by the upgrader are told by the EvictLeaderBeginTime
+// annotation of the pods, the others are created by the node drain or by hand and are left as is.
+func (tkmm *tikvMemberManager) cleanStaleEvictLeaderSchedulers(tc *v1alpha1.TikvCluster) error {
+	// the evictions are ended by the upgrader itself while upgrading
+	if tc.Spec.Paused || tc.Status.TiKV.Phase == v1alpha1.UpgradePhase {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	logger := tikvLogger(tc)
+
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	schedulers, err := pdCli.GetEvictLeaderSchedulers()
+	if err != nil {
+		return err
+	}
+	evicting := map[string]bool{}
+	for _, scheduler := range schedulers {
+		evicting[strings.TrimPrefix(scheduler, evictLeaderSchedulerPrefix)] = true
+	}
+
+	for id, store := range tc.Status.TiKV.Stores {
+		if _, ok := tc.Status.TiKV.NodeDrainEvictions[id]; ok {
+			continue
+		}
+		pod, err := tkmm.podLister.Pods(ns).Get(store.PodName)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := pod.Annotations[EvictLeaderBeginTime]; !ok {
+			continue
+		}
+		// the leaders of the cordoned stores are kept evicted
+		if _, cordoned := tc.Status.TiKV.CordonedStores[id]; evicting[id] && !cordoned {
+			storeID, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				return err
+			}
+			if err := pdCli.EndEvictLeader(storeID); err != nil {
+				return err
+			}
+			logger.Infof("store %s of pod %s is not being upgraded, removed the stale evict leader scheduler", id, store.PodName)
+			tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "StaleEvictLeaderSchedulerRemoved",
+				"store %s of pod %s is not being upgraded, removed the stale evict leader scheduler", id, store.PodName)
+		}
+		// the pod is not evicting anymore, or the stale begin time would make the next upgrade skip the eviction
+		pod = pod.DeepCopy()
+		delete(pod.Annotations, EvictLeaderBeginTime)
+		if _, err := tkmm.podControl.UpdatePod(tc, pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
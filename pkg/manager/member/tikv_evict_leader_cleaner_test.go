@@ -0,0 +1,123 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTiKVMemberManagerCleanStaleEvictLeaderSchedulers(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name        string
+		paused      bool
+		phase       v1alpha1.MemberPhase
+		annotated   []bool
+		schedulers  []string
+		evictions   map[string]string
+		expectEnd   []uint64
+		expectAnnos []bool
+	}{
+		{
+			name:        "no evict leader schedulers",
+			phase:       v1alpha1.NormalPhase,
+			annotated:   []bool{false, false, false},
+			expectAnnos: []bool{false, false, false},
+		},
+		{
+			name:        "remove the stale scheduler of the annotated pod",
+			phase:       v1alpha1.NormalPhase,
+			annotated:   []bool{true, false, false},
+			schedulers:  []string{"evict-leader-scheduler-1"},
+			expectEnd:   []uint64{1},
+			expectAnnos: []bool{false, false, false},
+		},
+		{
+			name:        "keep the schedulers while paused",
+			paused:      true,
+			phase:       v1alpha1.NormalPhase,
+			annotated:   []bool{true, false, false},
+			schedulers:  []string{"evict-leader-scheduler-1"},
+			expectAnnos: []bool{true, false, false},
+		},
+		{
+			name:        "keep the schedulers while upgrading",
+			phase:       v1alpha1.UpgradePhase,
+			annotated:   []bool{true, true, false},
+			schedulers:  []string{"evict-leader-scheduler-1", "evict-leader-scheduler-2"},
+			expectAnnos: []bool{true, true, false},
+		},
+		{
+			name:        "keep the schedulers not created by the upgrader",
+			phase:       v1alpha1.NormalPhase,
+			annotated:   []bool{true, false, false},
+			schedulers:  []string{"evict-leader-scheduler-1", "evict-leader-scheduler-2", "evict-leader-scheduler-3"},
+			evictions:   map[string]string{"1": "node-0", "2": "node-1"},
+			expectAnnos: []bool{true, false, false},
+		},
+		{
+			name:        "remove the annotation without scheduler",
+			phase:       v1alpha1.NormalPhase,
+			annotated:   []bool{false, true, false},
+			expectAnnos: []bool{false, false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.Paused = tt.paused
+			tc.Status.TiKV.Phase = tt.phase
+			tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{}
+			tc.Status.TiKV.NodeDrainEvictions = tt.evictions
+			tkmm, _, _, pdClient, podIndexer, _ := newFakeTiKVMemberManager(tc)
+			for i, annotated := range tt.annotated {
+				podName := TikvPodName(tc.GetName(), int32(i))
+				storeID := strconv.Itoa(i + 1)
+				tc.Status.TiKV.Stores[storeID] = v1alpha1.TiKVStore{ID: storeID, PodName: podName, State: v1alpha1.TiKVStateUp}
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: tc.GetNamespace()},
+				}
+				if annotated {
+					pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Format(time.RFC3339)}
+				}
+				g.Expect(podIndexer.Add(pod)).To(Succeed())
+			}
+			pdClient.AddReaction(pdapi.GetEvictLeaderSchedulersActionType, func(action *pdapi.Action) (interface{}, error) {
+				return tt.schedulers, nil
+			})
+			end := []uint64{}
+			pdClient.AddReaction(pdapi.EndEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+				end = append(end, action.ID)
+				return nil, nil
+			})
+
+			g.Expect(tkmm.cleanStaleEvictLeaderSchedulers(tc)).To(Succeed())
+			g.Expect(end).To(ConsistOf(tt.expectEnd))
+			for i, expectAnno := range tt.expectAnnos {
+				pod, err := tkmm.podLister.Pods(tc.GetNamespace()).Get(TikvPodName(tc.GetName(), int32(i)))
+				g.Expect(err).NotTo(HaveOccurred())
+				_, ok := pod.Annotations[EvictLeaderBeginTime]
+				g.Expect(ok).To(Equal(expectAnno))
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ package member
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
@@ -22,9 +23,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
-	"k8s.io/klog"
 )
 
+// maxFailoverHistory is the number of the failover records kept in the status
+const maxFailoverHistory = 10
+
 type tikvFailover struct {
 	tikvFailoverPeriod time.Duration
 	recorder           record.EventRecorder
@@ -39,14 +42,13 @@ func (tf *tikvFailover) isPodDesired(tc *v1alpha1.TikvCluster, podName string) b
 	ordinals := tc.TiKVStsDesiredOrdinals(true)
 	ordinal, err := util.GetOrdinalFromPodName(podName)
 	if err != nil {
-		klog.Errorf("unexpected pod name %q: %v", podName, err)
+		tikvLogger(tc).Errorf("unexpected pod name %q: %v", podName, err)
 		return false
 	}
 	return ordinals.Has(ordinal)
 }
 
 func (tf *tikvFailover) Failover(tc *v1alpha1.TikvCluster) error {
-	ns := tc.GetNamespace()
 	tcName := tc.GetName()
 
 	for storeID, store := range tc.Status.TiKV.Stores {
@@ -68,22 +70,37 @@ func (tf *tikvFailover) Failover(tc *v1alpha1.TikvCluster) error {
 				break
 			}
 		}
-		if store.State == v1alpha1.TiKVStateDown && time.Now().After(deadline) && !exist {
+		if tc.Spec.TiKV.FailoverStaleStores && store.Stale {
+			deadline = store.LastHeartbeatTime.Add(tf.tikvFailoverPeriod)
+		}
+		down := store.State == v1alpha1.TiKVStateDown || (tc.Spec.TiKV.FailoverStaleStores && store.Stale)
+		if down && time.Now().After(deadline) && !exist {
 			if tc.Status.TiKV.FailureStores == nil {
 				tc.Status.TiKV.FailureStores = map[string]v1alpha1.TiKVFailureStore{}
 			}
 			if tc.Spec.TiKV.MaxFailoverCount != nil && *tc.Spec.TiKV.MaxFailoverCount > 0 {
 				maxFailoverCount := *tc.Spec.TiKV.MaxFailoverCount
 				if len(tc.Status.TiKV.FailureStores) >= int(maxFailoverCount) {
-					klog.Warningf("%s/%s failure stores count reached the limit: %d", ns, tcName, tc.Spec.TiKV.MaxFailoverCount)
+					tikvLogger(tc).Warningf("failure stores count reached the limit: %d, skip failover of store %s of pod %s",
+						maxFailoverCount, store.ID, podName)
 					return nil
 				}
-				tc.Status.TiKV.FailureStores[storeID] = v1alpha1.TiKVFailureStore{
+				failureStore := v1alpha1.TiKVFailureStore{
 					PodName:   podName,
 					StoreID:   store.ID,
 					CreatedAt: metav1.Now(),
 				}
+				tc.Status.TiKV.FailureStores[storeID] = failureStore
+				// the newest failure store is replaced by the pod of the highest ordinal
+				if replacements := tc.TiKVStsDesiredOrdinals(false).Difference(tc.TiKVStsDesiredOrdinals(true)).List(); len(replacements) > 0 {
+					failureStore.ReplacementPodName = TikvPodName(tcName, replacements[len(replacements)-1])
+					tc.Status.TiKV.FailureStores[storeID] = failureStore
+				}
+				recordFailover(tc, failureStore)
 				msg := fmt.Sprintf("store[%s] is Down", store.ID)
+				if store.State != v1alpha1.TiKVStateDown {
+					msg = fmt.Sprintf("store[%s] is Up but its heartbeat is stale", store.ID)
+				}
 				tf.recorder.Event(tc, corev1.EventTypeWarning, unHealthEventReason, fmt.Sprintf(unHealthEventMsgPattern, "tikv", podName, msg))
 			}
 		}
@@ -101,6 +118,94 @@ func (tf *tikvFailover) Recover(tc *v1alpha1.TikvCluster) {
 			delete(tc.Status.TiKV.FailureStores, key)
 		}
 	}
+	if len(tc.Status.TiKV.FailureStores) == 0 {
+		return
+	}
+
+	// A failure store is recovered once it is Up again, but its record is only removed after its replacement store
+	// is confirmed Up in PD as well, since removing the record scales in a replacement pod and its store.
+	// The replacement pod is the one recorded by the failover, the failure stores recorded without it are paired with
+	// the replacement pods in the order they were created.
+	replacements := tc.TiKVStsDesiredOrdinals(false).Difference(tc.TiKVStsDesiredOrdinals(true)).List()
+	for i, key := range sortedFailureStoreKeys(tc.Status.TiKV.FailureStores) {
+		failureStore := tc.Status.TiKV.FailureStores[key]
+		replacementPodName := failureStore.ReplacementPodName
+		if replacementPodName == "" {
+			if i >= len(replacements) {
+				continue
+			}
+			replacementPodName = TikvPodName(tc.GetName(), replacements[i])
+		}
+		if store, ok := tc.Status.TiKV.Stores[failureStore.StoreID]; !ok || store.State != v1alpha1.TiKVStateUp {
+			continue
+		} else if tc.Spec.TiKV.FailoverStaleStores && store.Stale {
+			// the stale store failed over while it is Up, it is not recovered until its heartbeat is fresh again
+			continue
+		}
+		if !tf.isStoreUp(tc, replacementPodName) {
+			tikvLogger(tc).Infof("failure store %s is Up again, waiting for the store of its replacement pod %s to be Up",
+				failureStore.StoreID, replacementPodName)
+			continue
+		}
+		delete(tc.Status.TiKV.FailureStores, key)
+		recordRecovery(tc, failureStore)
+		tf.recorder.Event(tc, corev1.EventTypeNormal, "FailureStoreRecovered",
+			fmt.Sprintf("store[%s] of pod %s is Up again, the store of replacement pod %s is Up", failureStore.StoreID, failureStore.PodName, replacementPodName))
+	}
+	if len(tc.Status.TiKV.FailureStores) == 0 {
+		tf.recorder.Event(tc, corev1.EventTypeNormal, "FailoverRecovered", "all the tikv failure stores are recovered")
+	}
+}
+
+// recordFailover appends the failover of the failure store to the history, only the latest records are kept
+func recordFailover(tc *v1alpha1.TikvCluster, failureStore v1alpha1.TiKVFailureStore) {
+	history := append(tc.Status.TiKV.FailoverHistory, v1alpha1.TiKVFailoverRecord{
+		StoreID:            failureStore.StoreID,
+		PodName:            failureStore.PodName,
+		ReplacementPodName: failureStore.ReplacementPodName,
+		CreatedAt:          failureStore.CreatedAt,
+	})
+	if len(history) > maxFailoverHistory {
+		history = history[len(history)-maxFailoverHistory:]
+	}
+	tc.Status.TiKV.FailoverHistory = history
+}
+
+// recordRecovery sets the recovery time of the failover record of the failure store
+func recordRecovery(tc *v1alpha1.TikvCluster, failureStore v1alpha1.TiKVFailureStore) {
+	history := tc.Status.TiKV.FailoverHistory
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].StoreID == failureStore.StoreID && history[i].RecoveredAt == nil {
+			now := metav1.Now()
+			history[i].RecoveredAt = &now
+			return
+		}
+	}
+}
+
+func (tf *tikvFailover) isStoreUp(tc *v1alpha1.TikvCluster, podName string) bool {
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.PodName == podName {
+			return store.State == v1alpha1.TiKVStateUp
+		}
+	}
+	return false
+}
+
+// sortedFailureStoreKeys returns the keys of the failure stores in the order they were created
+func sortedFailureStoreKeys(failureStores map[string]v1alpha1.TiKVFailureStore) []string {
+	keys := make([]string, 0, len(failureStores))
+	for key := range failureStores {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := failureStores[keys[i]].CreatedAt, failureStores[keys[j]].CreatedAt
+		if !ci.Equal(&cj) {
+			return ci.Before(&cj)
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
 }
 
 type fakeTiKVFailover struct{}
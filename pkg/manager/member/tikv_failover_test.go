@@ -66,6 +66,65 @@ func TestTiKVFailoverFailover(t *testing.T) {
 				g.Expect(len(tc.Status.TiKV.FailureStores)).To(Equal(0))
 			},
 		},
+		{
+			name: "tikv store is stale",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.FailoverStaleStores = true
+				tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+					"1": {
+						State:              v1alpha1.TiKVStateUp,
+						PodName:            "tikv-1",
+						Stale:              true,
+						LastHeartbeatTime:  metav1.Time{Time: time.Now().Add(-70 * time.Minute)},
+						LastTransitionTime: metav1.Time{Time: time.Now().Add(-70 * time.Minute)},
+					},
+				}
+			},
+			err: false,
+			expectFn: func(t *testing.T, tc *v1alpha1.TikvCluster) {
+				g := NewGomegaWithT(t)
+				g.Expect(len(tc.Status.TiKV.FailureStores)).To(Equal(1))
+			},
+		},
+		{
+			name: "tikv store is stale but failover of stale stores is disabled",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+					"1": {
+						State:              v1alpha1.TiKVStateUp,
+						PodName:            "tikv-1",
+						Stale:              true,
+						LastHeartbeatTime:  metav1.Time{Time: time.Now().Add(-70 * time.Minute)},
+						LastTransitionTime: metav1.Time{Time: time.Now().Add(-70 * time.Minute)},
+					},
+				}
+			},
+			err: false,
+			expectFn: func(t *testing.T, tc *v1alpha1.TikvCluster) {
+				g := NewGomegaWithT(t)
+				g.Expect(len(tc.Status.TiKV.FailureStores)).To(Equal(0))
+			},
+		},
+		{
+			name: "tikv store is stale but heartbeat deadline not exceed",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.FailoverStaleStores = true
+				tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+					"1": {
+						State:              v1alpha1.TiKVStateUp,
+						PodName:            "tikv-1",
+						Stale:              true,
+						LastHeartbeatTime:  metav1.Time{Time: time.Now().Add(-30 * time.Minute)},
+						LastTransitionTime: metav1.Time{Time: time.Now().Add(-70 * time.Minute)},
+					},
+				}
+			},
+			err: false,
+			expectFn: func(t *testing.T, tc *v1alpha1.TikvCluster) {
+				g := NewGomegaWithT(t)
+				g.Expect(len(tc.Status.TiKV.FailureStores)).To(Equal(0))
+			},
+		},
 		{
 			name: "deadline not exceed",
 			update: func(tc *v1alpha1.TikvCluster) {
@@ -300,6 +359,175 @@ func TestTiKVFailoverFailover(t *testing.T) {
 	}
 }
 
+func TestTiKVFailoverRecover(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		staleStores  bool
+		stores       map[string]v1alpha1.TiKVStore
+		expectStores []string
+		expectEvents []string
+	}{
+		{
+			name: "failure stores are still down",
+			stores: map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-1", State: v1alpha1.TiKVStateDown},
+				"2": {ID: "2", PodName: "test-tikv-2", State: v1alpha1.TiKVStateDown},
+				"4": {ID: "4", PodName: "test-tikv-3", State: v1alpha1.TiKVStateUp},
+				"5": {ID: "5", PodName: "test-tikv-4", State: v1alpha1.TiKVStateUp},
+			},
+			expectStores: []string{"1", "2"},
+			expectEvents: []string{},
+		},
+		{
+			name: "replacement stores are not up",
+			stores: map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp},
+				"2": {ID: "2", PodName: "test-tikv-2", State: v1alpha1.TiKVStateUp},
+				"4": {ID: "4", PodName: "test-tikv-3", State: v1alpha1.TiKVStateDown},
+			},
+			expectStores: []string{"1", "2"},
+			expectEvents: []string{},
+		},
+		{
+			name: "partial recovery",
+			stores: map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp},
+				"2": {ID: "2", PodName: "test-tikv-2", State: v1alpha1.TiKVStateDown},
+				"4": {ID: "4", PodName: "test-tikv-3", State: v1alpha1.TiKVStateUp},
+				"5": {ID: "5", PodName: "test-tikv-4", State: v1alpha1.TiKVStateUp},
+			},
+			expectStores: []string{"2"},
+			expectEvents: []string{"Normal FailureStoreRecovered store[1] of pod test-tikv-1 is Up again, the store of replacement pod test-tikv-3 is Up"},
+		},
+		{
+			name: "partial recovery waiting for the replacement",
+			stores: map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-1", State: v1alpha1.TiKVStateDown},
+				"2": {ID: "2", PodName: "test-tikv-2", State: v1alpha1.TiKVStateUp},
+				"4": {ID: "4", PodName: "test-tikv-3", State: v1alpha1.TiKVStateUp},
+			},
+			expectStores: []string{"1", "2"},
+			expectEvents: []string{},
+		},
+		{
+			name:        "stale failure store is up",
+			staleStores: true,
+			stores: map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp, Stale: true},
+				"2": {ID: "2", PodName: "test-tikv-2", State: v1alpha1.TiKVStateUp},
+				"4": {ID: "4", PodName: "test-tikv-3", State: v1alpha1.TiKVStateUp},
+				"5": {ID: "5", PodName: "test-tikv-4", State: v1alpha1.TiKVStateUp},
+			},
+			expectStores: []string{"1"},
+			expectEvents: []string{"Normal FailureStoreRecovered store[2] of pod test-tikv-2 is Up again, the store of replacement pod test-tikv-4 is Up"},
+		},
+		{
+			name: "full recovery",
+			stores: map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp},
+				"2": {ID: "2", PodName: "test-tikv-2", State: v1alpha1.TiKVStateUp},
+				"4": {ID: "4", PodName: "test-tikv-3", State: v1alpha1.TiKVStateUp},
+				"5": {ID: "5", PodName: "test-tikv-4", State: v1alpha1.TiKVStateUp},
+			},
+			expectStores: []string{},
+			expectEvents: []string{
+				"Normal FailureStoreRecovered store[1] of pod test-tikv-1 is Up again, the store of replacement pod test-tikv-3 is Up",
+				"Normal FailureStoreRecovered store[2] of pod test-tikv-2 is Up again, the store of replacement pod test-tikv-4 is Up",
+				"Normal FailoverRecovered all the tikv failure stores are recovered",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			tc := newTikvClusterForPD()
+			tc.Spec.TiKV.Replicas = 3
+			tc.Spec.TiKV.FailoverStaleStores = tt.staleStores
+			tc.Status.TiKV.Stores = tt.stores
+			tc.Status.TiKV.FailureStores = map[string]v1alpha1.TiKVFailureStore{
+				"1": {PodName: "test-tikv-1", StoreID: "1", CreatedAt: metav1.Time{Time: now.Add(-2 * time.Hour)}},
+				"2": {PodName: "test-tikv-2", StoreID: "2", CreatedAt: metav1.Time{Time: now.Add(-time.Hour)}},
+			}
+			tikvFailover := newFakeTiKVFailover()
+
+			tikvFailover.Recover(tc)
+			keys := []string{}
+			for key := range tc.Status.TiKV.FailureStores {
+				keys = append(keys, key)
+			}
+			g.Expect(keys).To(ConsistOf(tt.expectStores))
+			g.Expect(collectEvents(tikvFailover.recorder.(*record.FakeRecorder).Events)).To(Equal(tt.expectEvents))
+		})
+	}
+}
+
+func TestTiKVFailoverRecoverRecordedReplacement(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Now()
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.Replicas = 3
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp},
+		"2": {ID: "2", PodName: "test-tikv-2", State: v1alpha1.TiKVStateDown},
+		"4": {ID: "4", PodName: "test-tikv-3", State: v1alpha1.TiKVStateDown},
+		"5": {ID: "5", PodName: "test-tikv-4", State: v1alpha1.TiKVStateUp},
+	}
+	// the replacement pods recorded do not follow the order the failure stores were created
+	tc.Status.TiKV.FailureStores = map[string]v1alpha1.TiKVFailureStore{
+		"1": {PodName: "test-tikv-1", StoreID: "1", ReplacementPodName: "test-tikv-4", CreatedAt: metav1.Time{Time: now.Add(-2 * time.Hour)}},
+		"2": {PodName: "test-tikv-2", StoreID: "2", ReplacementPodName: "test-tikv-3", CreatedAt: metav1.Time{Time: now.Add(-time.Hour)}},
+	}
+	tikvFailover := newFakeTiKVFailover()
+
+	tikvFailover.Recover(tc)
+	g.Expect(tc.Status.TiKV.FailureStores).To(HaveLen(1))
+	g.Expect(tc.Status.TiKV.FailureStores).To(HaveKey("2"))
+	g.Expect(collectEvents(tikvFailover.recorder.(*record.FakeRecorder).Events)).To(Equal([]string{
+		"Normal FailureStoreRecovered store[1] of pod test-tikv-1 is Up again, the store of replacement pod test-tikv-4 is Up",
+	}))
+}
+
+func TestTiKVFailoverHistory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.Replicas = 3
+	tc.Spec.TiKV.MaxFailoverCount = pointer.Int32Ptr(3)
+	for i := 0; i < maxFailoverHistory; i++ {
+		tc.Status.TiKV.FailoverHistory = append(tc.Status.TiKV.FailoverHistory, v1alpha1.TiKVFailoverRecord{StoreID: "0", PodName: "test-tikv-0"})
+	}
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+		"1": {
+			ID:                 "1",
+			State:              v1alpha1.TiKVStateDown,
+			PodName:            "test-tikv-1",
+			LastTransitionTime: metav1.Time{Time: time.Now().Add(-70 * time.Minute)},
+		},
+	}
+	tikvFailover := newFakeTiKVFailover()
+
+	g.Expect(tikvFailover.Failover(tc)).To(Succeed())
+	g.Expect(tc.Status.TiKV.FailureStores["1"].ReplacementPodName).To(Equal("test-tikv-3"))
+	history := tc.Status.TiKV.FailoverHistory
+	g.Expect(history).To(HaveLen(maxFailoverHistory))
+	record := history[len(history)-1]
+	g.Expect(record.StoreID).To(Equal("1"))
+	g.Expect(record.PodName).To(Equal("test-tikv-1"))
+	g.Expect(record.ReplacementPodName).To(Equal("test-tikv-3"))
+	g.Expect(record.CreatedAt.IsZero()).To(BeFalse())
+	g.Expect(record.RecoveredAt).To(BeNil())
+
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", State: v1alpha1.TiKVStateUp, PodName: "test-tikv-1"},
+		"4": {ID: "4", State: v1alpha1.TiKVStateUp, PodName: "test-tikv-3"},
+	}
+	tikvFailover.Recover(tc)
+	g.Expect(tc.Status.TiKV.FailureStores).To(BeEmpty())
+	history = tc.Status.TiKV.FailoverHistory
+	g.Expect(history[len(history)-1].RecoveredAt).NotTo(BeNil())
+	g.Expect(history[0].RecoveredAt).To(BeNil())
+}
+
 func newFakeTiKVFailover() *tikvFailover {
 	recorder := record.NewFakeRecorder(100)
 	return &tikvFailover{1 * time.Hour, recorder}
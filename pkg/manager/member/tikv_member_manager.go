@@ -15,73 +15,106 @@ package member
 
 import (
 	"fmt"
+	"path"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/features"
 	"github.com/tikv/tikv-operator/pkg/label"
 	"github.com/tikv/tikv-operator/pkg/manager"
+	"github.com/tikv/tikv-operator/pkg/metrics"
+	"github.com/tikv/tikv-operator/pkg/notification"
 	"github.com/tikv/tikv-operator/pkg/pdapi"
+	"github.com/tikv/tikv-operator/pkg/tikvapi"
 	"github.com/tikv/tikv-operator/pkg/util"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
 	v1 "k8s.io/client-go/listers/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
-	"k8s.io/klog"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	// tikvClusterCertPath is where the cert for inter-cluster communication stored (if any)
 	tikvClusterCertPath = "/var/lib/tikv-tls"
 
+	// crashLoopRestartThreshold is the restart count from which a tikv container is considered crash-looping
+	crashLoopRestartThreshold = 3
+
 	//find a better way to manage store only managed by tikv in Operator
-	tikvStoreLimitPattern = `%s-tikv-\d+\.%s-tikv-peer\.%s\.svc\:\d+`
+	tikvStoreLimitPattern = `%s-tikv-\d+\.%s\.%s\.svc\:\d+`
 )
 
 // tikvMemberManager implements manager.Manager.
 type tikvMemberManager struct {
 	setControl                   controller.StatefulSetControlInterface
 	svcControl                   controller.ServiceControlInterface
+	podControl                   controller.PodControlInterface
 	pdControl                    pdapi.PDControlInterface
+	tikvControl                  tikvapi.TiKVControlInterface
 	typedControl                 controller.TypedControlInterface
 	setLister                    v1.StatefulSetLister
 	svcLister                    corelisters.ServiceLister
 	podLister                    corelisters.PodLister
 	nodeLister                   corelisters.NodeLister
+	pvcLister                    corelisters.PersistentVolumeClaimLister
+	cmLister                     corelisters.ConfigMapLister
 	autoFailover                 bool
 	tikvFailover                 Failover
 	tikvScaler                   Scaler
 	tikvUpgrader                 Upgrader
+	notifier                     notification.Notifier
+	recorder                     record.EventRecorder
 	tikvStatefulSetIsUpgradingFn func(corelisters.PodLister, pdapi.PDControlInterface, *apps.StatefulSet, *v1alpha1.TikvCluster) (bool, error)
 }
 
 // NewTiKVMemberManager returns a *tikvMemberManager
 func NewTiKVMemberManager(
 	pdControl pdapi.PDControlInterface,
+	tikvControl tikvapi.TiKVControlInterface,
 	setControl controller.StatefulSetControlInterface,
 	svcControl controller.ServiceControlInterface,
+	podControl controller.PodControlInterface,
 	typedControl controller.TypedControlInterface,
 	setLister v1.StatefulSetLister,
 	svcLister corelisters.ServiceLister,
 	podLister corelisters.PodLister,
 	nodeLister corelisters.NodeLister,
+	pvcLister corelisters.PersistentVolumeClaimLister,
+	cmLister corelisters.ConfigMapLister,
 	autoFailover bool,
 	tikvFailover Failover,
 	tikvScaler Scaler,
-	tikvUpgrader Upgrader) manager.Manager {
+	tikvUpgrader Upgrader,
+	notifier notification.Notifier,
+	recorder record.EventRecorder) manager.Manager {
 	kvmm := tikvMemberManager{
 		pdControl:    pdControl,
+		tikvControl:  tikvControl,
 		podLister:    podLister,
 		nodeLister:   nodeLister,
+		pvcLister:    pvcLister,
+		cmLister:     cmLister,
 		setControl:   setControl,
 		svcControl:   svcControl,
+		podControl:   podControl,
 		typedControl: typedControl,
 		setLister:    setLister,
 		svcLister:    svcLister,
@@ -89,6 +122,8 @@ func NewTiKVMemberManager(
 		tikvFailover: tikvFailover,
 		tikvScaler:   tikvScaler,
 		tikvUpgrader: tikvUpgrader,
+		notifier:     notifier,
+		recorder:     recorder,
 	}
 	kvmm.tikvStatefulSetIsUpgradingFn = tikvStatefulSetIsUpgrading
 	return &kvmm
@@ -102,6 +137,11 @@ type SvcConfig struct {
 	MemberName func(clusterName string) string
 	Headless   bool
 	Type       corev1.ServiceType
+	// PublishNotReadyAddresses is required by the peer discovery through the headless service,
+	// the other services should not route to the not ready pods
+	PublishNotReadyAddresses bool
+	// Annotations are added to the service
+	Annotations map[string]string
 }
 
 // Sync fulfills the manager.Manager interface
@@ -113,53 +153,237 @@ func (tkmm *tikvMemberManager) Sync(tc *v1alpha1.TikvCluster) error {
 		return controller.RequeueErrorf("TikvCluster: [%s/%s], waiting for PD cluster running", ns, tcName)
 	}
 
-	if err := tkmm.syncStatefulSetForTikvCluster(tc); err != nil {
-		return err
-	}
+	// the services are synced before the statefulset, the pods register the stores to PD by their DNS names
+	// under the headless peer service, which must exist before the pods are created
+	svcConfig := SvcConfig{
+		Name:                     "peer",
+		Port:                     20160,
+		Headless:                 true,
+		SvcLabel:                 func(l label.Label) label.Label { return l.TiKV() },
+		MemberName:               func(string) string { return tc.TiKVPeerServiceName() },
+		Annotations:              tc.Spec.TiKV.PeerServiceAnnotations,
+		PublishNotReadyAddresses: true,
+	}
+	svcList := []*corev1.Service{getNewServiceForTikvCluster(tc, svcConfig)}
+	if tc.TiKVStatusServiceEnabled() {
+		svcList = append(svcList, getNewServiceForTikvCluster(tc, SvcConfig{
+			Name:       "status",
+			Port:       20180,
+			SvcLabel:   func(l label.Label) label.Label { return l.TiKV() },
+			MemberName: controller.TiKVMemberName,
+		}))
+	}
+
+	for _, eListener := range tc.Spec.TiKV.ListenersConfig.ExternalListeners {
+		accessMethod := eListener.GetAccessMethod()
+		if accessMethod != corev1.ServiceTypeNodePort && accessMethod != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		selectorsTikv, err := label.New().Instance(tcName).TiKV().Selector()
+		if err != nil {
+			return err
+		}
 
-	svcList := []*corev1.Service{}
-	if tc.Spec.TiKV.ListenersConfig.ExternalListeners != nil {
-		for _, eListener := range tc.Spec.TiKV.ListenersConfig.ExternalListeners {
-			if eListener.GetAccessMethod() == corev1.ServiceTypeNodePort {
-				selectorsTikv, err := label.New().Instance(tcName).TiKV().Selector()
-				if err != nil {
-					return err
-				}
+		pods, err := tkmm.podLister.Pods(ns).List(selectorsTikv)
+		if err != nil {
+			return err
+		}
 
-				pods, err := tkmm.podLister.Pods(ns).List(selectorsTikv)
+		ordinals, podMap := podsByOrdinal(pods, controller.TiKVMemberName(tcName))
+		if accessMethod == corev1.ServiceTypeNodePort && len(ordinals) > 0 {
+			if err := validateNodePorts(eListener, int(ordinals[len(ordinals)-1])+1); err != nil {
+				return err
+			}
+		}
+		for _, ordinal := range ordinals {
+			if accessMethod == corev1.ServiceTypeNodePort {
+				externalIP, err := nodePortExternalIP(tc, tkmm.nodeLister, eListener, podMap[ordinal])
 				if err != nil {
 					return err
 				}
-
-				for idx, pod := range pods {
-					svcList = append(svcList, getNewNodeportServiceForTikvCluster(tc, int32(idx), eListener, pod.Status.HostIP, false))
-				}
+				svcList = append(svcList, getNewNodeportServiceForTikvCluster(tc, ordinal, eListener, externalIP, false))
+			} else {
+				svcList = append(svcList, getNewLoadBalancerServiceForTikvCluster(tc, ordinal, eListener))
 			}
 		}
 	}
 
-	svcConfig := SvcConfig{
-		Name:       "peer",
-		Port:       20160,
-		Headless:   true,
-		SvcLabel:   func(l label.Label) label.Label { return l.TiKV() },
-		MemberName: controller.TiKVPeerMemberName,
+	for i := 0; i < len(svcList); i++ {
+		if err := tkmm.syncServiceForTikvCluster(tc, svcList[i]); err != nil {
+			return err
+		}
 	}
 
-	svcList = append(svcList, getNewServiceForTikvCluster(tc, svcConfig))
+	if err := tkmm.cleanStatusService(tc); err != nil {
+		return err
+	}
 
-	for i := 0; i < len(svcList); i++ {
-		if err := tkmm.syncServiceForTikvCluster(tc, svcList[i]); err != nil {
+	// the stores whose labels failed to be set requeue the cluster after the rest of the sync
+	labelsErr, err := tkmm.syncStatefulSetForTikvCluster(tc)
+	if err != nil {
+		return err
+	}
+
+	if err := tkmm.syncPodDisruptionBudget(tc); err != nil {
+		return err
+	}
+
+	if err := tkmm.syncNodeDrainLeaderEviction(tc); err != nil {
+		return err
+	}
+
+	if err := tkmm.cleanStaleEvictLeaderSchedulers(tc); err != nil {
+		return err
+	}
+
+	if err := tkmm.syncCordonedStores(tc); err != nil {
+		return err
+	}
+
+	if err := tkmm.syncStoreLimit(tc); err != nil {
+		return err
+	}
+
+	if err := tkmm.syncStoreWeights(tc); err != nil {
+		return err
+	}
+
+	if err := tkmm.syncScaleOutRebalance(tc); err != nil {
+		return err
+	}
+
+	if err := tkmm.offlineReregisteredStores(tc); err != nil {
+		return err
+	}
+
+	if err := tkmm.cleanTombstoneStores(tc); err != nil {
+		return err
+	}
+
+	// the replication check only surfaces a warning, so it does not block the sync
+	if err := tkmm.syncReplicationStatus(tc); err != nil {
+		tikvLogger(tc).Warningf("failed to check the replication of the tikv stores, %v", err)
+	}
+
+	if err := tkmm.cleanStaleExternalServices(tc); err != nil {
+		return err
+	}
+	return labelsErr
+}
+
+// syncPodDisruptionBudget creates or updates the PodDisruptionBudget of the tikv pods sized from the replicas,
+// or deletes it if disabled
+func (tkmm *tikvMemberManager) syncPodDisruptionBudget(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.Paused {
+		return nil
+	}
+
+	pdb := getNewTiKVPodDisruptionBudget(tc)
+	if !tc.TiKVPodDisruptionBudgetEnabled() {
+		current := &policyv1beta1.PodDisruptionBudget{}
+		exist, err := tkmm.typedControl.Exist(client.ObjectKey{Namespace: pdb.Namespace, Name: pdb.Name}, current)
+		if err != nil {
 			return err
 		}
+		if !exist || !metav1.IsControlledBy(current, tc) {
+			return nil
+		}
+		return tkmm.typedControl.Delete(tc, current)
+	}
+
+	if _, err := tkmm.typedControl.CreateOrUpdatePodDisruptionBudget(tc, pdb); err != nil {
+		return controller.RequeueErrorf("error creating or updating tikv poddisruptionbudget: %v", err)
+	}
+	return nil
+}
+
+func getNewTiKVPodDisruptionBudget(tc *v1alpha1.TikvCluster) *policyv1beta1.PodDisruptionBudget {
+	tikvLabel := labelTiKV(tc)
+	minAvailable := tc.Spec.TiKV.Replicas - tc.TiKVPodDisruptionBudgetMaxUnavailable()
+	if minAvailable < 0 {
+		minAvailable = 0
+	}
+	minAvailableVal := intstr.FromInt(int(minAvailable))
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.TiKVMemberName(tc.Name),
+			Namespace:       tc.Namespace,
+			Labels:          tikvLabel.Labels(),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailableVal,
+			Selector:     tikvLabel.LabelSelector(),
+		},
+	}
+}
+
+// cleanStatusService deletes the ClusterIP service fronting the status port of tikv once disabled
+func (tkmm *tikvMemberManager) cleanStatusService(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.Paused || tc.TiKVStatusServiceEnabled() {
+		return nil
+	}
+	svc, err := tkmm.svcLister.Services(tc.GetNamespace()).Get(controller.TiKVMemberName(tc.GetName()))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(svc, tc) {
+		return nil
+	}
+	return tkmm.svcControl.DeleteService(tc, svc)
+}
+
+// cleanStaleExternalServices deletes the per-pod external access services of the tikv cluster
+// whose pods have been scaled in, to release the allocated node ports and load balancers
+func (tkmm *tikvMemberManager) cleanStaleExternalServices(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.Paused {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return err
+	}
+	svcs, err := tkmm.svcLister.Services(ns).List(selector)
+	if err != nil {
+		return err
 	}
 
+	desiredPods := map[string]bool{}
+	for ordinal := range tc.TiKVStsDesiredOrdinals(false) {
+		desiredPods[TikvPodName(tcName, ordinal)] = true
+	}
+	for _, svc := range svcs {
+		if svc.Spec.Type != corev1.ServiceTypeNodePort && svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		podName, ok := svc.Spec.Selector[apps.StatefulSetPodNameLabel]
+		if !ok || desiredPods[podName] || !metav1.IsControlledBy(svc, tc) {
+			continue
+		}
+		_, err := tkmm.podLister.Pods(ns).Get(podName)
+		if err == nil {
+			// the pod is being scaled in
+			continue
+		}
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		tikvLogger(tc).Infof("tikv pod %s does not exist, delete its external service %s", podName, svc.GetName())
+		if err := tkmm.svcControl.DeleteService(tc, svc); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (tkmm *tikvMemberManager) syncServiceForTikvCluster(tc *v1alpha1.TikvCluster, newSvc *corev1.Service) error {
 	if tc.Spec.Paused {
-		klog.V(4).Infof("tikv cluster %s/%s is paused, skip syncing for tikv service", tc.GetNamespace(), tc.GetName())
+		tikvLogger(tc).V(4).Infof("tikv cluster is paused, skip syncing for tikv service")
 		return nil
 	}
 
@@ -178,51 +402,74 @@ func (tkmm *tikvMemberManager) syncServiceForTikvCluster(tc *v1alpha1.TikvCluste
 	}
 
 	oldSvc := oldSvcTmp.DeepCopy()
+	preserveNodePorts(newSvc, oldSvc)
 
 	equal, err := controller.ServiceEqual(newSvc, oldSvc)
 	if err != nil {
 		return err
 	}
-	if !equal {
-		svc := *oldSvc
-		svc.Spec = newSvc.Spec
-		// TODO add unit test
-		err = controller.SetServiceLastAppliedConfigAnnotation(&svc)
+	annotationsEqual, err := controller.ServiceAnnotationsEqual(newSvc, oldSvc)
+	if err != nil {
+		return err
+	}
+	if !equal || !annotationsEqual {
+		svc, err := controller.MergeService(newSvc, oldSvc)
 		if err != nil {
 			return err
 		}
-		svc.Spec.ClusterIP = oldSvc.Spec.ClusterIP
-		_, err = tkmm.svcControl.UpdateService(tc, &svc)
+		_, err = tkmm.svcControl.UpdateService(tc, svc)
 		return err
 	}
 
 	return nil
 }
 
-func (tkmm *tikvMemberManager) syncStatefulSetForTikvCluster(tc *v1alpha1.TikvCluster) error {
+// syncStatefulSetForTikvCluster syncs the status and the statefulset of tikv, the RequeueError of the stores whose
+// labels failed to be set is returned separately, as it must not block the rest of the sync
+func (tkmm *tikvMemberManager) syncStatefulSetForTikvCluster(tc *v1alpha1.TikvCluster) (error, error) {
 	ns := tc.GetNamespace()
 	tcName := tc.GetName()
 
 	oldSetTmp, err := tkmm.setLister.StatefulSets(ns).Get(controller.TiKVMemberName(tcName))
 	if err != nil && !errors.IsNotFound(err) {
-		return err
+		return nil, err
 	}
 	setNotExist := errors.IsNotFound(err)
 
 	oldSet := oldSetTmp.DeepCopy()
 
+	// the statefulset must not be scaled, upgraded or updated based on a stale or partial status, which is
+	// the case whenever the status fails to be synced from PD
 	if err := tkmm.syncTikvClusterStatus(tc, oldSet); err != nil {
-		return err
+		return nil, err
 	}
 
 	if tc.Spec.Paused {
-		klog.V(4).Infof("tikv cluster %s/%s is paused, skip syncing for tikv statefulset", tc.GetNamespace(), tc.GetName())
-		return nil
+		tikvLogger(tc).V(4).Infof("tikv cluster is paused, skip syncing for tikv statefulset")
+		return nil, nil
 	}
 
 	cm, err := tkmm.syncTiKVConfigMap(tc, oldSet)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !setNotExist && cm != nil && features.DefaultFeatureGate.Enabled(features.ConfigRollback) {
+		cm, err = tkmm.rollbackCrashLoopingConfigMap(tc, oldSet, cm)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !setNotExist && cm != nil && tc.BaseTiKVSpec().ConfigUpdateStrategy() == v1alpha1.ConfigUpdateStrategyInPlace {
+		tkmm.cleanStaleConfigMaps(tc, cm)
+	}
+
+	tkmm.checkAffinityNodeLabels(tc)
+	tkmm.checkPinnedNodes(tc)
+
+	if !setNotExist {
+		if err := tkmm.reconcileReplicasMismatch(tc, oldSet); err != nil {
+			return nil, err
+		}
 	}
 
 	// Recover failed stores if any before generating desired statefulset
@@ -232,60 +479,152 @@ func (tkmm *tikvMemberManager) syncStatefulSetForTikvCluster(tc *v1alpha1.TikvCl
 
 	newSet, err := getNewTiKVSetForTikvCluster(tc, cm)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if setNotExist {
+		if tc.Spec.TiKV.CloneFrom != nil {
+			if err := tkmm.syncClonedPVCs(tc, newSet); err != nil {
+				return nil, err
+			}
+		}
 		err = SetStatefulSetLastAppliedConfigAnnotation(newSet)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		err = tkmm.setControl.CreateStatefulSet(tc, newSet)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		tc.Status.TiKV.StatefulSet = &apps.StatefulSetStatus{}
-		return nil
+		tkmm.notifier.Notify(tc, v1alpha1.TiKVMemberType, notification.EventCreated, "")
+		return nil, nil
 	}
 
-	if _, err := tkmm.setStoreLabelsForTiKV(tc); err != nil {
-		return err
+	// the stores must not be scaled, upgraded or failed over until the restore data is placed
+	if restore, ok := tc.Annotations[label.AnnRestoring]; ok {
+		return nil, controller.RequeueErrorf("TikvCluster: [%s/%s], waiting for TikvRestore %s to restore the data", ns, tcName, restore)
 	}
 
+	// the stores whose labels failed to be set must not block the scaling, upgrade and failover, the cluster is
+	// requeued after the rest of the sync instead
+	setCount, labelsErr := tkmm.setStoreLabelsForTiKV(tc)
+	if labelsErr != nil && !controller.IsRequeueError(labelsErr) {
+		return nil, labelsErr
+	}
+	recordStoreLabelsSet(tc, setCount)
+
 	if !templateEqual(newSet, oldSet) || tc.Status.TiKV.Phase == v1alpha1.UpgradePhase {
+		if tc.Status.TiKV.Phase != v1alpha1.UpgradePhase {
+			tkmm.notifier.Notify(tc, v1alpha1.TiKVMemberType, notification.EventUpgrading, fmt.Sprintf("upgrading to %s", tc.TiKVImage()))
+		}
 		if err := tkmm.tikvUpgrader.Upgrade(tc, oldSet, newSet); err != nil {
-			return err
+			return nil, err
 		}
+	} else {
+		// the refused downgrade is reverted
+		tc.Status.TiKV.DowngradeRefused = false
 	}
 
+	if err := tkmm.setZoneDeleteSlots(tc, oldSet, newSet); err != nil {
+		return nil, err
+	}
 	if err := tkmm.tikvScaler.Scale(tc, oldSet, newSet); err != nil {
-		return err
+		return nil, err
 	}
 
 	if tkmm.autoFailover && tc.Spec.TiKV.MaxFailoverCount != nil {
-		if tc.TiKVAllPodsStarted() && !tc.TiKVAllStoresReady() {
+		storesUnhealthy := !tc.TiKVAllStoresReady() || (tc.Spec.TiKV.FailoverStaleStores && tc.TiKVAnyStoreStale())
+		skipped := tc.TiKVAllPodsStarted() && storesUnhealthy && tc.Spec.TiKV.FailoverPaused
+		if skipped {
+			// the skipped failover is only reported once until it is resumed or the stores are ready again
+			if !tc.Status.TiKV.FailoverSkipped {
+				tikvLogger(tc).Infof("failover of the tikv stores not ready is skipped as it is paused")
+				tkmm.recorder.Event(tc, corev1.EventTypeNormal, "FailoverPaused",
+					"failover of the tikv stores not ready is skipped as it is paused")
+			}
+		} else if tc.TiKVAllPodsStarted() && storesUnhealthy {
+			failureStores := len(tc.Status.TiKV.FailureStores)
 			if err := tkmm.tikvFailover.Failover(tc); err != nil {
-				return err
+				return nil, err
+			}
+			if len(tc.Status.TiKV.FailureStores) > failureStores {
+				tkmm.notifier.Notify(tc, v1alpha1.TiKVMemberType, notification.EventFailover,
+					fmt.Sprintf("%d failure stores", len(tc.Status.TiKV.FailureStores)))
 			}
 		}
+		tc.Status.TiKV.FailoverSkipped = skipped
 	}
 
-	return updateStatefulSet(tkmm.setControl, tc, newSet, oldSet)
+	// the replicas of oldSet are overwritten by updateStatefulSet
+	oldReplicas := *oldSet.Spec.Replicas
+	if err := updateStatefulSet(tkmm.setControl, tc, newSet, oldSet); err != nil {
+		return nil, err
+	}
+	if *newSet.Spec.Replicas != oldReplicas {
+		tkmm.notifier.Notify(tc, v1alpha1.TiKVMemberType, notification.EventScaled,
+			fmt.Sprintf("scaled from %d to %d", oldReplicas, *newSet.Spec.Replicas))
+	}
+	return labelsErr, nil
+}
+
+// reconcileReplicasMismatch detects the replicas of the statefulset being changed outside of the operator,
+// in Adopt policy the replicas of the spec are updated to match the statefulset scaled out, otherwise the scaler
+// scales the statefulset back to the desired replicas. A scale-in is never adopted, as the pods removed directly
+// leave their stores in PD without being offlined, it is reverted and must be done through the spec.
+// The adopted replicas are persisted with the TikvCluster before the statefulset is updated, otherwise the
+// statefulset would be scaled back if the update of the TikvCluster failed.
+func (tkmm *tikvMemberManager) reconcileReplicasMismatch(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSet) error {
+	lastAppliedSpec, _, err := GetLastAppliedConfig(oldSet)
+	if err != nil || lastAppliedSpec.Replicas == nil || oldSet.Spec.Replicas == nil {
+		return nil
+	}
+	actual := *oldSet.Spec.Replicas
+	if actual == *lastAppliedSpec.Replicas || actual == tc.TiKVStsDesiredReplicas() {
+		return nil
+	}
+
+	adopted := actual - int32(len(tc.Status.TiKV.FailureStores))
+	if tc.Spec.TiKV.ReplicasMismatchPolicy == v1alpha1.ReplicasMismatchPolicyAdopt {
+		switch {
+		case actual < *lastAppliedSpec.Replicas:
+			tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "ScaleInNotAdopted",
+				"tikv statefulset %s replicas were scaled in from %d to %d directly, reverting to %d, scale in through the replicas of the spec to offline the stores",
+				oldSet.GetName(), *lastAppliedSpec.Replicas, actual, tc.TiKVStsDesiredReplicas())
+			return nil
+		case adopted < 1:
+			tikvLogger(tc).Warningf("tikv statefulset replicas %d can not be adopted, revert to %d",
+				actual, tc.TiKVStsDesiredReplicas())
+		default:
+			tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "ReplicasAdopted",
+				"tikv statefulset %s replicas were changed from %d to %d directly, adopted as the desired replicas",
+				oldSet.GetName(), *lastAppliedSpec.Replicas, actual)
+			tc.Spec.TiKV.Replicas = adopted
+			return controller.RequeueErrorf("TikvCluster: [%s/%s], persisting the adopted tikv replicas %d before updating the statefulset",
+				tc.GetNamespace(), tc.GetName(), adopted)
+		}
+	}
+	tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "ReplicasReverted",
+		"tikv statefulset %s replicas were changed from %d to %d directly, reverting to %d",
+		oldSet.GetName(), *lastAppliedSpec.Replicas, actual, tc.TiKVStsDesiredReplicas())
+	return nil
 }
 
 func (tkmm *tikvMemberManager) syncTiKVConfigMap(tc *v1alpha1.TikvCluster, set *apps.StatefulSet) (*corev1.ConfigMap, error) {
-	// For backward compatibility, only sync tidb configmap when .tikv.config is non-nil
-	if tc.Spec.TiKV.Config == nil {
+	// For backward compatibility, only sync tidb configmap when .tikv.config is non-nil or filled by the sizing profile
+	if tc.TiKVConfig() == nil {
 		return nil, nil
 	}
 	newCm, err := getTikVConfigMap(tc)
 	if err != nil {
 		return nil, err
 	}
-	if set != nil && tc.BaseTiKVSpec().ConfigUpdateStrategy() == v1alpha1.ConfigUpdateStrategyInPlace {
+	if set != nil {
 		inUseName := FindConfigMapVolume(&set.Spec.Template.Spec, func(name string) bool {
 			return strings.HasPrefix(name, controller.TiKVMemberName(tc.Name))
 		})
-		if inUseName != "" {
+		// the config changed online is updated to the ConfigMap in use, so the pods are not restarted for it
+		inPlace := tc.BaseTiKVSpec().ConfigUpdateStrategy() == v1alpha1.ConfigUpdateStrategyInPlace
+		if inUseName != "" && (tkmm.reloadTiKVConfig(tc, inUseName, newCm) || inPlace) {
 			newCm.Name = inUseName
 		}
 	}
@@ -293,6 +632,162 @@ func (tkmm *tikvMemberManager) syncTiKVConfigMap(tc *v1alpha1.TikvCluster, set *
 	return tkmm.typedControl.CreateOrUpdateConfigMap(tc, newCm)
 }
 
+// cleanStaleConfigMaps deletes the digest-suffixed tikv ConfigMaps owned by the cluster other than the one in use,
+// which are left by ConfigUpdateStrategyRollingUpdate before the strategy is switched to InPlace. The ConfigMaps still
+// mounted by any tikv pod are kept. The failures are only logged, they are retried on the next sync.
+func (tkmm *tikvMemberManager) cleanStaleConfigMaps(tc *v1alpha1.TikvCluster, inUse *corev1.ConfigMap) {
+	logger := tikvLogger(tc)
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		logger.Warningf("failed to build the selector of the tikv ConfigMaps: %v", err)
+		return
+	}
+	cms, err := tkmm.cmLister.ConfigMaps(tc.GetNamespace()).List(selector)
+	if err != nil {
+		logger.Warningf("failed to list the tikv ConfigMaps: %v", err)
+		return
+	}
+	pods, err := tkmm.podLister.Pods(tc.GetNamespace()).List(selector)
+	if err != nil {
+		logger.Warningf("failed to list the tikv pods: %v", err)
+		return
+	}
+	mounted := map[string]bool{}
+	for _, pod := range pods {
+		for _, vol := range pod.Spec.Volumes {
+			if vol.ConfigMap != nil {
+				mounted[vol.ConfigMap.Name] = true
+			}
+		}
+	}
+
+	prefix := controller.TiKVMemberName(tc.GetName()) + "-"
+	for _, cm := range cms {
+		if cm.Name == inUse.Name || mounted[cm.Name] || !strings.HasPrefix(cm.Name, prefix) || !metav1.IsControlledBy(cm, tc) {
+			continue
+		}
+		if !isConfigMapDigest(strings.TrimPrefix(cm.Name, prefix)) {
+			continue
+		}
+		if err := tkmm.typedControl.Delete(tc, cm); err != nil {
+			logger.Warningf("failed to delete the stale tikv ConfigMap %s: %v", cm.Name, err)
+			continue
+		}
+		logger.Infof("deleted the stale tikv ConfigMap %s not used since the config update strategy is InPlace", cm.Name)
+	}
+}
+
+// rollbackCrashLoopingConfigMap returns the ConfigMap the tikv statefulset should use. When the pods upgraded to
+// a new ConfigMap are crash-looping, the ConfigMap of the pods not upgraded yet is returned instead to roll back
+// and halt the rollout, the new ConfigMap is not rolled out again until the config is changed.
+// Only ConfigUpdateStrategyRollingUpdate keeps the previous ConfigMap to roll back to.
+func (tkmm *tikvMemberManager) rollbackCrashLoopingConfigMap(tc *v1alpha1.TikvCluster, set *apps.StatefulSet, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if tc.BaseTiKVSpec().ConfigUpdateStrategy() != v1alpha1.ConfigUpdateStrategyRollingUpdate {
+		return cm, nil
+	}
+	isTiKVConfigMap := func(name string) bool {
+		return strings.HasPrefix(name, controller.TiKVMemberName(tcName))
+	}
+
+	if tc.Status.TiKV.FailedConfigMap == cm.Name {
+		inUseName := FindConfigMapVolume(&set.Spec.Template.Spec, isTiKVConfigMap)
+		if inUseName == "" || inUseName == cm.Name {
+			return cm, nil
+		}
+		tikvLogger(tc).V(4).Infof("tikv ConfigMap %s was rolled back, keep using %s", cm.Name, inUseName)
+		rollback := cm.DeepCopy()
+		rollback.Name = inUseName
+		return rollback, nil
+	}
+
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := tkmm.podLister.Pods(ns).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	var previousName string
+	var crashLoopingPod string
+	for _, pod := range pods {
+		name := FindConfigMapVolume(&pod.Spec, isTiKVConfigMap)
+		if name == "" {
+			continue
+		}
+		if name != cm.Name {
+			previousName = name
+			continue
+		}
+		if isCrashLooping(pod) {
+			crashLoopingPod = pod.GetName()
+		}
+	}
+	if crashLoopingPod == "" || previousName == "" {
+		return cm, nil
+	}
+
+	tikvLogger(tc).Warningf("tikv pod %s is crash-looping with ConfigMap %s, roll back to %s",
+		crashLoopingPod, cm.Name, previousName)
+	tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "ConfigRolledBack",
+		"tikv pod %s is crash-looping with ConfigMap %s, roll back to %s", crashLoopingPod, cm.Name, previousName)
+	tc.Status.TiKV.FailedConfigMap = cm.Name
+	rollback := cm.DeepCopy()
+	rollback.Name = previousName
+	return rollback, nil
+}
+
+// isCrashLooping returns whether any container of the pod restarted too many times
+func isCrashLooping(pod *corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.RestartCount >= crashLoopRestartThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// syncClonedPVCs creates the data volumes of the new statefulset ahead of it, each one cloned from the
+// volume of the same ordinal in the source cluster. A volume claim template can only carry a single
+// data source for all the pods, so the claims are created here and then adopted by the statefulset.
+func (tkmm *tikvMemberManager) syncClonedPVCs(tc *v1alpha1.TikvCluster, set *apps.StatefulSet) error {
+	ns := tc.GetNamespace()
+	srcSetName := controller.TiKVMemberName(tc.Spec.TiKV.CloneFrom.ClusterName)
+	template := set.Spec.VolumeClaimTemplates[0]
+	size := template.Spec.Resources.Requests[corev1.ResourceStorage]
+
+	for i := int32(0); i < *set.Spec.Replicas; i++ {
+		srcName := ordinalPVCName(v1alpha1.TiKVMemberType, srcSetName, i)
+		src, err := tkmm.pvcLister.PersistentVolumeClaims(ns).Get(srcName)
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("tikv cluster %s/%s can not be cloned, source pvc %s does not exist", ns, tc.GetName(), srcName)
+		}
+		if err != nil {
+			return err
+		}
+		srcSize := src.Spec.Resources.Requests[corev1.ResourceStorage]
+		if size.Cmp(srcSize) < 0 {
+			return fmt.Errorf("tikv cluster %s/%s can not be cloned, storage request %s is smaller than %s of source pvc %s",
+				ns, tc.GetName(), size.String(), srcSize.String(), srcName)
+		}
+
+		pvc := template.DeepCopy()
+		pvc.Name = ordinalPVCName(v1alpha1.TiKVMemberType, set.GetName(), i)
+		pvc.Namespace = ns
+		pvc.Labels = set.Spec.Selector.MatchLabels
+		pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+			Kind: "PersistentVolumeClaim",
+			Name: srcName,
+		}
+		if _, err := tkmm.typedControl.CreateOrUpdatePVC(tc, pvc, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func getNewServiceForTikvCluster(tc *v1alpha1.TikvCluster, svcConfig SvcConfig) *corev1.Service {
 	ns := tc.Namespace
 	tcName := tc.Name
@@ -317,9 +812,12 @@ func getNewServiceForTikvCluster(tc *v1alpha1.TikvCluster, svcConfig SvcConfig)
 				},
 			},
 			Selector:                 svcLabel,
-			PublishNotReadyAddresses: true,
+			PublishNotReadyAddresses: svcConfig.PublishNotReadyAddresses,
 		},
 	}
+	if len(svcConfig.Annotations) > 0 {
+		svc.Annotations = MergeLabels(svcConfig.Annotations)
+	}
 	if svcConfig.Headless {
 		svc.Spec.ClusterIP = "None"
 	} else {
@@ -343,11 +841,11 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 		annMount,
 		{Name: v1alpha1.TiKVMemberType.String(), MountPath: "/var/lib/tikv"},
 		{Name: "config", ReadOnly: true, MountPath: "/etc/tikv"},
-		{Name: "startup-script", ReadOnly: true, MountPath: "/usr/local/bin"},
+		{Name: "startup-script", ReadOnly: true, MountPath: path.Dir(tc.TiKVStartScriptPath())},
 	}
 	if tc.IsTLSClusterEnabled() {
 		volMounts = append(volMounts, corev1.VolumeMount{
-			Name: "tikv-tls", ReadOnly: true, MountPath: "/var/lib/tikv-tls",
+			Name: "tikv-tls", ReadOnly: true, MountPath: tlsClusterMountPath(tc, tikvClusterCertPath),
 		})
 	}
 
@@ -358,7 +856,7 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 				LocalObjectReference: corev1.LocalObjectReference{
 					Name: tikvConfigMap,
 				},
-				Items: []corev1.KeyToPath{{Key: "config-file", Path: "tikv.toml"}},
+				Items: tikvConfigVolumeItems(tc),
 			}},
 		},
 		{Name: "startup-script", VolumeSource: corev1.VolumeSource{
@@ -366,7 +864,8 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 				LocalObjectReference: corev1.LocalObjectReference{
 					Name: tikvConfigMap,
 				},
-				Items: []corev1.KeyToPath{{Key: "startup-script", Path: "tikv_start_script.sh"}},
+				Items:       []corev1.KeyToPath{{Key: "startup-script", Path: path.Base(tc.TiKVStartScriptPath())}},
+				DefaultMode: tc.Spec.TiKV.StartScriptMode,
 			}},
 		},
 	}
@@ -374,7 +873,7 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 		vols = append(vols, corev1.Volume{
 			Name: "tikv-tls", VolumeSource: corev1.VolumeSource{
 				Secret: &corev1.SecretVolumeSource{
-					SecretName: util.ClusterTLSSecretName(tc.Name, label.TiKVLabelVal),
+					SecretName: tlsClusterSecretName(tc, label.TiKVLabelVal),
 				},
 			},
 		})
@@ -420,10 +919,17 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 
 	tikvLabel := labelTiKV(tc)
 	setName := controller.TiKVMemberName(tcName)
-	podAnnotations := CombineAnnotations(controller.AnnProm(20180), baseTiKVSpec.Annotations())
+	podAnnotations := CombineAnnotations(promAnnotations(baseTiKVSpec, 20180), baseTiKVSpec.Annotations())
+	if restartedAt, ok := tc.Annotations[label.AnnTiKVRestartedAt]; ok && restartedAt != "" {
+		podAnnotations[label.AnnTiKVRestartedAt] = restartedAt
+	}
 	stsAnnotations := getStsAnnotations(tc, label.TiKVLabelVal)
-	capacity := controller.TiKVCapacity(tc.Spec.TiKV.Limits)
-	headlessSvcName := controller.TiKVPeerMemberName(tcName)
+	capacityLimits := tc.Spec.TiKV.Limits
+	if tc.Spec.TiKV.StorageCapacity != nil {
+		capacityLimits = corev1.ResourceList{corev1.ResourceStorage: *tc.Spec.TiKV.StorageCapacity}
+	}
+	capacity := controller.TiKVCapacity(capacityLimits)
+	headlessSvcName := tc.TiKVPeerServiceName()
 
 	env := []corev1.EnvVar{
 		{
@@ -448,17 +954,15 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 		},
 		{
 			Name:  "TZ",
-			Value: tc.Spec.Timezone,
+			Value: tc.Timezone(),
 		},
 	}
 	tikvContainer := corev1.Container{
 		Name:            v1alpha1.TiKVMemberType.String(),
 		Image:           tc.TiKVImage(),
 		ImagePullPolicy: baseTiKVSpec.ImagePullPolicy(),
-		Command:         []string{"/bin/sh", "/usr/local/bin/tikv_start_script.sh"},
-		SecurityContext: &corev1.SecurityContext{
-			Privileged: tc.TiKVContainerPrivilege(),
-		},
+		Command:         tikvStartCommand(tc),
+		SecurityContext: tc.TiKVContainerSecurityContext(),
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          "server",
@@ -467,7 +971,7 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 			},
 		},
 		VolumeMounts: volMounts,
-		Resources:    controller.ContainerResource(tc.Spec.TiKV.ResourceRequirements),
+		Resources:    controller.ContainerResource(tc.TiKVResourceRequirements()),
 	}
 	podSpec := baseTiKVSpec.BuildPodSpec()
 	if baseTiKVSpec.HostNetwork() {
@@ -482,12 +986,16 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 		})
 	}
 	tikvContainer.Env = util.AppendEnv(env, baseTiKVSpec.Env())
+	tikvContainer.EnvFrom = baseTiKVSpec.EnvFrom()
 	podSpec.Volumes = vols
 	podSpec.SecurityContext = podSecurityContext
 	podSpec.InitContainers = initContainers
 	podSpec.Containers = []corev1.Container{tikvContainer}
 	podSpec.ServiceAccountName = tc.Spec.TiKV.ServiceAccount
+	setTiKVZoneAffinity(tc, &podSpec)
 
+	// the partition starts at the replicas so that no pod is upgraded until the upgrader lowers it
+	replicas := tc.TiKVStsDesiredReplicas()
 	tikvset := &apps.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            setName,
@@ -497,11 +1005,11 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
 		},
 		Spec: apps.StatefulSetSpec{
-			Replicas: controller.Int32Ptr(tc.TiKVStsDesiredReplicas()),
+			Replicas: controller.Int32Ptr(replicas),
 			Selector: tikvLabel.LabelSelector(),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels:      tikvLabel.Labels(),
+					Labels:      CombineLabels(baseTiKVSpec.Labels(), tikvLabel),
 					Annotations: podAnnotations,
 				},
 				Spec: podSpec,
@@ -514,7 +1022,7 @@ func getNewTiKVSetForTikvCluster(tc *v1alpha1.TikvCluster, cm *corev1.ConfigMap)
 			UpdateStrategy: apps.StatefulSetUpdateStrategy{
 				Type: apps.RollingUpdateStatefulSetStrategyType,
 				RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{
-					Partition: controller.Int32Ptr(tc.TiKVStsDesiredReplicas()),
+					Partition: controller.Int32Ptr(replicas),
 				},
 			},
 		},
@@ -535,13 +1043,40 @@ func volumeClaimTemplate(r corev1.ResourceRequirements, metaName string, storage
 	}
 }
 
+// tikvConfigVolumeItems returns the files of the config volume of tikv, the extra config files are mounted by
+// their names alongside tikv.toml
+func tikvConfigVolumeItems(tc *v1alpha1.TikvCluster) []corev1.KeyToPath {
+	items := []corev1.KeyToPath{{Key: "config-file", Path: "tikv.toml"}}
+	names := make([]string, 0, len(tc.Spec.TiKV.ConfigFiles))
+	for name := range tc.Spec.TiKV.ConfigFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		items = append(items, corev1.KeyToPath{Key: name, Path: name})
+	}
+	return items
+}
+
 func getTikVConfigMap(tc *v1alpha1.TikvCluster) (*corev1.ConfigMap, error) {
 
-	config := tc.Spec.TiKV.Config
+	config := tc.TiKVConfig()
 	if config == nil {
 		return nil, nil
 	}
 
+	// make sure the tls paths of tikv match where the certificates are mounted
+	if tc.IsTLSClusterEnabled() {
+		config = config.DeepCopy()
+		if config.Security == nil {
+			config.Security = &v1alpha1.TiKVSecurityConfig{}
+		}
+		certPath := tlsClusterMountPath(tc, tikvClusterCertPath)
+		config.Security.CAPath = pointer.StringPtr(path.Join(certPath, corev1.ServiceAccountRootCAKey))
+		config.Security.CertPath = pointer.StringPtr(path.Join(certPath, corev1.TLSCertKey))
+		config.Security.KeyPath = pointer.StringPtr(path.Join(certPath, corev1.TLSPrivateKeyKey))
+	}
+
 	confText, err := MarshalTOML(config)
 	if err != nil {
 		return nil, err
@@ -566,6 +1101,9 @@ func getTikVConfigMap(tc *v1alpha1.TikvCluster) (*corev1.ConfigMap, error) {
 			"startup-script": startScript,
 		},
 	}
+	for name, content := range tc.Spec.TiKV.ConfigFiles {
+		cm.Data[name] = content
+	}
 
 	if tc.BaseTiKVSpec().ConfigUpdateStrategy() == v1alpha1.ConfigUpdateStrategyRollingUpdate {
 		if err := AddConfigMapDigestSuffix(cm); err != nil {
@@ -582,11 +1120,16 @@ func labelTiKV(tc *v1alpha1.TikvCluster) label.Label {
 }
 
 func (tkmm *tikvMemberManager) syncTikvClusterStatus(tc *v1alpha1.TikvCluster, set *apps.StatefulSet) error {
+	tc.Status.TiKV.Replicas = tc.Spec.TiKV.Replicas
+	tc.Status.TiKV.FailoverReplicas = tc.TiKVFailoverReplicas()
+	tc.Status.TiKV.StatefulSetReplicas = tc.TiKVStsDesiredReplicas()
 	if set == nil {
 		// skip if not created yet
 		return nil
 	}
 	tc.Status.TiKV.StatefulSet = &set.Status
+	// the status is only marked synced once all of it is synced from PD, so a partial sync is never acted on
+	tc.Status.TiKV.Synced = false
 	upgrading, err := tkmm.tikvStatefulSetIsUpgradingFn(tkmm.podLister, tkmm.pdControl, set, tc)
 	if err != nil {
 		return err
@@ -596,20 +1139,32 @@ func (tkmm *tikvMemberManager) syncTikvClusterStatus(tc *v1alpha1.TikvCluster, s
 	} else {
 		tc.Status.TiKV.Phase = v1alpha1.NormalPhase
 	}
+	if err := tkmm.syncTiKVRevisionStatus(tc, set); err != nil {
+		return err
+	}
 
 	previousStores := tc.Status.TiKV.Stores
+	previousTombstoneStores := tc.Status.TiKV.TombstoneStores
 	stores := map[string]v1alpha1.TiKVStore{}
 	tombstoneStores := map[string]v1alpha1.TiKVStore{}
 
 	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	cluster, err := pdCli.GetCluster()
+	if err != nil {
+		return err
+	}
+	// the stores must not be synced from a different cluster
+	if err := syncClusterID(tc, cluster, tkmm.recorder); err != nil {
+		return err
+	}
+
 	// This only returns Up/Down/Offline stores
 	storesInfo, err := pdCli.GetStores()
 	if err != nil {
-		tc.Status.TiKV.Synced = false
 		return err
 	}
 
-	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.Name, tc.Namespace))
+	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.TiKVPeerServiceName(), tc.Namespace))
 	if err != nil {
 		return err
 	}
@@ -626,13 +1181,20 @@ func (tkmm *tikvMemberManager) syncTikvClusterStatus(tc *v1alpha1.TikvCluster, s
 		// avoid LastHeartbeatTime be overwrite by zero time when pd lost LastHeartbeatTime
 		if status.LastHeartbeatTime.IsZero() {
 			if oldStatus, ok := previousStores[status.ID]; ok {
-				klog.V(4).Infof("the pod:%s's store LastHeartbeatTime is zero,so will keep in %v", status.PodName, oldStatus.LastHeartbeatTime)
+				tikvLogger(tc).V(4).Infof("the pod:%s's store LastHeartbeatTime is zero,so will keep in %v", status.PodName, oldStatus.LastHeartbeatTime)
 				status.LastHeartbeatTime = oldStatus.LastHeartbeatTime
 			}
 		}
 
 		oldStore, exist := previousStores[status.ID]
 
+		status.Stale = status.State == v1alpha1.TiKVStateUp && !status.LastHeartbeatTime.IsZero() &&
+			time.Since(status.LastHeartbeatTime.Time) > tc.TiKVStaleHeartbeatThreshold()
+		if status.Stale && !oldStore.Stale {
+			tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "StoreHeartbeatStale",
+				"store[%s] of pod %s is Up but its last heartbeat was at %s", status.ID, status.PodName, status.LastHeartbeatTime.Format(time.RFC3339))
+		}
+
 		status.LastTransitionTime = metav1.Now()
 		if exist && status.State == oldStore.State {
 			status.LastTransitionTime = oldStore.LastTransitionTime
@@ -641,10 +1203,11 @@ func (tkmm *tikvMemberManager) syncTikvClusterStatus(tc *v1alpha1.TikvCluster, s
 		stores[status.ID] = *status
 	}
 
+	reregistered := filterReregisteredStores(stores)
+
 	//this returns all tombstone stores
 	tombstoneStoresInfo, err := pdCli.GetTombStoneStores()
 	if err != nil {
-		tc.Status.TiKV.Synced = false
 		return err
 	}
 	for _, store := range tombstoneStoresInfo.Stores {
@@ -655,12 +1218,29 @@ func (tkmm *tikvMemberManager) syncTikvClusterStatus(tc *v1alpha1.TikvCluster, s
 		if status == nil {
 			continue
 		}
+		// the transition time tells how long the store has been tombstone
+		status.LastTransitionTime = metav1.Now()
+		if oldStore, exist := previousTombstoneStores[status.ID]; exist && !oldStore.LastTransitionTime.IsZero() {
+			status.LastTransitionTime = oldStore.LastTransitionTime
+		}
 		tombstoneStores[status.ID] = *status
 	}
 
+	if err := tkmm.setStoresExternalAddress(tc, stores); err != nil {
+		return err
+	}
+
 	tc.Status.TiKV.Synced = true
 	tc.Status.TiKV.Stores = stores
 	tc.Status.TiKV.TombstoneStores = tombstoneStores
+	tc.Status.TiKV.ReregisteredStores = reregistered
+	tkmm.syncScalingOutPods(tc)
+	syncScalePhase(tc, set)
+	storeStates := map[string]int{v1alpha1.TiKVStateTombstone: len(tombstoneStores)}
+	for _, store := range stores {
+		storeStates[store.State]++
+	}
+	metrics.SetTiKVStores(tc.GetNamespace(), tc.GetName(), storeStates)
 	tc.Status.TiKV.Image = ""
 	c := filterContainer(set, "tikv")
 	if c != nil {
@@ -669,6 +1249,100 @@ func (tkmm *tikvMemberManager) syncTikvClusterStatus(tc *v1alpha1.TikvCluster, s
 	return nil
 }
 
+// setStoresExternalAddress records the address assigned to the LoadBalancer services of the stores
+func (tkmm *tikvMemberManager) setStoresExternalAddress(tc *v1alpha1.TikvCluster, stores map[string]v1alpha1.TiKVStore) error {
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return err
+	}
+	svcs, err := tkmm.svcLister.Services(tc.GetNamespace()).List(selector)
+	if err != nil {
+		return err
+	}
+
+	addresses := map[string]string{}
+	for _, svc := range svcs {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			address := ingress.IP
+			if address == "" {
+				address = ingress.Hostname
+			}
+			if address != "" {
+				addresses[svc.Spec.Selector[apps.StatefulSetPodNameLabel]] = address
+				break
+			}
+		}
+	}
+
+	for id, store := range stores {
+		store.ExternalAddress = addresses[store.PodName]
+		stores[id] = store
+	}
+	return nil
+}
+
+// filterReregisteredStores handles the stores registered again by a recreated pod with a new store id, e.g. when
+// the data directory on the reused PVC does not match the cluster. Only the store with the highest id of a
+// pod is kept in the stores, the stale ones are removed from the stores, otherwise they are counted as extra stores
+// and trigger the failover once they become down. The IDs of the stale stores not offline yet are returned to be
+// offlined by offlineReregisteredStores.
+func filterReregisteredStores(stores map[string]v1alpha1.TiKVStore) []string {
+	latest := map[string]v1alpha1.TiKVStore{}
+	for _, store := range stores {
+		current, ok := latest[store.PodName]
+		if !ok || isNewerStore(store, current) {
+			latest[store.PodName] = store
+		}
+	}
+
+	var reregistered []string
+	for id, store := range stores {
+		if latest[store.PodName].ID == id {
+			continue
+		}
+		delete(stores, id)
+		if store.State != v1alpha1.TiKVStateOffline {
+			reregistered = append(reregistered, id)
+		}
+	}
+	sort.Strings(reregistered)
+	return reregistered
+}
+
+// offlineReregisteredStores offlines the stale stores of the pods registered again in PD
+func (tkmm *tikvMemberManager) offlineReregisteredStores(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.Paused {
+		return nil
+	}
+	logger := tikvLogger(tc)
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	for _, id := range tc.Status.TiKV.ReregisteredStores {
+		storeID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		if err := pdCli.DeleteStore(storeID); err != nil {
+			return controller.RequeueErrorf("TikvCluster: [%s/%s], failed to offline the stale store %s, %v",
+				tc.GetNamespace(), tc.GetName(), id, err)
+		}
+		logger.Infof("store %s is registered again by its pod with a new store id, offline the stale store", id)
+		tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "StaleStoreRemoved",
+			"store %s is registered again by its pod with a new store id, offline the stale store", id)
+	}
+	return nil
+}
+
+// isNewerStore returns whether store a is registered after store b by the id, which is allocated incrementally
+// by PD. The heartbeat is not compared, a store just registered again may not have sent any heartbeat yet.
+func isNewerStore(a, b v1alpha1.TiKVStore) bool {
+	aID, _ := strconv.ParseUint(a.ID, 10, 64)
+	bID, _ := strconv.ParseUint(b.ID, 10, 64)
+	return aID > bID
+}
+
 func (tkmm *tikvMemberManager) getTiKVStore(store *pdapi.StoreInfo) *v1alpha1.TiKVStore {
 	if store.Store == nil || store.Status == nil {
 		return nil
@@ -682,13 +1356,26 @@ func (tkmm *tikvMemberManager) getTiKVStore(store *pdapi.StoreInfo) *v1alpha1.Ti
 		PodName:           podName,
 		IP:                ip,
 		LeaderCount:       int32(store.Status.LeaderCount),
+		RegionCount:       int32(store.Status.RegionCount),
 		State:             store.Store.StateName,
 		LastHeartbeatTime: metav1.Time{Time: store.Status.LastHeartbeatTS},
+		Capacity:          *resource.NewQuantity(int64(store.Status.Capacity), resource.BinarySI),
+		Available:         *resource.NewQuantity(int64(store.Status.Available), resource.BinarySI),
 	}
 }
 
+// setStoreLabelsBackoff is the backoff of retrying to set the labels of a store
+var setStoreLabelsBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2,
+	Steps:    3,
+}
+
+// setStoreLabelsForTiKV sets the location labels of the nodes to the stores of their pods, a RequeueError is returned
+// if the labels of any store failed to be set after the retries
 func (tkmm *tikvMemberManager) setStoreLabelsForTiKV(tc *v1alpha1.TikvCluster) (int, error) {
 	ns := tc.GetNamespace()
+	logger := tikvLogger(tc)
 	// for unit test
 	setCount := 0
 
@@ -708,10 +1395,11 @@ func (tkmm *tikvMemberManager) setStoreLabelsForTiKV(tc *v1alpha1.TikvCluster) (
 		return setCount, nil
 	}
 
-	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.Name, tc.Namespace))
+	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.TiKVPeerServiceName(), tc.Namespace))
 	if err != nil {
 		return -1, err
 	}
+	var failedPods []string
 	for _, store := range storesInfo.Stores {
 		// In theory, the external tikv can join the cluster, and the operator would only manage the internal tikv.
 		// So we check the store owner to make sure it.
@@ -725,33 +1413,182 @@ func (tkmm *tikvMemberManager) setStoreLabelsForTiKV(tc *v1alpha1.TikvCluster) (
 		podName := status.PodName
 
 		pod, err := tkmm.podLister.Pods(ns).Get(podName)
+		if errors.IsNotFound(err) {
+			// the pod may be recreated soon, its store is labeled in the next sync
+			logger.Warningf("pod: [%s] not found, skipping set store labels", podName)
+			continue
+		}
 		if err != nil {
 			return setCount, err
 		}
 
 		nodeName := pod.Spec.NodeName
+		if nodeName == "" {
+			// the store of a pod not scheduled yet is labeled after the pod is bound to a node
+			logger.V(4).Infof("pod: [%s] is not scheduled yet, skipping set store labels", podName)
+			continue
+		}
 		ls, err := tkmm.getNodeLabels(nodeName, locationLabels)
 		if err != nil || len(ls) == 0 {
-			klog.Warningf("node: [%s] has no node labels, skipping set store labels for Pod: [%s/%s]", nodeName, ns, podName)
+			logger.Warningf("node: [%s] has no node labels, skipping set store labels for pod: [%s]", nodeName, podName)
 			continue
 		}
 
 		if !tkmm.storeLabelsEqualNodeLabels(store.Store.Labels, ls) {
-			set, err := pdCli.SetStoreLabels(store.Store.Id, ls)
+			var set bool
+			var setErr error
+			// the transient failures of PD are retried, a store left unlabeled may get the replicas misplaced
+			err := wait.ExponentialBackoff(setStoreLabelsBackoff, func() (bool, error) {
+				set, setErr = pdCli.SetStoreLabels(store.Store.Id, ls)
+				return setErr == nil, nil
+			})
 			if err != nil {
-				klog.Warningf("failed to set pod: [%s/%s]'s store labels: %v", ns, podName, ls)
+				logger.Warningf("failed to set pod: [%s]'s store labels: %v, error: %v", podName, ls, setErr)
+				failedPods = append(failedPods, podName)
 				continue
 			}
 			if set {
 				setCount++
-				klog.Infof("pod: [%s/%s] set labels: %v successfully", ns, podName, ls)
+				logger.Infof("pod: [%s] set labels: %v successfully", podName, ls)
 			}
 		}
 	}
 
+	if len(failedPods) > 0 {
+		return setCount, controller.RequeueErrorf("TikvCluster: [%s/%s], failed to set the store labels of pods %v", ns, tc.GetName(), failedPods)
+	}
 	return setCount, nil
 }
 
+// syncScalePhase records the phase of the scaling of tikv, the statefulset is scaled in only after the store
+// of the pod removed is tombstone, and scaled out before the stores of the pods added are up
+func syncScalePhase(tc *v1alpha1.TikvCluster, set *apps.StatefulSet) {
+	// the replicas of a statefulset default to 1
+	current := int32(1)
+	if set.Spec.Replicas != nil {
+		current = *set.Spec.Replicas
+	}
+	desired := tc.TiKVStsDesiredReplicas()
+	tc.Status.TiKV.CurrentReplicas = current
+	tc.Status.TiKV.DesiredReplicas = desired
+	switch {
+	case current > desired:
+		tc.Status.TiKV.ScalePhase = v1alpha1.ScaleInPhase
+	case current < desired || len(tc.Status.TiKV.ScalingOutPods) > 0:
+		tc.Status.TiKV.ScalePhase = v1alpha1.ScaleOutPhase
+	default:
+		tc.Status.TiKV.ScalePhase = v1alpha1.ScaleIdlePhase
+	}
+}
+
+// syncScalingOutPods removes the pods whose stores are up from the pods being scaled out, the pods no longer
+// desired are removed as they were scaled in before their stores were up
+func (tkmm *tikvMemberManager) syncScalingOutPods(tc *v1alpha1.TikvCluster) {
+	if len(tc.Status.TiKV.ScalingOutPods) == 0 {
+		return
+	}
+	upStores := map[string]string{}
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.State == v1alpha1.TiKVStateUp {
+			upStores[store.PodName] = store.ID
+		}
+	}
+	desiredOrdinals := tc.TiKVStsDesiredOrdinals(false)
+	var pods []string
+	for _, podName := range tc.Status.TiKV.ScalingOutPods {
+		if id, ok := upStores[podName]; ok {
+			tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "ScaledOut", "scaled out tikv, store %s of pod %s is up", id, podName)
+			continue
+		}
+		ordinal, err := util.GetOrdinalFromPodName(podName)
+		if err != nil || !desiredOrdinals.Has(ordinal) {
+			continue
+		}
+		pods = append(pods, podName)
+	}
+	tc.Status.TiKV.ScalingOutPods = pods
+}
+
+// tikvStartCommand returns the command running the start script, which is executed by sh unless it is mounted executable
+func tikvStartCommand(tc *v1alpha1.TikvCluster) []string {
+	if tc.TiKVStartScriptExecutable() {
+		return []string{tc.TiKVStartScriptPath()}
+	}
+	return []string{"/bin/sh", tc.TiKVStartScriptPath()}
+}
+
+// recordStoreLabelsSet records the number of the stores whose labels were set in the status and the metrics
+func recordStoreLabelsSet(tc *v1alpha1.TikvCluster, setCount int) {
+	tc.Status.TiKV.StoreLabelsSet = int32(setCount)
+	if setCount <= 0 {
+		return
+	}
+	now := metav1.Now()
+	tc.Status.TiKV.LastStoreLabelsSetTime = &now
+	metrics.TiKVStoreLabelsSet.WithLabelValues(tc.GetNamespace(), tc.GetName()).Add(float64(setCount))
+}
+
+// checkAffinityNodeLabels warns about node affinity label keys which do not
+// exist on any schedulable node, pods requiring them would stay Pending.
+// The missing keys are recorded in the status, the warning is only emitted when they change.
+func (tkmm *tikvMemberManager) checkAffinityNodeLabels(tc *v1alpha1.TikvCluster) {
+	var missing []string
+	keys := nodeAffinityLabelKeys(tc.BaseTiKVSpec().Affinity())
+	if len(keys) > 0 {
+		nodes, err := tkmm.nodeLister.List(labels.Everything())
+		if err != nil {
+			tikvLogger(tc).Warningf("failed to list nodes for checking affinity of tikv cluster: %v", err)
+			return
+		}
+		missing = missingNodeLabelKeys(keys, nodes)
+	}
+	if len(missing) == 0 {
+		tc.Status.TiKV.MissingAffinityNodeLabels = nil
+		return
+	}
+	if reflect.DeepEqual(missing, tc.Status.TiKV.MissingAffinityNodeLabels) {
+		return
+	}
+	tc.Status.TiKV.MissingAffinityNodeLabels = missing
+	tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "AffinityNodeLabelsMissing",
+		"affinity references node labels %v which do not exist on any schedulable node", missing)
+}
+
+// checkPinnedNodes warns about tikv pods which do not run on the nodes they are pinned to by the pinned
+// nodes annotation. The pins are only applied by the pod webhook when the pods are created, pods created
+// before the pins or while the webhook was unavailable keep running on other nodes until they are recreated.
+// The unpinned pods are recorded in the status, the warning is only emitted when they change.
+func (tkmm *tikvMemberManager) checkPinnedNodes(tc *v1alpha1.TikvCluster) {
+	pinned, err := tc.TiKVPinnedNodes()
+	if err != nil {
+		tikvLogger(tc).Warningf("failed to check the pinned nodes of tikv cluster: %v", err)
+		return
+	}
+	var unpinned []string
+	for ordinal, nodeName := range pinned {
+		podName := TikvPodName(tc.GetName(), ordinal)
+		pod, err := tkmm.podLister.Pods(tc.GetNamespace()).Get(podName)
+		if err != nil {
+			continue
+		}
+		if pod.Spec.NodeName != "" && pod.Spec.NodeName != nodeName {
+			unpinned = append(unpinned, podName)
+		}
+	}
+	if len(unpinned) == 0 {
+		tc.Status.TiKV.UnpinnedPods = nil
+		return
+	}
+	sort.Strings(unpinned)
+	if reflect.DeepEqual(unpinned, tc.Status.TiKV.UnpinnedPods) {
+		return
+	}
+	tc.Status.TiKV.UnpinnedPods = unpinned
+	tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "PinSkipped",
+		"tikv pods %v do not run on the nodes pinned by %s, the pins take effect once the pods are recreated",
+		unpinned, label.AnnTiKVPinnedNodes)
+}
+
 func (tkmm *tikvMemberManager) getNodeLabels(nodeName string, storeLabels []string) (map[string]string, error) {
 	node, err := tkmm.nodeLister.Get(nodeName)
 	if err != nil {
@@ -771,6 +1608,11 @@ func (tkmm *tikvMemberManager) getNodeLabels(nodeName string, storeLabels []stri
 				labels[storeLabel] = host
 			}
 		}
+		if storeLabel == "zone" {
+			if zone, found := ls[corev1.LabelZoneFailureDomain]; found {
+				labels[storeLabel] = zone
+			}
+		}
 
 	}
 	return labels, nil
@@ -790,6 +1632,40 @@ func (tkmm *tikvMemberManager) storeLabelsEqualNodeLabels(storeLabels []*metapb.
 	return reflect.DeepEqual(ls, nodeLabels)
 }
 
+// syncTiKVRevisionStatus records the revisions of the statefulset and of each pod, so the progress of
+// a rolling upgrade can be told from the status
+func (tkmm *tikvMemberManager) syncTiKVRevisionStatus(tc *v1alpha1.TikvCluster, set *apps.StatefulSet) error {
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return err
+	}
+	tikvPods, err := tkmm.podLister.Pods(tc.GetNamespace()).List(selector)
+	if err != nil {
+		return err
+	}
+	status := &tc.Status.TiKV
+	status.CurrentRevision = set.Status.CurrentRevision
+	status.UpdateRevision = set.Status.UpdateRevision
+	status.CurrentRevisionPods = 0
+	status.UpdateRevisionPods = 0
+	status.PodRevisions = map[string]string{}
+	for _, pod := range tikvPods {
+		revision, exist := pod.Labels[apps.ControllerRevisionHashLabelKey]
+		if !exist {
+			continue
+		}
+		status.PodRevisions[pod.GetName()] = revision
+		// the current and update revisions are the same when no rolling upgrade is in progress
+		if revision == status.CurrentRevision {
+			status.CurrentRevisionPods++
+		}
+		if revision == status.UpdateRevision {
+			status.UpdateRevisionPods++
+		}
+	}
+	return nil
+}
+
 func tikvStatefulSetIsUpgrading(podLister corelisters.PodLister, pdControl pdapi.PDControlInterface, set *apps.StatefulSet, tc *v1alpha1.TikvCluster) (bool, error) {
 	if statefulSetIsUpgrading(set) {
 		return true, nil
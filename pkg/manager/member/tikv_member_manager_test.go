@@ -14,6 +14,7 @@
 package member
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -22,24 +23,35 @@ import (
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/gomega"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned/fake"
 	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
 	"github.com/tikv/tikv-operator/pkg/controller"
 	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/metrics"
+	"github.com/tikv/tikv-operator/pkg/notification"
 	"github.com/tikv/tikv-operator/pkg/pdapi"
+	"github.com/tikv/tikv-operator/pkg/tikvapi"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestTiKVMemberManagerSyncCreate(t *testing.T) {
@@ -77,8 +89,8 @@ func TestTiKVMemberManagerSyncCreate(t *testing.T) {
 
 		tkmm, fakeSetControl, fakeSvcControl, pdClient, _, _ := newFakeTiKVMemberManager(tc)
 		pdClient.AddReaction(pdapi.GetConfigActionType, func(action *pdapi.Action) (interface{}, error) {
-			return &v1alpha1.PDConfig{
-				Replication: &v1alpha1.PDReplicationConfig{
+			return &pdapi.PDConfigFromAPI{
+				Replication: &pdapi.PDReplicationConfig{
 					LocationLabels: []string{"region", "zone", "rack", "host"},
 				},
 			}, nil
@@ -196,6 +208,7 @@ func TestTiKVMemberManagerSyncUpdate(t *testing.T) {
 		errWhenUpdateStatefulSet     bool
 		errWhenUpdateTiKVPeerService bool
 		errWhenGetStores             bool
+		errWhenGetTombstoneStores    bool
 		statusChange                 func(*apps.StatefulSet)
 		err                          bool
 		expectTiKVPeerServiceFn      func(*GomegaWithT, *corev1.Service, error)
@@ -234,6 +247,9 @@ func TestTiKVMemberManagerSyncUpdate(t *testing.T) {
 				return test.pdStores, nil
 			})
 			pdClient.AddReaction(pdapi.GetTombStoneStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+				if test.errWhenGetTombstoneStores {
+					return nil, fmt.Errorf("failed to get tombstone stores from pd cluster")
+				}
 				return test.tombstoneStores, nil
 			})
 			pdClient.AddReaction(pdapi.SetStoreLabelsActionType, func(action *pdapi.Action) (interface{}, error) {
@@ -353,6 +369,41 @@ func TestTiKVMemberManagerSyncUpdate(t *testing.T) {
 				g.Expect(len(tc.Status.TiKV.Stores)).To(Equal(0))
 			},
 		},
+		{
+			name: "partial tikv status is not acted on",
+			modify: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.Replicas = 5
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+			},
+			pdStores:                  &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}},
+			errWhenGetTombstoneStores: true,
+			err:                       true,
+			expectStatefulSetFn: func(g *GomegaWithT, set *apps.StatefulSet, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(int(*set.Spec.Replicas)).To(Equal(3))
+			},
+			expectTikvClusterFn: func(g *GomegaWithT, tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Status.TiKV.Synced).To(BeFalse())
+			},
+		},
+		{
+			name: "waiting for restore",
+			modify: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.Replicas = 5
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Annotations = map[string]string{label.AnnRestoring: "restore"}
+			},
+			pdStores:        &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}},
+			tombstoneStores: &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}},
+			err:             true,
+			expectStatefulSetFn: func(g *GomegaWithT, set *apps.StatefulSet, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(int(*set.Spec.Replicas)).To(Equal(3))
+			},
+			expectTikvClusterFn: func(g *GomegaWithT, tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Status.TiKV.StatefulSet.ObservedGeneration).To(Equal(int64(1)))
+			},
+		},
 	}
 
 	for i := range tests {
@@ -362,6 +413,451 @@ func TestTiKVMemberManagerSyncUpdate(t *testing.T) {
 	}
 }
 
+func TestTiKVMemberManagerNotify(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tc.Status.PD.Members = map[string]v1alpha1.PDMember{
+		"pd-0": {Name: "pd-0", Health: true},
+		"pd-1": {Name: "pd-1", Health: true},
+		"pd-2": {Name: "pd-2", Health: true},
+	}
+	tc.Status.PD.StatefulSet = &apps.StatefulSetStatus{ReadyReplicas: 3}
+
+	tkmm, fakeSetControl, _, pdClient, _, _ := newFakeTiKVMemberManager(tc)
+	notifier := notification.NewFakeNotifier()
+	tkmm.notifier = notifier
+	tkmm.tikvStatefulSetIsUpgradingFn = func(corelisters.PodLister, pdapi.PDControlInterface, *apps.StatefulSet, *v1alpha1.TikvCluster) (bool, error) {
+		return false, nil
+	}
+	pdClient.AddReaction(pdapi.GetConfigActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &pdapi.PDConfigFromAPI{Replication: &pdapi.PDReplicationConfig{}}, nil
+	})
+	pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}}, nil
+	})
+	pdClient.AddReaction(pdapi.GetTombStoneStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}}, nil
+	})
+	sync := func() error {
+		fakeSetControl.SetStatusChange(func(set *apps.StatefulSet) {
+			set.Status.Replicas = *set.Spec.Replicas
+		})
+		return tkmm.Sync(tc)
+	}
+
+	g.Expect(sync()).To(Succeed())
+	g.Expect(notifier.EventTypes()).To(Equal([]notification.EventType{notification.EventCreated}))
+
+	tc.Spec.TiKV.Replicas = 4
+	fakeSetControl.SetUpdateStatefulSetError(fmt.Errorf("update statefulset failed"), 0)
+	g.Expect(sync()).NotTo(Succeed())
+	g.Expect(notifier.EventTypes()).To(Equal([]notification.EventType{notification.EventCreated}))
+
+	g.Expect(sync()).To(Succeed())
+	g.Expect(notifier.EventTypes()).To(Equal([]notification.EventType{notification.EventCreated, notification.EventScaled}))
+	g.Expect(notifier.Events()[1].Message).To(Equal("scaled from 3 to 4"))
+
+	tc.Spec.TiKV.Image = "tikv-test-image-2"
+	g.Expect(sync()).To(Succeed())
+	g.Expect(notifier.EventTypes()).To(Equal([]notification.EventType{
+		notification.EventCreated, notification.EventScaled, notification.EventUpgrading}))
+
+	tkmm.autoFailover = true
+	tkmm.tikvFailover = &storeAddingFailover{}
+	tc.Spec.TiKV.MaxFailoverCount = pointer.Int32Ptr(3)
+	recorder := record.NewFakeRecorder(10)
+	tkmm.recorder = recorder
+	tc.Spec.TiKV.FailoverPaused = true
+	g.Expect(sync()).To(Succeed())
+	g.Expect(tc.Status.TiKV.FailureStores).To(BeEmpty())
+	g.Expect(notifier.EventTypes()).To(Equal([]notification.EventType{
+		notification.EventCreated, notification.EventScaled, notification.EventUpgrading}))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("FailoverPaused")))
+	g.Expect(tc.Status.TiKV.FailoverSkipped).To(BeTrue())
+	g.Expect(sync()).To(Succeed())
+	g.Expect(collectEvents(recorder.Events)).NotTo(ContainElement(ContainSubstring("FailoverPaused")))
+
+	tc.Spec.TiKV.FailoverPaused = false
+	g.Expect(sync()).To(Succeed())
+	g.Expect(tc.Status.TiKV.FailoverSkipped).To(BeFalse())
+	g.Expect(notifier.EventTypes()).To(Equal([]notification.EventType{
+		notification.EventCreated, notification.EventScaled, notification.EventUpgrading, notification.EventFailover}))
+}
+
+// storeAddingFailover marks a store as failed on every failover
+type storeAddingFailover struct{}
+
+func (f *storeAddingFailover) Failover(tc *v1alpha1.TikvCluster) error {
+	if tc.Status.TiKV.FailureStores == nil {
+		tc.Status.TiKV.FailureStores = map[string]v1alpha1.TiKVFailureStore{}
+	}
+	id := fmt.Sprintf("%d", len(tc.Status.TiKV.FailureStores)+1)
+	tc.Status.TiKV.FailureStores[id] = v1alpha1.TiKVFailureStore{StoreID: id}
+	return nil
+}
+
+func (f *storeAddingFailover) Recover(_ *v1alpha1.TikvCluster) {}
+
+func TestTiKVMemberManagerReconcileReplicasMismatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		policy         v1alpha1.ReplicasMismatchPolicy
+		stsReplicas    int32
+		expectReplicas []int32
+		expectSpec     int32
+		expectEvent    string
+	}{
+		{
+			name:           "revert scaled out statefulset by default",
+			stsReplicas:    5,
+			expectReplicas: []int32{4, 3},
+			expectSpec:     3,
+			expectEvent:    "ReplicasReverted",
+		},
+		{
+			name:           "revert scaled in statefulset",
+			policy:         v1alpha1.ReplicasMismatchPolicyRevert,
+			stsReplicas:    2,
+			expectReplicas: []int32{3, 3},
+			expectSpec:     3,
+			expectEvent:    "ReplicasReverted",
+		},
+		{
+			name:           "adopt scaled out statefulset",
+			policy:         v1alpha1.ReplicasMismatchPolicyAdopt,
+			stsReplicas:    5,
+			expectReplicas: []int32{5, 5},
+			expectSpec:     5,
+			expectEvent:    "ReplicasAdopted",
+		},
+		{
+			name:           "revert scaled in statefulset in adopt policy",
+			policy:         v1alpha1.ReplicasMismatchPolicyAdopt,
+			stsReplicas:    2,
+			expectReplicas: []int32{3, 3},
+			expectSpec:     3,
+			expectEvent:    "ScaleInNotAdopted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Status.PD.Members = map[string]v1alpha1.PDMember{
+				"pd-0": {Name: "pd-0", Health: true},
+				"pd-1": {Name: "pd-1", Health: true},
+				"pd-2": {Name: "pd-2", Health: true},
+			}
+			tc.Status.PD.StatefulSet = &apps.StatefulSetStatus{ReadyReplicas: 3}
+			tc.Spec.TiKV.ReplicasMismatchPolicy = tt.policy
+
+			tkmm, fakeSetControl, _, pdClient, _, _ := newFakeTiKVMemberManager(tc)
+			recorder := record.NewFakeRecorder(10)
+			tkmm.recorder = recorder
+			pdClient.AddReaction(pdapi.GetConfigActionType, func(action *pdapi.Action) (interface{}, error) {
+				return &pdapi.PDConfigFromAPI{Replication: &pdapi.PDReplicationConfig{}}, nil
+			})
+			pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+				return &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}}, nil
+			})
+			pdClient.AddReaction(pdapi.GetTombStoneStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+				return &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}}, nil
+			})
+			sync := func() *apps.StatefulSet {
+				fakeSetControl.SetStatusChange(func(set *apps.StatefulSet) {
+					set.Status.Replicas = *set.Spec.Replicas
+				})
+				// the adopted replicas are persisted before the statefulset is updated
+				if err := tkmm.Sync(tc); err != nil {
+					g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+				}
+				set, err := tkmm.setLister.StatefulSets(tc.Namespace).Get(controller.TiKVMemberName(tc.Name))
+				g.Expect(err).NotTo(HaveOccurred())
+				return set
+			}
+
+			set := sync()
+			g.Expect(*set.Spec.Replicas).To(Equal(int32(3)))
+
+			// scale the statefulset directly
+			set = set.DeepCopy()
+			set.Spec.Replicas = pointer.Int32Ptr(tt.stsReplicas)
+			g.Expect(fakeSetControl.SetIndexer.Update(set)).To(Succeed())
+
+			for _, replicas := range tt.expectReplicas {
+				set = sync()
+				g.Expect(*set.Spec.Replicas).To(Equal(replicas))
+			}
+			g.Expect(tc.Spec.TiKV.Replicas).To(Equal(tt.expectSpec))
+			events := collectEvents(recorder.Events)
+			g.Expect(events).To(HaveLen(1))
+			g.Expect(events[0]).To(ContainSubstring(tt.expectEvent))
+		})
+	}
+}
+
+func TestTiKVMemberManagerCleanStaleExternalServices(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tkmm, _, svcControl, _, podIndexer, _ := newFakeTiKVMemberManager(tc)
+	listener := v1alpha1.ExternalListenerConfig{
+		CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "external", ContainerPort: 20160},
+		AccessMethod:       corev1.ServiceTypeNodePort,
+	}
+
+	// pod 3 is being scaled in and pod 4 has been removed
+	for i := int32(0); i < 5; i++ {
+		g.Expect(svcControl.SvcIndexer.Add(getNewNodeportServiceForTikvCluster(tc, i, listener, "10.0.0.1", false))).To(Succeed())
+		if i < 4 {
+			g.Expect(podIndexer.Add(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      TikvPodName(tc.Name, i),
+					Namespace: tc.Namespace,
+					Labels:    label.New().Instance(tc.Name).TiKV().Labels(),
+				},
+			})).To(Succeed())
+		}
+	}
+	unowned := getNewLoadBalancerServiceForTikvCluster(tc, 5, listener)
+	unowned.OwnerReferences = nil
+	g.Expect(svcControl.SvcIndexer.Add(unowned)).To(Succeed())
+	g.Expect(svcControl.SvcIndexer.Add(getNewServiceForTikvCluster(tc, SvcConfig{
+		Name:       "peer",
+		Port:       20160,
+		Headless:   true,
+		SvcLabel:   func(l label.Label) label.Label { return l.TiKV() },
+		MemberName: controller.TiKVPeerMemberName,
+	}))).To(Succeed())
+
+	// the services of a paused cluster are kept
+	tc.Spec.Paused = true
+	g.Expect(tkmm.cleanStaleExternalServices(tc)).To(Succeed())
+	svcs, err := tkmm.svcLister.Services(tc.Namespace).List(labels.Everything())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(svcs).To(HaveLen(7))
+
+	tc.Spec.Paused = false
+	g.Expect(tkmm.cleanStaleExternalServices(tc)).To(Succeed())
+
+	svcs, err = tkmm.svcLister.Services(tc.Namespace).List(labels.Everything())
+	g.Expect(err).NotTo(HaveOccurred())
+	names := []string{}
+	for _, svc := range svcs {
+		names = append(names, svc.Name)
+	}
+	g.Expect(names).To(ConsistOf(
+		"test-tikv-0-external",
+		"test-tikv-1-external",
+		"test-tikv-2-external",
+		"test-tikv-3-external",
+		"test-tikv-5-external",
+		controller.TiKVPeerMemberName(tc.Name),
+	))
+}
+
+func TestTiKVMemberManagerSyncPeerServiceAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tkmm, _, svcControl, _, _, _ := newFakeTiKVMemberManager(tc)
+	svcConfig := SvcConfig{
+		Name:       "peer",
+		Port:       20160,
+		Headless:   true,
+		SvcLabel:   func(l label.Label) label.Label { return l.TiKV() },
+		MemberName: controller.TiKVPeerMemberName,
+	}
+
+	g.Expect(tkmm.syncServiceForTikvCluster(tc, getNewServiceForTikvCluster(tc, svcConfig))).To(Succeed())
+	svc, err := tkmm.svcLister.Services(tc.Namespace).Get(controller.TiKVPeerMemberName(tc.Name))
+	g.Expect(err).To(Succeed())
+	svc = svc.DeepCopy()
+	svc.Annotations["external"] = "value"
+	g.Expect(svcControl.SvcIndexer.Update(svc)).To(Succeed())
+
+	// the annotations are applied to the existing service without a change of its spec
+	svcConfig.Annotations = map[string]string{"service.mesh/enabled": "true"}
+	g.Expect(tkmm.syncServiceForTikvCluster(tc, getNewServiceForTikvCluster(tc, svcConfig))).To(Succeed())
+	svc, err = tkmm.svcLister.Services(tc.Namespace).Get(controller.TiKVPeerMemberName(tc.Name))
+	g.Expect(err).To(Succeed())
+	g.Expect(svc.Annotations).To(HaveKeyWithValue("service.mesh/enabled", "true"))
+	g.Expect(svc.Annotations).To(HaveKeyWithValue("external", "value"))
+	g.Expect(svc.Annotations).To(HaveKey(controller.LastAppliedConfigAnnotation))
+}
+
+func TestTiKVMemberManagerSyncPreservesNodePorts(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tkmm, _, svcControl, _, _, _ := newFakeTiKVMemberManager(tc)
+	listener := v1alpha1.ExternalListenerConfig{
+		CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "external", ContainerPort: 20170},
+		AccessMethod:       corev1.ServiceTypeNodePort,
+	}
+
+	newSvc := getNewNodeportServiceForTikvCluster(tc, 0, listener, "10.0.0.1", false)
+	g.Expect(tkmm.syncServiceForTikvCluster(tc, newSvc)).To(Succeed())
+	svc, err := tkmm.svcLister.Services(tc.Namespace).Get(newSvc.Name)
+	g.Expect(err).To(Succeed())
+	// the node port is assigned by kubernetes
+	svc = svc.DeepCopy()
+	svc.Spec.Ports[0].NodePort = 31234
+	g.Expect(svcControl.SvcIndexer.Update(svc)).To(Succeed())
+
+	g.Expect(tkmm.syncServiceForTikvCluster(tc, getNewNodeportServiceForTikvCluster(tc, 0, listener, "10.0.0.1", false))).To(Succeed())
+	svc, err = tkmm.svcLister.Services(tc.Namespace).Get(newSvc.Name)
+	g.Expect(err).To(Succeed())
+	g.Expect(svc.Spec.Ports[0].NodePort).To(Equal(int32(31234)))
+	g.Expect(svc.Annotations[controller.LastAppliedConfigAnnotation]).To(ContainSubstring(`"nodePort":31234`))
+
+	// the external ip is updated without a change of the node port
+	g.Expect(tkmm.syncServiceForTikvCluster(tc, getNewNodeportServiceForTikvCluster(tc, 0, listener, "10.0.0.2", false))).To(Succeed())
+	svc, err = tkmm.svcLister.Services(tc.Namespace).Get(newSvc.Name)
+	g.Expect(err).To(Succeed())
+	g.Expect(svc.Spec.ExternalIPs).To(Equal([]string{"10.0.0.2"}))
+	g.Expect(svc.Spec.Ports[0].NodePort).To(Equal(int32(31234)))
+}
+
+func TestTiKVMemberManagerSyncStatusService(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.StatusServiceEnabled = pointer.BoolPtr(true)
+	tkmm, _, _, _, _, _ := newFakeTiKVMemberManager(tc)
+	svcConfig := SvcConfig{
+		Name:       "status",
+		Port:       20180,
+		SvcLabel:   func(l label.Label) label.Label { return l.TiKV() },
+		MemberName: controller.TiKVMemberName,
+	}
+
+	g.Expect(tkmm.syncServiceForTikvCluster(tc, getNewServiceForTikvCluster(tc, svcConfig))).To(Succeed())
+	svc, err := tkmm.svcLister.Services(tc.Namespace).Get(controller.TiKVMemberName(tc.Name))
+	g.Expect(err).To(Succeed())
+	g.Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+	g.Expect(svc.Spec.Ports).To(HaveLen(1))
+	g.Expect(svc.Spec.Ports[0].Port).To(Equal(int32(20180)))
+	g.Expect(svc.Spec.Selector).To(Equal(label.New().Instance(tc.GetInstanceName()).TiKV().Labels()))
+
+	// the service is kept while enabled
+	g.Expect(tkmm.cleanStatusService(tc)).To(Succeed())
+	_, err = tkmm.svcLister.Services(tc.Namespace).Get(controller.TiKVMemberName(tc.Name))
+	g.Expect(err).To(Succeed())
+
+	tc.Spec.TiKV.StatusServiceEnabled = pointer.BoolPtr(false)
+	g.Expect(tkmm.cleanStatusService(tc)).To(Succeed())
+	_, err = tkmm.svcLister.Services(tc.Namespace).Get(controller.TiKVMemberName(tc.Name))
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestTiKVMemberManagerRollbackCrashLoopingConfigMap(t *testing.T) {
+	g := NewGomegaWithT(t)
+	newPod := func(ordinal int32, cmName string, restarts int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      TikvPodName("test", ordinal),
+				Namespace: corev1.NamespaceDefault,
+				Labels:    label.New().Instance("test").TiKV().Labels(),
+			},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{
+					Name: "config",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: cmName},
+						},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "tikv", RestartCount: restarts}},
+			},
+		}
+	}
+	tests := []struct {
+		name            string
+		updateTC        func(*v1alpha1.TikvCluster)
+		setConfigMap    string
+		pods            []*corev1.Pod
+		expectConfigMap string
+		expectFailed    string
+		expectEvents    int
+	}{
+		{
+			name:         "roll back when the new config is crash-looping",
+			setConfigMap: "test-tikv-new",
+			pods: []*corev1.Pod{
+				newPod(0, "test-tikv-old", 0),
+				newPod(1, "test-tikv-old", 0),
+				newPod(2, "test-tikv-new", 5),
+			},
+			expectConfigMap: "test-tikv-old",
+			expectFailed:    "test-tikv-new",
+			expectEvents:    1,
+		},
+		{
+			name:         "continue the rollout when the new config is healthy",
+			setConfigMap: "test-tikv-new",
+			pods: []*corev1.Pod{
+				newPod(0, "test-tikv-old", 0),
+				newPod(1, "test-tikv-old", 0),
+				newPod(2, "test-tikv-new", 1),
+			},
+			expectConfigMap: "test-tikv-new",
+		},
+		{
+			name: "keep the rolled back config until the config changes",
+			updateTC: func(tc *v1alpha1.TikvCluster) {
+				tc.Status.TiKV.FailedConfigMap = "test-tikv-new"
+			},
+			setConfigMap: "test-tikv-old",
+			pods: []*corev1.Pod{
+				newPod(0, "test-tikv-old", 0),
+				newPod(1, "test-tikv-old", 0),
+				newPod(2, "test-tikv-old", 0),
+			},
+			expectConfigMap: "test-tikv-old",
+			expectFailed:    "test-tikv-new",
+		},
+		{
+			name: "no rollback for in-place config update",
+			updateTC: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.ConfigUpdateStrategy = v1alpha1.ConfigUpdateStrategyInPlace
+			},
+			setConfigMap: "test-tikv-new",
+			pods: []*corev1.Pod{
+				newPod(0, "test-tikv-old", 0),
+				newPod(2, "test-tikv-new", 5),
+			},
+			expectConfigMap: "test-tikv-new",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.ConfigUpdateStrategy = v1alpha1.ConfigUpdateStrategyRollingUpdate
+			if tt.updateTC != nil {
+				tt.updateTC(tc)
+			}
+			tkmm, _, _, _, podIndexer, _ := newFakeTiKVMemberManager(tc)
+			recorder := record.NewFakeRecorder(10)
+			tkmm.recorder = recorder
+			for _, pod := range tt.pods {
+				g.Expect(podIndexer.Add(pod)).To(Succeed())
+			}
+			set := &apps.StatefulSet{
+				Spec: apps.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{Spec: newPod(0, tt.setConfigMap, 0).Spec},
+				},
+			}
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-tikv-new"}}
+
+			result, err := tkmm.rollbackCrashLoopingConfigMap(tc, set, cm)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result.Name).To(Equal(tt.expectConfigMap))
+			g.Expect(tc.Status.TiKV.FailedConfigMap).To(Equal(tt.expectFailed))
+			g.Expect(collectEvents(recorder.Events)).To(HaveLen(tt.expectEvents))
+		})
+	}
+}
+
 func TestTiKVMemberManagerTiKVStatefulSetIsUpgrading(t *testing.T) {
 	g := NewGomegaWithT(t)
 	type testcase struct {
@@ -469,11 +965,16 @@ func TestTiKVMemberManagerSetStoreLabelsForTiKV(t *testing.T) {
 		errWhenGetStores bool
 		hasNode          bool
 		hasPod           bool
+		podUnscheduled   bool
 		storeInfo        *pdapi.StoresInfo
 		errExpectFn      func(*GomegaWithT, error)
 		setCount         int
 		labelSetFailed   bool
+		// labelSetFailures fails setting the store labels the times before succeeding
+		labelSetFailures int
 	}
+	defer func(backoff wait.Backoff) { setStoreLabelsBackoff = backoff }(setStoreLabelsBackoff)
+	setStoreLabelsBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
 	testFn := func(test *testcase, t *testing.T) {
 		tc := newTikvClusterForPD()
 		pmm, _, _, pdClient, podIndexer, nodeIndexer := newFakeTiKVMemberManager(tc)
@@ -518,12 +1019,24 @@ func TestTiKVMemberManagerSetStoreLabelsForTiKV(t *testing.T) {
 					NodeName: "node-1",
 				},
 			}
+			if test.podUnscheduled {
+				pod.Spec.NodeName = ""
+			}
 			podIndexer.Add(pod)
 		}
 		if test.labelSetFailed {
 			pdClient.AddReaction(pdapi.SetStoreLabelsActionType, func(action *pdapi.Action) (interface{}, error) {
 				return false, fmt.Errorf("label set failed")
 			})
+		} else if test.labelSetFailures > 0 {
+			failures := 0
+			pdClient.AddReaction(pdapi.SetStoreLabelsActionType, func(action *pdapi.Action) (interface{}, error) {
+				if failures < test.labelSetFailures {
+					failures++
+					return false, fmt.Errorf("label set failed")
+				}
+				return true, nil
+			})
 		} else {
 			pdClient.AddReaction(pdapi.SetStoreLabelsActionType, func(action *pdapi.Action) (interface{}, error) {
 				return true, nil
@@ -623,8 +1136,77 @@ func TestTiKVMemberManagerSetStoreLabelsForTiKV(t *testing.T) {
 			hasNode: true,
 			hasPod:  false,
 			errExpectFn: func(g *GomegaWithT, err error) {
-				g.Expect(err).To(HaveOccurred())
-				g.Expect(strings.Contains(err.Error(), "not found")).To(BeTrue())
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			setCount:       0,
+			labelSetFailed: false,
+		},
+		{
+			name:             "pod of one store not found",
+			errWhenGetStores: false,
+			storeInfo: &pdapi.StoresInfo{
+				Stores: []*pdapi.StoreInfo{
+					{
+						Store: &pdapi.MetaStore{
+							Store: &metapb.Store{
+								Id:      330,
+								Address: fmt.Sprintf("%s-tikv-0.%s-tikv-peer.%s.svc:20160", "test", "test", "default"),
+							},
+							StateName: "Up",
+						},
+						Status: &pdapi.StoreStatus{
+							LeaderCount:     1,
+							LastHeartbeatTS: time.Now(),
+						},
+					},
+					{
+						Store: &pdapi.MetaStore{
+							Store: &metapb.Store{
+								Id:      333,
+								Address: fmt.Sprintf("%s-tikv-1.%s-tikv-peer.%s.svc:20160", "test", "test", "default"),
+							},
+							StateName: "Up",
+						},
+						Status: &pdapi.StoreStatus{
+							LeaderCount:     1,
+							LastHeartbeatTS: time.Now(),
+						},
+					},
+				},
+			},
+			hasNode: true,
+			hasPod:  true,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			setCount:       1,
+			labelSetFailed: false,
+		},
+		{
+			name:             "pod not scheduled",
+			errWhenGetStores: false,
+			storeInfo: &pdapi.StoresInfo{
+				Stores: []*pdapi.StoreInfo{
+					{
+						Store: &pdapi.MetaStore{
+							Store: &metapb.Store{
+								Id:      333,
+								Address: fmt.Sprintf("%s-tikv-1.%s-tikv-peer.%s.svc:20160", "test", "test", "default"),
+							},
+							StateName: "Up",
+						},
+						Status: &pdapi.StoreStatus{
+							LeaderCount:     1,
+							LastHeartbeatTS: time.Now(),
+						},
+					},
+				},
+			},
+			hasNode:        true,
+			hasPod:         true,
+			podUnscheduled: true,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
 			},
 			setCount:       0,
 			labelSetFailed: false,
@@ -732,7 +1314,8 @@ func TestTiKVMemberManagerSetStoreLabelsForTiKV(t *testing.T) {
 			hasNode: true,
 			hasPod:  true,
 			errExpectFn: func(g *GomegaWithT, err error) {
-				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+				g.Expect(err.Error()).To(ContainSubstring("test-tikv-1"))
 			},
 			setCount:       0,
 			labelSetFailed: true,
@@ -771,6 +1354,34 @@ func TestTiKVMemberManagerSetStoreLabelsForTiKV(t *testing.T) {
 			setCount:       1,
 			labelSetFailed: false,
 		},
+		{
+			name:             "labels not equal, set success after transient failures",
+			errWhenGetStores: false,
+			storeInfo: &pdapi.StoresInfo{
+				Stores: []*pdapi.StoreInfo{
+					{
+						Store: &pdapi.MetaStore{
+							Store: &metapb.Store{
+								Id:      333,
+								Address: fmt.Sprintf("%s-tikv-1.%s-tikv-peer.%s.svc:20160", "test", "test", "default"),
+							},
+							StateName: "Up",
+						},
+						Status: &pdapi.StoreStatus{
+							LeaderCount:     1,
+							LastHeartbeatTS: time.Now(),
+						},
+					},
+				},
+			},
+			hasNode: true,
+			hasPod:  true,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			setCount:         1,
+			labelSetFailures: 2,
+		},
 	}
 
 	for i := range tests {
@@ -779,15 +1390,282 @@ func TestTiKVMemberManagerSetStoreLabelsForTiKV(t *testing.T) {
 	}
 }
 
-func TestTiKVMemberManagerSyncTikvClusterStatus(t *testing.T) {
+func TestTiKVMemberManagerCheckAffinityNodeLabels(t *testing.T) {
 	g := NewGomegaWithT(t)
 	type testcase struct {
-		name                      string
-		updateTC                  func(*v1alpha1.TikvCluster)
-		upgradingFn               func(corelisters.PodLister, pdapi.PDControlInterface, *apps.StatefulSet, *v1alpha1.TikvCluster) (bool, error)
-		errWhenGetStores          bool
-		storeInfo                 *pdapi.StoresInfo
-		errWhenGetTombstoneStores bool
+		name          string
+		affinity      *corev1.Affinity
+		nodes         []*corev1.Node
+		reported      []string
+		expectMissing []string
+		expectEvents  int
+	}
+	nodeAffinity := func(op corev1.NodeSelectorOperator, keys ...string) *corev1.Affinity {
+		reqs := []corev1.NodeSelectorRequirement{}
+		for _, key := range keys {
+			reqs = append(reqs, corev1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: op,
+			})
+		}
+		return &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: reqs},
+					},
+				},
+			},
+		}
+	}
+	newNode := func(name string, unschedulable bool, labels map[string]string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		}
+	}
+	testFn := func(test *testcase, t *testing.T) {
+		t.Log(test.name)
+		tc := newTikvClusterForPD()
+		tc.Spec.TiKV.Affinity = test.affinity
+		tc.Status.TiKV.MissingAffinityNodeLabels = test.reported
+		tkmm, _, _, _, _, nodeIndexer := newFakeTiKVMemberManager(tc)
+		recorder := record.NewFakeRecorder(10)
+		tkmm.recorder = recorder
+		for _, node := range test.nodes {
+			nodeIndexer.Add(node)
+		}
+		tkmm.checkAffinityNodeLabels(tc)
+		g.Expect(tc.Status.TiKV.MissingAffinityNodeLabels).To(Equal(test.expectMissing))
+		g.Expect(collectEvents(recorder.Events)).To(HaveLen(test.expectEvents))
+	}
+	tests := []testcase{
+		{
+			name:          "no affinity",
+			affinity:      nil,
+			nodes:         []*corev1.Node{newNode("node-1", false, nil)},
+			expectMissing: nil,
+		},
+		{
+			name:     "affinity references present labels",
+			affinity: nodeAffinity(corev1.NodeSelectorOpExists, "zone", "disk"),
+			nodes: []*corev1.Node{
+				newNode("node-1", false, map[string]string{"zone": "a"}),
+				newNode("node-2", false, map[string]string{"disk": "ssd"}),
+			},
+			expectMissing: nil,
+		},
+		{
+			name:     "affinity references absent labels",
+			affinity: nodeAffinity(corev1.NodeSelectorOpExists, "zone", "disk"),
+			nodes: []*corev1.Node{
+				newNode("node-1", false, map[string]string{"zone": "a"}),
+			},
+			expectMissing: []string{"disk"},
+			expectEvents:  1,
+		},
+		{
+			name:     "absent labels are already reported",
+			affinity: nodeAffinity(corev1.NodeSelectorOpExists, "zone", "disk"),
+			nodes: []*corev1.Node{
+				newNode("node-1", false, map[string]string{"zone": "a"}),
+			},
+			reported:      []string{"disk"},
+			expectMissing: []string{"disk"},
+			expectEvents:  0,
+		},
+		{
+			name:     "reported labels are added to the nodes",
+			affinity: nodeAffinity(corev1.NodeSelectorOpExists, "disk"),
+			nodes: []*corev1.Node{
+				newNode("node-1", false, map[string]string{"disk": "ssd"}),
+			},
+			reported:      []string{"disk"},
+			expectMissing: nil,
+			expectEvents:  0,
+		},
+		{
+			name:     "label only exists on unschedulable nodes",
+			affinity: nodeAffinity(corev1.NodeSelectorOpExists, "zone"),
+			nodes: []*corev1.Node{
+				newNode("node-1", true, map[string]string{"zone": "a"}),
+				newNode("node-2", false, nil),
+			},
+			expectMissing: []string{"zone"},
+			expectEvents:  1,
+		},
+		{
+			name:          "absent labels of a DoesNotExist requirement",
+			affinity:      nodeAffinity(corev1.NodeSelectorOpDoesNotExist, "dedicated"),
+			nodes:         []*corev1.Node{newNode("node-1", false, nil)},
+			expectMissing: nil,
+		},
+		{
+			name:          "absent labels of a NotIn requirement",
+			affinity:      nodeAffinity(corev1.NodeSelectorOpNotIn, "dedicated"),
+			nodes:         []*corev1.Node{newNode("node-1", false, nil)},
+			expectMissing: nil,
+		},
+	}
+	for i := range tests {
+		testFn(&tests[i], t)
+	}
+}
+
+func TestTiKVMemberManagerSyncPodDisruptionBudget(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	disabled := false
+	maxUnavailable := int32(2)
+	tests := []struct {
+		name               string
+		update             func(tc *v1alpha1.TikvCluster)
+		expectExist        bool
+		expectMinAvailable int
+	}{
+		{
+			name:               "default",
+			update:             func(tc *v1alpha1.TikvCluster) {},
+			expectExist:        true,
+			expectMinAvailable: 4,
+		},
+		{
+			name: "custom max unavailable",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.PodDisruptionBudget = &v1alpha1.PodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable}
+			},
+			expectExist:        true,
+			expectMinAvailable: 3,
+		},
+		{
+			name: "disabled",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.PodDisruptionBudget = &v1alpha1.PodDisruptionBudgetSpec{Enabled: &disabled}
+			},
+			expectExist: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.TiKV.Replicas = 5
+			tkmm, _, _, _, _, _ := newFakeTiKVMemberManager(tc)
+			genericControl := controller.NewFakeGenericControl()
+			tkmm.typedControl = controller.NewTypedControl(genericControl)
+
+			// the pdb of the previous sync is updated or deleted
+			g.Expect(tkmm.syncPodDisruptionBudget(tc)).To(Succeed())
+			tt.update(tc)
+			g.Expect(tkmm.syncPodDisruptionBudget(tc)).To(Succeed())
+
+			pdb := &policyv1beta1.PodDisruptionBudget{}
+			exist, err := genericControl.Exist(client.ObjectKey{Namespace: tc.Namespace, Name: controller.TiKVMemberName(tc.Name)}, pdb)
+			g.Expect(err).To(Succeed())
+			g.Expect(exist).To(Equal(tt.expectExist))
+			if tt.expectExist {
+				g.Expect(pdb.Spec.MinAvailable.IntValue()).To(Equal(tt.expectMinAvailable))
+				g.Expect(pdb.Spec.Selector).To(Equal(label.New().Instance(tc.GetInstanceName()).TiKV().LabelSelector()))
+			}
+		})
+	}
+}
+
+func TestTiKVMemberManagerSyncClonedPVCs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	type testcase struct {
+		name        string
+		sourceSize  string
+		sourcePVCs  int
+		errExpectFn func(*GomegaWithT, error)
+	}
+	testFn := func(test *testcase, t *testing.T) {
+		t.Log(test.name)
+		tc := newTikvClusterForPD()
+		tc.Spec.TiKV.Replicas = 2
+		tc.Spec.TiKV.CloneFrom = &v1alpha1.TiKVCloneSource{ClusterName: "source"}
+		tkmm, _, _, _, _, _ := newFakeTiKVMemberManager(tc)
+		genericControl := controller.NewFakeGenericControl()
+		tkmm.typedControl = controller.NewTypedControl(genericControl)
+		pvcInformer := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0).Core().V1().PersistentVolumeClaims()
+		tkmm.pvcLister = pvcInformer.Lister()
+		for i := 0; i < test.sourcePVCs; i++ {
+			pvcInformer.Informer().GetIndexer().Add(&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("tikv-source-tikv-%d", i),
+					Namespace: tc.Namespace,
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceStorage: resource.MustParse(test.sourceSize),
+						},
+					},
+				},
+			})
+		}
+
+		set, err := getNewTiKVSetForTikvCluster(tc, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		err = tkmm.syncClonedPVCs(tc, set)
+		test.errExpectFn(g, err)
+		if err != nil {
+			return
+		}
+
+		for i := 0; i < 2; i++ {
+			pvc := &corev1.PersistentVolumeClaim{}
+			key := types.NamespacedName{Namespace: tc.Namespace, Name: fmt.Sprintf("tikv-test-tikv-%d", i)}
+			g.Expect(genericControl.FakeCli.Get(context.TODO(), key, pvc)).To(Succeed())
+			g.Expect(pvc.Spec.DataSource).To(Equal(&corev1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: fmt.Sprintf("tikv-source-tikv-%d", i),
+			}))
+			g.Expect(pvc.Spec.StorageClassName).To(Equal(pointer.StringPtr("my-storage-class")))
+			g.Expect(pvc.Labels).To(Equal(set.Spec.Selector.MatchLabels))
+		}
+	}
+	tests := []testcase{
+		{
+			name:       "clone every pvc from the same ordinal",
+			sourceSize: "100Gi",
+			sourcePVCs: 2,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+		},
+		{
+			name:       "source pvc does not exist",
+			sourceSize: "100Gi",
+			sourcePVCs: 1,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("source pvc tikv-source-tikv-1 does not exist"))
+			},
+		},
+		{
+			name:       "source pvc is larger than the storage request",
+			sourceSize: "200Gi",
+			sourcePVCs: 2,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("storage request 100Gi is smaller than 200Gi"))
+			},
+		},
+	}
+	for i := range tests {
+		testFn(&tests[i], t)
+	}
+}
+
+func TestTiKVMemberManagerSyncTikvClusterStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+	type testcase struct {
+		name                      string
+		updateTC                  func(*v1alpha1.TikvCluster)
+		upgradingFn               func(corelisters.PodLister, pdapi.PDControlInterface, *apps.StatefulSet, *v1alpha1.TikvCluster) (bool, error)
+		errWhenGetStores          bool
+		storeInfo                 *pdapi.StoresInfo
+		errWhenGetTombstoneStores bool
 		tombstoneStoreInfo        *pdapi.StoresInfo
 		errExpectFn               func(*GomegaWithT, error)
 		tcExpectFn                func(*GomegaWithT, *v1alpha1.TikvCluster)
@@ -838,6 +1716,37 @@ func TestTiKVMemberManagerSyncTikvClusterStatus(t *testing.T) {
 		}
 	}
 	tests := []testcase{
+		{
+			name: "replicas with failure stores",
+			updateTC: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.Replicas = 3
+				tc.Status.TiKV.FailureStores = map[string]v1alpha1.TiKVFailureStore{
+					"1": {PodName: "test-tikv-1", StoreID: "1"},
+				}
+			},
+			errWhenGetStores: true,
+			tcExpectFn: func(g *GomegaWithT, tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Status.TiKV.Replicas).To(Equal(int32(3)))
+				g.Expect(tc.Status.TiKV.FailoverReplicas).To(Equal(int32(1)))
+				g.Expect(tc.Status.TiKV.StatefulSetReplicas).To(Equal(int32(4)))
+			},
+		},
+		{
+			name: "failover replicas are capped by the max failover count",
+			updateTC: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.Replicas = 3
+				tc.Spec.TiKV.MaxFailoverCount = pointer.Int32Ptr(1)
+				tc.Status.TiKV.FailureStores = map[string]v1alpha1.TiKVFailureStore{
+					"1": {PodName: "test-tikv-1", StoreID: "1"},
+					"2": {PodName: "test-tikv-2", StoreID: "2"},
+				}
+			},
+			errWhenGetStores: true,
+			tcExpectFn: func(g *GomegaWithT, tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Status.TiKV.FailoverReplicas).To(Equal(int32(1)))
+				g.Expect(tc.Status.TiKV.StatefulSetReplicas).To(Equal(int32(4)))
+			},
+		},
 		{
 			name:     "whether statefulset is upgrading returns failed",
 			updateTC: nil,
@@ -856,6 +1765,21 @@ func TestTiKVMemberManagerSyncTikvClusterStatus(t *testing.T) {
 				g.Expect(tc.Status.TiKV.StatefulSet.Replicas).To(Equal(int32(3)))
 			},
 		},
+		{
+			name: "status synced before is not synced on a partial failure",
+			updateTC: func(tc *v1alpha1.TikvCluster) {
+				tc.Status.TiKV.Synced = true
+			},
+			upgradingFn: func(lister corelisters.PodLister, controlInterface pdapi.PDControlInterface, set *apps.StatefulSet, cluster *v1alpha1.TikvCluster) (bool, error) {
+				return false, fmt.Errorf("whether upgrading failed")
+			},
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+			},
+			tcExpectFn: func(g *GomegaWithT, tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Status.TiKV.Synced).To(BeFalse())
+			},
+		},
 		{
 			name:     "statefulset is upgrading",
 			updateTC: nil,
@@ -1048,6 +1972,54 @@ func TestTiKVMemberManagerSyncTikvClusterStatus(t *testing.T) {
 				g.Expect(tc.Status.TiKV.Synced).To(BeTrue())
 			},
 		},
+		{
+			name: "store is Up but its heartbeat is stale",
+			updateTC: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.StaleHeartbeatThreshold = &metav1.Duration{Duration: 5 * time.Minute}
+			},
+			upgradingFn: func(lister corelisters.PodLister, controlInterface pdapi.PDControlInterface, set *apps.StatefulSet, cluster *v1alpha1.TikvCluster) (bool, error) {
+				return false, nil
+			},
+			errWhenGetStores: false,
+			storeInfo: &pdapi.StoresInfo{
+				Stores: []*pdapi.StoreInfo{
+					{
+						Store: &pdapi.MetaStore{
+							Store: &metapb.Store{
+								Id:      333,
+								Address: fmt.Sprintf("%s-tikv-1.%s-tikv-peer.%s.svc:20160", "test", "test", "default"),
+							},
+							StateName: "Up",
+						},
+						Status: &pdapi.StoreStatus{
+							LastHeartbeatTS: time.Now().Add(-10 * time.Minute),
+						},
+					},
+					{
+						Store: &pdapi.MetaStore{
+							Store: &metapb.Store{
+								Id:      334,
+								Address: fmt.Sprintf("%s-tikv-2.%s-tikv-peer.%s.svc:20160", "test", "test", "default"),
+							},
+							StateName: "Up",
+						},
+						Status: &pdapi.StoreStatus{
+							LastHeartbeatTS: time.Now().Add(-time.Minute),
+						},
+					},
+				},
+			},
+			errWhenGetTombstoneStores: false,
+			tombstoneStoreInfo: &pdapi.StoresInfo{
+				Stores: []*pdapi.StoreInfo{},
+			},
+			errExpectFn: errExpectNil,
+			tcExpectFn: func(g *GomegaWithT, tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Status.TiKV.Stores["333"].Stale).To(BeTrue())
+				g.Expect(tc.Status.TiKV.Stores["334"].Stale).To(BeFalse())
+				g.Expect(tc.TiKVAnyStoreStale()).To(BeTrue())
+			},
+		},
 		{
 			name: "LastHeartbeatTS is zero, TikvClulster LastHeartbeatTS is zero",
 			updateTC: func(tc *v1alpha1.TikvCluster) {
@@ -1424,6 +2396,26 @@ func TestTiKVMemberManagerSyncTikvClusterStatus(t *testing.T) {
 				g.Expect(len(tc.Status.TiKV.Stores)).To(Equal(1))
 				g.Expect(len(tc.Status.TiKV.TombstoneStores)).To(Equal(1))
 				g.Expect(tc.Status.TiKV.Synced).To(BeTrue())
+				g.Expect(tc.Status.ClusterID).To(Equal("1"))
+			},
+		},
+		{
+			name: "cluster id changed",
+			updateTC: func(tc *v1alpha1.TikvCluster) {
+				tc.Status.ClusterID = "2"
+			},
+			upgradingFn: func(lister corelisters.PodLister, controlInterface pdapi.PDControlInterface, set *apps.StatefulSet, cluster *v1alpha1.TikvCluster) (bool, error) {
+				return false, nil
+			},
+			storeInfo:          &pdapi.StoresInfo{},
+			tombstoneStoreInfo: &pdapi.StoresInfo{},
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(strings.Contains(err.Error(), "changed from 2 to 1")).To(BeTrue())
+			},
+			tcExpectFn: func(g *GomegaWithT, tc *v1alpha1.TikvCluster) {
+				g.Expect(tc.Status.ClusterID).To(Equal("2"))
+				g.Expect(tc.Status.TiKV.Synced).To(BeFalse())
 			},
 		},
 	}
@@ -1434,69 +2426,290 @@ func TestTiKVMemberManagerSyncTikvClusterStatus(t *testing.T) {
 	}
 }
 
-func newFakeTiKVMemberManager(tc *v1alpha1.TikvCluster) (
-	*tikvMemberManager, *controller.FakeStatefulSetControl,
-	*controller.FakeServiceControl, *pdapi.FakePDClient, cache.Indexer, cache.Indexer) {
-	cli := fake.NewSimpleClientset()
-	kubeCli := kubefake.NewSimpleClientset()
-	pdControl := pdapi.NewFakePDControl(kubeCli)
-	pdClient := controller.NewFakePDClient(pdControl, tc)
-	setInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Apps().V1().StatefulSets()
-	svcInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Services()
-	epsInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Endpoints()
-	tcInformer := informers.NewSharedInformerFactory(cli, 0).Tikv().V1alpha1().TikvClusters()
-	setControl := controller.NewFakeStatefulSetControl(setInformer, tcInformer)
-	svcControl := controller.NewFakeServiceControl(svcInformer, epsInformer, tcInformer)
-	podInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Pods()
-	nodeInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Nodes()
-	tikvScaler := NewFakeTiKVScaler()
-	tikvUpgrader := NewFakeTiKVUpgrader()
-	genericControl := controller.NewFakeGenericControl()
+func TestTiKVMemberManagerSetStoresExternalAddress(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tkmm, _, svcControl, _, _, _ := newFakeTiKVMemberManager(tc)
+	listener := v1alpha1.ExternalListenerConfig{
+		CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "external", ContainerPort: 20160},
+		AccessMethod:       corev1.ServiceTypeLoadBalancer,
+	}
+	ingresses := [][]corev1.LoadBalancerIngress{
+		{{IP: "1.2.3.4"}},
+		{{Hostname: "tikv-1.example.com"}},
+		nil,
+	}
+	for i, ingress := range ingresses {
+		svc := getNewLoadBalancerServiceForTikvCluster(tc, int32(i), listener)
+		svc.Status.LoadBalancer.Ingress = ingress
+		g.Expect(svcControl.SvcIndexer.Add(svc)).To(Succeed())
+	}
 
-	tmm := &tikvMemberManager{
-		pdControl:    pdControl,
-		podLister:    podInformer.Lister(),
-		nodeLister:   nodeInformer.Lister(),
-		setControl:   setControl,
-		svcControl:   svcControl,
-		typedControl: controller.NewTypedControl(genericControl),
-		setLister:    setInformer.Lister(),
-		svcLister:    svcInformer.Lister(),
-		tikvScaler:   tikvScaler,
-		tikvUpgrader: tikvUpgrader,
+	stores := map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", PodName: "test-tikv-0"},
+		"2": {ID: "2", PodName: "test-tikv-1"},
+		"3": {ID: "3", PodName: "test-tikv-2", ExternalAddress: "stale"},
 	}
-	tmm.tikvStatefulSetIsUpgradingFn = tikvStatefulSetIsUpgrading
-	return tmm, setControl, svcControl, pdClient, podInformer.Informer().GetIndexer(), nodeInformer.Informer().GetIndexer()
+	g.Expect(tkmm.setStoresExternalAddress(tc, stores)).To(Succeed())
+	g.Expect(stores["1"].ExternalAddress).To(Equal("1.2.3.4"))
+	g.Expect(stores["2"].ExternalAddress).To(Equal("tikv-1.example.com"))
+	g.Expect(stores["3"].ExternalAddress).To(BeEmpty())
 }
 
-func TestGetNewServiceForTikvCluster(t *testing.T) {
+func TestFilterReregisteredStores(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Now()
+	stores := map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", PodName: "test-tikv-0", State: v1alpha1.TiKVStateUp, LastHeartbeatTime: metav1.Time{Time: now}},
+		"2": {ID: "2", PodName: "test-tikv-1", State: v1alpha1.TiKVStateDown, LastHeartbeatTime: metav1.Time{Time: now.Add(-time.Hour)}},
+		"3": {ID: "3", PodName: "test-tikv-2", State: v1alpha1.TiKVStateOffline, LastHeartbeatTime: metav1.Time{Time: now.Add(-time.Hour)}},
+		"4": {ID: "4", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp, LastHeartbeatTime: metav1.Time{Time: now}},
+		"5": {ID: "5", PodName: "test-tikv-2", State: v1alpha1.TiKVStateUp, LastHeartbeatTime: metav1.Time{Time: now}},
+		"6": {ID: "6", PodName: "test-tikv-3", State: v1alpha1.TiKVStateUp, LastHeartbeatTime: metav1.Time{Time: now}},
+		// registered again without any heartbeat yet
+		"7": {ID: "7", PodName: "test-tikv-3", State: v1alpha1.TiKVStateUp},
+		"8": {ID: "8", PodName: "test-tikv-4", State: v1alpha1.TiKVStateUp, LastHeartbeatTime: metav1.Time{Time: now}},
+		"9": {ID: "9", PodName: "test-tikv-4", State: v1alpha1.TiKVStateUp, LastHeartbeatTime: metav1.Time{Time: now.Add(-time.Minute)}},
+	}
+	// the offline store is already being removed
+	g.Expect(filterReregisteredStores(stores)).To(Equal([]string{"2", "6", "8"}))
+	g.Expect(stores).To(HaveLen(5))
+	g.Expect(stores).To(HaveKey("1"))
+	g.Expect(stores).To(HaveKey("4"))
+	g.Expect(stores).To(HaveKey("5"))
+	g.Expect(stores).To(HaveKey("7"))
+	g.Expect(stores).To(HaveKey("9"))
+}
+
+func TestTiKVMemberManagerOfflineReregisteredStores(t *testing.T) {
+	g := NewGomegaWithT(t)
 	tests := []struct {
-		name      string
-		tc        v1alpha1.TikvCluster
-		svcConfig SvcConfig
-		expected  corev1.Service
+		name         string
+		paused       bool
+		reregistered []string
+		expect       []uint64
 	}{
 		{
-			name: "basic",
-			tc: v1alpha1.TikvCluster{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "foo",
-					Namespace: "ns",
-				},
-			},
-			svcConfig: SvcConfig{
-				Name:       "peer",
-				Port:       20160,
-				Headless:   true,
-				SvcLabel:   func(l label.Label) label.Label { return l.TiKV() },
-				MemberName: controller.TiKVPeerMemberName,
-			},
-			expected: corev1.Service{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "foo-tikv-peer",
-					Namespace: "ns",
-					Labels: map[string]string{
-						"app.kubernetes.io/name":       "tikv-cluster",
+			name: "no reregistered stores",
+		},
+		{
+			name:         "offline the stale stores",
+			reregistered: []string{"2", "6"},
+			expect:       []uint64{2, 6},
+		},
+		{
+			name:         "the stale stores of a paused cluster are kept",
+			paused:       true,
+			reregistered: []string{"2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.Paused = tt.paused
+			tc.Status.TiKV.ReregisteredStores = tt.reregistered
+			tkmm, _, _, pdClient, _, _ := newFakeTiKVMemberManager(tc)
+			deleted := []uint64{}
+			pdClient.AddReaction(pdapi.DeleteStoreActionType, func(action *pdapi.Action) (interface{}, error) {
+				deleted = append(deleted, action.ID)
+				return nil, nil
+			})
+
+			g.Expect(tkmm.offlineReregisteredStores(tc)).To(Succeed())
+			g.Expect(deleted).To(ConsistOf(tt.expect))
+		})
+	}
+}
+
+func TestTiKVMemberManagerSyncTiKVRevisionStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tkmm, _, _, _, podIndexer, _ := newFakeTiKVMemberManager(tc)
+	set := &apps.StatefulSet{}
+	set.Status.CurrentRevision = "v1"
+	set.Status.UpdateRevision = "v2"
+	for ordinal, revision := range []string{"v2", "v1", "v1", ""} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      TikvPodName(tc.GetName(), int32(ordinal)),
+				Namespace: tc.GetNamespace(),
+				Labels:    label.New().Instance(tc.GetInstanceName()).TiKV().Labels(),
+			},
+		}
+		if revision != "" {
+			pod.Labels[apps.ControllerRevisionHashLabelKey] = revision
+		}
+		g.Expect(podIndexer.Add(pod)).To(Succeed())
+	}
+
+	g.Expect(tkmm.syncTiKVRevisionStatus(tc, set)).To(Succeed())
+	g.Expect(tc.Status.TiKV.CurrentRevision).To(Equal("v1"))
+	g.Expect(tc.Status.TiKV.UpdateRevision).To(Equal("v2"))
+	g.Expect(tc.Status.TiKV.CurrentRevisionPods).To(Equal(int32(2)))
+	g.Expect(tc.Status.TiKV.UpdateRevisionPods).To(Equal(int32(1)))
+	g.Expect(tc.Status.TiKV.PodRevisions).To(Equal(map[string]string{
+		TikvPodName(tc.GetName(), 0): "v2",
+		TikvPodName(tc.GetName(), 1): "v1",
+		TikvPodName(tc.GetName(), 2): "v1",
+	}))
+}
+
+func TestTiKVMemberManagerCheckPinnedNodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	type testcase struct {
+		name           string
+		pinned         string
+		podNodes       map[int32]string
+		reported       []string
+		expectUnpinned []string
+		expectEvents   int
+	}
+	testFn := func(test *testcase, t *testing.T) {
+		t.Log(test.name)
+		tc := newTikvClusterForPD()
+		if test.pinned != "" {
+			tc.Annotations = map[string]string{label.AnnTiKVPinnedNodes: test.pinned}
+		}
+		tc.Status.TiKV.UnpinnedPods = test.reported
+		tkmm, _, _, _, podIndexer, _ := newFakeTiKVMemberManager(tc)
+		recorder := record.NewFakeRecorder(10)
+		tkmm.recorder = recorder
+		for ordinal, nodeName := range test.podNodes {
+			podIndexer.Add(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      TikvPodName(tc.GetName(), ordinal),
+					Namespace: tc.GetNamespace(),
+				},
+				Spec: corev1.PodSpec{NodeName: nodeName},
+			})
+		}
+		tkmm.checkPinnedNodes(tc)
+		g.Expect(tc.Status.TiKV.UnpinnedPods).To(Equal(test.expectUnpinned))
+		g.Expect(collectEvents(recorder.Events)).To(HaveLen(test.expectEvents))
+	}
+	tests := []testcase{
+		{
+			name:           "no pins",
+			podNodes:       map[int32]string{0: "node-1"},
+			expectUnpinned: nil,
+		},
+		{
+			name:           "pods run on the pinned nodes",
+			pinned:         `{"0":"node-1","1":"node-2"}`,
+			podNodes:       map[int32]string{0: "node-1", 1: "node-2"},
+			expectUnpinned: nil,
+		},
+		{
+			name:           "pods do not run on the pinned nodes",
+			pinned:         `{"0":"node-1","1":"node-2","2":"node-3"}`,
+			podNodes:       map[int32]string{0: "node-2", 1: "node-2", 2: ""},
+			expectUnpinned: []string{TikvPodName("test", 0)},
+			expectEvents:   1,
+		},
+		{
+			name:           "unpinned pods are already reported",
+			pinned:         `{"0":"node-1"}`,
+			podNodes:       map[int32]string{0: "node-2"},
+			reported:       []string{TikvPodName("test", 0)},
+			expectUnpinned: []string{TikvPodName("test", 0)},
+			expectEvents:   0,
+		},
+		{
+			name:           "reported pods are recreated on the pinned nodes",
+			pinned:         `{"0":"node-1"}`,
+			podNodes:       map[int32]string{0: "node-1"},
+			reported:       []string{TikvPodName("test", 0)},
+			expectUnpinned: nil,
+			expectEvents:   0,
+		},
+		{
+			name:           "invalid annotation",
+			pinned:         `{"0":`,
+			podNodes:       map[int32]string{0: "node-2"},
+			expectUnpinned: nil,
+			expectEvents:   0,
+		},
+	}
+	for i := range tests {
+		testFn(&tests[i], t)
+	}
+}
+
+func newFakeTiKVMemberManager(tc *v1alpha1.TikvCluster) (
+	*tikvMemberManager, *controller.FakeStatefulSetControl,
+	*controller.FakeServiceControl, *pdapi.FakePDClient, cache.Indexer, cache.Indexer) {
+	cli := fake.NewSimpleClientset()
+	kubeCli := kubefake.NewSimpleClientset()
+	pdControl := pdapi.NewFakePDControl(kubeCli)
+	pdClient := controller.NewFakePDClient(pdControl, tc)
+	pdClient.AddReaction(pdapi.GetClusterActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &metapb.Cluster{Id: uint64(1)}, nil
+	})
+	setInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Apps().V1().StatefulSets()
+	svcInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Services()
+	epsInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Endpoints()
+	tcInformer := informers.NewSharedInformerFactory(cli, 0).Tikv().V1alpha1().TikvClusters()
+	setControl := controller.NewFakeStatefulSetControl(setInformer, tcInformer)
+	svcControl := controller.NewFakeServiceControl(svcInformer, epsInformer, tcInformer)
+	podInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Pods()
+	nodeInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Nodes()
+	pvcInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().PersistentVolumeClaims()
+	cmInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().ConfigMaps()
+	tikvScaler := NewFakeTiKVScaler()
+	tikvUpgrader := NewFakeTiKVUpgrader()
+	genericControl := controller.NewFakeGenericControl()
+
+	tmm := &tikvMemberManager{
+		pdControl:    pdControl,
+		tikvControl:  tikvapi.NewFakeTiKVControl(kubeCli),
+		podLister:    podInformer.Lister(),
+		nodeLister:   nodeInformer.Lister(),
+		pvcLister:    pvcInformer.Lister(),
+		cmLister:     cmInformer.Lister(),
+		setControl:   setControl,
+		svcControl:   svcControl,
+		podControl:   controller.NewFakePodControl(podInformer),
+		typedControl: controller.NewTypedControl(genericControl),
+		setLister:    setInformer.Lister(),
+		svcLister:    svcInformer.Lister(),
+		tikvScaler:   tikvScaler,
+		tikvUpgrader: tikvUpgrader,
+		notifier:     notification.NewFakeNotifier(),
+		recorder:     record.NewFakeRecorder(100),
+	}
+	tmm.tikvStatefulSetIsUpgradingFn = tikvStatefulSetIsUpgrading
+	return tmm, setControl, svcControl, pdClient, podInformer.Informer().GetIndexer(), nodeInformer.Informer().GetIndexer()
+}
+
+func TestGetNewServiceForTikvCluster(t *testing.T) {
+	tests := []struct {
+		name      string
+		tc        v1alpha1.TikvCluster
+		svcConfig SvcConfig
+		expected  corev1.Service
+	}{
+		{
+			name: "basic",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "ns",
+				},
+			},
+			svcConfig: SvcConfig{
+				Name:                     "peer",
+				Port:                     20160,
+				Headless:                 true,
+				SvcLabel:                 func(l label.Label) label.Label { return l.TiKV() },
+				MemberName:               controller.TiKVPeerMemberName,
+				PublishNotReadyAddresses: true,
+			},
+			expected: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-tikv-peer",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":       "tikv-cluster",
 						"app.kubernetes.io/managed-by": "tikv-operator",
 						"app.kubernetes.io/instance":   "foo",
 						"app.kubernetes.io/component":  "tikv",
@@ -1536,6 +2749,64 @@ func TestGetNewServiceForTikvCluster(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "status",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "ns",
+				},
+			},
+			svcConfig: SvcConfig{
+				Name:       "status",
+				Port:       20180,
+				SvcLabel:   func(l label.Label) label.Label { return l.TiKV() },
+				MemberName: controller.TiKVMemberName,
+			},
+			expected: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-tikv",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":       "tikv-cluster",
+						"app.kubernetes.io/managed-by": "tikv-operator",
+						"app.kubernetes.io/instance":   "foo",
+						"app.kubernetes.io/component":  "tikv",
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: "tikv.org/v1alpha1",
+							Kind:       "TikvCluster",
+							Name:       "foo",
+							UID:        "",
+							Controller: func(b bool) *bool {
+								return &b
+							}(true),
+							BlockOwnerDeletion: func(b bool) *bool {
+								return &b
+							}(true),
+						},
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type: corev1.ServiceTypeClusterIP,
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "status",
+							Port:       20180,
+							TargetPort: intstr.FromInt(20180),
+							Protocol:   corev1.ProtocolTCP,
+						},
+					},
+					Selector: map[string]string{
+						"app.kubernetes.io/name":       "tikv-cluster",
+						"app.kubernetes.io/managed-by": "tikv-operator",
+						"app.kubernetes.io/instance":   "foo",
+						"app.kubernetes.io/component":  "tikv",
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1616,6 +2887,22 @@ func TestGetNewTiKVSetForTikvCluster(t *testing.T) {
 			},
 			testSts: testAnnotations(t, map[string]string{"delete-slots": "[0,1]"}),
 		},
+		{
+			name: "tikv restarted at",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+					Annotations: map[string]string{
+						label.AnnTiKVRestartedAt: "2020-06-01T00:00:00Z",
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				g.Expect(sts.Spec.Template.Annotations).To(HaveKeyWithValue(label.AnnTiKVRestartedAt, "2020-06-01T00:00:00Z"))
+			},
+		},
 		{
 			name: "tikv should respect resources config",
 			tc: v1alpha1.TikvCluster{
@@ -1676,6 +2963,202 @@ func TestGetNewTiKVSetForTikvCluster(t *testing.T) {
 				}), "Expected the CAPACITY of tikv is properly set")
 			},
 		},
+		{
+			name: "tikv tls cluster with default secret and mount path",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TLSCluster: &v1alpha1.TLSCluster{Enabled: true},
+				},
+			},
+			testSts: testTLSClusterVolume(t, "tc-tikv-cluster-secret", "/var/lib/tikv-tls"),
+		},
+		{
+			name: "tikv tls cluster with custom secret and mount path",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TLSCluster: &v1alpha1.TLSCluster{
+						Enabled:    true,
+						SecretName: "my-cert",
+						MountPath:  "/etc/certs",
+					},
+				},
+			},
+			testSts: testTLSClusterVolume(t, "my-cert", "/etc/certs"),
+		},
+		{
+			name: "tikv container security context",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TiKV: v1alpha1.TiKVSpec{
+						ContainerSecurityContext: &corev1.SecurityContext{
+							RunAsNonRoot:           &enable,
+							ReadOnlyRootFilesystem: &enable,
+							Capabilities: &corev1.Capabilities{
+								Drop: []corev1.Capability{"ALL"},
+							},
+						},
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				tikvContainer := MapContainers(&sts.Spec.Template.Spec)[v1alpha1.TiKVMemberType.String()]
+				g.Expect(*tikvContainer.SecurityContext.RunAsNonRoot).To(BeTrue())
+				g.Expect(*tikvContainer.SecurityContext.ReadOnlyRootFilesystem).To(BeTrue())
+				g.Expect(tikvContainer.SecurityContext.Capabilities.Drop).To(Equal([]corev1.Capability{"ALL"}))
+				g.Expect(*tikvContainer.SecurityContext.Privileged).To(BeFalse())
+			},
+		},
+		{
+			name: "tikv privileged flag overrides the container security context",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TiKV: v1alpha1.TiKVSpec{
+						Privileged: &enable,
+						ContainerSecurityContext: &corev1.SecurityContext{
+							RunAsNonRoot: &enable,
+						},
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				tikvContainer := MapContainers(&sts.Spec.Template.Spec)[v1alpha1.TiKVMemberType.String()]
+				g.Expect(*tikvContainer.SecurityContext.RunAsNonRoot).To(BeTrue())
+				g.Expect(*tikvContainer.SecurityContext.Privileged).To(BeTrue())
+			},
+		},
+		{
+			name: "tikv extra pod labels",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					Labels: map[string]string{
+						"team":        "storage",
+						"cost-center": "db",
+					},
+					TiKV: v1alpha1.TiKVSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							Labels: map[string]string{
+								"cost-center":           "tikv",
+								label.ComponentLabelKey: "other",
+							},
+						},
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				podLabels := sts.Spec.Template.Labels
+				g.Expect(podLabels["team"]).To(Equal("storage"))
+				g.Expect(podLabels["cost-center"]).To(Equal("tikv"))
+				g.Expect(podLabels[label.ComponentLabelKey]).To(Equal(label.TiKVLabelVal))
+				g.Expect(sts.Spec.Selector.MatchLabels).NotTo(HaveKey("team"))
+				g.Expect(sts.Spec.Selector.MatchLabels).NotTo(HaveKey("cost-center"))
+				g.Expect(sts.Labels).NotTo(HaveKey("team"))
+			},
+		},
+		{
+			name: "tikv start script at the default path",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+			},
+			testSts: testStartScript(t, "/usr/local/bin", "tikv_start_script.sh", nil,
+				[]string{"/bin/sh", "/usr/local/bin/tikv_start_script.sh"}),
+		},
+		{
+			name: "tikv capacity overridden by the storage capacity",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TiKV: v1alpha1.TiKVSpec{
+						ResourceRequirements: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{
+								corev1.ResourceStorage: resource.MustParse("100Gi"),
+							},
+						},
+						StorageCapacity: resource.NewQuantity(90*1024*1024*1024, resource.BinarySI),
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				g.Expect(sts.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "CAPACITY", Value: "90GB"}))
+			},
+		},
+		{
+			name: "tikv peer service name overridden",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TiKV: v1alpha1.TiKVSpec{
+						PeerServiceName: "tikv-dns",
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				g.Expect(sts.Spec.ServiceName).To(Equal("tikv-dns"))
+				g.Expect(sts.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "HEADLESS_SERVICE_NAME", Value: "tikv-dns"}))
+			},
+		},
+		{
+			name: "tikv timezone defaults to UTC",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				g.Expect(sts.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "TZ", Value: "UTC"}))
+			},
+		},
+		{
+			name: "tikv start script at a custom path and executable",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TiKV: v1alpha1.TiKVSpec{
+						StartScriptPath: "/opt/tikv/start.sh",
+						StartScriptMode: pointer.Int32Ptr(0755),
+					},
+				},
+			},
+			testSts: testStartScript(t, "/opt/tikv", "start.sh", pointer.Int32Ptr(0755), []string{"/opt/tikv/start.sh"}),
+		},
 		// TODO add more tests
 	}
 
@@ -1690,6 +3173,49 @@ func TestGetNewTiKVSetForTikvCluster(t *testing.T) {
 	}
 }
 
+func testStartScript(t *testing.T, mountPath string, file string, mode *int32, command []string) func(sts *apps.StatefulSet) {
+	return func(sts *apps.StatefulSet) {
+		g := NewGomegaWithT(t)
+		podSpec := sts.Spec.Template.Spec
+		var scriptVolume *corev1.Volume
+		for i := range podSpec.Volumes {
+			if podSpec.Volumes[i].Name == "startup-script" {
+				scriptVolume = &podSpec.Volumes[i]
+			}
+		}
+		g.Expect(scriptVolume).NotTo(BeNil())
+		g.Expect(scriptVolume.ConfigMap.Items).To(Equal([]corev1.KeyToPath{{Key: "startup-script", Path: file}}))
+		g.Expect(scriptVolume.ConfigMap.DefaultMode).To(Equal(mode))
+		tikvContainer := podSpec.Containers[0]
+		g.Expect(tikvContainer.Command).To(Equal(command))
+		g.Expect(tikvContainer.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "startup-script", ReadOnly: true, MountPath: mountPath}))
+	}
+}
+
+func testTLSClusterVolume(t *testing.T, secretName string, mountPath string) func(sts *apps.StatefulSet) {
+	return func(sts *apps.StatefulSet) {
+		g := NewGomegaWithT(t)
+		var secretVolume *corev1.Volume
+		for i := range sts.Spec.Template.Spec.Volumes {
+			if sts.Spec.Template.Spec.Volumes[i].Name == "tikv-tls" {
+				secretVolume = &sts.Spec.Template.Spec.Volumes[i]
+			}
+		}
+		g.Expect(secretVolume).NotTo(BeNil())
+		g.Expect(secretVolume.Secret.SecretName).To(Equal(secretName))
+
+		var mount *corev1.VolumeMount
+		tikvContainer := MapContainers(&sts.Spec.Template.Spec)[v1alpha1.TiKVMemberType.String()]
+		for i := range tikvContainer.VolumeMounts {
+			if tikvContainer.VolumeMounts[i].Name == "tikv-tls" {
+				mount = &tikvContainer.VolumeMounts[i]
+			}
+		}
+		g.Expect(mount).NotTo(BeNil())
+		g.Expect(mount.MountPath).To(Equal(mountPath))
+	}
+}
+
 func TestTiKVInitContainers(t *testing.T) {
 	privileged := true
 	asRoot := false
@@ -2032,6 +3558,124 @@ func TestGetTiKVConfigMap(t *testing.T) {
 [raftstore]
   sync-log = false
   raft-base-tick-interval = "1s"
+`,
+				},
+			},
+		},
+		{
+			name: "tls paths follow the custom mount path",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TiKV: v1alpha1.TiKVSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							ConfigUpdateStrategy: &updateStrategy,
+						},
+						Config: &v1alpha1.TiKVConfig{},
+					},
+					TLSCluster: &v1alpha1.TLSCluster{
+						Enabled:   true,
+						MountPath: "/etc/certs",
+					},
+				},
+			},
+			expected: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-tikv",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":       "tikv-cluster",
+						"app.kubernetes.io/managed-by": "tikv-operator",
+						"app.kubernetes.io/instance":   "foo",
+						"app.kubernetes.io/component":  "tikv",
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: "tikv.org/v1alpha1",
+							Kind:       "TikvCluster",
+							Name:       "foo",
+							UID:        "",
+							Controller: func(b bool) *bool {
+								return &b
+							}(true),
+							BlockOwnerDeletion: func(b bool) *bool {
+								return &b
+							}(true),
+						},
+					},
+				},
+				Data: map[string]string{
+					"startup-script": "",
+					"config-file": `[security]
+  ca-path = "/etc/certs/ca.crt"
+  cert-path = "/etc/certs/tls.crt"
+  key-path = "/etc/certs/tls.key"
+`,
+				},
+			},
+		},
+		{
+			name: "config filled by the sizing profile",
+			tc: v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TikvClusterSpec{
+					TiKV: v1alpha1.TiKVSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							ConfigUpdateStrategy: &updateStrategy,
+						},
+						SizingProfile: v1alpha1.SizingProfileMedium,
+						Config: &v1alpha1.TiKVConfig{
+							Raftstore: &v1alpha1.TiKVRaftstoreConfig{
+								StorePoolSize: pointer.Int64Ptr(3),
+							},
+						},
+					},
+				},
+			},
+			expected: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-tikv",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":       "tikv-cluster",
+						"app.kubernetes.io/managed-by": "tikv-operator",
+						"app.kubernetes.io/instance":   "foo",
+						"app.kubernetes.io/component":  "tikv",
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: "tikv.org/v1alpha1",
+							Kind:       "TikvCluster",
+							Name:       "foo",
+							UID:        "",
+							Controller: func(b bool) *bool {
+								return &b
+							}(true),
+							BlockOwnerDeletion: func(b bool) *bool {
+								return &b
+							}(true),
+						},
+					},
+				},
+				Data: map[string]string{
+					"startup-script": "",
+					"config-file": `[storage]
+  [storage.block-cache]
+    capacity = "12533MB"
+
+[raftstore]
+  apply-pool-size = 2
+  store-pool-size = 3
+
+[readpool]
+  [readpool.unified]
+    max-thread-count = 6
 `,
 				},
 			},
@@ -2054,3 +3698,196 @@ func TestGetTiKVConfigMap(t *testing.T) {
 		})
 	}
 }
+
+func TestTiKVConfigMapReserveSpace(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.Config = nil
+	tc.Spec.TiKV.Requests[corev1.ResourceStorage] = resource.MustParse("100Gi")
+	tc.Spec.TiKV.ReserveSpacePercent = pointer.Int32Ptr(10)
+
+	cm, err := getTikVConfigMap(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cm).NotTo(BeNil())
+	g.Expect(cm.Data["config-file"]).To(ContainSubstring("[storage]\n  reserve-space = \"10240MB\"\n"))
+}
+
+func TestTiKVConfigFiles(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.Config = &v1alpha1.TiKVConfig{}
+	tc.Spec.TiKV.ConfigFiles = map[string]string{
+		"pd.toml": "[replication]\n",
+		"ca.crt":  "-----BEGIN CERTIFICATE-----\n",
+	}
+
+	cm, err := getTikVConfigMap(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cm.Data).To(HaveKeyWithValue("pd.toml", "[replication]\n"))
+	g.Expect(cm.Data).To(HaveKeyWithValue("ca.crt", "-----BEGIN CERTIFICATE-----\n"))
+
+	set, err := getNewTiKVSetForTikvCluster(tc, cm)
+	g.Expect(err).NotTo(HaveOccurred())
+	var items []corev1.KeyToPath
+	for _, vol := range set.Spec.Template.Spec.Volumes {
+		if vol.Name == "config" {
+			items = vol.ConfigMap.Items
+		}
+	}
+	g.Expect(items).To(Equal([]corev1.KeyToPath{
+		{Key: "config-file", Path: "tikv.toml"},
+		{Key: "ca.crt", Path: "ca.crt"},
+		{Key: "pd.toml", Path: "pd.toml"},
+	}))
+}
+
+func TestTiKVMemberManagerCleanStaleConfigMaps(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tc.UID = types.UID("test")
+	tkmm, _, _, _, podIndexer, _ := newFakeTiKVMemberManager(tc)
+
+	newCm := func(name string, owned bool) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: tc.GetNamespace(),
+			Labels:    label.New().Instance(tc.GetInstanceName()).TiKV().Labels(),
+		}}
+		if owned {
+			cm.OwnerReferences = []metav1.OwnerReference{controller.GetOwnerRef(tc)}
+		}
+		return cm
+	}
+	cms := []*corev1.ConfigMap{
+		newCm("test-tikv-0a1b2c3", true),
+		newCm("test-tikv-1a1b2c3", true),
+		newCm("test-tikv-2a1b2c3", true),
+		newCm("test-tikv-3a1b2c3", false),
+		newCm("test-tikv-startup", true),
+	}
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	var objects []runtime.Object
+	for _, cm := range cms {
+		g.Expect(cmIndexer.Add(cm)).To(Succeed())
+		objects = append(objects, cm)
+	}
+	tkmm.cmLister = corelisters.NewConfigMapLister(cmIndexer)
+	genericControl := controller.NewFakeGenericControl(objects...)
+	tkmm.typedControl = controller.NewTypedControl(genericControl)
+	podIndexer.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TikvPodName(tc.GetName(), 0),
+			Namespace: tc.GetNamespace(),
+			Labels:    label.New().Instance(tc.GetInstanceName()).TiKV().Labels(),
+		},
+		Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "test-tikv-2a1b2c3"},
+			}},
+		}}},
+	})
+
+	tkmm.cleanStaleConfigMaps(tc, cms[0])
+	for name, expectExist := range map[string]bool{
+		// in use
+		"test-tikv-0a1b2c3": true,
+		// stale
+		"test-tikv-1a1b2c3": false,
+		// mounted by a pod
+		"test-tikv-2a1b2c3": true,
+		// not owned by the cluster
+		"test-tikv-3a1b2c3": true,
+		// not suffixed by a digest
+		"test-tikv-startup": true,
+	} {
+		exist, err := tkmm.typedControl.Exist(client.ObjectKey{Namespace: tc.GetNamespace(), Name: name}, &corev1.ConfigMap{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(exist).To(Equal(expectExist), name)
+	}
+}
+
+func TestRecordStoreLabelsSet(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	defer metrics.DeleteTikvCluster(tc.GetNamespace(), tc.GetName())
+
+	recordStoreLabelsSet(tc, 2)
+	g.Expect(tc.Status.TiKV.StoreLabelsSet).To(Equal(int32(2)))
+	g.Expect(tc.Status.TiKV.LastStoreLabelsSetTime).NotTo(BeNil())
+	lastSetTime := tc.Status.TiKV.LastStoreLabelsSetTime
+
+	// the count is reset but the last set time is kept once the labels are in sync
+	recordStoreLabelsSet(tc, 0)
+	g.Expect(tc.Status.TiKV.StoreLabelsSet).To(Equal(int32(0)))
+	g.Expect(tc.Status.TiKV.LastStoreLabelsSetTime).To(Equal(lastSetTime))
+
+	recordStoreLabelsSet(tc, 1)
+	g.Expect(testutil.ToFloat64(metrics.TiKVStoreLabelsSet.WithLabelValues(tc.GetNamespace(), tc.GetName()))).To(Equal(float64(3)))
+}
+
+func TestTiKVMemberManagerSyncScalingOutPods(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.Replicas = 3
+	tc.Status.TiKV.ScalingOutPods = []string{"test-tikv-1", "test-tikv-2", "test-tikv-5"}
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", PodName: "test-tikv-0", State: v1alpha1.TiKVStateUp},
+		"2": {ID: "2", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp},
+		"3": {ID: "3", PodName: "test-tikv-2", State: v1alpha1.TiKVStateDown},
+	}
+	tkmm, _, _, _, _, _ := newFakeTiKVMemberManager(tc)
+	recorder := record.NewFakeRecorder(10)
+	tkmm.recorder = recorder
+
+	tkmm.syncScalingOutPods(tc)
+	g.Expect(tc.Status.TiKV.ScalingOutPods).To(Equal([]string{"test-tikv-2"}))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("store 2 of pod test-tikv-1 is up")))
+	g.Expect(recorder.Events).NotTo(Receive())
+}
+
+func TestSyncScalePhase(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		setReplicas    int32
+		scalingOutPods []string
+		expectPhase    v1alpha1.ScalePhase
+	}{
+		{
+			name:        "not scaling",
+			setReplicas: 3,
+			expectPhase: v1alpha1.ScaleIdlePhase,
+		},
+		{
+			name:        "scaling out",
+			setReplicas: 2,
+			expectPhase: v1alpha1.ScaleOutPhase,
+		},
+		{
+			name:           "waiting for the store of the pod added to be up",
+			setReplicas:    3,
+			scalingOutPods: []string{"test-tikv-2"},
+			expectPhase:    v1alpha1.ScaleOutPhase,
+		},
+		{
+			name:        "waiting for the store of the pod removed to be tombstone",
+			setReplicas: 4,
+			expectPhase: v1alpha1.ScaleInPhase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.TiKV.Replicas = 3
+			tc.Status.TiKV.ScalingOutPods = tt.scalingOutPods
+			set := &apps.StatefulSet{Spec: apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(tt.setReplicas)}}
+
+			syncScalePhase(tc, set)
+			g.Expect(tc.Status.TiKV.ScalePhase).To(Equal(tt.expectPhase))
+			g.Expect(tc.Status.TiKV.CurrentReplicas).To(Equal(tt.setReplicas))
+			g.Expect(tc.Status.TiKV.DesiredReplicas).To(Equal(int32(3)))
+			g.Expect(tc.TiKVScaling()).To(Equal(tt.expectPhase != v1alpha1.ScaleIdlePhase))
+		})
+	}
+}
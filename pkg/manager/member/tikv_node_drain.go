@@ -0,0 +1,121 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"strconv"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// taintNodeUnschedulable is the taint added by the node controller to the cordoned nodes
+const taintNodeUnschedulable = "node.kubernetes.io/unschedulable"
+
+// IsNodeDraining returns whether the node is cordoned, which is the first step of draining it
+func IsNodeDraining(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == taintNodeUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+// syncNodeDrainLeaderEviction evicts the leaders of the stores whose nodes are being drained, so the pods
+// can be killed without making the regions unavailable until their leases expire. The evictions are
+// ended once the node is uncordoned or the pod is moved to another node.
+func (tkmm *tikvMemberManager) syncNodeDrainLeaderEviction(tc *v1alpha1.TikvCluster) error {
+	if !controller.NodeDrainLeaderEviction || tc.Spec.Paused {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	logger := tikvLogger(tc)
+	if tc.Status.TiKV.NodeDrainEvictions == nil {
+		tc.Status.TiKV.NodeDrainEvictions = map[string]string{}
+	}
+	evictions := tc.Status.TiKV.NodeDrainEvictions
+
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	for id, store := range tc.Status.TiKV.Stores {
+		node, err := tkmm.getPodNode(ns, store.PodName)
+		if err != nil {
+			return err
+		}
+		// keep evicting while the drained pod is being recreated
+		if node == nil {
+			continue
+		}
+		draining := IsNodeDraining(node)
+		_, evicting := evictions[id]
+		if draining == evicting {
+			continue
+		}
+		storeID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		if draining {
+			nodeName := node.GetName()
+			if err := pdCli.BeginEvictLeader(storeID); err != nil {
+				return err
+			}
+			evictions[id] = nodeName
+			logger.Infof("node %s is being drained, begin to evict leaders of store %s", nodeName, id)
+			tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "EvictingLeaders",
+				"node %s of pod %s is being drained, evicting the leaders of store %s", nodeName, store.PodName, id)
+			continue
+		}
+		// the leaders of the cordoned stores are kept evicted
+		if _, cordoned := tc.Status.TiKV.CordonedStores[id]; !cordoned {
+			if err := pdCli.EndEvictLeader(storeID); err != nil {
+				return err
+			}
+		}
+		delete(evictions, id)
+		logger.Infof("pod %s is not on a draining node, end evicting leaders of store %s", store.PodName, id)
+	}
+
+	// the stores removed from the cluster are not tracked anymore
+	for id := range evictions {
+		if _, ok := tc.Status.TiKV.Stores[id]; !ok {
+			delete(evictions, id)
+		}
+	}
+	return nil
+}
+
+// getPodNode returns the node that the pod is running on, nil if the pod or the node is not found
+func (tkmm *tikvMemberManager) getPodNode(ns, podName string) (*corev1.Node, error) {
+	pod, err := tkmm.podLister.Pods(ns).Get(podName)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pod.Spec.NodeName == "" {
+		return nil, nil
+	}
+	node, err := tkmm.nodeLister.Get(pod.Spec.NodeName)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return node, err
+}
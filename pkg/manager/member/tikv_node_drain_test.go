@@ -0,0 +1,154 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsNodeDraining(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name   string
+		spec   corev1.NodeSpec
+		expect bool
+	}{
+		{
+			name:   "schedulable",
+			expect: false,
+		},
+		{
+			name:   "cordoned",
+			spec:   corev1.NodeSpec{Unschedulable: true},
+			expect: true,
+		},
+		{
+			name:   "unschedulable taint",
+			spec:   corev1.NodeSpec{Taints: []corev1.Taint{{Key: taintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}}},
+			expect: true,
+		},
+		{
+			name:   "other taints",
+			spec:   corev1.NodeSpec{Taints: []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}}},
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g.Expect(IsNodeDraining(&corev1.Node{Spec: tt.spec})).To(Equal(tt.expect))
+		})
+	}
+}
+
+func TestTiKVMemberManagerSyncNodeDrainLeaderEviction(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name          string
+		disabled      bool
+		paused        bool
+		unschedulable []bool
+		evictions     map[string]string
+		expectBegin   []uint64
+		expectEnd     []uint64
+		expect        map[string]string
+	}{
+		{
+			name:          "disabled",
+			disabled:      true,
+			unschedulable: []bool{true, false, false},
+			expect:        nil,
+		},
+		{
+			name:          "paused",
+			paused:        true,
+			unschedulable: []bool{true, false, false},
+			expect:        nil,
+		},
+		{
+			name:          "begin evicting leaders on the draining node",
+			unschedulable: []bool{true, false, false},
+			expectBegin:   []uint64{1},
+			expect:        map[string]string{"1": "node-0"},
+		},
+		{
+			name:          "already evicting",
+			unschedulable: []bool{true, false, false},
+			evictions:     map[string]string{"1": "node-0"},
+			expect:        map[string]string{"1": "node-0"},
+		},
+		{
+			name:          "end evicting leaders on the uncordoned node",
+			unschedulable: []bool{false, false, false},
+			evictions:     map[string]string{"1": "node-0", "4": "node-3"},
+			expectEnd:     []uint64{1},
+			expect:        map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(enabled bool) {
+				controller.NodeDrainLeaderEviction = enabled
+			}(controller.NodeDrainLeaderEviction)
+			controller.NodeDrainLeaderEviction = !tt.disabled
+
+			tc := newTikvClusterForPD()
+			tc.Spec.Paused = tt.paused
+			tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{}
+			tc.Status.TiKV.NodeDrainEvictions = tt.evictions
+			tkmm, _, _, pdClient, podIndexer, nodeIndexer := newFakeTiKVMemberManager(tc)
+			for i, unschedulable := range tt.unschedulable {
+				podName := TikvPodName(tc.GetName(), int32(i))
+				nodeName := fmt.Sprintf("node-%d", i)
+				storeID := strconv.Itoa(i + 1)
+				tc.Status.TiKV.Stores[storeID] = v1alpha1.TiKVStore{ID: storeID, PodName: podName, State: v1alpha1.TiKVStateUp}
+				g.Expect(podIndexer.Add(&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: tc.GetNamespace()},
+					Spec:       corev1.PodSpec{NodeName: nodeName},
+				})).To(Succeed())
+				g.Expect(nodeIndexer.Add(&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+					Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+				})).To(Succeed())
+			}
+			begin := []uint64{}
+			end := []uint64{}
+			pdClient.AddReaction(pdapi.BeginEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+				begin = append(begin, action.ID)
+				return nil, nil
+			})
+			pdClient.AddReaction(pdapi.EndEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+				end = append(end, action.ID)
+				return nil, nil
+			})
+
+			g.Expect(tkmm.syncNodeDrainLeaderEviction(tc)).To(Succeed())
+			g.Expect(begin).To(ConsistOf(tt.expectBegin))
+			g.Expect(end).To(ConsistOf(tt.expectEnd))
+			if tt.expect == nil {
+				g.Expect(tc.Status.TiKV.NodeDrainEvictions).To(BeNil())
+			} else {
+				g.Expect(tc.Status.TiKV.NodeDrainEvictions).To(Equal(tt.expect))
+			}
+		})
+	}
+}
@@ -2,15 +2,122 @@ package member
 
 import (
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/controller"
 	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	corelisters "k8s.io/client-go/listers/core/v1"
 )
 
+// nodePortExternalIP returns the external IP of the NodePort service of the pod, it is read from the label or
+// the annotation of the node of the pod if the external listener specifies the key, and falls back to the host IP
+func nodePortExternalIP(tc *v1alpha1.TikvCluster, nodeLister corelisters.NodeLister, extListener v1alpha1.ExternalListenerConfig, pod *corev1.Pod) (string, error) {
+	key := extListener.NodeExternalIPKey
+	if key == "" || pod.Spec.NodeName == "" {
+		return pod.Status.HostIP, nil
+	}
+	node, err := nodeLister.Get(pod.Spec.NodeName)
+	if errors.IsNotFound(err) {
+		return pod.Status.HostIP, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	ip, ok := node.Labels[key]
+	if !ok {
+		ip, ok = node.Annotations[key]
+	}
+	if !ok {
+		return pod.Status.HostIP, nil
+	}
+	if net.ParseIP(ip) == nil {
+		tikvLogger(tc).Warningf("node %s has an invalid external IP %q in %s, use the host IP of pod %s instead",
+			node.Name, ip, key, pod.Name)
+		return pod.Status.HostIP, nil
+	}
+	return ip, nil
+}
+
+// podsByOrdinal returns the ordinals of the pods of the statefulset in ascending order along with the pods keyed by
+// them, the ordinal is used as the id of the per-pod external services so that the service of a pod is stable
+// regardless of the order of the lister, the pods not named after the statefulset are ignored
+func podsByOrdinal(pods []*corev1.Pod, setName string) ([]int32, map[int32]*corev1.Pod) {
+	ordinals := []int32{}
+	podMap := map[int32]*corev1.Pod{}
+	for _, pod := range pods {
+		ordinal, err := util.GetOrdinalFromPodName(pod.Name)
+		if err != nil || pod.Name != fmt.Sprintf("%s-%d", setName, ordinal) {
+			continue
+		}
+		if _, ok := podMap[ordinal]; ok {
+			continue
+		}
+		podMap[ordinal] = pod
+		ordinals = append(ordinals, ordinal)
+	}
+	sort.Slice(ordinals, func(i, j int) bool { return ordinals[i] < ordinals[j] })
+	return ordinals, podMap
+}
+
+// preserveNodePorts keeps the node ports assigned by kubernetes to the existing service in the new service for the
+// ports without an explicit node port, e.g. the external starting port is not specified, so that the external
+// clients keep a stable port across reconciles
+func preserveNodePorts(newSvc, oldSvc *corev1.Service) {
+	if !hasNodePorts(newSvc) || !hasNodePorts(oldSvc) {
+		return
+	}
+	for i, port := range newSvc.Spec.Ports {
+		if port.NodePort != 0 {
+			continue
+		}
+		for _, oldPort := range oldSvc.Spec.Ports {
+			if port.Port == oldPort.Port && port.Protocol == oldPort.Protocol {
+				newSvc.Spec.Ports[i].NodePort = oldPort.NodePort
+				break
+			}
+		}
+	}
+}
+
+func hasNodePorts(svc *corev1.Service) bool {
+	return svc.Spec.Type == corev1.ServiceTypeNodePort || svc.Spec.Type == corev1.ServiceTypeLoadBalancer
+}
+
+// validateNodePorts checks the node ports allocated from the starting port of the external listener
+// to the given number of pods are within the service node port range
+func validateNodePorts(extListener v1alpha1.ExternalListenerConfig, count int) error {
+	if extListener.ExternalStartingPort <= 0 {
+		return nil
+	}
+	portRange, err := utilnet.ParsePortRange(controller.ServiceNodePortRange)
+	if err != nil {
+		return fmt.Errorf("invalid service node port range %q: %v", controller.ServiceNodePortRange, err)
+	}
+	invalidPorts := []string{}
+	for id := 0; id < count; id++ {
+		port := int(extListener.ExternalStartingPort) + id
+		if !portRange.Contains(port) {
+			invalidPorts = append(invalidPorts, strconv.Itoa(port))
+		}
+	}
+	if len(invalidPorts) > 0 {
+		return fmt.Errorf("node ports [%s] of external listener %s are out of the service node port range %s",
+			strings.Join(invalidPorts, ", "), extListener.Name, portRange)
+	}
+	return nil
+}
+
 func getNewNodeportServiceForTikvCluster(tc *v1alpha1.TikvCluster, id int32, extListener v1alpha1.ExternalListenerConfig, nodePortExternalIP string, isPD bool) *corev1.Service {
 	var (
 		tcName   = tc.Name
@@ -23,15 +130,17 @@ func getNewNodeportServiceForTikvCluster(tc *v1alpha1.TikvCluster, id int32, ext
 	}
 	if isPD {
 		lbPD := label.New().Instance(tcName).PD().Labels()
+		podName := fmt.Sprintf("%s-%d", controller.PDMemberName(tcName), id)
 		svc = corev1.Service{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:            fmt.Sprintf("%s-pb-%d-%s", tcName, id, extListener.Name),
-				Labels:          MergeLabels(lbPD, map[string]string{"statefulset.kubernetes.io/pod-name": fmt.Sprintf("basic-pd-%d", id)}),
+				Labels:          MergeLabels(lbPD, map[string]string{apps.StatefulSetPodNameLabel: podName}),
 				Namespace:       tc.Namespace,
+				Annotations:     listenerAnnotations(extListener),
 				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
 			},
 			Spec: corev1.ServiceSpec{
-				Selector: MergeLabels(lbPD, map[string]string{"statefulset.kubernetes.io/pod-name": fmt.Sprintf("basic-pd-%d", id)}),
+				Selector: MergeLabels(lbPD, map[string]string{apps.StatefulSetPodNameLabel: podName}),
 				Type:     corev1.ServiceTypeNodePort,
 				Ports: []corev1.ServicePort{
 					{
@@ -42,20 +151,23 @@ func getNewNodeportServiceForTikvCluster(tc *v1alpha1.TikvCluster, id int32, ext
 						Protocol:   corev1.ProtocolTCP,
 					},
 				},
-				ExternalIPs: []string{nodePortExternalIP},
+				ExternalIPs:           []string{nodePortExternalIP},
+				ExternalTrafficPolicy: extListener.GetExternalTrafficPolicy(),
 			},
 		}
 	} else {
 		lbTikv := label.New().Instance(tcName).TiKV().Labels()
+		podName := fmt.Sprintf("%s-%d", controller.TiKVMemberName(tcName), id)
 		svc = corev1.Service{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:            fmt.Sprintf("%s-tikv-%d-%s", tcName, id, extListener.Name),
-				Labels:          MergeLabels(lbTikv, map[string]string{"statefulset.kubernetes.io/pod-name": fmt.Sprintf("basic-tikv-%d", id)}),
+				Labels:          MergeLabels(lbTikv, map[string]string{apps.StatefulSetPodNameLabel: podName}),
 				Namespace:       tc.Namespace,
+				Annotations:     listenerAnnotations(extListener),
 				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
 			},
 			Spec: corev1.ServiceSpec{
-				Selector: MergeLabels(lbTikv, map[string]string{"statefulset.kubernetes.io/pod-name": fmt.Sprintf("basic-tikv-%d", id)}),
+				Selector: MergeLabels(lbTikv, map[string]string{apps.StatefulSetPodNameLabel: podName}),
 				Type:     corev1.ServiceTypeNodePort,
 				Ports: []corev1.ServicePort{
 					{
@@ -66,10 +178,55 @@ func getNewNodeportServiceForTikvCluster(tc *v1alpha1.TikvCluster, id int32, ext
 						Protocol:   corev1.ProtocolTCP,
 					},
 				},
-				ExternalIPs: []string{nodePortExternalIP},
+				ExternalIPs:           []string{nodePortExternalIP},
+				ExternalTrafficPolicy: extListener.GetExternalTrafficPolicy(),
 			},
 		}
 	}
 
 	return &svc
 }
+
+// listenerAnnotations records the security protocol of the external listener on its services for the clients and
+// the load balancers to tell how to connect, TiKV itself is not configured by it. The protocol is required by the
+// validation, so it is never empty.
+func listenerAnnotations(extListener v1alpha1.ExternalListenerConfig) map[string]string {
+	protocol := extListener.Type
+	anns := map[string]string{
+		label.AnnListenerSecurityProtocol: string(protocol),
+	}
+	if protocol.IsSSL() {
+		anns[label.AnnListenerTLSSecret] = extListener.TLSSecretName
+	}
+	return anns
+}
+
+// getNewLoadBalancerServiceForTikvCluster returns the LoadBalancer service exposing the tikv pod of the given ordinal,
+// the service annotations of the listeners config are passed through for the cloud LB controllers
+func getNewLoadBalancerServiceForTikvCluster(tc *v1alpha1.TikvCluster, id int32, extListener v1alpha1.ExternalListenerConfig) *corev1.Service {
+	tcName := tc.Name
+	podName := fmt.Sprintf("%s-%d", controller.TiKVMemberName(tcName), id)
+	lbTikv := label.New().Instance(tcName).TiKV().Labels()
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-tikv-%d-%s", tcName, id, extListener.Name),
+			Labels:          MergeLabels(lbTikv, map[string]string{apps.StatefulSetPodNameLabel: podName}),
+			Namespace:       tc.Namespace,
+			Annotations:     MergeLabels(tc.Spec.TiKV.ListenersConfig.ServiceAnnotations, listenerAnnotations(extListener)),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector:              MergeLabels(lbTikv, map[string]string{apps.StatefulSetPodNameLabel: podName}),
+			Type:                  corev1.ServiceTypeLoadBalancer,
+			ExternalTrafficPolicy: extListener.GetExternalTrafficPolicy(),
+			Ports: []corev1.ServicePort{
+				{
+					Name:       fmt.Sprintf("%s-%d-%s", tcName, id, extListener.Name),
+					Port:       extListener.ContainerPort,
+					TargetPort: intstr.FromInt(int(20160)),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
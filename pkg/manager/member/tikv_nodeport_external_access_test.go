@@ -0,0 +1,291 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetNewNodeportServiceSecurityProtocol(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name       string
+		listener   v1alpha1.ExternalListenerConfig
+		expectAnns map[string]string
+	}{
+		{
+			name: "plaintext",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolPlaintext, Name: "external", ContainerPort: 20160},
+				AccessMethod:       corev1.ServiceTypeNodePort,
+			},
+			expectAnns: map[string]string{
+				label.AnnListenerSecurityProtocol: "plaintext",
+			},
+		},
+		{
+			name: "ssl with tls secret",
+			listener: v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolSSL, Name: "external", ContainerPort: 20160},
+				AccessMethod:       corev1.ServiceTypeNodePort,
+				TLSSecretName:      "external-tls",
+			},
+			expectAnns: map[string]string{
+				label.AnnListenerSecurityProtocol: "ssl",
+				label.AnnListenerTLSSecret:        "external-tls",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+			}
+			for _, isPD := range []bool{true, false} {
+				svc := getNewNodeportServiceForTikvCluster(tc, 0, tt.listener, "10.0.0.1", isPD)
+				g.Expect(svc.Annotations).To(Equal(tt.expectAnns))
+			}
+		})
+	}
+}
+
+func TestGetNewNodeportServiceSelector(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := &v1alpha1.TikvCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "production", Namespace: "ns"},
+	}
+	listener := v1alpha1.ExternalListenerConfig{
+		CommonListenerSpec: v1alpha1.CommonListenerSpec{Name: "external", ContainerPort: 20160},
+		AccessMethod:       corev1.ServiceTypeNodePort,
+	}
+	tests := []struct {
+		name      string
+		isPD      bool
+		expectPod string
+	}{
+		{
+			name:      "tikv",
+			isPD:      false,
+			expectPod: "production-tikv-2",
+		},
+		{
+			name:      "pd",
+			isPD:      true,
+			expectPod: "production-pd-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := getNewNodeportServiceForTikvCluster(tc, 2, listener, "10.0.0.1", tt.isPD)
+			g.Expect(svc.Spec.Selector["statefulset.kubernetes.io/pod-name"]).To(Equal(tt.expectPod))
+			g.Expect(svc.Labels["statefulset.kubernetes.io/pod-name"]).To(Equal(tt.expectPod))
+		})
+	}
+}
+
+func TestGetNewLoadBalancerServiceForTikvCluster(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := &v1alpha1.TikvCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+	}
+	tc.Spec.TiKV.ListenersConfig.ServiceAnnotations = map[string]string{
+		"service.beta.kubernetes.io/aws-load-balancer-type": "nlb",
+	}
+	listener := v1alpha1.ExternalListenerConfig{
+		CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolPlaintext, Name: "external", ContainerPort: 20170},
+	}
+	g.Expect(listener.GetAccessMethod()).To(Equal(corev1.ServiceTypeLoadBalancer))
+
+	svc := getNewLoadBalancerServiceForTikvCluster(tc, 1, listener)
+	g.Expect(svc.Name).To(Equal("demo-tikv-1-external"))
+	g.Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+	g.Expect(svc.Spec.Selector["statefulset.kubernetes.io/pod-name"]).To(Equal("demo-tikv-1"))
+	g.Expect(svc.Spec.Ports).To(HaveLen(1))
+	g.Expect(svc.Spec.Ports[0].Port).To(Equal(int32(20170)))
+	g.Expect(svc.Spec.Ports[0].TargetPort.IntValue()).To(Equal(20160))
+	g.Expect(svc.Annotations).To(Equal(map[string]string{
+		"service.beta.kubernetes.io/aws-load-balancer-type": "nlb",
+		label.AnnListenerSecurityProtocol:                   "plaintext",
+	}))
+}
+
+func TestValidateNodePorts(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name         string
+		startingPort int32
+		count        int
+		portRange    string
+		expectErr    string
+	}{
+		{
+			name:         "dynamic node ports",
+			startingPort: 0,
+			count:        3,
+		},
+		{
+			name:         "node ports within the default range",
+			startingPort: 32765,
+			count:        3,
+		},
+		{
+			name:         "node ports out of the default range",
+			startingPort: 32766,
+			count:        4,
+			expectErr:    "node ports [32768, 32769] of external listener external are out of the service node port range 30000-32767",
+		},
+		{
+			name:         "node ports out of a configured range",
+			startingPort: 30000,
+			count:        2,
+			portRange:    "20000-30000",
+			expectErr:    "node ports [30001] of external listener external are out of the service node port range 20000-30000",
+		},
+		{
+			name:         "invalid node port range",
+			startingPort: 30000,
+			count:        1,
+			portRange:    "invalid",
+			expectErr:    `invalid service node port range "invalid"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(portRange string) {
+				controller.ServiceNodePortRange = portRange
+			}(controller.ServiceNodePortRange)
+			if tt.portRange != "" {
+				controller.ServiceNodePortRange = tt.portRange
+			}
+			listener := v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec:   v1alpha1.CommonListenerSpec{Name: "external", ContainerPort: 20160},
+				AccessMethod:         corev1.ServiceTypeNodePort,
+				ExternalStartingPort: tt.startingPort,
+			}
+			err := validateNodePorts(listener, tt.count)
+			if tt.expectErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.expectErr))
+			}
+		})
+	}
+}
+
+func TestExternalServicesTrafficPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := &v1alpha1.TikvCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+	}
+	tests := []struct {
+		name         string
+		policy       corev1.ServiceExternalTrafficPolicyType
+		expectPolicy corev1.ServiceExternalTrafficPolicyType
+	}{
+		{
+			name:         "default to Cluster",
+			expectPolicy: corev1.ServiceExternalTrafficPolicyTypeCluster,
+		},
+		{
+			name:         "Local",
+			policy:       corev1.ServiceExternalTrafficPolicyTypeLocal,
+			expectPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener := v1alpha1.ExternalListenerConfig{
+				CommonListenerSpec:    v1alpha1.CommonListenerSpec{Name: "external", ContainerPort: 20160},
+				ExternalTrafficPolicy: tt.policy,
+			}
+			svcs := []*corev1.Service{
+				getNewLoadBalancerServiceForTikvCluster(tc, 0, listener),
+				getNewNodeportServiceForTikvCluster(tc, 0, listener, "10.0.0.1", false),
+				getNewNodeportServiceForTikvCluster(tc, 0, listener, "10.0.0.1", true),
+			}
+			for _, svc := range svcs {
+				g.Expect(svc.Spec.ExternalTrafficPolicy).To(Equal(tt.expectPolicy))
+			}
+		})
+	}
+}
+
+func TestNodePortExternalIP(t *testing.T) {
+	g := NewGomegaWithT(t)
+	nodeInformer := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0).Core().V1().Nodes()
+	g.Expect(nodeInformer.Informer().GetIndexer().Add(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-1",
+			Labels:      map[string]string{"example.com/public-ip": "1.2.3.4", "example.com/invalid-ip": "node-1"},
+			Annotations: map[string]string{"example.com/public-ip-annotation": "5.6.7.8"},
+		},
+	})).To(Succeed())
+
+	tests := []struct {
+		name     string
+		key      string
+		nodeName string
+		expectIP string
+	}{
+		{name: "host ip by default", nodeName: "node-1", expectIP: "10.0.0.1"},
+		{name: "node label", key: "example.com/public-ip", nodeName: "node-1", expectIP: "1.2.3.4"},
+		{name: "node annotation", key: "example.com/public-ip-annotation", nodeName: "node-1", expectIP: "5.6.7.8"},
+		{name: "missing key", key: "example.com/missing", nodeName: "node-1", expectIP: "10.0.0.1"},
+		{name: "invalid ip", key: "example.com/invalid-ip", nodeName: "node-1", expectIP: "10.0.0.1"},
+		{name: "missing node", key: "example.com/public-ip", nodeName: "node-2", expectIP: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener := v1alpha1.ExternalListenerConfig{NodeExternalIPKey: tt.key}
+			pod := &corev1.Pod{
+				Spec:   corev1.PodSpec{NodeName: tt.nodeName},
+				Status: corev1.PodStatus{HostIP: "10.0.0.1"},
+			}
+			ip, err := nodePortExternalIP(newTikvClusterForPD(), nodeInformer.Lister(), listener, pod)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(ip).To(Equal(tt.expectIP))
+		})
+	}
+}
+
+func TestPodsByOrdinal(t *testing.T) {
+	g := NewGomegaWithT(t)
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	pods := []*corev1.Pod{
+		newPod("demo-tikv-3"),
+		newPod("demo-tikv-0"),
+		newPod("other-tikv-1"),
+		newPod("demo-tikv-debug"),
+		newPod("demo-tikv-3"),
+		newPod("demo-tikv-1"),
+	}
+	ordinals, podMap := podsByOrdinal(pods, controller.TiKVMemberName("demo"))
+	g.Expect(ordinals).To(Equal([]int32{0, 1, 3}))
+	g.Expect(podMap).To(HaveLen(3))
+	for _, ordinal := range ordinals {
+		g.Expect(podMap[ordinal].Name).To(Equal(TikvPodName("demo", ordinal)))
+	}
+}
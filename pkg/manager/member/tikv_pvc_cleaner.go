@@ -0,0 +1,121 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/manager"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+type tikvPVCCleaner struct {
+	setControl controller.StatefulSetControlInterface
+	pvcControl controller.PVCControlInterface
+	setLister  v1.StatefulSetLister
+	podLister  corelisters.PodLister
+	pvcLister  corelisters.PersistentVolumeClaimLister
+}
+
+// NewTiKVPVCCleaner returns a manager deleting the TiKV PVCs of a TikvCluster being deleted, the statefulset
+// of TiKV is deleted first and the PVCs are deleted once all the TiKV pods are gone, its Sync returns nil
+// once the PVCs are deleted
+func NewTiKVPVCCleaner(
+	setControl controller.StatefulSetControlInterface,
+	pvcControl controller.PVCControlInterface,
+	setLister v1.StatefulSetLister,
+	podLister corelisters.PodLister,
+	pvcLister corelisters.PersistentVolumeClaimLister) manager.Manager {
+	return &tikvPVCCleaner{
+		setControl: setControl,
+		pvcControl: pvcControl,
+		setLister:  setLister,
+		podLister:  podLister,
+		pvcLister:  pvcLister,
+	}
+}
+
+func (pc *tikvPVCCleaner) Sync(tc *v1alpha1.TikvCluster) error {
+	if tc.DeletionTimestamp == nil {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	logger := tikvLogger(tc)
+
+	set, err := pc.setLister.StatefulSets(ns).Get(controller.TiKVMemberName(tcName))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		if set.DeletionTimestamp == nil {
+			if err := pc.setControl.DeleteStatefulSet(tc, set); err != nil {
+				return err
+			}
+			logger.Infof("tikv cluster is being deleted, deleted statefulset %s before deleting the pvcs", set.GetName())
+		}
+		return controller.RequeueErrorf("TikvCluster: [%s/%s] is being deleted, waiting for the tikv statefulset to be deleted", ns, tcName)
+	}
+
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return err
+	}
+	pods, err := pc.podLister.Pods(ns).List(selector)
+	if err != nil {
+		return err
+	}
+	if len(pods) > 0 {
+		return controller.RequeueErrorf("TikvCluster: [%s/%s] is being deleted, waiting for %d tikv pods to be deleted", ns, tcName, len(pods))
+	}
+
+	pvcs, err := pc.pvcLister.PersistentVolumeClaims(ns).List(selector)
+	if err != nil {
+		return err
+	}
+	for _, pvc := range pvcs {
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		if err := pc.pvcControl.DeletePVC(tc, pvc); err != nil {
+			return err
+		}
+		logger.Infof("tikv cluster is being deleted, deleted pvc %s", pvc.GetName())
+	}
+	return nil
+}
+
+var _ manager.Manager = &tikvPVCCleaner{}
+
+type FakeTiKVPVCCleaner struct {
+	err error
+}
+
+// NewFakeTiKVPVCCleaner returns a fake tikv pvc cleaner
+func NewFakeTiKVPVCCleaner() *FakeTiKVPVCCleaner {
+	return &FakeTiKVPVCCleaner{}
+}
+
+func (fpc *FakeTiKVPVCCleaner) SetSyncError(err error) {
+	fpc.err = err
+}
+
+func (fpc *FakeTiKVPVCCleaner) Sync(_ *v1alpha1.TikvCluster) error {
+	return fpc.err
+}
+
+var _ manager.Manager = &FakeTiKVPVCCleaner{}
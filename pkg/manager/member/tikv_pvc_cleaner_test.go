@@ -0,0 +1,118 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned/fake"
+	informers "github.com/tikv/tikv-operator/pkg/client/informers/externalversions"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTiKVPVCCleanerSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name         string
+		deleting     bool
+		setExists    bool
+		pods         int
+		pvcs         int
+		expectErr    bool
+		expectRemain int
+	}{
+		{
+			name:         "not deleting",
+			deleting:     false,
+			pods:         0,
+			pvcs:         3,
+			expectRemain: 3,
+		},
+		{
+			name:         "waiting for the statefulset to be deleted",
+			deleting:     true,
+			setExists:    true,
+			pods:         3,
+			pvcs:         3,
+			expectErr:    true,
+			expectRemain: 3,
+		},
+		{
+			name:         "waiting for the pods to be deleted",
+			deleting:     true,
+			pods:         1,
+			pvcs:         3,
+			expectErr:    true,
+			expectRemain: 3,
+		},
+		{
+			name:         "delete the pvcs",
+			deleting:     true,
+			pods:         0,
+			pvcs:         3,
+			expectRemain: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			if tt.deleting {
+				now := metav1.Now()
+				tc.DeletionTimestamp = &now
+			}
+			kubeCli := kubefake.NewSimpleClientset()
+			setInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Apps().V1().StatefulSets()
+			podInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Pods()
+			pvcInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().PersistentVolumeClaims()
+			tcInformer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Tikv().V1alpha1().TikvClusters()
+			setControl := controller.NewFakeStatefulSetControl(setInformer, tcInformer)
+			pvcControl := controller.NewFakePVCControl(pvcInformer)
+			cleaner := NewTiKVPVCCleaner(setControl, pvcControl, setInformer.Lister(), podInformer.Lister(), pvcInformer.Lister())
+
+			labels := label.New().Instance(tc.GetInstanceName()).TiKV().Labels()
+			if tt.setExists {
+				g.Expect(setInformer.Informer().GetIndexer().Add(&apps.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{Name: controller.TiKVMemberName(tc.GetName()), Namespace: tc.GetNamespace()},
+				})).To(Succeed())
+			}
+			for i := 0; i < tt.pods; i++ {
+				g.Expect(podInformer.Informer().GetIndexer().Add(&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: TikvPodName(tc.GetName(), int32(i)), Namespace: tc.GetNamespace(), Labels: labels},
+				})).To(Succeed())
+			}
+			for i := 0; i < tt.pvcs; i++ {
+				g.Expect(pvcInformer.Informer().GetIndexer().Add(&corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("tikv-%s", TikvPodName(tc.GetName(), int32(i))), Namespace: tc.GetNamespace(), Labels: labels},
+				})).To(Succeed())
+			}
+
+			err := cleaner.Sync(tc)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(pvcInformer.Informer().GetIndexer().List()).To(HaveLen(tt.expectRemain))
+		})
+	}
+}
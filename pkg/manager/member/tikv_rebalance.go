@@ -0,0 +1,177 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"time"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// recordRebalancePod records the pod added by a scale-out to rebalance the regions to, the window of a
+// rebalance in progress is restarted
+func recordRebalancePod(tc *v1alpha1.TikvCluster, podName string) {
+	rebalance := tc.Status.TiKV.Rebalance
+	if rebalance == nil || rebalance.EndTime != nil {
+		tc.Status.TiKV.Rebalance = &v1alpha1.TiKVRebalanceStatus{Pods: []string{podName}}
+		return
+	}
+	if !sets.NewString(rebalance.Pods...).Has(podName) {
+		rebalance.Pods = append(rebalance.Pods, podName)
+	}
+	if rebalance.StartTime != nil {
+		now := metav1.Now()
+		rebalance.StartTime = &now
+	}
+}
+
+// syncScaleOutRebalance raises the region-schedule-limit of PD once the stores added by a scale-out are up to
+// rebalance the regions to them faster, and restores it once the new stores have caught up or the window elapses
+func (tkmm *tikvMemberManager) syncScaleOutRebalance(tc *v1alpha1.TikvCluster) error {
+	rebalance := tc.Status.TiKV.Rebalance
+	if tc.Spec.Paused || rebalance == nil || rebalance.EndTime != nil {
+		return nil
+	}
+	if tc.Spec.TiKV.ScaleOutRebalance == nil {
+		return tkmm.endScaleOutRebalance(tc, "the rebalance is disabled")
+	}
+	if rebalance.StartTime == nil {
+		return tkmm.startScaleOutRebalance(tc)
+	}
+
+	rebalance.Progress = rebalanceProgress(tc)
+	if rebalance.Progress >= 100 {
+		return tkmm.endScaleOutRebalance(tc, "the new stores have caught up")
+	}
+	if time.Since(rebalance.StartTime.Time) >= tc.TiKVRebalanceWindow() {
+		return tkmm.endScaleOutRebalance(tc, "the window elapsed")
+	}
+	return nil
+}
+
+// startScaleOutRebalance starts the rebalance after the scale-out completes, i.e. the stores of the new pods are up
+func (tkmm *tikvMemberManager) startScaleOutRebalance(tc *v1alpha1.TikvCluster) error {
+	if tc.TiKVStsActualReplicas() != tc.TiKVStsDesiredReplicas() {
+		return nil
+	}
+	rebalance := tc.Status.TiKV.Rebalance
+	logger := tikvLogger(tc)
+
+	// the pods scaled in before the scale-out completes are not waited for
+	var pods []string
+	for _, podName := range rebalance.Pods {
+		_, err := tkmm.podLister.Pods(tc.GetNamespace()).Get(podName)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		pods = append(pods, podName)
+	}
+	if len(pods) == 0 {
+		tc.Status.TiKV.Rebalance = nil
+		return nil
+	}
+	rebalance.Pods = pods
+	upPods := sets.NewString()
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.State == v1alpha1.TiKVStateUp {
+			upPods.Insert(store.PodName)
+		}
+	}
+	if !upPods.HasAll(pods...) {
+		return nil
+	}
+
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	config, err := pdCli.GetConfig()
+	if err != nil {
+		return err
+	}
+	limit := uint64(tc.TiKVRebalanceRegionScheduleLimit())
+	if config.Schedule == nil || config.Schedule.RegionScheduleLimit == nil {
+		logger.Warningf("the region-schedule-limit of pd is unknown, rebalance the regions to the pods %v as it is", pods)
+	} else if live := *config.Schedule.RegionScheduleLimit; live < limit {
+		if err := pdCli.UpdateScheduleConfig(pdapi.PDScheduleConfig{RegionScheduleLimit: &limit}); err != nil {
+			return err
+		}
+		original := int64(live)
+		rebalance.OriginalRegionScheduleLimit = &original
+		logger.Infof("raised the region-schedule-limit of pd from %d to %d to rebalance the regions to the pods %v", live, limit, pods)
+	}
+
+	now := metav1.Now()
+	rebalance.StartTime = &now
+	rebalance.Progress = rebalanceProgress(tc)
+	tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "RebalanceStarted",
+		"rebalancing the regions to the stores of the pods %v for up to %s", pods, tc.TiKVRebalanceWindow())
+	return nil
+}
+
+// endScaleOutRebalance restores the region-schedule-limit of PD raised by the rebalance
+func (tkmm *tikvMemberManager) endScaleOutRebalance(tc *v1alpha1.TikvCluster, reason string) error {
+	rebalance := tc.Status.TiKV.Rebalance
+	if rebalance.OriginalRegionScheduleLimit != nil {
+		original := uint64(*rebalance.OriginalRegionScheduleLimit)
+		pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+		if err := pdCli.UpdateScheduleConfig(pdapi.PDScheduleConfig{RegionScheduleLimit: &original}); err != nil {
+			return err
+		}
+		tikvLogger(tc).Infof("restored the region-schedule-limit of pd to %d", original)
+	}
+
+	now := metav1.Now()
+	rebalance.EndTime = &now
+	if rebalance.StartTime != nil {
+		tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "RebalanceEnded",
+			"ended rebalancing the regions to the stores of the pods %v at %d%%, %s", rebalance.Pods, rebalance.Progress, reason)
+	}
+	return nil
+}
+
+// rebalanceProgress returns the percentage of the average region count of the up stores that the stores of the
+// pods of the rebalance have
+func rebalanceProgress(tc *v1alpha1.TikvCluster) int32 {
+	pods := sets.NewString(tc.Status.TiKV.Rebalance.Pods...)
+	var regions, stores, newRegions, newStores int64
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.State != v1alpha1.TiKVStateUp {
+			continue
+		}
+		regions += int64(store.RegionCount)
+		stores++
+		if pods.Has(store.PodName) {
+			newRegions += int64(store.RegionCount)
+			newStores++
+		}
+	}
+	if regions == 0 {
+		return 100
+	}
+	if newStores == 0 {
+		return 0
+	}
+	progress := newRegions * stores * 100 / (newStores * regions)
+	if progress > 100 {
+		return 100
+	}
+	return int32(progress)
+}
@@ -0,0 +1,215 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestRecordRebalancePod(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+
+	recordRebalancePod(tc, "test-tikv-3")
+	recordRebalancePod(tc, "test-tikv-3")
+	recordRebalancePod(tc, "test-tikv-4")
+	g.Expect(tc.Status.TiKV.Rebalance).To(Equal(&v1alpha1.TiKVRebalanceStatus{Pods: []string{"test-tikv-3", "test-tikv-4"}}))
+
+	// the window of the rebalance in progress is restarted
+	started := metav1.NewTime(time.Now().Add(-time.Hour))
+	tc.Status.TiKV.Rebalance.StartTime = &started
+	recordRebalancePod(tc, "test-tikv-5")
+	g.Expect(tc.Status.TiKV.Rebalance.Pods).To(Equal([]string{"test-tikv-3", "test-tikv-4", "test-tikv-5"}))
+	g.Expect(tc.Status.TiKV.Rebalance.StartTime.Time).To(BeTemporally("~", time.Now(), time.Minute))
+
+	// a new rebalance is recorded after the last one ended
+	tc.Status.TiKV.Rebalance.EndTime = &started
+	recordRebalancePod(tc, "test-tikv-6")
+	g.Expect(tc.Status.TiKV.Rebalance).To(Equal(&v1alpha1.TiKVRebalanceStatus{Pods: []string{"test-tikv-6"}}))
+}
+
+func TestTiKVMemberManagerSyncScaleOutRebalance(t *testing.T) {
+	g := NewGomegaWithT(t)
+	limit := func(l uint64) *uint64 { return &l }
+	tests := []struct {
+		name           string
+		disabled       bool
+		paused         bool
+		rebalance      v1alpha1.TiKVRebalanceStatus
+		newStoreState  string
+		newStoreCount  int32
+		liveLimit      *uint64
+		scaling        bool
+		expectUpdates  []uint64
+		expectStarted  bool
+		expectEnded    bool
+		expectProgress int32
+	}{
+		{
+			name:          "waiting for the store of the new pod",
+			rebalance:     v1alpha1.TiKVRebalanceStatus{Pods: []string{"test-tikv-2"}},
+			newStoreState: v1alpha1.TiKVStateDown,
+			liveLimit:     limit(64),
+		},
+		{
+			name:          "waiting for the scale-out to complete",
+			rebalance:     v1alpha1.TiKVRebalanceStatus{Pods: []string{"test-tikv-2"}},
+			newStoreState: v1alpha1.TiKVStateUp,
+			liveLimit:     limit(64),
+			scaling:       true,
+		},
+		{
+			name:           "raise the limit once the new store is up",
+			rebalance:      v1alpha1.TiKVRebalanceStatus{Pods: []string{"test-tikv-2"}},
+			newStoreState:  v1alpha1.TiKVStateUp,
+			newStoreCount:  50,
+			liveLimit:      limit(64),
+			expectUpdates:  []uint64{4096},
+			expectStarted:  true,
+			expectProgress: 33,
+		},
+		{
+			name:           "the live limit is never lowered",
+			rebalance:      v1alpha1.TiKVRebalanceStatus{Pods: []string{"test-tikv-2"}},
+			newStoreState:  v1alpha1.TiKVStateUp,
+			liveLimit:      limit(8192),
+			expectStarted:  true,
+			expectProgress: 0,
+		},
+		{
+			name: "the limit is restored once the window elapses",
+			rebalance: v1alpha1.TiKVRebalanceStatus{
+				Pods:                        []string{"test-tikv-2"},
+				StartTime:                   &metav1.Time{Time: time.Now().Add(-time.Hour)},
+				OriginalRegionScheduleLimit: pointer.Int64Ptr(64),
+			},
+			newStoreState:  v1alpha1.TiKVStateUp,
+			newStoreCount:  100,
+			expectUpdates:  []uint64{64},
+			expectStarted:  true,
+			expectEnded:    true,
+			expectProgress: 60,
+		},
+		{
+			name: "the limit is restored once the new store caught up",
+			rebalance: v1alpha1.TiKVRebalanceStatus{
+				Pods:                        []string{"test-tikv-2"},
+				StartTime:                   &metav1.Time{Time: time.Now()},
+				OriginalRegionScheduleLimit: pointer.Int64Ptr(64),
+			},
+			newStoreState:  v1alpha1.TiKVStateUp,
+			newStoreCount:  200,
+			expectUpdates:  []uint64{64},
+			expectStarted:  true,
+			expectEnded:    true,
+			expectProgress: 100,
+		},
+		{
+			name: "the limit is restored if disabled in the window",
+			rebalance: v1alpha1.TiKVRebalanceStatus{
+				Pods:                        []string{"test-tikv-2"},
+				StartTime:                   &metav1.Time{Time: time.Now()},
+				OriginalRegionScheduleLimit: pointer.Int64Ptr(64),
+			},
+			disabled:      true,
+			newStoreState: v1alpha1.TiKVStateUp,
+			expectUpdates: []uint64{64},
+			expectStarted: true,
+			expectEnded:   true,
+		},
+		{
+			name: "the limit of a paused cluster is left raised",
+			rebalance: v1alpha1.TiKVRebalanceStatus{
+				Pods:                        []string{"test-tikv-2"},
+				StartTime:                   &metav1.Time{Time: time.Now().Add(-time.Hour)},
+				OriginalRegionScheduleLimit: pointer.Int64Ptr(64),
+			},
+			paused:        true,
+			newStoreState: v1alpha1.TiKVStateUp,
+			newStoreCount: 200,
+			expectStarted: true,
+		},
+		{
+			name:          "the rebalance of a paused cluster is not started",
+			rebalance:     v1alpha1.TiKVRebalanceStatus{Pods: []string{"test-tikv-2"}},
+			paused:        true,
+			newStoreState: v1alpha1.TiKVStateUp,
+			liveLimit:     limit(64),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.TiKV.Replicas = 3
+			tc.Spec.Paused = tt.paused
+			if !tt.disabled {
+				tc.Spec.TiKV.ScaleOutRebalance = &v1alpha1.TiKVScaleOutRebalance{}
+			}
+			actual := int32(3)
+			if tt.scaling {
+				actual = 2
+			}
+			tc.Status.TiKV.StatefulSet = &apps.StatefulSetStatus{Replicas: actual}
+			tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-0", State: v1alpha1.TiKVStateUp, RegionCount: 200},
+				"2": {ID: "2", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp, RegionCount: 200},
+				"3": {ID: "3", PodName: "test-tikv-2", State: tt.newStoreState, RegionCount: tt.newStoreCount},
+			}
+			rebalance := tt.rebalance
+			tc.Status.TiKV.Rebalance = &rebalance
+
+			tkmm, _, _, pdClient, podIndexer, _ := newFakeTiKVMemberManager(tc)
+			podIndexer.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-tikv-2", Namespace: tc.GetNamespace()}})
+			pdClient.AddReaction(pdapi.GetConfigActionType, func(action *pdapi.Action) (interface{}, error) {
+				return &pdapi.PDConfigFromAPI{Schedule: &pdapi.PDScheduleConfig{RegionScheduleLimit: tt.liveLimit}}, nil
+			})
+			var updates []uint64
+			pdClient.AddReaction(pdapi.UpdateScheduleActionType, func(action *pdapi.Action) (interface{}, error) {
+				updates = append(updates, *action.Schedule.RegionScheduleLimit)
+				return nil, nil
+			})
+
+			g.Expect(tkmm.syncScaleOutRebalance(tc)).To(Succeed())
+			g.Expect(updates).To(Equal(tt.expectUpdates))
+			status := tc.Status.TiKV.Rebalance
+			g.Expect(status.StartTime != nil).To(Equal(tt.expectStarted))
+			g.Expect(status.EndTime != nil).To(Equal(tt.expectEnded))
+			g.Expect(status.Progress).To(Equal(tt.expectProgress))
+			if tt.expectStarted && !tt.expectEnded {
+				g.Expect(tc.TiKVRebalancing()).To(BeTrue())
+			}
+		})
+	}
+}
+
+func TestTiKVMemberManagerSyncScaleOutRebalancePodsScaledIn(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.ScaleOutRebalance = &v1alpha1.TiKVScaleOutRebalance{}
+	tc.Status.TiKV.StatefulSet = &apps.StatefulSetStatus{Replicas: tc.Spec.TiKV.Replicas}
+	tc.Status.TiKV.Rebalance = &v1alpha1.TiKVRebalanceStatus{Pods: []string{"test-tikv-5"}}
+	tkmm, _, _, _, _, _ := newFakeTiKVMemberManager(tc)
+
+	g.Expect(tkmm.syncScaleOutRebalance(tc)).To(Succeed())
+	g.Expect(tc.Status.TiKV.Rebalance).To(BeNil())
+}
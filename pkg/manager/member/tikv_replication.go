@@ -0,0 +1,97 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// syncReplicationStatus compares the max-replicas of PD against the failure domains the up stores span,
+// a warning is recorded once the stores can't hold the replicas of a region in distinct failure domains
+func (tkmm *tikvMemberManager) syncReplicationStatus(tc *v1alpha1.TikvCluster) error {
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	config, err := pdCli.GetConfig()
+	if err != nil {
+		return err
+	}
+	storesInfo, err := pdCli.GetStores()
+	if err != nil {
+		return err
+	}
+	var locationLabels []string
+	var pdMaxReplicas *uint64
+	if config.Replication != nil {
+		locationLabels = config.Replication.LocationLabels
+		pdMaxReplicas = config.Replication.MaxReplicas
+	}
+
+	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.TiKVPeerServiceName(), tc.Namespace))
+	if err != nil {
+		return err
+	}
+	failureDomains := countFailureDomains(storesInfo, pattern, locationLabels)
+	// the stores are not bootstrapped yet
+	if failureDomains == 0 {
+		return nil
+	}
+
+	previousFailureDomains, previousMaxReplicas := tc.Status.TiKV.FailureDomains, tc.TiKVMaxReplicas()
+	if pdMaxReplicas != nil {
+		tc.Status.TiKV.MaxReplicas = int32(*pdMaxReplicas)
+	}
+	maxReplicas := tc.TiKVMaxReplicas()
+	tc.Status.TiKV.FailureDomains = failureDomains
+	if failureDomains >= maxReplicas {
+		return nil
+	}
+	if previousFailureDomains == 0 || previousFailureDomains >= previousMaxReplicas {
+		tikvLogger(tc).Warningf("tikv stores span %d failure domains, fewer than the max-replicas %d", failureDomains, maxReplicas)
+		tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "UnderReplicated",
+			"tikv stores span %d failure domains by the location labels %v, fewer than the max-replicas %d of pd",
+			failureDomains, locationLabels, maxReplicas)
+	}
+	return nil
+}
+
+// countFailureDomains returns the number of the distinct values of the location labels of the up stores
+// managed by the operator, each store is a failure domain of its own if there are no location labels
+func countFailureDomains(storesInfo *pdapi.StoresInfo, pattern *regexp.Regexp, locationLabels []string) int32 {
+	domains := map[string]struct{}{}
+	for _, store := range storesInfo.Stores {
+		if store.Store == nil || store.Store.StateName != v1alpha1.TiKVStateUp || !pattern.MatchString(store.Store.Address) {
+			continue
+		}
+		if len(locationLabels) == 0 {
+			domains[store.Store.Address] = struct{}{}
+			continue
+		}
+		labels := map[string]string{}
+		for _, label := range store.Store.Labels {
+			labels[label.Key] = label.Value
+		}
+		values := make([]string, 0, len(locationLabels))
+		for _, key := range locationLabels {
+			values = append(values, labels[key])
+		}
+		domains[strings.Join(values, "/")] = struct{}{}
+	}
+	return int32(len(domains))
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestTiKVMemberManagerSyncReplicationStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+	type store struct {
+		state string
+		zone  string
+	}
+	three := uint64(3)
+	tests := []struct {
+		name                 string
+		replication          *pdapi.PDReplicationConfig
+		stores               []store
+		previous             [2]int32
+		expectMaxReplicas    int32
+		expectFailureDomains int32
+		expectEvents         int
+	}{
+		{
+			name:        "no stores",
+			replication: &pdapi.PDReplicationConfig{MaxReplicas: &three},
+		},
+		{
+			name:                 "each store is a failure domain without location labels",
+			replication:          &pdapi.PDReplicationConfig{MaxReplicas: &three},
+			stores:               []store{{v1alpha1.TiKVStateUp, "a"}, {v1alpha1.TiKVStateUp, "a"}, {v1alpha1.TiKVStateUp, "a"}},
+			expectMaxReplicas:    3,
+			expectFailureDomains: 3,
+		},
+		{
+			name:                 "stores in fewer zones than max-replicas",
+			replication:          &pdapi.PDReplicationConfig{MaxReplicas: &three, LocationLabels: pdapi.StringSlice{"zone"}},
+			stores:               []store{{v1alpha1.TiKVStateUp, "a"}, {v1alpha1.TiKVStateUp, "a"}, {v1alpha1.TiKVStateUp, "b"}},
+			expectMaxReplicas:    3,
+			expectFailureDomains: 2,
+			expectEvents:         1,
+		},
+		{
+			name:                 "already under-replicated",
+			replication:          &pdapi.PDReplicationConfig{MaxReplicas: &three, LocationLabels: pdapi.StringSlice{"zone"}},
+			stores:               []store{{v1alpha1.TiKVStateUp, "a"}, {v1alpha1.TiKVStateUp, "a"}, {v1alpha1.TiKVStateUp, "b"}},
+			previous:             [2]int32{3, 2},
+			expectMaxReplicas:    3,
+			expectFailureDomains: 2,
+		},
+		{
+			name:                 "the down stores are not counted",
+			replication:          &pdapi.PDReplicationConfig{LocationLabels: pdapi.StringSlice{"zone"}},
+			stores:               []store{{v1alpha1.TiKVStateUp, "a"}, {v1alpha1.TiKVStateUp, "b"}, {v1alpha1.TiKVStateDown, "c"}},
+			previous:             [2]int32{3, 3},
+			expectMaxReplicas:    3,
+			expectFailureDomains: 2,
+			expectEvents:         1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Status.TiKV.MaxReplicas = tt.previous[0]
+			tc.Status.TiKV.FailureDomains = tt.previous[1]
+			tkmm, _, _, pdClient, _, _ := newFakeTiKVMemberManager(tc)
+			pdClient.AddReaction(pdapi.GetConfigActionType, func(action *pdapi.Action) (interface{}, error) {
+				return &pdapi.PDConfigFromAPI{Replication: tt.replication}, nil
+			})
+			storesInfo := &pdapi.StoresInfo{Stores: []*pdapi.StoreInfo{}}
+			for i, s := range tt.stores {
+				storesInfo.Stores = append(storesInfo.Stores, &pdapi.StoreInfo{
+					Store: &pdapi.MetaStore{
+						Store: &metapb.Store{
+							Id:      uint64(i + 1),
+							Address: fmt.Sprintf("%s-tikv-%d.%s-tikv-peer.%s.svc:20160", tc.Name, i, tc.Name, tc.Namespace),
+							Labels:  []*metapb.StoreLabel{{Key: "zone", Value: s.zone}},
+						},
+						StateName: s.state,
+					},
+				})
+			}
+			pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+				return storesInfo, nil
+			})
+
+			g.Expect(tkmm.syncReplicationStatus(tc)).To(Succeed())
+			g.Expect(tc.Status.TiKV.MaxReplicas).To(Equal(tt.expectMaxReplicas))
+			g.Expect(tc.Status.TiKV.FailureDomains).To(Equal(tt.expectFailureDomains))
+			g.Expect(collectEvents(tkmm.recorder.(*record.FakeRecorder).Events)).To(HaveLen(tt.expectEvents))
+		})
+	}
+}
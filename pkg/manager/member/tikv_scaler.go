@@ -23,26 +23,33 @@ import (
 	"github.com/tikv/tikv-operator/pkg/label"
 	"github.com/tikv/tikv-operator/pkg/pdapi"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corelisters "k8s.io/client-go/listers/core/v1"
-	"k8s.io/klog"
+	"k8s.io/client-go/tools/record"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 )
 
 type tikvScaler struct {
 	generalScaler
 	podLister corelisters.PodLister
+	recorder  record.EventRecorder
 }
 
 // NewTiKVScaler returns a tikv Scaler
 func NewTiKVScaler(pdControl pdapi.PDControlInterface,
 	pvcLister corelisters.PersistentVolumeClaimLister,
 	pvcControl controller.PVCControlInterface,
-	podLister corelisters.PodLister) Scaler {
-	return &tikvScaler{generalScaler{pdControl, pvcLister, pvcControl}, podLister}
+	podLister corelisters.PodLister,
+	recorder record.EventRecorder) Scaler {
+	return &tikvScaler{generalScaler{pdControl, pvcLister, pvcControl}, podLister, recorder}
 }
 
 func (tsd *tikvScaler) Scale(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) error {
 	scaling, _, _, _ := scaleOne(oldSet, newSet)
+	if scaling >= 0 {
+		tc.Status.TiKV.ScaleInRejected = false
+	}
 	if scaling > 0 {
 		return tsd.ScaleOut(tc, oldSet, newSet)
 	} else if scaling < 0 {
@@ -58,13 +65,21 @@ func (tsd *tikvScaler) ScaleOut(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulS
 		return nil
 	}
 
-	klog.Infof("scaling out tikv statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
+	tikvLogger(tc).Infof("scaling out tikv statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
 	_, err := tsd.deleteDeferDeletingPVC(tc, oldSet.GetName(), v1alpha1.TiKVMemberType, ordinal)
 	if err != nil {
 		return err
 	}
 
 	setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+	podName := ordinalPodName(v1alpha1.TiKVMemberType, tc.GetName(), ordinal)
+	if !sets.NewString(tc.Status.TiKV.ScalingOutPods...).Has(podName) {
+		tc.Status.TiKV.ScalingOutPods = append(tc.Status.TiKV.ScalingOutPods, podName)
+		tsd.recorder.Eventf(tc, corev1.EventTypeNormal, "ScalingOut", "scaling out tikv to %d replicas, adding pod %s", replicas, podName)
+	}
+	if tc.Spec.TiKV.ScaleOutRebalance != nil {
+		recordRebalancePod(tc, podName)
+	}
 	return nil
 }
 
@@ -78,12 +93,11 @@ func (tsd *tikvScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSe
 
 	// tikv can not scale in when it is upgrading
 	if tc.TiKVUpgrading() {
-		klog.Infof("the TikvCluster: [%s/%s]'s tikv is upgrading,can not scale in until upgrade have completed",
-			ns, tcName)
+		tikvLogger(tc).Infof("tikv is upgrading, can not scale in until upgrade have completed")
 		return nil
 	}
 
-	klog.Infof("scaling in tikv statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
+	tikvLogger(tc).Infof("scaling in tikv statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
 	// We need remove member from cluster before reducing statefulset replicas
 	podName := ordinalPodName(v1alpha1.TiKVMemberType, tcName, ordinal)
 	pod, err := tsd.podLister.Pods(ns).Get(podName)
@@ -91,6 +105,20 @@ func (tsd *tikvScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSe
 		return err
 	}
 
+	if upStores, ok := scaleInUpStores(tc, podName); ok && upStores < tc.TiKVMinUpStores() {
+		// the rejection is only reported once, the scale-in stays rejected until the stores are up or it is reverted
+		if !tc.Status.TiKV.ScaleInRejected {
+			tikvLogger(tc).Warningf("tikv scale in: offlining the store of pod %s leaves %d up stores, fewer than the minimum %d",
+				podName, upStores, tc.TiKVMinUpStores())
+			tsd.recorder.Eventf(tc, corev1.EventTypeWarning, "ScaleInRejected",
+				"refused to scale in tikv to %d replicas, offlining the store of pod %s leaves %d up stores, fewer than the minimum %d",
+				replicas, podName, upStores, tc.TiKVMinUpStores())
+		}
+		tc.Status.TiKV.ScaleInRejected = true
+		return nil
+	}
+	tc.Status.TiKV.ScaleInRejected = false
+
 	for _, store := range tc.Status.TiKV.Stores {
 		if store.PodName == podName {
 			state := store.State
@@ -100,10 +128,12 @@ func (tsd *tikvScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSe
 			}
 			if state != v1alpha1.TiKVStateOffline {
 				if err := controller.GetPDClient(tsd.pdControl, tc).DeleteStore(id); err != nil {
-					klog.Errorf("tikv scale in: failed to delete store %d, %v", id, err)
+					tikvLogger(tc).Errorf("tikv scale in: failed to delete store %d, %v", id, err)
 					return err
 				}
-				klog.Infof("tikv scale in: delete store %d for tikv %s/%s successfully", id, ns, podName)
+				tikvLogger(tc).Infof("tikv scale in: delete store %d for tikv %s/%s successfully", id, ns, podName)
+				tsd.recorder.Eventf(tc, corev1.EventTypeNormal, "ScalingIn",
+					"scaling in tikv to %d replicas, offlining store %d of pod %s", replicas, id, podName)
 			}
 			return controller.RequeueErrorf("TiKV %s/%s store %d  still in cluster, state: %s", ns, podName, id, state)
 		}
@@ -116,7 +146,7 @@ func (tsd *tikvScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSe
 			}
 
 			// TODO: double check if store is really not in Up/Offline/Down state
-			klog.Infof("TiKV %s/%s store %d becomes tombstone", ns, podName, id)
+			tikvLogger(tc).Infof("TiKV %s/%s store %d becomes tombstone", ns, podName, id)
 
 			pvcName := ordinalPVCName(v1alpha1.TiKVMemberType, setName, ordinal)
 			pvc, err := tsd.pvcLister.PersistentVolumeClaims(ns).Get(pvcName)
@@ -130,14 +160,16 @@ func (tsd *tikvScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSe
 			pvc.Annotations[label.AnnPVCDeferDeleting] = now
 			_, err = tsd.pvcControl.UpdatePVC(tc, pvc)
 			if err != nil {
-				klog.Errorf("tikv scale in: failed to set pvc %s/%s annotation: %s to %s",
+				tikvLogger(tc).Errorf("tikv scale in: failed to set pvc %s/%s annotation: %s to %s",
 					ns, pvcName, label.AnnPVCDeferDeleting, now)
 				return err
 			}
-			klog.Infof("tikv scale in: set pvc %s/%s annotation: %s to %s",
+			tikvLogger(tc).Infof("tikv scale in: set pvc %s/%s annotation: %s to %s",
 				ns, pvcName, label.AnnPVCDeferDeleting, now)
 
 			setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+			tsd.recorder.Eventf(tc, corev1.EventTypeNormal, "ScaledIn",
+				"scaled in tikv to %d replicas, store %d of pod %s is tombstone", replicas, id, podName)
 			return nil
 		}
 	}
@@ -173,13 +205,15 @@ func (tsd *tikvScaler) ScaleIn(tc *v1alpha1.TikvCluster, oldSet *apps.StatefulSe
 		pvc.Annotations[label.AnnPVCDeferDeleting] = now
 		_, err = tsd.pvcControl.UpdatePVC(tc, pvc)
 		if err != nil {
-			klog.Errorf("pod %s not ready, tikv scale in: failed to set pvc %s/%s annotation: %s to %s",
+			tikvLogger(tc).Errorf("pod %s not ready, tikv scale in: failed to set pvc %s/%s annotation: %s to %s",
 				podName, ns, pvcName, label.AnnPVCDeferDeleting, now)
 			return err
 		}
-		klog.Infof("pod %s not ready, tikv scale in: set pvc %s/%s annotation: %s to %s",
+		tikvLogger(tc).Infof("pod %s not ready, tikv scale in: set pvc %s/%s annotation: %s to %s",
 			podName, ns, pvcName, label.AnnPVCDeferDeleting, now)
 		setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+		tsd.recorder.Eventf(tc, corev1.EventTypeNormal, "ScaledIn",
+			"scaled in tikv to %d replicas, pod %s never joined the cluster", replicas, podName)
 		return nil
 	}
 	return fmt.Errorf("TiKV %s/%s not found in cluster", ns, podName)
@@ -210,3 +244,21 @@ func (fsd *fakeTiKVScaler) ScaleIn(_ *v1alpha1.TikvCluster, oldSet *apps.Statefu
 	setReplicasAndDeleteSlots(newSet, *oldSet.Spec.Replicas-1, nil)
 	return nil
 }
+
+// scaleInUpStores returns the number of the up stores left after the store of the pod is offlined, false is
+// returned if the store of the pod is not up, so the number of the up stores is not changed by the scale-in
+func scaleInUpStores(tc *v1alpha1.TikvCluster, podName string) (int32, bool) {
+	var upStores int32
+	podUp := false
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.State != v1alpha1.TiKVStateUp {
+			continue
+		}
+		if store.PodName == podName {
+			podUp = true
+			continue
+		}
+		upStores++
+	}
+	return upStores, podUp
+}
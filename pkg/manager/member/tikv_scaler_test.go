@@ -15,6 +15,7 @@ package member
 
 import (
 	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -29,6 +30,8 @@ import (
 	kubeinformers "k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 )
 
 func TestTiKVScalerScaleOut(t *testing.T) {
@@ -78,10 +81,15 @@ func TestTiKVScalerScaleOut(t *testing.T) {
 
 		err := scaler.ScaleOut(tc, oldSet, newSet)
 		test.errExpectFn(g, err)
+		recorder := scaler.recorder.(*record.FakeRecorder)
 		if test.changed {
 			g.Expect(int(*newSet.Spec.Replicas)).To(Equal(6))
+			g.Expect(tc.Status.TiKV.ScalingOutPods).To(Equal([]string{ordinalPodName(v1alpha1.TiKVMemberType, tc.GetName(), 5)}))
+			g.Expect(recorder.Events).To(Receive(ContainSubstring("ScalingOut")))
 		} else {
 			g.Expect(int(*newSet.Spec.Replicas)).To(Equal(5))
+			g.Expect(tc.Status.TiKV.ScalingOutPods).To(BeEmpty())
+			g.Expect(recorder.Events).NotTo(Receive())
 		}
 	}
 
@@ -363,6 +371,61 @@ func TestTiKVScalerScaleIn(t *testing.T) {
 			errExpectFn:   errExpectRequeue,
 			changed:       false,
 		},
+		{
+			name:          "scale in below the minimum up stores",
+			tikvUpgrading: false,
+			storeFun: func(tc *v1alpha1.TikvCluster) {
+				normalStoreFun(tc)
+				tc.Spec.TiKV.MinUpStores = pointer.Int32Ptr(5)
+			},
+			delStoreErr:   false,
+			hasPVC:        true,
+			storeIDSynced: true,
+			isPodReady:    true,
+			hasSynced:     true,
+			pvcUpdateErr:  false,
+			errExpectFn:   errExpectNil,
+			changed:       false,
+		},
+		{
+			name:          "scale in below the max-replicas of pd",
+			tikvUpgrading: false,
+			storeFun: func(tc *v1alpha1.TikvCluster) {
+				normalStoreFun(tc)
+				for _, id := range []string{"10", "11"} {
+					store := tc.Status.TiKV.Stores[id]
+					store.State = v1alpha1.TiKVStateDown
+					tc.Status.TiKV.Stores[id] = store
+				}
+			},
+			delStoreErr:   false,
+			hasPVC:        true,
+			storeIDSynced: true,
+			isPodReady:    true,
+			hasSynced:     true,
+			pvcUpdateErr:  false,
+			errExpectFn:   errExpectNil,
+			changed:       false,
+		},
+		{
+			name:          "the store of a down pod is offlined below the minimum up stores",
+			tikvUpgrading: false,
+			storeFun: func(tc *v1alpha1.TikvCluster) {
+				normalStoreFun(tc)
+				store := tc.Status.TiKV.Stores["1"]
+				store.State = v1alpha1.TiKVStateDown
+				tc.Status.TiKV.Stores["1"] = store
+				tc.Spec.TiKV.MinUpStores = pointer.Int32Ptr(5)
+			},
+			delStoreErr:   false,
+			hasPVC:        true,
+			storeIDSynced: true,
+			isPodReady:    true,
+			hasSynced:     true,
+			pvcUpdateErr:  false,
+			errExpectFn:   errExpectRequeue,
+			changed:       false,
+		},
 		{
 			name:          "store state is tombstone",
 			tikvUpgrading: false,
@@ -427,6 +490,40 @@ func TestTiKVScalerScaleIn(t *testing.T) {
 	}
 }
 
+func TestTiKVScalerScaleInRejected(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	normalStoreFun(tc)
+	tc.Spec.TiKV.MinUpStores = pointer.Int32Ptr(5)
+	oldSet := newStatefulSetForPDScale()
+
+	scaler, _, _, podIndexer, _ := newFakeTiKVScaler()
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TikvPodName(tc.GetName(), 4),
+			Namespace: corev1.NamespaceDefault,
+		},
+	}
+	g.Expect(podIndexer.Add(pod)).To(Succeed())
+	recorder := scaler.recorder.(*record.FakeRecorder)
+
+	// the rejection is only reported the first time
+	for i := 0; i < 2; i++ {
+		newSet := oldSet.DeepCopy()
+		newSet.Spec.Replicas = controller.Int32Ptr(3)
+		g.Expect(scaler.Scale(tc, oldSet, newSet)).To(Succeed())
+		g.Expect(*newSet.Spec.Replicas).To(Equal(int32(5)))
+		g.Expect(tc.Status.TiKV.ScaleInRejected).To(BeTrue())
+	}
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("ScaleInRejected")))
+	g.Expect(recorder.Events).NotTo(Receive())
+
+	// the scale-in is reverted
+	g.Expect(scaler.Scale(tc, oldSet, oldSet.DeepCopy())).To(Succeed())
+	g.Expect(tc.Status.TiKV.ScaleInRejected).To(BeFalse())
+}
+
 func newFakeTiKVScaler() (*tikvScaler, *pdapi.FakePDControl, cache.Indexer, cache.Indexer, *controller.FakePVCControl) {
 	kubeCli := kubefake.NewSimpleClientset()
 
@@ -436,7 +533,7 @@ func newFakeTiKVScaler() (*tikvScaler, *pdapi.FakePDControl, cache.Indexer, cach
 	pdControl := pdapi.NewFakePDControl(kubeCli)
 	pvcControl := controller.NewFakePVCControl(pvcInformer)
 
-	return &tikvScaler{generalScaler{pdControl, pvcInformer.Lister(), pvcControl}, podInformer.Lister()},
+	return &tikvScaler{generalScaler{pdControl, pvcInformer.Lister(), pvcControl}, podInformer.Lister(), record.NewFakeRecorder(100)},
 		pdControl, pvcInformer.Informer().GetIndexer(), podInformer.Informer().GetIndexer(), pvcControl
 }
 
@@ -448,6 +545,14 @@ func normalStoreFun(tc *v1alpha1.TikvCluster) {
 			State:   v1alpha1.TiKVStateUp,
 		},
 	}
+	for i := int32(0); i < 4; i++ {
+		id := strconv.Itoa(int(i) + 10)
+		tc.Status.TiKV.Stores[id] = v1alpha1.TiKVStore{
+			ID:      id,
+			PodName: ordinalPodName(v1alpha1.TiKVMemberType, tc.GetName(), i),
+			State:   v1alpha1.TiKVStateUp,
+		}
+	}
 }
 
 func tombstoneStoreFun(tc *v1alpha1.TikvCluster) {
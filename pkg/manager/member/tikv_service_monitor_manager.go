@@ -0,0 +1,191 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/manager"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ServiceMonitorGVK is the GroupVersionKind of the ServiceMonitor of the Prometheus Operator
+var ServiceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+type tikvServiceMonitorManager struct {
+	control controller.GenericControlInterface
+}
+
+// NewTiKVServiceMonitorManager returns a manager.Manager which syncs the ServiceMonitor scraping
+// the metrics of TiKV, the ServiceMonitor CRD is detected through the RESTMapper of the client
+// of the control, which should be able to discover the CRDs installed after the operator starts
+func NewTiKVServiceMonitorManager(control controller.GenericControlInterface) manager.Manager {
+	return &tikvServiceMonitorManager{control}
+}
+
+func (m *tikvServiceMonitorManager) Sync(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.Paused {
+		return nil
+	}
+	// the ServiceMonitor is only looked up if it is enabled or to be deleted, the clusters not using it
+	// don't request the kube-apiserver on every sync
+	if !serviceMonitorEnabled(tc) && !tc.Status.TiKV.ServiceMonitorCreated {
+		return nil
+	}
+
+	desired, err := getNewTiKVServiceMonitor(tc)
+	if err != nil {
+		return err
+	}
+	current := newServiceMonitor()
+	exist, err := m.control.Exist(client.ObjectKey{Namespace: desired.GetNamespace(), Name: desired.GetName()}, current)
+	if meta.IsNoMatchError(err) {
+		if serviceMonitorEnabled(tc) {
+			tikvLogger(tc).Warningf("the ServiceMonitor CRD of the Prometheus Operator is not installed, skip creating the ServiceMonitor")
+		}
+		tc.Status.TiKV.ServiceMonitorCreated = false
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !serviceMonitorEnabled(tc) {
+		if exist && metav1.IsControlledBy(current, tc) {
+			if err := m.control.Delete(tc, current); err != nil {
+				return err
+			}
+		}
+		tc.Status.TiKV.ServiceMonitorCreated = false
+		return nil
+	}
+
+	_, err = m.control.CreateOrUpdate(tc, desired, func(existing, desired runtime.Object) error {
+		existingSM := existing.(*unstructured.Unstructured)
+		desiredSM := desired.(*unstructured.Unstructured)
+		existingSM.SetLabels(desiredSM.GetLabels())
+		existingSM.Object["spec"] = desiredSM.Object["spec"]
+		return nil
+	}, true)
+	if err != nil {
+		return controller.RequeueErrorf("error creating or updating tikv servicemonitor: %v", err)
+	}
+	tc.Status.TiKV.ServiceMonitorCreated = true
+	return nil
+}
+
+func serviceMonitorEnabled(tc *v1alpha1.TikvCluster) bool {
+	return tc.Spec.TiKV.ServiceMonitor != nil && tc.Spec.TiKV.ServiceMonitor.Enabled
+}
+
+func newServiceMonitor() *unstructured.Unstructured {
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(ServiceMonitorGVK)
+	return sm
+}
+
+// getNewTiKVServiceMonitor returns the ServiceMonitor scraping the status port of the TiKV pods
+// through the endpoints of the TiKV peer service
+func getNewTiKVServiceMonitor(tc *v1alpha1.TikvCluster) (*unstructured.Unstructured, error) {
+	spec := tc.Spec.TiKV.ServiceMonitor
+	if spec == nil {
+		spec = &v1alpha1.ServiceMonitorSpec{}
+	}
+	tikvLabel := label.New().Instance(tc.GetInstanceName()).TiKV()
+	peerSvcName := tc.TiKVPeerServiceName()
+
+	// the external access services of TiKV are selected by the TiKV labels as well,
+	// only the peer service is kept so that each pod is scraped once
+	relabelings := []interface{}{
+		map[string]interface{}{
+			"sourceLabels": []interface{}{"__meta_kubernetes_service_name"},
+			"regex":        peerSvcName,
+			"action":       "keep",
+		},
+	}
+	for i := range spec.Relabelings {
+		relabeling, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec.Relabelings[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert the relabelings of tikv servicemonitor, tikvcluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+		}
+		relabelings = append(relabelings, relabeling)
+	}
+
+	endpoint := map[string]interface{}{
+		"targetPort":  int64(20180),
+		"path":        "/metrics",
+		"scheme":      tc.Scheme(),
+		"relabelings": relabelings,
+	}
+	if spec.Interval != "" {
+		endpoint["interval"] = spec.Interval
+	}
+	if tc.IsTLSClusterEnabled() {
+		secretName := tlsClusterSecretName(tc, label.TiKVLabelVal)
+		endpoint["tlsConfig"] = map[string]interface{}{
+			"ca":        map[string]interface{}{"secret": map[string]interface{}{"name": secretName, "key": "ca.crt"}},
+			"cert":      map[string]interface{}{"secret": map[string]interface{}{"name": secretName, "key": "tls.crt"}},
+			"keySecret": map[string]interface{}{"name": secretName, "key": "tls.key"},
+		}
+	}
+
+	selector := map[string]interface{}{}
+	for k, v := range tikvLabel.Labels() {
+		selector[k] = v
+	}
+
+	sm := newServiceMonitor()
+	sm.SetName(controller.TiKVMemberName(tc.Name))
+	sm.SetNamespace(tc.Namespace)
+	sm.SetLabels(CombineLabels(spec.Labels, tikvLabel))
+	sm.SetOwnerReferences([]metav1.OwnerReference{controller.GetOwnerRef(tc)})
+	sm.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": selector,
+		},
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []interface{}{tc.Namespace},
+		},
+		"endpoints": []interface{}{endpoint},
+	}
+	return sm, nil
+}
+
+type FakeTiKVServiceMonitorManager struct {
+	err error
+}
+
+func NewFakeTiKVServiceMonitorManager() *FakeTiKVServiceMonitorManager {
+	return &FakeTiKVServiceMonitorManager{}
+}
+
+func (fsmm *FakeTiKVServiceMonitorManager) SetSyncError(err error) {
+	fsmm.err = err
+}
+
+func (fsmm *FakeTiKVServiceMonitorManager) Sync(_ *v1alpha1.TikvCluster) error {
+	return fsmm.err
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestTiKVServiceMonitorManagerSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTikvClusterForPD()
+	tc.Spec.TiKV.ServiceMonitor = &v1alpha1.ServiceMonitorSpec{
+		Enabled:  true,
+		Interval: "15s",
+		Labels:   map[string]string{"release": "prometheus"},
+		Relabelings: []v1alpha1.RelabelConfig{{
+			SourceLabels: []string{"__meta_kubernetes_pod_node_name"},
+			TargetLabel:  "node",
+		}},
+	}
+	control := controller.NewFakeGenericControl()
+	m := NewTiKVServiceMonitorManager(control)
+	key := client.ObjectKey{Namespace: tc.Namespace, Name: controller.TiKVMemberName(tc.Name)}
+
+	// the ServiceMonitor is not looked up if it is disabled and not created
+	tc.Spec.TiKV.ServiceMonitor.Enabled = false
+	control.SetExistError(errors.New("unexpected lookup"), 0)
+	g.Expect(m.Sync(tc)).To(Succeed())
+	_, err := control.Exist(key, newServiceMonitor())
+	g.Expect(err).To(HaveOccurred())
+	tc.Spec.TiKV.ServiceMonitor.Enabled = true
+
+	// the ServiceMonitor CRD is not installed
+	control.SetExistError(&meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: ServiceMonitorGVK.Group, Kind: ServiceMonitorGVK.Kind}}, 0)
+	g.Expect(m.Sync(tc)).To(Succeed())
+	exist, err := control.Exist(key, newServiceMonitor())
+	g.Expect(err).To(Succeed())
+	g.Expect(exist).To(BeFalse())
+
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(tc.Status.TiKV.ServiceMonitorCreated).To(BeTrue())
+	sm := newServiceMonitor()
+	g.Expect(control.FakeCli.Get(context.TODO(), key, sm)).To(Succeed())
+	g.Expect(sm.GetLabels()).To(HaveKeyWithValue("release", "prometheus"))
+	endpoints, _, err := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+	g.Expect(err).To(Succeed())
+	g.Expect(endpoints).To(HaveLen(1))
+	endpoint := endpoints[0].(map[string]interface{})
+	g.Expect(endpoint["interval"]).To(Equal("15s"))
+	g.Expect(endpoint["scheme"]).To(Equal("http"))
+	relabelings := endpoint["relabelings"].([]interface{})
+	g.Expect(relabelings).To(HaveLen(2))
+	g.Expect(relabelings[0].(map[string]interface{})["regex"]).To(Equal(controller.TiKVPeerMemberName(tc.Name)))
+	g.Expect(relabelings[1].(map[string]interface{})["targetLabel"]).To(Equal("node"))
+
+	// the interval is updated
+	tc.Spec.TiKV.ServiceMonitor.Interval = "30s"
+	g.Expect(m.Sync(tc)).To(Succeed())
+	sm = newServiceMonitor()
+	g.Expect(control.FakeCli.Get(context.TODO(), key, sm)).To(Succeed())
+	endpoints, _, err = unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+	g.Expect(err).To(Succeed())
+	g.Expect(endpoints[0].(map[string]interface{})["interval"]).To(Equal("30s"))
+
+	// the ServiceMonitor is deleted once disabled
+	tc.Spec.TiKV.ServiceMonitor.Enabled = false
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(tc.Status.TiKV.ServiceMonitorCreated).To(BeFalse())
+	exist, err = control.Exist(key, newServiceMonitor())
+	g.Expect(err).To(Succeed())
+	g.Expect(exist).To(BeFalse())
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"strconv"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+)
+
+// syncStoreLimit applies the store limit of the spec to the stores registered in PD, the limits are
+// compared with the live ones on every sync so that the new stores and the changes of the spec are
+// picked up, and the limits reset in PD by hand are restored
+func (tkmm *tikvMemberManager) syncStoreLimit(tc *v1alpha1.TikvCluster) error {
+	storeLimit := tc.Spec.TiKV.StoreLimit
+	if tc.Spec.Paused || storeLimit == nil || (storeLimit.AddPeer == nil && storeLimit.RemovePeer == nil) || len(tc.Status.TiKV.Stores) == 0 {
+		return nil
+	}
+	logger := tikvLogger(tc)
+
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	limits, err := pdCli.GetStoreLimits()
+	if err != nil {
+		return err
+	}
+	for id := range tc.Status.TiKV.Stores {
+		storeID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		current := limits[storeID]
+		// the add-peer limit of the cordoned stores is kept 0
+		_, cordoned := tc.Status.TiKV.CordonedStores[id]
+		if storeLimit.AddPeer != nil && !cordoned && current.AddPeer != float64(*storeLimit.AddPeer) {
+			if err := pdCli.SetStoreLimit(storeID, pdapi.StoreLimitTypeAddPeer, float64(*storeLimit.AddPeer)); err != nil {
+				return err
+			}
+			logger.Infof("set the %s limit of store %s from %v to %d", pdapi.StoreLimitTypeAddPeer, id, current.AddPeer, *storeLimit.AddPeer)
+		}
+		if storeLimit.RemovePeer != nil && current.RemovePeer != float64(*storeLimit.RemovePeer) {
+			if err := pdCli.SetStoreLimit(storeID, pdapi.StoreLimitTypeRemovePeer, float64(*storeLimit.RemovePeer)); err != nil {
+				return err
+			}
+			logger.Infof("set the %s limit of store %s from %v to %d", pdapi.StoreLimitTypeRemovePeer, id, current.RemovePeer, *storeLimit.RemovePeer)
+		}
+	}
+	return nil
+}
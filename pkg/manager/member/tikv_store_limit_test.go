@@ -0,0 +1,110 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	"k8s.io/utils/pointer"
+)
+
+func TestTiKVMemberManagerSyncStoreLimit(t *testing.T) {
+	g := NewGomegaWithT(t)
+	type set struct {
+		id        uint64
+		limitType string
+		rate      float64
+	}
+	tests := []struct {
+		name       string
+		storeLimit *v1alpha1.TiKVStoreLimit
+		paused     bool
+		cordoned   map[string]string
+		limits     map[uint64]pdapi.StoreLimit
+		expect     []set
+	}{
+		{
+			name:   "no store limit",
+			limits: map[uint64]pdapi.StoreLimit{1: {AddPeer: 15, RemovePeer: 15}, 2: {AddPeer: 15, RemovePeer: 15}},
+		},
+		{
+			name:       "set the limits different from the live ones",
+			storeLimit: &v1alpha1.TiKVStoreLimit{AddPeer: pointer.Int32Ptr(5), RemovePeer: pointer.Int32Ptr(15)},
+			limits:     map[uint64]pdapi.StoreLimit{1: {AddPeer: 15, RemovePeer: 15}, 2: {AddPeer: 5, RemovePeer: 30}},
+			expect: []set{
+				{1, pdapi.StoreLimitTypeAddPeer, 5},
+				{2, pdapi.StoreLimitTypeRemovePeer, 15},
+			},
+		},
+		{
+			name:       "leave the limit not set",
+			storeLimit: &v1alpha1.TiKVStoreLimit{AddPeer: pointer.Int32Ptr(5)},
+			limits:     map[uint64]pdapi.StoreLimit{1: {AddPeer: 15, RemovePeer: 15}, 2: {AddPeer: 5, RemovePeer: 30}},
+			expect: []set{
+				{1, pdapi.StoreLimitTypeAddPeer, 5},
+			},
+		},
+		{
+			name:       "set the limits of the new store",
+			storeLimit: &v1alpha1.TiKVStoreLimit{AddPeer: pointer.Int32Ptr(5), RemovePeer: pointer.Int32Ptr(15)},
+			limits:     map[uint64]pdapi.StoreLimit{1: {AddPeer: 5, RemovePeer: 15}},
+			expect: []set{
+				{2, pdapi.StoreLimitTypeAddPeer, 5},
+				{2, pdapi.StoreLimitTypeRemovePeer, 15},
+			},
+		},
+		{
+			name:       "keep the add-peer limit of the cordoned store",
+			storeLimit: &v1alpha1.TiKVStoreLimit{AddPeer: pointer.Int32Ptr(5), RemovePeer: pointer.Int32Ptr(15)},
+			cordoned:   map[string]string{"1": "15"},
+			limits:     map[uint64]pdapi.StoreLimit{1: {AddPeer: 0, RemovePeer: 30}, 2: {AddPeer: 5, RemovePeer: 15}},
+			expect: []set{
+				{1, pdapi.StoreLimitTypeRemovePeer, 15},
+			},
+		},
+		{
+			name:       "leave the limits of a paused cluster",
+			storeLimit: &v1alpha1.TiKVStoreLimit{AddPeer: pointer.Int32Ptr(5), RemovePeer: pointer.Int32Ptr(15)},
+			paused:     true,
+			limits:     map[uint64]pdapi.StoreLimit{1: {AddPeer: 15, RemovePeer: 15}, 2: {AddPeer: 5, RemovePeer: 30}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.TiKV.StoreLimit = tt.storeLimit
+			tc.Spec.Paused = tt.paused
+			tc.Status.TiKV.CordonedStores = tt.cordoned
+			tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: TikvPodName(tc.GetName(), 0), State: v1alpha1.TiKVStateUp},
+				"2": {ID: "2", PodName: TikvPodName(tc.GetName(), 1), State: v1alpha1.TiKVStateUp},
+			}
+			tkmm, _, _, pdClient, _, _ := newFakeTiKVMemberManager(tc)
+			pdClient.AddReaction(pdapi.GetStoreLimitsActionType, func(action *pdapi.Action) (interface{}, error) {
+				return tt.limits, nil
+			})
+			sets := []set{}
+			pdClient.AddReaction(pdapi.SetStoreLimitActionType, func(action *pdapi.Action) (interface{}, error) {
+				sets = append(sets, set{action.ID, action.Name, action.Rate})
+				return nil, nil
+			})
+
+			g.Expect(tkmm.syncStoreLimit(tc)).To(Succeed())
+			g.Expect(sets).To(ConsistOf(tt.expect))
+		})
+	}
+}
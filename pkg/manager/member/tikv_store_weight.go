@@ -0,0 +1,162 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// syncStoreWeights applies the leader and region weights of the spec and of the annotations of the tikv pods to
+// the stores registered in PD, the weights are compared with the live ones on every sync like the store limit.
+// The weighted stores are recorded in the status, so that their weights are reset to 1 once they are no longer
+// weighted.
+func (tkmm *tikvMemberManager) syncStoreWeights(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.Paused || (len(tc.Spec.TiKV.StoreWeights) == 0 && len(tc.Status.TiKV.WeightedStores) == 0 &&
+		!tkmm.anyTiKVPodWeighted(tc)) {
+		return nil
+	}
+	logger := tikvLogger(tc)
+
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	storesInfo, err := pdCli.GetStores()
+	if err != nil {
+		return err
+	}
+	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.TiKVPeerServiceName(), tc.Namespace))
+	if err != nil {
+		return err
+	}
+	// the stores weighted before are kept until their weights are reset
+	weighted := sets.NewString(tc.Status.TiKV.WeightedStores...)
+	defer func() {
+		tc.Status.TiKV.WeightedStores = nil
+		if weighted.Len() > 0 {
+			tc.Status.TiKV.WeightedStores = weighted.List()
+		}
+	}()
+	registered := sets.NewString()
+	for _, store := range storesInfo.Stores {
+		if store.Store == nil || store.Status == nil || !pattern.Match([]byte(store.Store.Address)) {
+			continue
+		}
+		status := tkmm.getTiKVStore(store)
+		registered.Insert(status.ID)
+		leaderWeight, regionWeight := store.Status.LeaderWeight, store.Status.RegionWeight
+		selected := false
+		for _, weight := range tc.Spec.TiKV.StoreWeights {
+			if !storeLabelsContain(store.Store.Labels, weight.Labels) {
+				continue
+			}
+			if weight.LeaderWeight != nil {
+				leaderWeight = float64(*weight.LeaderWeight)
+				selected = true
+			}
+			if weight.RegionWeight != nil {
+				regionWeight = float64(*weight.RegionWeight)
+				selected = true
+			}
+			break
+		}
+
+		pod, err := tkmm.podLister.Pods(tc.GetNamespace()).Get(status.PodName)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if pod != nil {
+			for key, weight := range map[string]*float64{label.AnnTiKVLeaderWeight: &leaderWeight, label.AnnTiKVRegionWeight: &regionWeight} {
+				if err := parseWeightAnnotation(pod.Annotations, key, weight); err != nil {
+					logger.Warningf("ignore the weight annotation of pod %s: %v", status.PodName, err)
+				} else if _, ok := pod.Annotations[key]; ok {
+					selected = true
+				}
+			}
+		}
+
+		reset := false
+		if selected {
+			weighted.Insert(status.ID)
+		} else if weighted.Has(status.ID) {
+			// the store is no longer weighted, its weights are reset to the default ones of PD
+			leaderWeight, regionWeight = 1, 1
+			reset = true
+		}
+		if leaderWeight != store.Status.LeaderWeight || regionWeight != store.Status.RegionWeight {
+			if err := pdCli.SetStoreWeight(store.Store.Id, leaderWeight, regionWeight); err != nil {
+				return err
+			}
+			logger.Infof("set the weights of store %s from leader %v region %v to leader %v region %v", status.ID,
+				store.Status.LeaderWeight, store.Status.RegionWeight, leaderWeight, regionWeight)
+		}
+		if reset {
+			weighted.Delete(status.ID)
+		}
+	}
+	// the stores removed from PD are forgotten
+	weighted = weighted.Intersection(registered)
+	return nil
+}
+
+// anyTiKVPodWeighted returns whether any tikv pod has the weight annotations
+func (tkmm *tikvMemberManager) anyTiKVPodWeighted(tc *v1alpha1.TikvCluster) bool {
+	for _, store := range tc.Status.TiKV.Stores {
+		pod, err := tkmm.podLister.Pods(tc.GetNamespace()).Get(store.PodName)
+		if err != nil {
+			continue
+		}
+		if _, ok := pod.Annotations[label.AnnTiKVLeaderWeight]; ok {
+			return true
+		}
+		if _, ok := pod.Annotations[label.AnnTiKVRegionWeight]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWeightAnnotation sets the weight to the one in the annotation of the key if annotated
+func parseWeightAnnotation(anns map[string]string, key string, weight *float64) error {
+	val, ok := anns[key]
+	if !ok {
+		return nil
+	}
+	w, err := strconv.ParseFloat(val, 64)
+	if err != nil || w < 0 {
+		return fmt.Errorf("annotation %s=%q is not a non-negative number", key, val)
+	}
+	*weight = w
+	return nil
+}
+
+// storeLabelsContain returns whether the store labels contain all the labels
+func storeLabelsContain(storeLabels []*metapb.StoreLabel, labels map[string]string) bool {
+	ls := map[string]string{}
+	for _, l := range storeLabels {
+		ls[l.GetKey()] = l.GetValue()
+	}
+	for k, v := range labels {
+		if val, ok := ls[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
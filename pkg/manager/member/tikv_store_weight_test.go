@@ -0,0 +1,149 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestTiKVMemberManagerSyncStoreWeights(t *testing.T) {
+	g := NewGomegaWithT(t)
+	type set struct {
+		id           uint64
+		leaderWeight float64
+		regionWeight float64
+	}
+	tests := []struct {
+		name    string
+		weights []v1alpha1.TiKVStoreWeight
+		paused  bool
+		podAnns map[string]string
+		// the live leader weights of the stores, 1 if not set
+		leaderWeights  map[uint64]float64
+		weighted       []string
+		expect         []set
+		expectWeighted []string
+	}{
+		{
+			name: "no weights",
+		},
+		{
+			name: "keep the leaders off the stores of the zone",
+			weights: []v1alpha1.TiKVStoreWeight{
+				{Labels: map[string]string{"zone": "backup"}, LeaderWeight: pointer.Int32Ptr(0)},
+			},
+			expect:         []set{{2, 0, 1}},
+			expectWeighted: []string{"2"},
+		},
+		{
+			name: "the first weight selecting the store applies",
+			weights: []v1alpha1.TiKVStoreWeight{
+				{Labels: map[string]string{"zone": "backup"}, LeaderWeight: pointer.Int32Ptr(0)},
+				{LeaderWeight: pointer.Int32Ptr(2), RegionWeight: pointer.Int32Ptr(1)},
+			},
+			expect:         []set{{1, 2, 1}, {2, 0, 1}},
+			expectWeighted: []string{"1", "2"},
+		},
+		{
+			name:           "the annotations of the pod take precedence",
+			weights:        []v1alpha1.TiKVStoreWeight{{RegionWeight: pointer.Int32Ptr(2)}},
+			podAnns:        map[string]string{label.AnnTiKVLeaderWeight: "0.5", label.AnnTiKVRegionWeight: "1"},
+			expect:         []set{{1, 0.5, 1}, {2, 1, 2}},
+			expectWeighted: []string{"1", "2"},
+		},
+		{
+			name:    "invalid annotations are ignored",
+			podAnns: map[string]string{label.AnnTiKVLeaderWeight: "-1"},
+		},
+		{
+			name:    "leave the weights of a paused cluster",
+			weights: []v1alpha1.TiKVStoreWeight{{RegionWeight: pointer.Int32Ptr(2)}},
+			paused:  true,
+			podAnns: map[string]string{label.AnnTiKVLeaderWeight: "0.5"},
+		},
+		{
+			name: "reset the weights of the stores no longer weighted",
+			weights: []v1alpha1.TiKVStoreWeight{
+				{Labels: map[string]string{"zone": "backup"}, LeaderWeight: pointer.Int32Ptr(0)},
+			},
+			leaderWeights:  map[uint64]float64{1: 2, 2: 0},
+			weighted:       []string{"1", "3"},
+			expect:         []set{{1, 1, 1}},
+			expectWeighted: []string{"2"},
+		},
+		{
+			name:           "reset the weights of the stores once all the weights are removed",
+			leaderWeights:  map[uint64]float64{1: 0},
+			weighted:       []string{"1", "2"},
+			expect:         []set{{1, 1, 1}},
+			expectWeighted: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.TiKV.StoreWeights = tt.weights
+			tc.Spec.Paused = tt.paused
+			tc.Status.TiKV.WeightedStores = tt.weighted
+			tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: TikvPodName(tc.GetName(), 0), State: v1alpha1.TiKVStateUp},
+				"2": {ID: "2", PodName: TikvPodName(tc.GetName(), 1), State: v1alpha1.TiKVStateUp},
+			}
+			tkmm, _, _, pdClient, podIndexer, _ := newFakeTiKVMemberManager(tc)
+			podIndexer.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:        TikvPodName(tc.GetName(), 0),
+				Namespace:   tc.GetNamespace(),
+				Annotations: tt.podAnns,
+			}})
+			zones := map[uint64]string{1: "primary", 2: "backup"}
+			pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+				stores := &pdapi.StoresInfo{}
+				for id := uint64(1); id <= 2; id++ {
+					leaderWeight, ok := tt.leaderWeights[id]
+					if !ok {
+						leaderWeight = 1
+					}
+					stores.Stores = append(stores.Stores, &pdapi.StoreInfo{
+						Store: &pdapi.MetaStore{Store: &metapb.Store{
+							Id:      id,
+							Address: fmt.Sprintf("%s-tikv-%d.%s-tikv-peer.%s.svc:20160", tc.GetName(), id-1, tc.GetName(), tc.GetNamespace()),
+							Labels:  []*metapb.StoreLabel{{Key: "zone", Value: zones[id]}},
+						}},
+						Status: &pdapi.StoreStatus{LeaderWeight: leaderWeight, RegionWeight: 1},
+					})
+				}
+				return stores, nil
+			})
+			var sets []set
+			pdClient.AddReaction(pdapi.SetStoreWeightActionType, func(action *pdapi.Action) (interface{}, error) {
+				sets = append(sets, set{action.ID, action.Weights[0], action.Weights[1]})
+				return nil, nil
+			})
+
+			g.Expect(tkmm.syncStoreWeights(tc)).To(Succeed())
+			g.Expect(sets).To(ConsistOf(tt.expect))
+			g.Expect(tc.Status.TiKV.WeightedStores).To(Equal(tt.expectWeighted))
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/manager"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type tikvStoresCleaner struct {
+	pdControl pdapi.PDControlInterface
+	recorder  record.EventRecorder
+}
+
+// NewTiKVStoresCleaner returns a manager offlining the TiKV stores of a TikvCluster being deleted, its Sync
+// returns nil once all the stores become tombstone or the cleanup times out, so the cluster can be removed
+// without leaving the stores in PD. The stores are left as they are if PD has too few other up stores to take
+// over their replicas, e.g. the whole cluster, PD included, is deleted, as they would never become tombstone
+func NewTiKVStoresCleaner(pdControl pdapi.PDControlInterface, recorder record.EventRecorder) manager.Manager {
+	return &tikvStoresCleaner{
+		pdControl: pdControl,
+		recorder:  recorder,
+	}
+}
+
+func (sc *tikvStoresCleaner) Sync(tc *v1alpha1.TikvCluster) error {
+	if tc.DeletionTimestamp == nil {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	logger := tikvLogger(tc)
+
+	if time.Since(tc.DeletionTimestamp.Time) > controller.TiKVStoresCleanupTimeout {
+		logger.Warningf("tikv stores are not tombstone within %v, give up cleaning up the stores", controller.TiKVStoresCleanupTimeout)
+		sc.recorder.Eventf(tc, corev1.EventTypeWarning, "StoresCleanupTimeout",
+			"tikv stores are not tombstone within %v, the cluster is deleted with the stores left in pd", controller.TiKVStoresCleanupTimeout)
+		return nil
+	}
+
+	pdCli := controller.GetPDClient(sc.pdControl, tc)
+	// This only returns Up/Down/Offline stores
+	storesInfo, err := pdCli.GetStores()
+	if err != nil {
+		return controller.RequeueErrorf("TikvCluster: [%s/%s], failed to get the tikv stores to clean up, %v", ns, tcName, err)
+	}
+	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tcName, tc.TiKVPeerServiceName(), ns))
+	if err != nil {
+		return err
+	}
+	var stores []*pdapi.StoreInfo
+	var otherUpStores int32
+	for _, store := range storesInfo.Stores {
+		if store.Store == nil {
+			continue
+		}
+		if pattern.MatchString(store.Store.Address) {
+			stores = append(stores, store)
+		} else if store.Store.StateName == v1alpha1.TiKVStateUp {
+			otherUpStores++
+		}
+	}
+	if len(stores) == 0 {
+		logger.Infof("all the tikv stores are tombstone, the cluster can be deleted")
+		return nil
+	}
+	if maxReplicas := tc.TiKVMaxReplicas(); otherUpStores < maxReplicas {
+		logger.Infof("pd has %d other up stores, fewer than the max-replicas %d, skip offlining the %d tikv stores",
+			otherUpStores, maxReplicas, len(stores))
+		sc.recorder.Eventf(tc, corev1.EventTypeNormal, "StoresCleanupSkipped",
+			"pd has %d up stores of the other clusters, fewer than the max-replicas %d, the cluster is deleted with the stores left in pd",
+			otherUpStores, maxReplicas)
+		return nil
+	}
+
+	for _, store := range stores {
+		if store.Store.StateName == v1alpha1.TiKVStateOffline {
+			continue
+		}
+		storeID := store.Store.GetId()
+		if err := pdCli.DeleteStore(storeID); err != nil {
+			return err
+		}
+		logger.Infof("tikv cluster is being deleted, offline store %d", storeID)
+	}
+	return controller.RequeueErrorf("TikvCluster: [%s/%s] is being deleted, waiting for %d tikv stores to become tombstone", ns, tcName, len(stores))
+}
+
+var _ manager.Manager = &tikvStoresCleaner{}
+
+type FakeTiKVStoresCleaner struct {
+	err error
+}
+
+// NewFakeTiKVStoresCleaner returns a fake tikv stores cleaner
+func NewFakeTiKVStoresCleaner() *FakeTiKVStoresCleaner {
+	return &FakeTiKVStoresCleaner{}
+}
+
+func (fsc *FakeTiKVStoresCleaner) SetSyncError(err error) {
+	fsc.err = err
+}
+
+func (fsc *FakeTiKVStoresCleaner) Sync(_ *v1alpha1.TikvCluster) error {
+	return fsc.err
+}
+
+var _ manager.Manager = &FakeTiKVStoresCleaner{}
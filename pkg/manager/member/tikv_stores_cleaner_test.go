@@ -0,0 +1,137 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestTiKVStoresCleanerSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name          string
+		deleting      bool
+		deletedAgo    time.Duration
+		otherStores   int
+		states        []string
+		expectOffline []uint64
+		expectErr     bool
+	}{
+		{
+			name:   "not being deleted",
+			states: []string{v1alpha1.TiKVStateUp},
+		},
+		{
+			name:          "offline the stores",
+			deleting:      true,
+			otherStores:   3,
+			states:        []string{v1alpha1.TiKVStateUp, v1alpha1.TiKVStateOffline, v1alpha1.TiKVStateDown},
+			expectOffline: []uint64{1, 3},
+			expectErr:     true,
+		},
+		{
+			name:        "waiting for the offline stores",
+			deleting:    true,
+			otherStores: 3,
+			states:      []string{v1alpha1.TiKVStateOffline},
+			expectErr:   true,
+		},
+		{
+			name:     "all the stores are tombstone",
+			deleting: true,
+			states:   []string{},
+		},
+		{
+			name:       "timed out",
+			deleting:   true,
+			deletedAgo: 10 * time.Minute,
+			states:     []string{v1alpha1.TiKVStateOffline},
+		},
+		{
+			name:     "the whole cluster is deleted with pd",
+			deleting: true,
+			states:   []string{v1alpha1.TiKVStateUp, v1alpha1.TiKVStateUp, v1alpha1.TiKVStateUp},
+		},
+		{
+			name:        "too few other up stores to take over the replicas",
+			deleting:    true,
+			otherStores: 2,
+			states:      []string{v1alpha1.TiKVStateUp, v1alpha1.TiKVStateOffline},
+		},
+	}
+	defer func(timeout time.Duration) {
+		controller.TiKVStoresCleanupTimeout = timeout
+	}(controller.TiKVStoresCleanupTimeout)
+	controller.TiKVStoresCleanupTimeout = 5 * time.Minute
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			if tt.deleting {
+				deletionTimestamp := metav1.NewTime(time.Now().Add(-tt.deletedAgo))
+				tc.DeletionTimestamp = &deletionTimestamp
+			}
+			pdControl := pdapi.NewFakePDControl(kubefake.NewSimpleClientset())
+			pdClient := controller.NewFakePDClient(pdControl, tc)
+			storesInfo := &pdapi.StoresInfo{}
+			// the stores of the other clusters are not offlined
+			for i := 0; i < tt.otherStores; i++ {
+				storesInfo.Stores = append(storesInfo.Stores, &pdapi.StoreInfo{
+					Store: &pdapi.MetaStore{
+						Store:     &metapb.Store{Id: uint64(100 + i), Address: fmt.Sprintf("external-tikv-%d:20160", i)},
+						StateName: v1alpha1.TiKVStateUp,
+					},
+				})
+			}
+			for i, state := range tt.states {
+				storesInfo.Stores = append(storesInfo.Stores, &pdapi.StoreInfo{
+					Store: &pdapi.MetaStore{
+						Store: &metapb.Store{
+							Id:      uint64(i + 1),
+							Address: fmt.Sprintf("%s-tikv-%d.%s-tikv-peer.%s.svc:20160", tc.Name, i, tc.Name, tc.Namespace),
+						},
+						StateName: state,
+					},
+				})
+			}
+			pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+				return storesInfo, nil
+			})
+			offline := []uint64{}
+			pdClient.AddReaction(pdapi.DeleteStoreActionType, func(action *pdapi.Action) (interface{}, error) {
+				offline = append(offline, action.ID)
+				return nil, nil
+			})
+
+			cleaner := NewTiKVStoresCleaner(pdControl, record.NewFakeRecorder(10))
+			err := cleaner.Sync(tc)
+			if tt.expectErr {
+				g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(offline).To(ConsistOf(tt.expectOffline))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// cleanTombstoneStores removes the tombstone stores from PD once all of them have been tombstone for the
+// retention. PD removes all the tombstone stores at once, so nothing is removed while PD has tombstone stores
+// not managed by the operator.
+func (tkmm *tikvMemberManager) cleanTombstoneStores(tc *v1alpha1.TikvCluster) error {
+	if tc.Spec.TiKV.TombstoneCleanup == nil || tc.Spec.Paused || len(tc.Status.TiKV.TombstoneStores) == 0 {
+		return nil
+	}
+	logger := tikvLogger(tc)
+	// the pod of a store scaled in is removed only after the store is seen tombstone
+	if tc.Status.TiKV.ScalePhase == v1alpha1.ScaleInPhase {
+		logger.V(4).Infof("tikv is scaling in, the tombstone stores are not removed")
+		return nil
+	}
+	retention := tc.TiKVTombstoneRetention()
+	ids := sets.NewString()
+	for id, store := range tc.Status.TiKV.TombstoneStores {
+		if time.Since(store.LastTransitionTime.Time) < retention {
+			return nil
+		}
+		ids.Insert(id)
+	}
+
+	pdCli := controller.GetPDClient(tkmm.pdControl, tc)
+	tombstoneStoresInfo, err := pdCli.GetTombStoneStores()
+	if err != nil {
+		return err
+	}
+	for _, store := range tombstoneStoresInfo.Stores {
+		if store.Store == nil {
+			continue
+		}
+		if id := fmt.Sprintf("%d", store.Store.GetId()); !ids.Has(id) {
+			logger.V(4).Infof("pd has the tombstone store %s not managed or not synced, the tombstone stores are not removed", id)
+			return nil
+		}
+	}
+
+	if err := pdCli.RemoveTombstoneStores(); err != nil {
+		return err
+	}
+	logger.Infof("removed the tombstone stores %v from pd", ids.List())
+	tkmm.recorder.Eventf(tc, corev1.EventTypeNormal, "TombstoneStoresRemoved",
+		"removed the tombstone stores %v from pd after %s", ids.List(), retention)
+	tc.Status.TiKV.TombstoneStores = map[string]v1alpha1.TiKVStore{}
+	return nil
+}
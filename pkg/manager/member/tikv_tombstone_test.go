@@ -0,0 +1,104 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTiKVMemberManagerCleanTombstoneStores(t *testing.T) {
+	g := NewGomegaWithT(t)
+	expired := metav1.NewTime(time.Now().Add(-48 * time.Hour))
+	tests := []struct {
+		name          string
+		disabled      bool
+		scalePhase    v1alpha1.ScalePhase
+		tombstoneTime metav1.Time
+		pdStoreIDs    []uint64
+		expectRemoved bool
+	}{
+		{
+			name:          "disabled",
+			disabled:      true,
+			tombstoneTime: expired,
+			pdStoreIDs:    []uint64{1, 2},
+		},
+		{
+			name:          "the tombstone stores are removed after the retention",
+			tombstoneTime: expired,
+			pdStoreIDs:    []uint64{1, 2},
+			expectRemoved: true,
+		},
+		{
+			name:          "a store is tombstone for less than the retention",
+			tombstoneTime: metav1.Now(),
+			pdStoreIDs:    []uint64{1, 2},
+		},
+		{
+			name:          "pd has a tombstone store not managed",
+			tombstoneTime: expired,
+			pdStoreIDs:    []uint64{1, 2, 3},
+		},
+		{
+			name:          "tikv is scaling in",
+			scalePhase:    v1alpha1.ScaleInPhase,
+			tombstoneTime: expired,
+			pdStoreIDs:    []uint64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			if !tt.disabled {
+				tc.Spec.TiKV.TombstoneCleanup = &v1alpha1.TiKVTombstoneCleanup{}
+			}
+			tc.Status.TiKV.ScalePhase = tt.scalePhase
+			tc.Status.TiKV.TombstoneStores = map[string]v1alpha1.TiKVStore{
+				"1": {ID: "1", PodName: "test-tikv-3", State: v1alpha1.TiKVStateTombstone, LastTransitionTime: expired},
+				"2": {ID: "2", PodName: "test-tikv-4", State: v1alpha1.TiKVStateTombstone, LastTransitionTime: tt.tombstoneTime},
+			}
+
+			tkmm, _, _, pdClient, _, _ := newFakeTiKVMemberManager(tc)
+			pdClient.AddReaction(pdapi.GetTombStoneStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+				storesInfo := &pdapi.StoresInfo{}
+				for _, id := range tt.pdStoreIDs {
+					storesInfo.Stores = append(storesInfo.Stores, &pdapi.StoreInfo{
+						Store: &pdapi.MetaStore{Store: &metapb.Store{Id: id}, StateName: v1alpha1.TiKVStateTombstone},
+					})
+				}
+				return storesInfo, nil
+			})
+			removed := false
+			pdClient.AddReaction(pdapi.RemoveTombstoneStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+				removed = true
+				return nil, nil
+			})
+
+			g.Expect(tkmm.cleanTombstoneStores(tc)).To(Succeed())
+			g.Expect(removed).To(Equal(tt.expectRemoved))
+			if tt.expectRemoved {
+				g.Expect(tc.Status.TiKV.TombstoneStores).To(BeEmpty())
+			} else {
+				g.Expect(tc.Status.TiKV.TombstoneStores).To(HaveLen(2))
+			}
+		})
+	}
+}
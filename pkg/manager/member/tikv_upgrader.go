@@ -18,13 +18,16 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/controller"
+	"github.com/tikv/tikv-operator/pkg/label"
 	"github.com/tikv/tikv-operator/pkg/pdapi"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 )
 
@@ -39,16 +42,19 @@ type tikvUpgrader struct {
 	pdControl  pdapi.PDControlInterface
 	podControl controller.PodControlInterface
 	podLister  corelisters.PodLister
+	recorder   record.EventRecorder
 }
 
 // NewTiKVUpgrader returns a tikv Upgrader
 func NewTiKVUpgrader(pdControl pdapi.PDControlInterface,
 	podControl controller.PodControlInterface,
-	podLister corelisters.PodLister) Upgrader {
+	podLister corelisters.PodLister,
+	recorder record.EventRecorder) Upgrader {
 	return &tikvUpgrader{
 		pdControl:  pdControl,
 		podControl: podControl,
 		podLister:  podLister,
+		recorder:   recorder,
 	}
 }
 
@@ -68,6 +74,26 @@ func (tku *tikvUpgrader) Upgrade(tc *v1alpha1.TikvCluster, oldSet *apps.Stateful
 		return fmt.Errorf("Tidbcluster: [%s/%s]'s tikv status sync failed, can not to be upgraded", ns, tcName)
 	}
 
+	if from, to, downgrade := isTiKVDowngrade(tc); downgrade && !allowDowngrade(tc) {
+		// tikv doesn't support downgrading, the data written by the newer version may not be read by the older one
+		_, podSpec, err := GetLastAppliedConfig(oldSet)
+		if err != nil {
+			return err
+		}
+		newSet.Spec.Template.Spec = *podSpec
+		// the refusal is only reported once, the downgrade stays refused until it is reverted or allowed
+		if !tc.Status.TiKV.DowngradeRefused {
+			tikvLogger(tc).Warningf("refused to downgrade tikv from %s to %s, set annotation %s to %q to downgrade anyway",
+				from, to, label.AnnAllowDowngradeKey, label.AnnAllowDowngradeVal)
+			tku.recorder.Eventf(tc, corev1.EventTypeWarning, "DowngradeRefused",
+				"refused to downgrade tikv from %s to %s, set annotation %s to %q to downgrade anyway",
+				from, to, label.AnnAllowDowngradeKey, label.AnnAllowDowngradeVal)
+		}
+		tc.Status.TiKV.DowngradeRefused = true
+		return nil
+	}
+	tc.Status.TiKV.DowngradeRefused = false
+
 	tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
 	if !templateEqual(newSet, oldSet) {
 		return nil
@@ -83,7 +109,7 @@ func (tku *tikvUpgrader) Upgrade(tc *v1alpha1.TikvCluster, oldSet *apps.Stateful
 		// If we encounter this situation, we will let the native statefulset controller do the upgrade completely, which may be unsafe for upgrading tikv.
 		// Therefore, in the production environment, we should try to avoid modifying the tikv statefulset update strategy directly.
 		newSet.Spec.UpdateStrategy = oldSet.Spec.UpdateStrategy
-		klog.Warningf("tidbcluster: [%s/%s] tikv statefulset %s UpdateStrategy has been modified manually", ns, tcName, oldSet.GetName())
+		tikvLogger(tc).Warningf("tikv statefulset %s UpdateStrategy has been modified manually", oldSet.GetName())
 		return nil
 	}
 
@@ -117,12 +143,86 @@ func (tku *tikvUpgrader) Upgrade(tc *v1alpha1.TikvCluster, oldSet *apps.Stateful
 			continue
 		}
 
+		if err := tku.evictLeadersInAdvance(tc, podOrdinals[:_i]); err != nil {
+			return err
+		}
 		return tku.upgradeTiKVPod(tc, i, newSet)
 	}
 
 	return nil
 }
 
+// isTiKVDowngrade returns the running and the desired versions of tikv, and whether the desired version is lower
+// than the running one. The images without a semantic version in the tag, e.g. latest, are never considered as
+// downgrades.
+func isTiKVDowngrade(tc *v1alpha1.TikvCluster) (string, string, bool) {
+	from := tc.TiKVRunningVersion()
+	if from == "" {
+		return "", "", false
+	}
+	to := tc.TiKVVersion()
+	fromVersion, err := semver.NewVersion(from)
+	if err != nil {
+		return from, to, false
+	}
+	toVersion, err := semver.NewVersion(to)
+	if err != nil {
+		return from, to, false
+	}
+	return from, to, toVersion.LessThan(fromVersion)
+}
+
+func allowDowngrade(tc *v1alpha1.TikvCluster) bool {
+	return tc.Annotations[label.AnnAllowDowngradeKey] == label.AnnAllowDowngradeVal
+}
+
+// evictLeadersInAdvance begins to evict the leaders of the pods to upgrade next, so that at most
+// LeaderEvictionParallelism pods, including the one being upgraded, are evicting leaders at the same time.
+// It is skipped when any store is unhealthy, to not make more stores unavailable than the cluster can tolerate.
+func (tku *tikvUpgrader) evictLeadersInAdvance(tc *v1alpha1.TikvCluster, ordinals []int32) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	parallelism := tc.TiKVLeaderEvictionParallelism()
+	if parallelism <= 1 || len(ordinals) == 0 {
+		return nil
+	}
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.State != v1alpha1.TiKVStateUp {
+			tikvLogger(tc).Infof("tikv upgrader: store %s is %s, skip evicting leaders in advance", store.ID, store.State)
+			return nil
+		}
+	}
+
+	// the pod being upgraded is evicting leaders
+	evicting := 1
+	for _i := len(ordinals) - 1; _i >= 0 && evicting < parallelism; _i-- {
+		i := ordinals[_i]
+		store := tku.getStoreByOrdinal(tc, i)
+		if store == nil {
+			continue
+		}
+		pod, err := tku.podLister.Pods(ns).Get(TikvPodName(tcName, i))
+		if err != nil {
+			return err
+		}
+		if pod.Labels[apps.ControllerRevisionHashLabelKey] == tc.Status.TiKV.StatefulSet.UpdateRevision {
+			continue
+		}
+		evicting++
+		if _, ok := pod.Annotations[EvictLeaderBeginTime]; ok {
+			continue
+		}
+		storeID, err := strconv.ParseUint(store.ID, 10, 64)
+		if err != nil {
+			return err
+		}
+		if err := tku.beginEvictLeader(tc, storeID, pod.DeepCopy()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (tku *tikvUpgrader) upgradeTiKVPod(tc *v1alpha1.TikvCluster, ordinal int32, newSet *apps.StatefulSet) error {
 	ns := tc.GetNamespace()
 	tcName := tc.GetName()
@@ -181,11 +281,11 @@ func (tku *tikvUpgrader) beginEvictLeader(tc *v1alpha1.TikvCluster, storeID uint
 	podName := pod.GetName()
 	err := controller.GetPDClient(tku.pdControl, tc).BeginEvictLeader(storeID)
 	if err != nil {
-		klog.Errorf("tikv upgrader: failed to begin evict leader: %d, %s/%s, %v",
+		tikvLogger(tc).Errorf("tikv upgrader: failed to begin evict leader: %d, %s/%s, %v",
 			storeID, ns, podName, err)
 		return err
 	}
-	klog.Infof("tikv upgrader: begin evict leader: %d, %s/%s successfully", storeID, ns, podName)
+	tikvLogger(tc).Infof("tikv upgrader: begin evict leader: %d, %s/%s successfully", storeID, ns, podName)
 	if pod.Annotations == nil {
 		pod.Annotations = map[string]string{}
 	}
@@ -193,11 +293,11 @@ func (tku *tikvUpgrader) beginEvictLeader(tc *v1alpha1.TikvCluster, storeID uint
 	pod.Annotations[EvictLeaderBeginTime] = now
 	_, err = tku.podControl.UpdatePod(tc, pod)
 	if err != nil {
-		klog.Errorf("tikv upgrader: failed to set pod %s/%s annotation %s to %s, %v",
+		tikvLogger(tc).Errorf("tikv upgrader: failed to set pod %s/%s annotation %s to %s, %v",
 			ns, podName, EvictLeaderBeginTime, now, err)
 		return err
 	}
-	klog.Infof("tikv upgrader: set pod %s/%s annotation %s to %s successfully",
+	tikvLogger(tc).Infof("tikv upgrader: set pod %s/%s annotation %s to %s successfully",
 		ns, podName, EvictLeaderBeginTime, now)
 	return nil
 }
@@ -212,13 +312,18 @@ func (tku *tikvUpgrader) endEvictLeader(tc *v1alpha1.TikvCluster, ordinal int32)
 	if err != nil {
 		return err
 	}
+	// the leaders of the store stay evicted until the node it is on is uncordoned
+	if node, ok := tc.Status.TiKV.NodeDrainEvictions[store.ID]; ok {
+		tikvLogger(tc).Infof("tikv upgrader: keep evicting leader storeID: %d ordinal: %d, node %s is being drained", storeID, ordinal, node)
+		return nil
+	}
 
-	err = tku.pdControl.GetPDClient(pdapi.Namespace(tc.GetNamespace()), tc.GetName(), tc.IsTLSClusterEnabled()).EndEvictLeader(storeID)
+	err = controller.GetPDClient(tku.pdControl, tc).EndEvictLeader(storeID)
 	if err != nil {
-		klog.Errorf("tikv upgrader: failed to end evict leader storeID: %d ordinal: %d, %v", storeID, ordinal, err)
+		tikvLogger(tc).Errorf("tikv upgrader: failed to end evict leader storeID: %d ordinal: %d, %v", storeID, ordinal, err)
 		return err
 	}
-	klog.Infof("tikv upgrader: end evict leader storeID: %d ordinal: %d successfully", storeID, ordinal)
+	tikvLogger(tc).Infof("tikv upgrader: end evict leader storeID: %d ordinal: %d successfully", storeID, ordinal)
 	return nil
 }
 
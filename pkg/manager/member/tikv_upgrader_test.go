@@ -31,6 +31,7 @@ import (
 	kubeinformers "k8s.io/client-go/informers"
 	podinformers "k8s.io/client-go/informers/core/v1"
 	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 )
 
@@ -466,6 +467,42 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
 			},
 		},
+		{
+			name: "keep evicting leaders of the store on a draining node",
+			changeFn: func(tc *v1alpha1.TikvCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+				tc.Status.TiKV.Synced = true
+				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
+				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
+				tc.Status.TiKV.NodeDrainEvictions = map[string]string{"2": "node-1"}
+				store := tc.Status.TiKV.Stores["2"]
+				store.LeaderCount = 0
+				tc.Status.TiKV.Stores["2"] = store
+			},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				SetStatefulSetLastAppliedConfigAnnotation(oldSet)
+				oldSet.Status.CurrentReplicas = 2
+				oldSet.Status.UpdatedReplicas = 1
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = controller.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Format(time.RFC3339)}
+					}
+				}
+			},
+			beginEvictLeaderErr: false,
+			endEvictLeaderErr:   true,
+			updatePodErr:        false,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TikvCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(1)))
+			},
+		},
 		{
 			name: "update pod failed after begin evict leaders on store[2]",
 			changeFn: func(tc *v1alpha1.TikvCluster) {
@@ -531,6 +568,104 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 	}
 }
 
+func TestTiKVUpgraderEvictLeadersInParallel(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name          string
+		parallelism   *int32
+		maxReplicas   int32
+		changeFn      func(*v1alpha1.TikvCluster)
+		expectEvicted []int32
+	}{
+		{
+			name:          "evict leaders one by one by default",
+			parallelism:   nil,
+			expectEvicted: []int32{2},
+		},
+		{
+			name:          "evict leaders of two pods",
+			parallelism:   pointer.Int32Ptr(2),
+			maxReplicas:   1,
+			expectEvicted: []int32{2, 1},
+		},
+		{
+			name:          "parallelism capped by the up stores beyond the max-replicas",
+			parallelism:   pointer.Int32Ptr(5),
+			maxReplicas:   1,
+			expectEvicted: []int32{2, 1},
+		},
+		{
+			name:          "parallelism not less than the stores",
+			parallelism:   pointer.Int32Ptr(3),
+			maxReplicas:   3,
+			expectEvicted: []int32{2},
+		},
+		{
+			name:        "do not evict leaders in advance when a store is down",
+			parallelism: pointer.Int32Ptr(3),
+			maxReplicas: 1,
+			changeFn: func(tc *v1alpha1.TikvCluster) {
+				store := tc.Status.TiKV.Stores["1"]
+				store.State = v1alpha1.TiKVStateDown
+				tc.Status.TiKV.Stores["1"] = store
+			},
+			expectEvicted: []int32{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upgrader, pdControl, _, podInformer := newTiKVUpgrader()
+			tc := newTikvClusterForTiKVUpgrader()
+			tc.Status.PD.Phase = v1alpha1.NormalPhase
+			tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+			tc.Spec.TiKV.LeaderEvictionParallelism = tt.parallelism
+			tc.Status.TiKV.MaxReplicas = tt.maxReplicas
+			if tt.changeFn != nil {
+				tt.changeFn(tc)
+			}
+
+			evictedStores := []string{}
+			pdClient := controller.NewFakePDClient(pdControl, tc)
+			pdClient.AddReaction(pdapi.BeginEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+				evictedStores = append(evictedStores, strconv.FormatUint(action.ID, 10))
+				return nil, nil
+			})
+
+			oldSet := oldStatefulSetForTiKVUpgrader()
+			SetStatefulSetLastAppliedConfigAnnotation(oldSet)
+			newSet := newStatefulSetForTiKVUpgrader()
+			for _, pod := range getTiKVPods(oldSet) {
+				podInformer.Informer().GetIndexer().Add(pod)
+			}
+
+			err := upgrader.Upgrade(tc, oldSet, newSet)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(3)))
+
+			expectStores := []string{}
+			for _, ordinal := range tt.expectEvicted {
+				expectStores = append(expectStores, strconv.Itoa(int(ordinal)+1))
+			}
+			g.Expect(evictedStores).To(ConsistOf(expectStores))
+			for i := int32(0); i < 3; i++ {
+				pod, err := podInformer.Lister().Pods(tc.Namespace).Get(TikvPodName(upgradeTcName, i))
+				g.Expect(err).NotTo(HaveOccurred())
+				_, evicting := pod.Annotations[EvictLeaderBeginTime]
+				g.Expect(evicting).To(Equal(containsOrdinal(tt.expectEvicted, i)))
+			}
+		})
+	}
+}
+
+func containsOrdinal(ordinals []int32, ordinal int32) bool {
+	for _, o := range ordinals {
+		if o == ordinal {
+			return true
+		}
+	}
+	return false
+}
+
 func newTiKVUpgrader() (Upgrader, *pdapi.FakePDControl, *controller.FakePodControl, podinformers.PodInformer) {
 	kubeCli := kubefake.NewSimpleClientset()
 	podInformer := kubeinformers.NewSharedInformerFactory(kubeCli, 0).Core().V1().Pods()
@@ -540,6 +675,7 @@ func newTiKVUpgrader() (Upgrader, *pdapi.FakePDControl, *controller.FakePodContr
 		pdControl:  pdControl,
 		podControl: podControl,
 		podLister:  podInformer.Lister(),
+		recorder:   record.NewFakeRecorder(100),
 	}, pdControl, podControl, podInformer
 }
 
@@ -669,3 +805,80 @@ func getTiKVPods(set *apps.StatefulSet) []*corev1.Pod {
 	}
 	return pods
 }
+
+func TestTiKVUpgraderRefuseDowngrade(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name           string
+		runningImage   string
+		image          string
+		allowDowngrade bool
+		refused        bool
+		expectImage    string
+		expectEvents   int
+	}{
+		{
+			name:         "upgrade",
+			runningImage: "pingcap/tikv:v4.0.0",
+			image:        "pingcap/tikv:v4.0.2",
+			expectImage:  "pingcap/tikv:v4.0.2",
+		},
+		{
+			name:         "downgrade refused",
+			runningImage: "pingcap/tikv:v4.0.2",
+			image:        "pingcap/tikv:v4.0.0",
+			expectImage:  "pingcap/tikv:v4.0.2",
+			expectEvents: 1,
+		},
+		{
+			name:         "downgrade refused before",
+			runningImage: "pingcap/tikv:v4.0.2",
+			image:        "pingcap/tikv:v4.0.0",
+			refused:      true,
+			expectImage:  "pingcap/tikv:v4.0.2",
+		},
+		{
+			name:           "downgrade allowed",
+			runningImage:   "pingcap/tikv:v4.0.2",
+			image:          "pingcap/tikv:v4.0.0",
+			allowDowngrade: true,
+			expectImage:    "pingcap/tikv:v4.0.0",
+		},
+		{
+			name:         "version unknown",
+			runningImage: "pingcap/tikv:v4.0.2",
+			image:        "pingcap/tikv:nightly",
+			expectImage:  "pingcap/tikv:nightly",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upgrader, _, _, _ := newTiKVUpgrader()
+			recorder := record.NewFakeRecorder(10)
+			upgrader.(*tikvUpgrader).recorder = recorder
+			tc := newTikvClusterForTiKVUpgrader()
+			tc.Spec.TiKV.Image = tt.image
+			tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+			tc.Status.TiKV.Image = tt.runningImage
+			tc.Status.TiKV.DowngradeRefused = tt.refused
+			if tt.allowDowngrade {
+				tc.Annotations = map[string]string{label.AnnAllowDowngradeKey: label.AnnAllowDowngradeVal}
+			}
+			oldSet := oldStatefulSetForTiKVUpgrader()
+			oldSet.Spec.Template.Spec.Containers[0].Image = tt.runningImage
+			g.Expect(SetStatefulSetLastAppliedConfigAnnotation(oldSet)).To(Succeed())
+			newSet := newStatefulSetForTiKVUpgrader()
+			newSet.Spec.Template.Spec.Containers[0].Image = tt.image
+
+			g.Expect(upgrader.Upgrade(tc, oldSet, newSet)).To(Succeed())
+			g.Expect(newSet.Spec.Template.Spec.Containers[0].Image).To(Equal(tt.expectImage))
+			if tt.expectImage == tt.runningImage {
+				g.Expect(tc.Status.TiKV.Phase).To(Equal(v1alpha1.NormalPhase))
+			} else {
+				g.Expect(tc.Status.TiKV.Phase).To(Equal(v1alpha1.UpgradePhase))
+			}
+			g.Expect(tc.Status.TiKV.DowngradeRefused).To(Equal(tt.expectImage == tt.runningImage))
+			g.Expect(recorder.Events).To(HaveLen(tt.expectEvents))
+		})
+	}
+}
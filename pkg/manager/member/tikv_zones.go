@@ -0,0 +1,142 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"sort"
+
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/features"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// setTiKVZoneAffinity restricts the tikv pods to the nodes of the zones declared, each pod is pinned to its own zone
+// by the admission webhook
+func setTiKVZoneAffinity(tc *v1alpha1.TikvCluster, podSpec *corev1.PodSpec) {
+	if len(tc.Spec.TiKV.Zones) == 0 {
+		return
+	}
+	zones := make([]string, 0, len(tc.Spec.TiKV.Zones))
+	for _, zone := range tc.Spec.TiKV.Zones {
+		zones = append(zones, zone.Name)
+	}
+	podSpec.Affinity = util.AddRequiredNodeSelector(podSpec.Affinity, util.ZoneNodeSelector(zones...))
+}
+
+// setZoneDeleteSlots picks the pods to remove from the zones having more pods than their replicas, and records
+// them as the delete slots of the new statefulset. The pods of the zones not declared any more and the surplus pods
+// of the zones are replaced by the pods scaled out, which the pod webhook assigns to the zones lacking pods, so the
+// zones are rebalanced even if the total replicas are unchanged. On a scale-in the pods without a zone, e.g. admitted
+// while the pod webhook failed, are picked first, then the pods of the highest ordinals. Without the
+// AdvancedStatefulSet feature the pods of the highest ordinals are always scaled in, and the zones are only
+// rebalanced by the pods scaled out later. The pods without a zone are reported by a warning event on a scale-in.
+func (tkmm *tikvMemberManager) setZoneDeleteSlots(tc *v1alpha1.TikvCluster, oldSet, newSet *apps.StatefulSet) error {
+	if len(tc.Spec.TiKV.Zones) == 0 || !features.DefaultFeatureGate.Enabled(features.AdvancedStatefulSet) {
+		return nil
+	}
+	actual := helper.GetPodOrdinals(*oldSet.Spec.Replicas, oldSet)
+	scaleIn := actual.Len() - int(*newSet.Spec.Replicas)
+
+	surplus := map[string]int32{}
+	for _, zone := range tc.Spec.TiKV.Zones {
+		surplus[zone.Name] = -zone.Replicas
+	}
+	podZones := map[int32]string{}
+	var unzoned []string
+	for ordinal := range actual {
+		pod, err := tkmm.podLister.Pods(tc.GetNamespace()).Get(TikvPodName(tc.GetName(), ordinal))
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		zone, ok := pod.Annotations[label.AnnTiKVZone]
+		if !ok {
+			unzoned = append(unzoned, pod.GetName())
+		}
+		podZones[ordinal] = zone
+		if _, ok := surplus[zone]; ok {
+			surplus[zone]++
+		}
+	}
+
+	if scaleIn > 0 && len(unzoned) > 0 {
+		sort.Strings(unzoned)
+		tkmm.recorder.Eventf(tc, corev1.EventTypeWarning, "PodsWithoutZone",
+			"tikv pods %v are not assigned to any zone, check the pod admission webhook", unzoned)
+	}
+
+	deletions := sets.NewInt32()
+	ordinals := actual.List()
+	pick := func(limit int, pickable func(ordinal int32) bool) {
+		for i := len(ordinals) - 1; i >= 0 && deletions.Len() < limit; i-- {
+			ordinal := ordinals[i]
+			if deletions.Has(ordinal) || !pickable(ordinal) {
+				continue
+			}
+			deletions.Insert(ordinal)
+			if zone, ok := podZones[ordinal]; ok {
+				surplus[zone]--
+			}
+		}
+	}
+	declared := func(ordinal int32) bool {
+		zone, ok := podZones[ordinal]
+		if !ok {
+			return false
+		}
+		_, declared := surplus[zone]
+		return declared
+	}
+	pick(scaleIn, func(ordinal int32) bool {
+		return !declared(ordinal)
+	})
+	pick(len(ordinals), func(ordinal int32) bool {
+		return podZones[ordinal] != "" && !declared(ordinal)
+	})
+	pick(len(ordinals), func(ordinal int32) bool {
+		return declared(ordinal) && surplus[podZones[ordinal]] > 0
+	})
+	pick(scaleIn, func(int32) bool { return true })
+	if deletions.Len() == 0 {
+		return nil
+	}
+
+	desired := actual.Difference(deletions)
+	deleteSlots := helper.GetDeleteSlots(newSet)
+	if desired.Len() > 0 {
+		last := desired.List()[desired.Len()-1]
+		for ordinal := int32(0); ordinal < last; ordinal++ {
+			if !desired.Has(ordinal) {
+				deleteSlots.Insert(ordinal)
+			}
+		}
+	}
+	// the pods removed to be replaced are skipped by the ordinals scaled out
+	for {
+		replaced := helper.GetPodOrdinalsFromReplicasAndDeleteSlots(*newSet.Spec.Replicas, deleteSlots).Intersection(deletions)
+		if replaced.Len() == 0 {
+			break
+		}
+		deleteSlots.Insert(replaced.List()...)
+	}
+	return helper.SetDeleteSlots(newSet, deleteSlots)
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/features"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestSetTiKVZoneAffinity(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTikvClusterForPD()
+	podSpec := corev1.PodSpec{}
+	setTiKVZoneAffinity(tc, &podSpec)
+	g.Expect(podSpec.Affinity).To(BeNil())
+
+	tc.Spec.TiKV.Zones = []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}, {Name: "b", Replicas: 2}}
+	setTiKVZoneAffinity(tc, &podSpec)
+	g.Expect(podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal(util.ZoneNodeSelector("a", "b")))
+}
+
+func TestTiKVMemberManagerSetZoneDeleteSlots(t *testing.T) {
+	g := NewGomegaWithT(t)
+	features.DefaultFeatureGate.Set("AdvancedStatefulSet=true")
+	defer features.DefaultFeatureGate.Set("AdvancedStatefulSet=false")
+
+	tests := []struct {
+		name              string
+		zones             []v1alpha1.TiKVZone
+		podZones          []string
+		replicas          int32
+		expectDeleteSlots sets.Int32
+		expectEvent       bool
+	}{
+		{
+			name:              "no zones",
+			podZones:          []string{"a", "a", "b"},
+			replicas:          2,
+			expectDeleteSlots: sets.NewInt32(),
+		},
+		{
+			name:              "not scaling in",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}, {Name: "b", Replicas: 2}},
+			podZones:          []string{"a", "a", "b"},
+			replicas:          4,
+			expectDeleteSlots: sets.NewInt32(),
+		},
+		{
+			name:              "scale in the zone with surplus pods",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}, {Name: "b", Replicas: 2}},
+			podZones:          []string{"a", "a", "b", "b"},
+			replicas:          3,
+			expectDeleteSlots: sets.NewInt32(1),
+		},
+		{
+			name:              "scale in the pods of the zones removed first",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}},
+			podZones:          []string{"c", "a", "a"},
+			replicas:          2,
+			expectDeleteSlots: sets.NewInt32(0),
+		},
+		{
+			name:              "scale in the highest ordinals of the zones with surplus pods",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}, {Name: "b", Replicas: 1}},
+			podZones:          []string{"a", "a", "b", "a"},
+			replicas:          2,
+			expectDeleteSlots: sets.NewInt32(1),
+		},
+		{
+			name:              "move the surplus pods to the zones lacking pods",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}, {Name: "b", Replicas: 2}},
+			podZones:          []string{"a", "a", "b"},
+			replicas:          3,
+			expectDeleteSlots: sets.NewInt32(1),
+		},
+		{
+			name:              "move the surplus pod of the highest ordinal",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}, {Name: "b", Replicas: 2}},
+			podZones:          []string{"b", "a", "a"},
+			replicas:          3,
+			expectDeleteSlots: sets.NewInt32(2),
+		},
+		{
+			name:              "move the pods of the zones removed",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}, {Name: "b", Replicas: 1}},
+			podZones:          []string{"a", "c", "a"},
+			replicas:          3,
+			expectDeleteSlots: sets.NewInt32(1),
+		},
+		{
+			name:              "pods without a zone are not moved",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}, {Name: "b", Replicas: 1}},
+			podZones:          []string{"a", "", "a"},
+			replicas:          3,
+			expectDeleteSlots: sets.NewInt32(),
+		},
+		{
+			name:              "scale in the pods without a zone first",
+			zones:             []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}},
+			podZones:          []string{"a", "", "a"},
+			replicas:          2,
+			expectDeleteSlots: sets.NewInt32(1),
+			expectEvent:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTikvClusterForPD()
+			tc.Spec.TiKV.Zones = tt.zones
+			tkmm, _, _, _, podIndexer, _ := newFakeTiKVMemberManager(tc)
+			recorder := record.NewFakeRecorder(10)
+			tkmm.recorder = recorder
+			for i, zone := range tt.podZones {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      TikvPodName(tc.GetName(), int32(i)),
+						Namespace: tc.GetNamespace(),
+					},
+				}
+				if zone != "" {
+					pod.Annotations = map[string]string{label.AnnTiKVZone: zone}
+				}
+				podIndexer.Add(pod)
+			}
+			oldSet := &apps.StatefulSet{Spec: apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(int32(len(tt.podZones)))}}
+			newSet := &apps.StatefulSet{Spec: apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(tt.replicas)}}
+
+			g.Expect(tkmm.setZoneDeleteSlots(tc, oldSet, newSet)).To(Succeed())
+			g.Expect(helper.GetDeleteSlots(newSet)).To(Equal(tt.expectDeleteSlots))
+			if tt.expectEvent {
+				g.Expect(recorder.Events).To(Receive(ContainSubstring("PodsWithoutZone")))
+			} else {
+				g.Expect(recorder.Events).NotTo(Receive())
+			}
+		})
+	}
+}
@@ -18,18 +18,25 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
 	"github.com/tikv/tikv-operator/pkg/controller"
 	"github.com/tikv/tikv-operator/pkg/label"
 	"github.com/tikv/tikv-operator/pkg/util"
+	"github.com/tikv/tikv-operator/pkg/util/log"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
@@ -128,7 +135,8 @@ func statefulSetEqual(new apps.StatefulSet, old apps.StatefulSet) bool {
 	return false
 }
 
-// templateEqual compares the new podTemplateSpec's spec with old podTemplateSpec's last applied config
+// templateEqual compares the new podTemplateSpec with old podTemplateSpec's last applied config,
+// the labels and annotations of the pod template are compared as well so that their changes are rolled out to the pods
 func templateEqual(new *apps.StatefulSet, old *apps.StatefulSet) bool {
 	oldStsSpec := apps.StatefulSetSpec{}
 	lastAppliedConfig, ok := old.Annotations[LastAppliedConfigAnnotation]
@@ -138,7 +146,22 @@ func templateEqual(new *apps.StatefulSet, old *apps.StatefulSet) bool {
 			klog.Errorf("unmarshal PodTemplate: [%s/%s]'s applied config failed,error: %v", old.GetNamespace(), old.GetName(), err)
 			return false
 		}
-		return apiequality.Semantic.DeepEqual(oldStsSpec.Template.Spec, new.Spec.Template.Spec)
+		// the last applied pod template annotation is retained only for backward compatibility
+		oldAnnotations := map[string]string{}
+		for k, v := range oldStsSpec.Template.Annotations {
+			if k != LastAppliedConfigAnnotation {
+				oldAnnotations[k] = v
+			}
+		}
+		newAnnotations := map[string]string{}
+		for k, v := range new.Spec.Template.Annotations {
+			if k != LastAppliedConfigAnnotation {
+				newAnnotations[k] = v
+			}
+		}
+		return apiequality.Semantic.DeepEqual(oldStsSpec.Template.Spec, new.Spec.Template.Spec) &&
+			apiequality.Semantic.DeepEqual(oldStsSpec.Template.Labels, new.Spec.Template.Labels) &&
+			apiequality.Semantic.DeepEqual(oldAnnotations, newAnnotations)
 	}
 	return false
 }
@@ -182,6 +205,39 @@ func CombineAnnotations(a, b map[string]string) map[string]string {
 	return a
 }
 
+// promAnnotations returns the prometheus scraping annotations of the component pods, which are nil
+// if disabled by the PrometheusAnnotations of the component
+func promAnnotations(spec v1alpha1.ComponentAccessor, port int32) map[string]string {
+	pa := spec.PrometheusAnnotations()
+	if pa == nil {
+		return controller.AnnProm(port)
+	}
+	if pa.Enabled != nil && !*pa.Enabled {
+		return nil
+	}
+	if pa.Port != nil {
+		port = *pa.Port
+	}
+	anns := controller.AnnProm(port)
+	if pa.Path != "" {
+		anns["prometheus.io/path"] = pa.Path
+	}
+	return anns
+}
+
+// CombineLabels merges the extra labels of the pods with the labels managed by the operator,
+// the managed ones take precedence so that the pods always match the selector of their StatefulSet
+func CombineLabels(extra map[string]string, managed label.Label) map[string]string {
+	labels := map[string]string{}
+	for k, v := range extra {
+		labels[k] = v
+	}
+	for k, v := range managed.Labels() {
+		labels[k] = v
+	}
+	return labels
+}
+
 // NeedForceUpgrade check if force upgrade is necessary
 func NeedForceUpgrade(tc *v1alpha1.TikvCluster) bool {
 	// Check if annotation 'tikv.org/force-upgrade: "true"' is set
@@ -230,7 +286,7 @@ func Sha256Sum(v interface{}) (string, error) {
 }
 
 func AddConfigMapDigestSuffix(cm *corev1.ConfigMap) error {
-	sum, err := Sha256Sum(cm.Data)
+	sum, err := Sha256Sum(normalizedConfigMapData(cm.Data))
 	if err != nil {
 		return err
 	}
@@ -239,6 +295,62 @@ func AddConfigMapDigestSuffix(cm *corev1.ConfigMap) error {
 	return nil
 }
 
+// isConfigMapDigest returns whether the suffix is a digest added by AddConfigMapDigestSuffix
+func isConfigMapDigest(suffix string) bool {
+	if len(suffix) != 7 {
+		return false
+	}
+	for _, c := range suffix {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizedConfigMapData returns the data of the ConfigMap with the TOML config files normalized, i.e. parsed and
+// marshaled again with the keys sorted, so the semantically equivalent configs have the same digest
+func normalizedConfigMapData(data map[string]string) map[string]string {
+	normalized := make(map[string]string, len(data))
+	for key, value := range data {
+		normalized[key] = value
+		if !isTOMLConfigKey(key) {
+			continue
+		}
+		if config, err := normalizeTOML(value); err == nil {
+			normalized[key] = config
+		}
+	}
+	return normalized
+}
+
+func isTOMLConfigKey(key string) bool {
+	return key == "config-file" || strings.HasSuffix(key, ".toml")
+}
+
+func normalizeTOML(config string) (string, error) {
+	parsed := map[string]interface{}{}
+	if err := UnmarshalTOML([]byte(config), &parsed); err != nil {
+		return "", err
+	}
+	data, err := MarshalTOML(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// configMapEquivalent returns whether the ConfigMap of the name exists and has the data semantically equivalent to
+// the ConfigMap, so the pods using it don't need to be restarted to roll out the ConfigMap
+func configMapEquivalent(typedControl controller.TypedControlInterface, name string, cm *corev1.ConfigMap) bool {
+	inUse := &corev1.ConfigMap{}
+	exist, err := typedControl.Exist(client.ObjectKey{Namespace: cm.GetNamespace(), Name: name}, inUse)
+	if err != nil || !exist {
+		return false
+	}
+	return reflect.DeepEqual(normalizedConfigMapData(inUse.Data), normalizedConfigMapData(cm.Data))
+}
+
 // getStsAnnotations gets annotations for statefulset of given component.
 func getStsAnnotations(tc *v1alpha1.TikvCluster, component string) map[string]string {
 	anns := map[string]string{}
@@ -279,7 +391,9 @@ func updateStatefulSet(setCtl controller.StatefulSetControlInterface, tc *v1alph
 	if oldSet.Annotations == nil {
 		oldSet.Annotations = map[string]string{}
 	}
-	if !statefulSetEqual(*newSet, *oldSet) || isOrphan {
+	// the replicas of the statefulset may have been changed outside of the operator
+	replicasChanged := *newSet.Spec.Replicas != *oldSet.Spec.Replicas
+	if !statefulSetEqual(*newSet, *oldSet) || isOrphan || replicasChanged {
 		set := *oldSet
 		// Retain the deprecated last applied pod template annotation for backward compatibility
 		var podConfig string
@@ -313,6 +427,23 @@ func clusterSecretName(tc *v1alpha1.TikvCluster, component string) string {
 	return fmt.Sprintf("%s-%s-cluster-secret", tc.Name, component)
 }
 
+// tlsClusterSecretName returns the name of the secret holding the cluster certificates of the component
+func tlsClusterSecretName(tc *v1alpha1.TikvCluster, component string) string {
+	if tc.Spec.TLSCluster != nil && tc.Spec.TLSCluster.SecretName != "" {
+		return tc.Spec.TLSCluster.SecretName
+	}
+	return clusterSecretName(tc, component)
+}
+
+// tlsClusterMountPath returns the directory where the cluster certificates are mounted,
+// defaultPath is used if no mount path is specified in the spec
+func tlsClusterMountPath(tc *v1alpha1.TikvCluster, defaultPath string) string {
+	if tc.Spec.TLSCluster != nil && tc.Spec.TLSCluster.MountPath != "" {
+		return tc.Spec.TLSCluster.MountPath
+	}
+	return defaultPath
+}
+
 // filter targetContainer by  containerName, If not find, then return nil
 func filterContainer(sts *apps.StatefulSet, containerName string) *corev1.Container {
 	for _, c := range sts.Spec.Template.Spec.Containers {
@@ -345,3 +476,81 @@ func MergeLabels(l ...map[string]string) map[string]string {
 	}
 	return res
 }
+
+// nodeAffinityLabelKeys returns the node label keys referenced by the node affinity
+func nodeAffinityLabelKeys(affinity *corev1.Affinity) []string {
+	if affinity == nil || affinity.NodeAffinity == nil {
+		return nil
+	}
+	var terms []corev1.NodeSelectorTerm
+	na := affinity.NodeAffinity
+	if na.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		terms = append(terms, na.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms...)
+	}
+	for _, term := range na.PreferredDuringSchedulingIgnoredDuringExecution {
+		terms = append(terms, term.Preference)
+	}
+
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, term := range terms {
+		for _, req := range term.MatchExpressions {
+			// nodes without the label satisfy these requirements
+			if req.Operator == corev1.NodeSelectorOpDoesNotExist || req.Operator == corev1.NodeSelectorOpNotIn {
+				continue
+			}
+			if !seen[req.Key] {
+				seen[req.Key] = true
+				keys = append(keys, req.Key)
+			}
+		}
+	}
+	return keys
+}
+
+// missingNodeLabelKeys returns the keys which do not exist on any schedulable node
+func missingNodeLabelKeys(keys []string, nodes []*corev1.Node) []string {
+	missing := []string{}
+	for _, key := range keys {
+		found := false
+		for _, node := range nodes {
+			if node.Spec.Unschedulable {
+				continue
+			}
+			if _, ok := node.Labels[key]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// pdLogger returns the logger of the pd members of the TikvCluster
+func pdLogger(tc *v1alpha1.TikvCluster) log.Logger {
+	return log.ForCluster(tc, label.PDLabelVal)
+}
+
+// tikvLogger returns the logger of the tikv stores of the TikvCluster
+func tikvLogger(tc *v1alpha1.TikvCluster) log.Logger {
+	return log.ForCluster(tc, label.TiKVLabelVal)
+}
+
+// syncClusterID records the id of the cluster served by PD in the status. The id never changes in the life of a
+// cluster, a different one means the TikvCluster is pointed to another PD cluster by mistake, so the recorded id
+// is kept and an error is returned to stop syncing the cluster
+func syncClusterID(tc *v1alpha1.TikvCluster, cluster *metapb.Cluster, recorder record.EventRecorder) error {
+	clusterID := strconv.FormatUint(cluster.GetId(), 10)
+	if tc.Status.ClusterID != "" && tc.Status.ClusterID != clusterID {
+		recorder.Eventf(tc, corev1.EventTypeWarning, "ClusterIDChanged",
+			"the id of the cluster served by pd changed from %s to %s, the cluster may be pointed to a different pd, stop syncing",
+			tc.Status.ClusterID, clusterID)
+		return fmt.Errorf("TikvCluster: [%s/%s], the id of the cluster served by pd changed from %s to %s",
+			tc.GetNamespace(), tc.GetName(), tc.Status.ClusterID, clusterID)
+	}
+	tc.Status.ClusterID = clusterID
+	return nil
+}
@@ -20,8 +20,10 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/controller"
 	"github.com/tikv/tikv-operator/pkg/label"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -150,3 +152,251 @@ func TestGetStsAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateEqual(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newSet := func() *apps.StatefulSet {
+		return &apps.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: metav1.NamespaceDefault,
+			},
+			Spec: apps.StatefulSetSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:      map[string]string{"app": "tikv"},
+						Annotations: map[string]string{"prometheus.io/scrape": "true"},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "tikv", Image: "tikv:v4.0.0"}},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name   string
+		update func(set *apps.StatefulSet)
+		expect bool
+	}{
+		{
+			name:   "equal",
+			update: func(set *apps.StatefulSet) {},
+			expect: true,
+		},
+		{
+			name: "the last applied pod template annotation is ignored",
+			update: func(set *apps.StatefulSet) {
+				set.Spec.Template.Annotations[LastAppliedConfigAnnotation] = "{}"
+			},
+			expect: true,
+		},
+		{
+			name: "pod spec changed",
+			update: func(set *apps.StatefulSet) {
+				set.Spec.Template.Spec.Containers[0].Image = "tikv:v4.0.1"
+			},
+			expect: false,
+		},
+		{
+			name: "pod annotations changed",
+			update: func(set *apps.StatefulSet) {
+				set.Spec.Template.Annotations["foo"] = "bar"
+			},
+			expect: false,
+		},
+		{
+			name: "pod labels changed",
+			update: func(set *apps.StatefulSet) {
+				set.Spec.Template.Labels["team"] = "storage"
+			},
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := newSet()
+			g.Expect(SetStatefulSetLastAppliedConfigAnnotation(old)).To(Succeed())
+			set := newSet()
+			tt.update(set)
+			g.Expect(templateEqual(set, old)).To(Equal(tt.expect))
+		})
+	}
+}
+
+func TestUpdateStatefulSetAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	_, setControl, _, _, _, _, _ := newFakePDMemberManager()
+	tc := newTikvClusterForPD()
+	old := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   metav1.NamespaceDefault,
+			Annotations: map[string]string{helper.DeleteSlotsAnn: "[1]"},
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas: controller.Int32Ptr(3),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"foo": "bar"},
+				},
+			},
+		},
+	}
+	g.Expect(SetStatefulSetLastAppliedConfigAnnotation(old)).To(Succeed())
+	g.Expect(setControl.SetIndexer.Add(old)).To(Succeed())
+
+	newSet := old.DeepCopy()
+	newSet.Annotations = map[string]string{helper.DeleteSlotsAnn: "[1,2]"}
+	newSet.Spec.Template.Annotations = map[string]string{"foo": "baz"}
+	g.Expect(updateStatefulSet(setControl, tc, newSet, old)).To(Succeed())
+
+	obj, exist, err := setControl.SetIndexer.Get(old)
+	g.Expect(err).To(Succeed())
+	g.Expect(exist).To(BeTrue())
+	updated := obj.(*apps.StatefulSet)
+	g.Expect(updated.Annotations[helper.DeleteSlotsAnn]).To(Equal("[1,2]"))
+	g.Expect(updated.Spec.Template.Annotations["foo"]).To(Equal("baz"))
+	// the new pod template annotations are recorded so that the next sync sees no change
+	g.Expect(templateEqual(newSet, updated)).To(BeTrue())
+}
+
+func TestPromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	disabled := false
+	port := int32(20181)
+	tests := []struct {
+		name   string
+		update func(tc *v1alpha1.TikvCluster)
+		expect map[string]string
+	}{
+		{
+			name:   "default",
+			update: func(tc *v1alpha1.TikvCluster) {},
+			expect: map[string]string{
+				"prometheus.io/scrape": "true",
+				"prometheus.io/path":   "/metrics",
+				"prometheus.io/port":   "20180",
+			},
+		},
+		{
+			name: "disabled at the cluster level",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.PrometheusAnnotations = &v1alpha1.PrometheusAnnotations{Enabled: &disabled}
+			},
+			expect: nil,
+		},
+		{
+			name: "component-level path and port override the cluster-level setting",
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.PrometheusAnnotations = &v1alpha1.PrometheusAnnotations{Enabled: &disabled}
+				tc.Spec.TiKV.PrometheusAnnotations = &v1alpha1.PrometheusAnnotations{
+					Path: "/status/metrics",
+					Port: &port,
+				}
+			},
+			expect: map[string]string{
+				"prometheus.io/scrape": "true",
+				"prometheus.io/path":   "/status/metrics",
+				"prometheus.io/port":   "20181",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TikvCluster{}
+			tt.update(tc)
+			g.Expect(promAnnotations(tc.BaseTiKVSpec(), 20180)).To(Equal(tt.expect))
+		})
+	}
+}
+
+func TestAddConfigMapDigestSuffix(t *testing.T) {
+	g := NewGomegaWithT(t)
+	config := `log-level = "info"
+
+[raftstore]
+raft-log-gc-threshold = 50
+store-pool-size = 2
+
+[server]
+grpc-concurrency = 4
+`
+	tests := []struct {
+		name          string
+		config        string
+		pdConfig      string
+		startupScript string
+		expectSame    bool
+	}{
+		{
+			name:          "same config",
+			config:        config,
+			pdConfig:      "a = 1\nb = 2\n",
+			startupScript: "start",
+			expectSame:    true,
+		},
+		{
+			name: "reordered sections and items",
+			config: `log-level = "info"
+[server]
+  grpc-concurrency = 4
+
+[raftstore]
+  store-pool-size = 2
+  raft-log-gc-threshold = 50
+`,
+			pdConfig:      "a = 1\nb = 2\n",
+			startupScript: "start",
+			expectSame:    true,
+		},
+		{
+			name:          "reordered config file",
+			config:        config,
+			pdConfig:      "b = 2\n\na = 1",
+			startupScript: "start",
+			expectSame:    true,
+		},
+		{
+			name: "config changed",
+			config: `log-level = "info"
+
+[raftstore]
+raft-log-gc-threshold = 100
+store-pool-size = 2
+
+[server]
+grpc-concurrency = 4
+`,
+			pdConfig:      "a = 1\nb = 2\n",
+			startupScript: "start",
+			expectSame:    false,
+		},
+		{
+			name:          "startup script not normalized",
+			config:        config,
+			pdConfig:      "a = 1\nb = 2\n",
+			startupScript: "start\n",
+			expectSame:    false,
+		},
+	}
+
+	newConfigMap := func(config, pdConfig, startupScript string) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tikv"},
+			Data:       map[string]string{"config-file": config, "pd.toml": pdConfig, "startup-script": startupScript},
+		}
+		g.Expect(AddConfigMapDigestSuffix(cm)).To(Succeed())
+		return cm
+	}
+	base := newConfigMap(config, "a = 1\nb = 2\n", "start")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap(tt.config, tt.pdConfig, tt.startupScript)
+			g.Expect(cm.Name == base.Name).To(Equal(tt.expectSame))
+		})
+	}
+}
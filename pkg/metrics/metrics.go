@@ -0,0 +1,139 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics holds the Prometheus metrics of the operator itself.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+)
+
+const namespace = "tikv_operator"
+
+// tikvStoreStates are the states that the TiKVStores gauge is reported for
+var tikvStoreStates = []string{
+	v1alpha1.TiKVStateUp,
+	v1alpha1.TiKVStateDown,
+	v1alpha1.TiKVStateOffline,
+	v1alpha1.TiKVStateTombstone,
+}
+
+const (
+	// ReconcileErrorRequeue is the type of the errors which only requeue the TikvCluster
+	ReconcileErrorRequeue = "requeue"
+	// ReconcileErrorOther is the type of all the other reconcile errors
+	ReconcileErrorOther = "other"
+)
+
+var (
+	// Registry is the registry of the metrics of the operator, it is served by Handler
+	Registry = prometheus.NewRegistry()
+
+	// ReconcileDuration observes the duration of every reconcile of a TikvCluster
+	ReconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "reconcile",
+			Name:      "duration_seconds",
+			Help:      "Duration of the reconciles of the TikvClusters.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, []string{"namespace", "cluster"})
+
+	// ReconcileErrors counts the failed reconciles of a TikvCluster by the error type
+	ReconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "reconcile",
+			Name:      "errors_total",
+			Help:      "Number of the failed reconciles of the TikvClusters by the error type.",
+		}, []string{"namespace", "cluster", "type"})
+
+	// PDAPIDuration observes the latency of the PD API calls by the method
+	PDAPIDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "pd_api",
+			Name:      "duration_seconds",
+			Help:      "Latency of the PD API calls by the method.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		}, []string{"method"})
+
+	// PDAPIErrors counts the failed PD API calls by the method
+	PDAPIErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "pd_api",
+			Name:      "errors_total",
+			Help:      "Number of the failed PD API calls by the method.",
+		}, []string{"method"})
+
+	// TiKVStores is the number of the TiKV stores of a TikvCluster by the store state
+	TiKVStores = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "tikv",
+			Name:      "stores",
+			Help:      "Number of the TiKV stores of the TikvClusters by the store state.",
+		}, []string{"namespace", "cluster", "state"})
+
+	// TiKVStoreLabelsSet counts the stores of a TikvCluster whose labels were set in PD, the labels of a store
+	// are only set once they differ from the node labels, so a steady increase indicates they are set repeatedly
+	TiKVStoreLabelsSet = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "tikv",
+			Name:      "store_labels_set_total",
+			Help:      "Number of the times the labels of the TiKV stores of the TikvClusters were set in PD.",
+		}, []string{"namespace", "cluster"})
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		ReconcileDuration,
+		ReconcileErrors,
+		PDAPIDuration,
+		PDAPIErrors,
+		TiKVStores,
+		TiKVStoreLabelsSet,
+	)
+}
+
+// Handler returns the http handler serving the metrics in Registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// SetTiKVStores sets the number of the TiKV stores of a TikvCluster in each of the states,
+// the states missing in counts are reset to 0
+func SetTiKVStores(ns, name string, counts map[string]int) {
+	for _, state := range tikvStoreStates {
+		TiKVStores.WithLabelValues(ns, name, state).Set(float64(counts[state]))
+	}
+}
+
+// DeleteTikvCluster removes the metrics of a deleted TikvCluster
+func DeleteTikvCluster(ns, name string) {
+	ReconcileDuration.DeleteLabelValues(ns, name)
+	ReconcileErrors.DeleteLabelValues(ns, name, ReconcileErrorRequeue)
+	ReconcileErrors.DeleteLabelValues(ns, name, ReconcileErrorOther)
+	for _, state := range tikvStoreStates {
+		TiKVStores.DeleteLabelValues(ns, name, state)
+	}
+	TiKVStoreLabelsSet.DeleteLabelValues(ns, name)
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+)
+
+func TestSetTiKVStores(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	SetTiKVStores("default", "demo", map[string]int{v1alpha1.TiKVStateUp: 2, v1alpha1.TiKVStateDown: 1})
+	g.Expect(testutil.ToFloat64(TiKVStores.WithLabelValues("default", "demo", v1alpha1.TiKVStateUp))).To(Equal(float64(2)))
+	g.Expect(testutil.ToFloat64(TiKVStores.WithLabelValues("default", "demo", v1alpha1.TiKVStateDown))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(TiKVStores.WithLabelValues("default", "demo", v1alpha1.TiKVStateTombstone))).To(Equal(float64(0)))
+
+	// the stores no longer in a state are reset
+	SetTiKVStores("default", "demo", map[string]int{v1alpha1.TiKVStateUp: 3})
+	g.Expect(testutil.ToFloat64(TiKVStores.WithLabelValues("default", "demo", v1alpha1.TiKVStateUp))).To(Equal(float64(3)))
+	g.Expect(testutil.ToFloat64(TiKVStores.WithLabelValues("default", "demo", v1alpha1.TiKVStateDown))).To(Equal(float64(0)))
+
+	DeleteTikvCluster("default", "demo")
+	g.Expect(TiKVStores.DeleteLabelValues("default", "demo", v1alpha1.TiKVStateUp)).To(BeFalse())
+}
+
+func TestHandler(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ReconcileErrors.WithLabelValues("default", "demo", ReconcileErrorRequeue).Inc()
+	defer DeleteTikvCluster("default", "demo")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(body)).To(ContainSubstring(`tikv_operator_reconcile_errors_total{cluster="demo",namespace="default",type="requeue"} 1`))
+}
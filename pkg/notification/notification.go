@@ -0,0 +1,185 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/httputil"
+	"k8s.io/klog"
+)
+
+const (
+	defaultRetries = 3
+	defaultTimeout = 5 * time.Second
+	defaultBackoff = time.Second
+)
+
+// EventType is the type of a lifecycle event of a tikv cluster
+type EventType string
+
+const (
+	// EventCreated is sent when the statefulset of a component is created
+	EventCreated EventType = "Created"
+	// EventUpgrading is sent when a component starts a rolling upgrade
+	EventUpgrading EventType = "Upgrading"
+	// EventScaled is sent when the replicas of a component are changed
+	EventScaled EventType = "Scaled"
+	// EventFailover is sent when a failed member is replaced
+	EventFailover EventType = "Failover"
+)
+
+// Payload is the JSON body POSTed to the notification webhook
+type Payload struct {
+	Type      EventType `json:"type"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Component string    `json:"component"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Notifier dispatches lifecycle events of a tikv cluster
+type Notifier interface {
+	// Notify sends the event to the notification webhook of the tikv cluster, if any
+	Notify(tc *v1alpha1.TikvCluster, memberType v1alpha1.MemberType, eventType EventType, message string)
+}
+
+type webhookNotifier struct {
+	httpClient *http.Client
+	backoff    time.Duration
+}
+
+// NewWebhookNotifier returns a Notifier which POSTs the events to the webhook
+// in the background, failed calls are retried with a linear backoff
+func NewWebhookNotifier() Notifier {
+	return &webhookNotifier{
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		backoff:    defaultBackoff,
+	}
+}
+
+func (wn *webhookNotifier) Notify(tc *v1alpha1.TikvCluster, memberType v1alpha1.MemberType, eventType EventType, message string) {
+	webhook := tc.Spec.NotificationWebhook
+	if webhook == nil || webhook.URL == "" {
+		return
+	}
+	retries := defaultRetries
+	if webhook.Retries != nil {
+		retries = int(*webhook.Retries)
+	}
+	payload := newPayload(tc, memberType, eventType, message)
+	go func() {
+		if err := wn.send(webhook.URL, payload, retries); err != nil {
+			klog.Errorf("failed to notify %s event of tikv cluster %s/%s to %s: %v",
+				eventType, payload.Namespace, payload.Name, webhook.URL, err)
+		}
+	}()
+}
+
+func (wn *webhookNotifier) send(url string, payload *Payload, retries int) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		err = wn.post(url, body)
+		if err == nil || i >= retries {
+			return err
+		}
+		klog.V(4).Infof("notify %s event of tikv cluster %s/%s failed, retry %d: %v",
+			payload.Type, payload.Namespace, payload.Name, i+1, err)
+		time.Sleep(time.Duration(i+1) * wn.backoff)
+	}
+}
+
+func (wn *webhookNotifier) post(url string, body []byte) error {
+	res, err := wn.httpClient.Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func newPayload(tc *v1alpha1.TikvCluster, memberType v1alpha1.MemberType, eventType EventType, message string) *Payload {
+	return &Payload{
+		Type:      eventType,
+		Namespace: tc.GetNamespace(),
+		Name:      tc.GetName(),
+		Component: memberType.String(),
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+}
+
+type dryRunNotifier struct{}
+
+// NewDryRunNotifier returns a Notifier which only logs the events
+func NewDryRunNotifier() Notifier {
+	return &dryRunNotifier{}
+}
+
+func (dn *dryRunNotifier) Notify(tc *v1alpha1.TikvCluster, memberType v1alpha1.MemberType, eventType EventType, message string) {
+	if webhook := tc.Spec.NotificationWebhook; webhook == nil || webhook.URL == "" {
+		return
+	}
+	klog.Infof("[dry-run] would notify %s event of %s of tikv cluster %s/%s: %s",
+		eventType, memberType, tc.GetNamespace(), tc.GetName(), message)
+}
+
+// FakeNotifier is a fake Notifier which records the events
+type FakeNotifier struct {
+	mu     sync.Mutex
+	events []Payload
+}
+
+// NewFakeNotifier returns a FakeNotifier
+func NewFakeNotifier() *FakeNotifier {
+	return &FakeNotifier{}
+}
+
+func (fn *FakeNotifier) Notify(tc *v1alpha1.TikvCluster, memberType v1alpha1.MemberType, eventType EventType, message string) {
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+	fn.events = append(fn.events, *newPayload(tc, memberType, eventType, message))
+}
+
+// Events returns the recorded events
+func (fn *FakeNotifier) Events() []Payload {
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+	return append([]Payload{}, fn.events...)
+}
+
+// EventTypes returns the types of the recorded events
+func (fn *FakeNotifier) EventTypes() []EventType {
+	types := []EventType{}
+	for _, e := range fn.Events() {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+var _ Notifier = &webhookNotifier{}
+var _ Notifier = &FakeNotifier{}
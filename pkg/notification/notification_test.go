@@ -0,0 +1,116 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestWebhookNotifierSend(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name          string
+		failures      int32
+		retries       int
+		expectCalls   int32
+		expectSuccess bool
+	}{
+		{
+			name:          "succeed at the first call",
+			failures:      0,
+			retries:       3,
+			expectCalls:   1,
+			expectSuccess: true,
+		},
+		{
+			name:          "succeed after retries",
+			failures:      2,
+			retries:       3,
+			expectCalls:   3,
+			expectSuccess: true,
+		},
+		{
+			name:          "give up after retries",
+			failures:      10,
+			retries:       2,
+			expectCalls:   3,
+			expectSuccess: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var received Payload
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.Method).To(Equal("POST"))
+				if atomic.AddInt32(&calls, 1) <= tt.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				g.Expect(json.NewDecoder(r.Body).Decode(&received)).To(Succeed())
+			}))
+			defer srv.Close()
+
+			wn := &webhookNotifier{httpClient: srv.Client(), backoff: time.Millisecond}
+			payload := newPayload(newTikvCluster(srv.URL), v1alpha1.TiKVMemberType, EventScaled, "scaled from 3 to 4")
+			err := wn.send(srv.URL, payload, tt.retries)
+			if tt.expectSuccess {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(received.Type).To(Equal(EventScaled))
+				g.Expect(received.Namespace).To(Equal("ns"))
+				g.Expect(received.Name).To(Equal("demo"))
+				g.Expect(received.Component).To(Equal("tikv"))
+				g.Expect(received.Message).To(Equal("scaled from 3 to 4"))
+			} else {
+				g.Expect(err).To(HaveOccurred())
+			}
+			g.Expect(atomic.LoadInt32(&calls)).To(Equal(tt.expectCalls))
+		})
+	}
+}
+
+func TestWebhookNotifierNotify(t *testing.T) {
+	g := NewGomegaWithT(t)
+	received := make(chan Payload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		g.Expect(json.NewDecoder(r.Body).Decode(&p)).To(Succeed())
+		received <- p
+	}))
+	defer srv.Close()
+
+	tc := newTikvCluster(srv.URL)
+	tc.Spec.NotificationWebhook.Retries = pointer.Int32Ptr(0)
+	NewWebhookNotifier().Notify(tc, v1alpha1.TiKVMemberType, EventCreated, "")
+	g.Eventually(received).Should(Receive(WithTransform(func(p Payload) EventType { return p.Type }, Equal(EventCreated))))
+}
+
+func newTikvCluster(url string) *v1alpha1.TikvCluster {
+	return &v1alpha1.TikvCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+		Spec: v1alpha1.TikvClusterSpec{
+			NotificationWebhook: &v1alpha1.NotificationWebhook{URL: url},
+		},
+	}
+}
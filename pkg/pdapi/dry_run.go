@@ -0,0 +1,138 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdapi
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// dryRunPDControl wraps a PDControlInterface whose PD clients only log the write calls
+type dryRunPDControl struct {
+	PDControlInterface
+}
+
+// NewDryRunPDControl returns a PDControlInterface whose PD clients read through the given control,
+// but only log the intended changes to PD, without issuing them
+func NewDryRunPDControl(control PDControlInterface) PDControlInterface {
+	return &dryRunPDControl{control}
+}
+
+func (c *dryRunPDControl) GetPDClient(namespace Namespace, tcName string, tlsEnabled bool, tlsSecretName string) PDClient {
+	return &dryRunPDClient{
+		PDClient: c.PDControlInterface.GetPDClient(namespace, tcName, tlsEnabled, tlsSecretName),
+		cluster:  fmt.Sprintf("%s/%s", namespace, tcName),
+	}
+}
+
+func (c *dryRunPDControl) GetPDEtcdClient(namespace Namespace, tcName string, tlsEnabled bool, tlsSecretName string) (PDEtcdClient, error) {
+	return &dryRunPDEtcdClient{cluster: fmt.Sprintf("%s/%s", namespace, tcName)}, nil
+}
+
+// dryRunPDClient passes the read calls to the wrapped PDClient and only logs the write calls
+type dryRunPDClient struct {
+	PDClient
+	cluster string
+}
+
+func (c *dryRunPDClient) log(format string, args ...interface{}) {
+	klog.Infof("[dry-run] would %s in pd of %s", fmt.Sprintf(format, args...), c.cluster)
+}
+
+func (c *dryRunPDClient) SetStoreLabels(storeID uint64, labels map[string]string) (bool, error) {
+	c.log("set the labels of store %d to %v", storeID, labels)
+	return true, nil
+}
+
+func (c *dryRunPDClient) UpdateReplicationConfig(config PDReplicationConfig) error {
+	c.log("update the replication config to %+v", config)
+	return nil
+}
+
+func (c *dryRunPDClient) UpdateScheduleConfig(config PDScheduleConfig) error {
+	c.log("update the schedule config to %+v", config)
+	return nil
+}
+
+func (c *dryRunPDClient) DeleteStore(storeID uint64) error {
+	c.log("delete store %d", storeID)
+	return nil
+}
+
+func (c *dryRunPDClient) SetStoreState(storeID uint64, state string) error {
+	c.log("set the state of store %d to %s", storeID, state)
+	return nil
+}
+
+func (c *dryRunPDClient) DeleteMember(name string) error {
+	c.log("delete member %s", name)
+	return nil
+}
+
+func (c *dryRunPDClient) DeleteMemberByID(memberID uint64) error {
+	c.log("delete member %d", memberID)
+	return nil
+}
+
+func (c *dryRunPDClient) BeginEvictLeader(storeID uint64) error {
+	c.log("begin evicting the leaders of store %d", storeID)
+	return nil
+}
+
+func (c *dryRunPDClient) EndEvictLeader(storeID uint64) error {
+	c.log("end evicting the leaders of store %d", storeID)
+	return nil
+}
+
+func (c *dryRunPDClient) TransferPDLeader(name string) error {
+	c.log("transfer the leader to member %s", name)
+	return nil
+}
+
+func (c *dryRunPDClient) PauseSchedulers(schedulers []string, delay time.Duration) error {
+	c.log("pause the schedulers %v for %s", schedulers, delay)
+	return nil
+}
+
+func (c *dryRunPDClient) SetStoreLimit(storeID uint64, limitType string, rate float64) error {
+	c.log("set the %s limit of store %d to %v", limitType, storeID, rate)
+	return nil
+}
+
+func (c *dryRunPDClient) SetStoreWeight(storeID uint64, leaderWeight, regionWeight float64) error {
+	c.log("set the leader weight of store %d to %v and the region weight to %v", storeID, leaderWeight, regionWeight)
+	return nil
+}
+
+func (c *dryRunPDClient) RemoveTombstoneStores() error {
+	c.log("remove the tombstone stores")
+	return nil
+}
+
+// dryRunPDEtcdClient only logs the write calls to the etcd of PD
+type dryRunPDEtcdClient struct {
+	cluster string
+}
+
+func (c *dryRunPDEtcdClient) PutKey(key, value string) error {
+	klog.Infof("[dry-run] would put key %s in pd etcd of %s", key, c.cluster)
+	return nil
+}
+
+func (c *dryRunPDEtcdClient) DeleteKey(key string) error {
+	klog.Infof("[dry-run] would delete key %s in pd etcd of %s", key, c.cluster)
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdapi
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDryRunPDClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fakePDControl := NewFakePDControl(kubefake.NewSimpleClientset())
+	fakePDClient := NewFakePDClient()
+	fakePDControl.SetPDClient(Namespace("default"), "demo", fakePDClient)
+	pdClient := NewDryRunPDControl(fakePDControl).GetPDClient(Namespace("default"), "demo", false, "")
+
+	fakePDClient.AddReaction(GetStoresActionType, func(action *Action) (interface{}, error) {
+		return &StoresInfo{Count: 1}, nil
+	})
+	stores, err := pdClient.GetStores()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stores.Count).To(Equal(1))
+
+	fakePDClient.AddReaction(DeleteStoreActionType, func(action *Action) (interface{}, error) {
+		return nil, fmt.Errorf("store should not be deleted in dry-run mode")
+	})
+	g.Expect(pdClient.DeleteStore(1)).To(Succeed())
+}
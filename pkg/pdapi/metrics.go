@@ -0,0 +1,175 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdapi
+
+import (
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/tikv-operator/pkg/metrics"
+)
+
+// metricsPDControl wraps a PDControlInterface to record the latency and the errors of the PD API calls
+type metricsPDControl struct {
+	PDControlInterface
+}
+
+// NewMetricsPDControl returns a PDControlInterface whose PD clients record the latency and the errors
+// of every PD API call in the operator metrics
+func NewMetricsPDControl(control PDControlInterface) PDControlInterface {
+	return &metricsPDControl{control}
+}
+
+func (c *metricsPDControl) GetPDClient(namespace Namespace, tcName string, tlsEnabled bool, tlsSecretName string) PDClient {
+	return &metricsPDClient{c.PDControlInterface.GetPDClient(namespace, tcName, tlsEnabled, tlsSecretName)}
+}
+
+type metricsPDClient struct {
+	client PDClient
+}
+
+func observe(method string, start time.Time, err error) {
+	metrics.PDAPIDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.PDAPIErrors.WithLabelValues(method).Inc()
+	}
+}
+
+func (c *metricsPDClient) GetHealth() (info *HealthInfo, err error) {
+	defer func(start time.Time) { observe("GetHealth", start, err) }(time.Now())
+	return c.client.GetHealth()
+}
+
+func (c *metricsPDClient) GetConfig() (config *PDConfigFromAPI, err error) {
+	defer func(start time.Time) { observe("GetConfig", start, err) }(time.Now())
+	return c.client.GetConfig()
+}
+
+func (c *metricsPDClient) GetCluster() (cluster *metapb.Cluster, err error) {
+	defer func(start time.Time) { observe("GetCluster", start, err) }(time.Now())
+	return c.client.GetCluster()
+}
+
+func (c *metricsPDClient) GetMembers() (members *MembersInfo, err error) {
+	defer func(start time.Time) { observe("GetMembers", start, err) }(time.Now())
+	return c.client.GetMembers()
+}
+
+func (c *metricsPDClient) GetStores() (stores *StoresInfo, err error) {
+	defer func(start time.Time) { observe("GetStores", start, err) }(time.Now())
+	return c.client.GetStores()
+}
+
+func (c *metricsPDClient) GetTombStoneStores() (stores *StoresInfo, err error) {
+	defer func(start time.Time) { observe("GetTombStoneStores", start, err) }(time.Now())
+	return c.client.GetTombStoneStores()
+}
+
+func (c *metricsPDClient) GetStore(storeID uint64) (store *StoreInfo, err error) {
+	defer func(start time.Time) { observe("GetStore", start, err) }(time.Now())
+	return c.client.GetStore(storeID)
+}
+
+func (c *metricsPDClient) SetStoreLabels(storeID uint64, labels map[string]string) (set bool, err error) {
+	defer func(start time.Time) { observe("SetStoreLabels", start, err) }(time.Now())
+	return c.client.SetStoreLabels(storeID, labels)
+}
+
+func (c *metricsPDClient) UpdateReplicationConfig(config PDReplicationConfig) (err error) {
+	defer func(start time.Time) { observe("UpdateReplicationConfig", start, err) }(time.Now())
+	return c.client.UpdateReplicationConfig(config)
+}
+
+func (c *metricsPDClient) UpdateScheduleConfig(config PDScheduleConfig) (err error) {
+	defer func(start time.Time) { observe("UpdateScheduleConfig", start, err) }(time.Now())
+	return c.client.UpdateScheduleConfig(config)
+}
+
+func (c *metricsPDClient) DeleteStore(storeID uint64) (err error) {
+	defer func(start time.Time) { observe("DeleteStore", start, err) }(time.Now())
+	return c.client.DeleteStore(storeID)
+}
+
+func (c *metricsPDClient) SetStoreState(storeID uint64, state string) (err error) {
+	defer func(start time.Time) { observe("SetStoreState", start, err) }(time.Now())
+	return c.client.SetStoreState(storeID, state)
+}
+
+func (c *metricsPDClient) DeleteMember(name string) (err error) {
+	defer func(start time.Time) { observe("DeleteMember", start, err) }(time.Now())
+	return c.client.DeleteMember(name)
+}
+
+func (c *metricsPDClient) DeleteMemberByID(memberID uint64) (err error) {
+	defer func(start time.Time) { observe("DeleteMemberByID", start, err) }(time.Now())
+	return c.client.DeleteMemberByID(memberID)
+}
+
+func (c *metricsPDClient) BeginEvictLeader(storeID uint64) (err error) {
+	defer func(start time.Time) { observe("BeginEvictLeader", start, err) }(time.Now())
+	return c.client.BeginEvictLeader(storeID)
+}
+
+func (c *metricsPDClient) EndEvictLeader(storeID uint64) (err error) {
+	defer func(start time.Time) { observe("EndEvictLeader", start, err) }(time.Now())
+	return c.client.EndEvictLeader(storeID)
+}
+
+func (c *metricsPDClient) GetEvictLeaderSchedulers() (schedulers []string, err error) {
+	defer func(start time.Time) { observe("GetEvictLeaderSchedulers", start, err) }(time.Now())
+	return c.client.GetEvictLeaderSchedulers()
+}
+
+func (c *metricsPDClient) GetPDLeader() (leader *pdpb.Member, err error) {
+	defer func(start time.Time) { observe("GetPDLeader", start, err) }(time.Now())
+	return c.client.GetPDLeader()
+}
+
+func (c *metricsPDClient) TransferPDLeader(name string) (err error) {
+	defer func(start time.Time) { observe("TransferPDLeader", start, err) }(time.Now())
+	return c.client.TransferPDLeader(name)
+}
+
+func (c *metricsPDClient) GetSchedulers(paused bool) (schedulers []string, err error) {
+	defer func(start time.Time) { observe("GetSchedulers", start, err) }(time.Now())
+	return c.client.GetSchedulers(paused)
+}
+
+func (c *metricsPDClient) PauseSchedulers(schedulers []string, delay time.Duration) (err error) {
+	defer func(start time.Time) { observe("PauseSchedulers", start, err) }(time.Now())
+	return c.client.PauseSchedulers(schedulers, delay)
+}
+
+func (c *metricsPDClient) GetStoreLimits() (limits map[uint64]StoreLimit, err error) {
+	defer func(start time.Time) { observe("GetStoreLimits", start, err) }(time.Now())
+	return c.client.GetStoreLimits()
+}
+
+func (c *metricsPDClient) SetStoreLimit(storeID uint64, limitType string, rate float64) (err error) {
+	defer func(start time.Time) { observe("SetStoreLimit", start, err) }(time.Now())
+	return c.client.SetStoreLimit(storeID, limitType, rate)
+}
+
+func (c *metricsPDClient) SetStoreWeight(storeID uint64, leaderWeight, regionWeight float64) (err error) {
+	defer func(start time.Time) { observe("SetStoreWeight", start, err) }(time.Now())
+	return c.client.SetStoreWeight(storeID, leaderWeight, regionWeight)
+}
+
+func (c *metricsPDClient) RemoveTombstoneStores() (err error) {
+	defer func(start time.Time) { observe("RemoveTombstoneStores", start, err) }(time.Now())
+	return c.client.RemoveTombstoneStores()
+}
+
+var _ PDClient = &metricsPDClient{}
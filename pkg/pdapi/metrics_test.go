@@ -0,0 +1,51 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdapi
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tikv/tikv-operator/pkg/metrics"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMetricsPDClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fakePDControl := NewFakePDControl(kubefake.NewSimpleClientset())
+	fakePDClient := NewFakePDClient()
+	fakePDControl.SetPDClient(Namespace("default"), "demo", fakePDClient)
+	pdClient := NewMetricsPDControl(fakePDControl).GetPDClient(Namespace("default"), "demo", false, "")
+
+	healthErrors := testutil.ToFloat64(metrics.PDAPIErrors.WithLabelValues("GetHealth"))
+	storesErrors := testutil.ToFloat64(metrics.PDAPIErrors.WithLabelValues("GetStores"))
+
+	fakePDClient.AddReaction(GetHealthActionType, func(action *Action) (interface{}, error) {
+		return &HealthInfo{Healths: []MemberHealth{{Name: "pd-0", Health: true}}}, nil
+	})
+	health, err := pdClient.GetHealth()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(health.Healths).To(HaveLen(1))
+	g.Expect(testutil.ToFloat64(metrics.PDAPIErrors.WithLabelValues("GetHealth"))).To(Equal(healthErrors))
+
+	fakePDClient.AddReaction(GetStoresActionType, func(action *Action) (interface{}, error) {
+		return nil, fmt.Errorf("pd is unavailable")
+	})
+	_, err = pdClient.GetStores()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(testutil.ToFloat64(metrics.PDAPIErrors.WithLabelValues("GetStores"))).To(Equal(storesErrors + 1))
+}
@@ -28,7 +28,6 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/pkg/typeutil"
 	"github.com/tikv/tikv-operator/pkg/httputil"
-	"github.com/tikv/tikv-operator/pkg/util"
 	"github.com/tikv/tikv-operator/pkg/util/crypto"
 	types "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -44,10 +43,11 @@ type Namespace string
 
 // PDControlInterface is an interface that knows how to manage and get tidb cluster's PD client
 type PDControlInterface interface {
-	// GetPDClient provides PDClient of the tidb cluster.
-	GetPDClient(Namespace, string, bool) PDClient
+	// GetPDClient provides PDClient of the tidb cluster, the client certificates are loaded from the
+	// tls secret if tls is enabled
+	GetPDClient(namespace Namespace, tcName string, tlsEnabled bool, tlsSecretName string) PDClient
 	// GetPDEtcdClient provides PD etcd Client of the tidb cluster.
-	GetPDEtcdClient(namespace Namespace, tcName string, tlsEnabled bool) (PDEtcdClient, error)
+	GetPDEtcdClient(namespace Namespace, tcName string, tlsEnabled bool, tlsSecretName string) (PDEtcdClient, error)
 }
 
 // defaultPDControl is the default implementation of PDControlInterface.
@@ -64,10 +64,9 @@ func NewDefaultPDControl(kubeCli kubernetes.Interface) PDControlInterface {
 	return &defaultPDControl{kubeCli: kubeCli, pdClients: map[string]PDClient{}, pdEtcdClients: map[string]PDEtcdClient{}}
 }
 
-// GetTLSConfig returns *tls.Config for given TiDB cluster.
+// GetTLSConfig returns *tls.Config loaded from the client tls secret of a TiDB cluster.
 // It loads in-cluster root ca if caCert is empty.
-func GetTLSConfig(kubeCli kubernetes.Interface, namespace Namespace, tcName string, caCert []byte) (*tls.Config, error) {
-	secretName := util.ClusterClientTLSSecretName(tcName)
+func GetTLSConfig(kubeCli kubernetes.Interface, namespace Namespace, secretName string, caCert []byte) (*tls.Config, error) {
 	secret, err := kubeCli.CoreV1().Secrets(string(namespace)).Get(secretName, types.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("unable to load certificates from secret %s/%s: %v", namespace, secretName, err)
@@ -76,7 +75,7 @@ func GetTLSConfig(kubeCli kubernetes.Interface, namespace Namespace, tcName stri
 	return crypto.LoadTlsConfigFromSecret(secret, caCert)
 }
 
-func (pdc *defaultPDControl) GetPDEtcdClient(namespace Namespace, tcName string, tlsEnabled bool) (PDEtcdClient, error) {
+func (pdc *defaultPDControl) GetPDEtcdClient(namespace Namespace, tcName string, tlsEnabled bool, tlsSecretName string) (PDEtcdClient, error) {
 	pdc.etcdmutex.Lock()
 	defer pdc.etcdmutex.Unlock()
 
@@ -84,7 +83,7 @@ func (pdc *defaultPDControl) GetPDEtcdClient(namespace Namespace, tcName string,
 	var err error
 
 	if tlsEnabled {
-		tlsConfig, err = GetTLSConfig(pdc.kubeCli, namespace, tcName, nil)
+		tlsConfig, err = GetTLSConfig(pdc.kubeCli, namespace, tlsSecretName, nil)
 		if err != nil {
 			klog.Errorf("Unable to get tls config for tidb cluster %q, pd etcd client may not work: %v", tcName, err)
 			return nil, err
@@ -103,7 +102,7 @@ func (pdc *defaultPDControl) GetPDEtcdClient(namespace Namespace, tcName string,
 }
 
 // GetPDClient provides a PDClient of real pd cluster,if the PDClient not existing, it will create new one.
-func (pdc *defaultPDControl) GetPDClient(namespace Namespace, tcName string, tlsEnabled bool) PDClient {
+func (pdc *defaultPDControl) GetPDClient(namespace Namespace, tcName string, tlsEnabled bool, tlsSecretName string) PDClient {
 	pdc.mutex.Lock()
 	defer pdc.mutex.Unlock()
 
@@ -113,10 +112,17 @@ func (pdc *defaultPDControl) GetPDClient(namespace Namespace, tcName string, tls
 
 	if tlsEnabled {
 		scheme = "https"
-		tlsConfig, err = GetTLSConfig(pdc.kubeCli, namespace, tcName, nil)
+		tlsConfig, err = GetTLSConfig(pdc.kubeCli, namespace, tlsSecretName, nil)
 		if err != nil {
 			klog.Errorf("Unable to get tls config for tidb cluster %q, pd client may not work: %v", tcName, err)
-			return &pdClient{url: PdClientURL(namespace, tcName, scheme), httpClient: &http.Client{Timeout: DefaultTimeout}}
+			// fail every request with the tls error instead of talking plain http to a tls-enabled PD
+			return &pdClient{
+				url: PdClientURL(namespace, tcName, scheme),
+				httpClient: &http.Client{
+					Timeout:   DefaultTimeout,
+					Transport: errorRoundTripper{err: fmt.Errorf("pd client of tidb cluster %s/%s is not usable: %v", namespace, tcName, err)},
+				},
+			}
 		}
 
 		return NewPDClient(PdClientURL(namespace, tcName, scheme), DefaultTimeout, tlsConfig)
@@ -129,6 +135,15 @@ func (pdc *defaultPDControl) GetPDClient(namespace Namespace, tcName string, tls
 	return pdc.pdClients[key]
 }
 
+// errorRoundTripper fails every request with err
+type errorRoundTripper struct {
+	err error
+}
+
+func (rt errorRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, rt.err
+}
+
 // pdClientKey returns the pd client key
 func pdClientKey(scheme string, namespace Namespace, clusterName string) string {
 	return fmt.Sprintf("%s.%s.%s", scheme, clusterName, string(namespace))
@@ -168,6 +183,8 @@ type PDClient interface {
 	SetStoreLabels(storeID uint64, labels map[string]string) (bool, error)
 	// UpdateReplicationConfig updates the replication config
 	UpdateReplicationConfig(config PDReplicationConfig) error
+	// UpdateScheduleConfig updates the schedule config, the fields not set are left unchanged
+	UpdateScheduleConfig(config PDScheduleConfig) error
 	// DeleteStore deletes a TiKV store from cluster
 	DeleteStore(storeID uint64) error
 	// SetStoreState sets store to specified state.
@@ -187,6 +204,18 @@ type PDClient interface {
 	GetPDLeader() (*pdpb.Member, error)
 	// TransferPDLeader transfers pd leader to specified member
 	TransferPDLeader(name string) error
+	// GetSchedulers returns the names of the schedulers, only the paused ones if paused
+	GetSchedulers(paused bool) ([]string, error)
+	// PauseSchedulers pauses the schedulers for the delay, a zero delay resumes them
+	PauseSchedulers(schedulers []string, delay time.Duration) error
+	// GetStoreLimits returns the store limits of all the stores by the store id
+	GetStoreLimits() (map[uint64]StoreLimit, error)
+	// SetStoreLimit sets the store limit of the type, i.e. add-peer or remove-peer, of a store
+	SetStoreLimit(storeID uint64, limitType string, rate float64) error
+	// SetStoreWeight sets the leader weight and the region weight of a store
+	SetStoreWeight(storeID uint64, leaderWeight, regionWeight float64) error
+	// RemoveTombstoneStores removes the records of all the tombstone stores from PD
+	RemoveTombstoneStores() error
 }
 
 var (
@@ -200,6 +229,15 @@ var (
 	pdLeaderPrefix         = "pd/api/v1/leader"
 	pdLeaderTransferPrefix = "pd/api/v1/leader/transfer"
 	pdReplicationPrefix    = "pd/api/v1/config/replicate"
+	pdSchedulePrefix       = "pd/api/v1/config/schedule"
+	storesLimitPrefix      = "pd/api/v1/stores/limit"
+)
+
+const (
+	// StoreLimitTypeAddPeer limits the peers added to a store
+	StoreLimitTypeAddPeer = "add-peer"
+	// StoreLimitTypeRemovePeer limits the peers removed from a store
+	StoreLimitTypeRemovePeer = "remove-peer"
 )
 
 // pdClient is default implementation of PDClient
@@ -251,6 +289,8 @@ type StoreStatus struct {
 	ReceivingSnapCount uint32            `json:"receiving_snap_count"`
 	ApplyingSnapCount  uint32            `json:"applying_snap_count"`
 	IsBusy             bool              `json:"is_busy"`
+	LeaderWeight       float64           `json:"leader_weight"`
+	RegionWeight       float64           `json:"region_weight"`
 
 	StartTS         time.Time         `json:"start_ts"`
 	LastHeartbeatTS time.Time         `json:"last_heartbeat_ts"`
@@ -269,6 +309,13 @@ type StoresInfo struct {
 	Stores []*StoreInfo `json:"stores"`
 }
 
+// StoreLimit is the store limit of a store returned from PD RESTful interface,
+// the rates are the number of the operators per minute
+type StoreLimit struct {
+	AddPeer    float64 `json:"add-peer"`
+	RemovePeer float64 `json:"remove-peer"`
+}
+
 // MembersInfo is PD members info returned from PD RESTful interface
 //type Members map[string][]*pdpb.Member
 type MembersInfo struct {
@@ -545,6 +592,24 @@ func (pc *pdClient) UpdateReplicationConfig(config PDReplicationConfig) error {
 	return fmt.Errorf("failed %v to update replication: %v", res.StatusCode, err)
 }
 
+func (pc *pdClient) UpdateScheduleConfig(config PDScheduleConfig) error {
+	apiURL := fmt.Sprintf("%s/%s", pc.url, pdSchedulePrefix)
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	res, err := pc.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err = httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to update schedule: %v", res.StatusCode, err)
+}
+
 func (pc *pdClient) BeginEvictLeader(storeID uint64) error {
 	leaderEvictInfo := getLeaderEvictSchedulerInfo(storeID)
 	apiURL := fmt.Sprintf("%s/%s", pc.url, schedulersPrefix)
@@ -676,6 +741,116 @@ func (pc *pdClient) TransferPDLeader(memberName string) error {
 	return fmt.Errorf("failed %v to transfer pd leader to %s,error: %v", res.StatusCode, memberName, err2)
 }
 
+func (pc *pdClient) GetSchedulers(paused bool) ([]string, error) {
+	apiURL := fmt.Sprintf("%s/%s", pc.url, schedulersPrefix)
+	if paused {
+		apiURL += "?status=paused"
+	}
+	body, err := httputil.GetBodyOK(pc.httpClient, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	schedulers := []string{}
+	if err := json.Unmarshal(body, &schedulers); err != nil {
+		return nil, err
+	}
+	return schedulers, nil
+}
+
+func (pc *pdClient) PauseSchedulers(schedulers []string, delay time.Duration) error {
+	data, err := json.Marshal(map[string]int64{"delay": int64(delay / time.Second)})
+	if err != nil {
+		return err
+	}
+	for _, scheduler := range schedulers {
+		if err := pc.pauseScheduler(scheduler, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pc *pdClient) pauseScheduler(scheduler string, data []byte) error {
+	apiURL := fmt.Sprintf("%s/%s/%s", pc.url, schedulersPrefix, scheduler)
+	res, err := pc.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err2 := httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to pause scheduler %s, error: %v", res.StatusCode, scheduler, err2)
+}
+
+func (pc *pdClient) GetStoreLimits() (map[uint64]StoreLimit, error) {
+	apiURL := fmt.Sprintf("%s/%s", pc.url, storesLimitPrefix)
+	body, err := httputil.GetBodyOK(pc.httpClient, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	limits := map[uint64]StoreLimit{}
+	if err := json.Unmarshal(body, &limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+func (pc *pdClient) SetStoreLimit(storeID uint64, limitType string, rate float64) error {
+	apiURL := fmt.Sprintf("%s/%s/%d/limit", pc.url, storePrefix, storeID)
+	data, err := json.Marshal(map[string]interface{}{"type": limitType, "rate": rate})
+	if err != nil {
+		return err
+	}
+	res, err := pc.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err2 := httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to set %s limit of store %d, error: %v", res.StatusCode, limitType, storeID, err2)
+}
+
+func (pc *pdClient) SetStoreWeight(storeID uint64, leaderWeight, regionWeight float64) error {
+	apiURL := fmt.Sprintf("%s/%s/%d/weight", pc.url, storePrefix, storeID)
+	data, err := json.Marshal(map[string]interface{}{"leader": leaderWeight, "region": regionWeight})
+	if err != nil {
+		return err
+	}
+	res, err := pc.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err2 := httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to set the weight of store %d, error: %v", res.StatusCode, storeID, err2)
+}
+
+func (pc *pdClient) RemoveTombstoneStores() error {
+	apiURL := fmt.Sprintf("%s/%s/remove-tombstone", pc.url, storesPrefix)
+	req, err := http.NewRequest("DELETE", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	res, err := pc.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err2 := httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to remove the tombstone stores, error: %v", res.StatusCode, err2)
+}
+
 func (pc *pdClient) getBodyOK(apiURL string) ([]byte, error) {
 	res, err := pc.httpClient.Get(apiURL)
 	if err != nil {
@@ -731,11 +906,18 @@ const (
 	DeleteMemberActionType             ActionType = "DeleteMember "
 	SetStoreLabelsActionType           ActionType = "SetStoreLabels"
 	UpdateReplicationActionType        ActionType = "UpdateReplicationConfig"
+	UpdateScheduleActionType           ActionType = "UpdateScheduleConfig"
 	BeginEvictLeaderActionType         ActionType = "BeginEvictLeader"
 	EndEvictLeaderActionType           ActionType = "EndEvictLeader"
 	GetEvictLeaderSchedulersActionType ActionType = "GetEvictLeaderSchedulers"
 	GetPDLeaderActionType              ActionType = "GetPDLeader"
 	TransferPDLeaderActionType         ActionType = "TransferPDLeader"
+	GetSchedulersActionType            ActionType = "GetSchedulers"
+	PauseSchedulersActionType          ActionType = "PauseSchedulers"
+	GetStoreLimitsActionType           ActionType = "GetStoreLimits"
+	SetStoreLimitActionType            ActionType = "SetStoreLimit"
+	SetStoreWeightActionType           ActionType = "SetStoreWeight"
+	RemoveTombstoneStoresActionType    ActionType = "RemoveTombstoneStores"
 )
 
 type NotFoundReaction struct {
@@ -751,6 +933,12 @@ type Action struct {
 	Name        string
 	Labels      map[string]string
 	Replication PDReplicationConfig
+	Schedule    PDScheduleConfig
+	Delay       time.Duration
+	Rate        float64
+	Weights     [2]float64
+	Paused      bool
+	Schedulers  []string
 }
 
 type Reaction func(action *Action) (interface{}, error)
@@ -900,6 +1088,16 @@ func (pc *FakePDClient) UpdateReplicationConfig(config PDReplicationConfig) erro
 	return nil
 }
 
+// UpdateScheduleConfig updates the schedule config
+func (pc *FakePDClient) UpdateScheduleConfig(config PDScheduleConfig) error {
+	if reaction, ok := pc.reactions[UpdateScheduleActionType]; ok {
+		action := &Action{Schedule: config}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
 func (pc *FakePDClient) BeginEvictLeader(storeID uint64) error {
 	if reaction, ok := pc.reactions[BeginEvictLeaderActionType]; ok {
 		action := &Action{ID: storeID}
@@ -944,3 +1142,56 @@ func (pc *FakePDClient) TransferPDLeader(memberName string) error {
 	}
 	return nil
 }
+
+func (pc *FakePDClient) GetSchedulers(paused bool) ([]string, error) {
+	if reaction, ok := pc.reactions[GetSchedulersActionType]; ok {
+		action := &Action{Paused: paused}
+		result, err := reaction(action)
+		return result.([]string), err
+	}
+	return nil, nil
+}
+
+func (pc *FakePDClient) PauseSchedulers(schedulers []string, delay time.Duration) error {
+	if reaction, ok := pc.reactions[PauseSchedulersActionType]; ok {
+		action := &Action{Schedulers: schedulers, Delay: delay}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
+func (pc *FakePDClient) GetStoreLimits() (map[uint64]StoreLimit, error) {
+	if reaction, ok := pc.reactions[GetStoreLimitsActionType]; ok {
+		action := &Action{}
+		result, err := reaction(action)
+		return result.(map[uint64]StoreLimit), err
+	}
+	return map[uint64]StoreLimit{}, nil
+}
+
+func (pc *FakePDClient) SetStoreLimit(storeID uint64, limitType string, rate float64) error {
+	if reaction, ok := pc.reactions[SetStoreLimitActionType]; ok {
+		action := &Action{ID: storeID, Name: limitType, Rate: rate}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
+func (pc *FakePDClient) SetStoreWeight(storeID uint64, leaderWeight, regionWeight float64) error {
+	if reaction, ok := pc.reactions[SetStoreWeightActionType]; ok {
+		action := &Action{ID: storeID, Weights: [2]float64{leaderWeight, regionWeight}}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
+func (pc *FakePDClient) RemoveTombstoneStores() error {
+	if reaction, ok := pc.reactions[RemoveTombstoneStoresActionType]; ok {
+		_, err := reaction(&Action{})
+		return err
+	}
+	return nil
+}
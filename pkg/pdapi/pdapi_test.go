@@ -21,11 +21,16 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 )
 
 const (
@@ -648,3 +653,164 @@ func readJSON(r io.ReadCloser, data interface{}) error {
 
 	return nil
 }
+
+func TestGetPDClientWithTLS(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tcs := []struct {
+		caseName   string
+		secretName string
+		secret     *corev1.Secret
+		errMsg     string
+	}{
+		{
+			caseName:   "client secret is missing",
+			secretName: "demo-cluster-client-secret",
+			errMsg:     "unable to load certificates from secret ns/demo-cluster-client-secret",
+		},
+		{
+			caseName:   "client secret has no key",
+			secretName: "demo-cluster-client-secret",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "demo-cluster-client-secret", Namespace: "ns"},
+				Data: map[string][]byte{
+					corev1.TLSCertKey: []byte("cert"),
+				},
+			},
+			errMsg: "cert or key does not exist in secret ns/demo-cluster-client-secret",
+		},
+		{
+			caseName:   "custom client secret",
+			secretName: "demo-tls",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "demo-tls", Namespace: "ns"},
+				Data: map[string][]byte{
+					corev1.TLSCertKey: []byte("cert"),
+				},
+			},
+			errMsg: "cert or key does not exist in secret ns/demo-tls",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Log(tc.caseName)
+		kubeCli := kubefake.NewSimpleClientset()
+		if tc.secret != nil {
+			_, err := kubeCli.CoreV1().Secrets(tc.secret.Namespace).Create(tc.secret)
+			g.Expect(err).NotTo(HaveOccurred())
+		}
+		pdClient := NewDefaultPDControl(kubeCli).GetPDClient(Namespace("ns"), "demo", true, tc.secretName)
+		_, err := pdClient.GetStores()
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("pd client of tidb cluster ns/demo is not usable"))
+		g.Expect(err.Error()).To(ContainSubstring(tc.errMsg))
+	}
+}
+
+func TestPauseSchedulers(t *testing.T) {
+	g := NewGomegaWithT(t)
+	paused := map[string]int64{}
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		if request.Method == "GET" {
+			g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s", schedulersPrefix)))
+			if request.URL.Query().Get("status") == "paused" {
+				w.Write([]byte(`["balance-leader-scheduler"]`))
+				return
+			}
+			w.Write([]byte(`["balance-leader-scheduler","balance-region-scheduler","evict-leader-scheduler-1"]`))
+			return
+		}
+		g.Expect(request.Method).To(Equal("POST"))
+		body := map[string]int64{}
+		g.Expect(readJSON(request.Body, &body)).To(Succeed())
+		paused[strings.TrimPrefix(request.URL.Path, fmt.Sprintf("/%s/", schedulersPrefix))] = body["delay"]
+		w.WriteHeader(http.StatusOK)
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	schedulers, err := pdClient.GetSchedulers(false)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(schedulers).To(Equal([]string{"balance-leader-scheduler", "balance-region-scheduler", "evict-leader-scheduler-1"}))
+	schedulers, err = pdClient.GetSchedulers(true)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(schedulers).To(Equal([]string{"balance-leader-scheduler"}))
+
+	balancers := []string{"balance-leader-scheduler", "balance-region-scheduler"}
+	g.Expect(pdClient.PauseSchedulers(balancers, 10*time.Minute)).To(Succeed())
+	g.Expect(paused).To(Equal(map[string]int64{"balance-leader-scheduler": 600, "balance-region-scheduler": 600}))
+	g.Expect(pdClient.PauseSchedulers(balancers, 0)).To(Succeed())
+	g.Expect(paused).To(Equal(map[string]int64{"balance-leader-scheduler": 0, "balance-region-scheduler": 0}))
+}
+
+func TestStoreLimit(t *testing.T) {
+	g := NewGomegaWithT(t)
+	set := map[string]interface{}{}
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		if request.Method == "GET" {
+			g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s", storesLimitPrefix)))
+			w.Write([]byte(`{"1":{"add-peer":15,"remove-peer":15},"4":{"add-peer":5,"remove-peer":30}}`))
+			return
+		}
+		g.Expect(request.Method).To(Equal("POST"))
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s/1/limit", storePrefix)))
+		g.Expect(readJSON(request.Body, &set)).To(Succeed())
+		w.WriteHeader(http.StatusOK)
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	limits, err := pdClient.GetStoreLimits()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(limits).To(Equal(map[uint64]StoreLimit{1: {AddPeer: 15, RemovePeer: 15}, 4: {AddPeer: 5, RemovePeer: 30}}))
+	g.Expect(pdClient.SetStoreLimit(1, StoreLimitTypeAddPeer, 10)).To(Succeed())
+	g.Expect(set).To(Equal(map[string]interface{}{"type": "add-peer", "rate": float64(10)}))
+}
+
+func TestSetStoreWeight(t *testing.T) {
+	g := NewGomegaWithT(t)
+	set := map[string]interface{}{}
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		g.Expect(request.Method).To(Equal("POST"))
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s/1/weight", storePrefix)))
+		g.Expect(readJSON(request.Body, &set)).To(Succeed())
+		w.WriteHeader(http.StatusOK)
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	g.Expect(pdClient.SetStoreWeight(1, 0, 2)).To(Succeed())
+	g.Expect(set).To(Equal(map[string]interface{}{"leader": float64(0), "region": float64(2)}))
+}
+
+func TestRemoveTombstoneStores(t *testing.T) {
+	g := NewGomegaWithT(t)
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		g.Expect(request.Method).To(Equal("DELETE"))
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s/remove-tombstone", storesPrefix)))
+		w.WriteHeader(http.StatusOK)
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	g.Expect(pdClient.RemoveTombstoneStores()).To(Succeed())
+}
+
+func TestUpdateScheduleConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+	updated := map[string]interface{}{}
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		g.Expect(request.Method).To(Equal("POST"))
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s", pdSchedulePrefix)))
+		g.Expect(readJSON(request.Body, &updated)).To(Succeed())
+		w.WriteHeader(http.StatusOK)
+	})
+	defer svc.Close()
+
+	limit := uint64(8)
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	g.Expect(pdClient.UpdateScheduleConfig(PDScheduleConfig{LeaderScheduleLimit: &limit})).To(Succeed())
+	g.Expect(updated).To(Equal(map[string]interface{}{"leader-schedule-limit": float64(8)}))
+}
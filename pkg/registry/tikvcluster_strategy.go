@@ -33,12 +33,15 @@ func (TikvClusterStrategy) NewObject() runtime.Object {
 
 func (TikvClusterStrategy) PrepareForCreate(ctx context.Context, obj runtime.Object) {
 	if tc, ok := castTikvCluster(obj); ok {
-		defaulting.SetTikvClusterDefault(tc)
+		defaulting.SetDefaults_TikvCluster(tc)
 	}
 }
 
 func (TikvClusterStrategy) PrepareForUpdate(ctx context.Context, obj, old runtime.Object) {
-	// no op to not affect the cluster managed by old versions of the helm chart
+	// only the omitted fields are defaulted, which is what the controller assumes during reconciling anyway
+	if tc, ok := castTikvCluster(obj); ok {
+		defaulting.SetDefaults_TikvCluster(tc)
+	}
 }
 
 func (TikvClusterStrategy) Validate(ctx context.Context, obj runtime.Object) field.ErrorList {
@@ -0,0 +1,189 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikvapi
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tikv/tikv-operator/pkg/httputil"
+	"github.com/tikv/tikv-operator/pkg/pdapi"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+const (
+	DefaultTimeout = 5 * time.Second
+
+	configPrefix = "config"
+)
+
+// TiKVControlInterface is an interface that knows how to get the client of the status server of a tikv pod
+type TiKVControlInterface interface {
+	// GetTiKVPodClient provides TiKVClient of the tikv pod of the tidb cluster.
+	GetTiKVPodClient(namespace string, tcName string, peerServiceName string, podName string, tlsEnabled bool, tlsSecretName string) TiKVClient
+}
+
+// defaultTiKVControl is the default implementation of TiKVControlInterface.
+type defaultTiKVControl struct {
+	mutex       sync.Mutex
+	kubeCli     kubernetes.Interface
+	tikvClients map[string]TiKVClient
+}
+
+// NewDefaultTiKVControl returns a defaultTiKVControl instance
+func NewDefaultTiKVControl(kubeCli kubernetes.Interface) TiKVControlInterface {
+	return &defaultTiKVControl{kubeCli: kubeCli, tikvClients: map[string]TiKVClient{}}
+}
+
+// GetTiKVPodClient provides a TiKVClient of the tikv pod, if the TiKVClient not existing, it will create new one.
+func (tc *defaultTiKVControl) GetTiKVPodClient(namespace string, tcName string, peerServiceName string, podName string, tlsEnabled bool, tlsSecretName string) TiKVClient {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	var tlsConfig *tls.Config
+	var err error
+	var scheme = "http"
+
+	if tlsEnabled {
+		scheme = "https"
+		tlsConfig, err = pdapi.GetTLSConfig(tc.kubeCli, pdapi.Namespace(namespace), tlsSecretName, nil)
+		if err != nil {
+			klog.Errorf("Unable to get tls config for tidb cluster %q, tikv client may not work: %v", tcName, err)
+		}
+		return NewTiKVClient(TiKVPodClientURL(namespace, peerServiceName, podName, scheme), DefaultTimeout, tlsConfig)
+	}
+
+	key := tikvClientKey(scheme, namespace, tcName, podName)
+	if _, ok := tc.tikvClients[key]; !ok {
+		tc.tikvClients[key] = NewTiKVClient(TiKVPodClientURL(namespace, peerServiceName, podName, scheme), DefaultTimeout, nil)
+	}
+	return tc.tikvClients[key]
+}
+
+// tikvClientKey returns the tikv client key
+func tikvClientKey(scheme, namespace, tcName, podName string) string {
+	return fmt.Sprintf("%s.%s.%s.%s", scheme, tcName, namespace, podName)
+}
+
+// TiKVPodClientURL builds the url of the status server of the tikv pod
+func TiKVPodClientURL(namespace, peerServiceName, podName, scheme string) string {
+	return fmt.Sprintf("%s://%s.%s.%s:20180", scheme, podName, peerServiceName, namespace)
+}
+
+// TiKVClient provides the api of the status server of tikv
+type TiKVClient interface {
+	// UpdateConfig changes the config of tikv online, the keys are the dotted paths of the config items,
+	// e.g. raftstore.raft-log-gc-threshold
+	UpdateConfig(config map[string]interface{}) error
+}
+
+// dryRunTiKVControl returns TiKVClients which only log the config changes
+type dryRunTiKVControl struct{}
+
+// NewDryRunTiKVControl returns a TiKVControlInterface which does not change the config of tikv
+func NewDryRunTiKVControl() TiKVControlInterface {
+	return &dryRunTiKVControl{}
+}
+
+func (tc *dryRunTiKVControl) GetTiKVPodClient(namespace string, tcName string, peerServiceName string, podName string, tlsEnabled bool, tlsSecretName string) TiKVClient {
+	return &dryRunTiKVClient{pod: fmt.Sprintf("%s/%s", namespace, podName)}
+}
+
+type dryRunTiKVClient struct {
+	pod string
+}
+
+func (c *dryRunTiKVClient) UpdateConfig(config map[string]interface{}) error {
+	klog.Infof("[dry-run] would update the config of tikv %s to %v", c.pod, config)
+	return nil
+}
+
+// tikvClient is default implementation of TiKVClient
+type tikvClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewTiKVClient returns a new TiKVClient
+func NewTiKVClient(url string, timeout time.Duration, tlsConfig *tls.Config) TiKVClient {
+	return &tikvClient{
+		url: url,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+}
+
+func (c *tikvClient) UpdateConfig(config map[string]interface{}) error {
+	apiURL := fmt.Sprintf("%s/%s", c.url, configPrefix)
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	res, err := c.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err2 := httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to update config of tikv %s, error: %v", res.StatusCode, c.url, err2)
+}
+
+type FakeTiKVControl struct {
+	defaultTiKVControl
+}
+
+func NewFakeTiKVControl(kubeCli kubernetes.Interface) *FakeTiKVControl {
+	return &FakeTiKVControl{
+		defaultTiKVControl{kubeCli: kubeCli, tikvClients: map[string]TiKVClient{}},
+	}
+}
+
+func (ftc *FakeTiKVControl) SetTiKVPodClient(namespace, tcName, podName string, tikvClient TiKVClient) {
+	ftc.defaultTiKVControl.tikvClients[tikvClientKey("http", namespace, tcName, podName)] = tikvClient
+}
+
+// FakeTiKVClient records the config updated and fails the updates with the error set
+type FakeTiKVClient struct {
+	Configs         []map[string]interface{}
+	updateConfigErr error
+}
+
+func NewFakeTiKVClient() *FakeTiKVClient {
+	return &FakeTiKVClient{}
+}
+
+func (c *FakeTiKVClient) SetUpdateConfigError(err error) {
+	c.updateConfigErr = err
+}
+
+func (c *FakeTiKVClient) UpdateConfig(config map[string]interface{}) error {
+	if c.updateConfigErr != nil {
+		return c.updateConfigErr
+	}
+	c.Configs = append(c.Configs, config)
+	return nil
+}
+
+var _ TiKVClient = &FakeTiKVClient{}
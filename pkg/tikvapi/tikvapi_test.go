@@ -0,0 +1,65 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikvapi
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestUpdateConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name      string
+		status    int
+		expectErr bool
+	}{
+		{
+			name:   "config updated",
+			status: http.StatusOK,
+		},
+		{
+			name:      "config not supported to change online",
+			status:    http.StatusInternalServerError,
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := map[string]interface{}{}
+			svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+				g.Expect(request.Method).To(Equal("POST"))
+				g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s", configPrefix)))
+				g.Expect(json.NewDecoder(request.Body).Decode(&updated)).To(Succeed())
+				w.WriteHeader(tt.status)
+			}))
+			defer svc.Close()
+
+			tikvClient := NewTiKVClient(svc.URL, DefaultTimeout, &tls.Config{})
+			err := tikvClient.UpdateConfig(map[string]interface{}{"raftstore.raft-log-gc-threshold": 100})
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(updated).To(Equal(map[string]interface{}{"raftstore.raft-log-gc-threshold": float64(100)}))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package log provides a leveled logger which appends the key-value pairs of its context,
+// e.g. the namespace and the name of a TikvCluster, to every line logged by klog.
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// Logger logs through klog with the key-value pairs of its context
+type Logger struct {
+	keysAndValues []interface{}
+}
+
+// ForCluster returns a Logger carrying the namespace and the name of the TikvCluster
+// and the component being synced, e.g. "pd" or "tikv"
+func ForCluster(tc metav1.Object, component string) Logger {
+	return Logger{}.WithValues("namespace", tc.GetNamespace(), "name", tc.GetName(), "component", component)
+}
+
+// WithValues returns a copy of the Logger with the given key-value pairs appended to its context
+func (l Logger) WithValues(keysAndValues ...interface{}) Logger {
+	kvs := make([]interface{}, 0, len(l.keysAndValues)+len(keysAndValues))
+	kvs = append(kvs, l.keysAndValues...)
+	kvs = append(kvs, keysAndValues...)
+	return Logger{keysAndValues: kvs}
+}
+
+// Infof logs to the INFO log
+func (l Logger) Infof(format string, args ...interface{}) {
+	klog.InfoDepth(1, l.format(format, args...))
+}
+
+// Warningf logs to the WARNING and INFO logs
+func (l Logger) Warningf(format string, args ...interface{}) {
+	klog.WarningDepth(1, l.format(format, args...))
+}
+
+// Errorf logs to the ERROR, WARNING and INFO logs
+func (l Logger) Errorf(format string, args ...interface{}) {
+	klog.ErrorDepth(1, l.format(format, args...))
+}
+
+// V returns a Verbose which only logs when the verbosity of klog is at least level
+func (l Logger) V(level klog.Level) Verbose {
+	return Verbose{enabled: bool(klog.V(level)), logger: l}
+}
+
+func (l Logger) format(format string, args ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(format, args...))
+	for i := 0; i < len(l.keysAndValues); i += 2 {
+		var v interface{} = "<missing>"
+		if i+1 < len(l.keysAndValues) {
+			v = l.keysAndValues[i+1]
+		}
+		fmt.Fprintf(&b, " %v=%q", l.keysAndValues[i], fmt.Sprint(v))
+	}
+	return b.String()
+}
+
+// Verbose is a Logger enabled or disabled by the verbosity of klog
+type Verbose struct {
+	enabled bool
+	logger  Logger
+}
+
+// Enabled returns whether the Verbose logs
+func (v Verbose) Enabled() bool {
+	return v.enabled
+}
+
+// Infof logs to the INFO log if the Verbose is enabled
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v.enabled {
+		klog.InfoDepth(1, v.logger.format(format, args...))
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoggerFormat(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := &metav1.ObjectMeta{Namespace: "default", Name: "demo"}
+	logger := ForCluster(tc, "tikv")
+	g.Expect(logger.format("scaling out %s", "demo-tikv")).To(Equal(
+		`scaling out demo-tikv namespace="default" name="demo" component="tikv"`))
+
+	// WithValues does not modify the context of the parent logger
+	podLogger := logger.WithValues("pod", "demo-tikv-0")
+	g.Expect(podLogger.format("evicting leaders")).To(Equal(
+		`evicting leaders namespace="default" name="demo" component="tikv" pod="demo-tikv-0"`))
+	g.Expect(logger.format("evicting leaders")).To(Equal(
+		`evicting leaders namespace="default" name="demo" component="tikv"`))
+
+	g.Expect(Logger{}.WithValues("store").format("up")).To(Equal(`up store="<missing>"`))
+}
@@ -26,10 +26,32 @@ const (
 	Ready = "Ready"
 	// StatefulSetNotUpToDate is added when one of statefulsets is not up to date.
 	StatfulSetNotUpToDate = "StatefulSetNotUpToDate"
+	// Suspended is added when the tikv cluster is suspended.
+	Suspended = "Suspended"
 	// PDUnhealthy is added when one of pd members is unhealthy.
 	PDUnhealthy = "PDUnhealthy"
 	// TiKVStoreNotUp is added when one of tikv stores is not up.
 	TiKVStoreNotUp = "TiKVStoreNotUp"
+	// MaxFailoverCountReached is added when the tikv failure stores reach the max failover count.
+	MaxFailoverCountReached = "MaxFailoverCountReached"
+	// FailoverAvailable is added when the tikv failure stores are below the max failover count.
+	FailoverAvailable = "FailoverAvailable"
+	// FailoverDisabled is added when the max failover count of tikv is 0.
+	FailoverDisabled = "FailoverDisabled"
+	// InsufficientFailureDomains is added when the tikv stores span fewer failure domains than max-replicas.
+	InsufficientFailureDomains = "InsufficientFailureDomains"
+	// SufficientFailureDomains is added when the tikv stores span no fewer failure domains than max-replicas.
+	SufficientFailureDomains = "SufficientFailureDomains"
+	// ReplicationUnknown is added when the replication of the tikv stores is not known yet.
+	ReplicationUnknown = "ReplicationUnknown"
+	// SupportedVersion is added when the version of the tikv image is supported.
+	SupportedVersion = "SupportedVersion"
+	// UnsupportedVersion is added when the version of the tikv image is below the minimum supported version.
+	UnsupportedVersion = "UnsupportedVersion"
+	// VersionDowngrade is added when the version of the tikv image is lower than the version running.
+	VersionDowngrade = "VersionDowngrade"
+	// UnknownVersion is added when the version can't be parsed from the tag of the tikv image.
+	UnknownVersion = "UnknownVersion"
 )
 
 // NewTikvClusterCondition creates a new tikvcluster condition.
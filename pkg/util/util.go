@@ -160,20 +160,22 @@ func (e SortEnvByName) Less(i, j int) bool {
 	return e[i].Name < e[j].Name
 }
 
-// AppendEnv appends envs `b` into `a` ignoring envs whose names already exist
-// in `b`.
+// AppendEnv appends envs `b` into `a`, envs of `b` override the envs of `a` with
+// the same names in place, and the last one wins among the envs of `b` with the
+// same name, so that each name appears only once.
 // Note that this will not change relative order of envs.
 func AppendEnv(a []corev1.EnvVar, b []corev1.EnvVar) []corev1.EnvVar {
-	aMap := make(map[string]corev1.EnvVar)
-	for _, e := range a {
-		aMap[e.Name] = e
-	}
-	for _, e := range b {
-		if _, ok := aMap[e.Name]; !ok {
-			a = append(a, e)
+	envs := make([]corev1.EnvVar, 0, len(a)+len(b))
+	index := make(map[string]int)
+	for _, e := range append(a[:len(a):len(a)], b...) {
+		if i, ok := index[e.Name]; ok {
+			envs[i] = e
+			continue
 		}
+		index[e.Name] = len(envs)
+		envs = append(envs, e)
 	}
-	return a
+	return envs
 }
 
 // IsOwnedByTikvCluster checks if the given object is owned by TikvCluster.
@@ -208,3 +210,55 @@ func RetainManagedFields(desiredSvc, existedSvc *corev1.Service) {
 		}
 	}
 }
+
+// AddRequiredNodeSelector adds the node selector to the required node affinity of the affinity, the terms of the
+// node selectors are ORed, so each term of the affinity is combined with each term of the node selector to AND them
+func AddRequiredNodeSelector(affinity *corev1.Affinity, nodeSelector *corev1.NodeSelector) *corev1.Affinity {
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	} else {
+		affinity = affinity.DeepCopy()
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := affinity.NodeAffinity
+	required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = nodeSelector.DeepCopy()
+		return affinity
+	}
+	var terms []corev1.NodeSelectorTerm
+	for _, term := range required.NodeSelectorTerms {
+		for _, selectorTerm := range nodeSelector.NodeSelectorTerms {
+			merged := term.DeepCopy()
+			merged.MatchExpressions = append(merged.MatchExpressions, selectorTerm.MatchExpressions...)
+			merged.MatchFields = append(merged.MatchFields, selectorTerm.MatchFields...)
+			terms = append(terms, *merged)
+		}
+	}
+	required.NodeSelectorTerms = terms
+	return affinity
+}
+
+// NodeNameSelector returns the node selector selecting the nodes by the names
+func NodeNameSelector(nodes ...string) *corev1.NodeSelector {
+	return &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+		MatchFields: []corev1.NodeSelectorRequirement{{
+			Key:      "metadata.name",
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   nodes,
+		}},
+	}}}
+}
+
+// ZoneNodeSelector returns the node selector selecting the nodes in the zones
+func ZoneNodeSelector(zones ...string) *corev1.NodeSelector {
+	return &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+		MatchExpressions: []corev1.NodeSelectorRequirement{{
+			Key:      corev1.LabelZoneFailureDomain,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   zones,
+		}},
+	}}}
+}
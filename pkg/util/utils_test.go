@@ -136,7 +136,7 @@ func TestAppendEnv(t *testing.T) {
 		want []corev1.EnvVar
 	}{
 		{
-			name: "envs whose names exist are ignored",
+			name: "envs whose names exist are overridden in place",
 			a: []corev1.EnvVar{
 				{
 					Name:  "foo",
@@ -164,11 +164,11 @@ func TestAppendEnv(t *testing.T) {
 			want: []corev1.EnvVar{
 				{
 					Name:  "foo",
-					Value: "bar",
+					Value: "barbar",
 				},
 				{
 					Name:  "xxx",
-					Value: "xxx",
+					Value: "yyy",
 				},
 				{
 					Name:  "new",
@@ -176,6 +176,53 @@ func TestAppendEnv(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "duplicate envs are deduplicated",
+			a: []corev1.EnvVar{
+				{
+					Name:  "TZ",
+					Value: "UTC",
+				},
+				{
+					Name:  "CAPACITY",
+					Value: "100GB",
+				},
+			},
+			b: []corev1.EnvVar{
+				{
+					Name:  "TZ",
+					Value: "Asia/Shanghai",
+				},
+				{
+					Name:  "TZ",
+					Value: "Europe/Berlin",
+				},
+				{
+					Name: "CAPACITY",
+					ValueFrom: &corev1.EnvVarSource{
+						ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "tikv"},
+							Key:                  "capacity",
+						},
+					},
+				},
+			},
+			want: []corev1.EnvVar{
+				{
+					Name:  "TZ",
+					Value: "Europe/Berlin",
+				},
+				{
+					Name: "CAPACITY",
+					ValueFrom: &corev1.EnvVarSource{
+						ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "tikv"},
+							Key:                  "capacity",
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
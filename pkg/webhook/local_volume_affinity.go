@@ -0,0 +1,70 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"github.com/tikv/tikv-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// localVolumeAffinityInjector injects the node affinity of the local PVs bound to the tikv pods into the pods, so the
+// pods recreated are scheduled back to the nodes their local disks live on
+type localVolumeAffinityInjector struct {
+	kubeCli kubernetes.Interface
+}
+
+func (li *localVolumeAffinityInjector) Name() string {
+	return "inject the node affinity of the local volumes"
+}
+
+func (li *localVolumeAffinityInjector) Mutate(ns string, pod *corev1.Pod, _ bool) (bool, error) {
+	injected := false
+	for _, vol := range pod.Spec.Volumes {
+		if vol.PersistentVolumeClaim == nil {
+			continue
+		}
+		nodeSelector, err := li.localVolumeNodeSelector(ns, vol.PersistentVolumeClaim.ClaimName)
+		if err != nil {
+			return injected, err
+		}
+		if nodeSelector == nil {
+			continue
+		}
+		pod.Spec.Affinity = util.AddRequiredNodeSelector(pod.Spec.Affinity, nodeSelector)
+		injected = true
+	}
+	return injected, nil
+}
+
+// localVolumeNodeSelector returns the required node affinity of the local PV bound to the pvc, nil if the pvc is not
+// bound yet or the PV is not a local volume
+func (li *localVolumeAffinityInjector) localVolumeNodeSelector(ns, pvcName string) (*corev1.NodeSelector, error) {
+	pvc, err := li.kubeCli.CoreV1().PersistentVolumeClaims(ns).Get(pvcName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if pvc.Spec.VolumeName == "" {
+		return nil, nil
+	}
+	pv, err := li.kubeCli.CoreV1().PersistentVolumes().Get(pvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if pv.Spec.Local == nil || pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return nil, nil
+	}
+	return pv.Spec.NodeAffinity.Required, nil
+}
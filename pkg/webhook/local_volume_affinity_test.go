@@ -0,0 +1,146 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/scheme"
+	"github.com/tikv/tikv-operator/pkg/util"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestLocalVolumeAffinityInjectorHandle(t *testing.T) {
+	g := NewGomegaWithT(t)
+	hostTerm := corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+		{Key: "kubernetes.io/hostname", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-1"}},
+	}}
+	zoneTerm := corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+		{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+	}}
+	tests := []struct {
+		name           string
+		labels         map[string]string
+		local          bool
+		bound          bool
+		podAffinity    *corev1.NodeSelector
+		expectAffinity *corev1.NodeSelector
+	}{
+		{
+			name:           "local volume bound",
+			labels:         label.New().Instance("demo").TiKV().Labels(),
+			local:          true,
+			bound:          true,
+			expectAffinity: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{hostTerm}},
+		},
+		{
+			name:        "local volume bound with the node affinity of the pod",
+			labels:      label.New().Instance("demo").TiKV().Labels(),
+			local:       true,
+			bound:       true,
+			podAffinity: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{zoneTerm}},
+			expectAffinity: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+				{MatchExpressions: append(zoneTerm.MatchExpressions, hostTerm.MatchExpressions...)},
+			}},
+		},
+		{
+			name:   "pvc not bound",
+			labels: label.New().Instance("demo").TiKV().Labels(),
+			local:  true,
+		},
+		{
+			name:   "not a local volume",
+			labels: label.New().Instance("demo").TiKV().Labels(),
+			bound:  true,
+		},
+		{
+			name:   "not a tikv pod",
+			labels: label.New().Instance("demo").PD().Labels(),
+			local:  true,
+			bound:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "tikv-demo-tikv-0", Namespace: "ns"},
+			}
+			if tt.bound {
+				pvc.Spec.VolumeName = "pv-0"
+			}
+			pv := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-0"},
+				Spec: corev1.PersistentVolumeSpec{
+					NodeAffinity: &corev1.VolumeNodeAffinity{
+						Required: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{hostTerm}},
+					},
+				},
+			}
+			if tt.local {
+				pv.Spec.Local = &corev1.LocalVolumeSource{Path: "/mnt/disks/tikv"}
+			}
+			decoder, err := admission.NewDecoder(scheme.Scheme)
+			g.Expect(err).NotTo(HaveOccurred())
+			defaulter := &tikvPodDefaulter{mutators: []podMutator{
+				&localVolumeAffinityInjector{kubeCli: kubefake.NewSimpleClientset(pvc, pv)},
+			}}
+			g.Expect(admission.InjectDecoderInto(decoder, defaulter)).To(BeTrue())
+
+			pod := &corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "demo-tikv-0", Namespace: "ns", Labels: tt.labels},
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{{
+						Name: "tikv",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.GetName()},
+						},
+					}},
+				},
+			}
+			if tt.podAffinity != nil {
+				pod.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: tt.podAffinity,
+				}}
+			}
+			raw, err := json.Marshal(pod)
+			g.Expect(err).NotTo(HaveOccurred())
+			resp := defaulter.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: admissionv1beta1.Create,
+				Namespace: "ns",
+				Object:    runtime.RawExtension{Raw: raw},
+			}})
+			g.Expect(resp.Allowed).To(BeTrue())
+			if tt.expectAffinity == nil {
+				g.Expect(resp.Patches).To(BeEmpty())
+				return
+			}
+			g.Expect(resp.Patches).NotTo(BeEmpty())
+			for _, patch := range resp.Patches {
+				g.Expect(patch.Path).To(HavePrefix("/spec/affinity"))
+			}
+			affinity := util.AddRequiredNodeSelector(pod.Spec.Affinity, pv.Spec.NodeAffinity.Required)
+			g.Expect(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal(tt.expectAffinity))
+		})
+	}
+}
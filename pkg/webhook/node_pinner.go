@@ -0,0 +1,59 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodePinner pins the tikv pods to the nodes set by the pinned nodes annotation of the tikv cluster, e.g. to keep
+// the pods on their nodes during a maintenance. A running pod is never moved by the scheduler, so the pin takes
+// effect when the pod is recreated, and the pod recreated after the pin is removed from the annotation is
+// scheduled freely again.
+type nodePinner struct {
+	cli versioned.Interface
+}
+
+func (np *nodePinner) Name() string {
+	return "pin the node"
+}
+
+func (np *nodePinner) Mutate(ns string, pod *corev1.Pod, _ bool) (bool, error) {
+	tcName := pod.Labels[label.InstanceLabelKey]
+	if tcName == "" {
+		return false, nil
+	}
+	tc, err := np.cli.TikvV1alpha1().TikvClusters(ns).Get(tcName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	pinned, err := tc.TiKVPinnedNodes()
+	if err != nil {
+		return false, err
+	}
+	ordinal, err := util.GetOrdinalFromPodName(pod.GetName())
+	if err != nil {
+		return false, err
+	}
+	node := pinned[ordinal]
+	if node == "" {
+		return false, nil
+	}
+	pod.Spec.Affinity = util.AddRequiredNodeSelector(pod.Spec.Affinity, util.NodeNameSelector(node))
+	return true, nil
+}
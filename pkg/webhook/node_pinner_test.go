@@ -0,0 +1,102 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned/fake"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodePinnerMutate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	zoneTerm := corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+		{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+	}}
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		podAffinity    *corev1.NodeSelector
+		expectErr      bool
+		expectAffinity *corev1.NodeSelector
+	}{
+		{
+			name: "not pinned",
+		},
+		{
+			name:           "pinned",
+			annotations:    map[string]string{label.AnnTiKVPinnedNodes: `{"0":"node-1","2":"node-3"}`},
+			expectAffinity: util.NodeNameSelector("node-1"),
+		},
+		{
+			name:        "pinned with the node affinity of the pod",
+			annotations: map[string]string{label.AnnTiKVPinnedNodes: `{"0":"node-1"}`},
+			podAffinity: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{zoneTerm}},
+			expectAffinity: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: zoneTerm.MatchExpressions,
+				MatchFields:      util.NodeNameSelector("node-1").NodeSelectorTerms[0].MatchFields,
+			}}},
+		},
+		{
+			name:        "the other pods pinned",
+			annotations: map[string]string{label.AnnTiKVPinnedNodes: `{"1":"node-2"}`},
+		},
+		{
+			name:        "unpinned",
+			annotations: map[string]string{label.AnnTiKVPinnedNodes: `{}`},
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{label.AnnTiKVPinnedNodes: `["node-1"]`},
+			expectErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns", Annotations: tt.annotations},
+			}
+			pinner := &nodePinner{cli: fake.NewSimpleClientset(tc)}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "demo-tikv-0", Namespace: "ns", Labels: label.New().Instance("demo").TiKV().Labels()},
+			}
+			if tt.podAffinity != nil {
+				pod.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: tt.podAffinity,
+				}}
+			}
+
+			mutated, err := pinner.Mutate("ns", pod, false)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutated).To(Equal(tt.expectAffinity != nil))
+			if tt.expectAffinity == nil {
+				if tt.podAffinity == nil {
+					g.Expect(pod.Spec.Affinity).To(BeNil())
+				}
+				return
+			}
+			g.Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal(tt.expectAffinity))
+		})
+	}
+}
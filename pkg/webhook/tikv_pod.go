@@ -0,0 +1,93 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	"github.com/tikv/tikv-operator/pkg/label"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// podMutator mutates the tikv pod being created in the namespace, and returns whether the pod is changed,
+// nothing but the pod may be changed in a dry run
+type podMutator interface {
+	Name() string
+	Mutate(ns string, pod *corev1.Pod, dryRun bool) (bool, error)
+}
+
+// tikvPodDefaulter applies the mutators to the tikv pods being created in order. The pods are always admitted,
+// the mutators failed are skipped.
+type tikvPodDefaulter struct {
+	mutators []podMutator
+	decoder  *admission.Decoder
+}
+
+// NewTiKVPodDefaulter returns an admission.Handler pinning the tikv pods to their zones, the nodes of their
+// local volumes and the nodes they are pinned to by the tikv cluster
+func NewTiKVPodDefaulter(kubeCli kubernetes.Interface, cli versioned.Interface) admission.Handler {
+	return &tikvPodDefaulter{mutators: []podMutator{
+		&zoneAssigner{kubeCli: kubeCli, cli: cli},
+		&localVolumeAffinityInjector{kubeCli: kubeCli},
+		&nodePinner{cli: cli},
+	}}
+}
+
+func (pd *tikvPodDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create {
+		return admission.Allowed("")
+	}
+	pod := &corev1.Pod{}
+	if err := pd.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	l := label.Label(pod.Labels)
+	if !l.IsManagedByTiKVOperator() || !l.IsTiKV() {
+		return admission.Allowed("")
+	}
+
+	dryRun := req.DryRun != nil && *req.DryRun
+	mutated := false
+	for _, mutator := range pd.mutators {
+		changed, err := mutator.Mutate(req.Namespace, pod, dryRun)
+		if err != nil {
+			klog.Warningf("failed to %s for tikv pod %s/%s, error: %v", mutator.Name(), req.Namespace, pod.GetName(), err)
+			continue
+		}
+		mutated = mutated || changed
+	}
+	if !mutated {
+		return admission.Allowed("")
+	}
+	marshaled, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+func (pd *tikvPodDefaulter) InjectDecoder(d *admission.Decoder) error {
+	pd.decoder = d
+	return nil
+}
+
+var _ admission.Handler = &tikvPodDefaulter{}
+var _ admission.DecoderInjector = &tikvPodDefaulter{}
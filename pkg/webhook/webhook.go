@@ -0,0 +1,144 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	"github.com/tikv/tikv-operator/pkg/registry"
+	"github.com/tikv/tikv-operator/pkg/scheme"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// TikvClusterValidatingPath is the path that the TikvCluster validating webhook is served on
+	TikvClusterValidatingPath = "/validate-tikvcluster"
+	// TikvClusterMutatingPath is the path that the TikvCluster mutating webhook is served on
+	TikvClusterMutatingPath = "/mutate-tikvcluster"
+	// TiKVPodMutatingPath is the path that the tikv pod mutating webhook is served on
+	TiKVPodMutatingPath = "/mutate-tikv-pod"
+)
+
+// strategyValidator validates the admission requests with the validation of a registry strategy,
+// which is the same validation that the controller performs during reconciling
+type strategyValidator struct {
+	strategy registry.CreateUpdateStrategy
+	decoder  *admission.Decoder
+}
+
+// NewStrategyValidator returns an admission.Handler validating the objects with the strategy
+func NewStrategyValidator(strategy registry.CreateUpdateStrategy) admission.Handler {
+	return &strategyValidator{strategy: strategy}
+}
+
+func (sv *strategyValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := sv.strategy.NewObject()
+	if err := sv.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	switch req.Operation {
+	case admissionv1beta1.Create:
+		if errs := sv.strategy.Validate(ctx, obj); len(errs) > 0 {
+			return admission.Denied(errs.ToAggregate().Error())
+		}
+	case admissionv1beta1.Update:
+		old := sv.strategy.NewObject()
+		if err := sv.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if errs := sv.strategy.ValidateUpdate(ctx, obj, old); len(errs) > 0 {
+			return admission.Denied(errs.ToAggregate().Error())
+		}
+	}
+	return admission.Allowed("")
+}
+
+func (sv *strategyValidator) InjectDecoder(d *admission.Decoder) error {
+	sv.decoder = d
+	return nil
+}
+
+// strategyDefaulter sets the defaults of the objects in the admission requests with the registry strategy,
+// the response carries the JSON patches from the requested object to the defaulted one
+type strategyDefaulter struct {
+	strategy registry.CreateUpdateStrategy
+	decoder  *admission.Decoder
+}
+
+// NewStrategyDefaulter returns an admission.Handler defaulting the objects with the strategy
+func NewStrategyDefaulter(strategy registry.CreateUpdateStrategy) admission.Handler {
+	return &strategyDefaulter{strategy: strategy}
+}
+
+func (sd *strategyDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := sd.strategy.NewObject()
+	if err := sd.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	switch req.Operation {
+	case admissionv1beta1.Create:
+		sd.strategy.PrepareForCreate(ctx, obj)
+	case admissionv1beta1.Update:
+		old := sd.strategy.NewObject()
+		if err := sd.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		sd.strategy.PrepareForUpdate(ctx, obj, old)
+	default:
+		return admission.Allowed("")
+	}
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+func (sd *strategyDefaulter) InjectDecoder(d *admission.Decoder) error {
+	sd.decoder = d
+	return nil
+}
+
+// StartServer serves the mutating and validating webhooks of TikvCluster and the mutating webhook of the tikv pods over
+// TLS on the port until stopCh is closed, the tls.crt and tls.key in certDir are used as the serving certificate
+func StartServer(port int, certDir string, kubeCli kubernetes.Interface, cli versioned.Interface, stopCh <-chan struct{}) error {
+	server := &ctrlwebhook.Server{
+		Port:    port,
+		CertDir: certDir,
+	}
+	if err := server.InjectFunc(func(i interface{}) error {
+		_, err := inject.SchemeInto(scheme.Scheme, i)
+		return err
+	}); err != nil {
+		return err
+	}
+	server.Register(TikvClusterMutatingPath, &admission.Webhook{Handler: NewStrategyDefaulter(registry.TikvClusterStrategy{})})
+	server.Register(TikvClusterValidatingPath, &admission.Webhook{Handler: NewStrategyValidator(registry.TikvClusterStrategy{})})
+	server.Register(TiKVPodMutatingPath, &admission.Webhook{Handler: NewTiKVPodDefaulter(kubeCli, cli)})
+	klog.Infof("serving the admission webhook on port %d", port)
+	return server.Start(stopCh)
+}
+
+var _ admission.Handler = &strategyValidator{}
+var _ admission.DecoderInjector = &strategyValidator{}
+var _ admission.Handler = &strategyDefaulter{}
+var _ admission.DecoderInjector = &strategyDefaulter{}
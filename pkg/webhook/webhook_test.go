@@ -0,0 +1,227 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/registry"
+	"github.com/tikv/tikv-operator/pkg/scheme"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestStrategyValidatorHandle(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name          string
+		operation     admissionv1beta1.Operation
+		update        func(tc *v1alpha1.TikvCluster)
+		updateOld     func(tc *v1alpha1.TikvCluster)
+		expectAllowed bool
+		expectReason  string
+	}{
+		{
+			name:          "create a valid cluster",
+			operation:     admissionv1beta1.Create,
+			expectAllowed: true,
+		},
+		{
+			name:      "create a cluster without storage request",
+			operation: admissionv1beta1.Create,
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.ResourceRequirements = corev1.ResourceRequirements{}
+			},
+			expectAllowed: false,
+			expectReason:  "spec.tikv.requests.storage",
+		},
+		{
+			name:      "create a cluster with conflicting listener ports",
+			operation: admissionv1beta1.Create,
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.ListenersConfig.ExternalListeners = []v1alpha1.ExternalListenerConfig{
+					{CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolPlaintext, Name: "a", ContainerPort: 20170}},
+					{CommonListenerSpec: v1alpha1.CommonListenerSpec{Type: v1alpha1.SecurityProtocolPlaintext, Name: "b", ContainerPort: 20170}},
+				}
+			},
+			expectAllowed: false,
+			expectReason:  "spec.tikv.listenersConfig.externalListeners[1].containerPort",
+		},
+		{
+			name:      "scale in PD within the quorum",
+			operation: admissionv1beta1.Update,
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.PD.Replicas = 3
+			},
+			updateOld: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.PD.Replicas = 5
+			},
+			expectAllowed: true,
+		},
+		{
+			name:      "scale in PD below the quorum",
+			operation: admissionv1beta1.Update,
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.PD.Replicas = 1
+			},
+			updateOld: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.PD.Replicas = 3
+			},
+			expectAllowed: false,
+			expectReason:  "spec.pd.replicas",
+		},
+		{
+			name:      "delete is always allowed",
+			operation: admissionv1beta1.Delete,
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.ResourceRequirements = corev1.ResourceRequirements{}
+			},
+			expectAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, err := admission.NewDecoder(scheme.Scheme)
+			g.Expect(err).NotTo(HaveOccurred())
+			validator := NewStrategyValidator(registry.TikvClusterStrategy{})
+			g.Expect(admission.InjectDecoderInto(decoder, validator)).To(BeTrue())
+
+			tc := newTikvCluster()
+			old := newTikvCluster()
+			if tt.update != nil {
+				tt.update(tc)
+			}
+			if tt.updateOld != nil {
+				tt.updateOld(old)
+			}
+			req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: tt.operation,
+				Object:    rawExtension(g, tc),
+			}}
+			if tt.operation == admissionv1beta1.Update {
+				req.OldObject = rawExtension(g, old)
+			}
+			resp := validator.Handle(context.TODO(), req)
+			g.Expect(resp.Allowed).To(Equal(tt.expectAllowed), "%v", resp.Result)
+			if tt.expectReason != "" {
+				g.Expect(string(resp.Result.Reason)).To(ContainSubstring(tt.expectReason))
+			}
+		})
+	}
+}
+
+func newTikvCluster() *v1alpha1.TikvCluster {
+	requirements := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse("10G"),
+		},
+	}
+	tc := &v1alpha1.TikvCluster{
+		TypeMeta:   metav1.TypeMeta{Kind: "TikvCluster", APIVersion: "tikv.org/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+	}
+	tc.Spec.Version = "v4.0.0"
+	tc.Spec.ImagePullPolicy = corev1.PullIfNotPresent
+	tc.Spec.ConfigUpdateStrategy = v1alpha1.ConfigUpdateStrategyInPlace
+	tc.Spec.PD.Replicas = 3
+	tc.Spec.PD.BaseImage = "pingcap/pd"
+	tc.Spec.PD.MaxFailoverCount = pointer.Int32Ptr(3)
+	tc.Spec.PD.ResourceRequirements = requirements
+	tc.Spec.TiKV.Replicas = 3
+	tc.Spec.TiKV.BaseImage = "pingcap/tikv"
+	tc.Spec.TiKV.MaxFailoverCount = pointer.Int32Ptr(3)
+	tc.Spec.TiKV.ResourceRequirements = requirements
+	return tc
+}
+
+func rawExtension(g *GomegaWithT, tc *v1alpha1.TikvCluster) runtime.RawExtension {
+	raw, err := json.Marshal(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	return runtime.RawExtension{Raw: raw}
+}
+
+func TestStrategyDefaulterHandle(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name          string
+		operation     admissionv1beta1.Operation
+		update        func(tc *v1alpha1.TikvCluster)
+		expectPatches []string
+	}{
+		{
+			name:          "fully defaulted cluster",
+			operation:     admissionv1beta1.Create,
+			expectPatches: []string{},
+		},
+		{
+			name:      "create a cluster with omitted fields",
+			operation: admissionv1beta1.Create,
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.TiKV.MaxFailoverCount = nil
+				tc.Spec.ConfigUpdateStrategy = ""
+			},
+			expectPatches: []string{"/spec/configUpdateStrategy", "/spec/tikv/maxFailoverCount"},
+		},
+		{
+			name:      "update a cluster with omitted fields",
+			operation: admissionv1beta1.Update,
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.PD.ResourceRequirements = corev1.ResourceRequirements{}
+			},
+			expectPatches: []string{"/spec/pd/requests"},
+		},
+		{
+			name:      "delete is not defaulted",
+			operation: admissionv1beta1.Delete,
+			update: func(tc *v1alpha1.TikvCluster) {
+				tc.Spec.ConfigUpdateStrategy = ""
+			},
+			expectPatches: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, err := admission.NewDecoder(scheme.Scheme)
+			g.Expect(err).NotTo(HaveOccurred())
+			defaulter := NewStrategyDefaulter(registry.TikvClusterStrategy{})
+			g.Expect(admission.InjectDecoderInto(decoder, defaulter)).To(BeTrue())
+
+			tc := newTikvCluster()
+			if tt.update != nil {
+				tt.update(tc)
+			}
+			req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: tt.operation,
+				Object:    rawExtension(g, tc),
+				OldObject: rawExtension(g, newTikvCluster()),
+			}}
+			resp := defaulter.Handle(context.TODO(), req)
+			g.Expect(resp.Allowed).To(BeTrue(), "%v", resp.Result)
+			paths := []string{}
+			for _, patch := range resp.Patches {
+				paths = append(paths, patch.Path)
+			}
+			g.Expect(paths).To(ConsistOf(tt.expectPatches))
+		})
+	}
+}
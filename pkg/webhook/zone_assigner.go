@@ -0,0 +1,126 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// zoneAssigner assigns the tikv pods to the zones declared in the spec of tikv, the zone assigned is recorded on the
+// pvc of the pod, so the pod recreated stays in the zone its data lives in
+type zoneAssigner struct {
+	kubeCli kubernetes.Interface
+	cli     versioned.Interface
+}
+
+func (za *zoneAssigner) Name() string {
+	return "assign the zone"
+}
+
+func (za *zoneAssigner) Mutate(ns string, pod *corev1.Pod, dryRun bool) (bool, error) {
+	tcName := pod.Labels[label.InstanceLabelKey]
+	if tcName == "" {
+		return false, nil
+	}
+	tc, err := za.cli.TikvV1alpha1().TikvClusters(ns).Get(tcName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	if len(tc.Spec.TiKV.Zones) == 0 {
+		return false, nil
+	}
+
+	var pvc *corev1.PersistentVolumeClaim
+	for _, vol := range pod.Spec.Volumes {
+		if vol.Name == v1alpha1.TiKVMemberType.String() && vol.PersistentVolumeClaim != nil {
+			pvc, err = za.kubeCli.CoreV1().PersistentVolumeClaims(ns).Get(vol.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			break
+		}
+	}
+
+	zone := ""
+	if pvc != nil && zoneDeclared(tc, pvc.Annotations[label.AnnTiKVZone]) {
+		zone = pvc.Annotations[label.AnnTiKVZone]
+	} else {
+		zone, err = za.pickZone(tc, pod.GetName())
+		if err != nil {
+			return false, err
+		}
+		if pvc != nil && !dryRun {
+			pvc = pvc.DeepCopy()
+			if pvc.Annotations == nil {
+				pvc.Annotations = map[string]string{}
+			}
+			pvc.Annotations[label.AnnTiKVZone] = zone
+			if _, err := za.kubeCli.CoreV1().PersistentVolumeClaims(ns).Update(pvc); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[label.AnnTiKVZone] = zone
+	pod.Spec.Affinity = util.AddRequiredNodeSelector(pod.Spec.Affinity, util.ZoneNodeSelector(zone))
+	return true, nil
+}
+
+// pickZone picks the zone lacking the most pods for the pod, the zone declared first wins a tie. The zone with the
+// fewest pods is picked if all the zones are full, e.g. when the pods of the zones scaled in are not deleted yet.
+func (za *zoneAssigner) pickZone(tc *v1alpha1.TikvCluster, podName string) (string, error) {
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return "", err
+	}
+	pods, err := za.kubeCli.CoreV1().Pods(tc.GetNamespace()).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+	counts := map[string]int32{}
+	for _, pod := range pods.Items {
+		if pod.GetName() == podName || pod.DeletionTimestamp != nil {
+			continue
+		}
+		counts[pod.Annotations[label.AnnTiKVZone]]++
+	}
+
+	zones := tc.Spec.TiKV.Zones
+	picked := zones[0]
+	for _, zone := range zones[1:] {
+		deficit, pickedDeficit := zone.Replicas-counts[zone.Name], picked.Replicas-counts[picked.Name]
+		if deficit > pickedDeficit || (pickedDeficit <= 0 && deficit <= 0 && counts[zone.Name] < counts[picked.Name]) {
+			picked = zone
+		}
+	}
+	return picked.Name, nil
+}
+
+func zoneDeclared(tc *v1alpha1.TikvCluster, zone string) bool {
+	for _, z := range tc.Spec.TiKV.Zones {
+		if z.Name == zone {
+			return true
+		}
+	}
+	return false
+}
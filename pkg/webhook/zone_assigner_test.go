@@ -0,0 +1,139 @@
+// Copyright 2020 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tikv/tikv-operator/pkg/apis/tikv/v1alpha1"
+	"github.com/tikv/tikv-operator/pkg/client/clientset/versioned/fake"
+	"github.com/tikv/tikv-operator/pkg/label"
+	"github.com/tikv/tikv-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestZoneAssignerMutate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		name       string
+		zones      []v1alpha1.TiKVZone
+		podZones   []string
+		pvcZone    string
+		dryRun     bool
+		expectZone string
+	}{
+		{
+			name:       "no zones",
+			expectZone: "",
+		},
+		{
+			name:       "zone lacking the most pods",
+			zones:      []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}, {Name: "b", Replicas: 2}},
+			podZones:   []string{"a"},
+			expectZone: "b",
+		},
+		{
+			name:       "zone declared first wins a tie",
+			zones:      []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}, {Name: "b", Replicas: 1}},
+			expectZone: "a",
+		},
+		{
+			name:       "zone with the fewest pods when all the zones are full",
+			zones:      []v1alpha1.TiKVZone{{Name: "a", Replicas: 1}, {Name: "b", Replicas: 1}},
+			podZones:   []string{"a", "a", "b"},
+			expectZone: "b",
+		},
+		{
+			name:       "zone recorded on the pvc",
+			zones:      []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}, {Name: "b", Replicas: 2}},
+			podZones:   []string{"a"},
+			pvcZone:    "a",
+			expectZone: "a",
+		},
+		{
+			name:       "zone recorded on the pvc not declared anymore",
+			zones:      []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}, {Name: "b", Replicas: 2}},
+			podZones:   []string{"b"},
+			pvcZone:    "c",
+			expectZone: "a",
+		},
+		{
+			name:       "zone not recorded on the pvc in a dry run",
+			zones:      []v1alpha1.TiKVZone{{Name: "a", Replicas: 2}, {Name: "b", Replicas: 2}},
+			podZones:   []string{"a"},
+			dryRun:     true,
+			expectZone: "b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TikvCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+			}
+			tc.Spec.TiKV.Zones = tt.zones
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "tikv-demo-tikv-0", Namespace: "ns"},
+			}
+			if tt.pvcZone != "" {
+				pvc.Annotations = map[string]string{label.AnnTiKVZone: tt.pvcZone}
+			}
+			objects := []runtime.Object{pvc}
+			for i, zone := range tt.podZones {
+				objects = append(objects, &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        fmt.Sprintf("demo-tikv-%d", i+1),
+						Namespace:   "ns",
+						Labels:      label.New().Instance("demo").TiKV().Labels(),
+						Annotations: map[string]string{label.AnnTiKVZone: zone},
+					},
+				})
+			}
+			kubeCli := kubefake.NewSimpleClientset(objects...)
+			assigner := &zoneAssigner{kubeCli: kubeCli, cli: fake.NewSimpleClientset(tc)}
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "demo-tikv-0", Namespace: "ns", Labels: label.New().Instance("demo").TiKV().Labels()},
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{{
+						Name: "tikv",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.GetName()},
+						},
+					}},
+				},
+			}
+			mutated, err := assigner.Mutate("ns", pod, tt.dryRun)
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.expectZone == "" {
+				g.Expect(mutated).To(BeFalse())
+				return
+			}
+			g.Expect(mutated).To(BeTrue())
+			g.Expect(pod.Annotations[label.AnnTiKVZone]).To(Equal(tt.expectZone))
+			g.Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal(util.ZoneNodeSelector(tt.expectZone)))
+			pvc, err = kubeCli.CoreV1().PersistentVolumeClaims("ns").Get(pvc.GetName(), metav1.GetOptions{})
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.dryRun {
+				g.Expect(pvc.Annotations).NotTo(HaveKey(label.AnnTiKVZone))
+			} else {
+				g.Expect(pvc.Annotations[label.AnnTiKVZone]).To(Equal(tt.expectZone))
+			}
+		})
+	}
+}